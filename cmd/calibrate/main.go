@@ -0,0 +1,101 @@
+// Command calibrate estimates item response theory parameters for question bank items
+// from historical answers and re-scores the ability (theta) of every graded result.
+//
+// Usage:
+//
+//	calibrate [-model rasch|2pl] [-test ID [-pilot ID]] [-min-responses N]
+//
+// Without -test every graded test with at least -min-responses results is calibrated.
+// With -pilot the adaptive test's question pool takes its parameters from the matching
+// questions of a graded pilot test, which is calibrated first.
+// The database is configured with the same environment variables as the server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"gocbt/internal/services"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+func main() {
+	model := flag.String("model", string(models.IRTModelRasch), "IRT model: rasch or 2pl")
+	testID := flag.Int("test", 0, "calibrate only this test (default: all tests)")
+	pilotTestID := flag.Int("pilot", 0, "calibrate the adaptive -test from this graded pilot test")
+	minResponses := flag.Int("min-responses", models.DefaultMinCalibrationResponses, "graded results a test needs before it is calibrated")
+	flag.Parse()
+
+	irtModel := models.IRTModel(*model)
+	if !irtModel.IsValid() {
+		log.Fatalf("Invalid model %q: %v", *model, auth.ErrInvalidIRTModel)
+	}
+	if *pilotTestID > 0 && *testID <= 0 {
+		log.Fatalf("-pilot requires -test")
+	}
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Connect to database
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Run migrations
+	if err := db.RunMigrations("migrations"); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Re-scored results are recorded in the result ledger like any other change
+	resultSigner, err := auth.LoadResultSigner(&cfg.Integrity, true)
+	if err != nil {
+		log.Fatalf("Failed to load result signing key: %v", err)
+	}
+
+	calibrationService := services.NewCalibrationService(
+		database.NewAnalysisRepository(db),
+		database.NewTestRepository(db),
+		database.NewQuestionRepository(db),
+		database.NewTestResultRepository(db),
+		services.NewIntegrityService(database.NewIntegrityRepository(db), resultSigner),
+	)
+
+	var reports []*models.CalibrationReport
+	if *pilotTestID > 0 {
+		report, err := calibrationService.CalibrateFromPilot(*testID, *pilotTestID, irtModel, *minResponses)
+		if err != nil {
+			log.Fatalf("Failed to calibrate test %d from pilot test %d: %v", *testID, *pilotTestID, err)
+		}
+		reports = append(reports, report)
+	} else if *testID > 0 {
+		report, err := calibrationService.CalibrateTest(*testID, irtModel, *minResponses)
+		if err != nil {
+			log.Fatalf("Failed to calibrate test %d: %v", *testID, err)
+		}
+		reports = append(reports, report)
+	} else {
+		reports, err = calibrationService.CalibrateAll(irtModel, *minResponses)
+		if err != nil {
+			log.Fatalf("Failed to calibrate tests: %v", err)
+		}
+	}
+
+	for _, report := range reports {
+		status := "converged"
+		if !report.Converged {
+			status = "did not converge"
+		}
+		fmt.Printf("Test %d (%s): %d candidates, %d questions calibrated, %d skipped, %d results scored, %s after %d iterations\n",
+			report.TestID, report.Model, report.Candidates, report.Calibrated, report.Skipped, report.Scored, status, report.Iterations)
+	}
+	fmt.Printf("Calibrated %d test(s)\n", len(reports))
+}
@@ -14,7 +14,9 @@ import (
 	"gocbt/internal/auth"
 	"gocbt/internal/config"
 	"gocbt/internal/database"
+	"gocbt/internal/mail"
 	"gocbt/internal/middleware"
+	"gocbt/internal/models"
 	"gocbt/internal/services"
 
 	"github.com/gorilla/handlers"
@@ -46,30 +48,166 @@ func main() {
 	sessionRepo := database.NewTestSessionRepository(db)
 	answerRepo := database.NewUserAnswerRepository(db)
 	resultRepo := database.NewTestResultRepository(db)
+	accessRepo := database.NewTestAccessRepository(db)
+	sebRepo := database.NewSEBRepository(db)
+	gradingRepo := database.NewGradingScaleRepository(db)
+	overrideRepo := database.NewScoreOverrideRepository(db)
+	releaseRepo := database.NewReleaseRepository(db)
+	practiceRepo := database.NewPracticeResultRepository(db)
+	analysisRepo := database.NewAnalysisRepository(db)
+	adaptiveRepo := database.NewAdaptiveRepository(db)
+	exportRepo := database.NewExportRepository(db)
+	certificateRepo := database.NewCertificateRepository(db)
+	courseRepo := database.NewCourseRepository(db)
+	groupRepo := database.NewGroupRepository(db)
+	integrityRepo := database.NewIntegrityRepository(db)
+	roleRepo := database.NewRoleRepository(db)
+	tokenRepo := database.NewTokenRepository(db)
+	oidcRepo := database.NewOIDCRepository(db)
+	identityRepo := database.NewIdentityRepository(db)
+	twoFactorRepo := database.NewTwoFactorRepository(db)
+	accountRepo := database.NewAccountRepository(db)
+
+	// Load the result signing key, creating it on first start
+	resultSigner, err := auth.LoadResultSigner(&cfg.Integrity, true)
+	if err != nil {
+		log.Fatalf("Failed to load result signing key: %v", err)
+	}
 
 	// Initialize services
+	integrityService := services.NewIntegrityService(integrityRepo, resultSigner)
 	passwordManager := auth.NewPasswordManager()
-	userService := services.NewUserService(userRepo, passwordManager)
-	testService := services.NewTestService(testRepo)
+
+	// Check passwords against the directory when one is configured, keeping local
+	// passwords for the roles allowed to use them
+	authenticators := []models.Authenticator{services.NewLocalAuthenticator(userRepo, passwordManager, nil)}
+	if cfg.LDAP.URL != "" {
+		if cfg.LDAP.BaseDN == "" {
+			log.Fatal("LDAP_BASE_DN is required when LDAP_URL is set")
+		}
+		if !models.UserRole(cfg.LDAP.DefaultRole).IsValid() {
+			log.Fatalf("Invalid LDAP_DEFAULT_ROLE %q", cfg.LDAP.DefaultRole)
+		}
+		for group, role := range cfg.LDAP.RoleMapping {
+			if !models.UserRole(role).IsValid() {
+				log.Fatalf("Invalid role %q mapped from %q in LDAP_ROLE_MAPPING", role, group)
+			}
+		}
+		localRoles := make([]models.UserRole, 0, len(cfg.LDAP.LocalLoginRoles))
+		for _, role := range cfg.LDAP.LocalLoginRoles {
+			if !models.UserRole(role).IsValid() {
+				log.Fatalf("Invalid role %q in LDAP_LOCAL_LOGIN_ROLES", role)
+			}
+			localRoles = append(localRoles, models.UserRole(role))
+		}
+
+		ldapClient := auth.NewLDAPClient(&cfg.LDAP, nil)
+		authenticators = []models.Authenticator{
+			services.NewDirectoryAuthenticator(ldapClient, userRepo, identityRepo, groupRepo, &cfg.LDAP),
+			services.NewLocalAuthenticator(userRepo, passwordManager, localRoles),
+		}
+	}
+	userService := services.NewUserService(userRepo, passwordManager, authenticators...)
+	roleService := services.NewRoleService(roleRepo, userRepo)
+	testService := services.NewTestService(testRepo, userRepo, roleRepo, integrityService)
 	questionService := services.NewQuestionService(questionRepo)
-	resultService := services.NewTestResultService(resultRepo, sessionRepo, answerRepo, testRepo, questionRepo)
-	sessionService := services.NewTestSessionService(sessionRepo, answerRepo, testRepo, questionRepo, resultService)
+	gradingService := services.NewGradingScaleService(gradingRepo, testRepo, resultRepo, integrityService)
+	resultService := services.NewTestResultService(resultRepo, sessionRepo, answerRepo, testRepo, questionRepo, overrideRepo, adaptiveRepo, gradingService, integrityService)
+	accessService := services.NewTestAccessService(accessRepo, testRepo)
+	groupService := services.NewGroupService(groupRepo, testRepo, userRepo)
+	practiceService := services.NewPracticeService(practiceRepo, sessionRepo, answerRepo, testRepo, questionRepo)
+	adaptiveService := services.NewAdaptiveService(adaptiveRepo, testRepo, questionRepo)
+	sessionService := services.NewTestSessionService(sessionRepo, answerRepo, testRepo, questionRepo, resultService, accessService, practiceService, adaptiveService, groupService)
+	sebService := services.NewSEBService(sebRepo, testRepo, cfg.App.FrontendURL)
+	regradeService := services.NewRegradeService(testRepo, questionRepo, answerRepo, resultRepo, userRepo, overrideRepo, adaptiveRepo, gradingService, integrityService)
+	overrideService := services.NewScoreOverrideService(overrideRepo, answerRepo, questionRepo, resultRepo, regradeService)
+	releaseService := services.NewReleaseService(releaseRepo, testRepo, resultRepo, questionRepo, answerRepo)
+	analysisService := services.NewAnalysisService(analysisRepo, testRepo, questionRepo)
+	exportService := services.NewExportService(exportRepo, testRepo, questionRepo, overrideRepo)
+	certificateService := services.NewCertificateService(certificateRepo, resultRepo, testRepo, userRepo, releaseService, cfg.App.CertificateVerifyURL)
+	gradebookService := services.NewGradebookService(courseRepo, testRepo, userRepo, gradingService, releaseService)
+
+	// Load the token signing keys, creating one on first start
+	jwtKeys, err := auth.LoadKeySet(&cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
 
-	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(&cfg.JWT)
+	// Initialize JWT manager and token service
+	jwtManager := auth.NewJWTManager(&cfg.JWT, jwtKeys)
+	tokenService := services.NewTokenService(tokenRepo, userRepo, roleRepo, jwtManager, cfg.JWT.RefreshExpiration)
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, roleRepo, userRepo, cfg.App.TOTPIssuer)
+
+	// Enable OpenID Connect single sign-on when an identity provider is configured
+	var oidcClient *auth.OIDCClient
+	if cfg.OIDC.IssuerURL != "" {
+		if cfg.OIDC.ClientID == "" {
+			log.Fatal("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+		}
+		if !models.UserRole(cfg.OIDC.DefaultRole).IsValid() {
+			log.Fatalf("Invalid OIDC_DEFAULT_ROLE %q", cfg.OIDC.DefaultRole)
+		}
+		for value, role := range cfg.OIDC.RoleMapping {
+			if !models.UserRole(role).IsValid() {
+				log.Fatalf("Invalid role %q mapped from %q in OIDC_ROLE_MAPPING", role, value)
+			}
+		}
+		oidcClient = auth.NewOIDCClient(&cfg.OIDC, nil)
+	}
+	oidcService := services.NewOIDCService(oidcRepo, identityRepo, userRepo, oidcClient, &cfg.OIDC)
+
+	// Load the key signing password reset and verification links, creating it on first start
+	accountTokenSigner, err := auth.LoadAccountTokenSigner(&cfg.Account, true)
+	if err != nil {
+		log.Fatalf("Failed to load account token key: %v", err)
+	}
+
+	// Deliver email in the background, so that requests which send mail take no longer
+	// than those which do not
+	mailer, err := mail.New(&cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to configure mail: %v", err)
+	}
+	if cfg.Mail.Driver != mail.DriverSMTP && cfg.App.Environment == "production" {
+		log.Printf("Warning: MAIL_DRIVER is %q; password reset and verification emails are not sent", cfg.Mail.Driver)
+	}
+	mailer = mail.Background(mailer, func(message *mail.Message, err error) {
+		log.Printf("Failed to send email to %s: %v", message.To, err)
+	})
+	accountService := services.NewAccountService(accountRepo, userRepo, tokenRepo, passwordManager, accountTokenSigner, mailer, &cfg.Account, cfg.App.FrontendURL)
 
 	// Initialize middleware
-	authMiddleware := auth.NewMiddleware(jwtManager)
+	authMiddleware := auth.NewMiddleware(jwtManager, roleService, tokenService)
+	sebValidator := middleware.NewSEBValidator(sebService, sessionService)
 
 	// Initialize handlers
-	authHandler := api.NewAuthHandler(userService, jwtManager)
-	testHandler := api.NewTestHandler(testService, questionService)
-	questionHandler := api.NewQuestionHandler(questionService)
-	sessionHandler := api.NewSessionHandler(sessionService)
-	resultHandler := api.NewResultHandler(resultService)
+	policy := auth.NewPolicy()
+	authHandler := api.NewAuthHandler(userService, tokenService, twoFactorService, accountService)
+	oidcHandler := api.NewOIDCHandler(oidcService, tokenService, twoFactorService)
+	twoFactorHandler := api.NewTwoFactorHandler(twoFactorService)
+	testHandler := api.NewTestHandler(testService, questionService, policy)
+	questionHandler := api.NewQuestionHandler(questionService, testService, policy)
+	sessionHandler := api.NewSessionHandler(sessionService, releaseService, testService, policy)
+	resultHandler := api.NewResultHandler(resultService, releaseService, testService, sessionService, policy)
+	accessHandler := api.NewAccessHandler(accessService, testService, policy)
+	sebHandler := api.NewSEBHandler(sebService, testService, policy)
+	gradingHandler := api.NewGradingHandler(gradingService, testService, policy)
+	regradeHandler := api.NewRegradeHandler(regradeService, questionService, testService, policy)
+	overrideHandler := api.NewOverrideHandler(overrideService, testService, policy)
+	releaseHandler := api.NewReleaseHandler(releaseService, testService, policy)
+	practiceHandler := api.NewPracticeHandler(practiceService, testService, policy)
+	analysisHandler := api.NewAnalysisHandler(analysisService, testService, policy)
+	adaptiveHandler := api.NewAdaptiveHandler(adaptiveService, testService, policy)
+	exportHandler := api.NewExportHandler(exportService, testService, policy)
+	certificateHandler := api.NewCertificateHandler(certificateService, resultService, testService, policy)
+	integrityHandler := api.NewIntegrityHandler(integrityService, resultService, testService, policy)
+	gradebookHandler := api.NewGradebookHandler(gradebookService, testService, policy)
+	groupHandler := api.NewGroupHandler(groupService, testService, policy)
+	roleHandler := api.NewRoleHandler(roleService)
+	jwksHandler := api.NewJWKSHandler(jwtKeys)
 
 	// Setup routes
-	router := setupRoutes(authHandler, testHandler, questionHandler, sessionHandler, resultHandler, authMiddleware)
+	router := setupRoutes(authHandler, oidcHandler, twoFactorHandler, testHandler, questionHandler, sessionHandler, resultHandler, accessHandler, sebHandler, gradingHandler, regradeHandler, overrideHandler, releaseHandler, practiceHandler, analysisHandler, adaptiveHandler, exportHandler, certificateHandler, integrityHandler, gradebookHandler, groupHandler, roleHandler, jwksHandler, authMiddleware, sebValidator)
 
 	// Create rate limiter (100 requests per minute per IP)
 	rateLimiter := middleware.NewRateLimiter(100, time.Minute)
@@ -80,11 +218,16 @@ func main() {
 	secureRouter = middleware.RequestSizeLimit(10 * 1024 * 1024)(secureRouter) // 10MB limit
 	secureRouter = middleware.ValidateContentType("application/json")(secureRouter)
 
+	// Resolve client IPs from proxy headers only when running behind a trusted proxy
+	if cfg.Server.TrustProxyHeaders {
+		secureRouter = handlers.ProxyHeaders(secureRouter)
+	}
+
 	// Setup CORS
 	corsHandler := handlers.CORS(
 		handlers.AllowedOrigins(cfg.App.CORSOrigins),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", models.SEBConfigKeyHashHeader, models.SEBRequestHashHeader}),
 	)(secureRouter)
 
 	// Create HTTP server
@@ -96,6 +239,17 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Rotate the token signing keys on schedule, checking every minute
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := jwtKeys.Rotate(); err != nil {
+				log.Printf("Failed to rotate JWT signing keys: %v", err)
+			}
+		}
+	}()
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Starting server on %s", server.Addr)
@@ -123,7 +277,7 @@ func main() {
 }
 
 // setupRoutes configures the application routes
-func setupRoutes(authHandler *api.AuthHandler, testHandler *api.TestHandler, questionHandler *api.QuestionHandler, sessionHandler *api.SessionHandler, resultHandler *api.ResultHandler, authMiddleware *auth.Middleware) *mux.Router {
+func setupRoutes(authHandler *api.AuthHandler, oidcHandler *api.OIDCHandler, twoFactorHandler *api.TwoFactorHandler, testHandler *api.TestHandler, questionHandler *api.QuestionHandler, sessionHandler *api.SessionHandler, resultHandler *api.ResultHandler, accessHandler *api.AccessHandler, sebHandler *api.SEBHandler, gradingHandler *api.GradingHandler, regradeHandler *api.RegradeHandler, overrideHandler *api.OverrideHandler, releaseHandler *api.ReleaseHandler, practiceHandler *api.PracticeHandler, analysisHandler *api.AnalysisHandler, adaptiveHandler *api.AdaptiveHandler, exportHandler *api.ExportHandler, certificateHandler *api.CertificateHandler, integrityHandler *api.IntegrityHandler, gradebookHandler *api.GradebookHandler, groupHandler *api.GroupHandler, roleHandler *api.RoleHandler, jwksHandler *api.JWKSHandler, authMiddleware *auth.Middleware, sebValidator *middleware.SEBValidator) *mux.Router {
 	router := mux.NewRouter()
 
 	// Health check endpoint
@@ -132,6 +286,9 @@ func setupRoutes(authHandler *api.AuthHandler, testHandler *api.TestHandler, que
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
+	// Public keys for verifying access tokens
+	router.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods("GET")
+
 	// API routes
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
 
@@ -139,12 +296,32 @@ func setupRoutes(authHandler *api.AuthHandler, testHandler *api.TestHandler, que
 	authRouter := apiRouter.PathPrefix("/auth").Subrouter()
 	authRouter.HandleFunc("/register", authHandler.Register).Methods("POST")
 	authRouter.HandleFunc("/login", authHandler.Login).Methods("POST")
+	authRouter.HandleFunc("/login/2fa", authHandler.LoginTwoFactor).Methods("POST")
+	authRouter.HandleFunc("/login/2fa/enrol", authHandler.LoginTwoFactorEnrol).Methods("POST")
+	authRouter.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST")
+	authRouter.HandleFunc("/password/forgot", authHandler.ForgotPassword).Methods("POST")
+	authRouter.HandleFunc("/password/reset", authHandler.ResetPassword).Methods("POST")
+	authRouter.HandleFunc("/verify-email", authHandler.VerifyEmail).Methods("POST")
+	authRouter.HandleFunc("/oidc/start", oidcHandler.StartLogin).Methods("POST")
+	authRouter.HandleFunc("/oidc/callback", oidcHandler.Callback).Methods("POST")
+
+	// Certificate verification routes (public, for employers checking a certificate)
+	certificateRouter := apiRouter.PathPrefix("/certificates").Subrouter()
+	certificateRouter.HandleFunc("/verify/{verification_id}", certificateHandler.VerifyCertificate).Methods("GET")
 
 	// Protected auth routes
 	protectedAuthRouter := apiRouter.PathPrefix("/auth").Subrouter()
 	protectedAuthRouter.Use(authMiddleware.Authenticate)
 	protectedAuthRouter.HandleFunc("/profile", authHandler.Profile).Methods("GET")
-	protectedAuthRouter.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST")
+	protectedAuthRouter.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	protectedAuthRouter.HandleFunc("/logout-all", authHandler.LogoutAll).Methods("POST")
+	protectedAuthRouter.HandleFunc("/verify-email/send", authHandler.SendEmailVerification).Methods("POST")
+	protectedAuthRouter.HandleFunc("/permissions", roleHandler.GetMyPermissions).Methods("GET")
+	protectedAuthRouter.HandleFunc("/2fa", twoFactorHandler.GetStatus).Methods("GET")
+	protectedAuthRouter.HandleFunc("/2fa", twoFactorHandler.Disable).Methods("DELETE")
+	protectedAuthRouter.HandleFunc("/2fa/enrol", twoFactorHandler.StartEnrolment).Methods("POST")
+	protectedAuthRouter.HandleFunc("/2fa/confirm", twoFactorHandler.ConfirmEnrolment).Methods("POST")
+	protectedAuthRouter.HandleFunc("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
 
 	// Test routes (protected)
 	testRouter := apiRouter.PathPrefix("/tests").Subrouter()
@@ -156,6 +333,45 @@ func setupRoutes(authHandler *api.AuthHandler, testHandler *api.TestHandler, que
 	testRouter.HandleFunc("/{id:[0-9]+}", testHandler.UpdateTest).Methods("PUT")
 	testRouter.HandleFunc("/{id:[0-9]+}", testHandler.DeleteTest).Methods("DELETE")
 	testRouter.HandleFunc("/{id:[0-9]+}/questions", testHandler.GetTestQuestions).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/co-owners", testHandler.GetCoOwners).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/co-owners", testHandler.SetCoOwners).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/access-policy", accessHandler.GetPolicy).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/access-policy", accessHandler.UpdatePolicy).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/access-policy", accessHandler.DeletePolicy).Methods("DELETE")
+	testRouter.HandleFunc("/{id:[0-9]+}/access-code", accessHandler.GetCurrentCode).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/access-failures", accessHandler.GetFailures).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/seb-settings", sebHandler.GetSettings).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/seb-settings", sebHandler.UpdateSettings).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/seb-settings", sebHandler.DeleteSettings).Methods("DELETE")
+	testRouter.HandleFunc("/{id:[0-9]+}/seb-config", sebHandler.DownloadConfig).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/grading-scale", gradingHandler.AssignScale).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/grades/recompute", gradingHandler.RecomputeGrades).Methods("POST")
+	testRouter.HandleFunc("/{id:[0-9]+}/regrade", regradeHandler.RegradeTest).Methods("POST")
+	testRouter.HandleFunc("/{id:[0-9]+}/overrides", overrideHandler.GetTestOverrides).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/release-settings", releaseHandler.GetSettings).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/release-settings", releaseHandler.UpdateSettings).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/release-settings", releaseHandler.DeleteSettings).Methods("DELETE")
+	testRouter.HandleFunc("/{id:[0-9]+}/certificate-template", certificateHandler.GetTemplate).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/certificate-template", certificateHandler.UpdateTemplate).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/certificate-template", certificateHandler.DeleteTemplate).Methods("DELETE")
+	testRouter.HandleFunc("/{id:[0-9]+}/release", releaseHandler.ReleaseResults).Methods("POST")
+	testRouter.HandleFunc("/{id:[0-9]+}/release", releaseHandler.WithdrawResults).Methods("DELETE")
+	testRouter.HandleFunc("/{id:[0-9]+}/practice-attempts", practiceHandler.GetTestAttempts).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/adaptive-settings", adaptiveHandler.GetSettings).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/adaptive-settings", adaptiveHandler.UpdateSettings).Methods("PUT")
+	testRouter.HandleFunc("/{id:[0-9]+}/adaptive-settings", adaptiveHandler.DeleteSettings).Methods("DELETE")
+	testRouter.HandleFunc("/{id:[0-9]+}/groups", groupHandler.GetTestGroups).Methods("GET")
+	testRouter.HandleFunc("/{id:[0-9]+}/groups", groupHandler.AssignTestGroups).Methods("PUT")
+
+	// Grading scale routes (protected)
+	gradingRouter := apiRouter.PathPrefix("/grading-scales").Subrouter()
+	gradingRouter.Use(authMiddleware.Authenticate)
+	gradingRouter.HandleFunc("", gradingHandler.CreateScale).Methods("POST")
+	gradingRouter.HandleFunc("", gradingHandler.ListScales).Methods("GET")
+	gradingRouter.HandleFunc("/{id:[0-9]+}", gradingHandler.GetScale).Methods("GET")
+	gradingRouter.HandleFunc("/{id:[0-9]+}", gradingHandler.UpdateScale).Methods("PUT")
+	gradingRouter.HandleFunc("/{id:[0-9]+}", gradingHandler.DeleteScale).Methods("DELETE")
+	gradingRouter.HandleFunc("/{id:[0-9]+}/default", gradingHandler.SetDefaultScale).Methods("POST")
 
 	// Question routes (protected)
 	questionRouter := apiRouter.PathPrefix("/questions").Subrouter()
@@ -164,19 +380,25 @@ func setupRoutes(authHandler *api.AuthHandler, testHandler *api.TestHandler, que
 	questionRouter.HandleFunc("/{id:[0-9]+}", questionHandler.GetQuestion).Methods("GET")
 	questionRouter.HandleFunc("/{id:[0-9]+}", questionHandler.UpdateQuestion).Methods("PUT")
 	questionRouter.HandleFunc("/{id:[0-9]+}", questionHandler.DeleteQuestion).Methods("DELETE")
+	questionRouter.HandleFunc("/{id:[0-9]+}/feedback", questionHandler.UpdateFeedback).Methods("PUT")
+	questionRouter.HandleFunc("/{id:[0-9]+}/feedback", questionHandler.DeleteFeedback).Methods("DELETE")
 	questionRouter.HandleFunc("/{id:[0-9]+}/options", questionHandler.AddOption).Methods("POST")
 	questionRouter.HandleFunc("/{id:[0-9]+}/options/{optionId:[0-9]+}", questionHandler.UpdateOption).Methods("PUT")
 	questionRouter.HandleFunc("/{id:[0-9]+}/options/{optionId:[0-9]+}", questionHandler.DeleteOption).Methods("DELETE")
 	questionRouter.HandleFunc("/{id:[0-9]+}/answers", questionHandler.AddCorrectAnswer).Methods("POST")
+	questionRouter.HandleFunc("/{id:[0-9]+}/regrade", regradeHandler.RegradeQuestion).Methods("POST")
+	questionRouter.HandleFunc("/{id:[0-9]+}/void", overrideHandler.VoidQuestion).Methods("POST")
 
 	// Session routes (protected)
 	sessionRouter := apiRouter.PathPrefix("/sessions").Subrouter()
 	sessionRouter.Use(authMiddleware.Authenticate)
+	sessionRouter.Use(sebValidator.RequireSEB)
 	sessionRouter.HandleFunc("/start", sessionHandler.StartSession).Methods("POST")
 	sessionRouter.HandleFunc("/my", sessionHandler.GetUserSessions).Methods("GET")
 	sessionRouter.HandleFunc("/{token}", sessionHandler.GetSession).Methods("GET")
 	sessionRouter.HandleFunc("/{token}/answers", sessionHandler.SubmitAnswer).Methods("POST")
 	sessionRouter.HandleFunc("/{token}/answers", sessionHandler.GetSessionAnswers).Methods("GET")
+	sessionRouter.HandleFunc("/{token}/adaptive", sessionHandler.GetAdaptiveState).Methods("GET")
 	sessionRouter.HandleFunc("/{token}/submit", sessionHandler.SubmitSession).Methods("POST")
 	sessionRouter.HandleFunc("/{token}/progress", sessionHandler.UpdateProgress).Methods("PUT")
 
@@ -189,6 +411,85 @@ func setupRoutes(authHandler *api.AuthHandler, testHandler *api.TestHandler, que
 	resultRouter.HandleFunc("/session/{sessionId:[0-9]+}/calculate", resultHandler.CalculateResult).Methods("POST")
 	resultRouter.HandleFunc("/test/{id:[0-9]+}", resultHandler.GetTestResults).Methods("GET")
 	resultRouter.HandleFunc("/test/{id:[0-9]+}/statistics", resultHandler.GetTestStatistics).Methods("GET")
+	resultRouter.HandleFunc("/test/{id:[0-9]+}/item-analysis", analysisHandler.GetItemAnalysis).Methods("GET")
+	resultRouter.HandleFunc("/test/{id:[0-9]+}/reliability", analysisHandler.GetReliabilityReport).Methods("GET")
+	resultRouter.HandleFunc("/test/{id:[0-9]+}/export", exportHandler.ExportResults).Methods("GET")
+	resultRouter.HandleFunc("/test/{id:[0-9]+}/answers/export", exportHandler.ExportAnswers).Methods("GET")
+	resultRouter.HandleFunc("/{id:[0-9]+}/override", overrideHandler.OverrideResult).Methods("POST")
+	resultRouter.HandleFunc("/{id:[0-9]+}/review", resultHandler.GetReview).Methods("GET")
+	resultRouter.HandleFunc("/{id:[0-9]+}/report", certificateHandler.GetScoreReport).Methods("GET")
+	resultRouter.HandleFunc("/{id:[0-9]+}/certificate", certificateHandler.GetCertificate).Methods("GET")
+	resultRouter.HandleFunc("/{id:[0-9]+}/integrity", integrityHandler.VerifyResult).Methods("GET")
+
+	// Result integrity routes (protected)
+	integrityRouter := apiRouter.PathPrefix("/integrity").Subrouter()
+	integrityRouter.Use(authMiddleware.Authenticate)
+	integrityRouter.HandleFunc("/verify", integrityHandler.VerifyAll).Methods("GET")
+	integrityRouter.HandleFunc("/public-key", integrityHandler.GetPublicKey).Methods("GET")
+
+	// Class and group routes (protected)
+	groupRouter := apiRouter.PathPrefix("/groups").Subrouter()
+	groupRouter.Use(authMiddleware.Authenticate)
+	groupRouter.HandleFunc("", groupHandler.CreateGroup).Methods("POST")
+	groupRouter.HandleFunc("", groupHandler.ListGroups).Methods("GET")
+	groupRouter.HandleFunc("/my", groupHandler.GetMyGroups).Methods("GET")
+	groupRouter.HandleFunc("/join", groupHandler.JoinGroup).Methods("POST")
+	groupRouter.HandleFunc("/{id:[0-9]+}", groupHandler.GetGroup).Methods("GET")
+	groupRouter.HandleFunc("/{id:[0-9]+}", groupHandler.UpdateGroup).Methods("PUT")
+	groupRouter.HandleFunc("/{id:[0-9]+}", groupHandler.DeleteGroup).Methods("DELETE")
+	groupRouter.HandleFunc("/{id:[0-9]+}/invite-code", groupHandler.RegenerateInviteCode).Methods("POST")
+	groupRouter.HandleFunc("/{id:[0-9]+}/invite-code", groupHandler.CloseInviteCode).Methods("DELETE")
+	groupRouter.HandleFunc("/{id:[0-9]+}/directory-group", groupHandler.LinkDirectoryGroup).Methods("PUT")
+	groupRouter.HandleFunc("/{id:[0-9]+}/members", groupHandler.GetMembers).Methods("GET")
+	groupRouter.HandleFunc("/{id:[0-9]+}/members", groupHandler.AddMember).Methods("POST")
+	groupRouter.HandleFunc("/{id:[0-9]+}/members/{userId:[0-9]+}", groupHandler.RemoveMember).Methods("DELETE")
+
+	// Role and permission routes (protected, role managers only)
+	roleRouter := apiRouter.PathPrefix("/roles").Subrouter()
+	roleRouter.Use(authMiddleware.Authenticate)
+	roleRouter.Use(authMiddleware.RequirePermission(models.PermRolesManage))
+	roleRouter.HandleFunc("", roleHandler.CreateRole).Methods("POST")
+	roleRouter.HandleFunc("", roleHandler.ListRoles).Methods("GET")
+	roleRouter.HandleFunc("/permissions", roleHandler.ListPermissions).Methods("GET")
+	roleRouter.HandleFunc("/{id:[0-9]+}", roleHandler.GetRole).Methods("GET")
+	roleRouter.HandleFunc("/{id:[0-9]+}", roleHandler.UpdateRole).Methods("PUT")
+	roleRouter.HandleFunc("/{id:[0-9]+}", roleHandler.DeleteRole).Methods("DELETE")
+	roleRouter.HandleFunc("/{id:[0-9]+}/two-factor", roleHandler.SetTwoFactorRequired).Methods("PUT")
+
+	// User role assignment routes (protected, role managers only)
+	userRouter := apiRouter.PathPrefix("/users").Subrouter()
+	userRouter.Use(authMiddleware.Authenticate)
+	userRouter.Use(authMiddleware.RequirePermission(models.PermRolesManage))
+	userRouter.HandleFunc("/{id:[0-9]+}/roles", roleHandler.GetUserRoles).Methods("GET")
+	userRouter.HandleFunc("/{id:[0-9]+}/roles", roleHandler.SetUserRoles).Methods("PUT")
+	userRouter.HandleFunc("/{id:[0-9]+}/two-factor", twoFactorHandler.ResetUser).Methods("DELETE")
+
+	// Course gradebook routes (protected)
+	courseRouter := apiRouter.PathPrefix("/courses").Subrouter()
+	courseRouter.Use(authMiddleware.Authenticate)
+	courseRouter.HandleFunc("", gradebookHandler.CreateCourse).Methods("POST")
+	courseRouter.HandleFunc("", gradebookHandler.ListCourses).Methods("GET")
+	courseRouter.HandleFunc("/{id:[0-9]+}", gradebookHandler.GetCourse).Methods("GET")
+	courseRouter.HandleFunc("/{id:[0-9]+}", gradebookHandler.UpdateCourse).Methods("PUT")
+	courseRouter.HandleFunc("/{id:[0-9]+}", gradebookHandler.DeleteCourse).Methods("DELETE")
+	courseRouter.HandleFunc("/{id:[0-9]+}/gradebook", gradebookHandler.GetGradebook).Methods("GET")
+	courseRouter.HandleFunc("/{id:[0-9]+}/gradebook/me", gradebookHandler.GetMyGrade).Methods("GET")
+	courseRouter.HandleFunc("/{id:[0-9]+}/gradebook/export", gradebookHandler.ExportGradebook).Methods("GET")
+
+	// Answer routes (protected)
+	answerRouter := apiRouter.PathPrefix("/answers").Subrouter()
+	answerRouter.Use(authMiddleware.Authenticate)
+	answerRouter.HandleFunc("/{id:[0-9]+}/override", overrideHandler.OverrideAnswer).Methods("POST")
+
+	// Score override routes (protected)
+	overrideRouter := apiRouter.PathPrefix("/overrides").Subrouter()
+	overrideRouter.Use(authMiddleware.Authenticate)
+	overrideRouter.HandleFunc("/{id:[0-9]+}", overrideHandler.RevokeOverride).Methods("DELETE")
+
+	// Practice attempt routes (protected)
+	practiceRouter := apiRouter.PathPrefix("/practice").Subrouter()
+	practiceRouter.Use(authMiddleware.Authenticate)
+	practiceRouter.HandleFunc("/my", practiceHandler.GetMyAttempts).Methods("GET")
 
 	return router
 }
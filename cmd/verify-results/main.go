@@ -0,0 +1,87 @@
+// Command verify-results checks the tamper-evident result ledger: every hash-chain link,
+// entry hash and signature, and every stored result against its last recorded state.
+// It reports results that were created, modified or deleted outside the application.
+//
+// Usage:
+//
+//	verify-results [-seal-existing]
+//
+// -seal-existing first records and signs results that have no ledger entry yet, such as
+// results stored before the ledger was introduced. Only use it on a database you trust.
+// The database and signing key are configured with the same environment variables as the
+// server. The command exits with status 1 when any problem is found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"gocbt/internal/services"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+func main() {
+	sealExisting := flag.Bool("seal-existing", false, "record and sign results that have no ledger entry before verifying")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Connect to database
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Run migrations
+	if err := db.RunMigrations("migrations"); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Verifying with a newly generated key would fail every signature, so the key must exist
+	resultSigner, err := auth.LoadResultSigner(&cfg.Integrity, false)
+	if err != nil {
+		log.Fatalf("Failed to load result signing key: %v", err)
+	}
+
+	integrityService := services.NewIntegrityService(database.NewIntegrityRepository(db), resultSigner)
+
+	if *sealExisting {
+		sealed, err := integrityService.SealUnrecorded()
+		if err != nil {
+			log.Fatalf("Failed to seal existing results after %d: %v", sealed, err)
+		}
+		fmt.Printf("Sealed %d existing result(s)\n", sealed)
+	}
+
+	report, err := integrityService.VerifyAll()
+	if err != nil {
+		log.Fatalf("Failed to verify results: %v", err)
+	}
+
+	for _, issue := range report.Issues {
+		location := ""
+		if issue.Sequence != nil {
+			location += fmt.Sprintf(" entry %d", *issue.Sequence)
+		}
+		if issue.ResultID != nil {
+			location += fmt.Sprintf(" result %d", *issue.ResultID)
+		}
+		fmt.Printf("%s:%s: %s\n", issue.Problem, location, issue.Detail)
+	}
+
+	fmt.Printf("Checked %d ledger entries and %d results with key %s\n", report.Entries, report.Results, report.KeyID)
+	fmt.Printf("Ledger head: %s\n", report.HeadHash)
+	if !report.Valid {
+		fmt.Printf("Found %d problem(s)\n", len(report.Issues))
+		os.Exit(1)
+	}
+	fmt.Println("All results verified")
+}
@@ -0,0 +1,75 @@
+'use client';
+
+import React, { useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
+import { useRouter } from 'next/navigation';
+import { useAuth } from '@/contexts/AuthContext';
+import { Button } from '@/components/ui/Button';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/Card';
+import { BookOpen } from 'lucide-react';
+
+export default function AuthCallbackPage() {
+  const [error, setError] = useState('');
+  const { completeSSO } = useAuth();
+  const router = useRouter();
+  // The code can only be redeemed once, even if the effect runs twice
+  const started = useRef(false);
+
+  useEffect(() => {
+    if (started.current) {
+      return;
+    }
+    started.current = true;
+
+    const params = new URLSearchParams(window.location.search);
+    const code = params.get('code');
+    const state = params.get('state');
+
+    if (params.get('error')) {
+      setError(params.get('error_description') || 'Sign-in was cancelled at your identity provider.');
+      return;
+    }
+    if (!code || !state) {
+      setError('The sign-in response is incomplete. Please try again.');
+      return;
+    }
+
+    completeSSO(code, state)
+      // The sign-in page asks for the two-factor code if one is needed
+      .then((challenge) => router.replace(challenge ? '/login' : '/dashboard'))
+      .catch((err: any) => {
+        setError(err.response?.data?.message || err.message || 'Single sign-on failed. Please try again.');
+      });
+  }, [completeSSO, router]);
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 dark:bg-gray-900 py-12 px-4 sm:px-6 lg:px-8">
+      <div className="max-w-md w-full space-y-8">
+        <div className="flex justify-center">
+          <BookOpen className="h-12 w-12 text-blue-600" />
+        </div>
+
+        <Card>
+          <CardHeader>
+            <CardTitle>{error ? 'Sign-in failed' : 'Signing you in...'}</CardTitle>
+            <CardDescription>
+              {error ? 'Your school account could not be used to sign in' : 'Completing sign-in with your school account'}
+            </CardDescription>
+          </CardHeader>
+          {error && (
+            <CardContent className="space-y-6">
+              <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded">
+                {error}
+              </div>
+              <Link href="/login">
+                <Button variant="outline" className="w-full">
+                  Back to sign in
+                </Button>
+              </Link>
+            </CardContent>
+          )}
+        </Card>
+      </div>
+    </div>
+  );
+}
@@ -5,7 +5,7 @@ import { useAuth } from '@/contexts/AuthContext';
 import Layout from '@/components/Layout';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/Card';
 import { Button } from '@/components/ui/Button';
-import { testsApi, sessionsApi, resultsApi, TestResult, TestSession, Test } from '@/lib/api';
+import { authApi, testsApi, sessionsApi, resultsApi, TestResult, TestSession, Test } from '@/lib/api';
 import { 
   BookOpen, 
   Clock, 
@@ -32,11 +32,21 @@ export default function DashboardPage() {
   const [recentSessions, setRecentSessions] = useState<TestSession[]>([]);
   const [recentResults, setRecentResults] = useState<TestResult[]>([]);
   const [allTests, setAllTests] = useState<Test[]>([]);
+  const [verificationMessage, setVerificationMessage] = useState('');
 
   useEffect(() => {
     fetchDashboardData();
   }, [user]);
 
+  const sendVerificationEmail = async () => {
+    try {
+      const response = await authApi.sendEmailVerification();
+      setVerificationMessage(response.data.message);
+    } catch (err: any) {
+      setVerificationMessage(err.response?.data?.message || 'The verification email could not be sent.');
+    }
+  };
+
   const fetchDashboardData = async () => {
     try {
       if (user?.role === 'student') {
@@ -124,6 +134,23 @@ export default function DashboardPage() {
           </p>
         </div>
 
+        {/* Email verification */}
+        {user?.email_verified === false && (
+          <div className="flex items-center justify-between bg-yellow-50 border border-yellow-200 text-yellow-800 px-4 py-3 rounded">
+            <div className="flex items-center">
+              <AlertCircle className="h-5 w-5 mr-2" />
+              <span>
+                {verificationMessage || `Please confirm your email address ${user.email} with the link we sent you.`}
+              </span>
+            </div>
+            {!verificationMessage && (
+              <Button variant="outline" size="sm" onClick={sendVerificationEmail}>
+                Resend link
+              </Button>
+            )}
+          </div>
+        )}
+
         {/* Stats Cards */}
         <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-6">
           <Card>
@@ -4,20 +4,25 @@ import React, { useState } from 'react';
 import Link from 'next/link';
 import { useRouter } from 'next/navigation';
 import { useAuth } from '@/contexts/AuthContext';
+import { TwoFactorEnrolment } from '@/lib/api';
 import { Button } from '@/components/ui/Button';
 import { Input } from '@/components/ui/Input';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/Card';
 import { ThemeToggle } from '@/components/ThemeToggle';
-import { BookOpen, Eye, EyeOff } from 'lucide-react';
+import { BookOpen, Eye, EyeOff, ShieldCheck } from 'lucide-react';
 
 export default function LoginPage() {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [showPassword, setShowPassword] = useState(false);
   const [loading, setLoading] = useState(false);
+  const [ssoLoading, setSSOLoading] = useState(false);
   const [error, setError] = useState('');
+  const [code, setCode] = useState('');
+  const [enrolment, setEnrolment] = useState<TwoFactorEnrolment | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
   
-  const { login } = useAuth();
+  const { login, startSSO, twoFactorChallenge, completeTwoFactor, startTwoFactorEnrolment, cancelTwoFactor } = useAuth();
   const router = useRouter();
 
   const handleSubmit = async (e: React.FormEvent) => {
@@ -26,8 +31,10 @@ export default function LoginPage() {
     setError('');
 
     try {
-      await login(username, password);
-      router.push('/dashboard');
+      const challenge = await login(username, password);
+      if (!challenge) {
+        router.push('/dashboard');
+      }
     } catch (err: any) {
       setError(err.response?.data?.message || 'Login failed. Please try again.');
     } finally {
@@ -35,6 +42,60 @@ export default function LoginPage() {
     }
   };
 
+  const handleTwoFactorSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setLoading(true);
+    setError('');
+
+    try {
+      const codes = await completeTwoFactor(code);
+      setCode('');
+      setEnrolment(null);
+      if (codes && codes.length > 0) {
+        // Recovery codes of a new enrolment are only shown once
+        setRecoveryCodes(codes);
+      } else {
+        router.push('/dashboard');
+      }
+    } catch (err: any) {
+      setError(err.response?.data?.message || err.message || 'Verification failed. Please try again.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleStartEnrolment = async () => {
+    setLoading(true);
+    setError('');
+
+    try {
+      setEnrolment(await startTwoFactorEnrolment());
+    } catch (err: any) {
+      setError(err.response?.data?.message || err.message || 'Could not set up an authenticator. Please sign in again.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleCancelTwoFactor = () => {
+    cancelTwoFactor();
+    setCode('');
+    setEnrolment(null);
+    setError('');
+  };
+
+  const handleSSO = async () => {
+    setSSOLoading(true);
+    setError('');
+
+    try {
+      await startSSO();
+    } catch (err: any) {
+      setError(err.response?.data?.message || err.message || 'Single sign-on failed. Please try again.');
+      setSSOLoading(false);
+    }
+  };
+
   return (
     <div className="min-h-screen flex items-center justify-center bg-gray-50 dark:bg-gray-900 py-12 px-4 sm:px-6 lg:px-8">
       <div className="max-w-md w-full space-y-8">
@@ -54,6 +115,107 @@ export default function LoginPage() {
           </p>
         </div>
 
+        {recoveryCodes ? (
+        <Card>
+          <CardHeader>
+            <CardTitle>Save your recovery codes</CardTitle>
+            <CardDescription>
+              Each code signs you in once if you lose your authenticator. They will not be shown again.
+            </CardDescription>
+          </CardHeader>
+          <CardContent>
+            <div className="grid grid-cols-2 gap-2 font-mono text-sm bg-gray-50 dark:bg-gray-800 p-4 rounded">
+              {recoveryCodes.map((recoveryCode) => (
+                <span key={recoveryCode}>{recoveryCode}</span>
+              ))}
+            </div>
+            <Button className="w-full mt-6" onClick={() => router.push('/dashboard')}>
+              Continue
+            </Button>
+          </CardContent>
+        </Card>
+        ) : twoFactorChallenge ? (
+        <Card>
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <ShieldCheck className="h-5 w-5 text-blue-600" />
+              Two-factor authentication
+            </CardTitle>
+            <CardDescription>
+              {twoFactorChallenge.enrolment_required
+                ? 'Your role requires an authenticator app. Set one up to finish signing in.'
+                : 'Enter the code from your authenticator app, or one of your recovery codes'}
+            </CardDescription>
+          </CardHeader>
+          <CardContent>
+            {twoFactorChallenge.enrolment_required && !enrolment ? (
+              <div className="space-y-6">
+                {error && (
+                  <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded">
+                    {error}
+                  </div>
+                )}
+                <Button className="w-full" onClick={handleStartEnrolment} disabled={loading}>
+                  {loading ? 'Setting up...' : 'Set up authenticator'}
+                </Button>
+              </div>
+            ) : (
+              <form onSubmit={handleTwoFactorSubmit} className="space-y-6">
+                {error && (
+                  <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded">
+                    {error}
+                  </div>
+                )}
+
+                {enrolment && (
+                  <div className="text-sm text-gray-700 dark:text-gray-300 space-y-2">
+                    <p>
+                      Add this account to your authenticator app by{' '}
+                      <a href={enrolment.provisioning_uri} className="text-blue-600 underline">
+                        opening this link
+                      </a>{' '}
+                      or entering the key below, then enter the code it shows.
+                    </p>
+                    <p className="font-mono break-all bg-gray-50 dark:bg-gray-800 p-2 rounded">
+                      {enrolment.secret}
+                    </p>
+                  </div>
+                )}
+
+                <div>
+                  <label htmlFor="code" className="block text-sm font-medium text-gray-700">
+                    Verification code
+                  </label>
+                  <Input
+                    id="code"
+                    type="text"
+                    inputMode="text"
+                    autoComplete="one-time-code"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    required
+                    className="mt-1"
+                    placeholder="123456"
+                  />
+                </div>
+
+                <Button type="submit" className="w-full" disabled={loading}>
+                  {loading ? 'Verifying...' : 'Verify'}
+                </Button>
+              </form>
+            )}
+
+            <Button
+              type="button"
+              variant="outline"
+              className="w-full mt-4"
+              onClick={handleCancelTwoFactor}
+            >
+              Back to sign in
+            </Button>
+          </CardContent>
+        </Card>
+        ) : (
         <Card>
           <CardHeader>
             <CardTitle>Welcome back</CardTitle>
@@ -109,6 +271,11 @@ export default function LoginPage() {
                     )}
                   </button>
                 </div>
+                <div className="mt-2 text-right">
+                  <Link href="/forgot-password" className="text-sm text-blue-600 hover:underline">
+                    Forgot your password?
+                  </Link>
+                </div>
               </div>
 
               <Button
@@ -120,6 +287,16 @@ export default function LoginPage() {
               </Button>
             </form>
 
+            <Button
+              type="button"
+              variant="outline"
+              className="w-full mt-4"
+              onClick={handleSSO}
+              disabled={ssoLoading}
+            >
+              {ssoLoading ? 'Redirecting...' : 'Sign in with school account'}
+            </Button>
+
             <div className="mt-6">
               <div className="relative">
                 <div className="absolute inset-0 flex items-center">
@@ -140,6 +317,7 @@ export default function LoginPage() {
             </div>
           </CardContent>
         </Card>
+        )}
 
         <div className="text-center">
           <p className="text-xs text-gray-500">
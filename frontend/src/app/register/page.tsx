@@ -47,8 +47,9 @@ export default function RegisterPage() {
 
     try {
       const { confirmPassword, ...registerData } = formData;
-      await register(registerData);
-      router.push('/dashboard');
+      const challenge = await register(registerData);
+      // The sign-in page asks for the two-factor code if one is needed
+      router.push(challenge ? '/login' : '/dashboard');
     } catch (err: any) {
       setError(err.response?.data?.message || 'Registration failed. Please try again.');
     } finally {
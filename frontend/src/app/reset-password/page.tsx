@@ -0,0 +1,137 @@
+'use client';
+
+import React, { useEffect, useState } from 'react';
+import Link from 'next/link';
+import { authApi } from '@/lib/api';
+import { Button } from '@/components/ui/Button';
+import { Input } from '@/components/ui/Input';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/Card';
+import { BookOpen, Eye, EyeOff } from 'lucide-react';
+
+export default function ResetPasswordPage() {
+  const [token, setToken] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [showPassword, setShowPassword] = useState(false);
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+  const [done, setDone] = useState(false);
+
+  useEffect(() => {
+    const linkToken = new URLSearchParams(window.location.search).get('token');
+    if (linkToken) {
+      setToken(linkToken);
+    } else {
+      setError('This reset link is incomplete. Please request a new one.');
+    }
+  }, []);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+
+    if (password !== confirmPassword) {
+      setError('Passwords do not match');
+      return;
+    }
+
+    setLoading(true);
+    try {
+      await authApi.resetPassword(token, password);
+      setDone(true);
+    } catch (err: any) {
+      setError(err.response?.data?.message || 'Your password could not be reset. Please try again.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 dark:bg-gray-900 py-12 px-4 sm:px-6 lg:px-8">
+      <div className="max-w-md w-full space-y-8">
+        <div className="flex justify-center">
+          <BookOpen className="h-12 w-12 text-blue-600" />
+        </div>
+
+        <Card>
+          <CardHeader>
+            <CardTitle>{done ? 'Password changed' : 'Choose a new password'}</CardTitle>
+            <CardDescription>
+              {done
+                ? 'You have been signed out of all devices. Sign in with your new password.'
+                : 'Use at least 8 characters with upper and lower case letters, a digit and a symbol'}
+            </CardDescription>
+          </CardHeader>
+          <CardContent className="space-y-6">
+            {!done && (
+              <form className="space-y-6" onSubmit={handleSubmit}>
+                {error && (
+                  <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded">
+                    {error}{' '}
+                    <Link href="/forgot-password" className="underline">
+                      Request a new link
+                    </Link>
+                  </div>
+                )}
+
+                <div>
+                  <label htmlFor="password" className="block text-sm font-medium text-gray-700">
+                    New password
+                  </label>
+                  <div className="mt-1 relative">
+                    <Input
+                      id="password"
+                      type={showPassword ? 'text' : 'password'}
+                      value={password}
+                      onChange={(e) => setPassword(e.target.value)}
+                      required
+                      autoComplete="new-password"
+                      placeholder="Enter a new password"
+                    />
+                    <button
+                      type="button"
+                      className="absolute inset-y-0 right-0 pr-3 flex items-center"
+                      onClick={() => setShowPassword(!showPassword)}
+                    >
+                      {showPassword ? (
+                        <EyeOff className="h-4 w-4 text-gray-400" />
+                      ) : (
+                        <Eye className="h-4 w-4 text-gray-400" />
+                      )}
+                    </button>
+                  </div>
+                </div>
+
+                <div>
+                  <label htmlFor="confirmPassword" className="block text-sm font-medium text-gray-700">
+                    Confirm new password
+                  </label>
+                  <Input
+                    id="confirmPassword"
+                    type={showPassword ? 'text' : 'password'}
+                    value={confirmPassword}
+                    onChange={(e) => setConfirmPassword(e.target.value)}
+                    required
+                    autoComplete="new-password"
+                    className="mt-1"
+                    placeholder="Enter the new password again"
+                  />
+                </div>
+
+                <Button type="submit" className="w-full" disabled={loading || !token}>
+                  {loading ? 'Saving...' : 'Set new password'}
+                </Button>
+              </form>
+            )}
+
+            <Link href="/login">
+              <Button variant={done ? 'default' : 'outline'} className="w-full">
+                {done ? 'Sign in' : 'Back to sign in'}
+              </Button>
+            </Link>
+          </CardContent>
+        </Card>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,74 @@
+'use client';
+
+import React, { useEffect, useRef, useState } from 'react';
+import Link from 'next/link';
+import { authApi } from '@/lib/api';
+import { Button } from '@/components/ui/Button';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/Card';
+import { BookOpen } from 'lucide-react';
+
+export default function VerifyEmailPage() {
+  const [error, setError] = useState('');
+  const [email, setEmail] = useState('');
+  // The link can only be used once, even if the effect runs twice
+  const started = useRef(false);
+
+  useEffect(() => {
+    if (started.current) {
+      return;
+    }
+    started.current = true;
+
+    const token = new URLSearchParams(window.location.search).get('token');
+    if (!token) {
+      setError('This verification link is incomplete.');
+      return;
+    }
+
+    authApi
+      .verifyEmail(token)
+      .then((response) => setEmail(response.data.email))
+      .catch((err: any) => {
+        setError(err.response?.data?.message || 'Your email address could not be confirmed. Please try again.');
+      });
+  }, []);
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gray-50 dark:bg-gray-900 py-12 px-4 sm:px-6 lg:px-8">
+      <div className="max-w-md w-full space-y-8">
+        <div className="flex justify-center">
+          <BookOpen className="h-12 w-12 text-blue-600" />
+        </div>
+
+        <Card>
+          <CardHeader>
+            <CardTitle>
+              {error ? 'Verification failed' : email ? 'Email address confirmed' : 'Confirming your email address...'}
+            </CardTitle>
+            <CardDescription>
+              {error
+                ? 'Sign in and ask for a new verification link from your dashboard'
+                : email
+                  ? `${email} is now confirmed for your account`
+                  : 'Checking your verification link'}
+            </CardDescription>
+          </CardHeader>
+          {(error || email) && (
+            <CardContent className="space-y-6">
+              {error && (
+                <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded">
+                  {error}
+                </div>
+              )}
+              <Link href="/dashboard">
+                <Button variant="outline" className="w-full">
+                  Continue to GoCBT
+                </Button>
+              </Link>
+            </CardContent>
+          )}
+        </Card>
+      </div>
+    </div>
+  );
+}
@@ -1,12 +1,14 @@
 'use client';
 
 import React, { createContext, useContext, useEffect, useState } from 'react';
-import { User, authApi } from '@/lib/api';
+import { AuthSession, LoginResponse, TwoFactorChallenge, TwoFactorEnrolment, User, authApi } from '@/lib/api';
 
 interface AuthContextType {
   user: User | null;
   token: string | null;
-  login: (username: string, password: string) => Promise<void>;
+  // Set while a login waits for its two-factor code
+  twoFactorChallenge: TwoFactorChallenge | null;
+  login: (username: string, password: string) => Promise<TwoFactorChallenge | null>;
   register: (data: {
     username: string;
     email: string;
@@ -14,7 +16,12 @@ interface AuthContextType {
     first_name: string;
     last_name: string;
     role: string;
-  }) => Promise<void>;
+  }) => Promise<TwoFactorChallenge | null>;
+  startSSO: () => Promise<void>;
+  completeSSO: (code: string, state: string) => Promise<TwoFactorChallenge | null>;
+  completeTwoFactor: (code: string) => Promise<string[] | undefined>;
+  startTwoFactorEnrolment: () => Promise<TwoFactorEnrolment>;
+  cancelTwoFactor: () => void;
   logout: () => void;
   loading: boolean;
   isAuthenticated: boolean;
@@ -25,6 +32,7 @@ const AuthContext = createContext<AuthContextType | undefined>(undefined);
 export function AuthProvider({ children }: { children: React.ReactNode }) {
   const [user, setUser] = useState<User | null>(null);
   const [token, setToken] = useState<string | null>(null);
+  const [twoFactorChallenge, setTwoFactorChallenge] = useState<TwoFactorChallenge | null>(null);
   const [loading, setLoading] = useState(true);
 
   useEffect(() => {
@@ -36,9 +44,54 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       setToken(savedToken);
       setUser(JSON.parse(savedUser));
     }
+
+    // A login started on another page, such as the single sign-on callback, may be
+    // waiting for its two-factor code
+    const savedChallenge = sessionStorage.getItem('two_factor_challenge');
+    if (savedChallenge) {
+      const challenge: TwoFactorChallenge = JSON.parse(savedChallenge);
+      if (new Date(challenge.expires_at) > new Date()) {
+        setTwoFactorChallenge(challenge);
+      } else {
+        sessionStorage.removeItem('two_factor_challenge');
+      }
+    }
     setLoading(false);
   }, []);
 
+  const saveSession = (session: AuthSession) => {
+    const { token: newToken, refresh_token: refreshToken, user: newUser } = session;
+
+    // Validate token format (basic JWT structure check)
+    if (!newToken || newToken.split('.').length !== 3) {
+      throw new Error('Invalid authentication token');
+    }
+
+    setToken(newToken);
+    setUser(newUser);
+    localStorage.setItem('token', newToken);
+    localStorage.setItem('refresh_token', refreshToken);
+    localStorage.setItem('user', JSON.stringify(newUser));
+  };
+
+  // handleLoginResponse signs in with the tokens of a login, or keeps its two-factor
+  // challenge until the code is entered
+  const handleLoginResponse = (response: LoginResponse): TwoFactorChallenge | null => {
+    if ('two_factor_required' in response) {
+      setTwoFactorChallenge(response);
+      sessionStorage.setItem('two_factor_challenge', JSON.stringify(response));
+      return response;
+    }
+
+    saveSession(response);
+    return null;
+  };
+
+  const clearTwoFactorChallenge = () => {
+    setTwoFactorChallenge(null);
+    sessionStorage.removeItem('two_factor_challenge');
+  };
+
   const login = async (username: string, password: string) => {
     try {
       // Basic client-side validation
@@ -52,17 +105,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       }
 
       const response = await authApi.login(username.trim(), password);
-      const { token: newToken, user: newUser } = response.data;
-
-      // Validate token format (basic JWT structure check)
-      if (!newToken || newToken.split('.').length !== 3) {
-        throw new Error('Invalid authentication token');
-      }
-
-      setToken(newToken);
-      setUser(newUser);
-      localStorage.setItem('token', newToken);
-      localStorage.setItem('user', JSON.stringify(newUser));
+      return handleLoginResponse(response.data);
     } catch (error) {
       throw error;
     }
@@ -103,34 +146,82 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       }
 
       const response = await authApi.register(sanitizedData);
-      const { token: newToken, user: newUser } = response.data;
-
-      // Validate token format
-      if (!newToken || newToken.split('.').length !== 3) {
-        throw new Error('Invalid authentication token');
-      }
-
-      setToken(newToken);
-      setUser(newUser);
-      localStorage.setItem('token', newToken);
-      localStorage.setItem('user', JSON.stringify(newUser));
+      return handleLoginResponse(response.data);
     } catch (error) {
       throw error;
     }
   };
 
+  const startSSO = async () => {
+    const response = await authApi.startSSO();
+    const authorizationUrl = response.data.authorization_url;
+
+    // Remember the state so the callback only accepts the sign-in this browser started
+    const state = new URL(authorizationUrl).searchParams.get('state');
+    if (!state) {
+      throw new Error('Invalid single sign-on response');
+    }
+    sessionStorage.setItem('sso_state', state);
+
+    window.location.assign(authorizationUrl);
+  };
+
+  const completeSSO = async (code: string, state: string) => {
+    const expectedState = sessionStorage.getItem('sso_state');
+    sessionStorage.removeItem('sso_state');
+    if (!expectedState || expectedState !== state) {
+      throw new Error('Single sign-on was not started from this browser. Please try again.');
+    }
+
+    const response = await authApi.completeSSO(code, state);
+    return handleLoginResponse(response.data);
+  };
+
+  const completeTwoFactor = async (code: string) => {
+    if (!twoFactorChallenge) {
+      throw new Error('Your sign-in has expired. Please sign in again.');
+    }
+
+    const response = await authApi.completeTwoFactor(twoFactorChallenge.challenge_token, code.trim());
+    saveSession(response.data);
+    clearTwoFactorChallenge();
+    return response.data.recovery_codes;
+  };
+
+  const startTwoFactorEnrolment = async () => {
+    if (!twoFactorChallenge) {
+      throw new Error('Your sign-in has expired. Please sign in again.');
+    }
+
+    const response = await authApi.startTwoFactorEnrolment(twoFactorChallenge.challenge_token);
+    return response.data;
+  };
+
   const logout = () => {
+    // Revoke the tokens on the server; sign out locally even if that fails
+    const refreshToken = localStorage.getItem('refresh_token') || undefined;
+    if (localStorage.getItem('token')) {
+      authApi.logout(refreshToken).catch(() => {});
+    }
+
     setUser(null);
     setToken(null);
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
   };
 
   const value = {
     user,
     token,
+    twoFactorChallenge,
     login,
     register,
+    startSSO,
+    completeSSO,
+    completeTwoFactor,
+    startTwoFactorEnrolment,
+    cancelTwoFactor: clearTwoFactorChallenge,
     logout,
     loading,
     isAuthenticated: !!user && !!token,
@@ -1,4 +1,4 @@
-import axios from 'axios';
+import axios, { InternalAxiosRequestConfig } from 'axios';
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8081/api/v1';
 
@@ -14,6 +14,27 @@ export const api = axios.create({
   maxRedirects: 0, // Prevent redirect attacks
 });
 
+// Refresh tokens are rotated, so concurrent requests share a single refresh
+let refreshing: Promise<string> | null = null;
+
+const refreshAccessToken = (refreshToken: string): Promise<string> => {
+  if (!refreshing) {
+    refreshing = axios
+      .post<AuthTokens>(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken }, {
+        headers: { 'Content-Type': 'application/json' },
+      })
+      .then((response) => {
+        localStorage.setItem('token', response.data.token);
+        localStorage.setItem('refresh_token', response.data.refresh_token);
+        return response.data.token;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
 // Request interceptor to add auth token and security headers
 api.interceptors.request.use(
   (config) => {
@@ -48,10 +69,26 @@ api.interceptors.response.use(
     }
     return response;
   },
-  (error) => {
+  async (error) => {
+    // Renew an expired access token once with the refresh token, then retry the request
+    const original = error.config as (InternalAxiosRequestConfig & { _retry?: boolean }) | undefined;
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (error.response?.status === 401 && refreshToken && original && !original._retry &&
+        !original.url?.startsWith('/auth/')) {
+      original._retry = true;
+      try {
+        const token = await refreshAccessToken(refreshToken);
+        original.headers.Authorization = `Bearer ${token}`;
+        return api(original);
+      } catch {
+        // Fall through to sign the user out
+      }
+    }
+
     // Handle authentication errors
     if (error.response?.status === 401) {
       localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
       localStorage.removeItem('user');
       window.location.href = '/login';
       return Promise.reject(new Error('Authentication required'));
@@ -86,6 +123,7 @@ export interface User {
   last_name: string;
   role: 'student' | 'teacher' | 'admin';
   is_active: boolean;
+  email_verified?: boolean;
   created_at: string;
   updated_at: string;
 }
@@ -199,10 +237,44 @@ export interface ApiResponse<T> {
   message?: string;
 }
 
+export interface AuthTokens {
+  token: string;
+  expires_at: string;
+  refresh_token: string;
+  refresh_expires_at: string;
+}
+
+export interface AuthSession extends AuthTokens {
+  user: User;
+  // Only sent when the login completed two-factor enrolment
+  recovery_codes?: string[];
+}
+
+// Returned instead of tokens when a login needs a two-factor code
+export interface TwoFactorChallenge {
+  two_factor_required: true;
+  challenge_token: string;
+  expires_at: string;
+  enrolment_required: boolean;
+}
+
+export interface TwoFactorEnrolment {
+  secret: string;
+  provisioning_uri: string;
+}
+
+export type LoginResponse = AuthSession | TwoFactorChallenge;
+
 // Auth API
 export const authApi = {
   login: (username: string, password: string) =>
-    api.post<{ token: string; user: User }>('/auth/login', { username, password }),
+    api.post<LoginResponse>('/auth/login', { username, password }),
+
+  completeTwoFactor: (challengeToken: string, code: string) =>
+    api.post<AuthSession>('/auth/login/2fa', { challenge_token: challengeToken, code }),
+
+  startTwoFactorEnrolment: (challengeToken: string) =>
+    api.post<TwoFactorEnrolment>('/auth/login/2fa/enrol', { challenge_token: challengeToken }),
   
   register: (data: {
     username: string;
@@ -211,11 +283,33 @@ export const authApi = {
     first_name: string;
     last_name: string;
     role: string;
-  }) => api.post<{ token: string; user: User }>('/auth/register', data),
+  }) => api.post<LoginResponse>('/auth/register', data),
   
   getProfile: () => api.get<User>('/auth/profile'),
   
-  refreshToken: () => api.post<{ token: string }>('/auth/refresh'),
+  refreshToken: (refreshToken: string) =>
+    api.post<AuthTokens>('/auth/refresh', { refresh_token: refreshToken }),
+
+  logout: (refreshToken?: string) =>
+    api.post('/auth/logout', refreshToken ? { refresh_token: refreshToken } : {}),
+
+  logoutAll: () => api.post('/auth/logout-all'),
+
+  startSSO: () => api.post<{ authorization_url: string }>('/auth/oidc/start', {}),
+
+  completeSSO: (code: string, state: string) =>
+    api.post<LoginResponse>('/auth/oidc/callback', { code, state }),
+
+  forgotPassword: (email: string) =>
+    api.post<{ success: boolean; message: string }>('/auth/password/forgot', { email }),
+
+  resetPassword: (token: string, password: string) =>
+    api.post<{ success: boolean; message: string }>('/auth/password/reset', { token, password }),
+
+  verifyEmail: (token: string) => api.post<User>('/auth/verify-email', { token }),
+
+  sendEmailVerification: () =>
+    api.post<{ success: boolean; message: string }>('/auth/verify-email/send'),
 };
 
 // Tests API
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// AccessHandler handles test access gate requests
+type AccessHandler struct {
+	accessService models.TestAccessService
+	testService   models.TestService
+	policy        *auth.Policy
+}
+
+// NewAccessHandler creates a new access handler
+func NewAccessHandler(accessService models.TestAccessService, testService models.TestService, policy *auth.Policy) *AccessHandler {
+	return &AccessHandler{
+		accessService: accessService,
+		testService:   testService,
+		policy:        policy,
+	}
+}
+
+// UpdateAccessPolicyRequest represents an access policy update request
+type UpdateAccessPolicyRequest struct {
+	AccessCode          *string  `json:"access_code"`
+	CodeRotationMinutes int      `json:"code_rotation_minutes"`
+	AllowedCIDRs        []string `json:"allowed_cidrs"`
+}
+
+// GetPolicy handles getting the access policy for a test
+func (h *AccessHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can view access policies
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	policy, err := h.accessService.GetPolicy(testID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Access policy not found", http.StatusNotFound)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, policy)
+}
+
+// UpdatePolicy handles creating or replacing the access policy for a test
+func (h *AccessHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change access policies
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req UpdateAccessPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.AccessCode != nil && !utils.ValidateTextLength(*req.AccessCode, 0, 100) {
+		utils.WriteErrorResponse(w, "Access code must be 0-100 characters", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.accessService.UpdatePolicy(testID, req.AccessCode, req.CodeRotationMinutes, req.AllowedCIDRs)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidCIDR, auth.ErrAccessCodeRequired:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		case auth.ErrInvalidCredentials:
+			utils.WriteErrorResponse(w, "Code rotation must be between 0 and 1440 minutes", http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update access policy", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, policy)
+}
+
+// DeletePolicy handles removing all access gates from a test
+func (h *AccessHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change access policies
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	if err := h.accessService.DeletePolicy(testID); err != nil {
+		utils.WriteErrorResponse(w, "Failed to delete access policy", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// GetCurrentCode handles getting the access code a proctor should announce
+func (h *AccessHandler) GetCurrentCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors and those who may follow its sessions can see access codes
+	if !canAccessTest(r, h.policy, h.testService, testID, 0, auth.ActionEditTest) &&
+		!authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewSession) {
+		return
+	}
+
+	code, err := h.accessService.GetCurrentAccessCode(testID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Test has no access code", http.StatusNotFound)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, code)
+}
+
+// GetFailures handles getting audited access gate failures for a test
+func (h *AccessHandler) GetFailures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors and those who may follow its sessions can view the access audit log
+	if !canAccessTest(r, h.policy, h.testService, testID, 0, auth.ActionEditTest) &&
+		!authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewSession) {
+		return
+	}
+
+	// Get pagination parameters
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	failures, err := h.accessService.GetFailures(testID, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to get access failures", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, failures)
+}
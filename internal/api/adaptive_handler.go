@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// AdaptiveHandler handles adaptive test settings requests
+type AdaptiveHandler struct {
+	adaptiveService models.AdaptiveService
+	testService     models.TestService
+	policy          *auth.Policy
+}
+
+// NewAdaptiveHandler creates a new adaptive test settings handler
+func NewAdaptiveHandler(adaptiveService models.AdaptiveService, testService models.TestService, policy *auth.Policy) *AdaptiveHandler {
+	return &AdaptiveHandler{
+		adaptiveService: adaptiveService,
+		testService:     testService,
+		policy:          policy,
+	}
+}
+
+// UpdateAdaptiveSettingsRequest represents an adaptive settings update request
+type UpdateAdaptiveSettingsRequest struct {
+	MinQuestions int     `json:"min_questions"`
+	MaxQuestions int     `json:"max_questions"`
+	TargetSE     float64 `json:"target_se"`
+	PassingTheta float64 `json:"passing_theta"`
+}
+
+// GetSettings handles getting the adaptive settings for a test
+func (h *AdaptiveHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can view adaptive settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	settings, err := h.adaptiveService.GetSettings(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get adaptive settings", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
+
+// UpdateSettings handles creating or replacing the adaptive settings for a test
+func (h *AdaptiveHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change adaptive settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req UpdateAdaptiveSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.adaptiveService.UpdateSettings(testID, req.MinQuestions, req.MaxQuestions, req.TargetSE, req.PassingTheta)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidAdaptiveSettings:
+			utils.WriteErrorResponse(w, "Adaptive settings need 1 <= min_questions <= max_questions <= "+strconv.Itoa(models.MaxAdaptiveQuestions)+", 0 < target_se < 2 and |passing_theta| <= "+strconv.FormatFloat(models.MaxAdaptivePassingTheta, 'f', -1, 64), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update adaptive settings", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
+
+// DeleteSettings handles resetting a test to the default adaptive settings
+func (h *AdaptiveHandler) DeleteSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change adaptive settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	if err := h.adaptiveService.DeleteSettings(testID); err != nil {
+		utils.WriteErrorResponse(w, "Failed to delete adaptive settings", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// AnalysisHandler handles test analysis requests
+type AnalysisHandler struct {
+	analysisService models.AnalysisService
+	testService     models.TestService
+	policy          *auth.Policy
+}
+
+// NewAnalysisHandler creates a new test analysis handler
+func NewAnalysisHandler(analysisService models.AnalysisService, testService models.TestService, policy *auth.Policy) *AnalysisHandler {
+	return &AnalysisHandler{
+		analysisService: analysisService,
+		testService:     testService,
+		policy:          policy,
+	}
+}
+
+// GetItemAnalysis handles getting the per-question analysis of a test
+func (h *AnalysisHandler) GetItemAnalysis(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who view the test's results can view item analysis
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
+		return
+	}
+
+	analysis, err := h.analysisService.GetItemAnalysis(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get item analysis", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, analysis)
+}
+
+// GetReliabilityReport handles getting the score distribution and reliability of a test
+func (h *AnalysisHandler) GetReliabilityReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who view the test's results can view reliability reports
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
+		return
+	}
+
+	// Get the number of histogram bins
+	bins := models.DefaultHistogramBins
+	if b := r.URL.Query().Get("bins"); b != "" {
+		parsed, err := strconv.Atoi(b)
+		if err != nil || parsed < 1 || parsed > models.MaxHistogramBins {
+			utils.WriteErrorResponse(w, "Bins must be between 1 and "+strconv.Itoa(models.MaxHistogramBins), http.StatusBadRequest)
+			return
+		}
+		bins = parsed
+	}
+
+	report, err := h.analysisService.GetReliabilityReport(testID, bins)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get reliability report", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, report)
+}
@@ -5,20 +5,26 @@ import (
 	"gocbt/internal/auth"
 	"gocbt/internal/models"
 	"gocbt/internal/utils"
+	"io"
 	"net/http"
 )
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	userService models.UserService
-	jwtManager  *auth.JWTManager
+	userService      models.UserService
+	tokenService     models.TokenService
+	twoFactorService models.TwoFactorService
+	accountService   models.AccountService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(userService models.UserService, jwtManager *auth.JWTManager) *AuthHandler {
+func NewAuthHandler(userService models.UserService, tokenService models.TokenService, twoFactorService models.TwoFactorService,
+	accountService models.AccountService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtManager:  jwtManager,
+		userService:      userService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		accountService:   accountService,
 	}
 }
 
@@ -38,10 +44,50 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// AuthResponse represents an authentication response
+// RefreshTokenRequest represents a token refresh or logout request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TwoFactorLoginRequest represents the second step of a login
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorChallengeRequest represents a request made with a login challenge
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// ForgotPasswordRequest represents a request for a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents setting a new password with a password reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest represents confirming an email address with a verification token
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// AuthResponse represents an authentication response. Recovery codes are only sent when
+// the login completed two-factor enrolment.
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	*models.TokenPair
+	User          *models.User `json:"user"`
+	RecoveryCodes []string     `json:"recovery_codes,omitempty"`
+}
+
+// MessageResponse represents a response carrying only a message
+type MessageResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
 }
 
 // ErrorResponse represents an error response
@@ -123,21 +169,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.jwtManager.GenerateToken(user)
-	if err != nil {
-		writeErrorResponse(w, "Token generation failed", http.StatusInternalServerError)
-		return
-	}
-
-	response := AuthResponse{
-		Token: token,
-		User:  user,
-	}
+	// Users without the email can ask for another one from their profile
+	h.accountService.SendEmailVerification(user.ID)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	writeLoginResponse(w, h.tokenService, h.twoFactorService, user, []string{models.AuthMethodPassword}, http.StatusCreated)
 }
 
 // Login handles user login
@@ -168,35 +203,189 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	clientIP := utils.ClientIP(r)
+	if err := h.accountService.CheckLogin(req.Username, clientIP); err != nil {
+		switch err {
+		case auth.ErrTooManyLoginAttempts:
+			writeErrorResponse(w, err.Error(), http.StatusTooManyRequests)
+		default:
+			writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
 	user, err := h.userService.Login(req.Username, req.Password)
 	if err != nil {
+		// Wrong usernames and passwords count towards throttling
+		if err == auth.ErrInvalidCredentials {
+			if err := h.accountService.RecordLoginFailure(req.Username, clientIP); err != nil {
+				writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+				return
+			}
+		}
+
 		switch err {
 		case auth.ErrInvalidCredentials:
 			writeErrorResponse(w, "Invalid username or password", http.StatusUnauthorized)
 		case auth.ErrUserNotActive:
 			writeErrorResponse(w, "Account is not active", http.StatusForbidden)
+		case auth.ErrDirectoryAccountConflict, auth.ErrEmailExists:
+			writeErrorResponse(w, err.Error(), http.StatusConflict)
+		case auth.ErrDirectoryAccountNotFound:
+			writeErrorResponse(w, err.Error(), http.StatusForbidden)
+		case auth.ErrDirectoryUnavailable:
+			writeErrorResponse(w, "Directory server is unavailable; try again later", http.StatusServiceUnavailable)
+		default:
+			writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	amr := []string{models.AuthMethodPassword}
+	challenge, err := h.twoFactorService.BeginLogin(user, amr)
+	if err != nil {
+		writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		return
+	}
+
+	// Failed sign-ins are only forgotten once the second step succeeds too
+	if challenge != nil {
+		writeJSONResponse(w, challenge, http.StatusOK)
+		return
+	}
+
+	if err := h.accountService.RecordLoginSuccess(req.Username); err != nil {
+		writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		return
+	}
+
+	writeTokenResponse(w, h.tokenService, user, amr, http.StatusOK)
+}
+
+// LoginTwoFactor handles the second step of a login: a TOTP code or a recovery code
+// completes the login challenge and tokens are issued
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if utils.IsEmpty(req.ChallengeToken) || utils.IsEmpty(req.Code) {
+		writeErrorResponse(w, "Challenge token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	challengeUser, err := h.twoFactorService.GetChallengeUser(req.ChallengeToken)
+	if err != nil {
+		switch err {
+		case auth.ErrInvalidTwoFactorChallenge, auth.ErrUserNotFound:
+			writeErrorResponse(w, auth.ErrInvalidTwoFactorChallenge.Error(), http.StatusUnauthorized)
+		default:
+			writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Wrong codes count towards the same throttling as wrong passwords
+	clientIP := utils.ClientIP(r)
+	if err := h.accountService.CheckLogin(challengeUser.Username, clientIP); err != nil {
+		switch err {
+		case auth.ErrTooManyLoginAttempts:
+			writeErrorResponse(w, err.Error(), http.StatusTooManyRequests)
+		default:
+			writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	result, err := h.twoFactorService.CompleteLogin(req.ChallengeToken, req.Code)
+	if err != nil {
+		if err == auth.ErrInvalidTwoFactorCode {
+			if err := h.accountService.RecordLoginFailure(challengeUser.Username, clientIP); err != nil {
+				writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		switch err {
+		case auth.ErrInvalidTwoFactorChallenge, auth.ErrInvalidTwoFactorCode:
+			writeErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		case auth.ErrUserNotFound:
+			writeErrorResponse(w, auth.ErrInvalidTwoFactorChallenge.Error(), http.StatusUnauthorized)
+		case auth.ErrTwoFactorNotEnrolled:
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		case auth.ErrUserNotActive:
+			writeErrorResponse(w, "Account is not active", http.StatusForbidden)
 		default:
 			writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
 		}
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.jwtManager.GenerateToken(user)
+	if err := h.accountService.RecordLoginSuccess(result.User.Username); err != nil {
+		writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		return
+	}
+
+	// Issue an access token and a refresh token
+	tokens, err := h.tokenService.IssueTokens(result.User, result.AMR)
 	if err != nil {
 		writeErrorResponse(w, "Token generation failed", http.StatusInternalServerError)
 		return
 	}
 
 	response := AuthResponse{
-		Token: token,
-		User:  user,
+		TokenPair:     tokens,
+		User:          result.User,
+		RecoveryCodes: result.RecoveryCodes,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// LoginTwoFactorEnrol handles setting up an authenticator during a login, for a user whose
+// role requires two-factor authentication. A code from it then completes the login.
+func (h *AuthHandler) LoginTwoFactorEnrol(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TwoFactorChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if utils.IsEmpty(req.ChallengeToken) {
+		writeErrorResponse(w, "Challenge token is required", http.StatusBadRequest)
+		return
+	}
+
+	enrolment, err := h.twoFactorService.StartLoginEnrolment(req.ChallengeToken)
+	if err != nil {
+		switch err {
+		case auth.ErrInvalidTwoFactorChallenge, auth.ErrUserNotFound:
+			writeErrorResponse(w, auth.ErrInvalidTwoFactorChallenge.Error(), http.StatusUnauthorized)
+		case auth.ErrTwoFactorAlreadyEnabled:
+			writeErrorResponse(w, err.Error(), http.StatusConflict)
+		default:
+			writeErrorResponse(w, "Failed to start two-factor enrolment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrolment)
+}
+
 // Profile handles getting user profile
 func (h *AuthHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -225,33 +414,267 @@ func (h *AuthHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-// RefreshToken handles token refresh
+// RefreshToken handles exchanging a refresh token for new tokens. The refresh token is
+// rotated: the one presented stops working, and presenting it again signs out the login.
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get current token from Authorization header
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || len(authHeader) < 7 {
-		writeErrorResponse(w, "Authorization header required", http.StatusUnauthorized)
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	tokenString := authHeader[7:] // Remove "Bearer " prefix
+	if utils.IsEmpty(req.RefreshToken) {
+		writeErrorResponse(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
 
-	newToken, err := h.jwtManager.RefreshToken(tokenString)
+	tokens, err := h.tokenService.Refresh(req.RefreshToken)
 	if err != nil {
-		writeErrorResponse(w, "Token refresh failed", http.StatusUnauthorized)
+		switch err {
+		case auth.ErrInvalidRefreshToken, auth.ErrRefreshTokenReused:
+			writeErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		case auth.ErrTwoFactorRequired:
+			writeErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		case auth.ErrUserNotActive:
+			writeErrorResponse(w, "Account is not active", http.StatusForbidden)
+		default:
+			writeErrorResponse(w, "Token refresh failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout handles signing out of the current device: the access token is revoked and, if
+// the refresh token is sent, so is its login
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	response := map[string]string{
-		"token": newToken,
+	claims, ok := auth.GetUserFromContext(r)
+	if !ok {
+		writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The body is optional
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tokenService.Logout(claims.UserID, claims.ID, claims.ExpiresAt.Time, req.RefreshToken); err != nil {
+		writeErrorResponse(w, "Logout failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MessageResponse{Success: true, Message: "Logged out successfully"})
+}
+
+// LogoutAll handles signing out of every device by revoking all of the user's tokens
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := auth.GetUserFromContext(r)
+	if !ok {
+		writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.tokenService.LogoutAll(claims.UserID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		writeErrorResponse(w, "Logout failed", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MessageResponse{Success: true, Message: "Logged out of all devices"})
+}
+
+// ForgotPassword handles requests for a password reset link. The response is the same
+// whether or not an account uses the address.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Email = utils.SanitizeString(req.Email)
+	if utils.IsEmpty(req.Email) || !utils.ValidateEmail(req.Email) {
+		writeErrorResponse(w, "A valid email address is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(req.Email); err != nil {
+		writeErrorResponse(w, "Password reset failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MessageResponse{
+		Success: true,
+		Message: "If an account uses this address, a password reset link has been sent to it",
+	})
+}
+
+// ResetPassword handles setting a new password with the token of a password reset link.
+// The account is signed out of all devices.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if utils.IsEmpty(req.Token) || utils.IsEmpty(req.Password) {
+		writeErrorResponse(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountService.ResetPassword(req.Token, req.Password); err != nil {
+		switch err {
+		case auth.ErrInvalidAccountToken, auth.ErrPasswordTooShort, auth.ErrPasswordTooLong,
+			auth.ErrPasswordMissingUppercase, auth.ErrPasswordMissingLowercase,
+			auth.ErrPasswordMissingDigit, auth.ErrPasswordMissingSpecial:
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeErrorResponse(w, "Password reset failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MessageResponse{Success: true, Message: "Password has been reset; sign in with your new password"})
+}
+
+// VerifyEmail handles confirming an email address with the token of a verification link
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if utils.IsEmpty(req.Token) {
+		writeErrorResponse(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.accountService.VerifyEmail(req.Token)
+	if err != nil {
+		switch err {
+		case auth.ErrInvalidAccountToken:
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeErrorResponse(w, "Email verification failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// SendEmailVerification handles sending the authenticated user another email
+// verification link
+func (h *AuthHandler) SendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		writeErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.accountService.SendEmailVerification(userID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			writeErrorResponse(w, "User not found", http.StatusNotFound)
+		case auth.ErrEmailAlreadyVerified:
+			writeErrorResponse(w, err.Error(), http.StatusConflict)
+		case auth.ErrTooManyAccountEmails:
+			writeErrorResponse(w, err.Error(), http.StatusTooManyRequests)
+		default:
+			writeErrorResponse(w, "Failed to send verification email", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(MessageResponse{Success: true, Message: "Verification email sent"})
+}
+
+// writeLoginResponse writes the response to a login that passed its first step with the
+// given authentication methods: tokens, or a two-factor challenge if the user needs a
+// second step
+func writeLoginResponse(w http.ResponseWriter, tokenService models.TokenService, twoFactorService models.TwoFactorService,
+	user *models.User, amr []string, statusCode int) {
+	challenge, err := twoFactorService.BeginLogin(user, amr)
+	if err != nil {
+		writeErrorResponse(w, "Login failed", http.StatusInternalServerError)
+		return
+	}
+
+	if challenge != nil {
+		writeJSONResponse(w, challenge, statusCode)
+		return
+	}
+
+	writeTokenResponse(w, tokenService, user, amr, statusCode)
+}
+
+// writeTokenResponse issues an access token and a refresh token for a completed login
+func writeTokenResponse(w http.ResponseWriter, tokenService models.TokenService, user *models.User, amr []string, statusCode int) {
+	tokens, err := tokenService.IssueTokens(user, amr)
+	if err != nil {
+		writeErrorResponse(w, "Token generation failed", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONResponse(w, AuthResponse{
+		TokenPair: tokens,
+		User:      user,
+	}, statusCode)
+}
+
+// writeJSONResponse writes a JSON response with the given status code
+func writeJSONResponse(w http.ResponseWriter, response interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// CertificateHandler handles score report and certificate requests
+type CertificateHandler struct {
+	certificateService models.CertificateService
+	resultService      models.TestResultService
+	testService        models.TestService
+	policy             *auth.Policy
+}
+
+// NewCertificateHandler creates a new score report and certificate handler
+func NewCertificateHandler(certificateService models.CertificateService, resultService models.TestResultService, testService models.TestService, policy *auth.Policy) *CertificateHandler {
+	return &CertificateHandler{
+		certificateService: certificateService,
+		resultService:      resultService,
+		testService:        testService,
+		policy:             policy,
+	}
+}
+
+// UpdateCertificateTemplateRequest represents a certificate template update request
+type UpdateCertificateTemplateRequest struct {
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	SignatoryName  string `json:"signatory_name"`
+	SignatoryTitle string `json:"signatory_title"`
+}
+
+// GetTemplate handles getting the certificate template for a test
+func (h *CertificateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can view certificate templates
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	template, err := h.certificateService.GetTemplate(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get certificate template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, template)
+}
+
+// UpdateTemplate handles creating or replacing the certificate template for a test
+func (h *CertificateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change certificate templates
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req UpdateCertificateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.certificateService.UpdateTemplate(testID, req.Title, req.Body, req.SignatoryName, req.SignatoryTitle)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidCertificateTemplate:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update certificate template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, template)
+}
+
+// DeleteTemplate handles restoring the default certificate template for a test
+func (h *CertificateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change certificate templates
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	if err := h.certificateService.DeleteTemplate(testID); err != nil {
+		utils.WriteErrorResponse(w, "Failed to delete certificate template", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// GetScoreReport handles downloading the PDF score report of a result
+func (h *CertificateHandler) GetScoreReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resultID, isStaff, ok := h.authorizeResult(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.certificateService.GetScoreReport(resultID, isStaff)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		case auth.ErrResultNotReleased:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusForbidden)
+		default:
+			utils.WriteErrorResponse(w, "Failed to generate score report", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writePDF(w, fmt.Sprintf("score-report-%d.pdf", resultID), report)
+}
+
+// GetCertificate handles downloading the PDF certificate of a passed result
+func (h *CertificateHandler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resultID, isStaff, ok := h.authorizeResult(w, r)
+	if !ok {
+		return
+	}
+
+	certificate, document, err := h.certificateService.GetCertificate(resultID, isStaff)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		case auth.ErrResultNotReleased:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusForbidden)
+		case auth.ErrResultNotPassed:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+		default:
+			utils.WriteErrorResponse(w, "Failed to generate certificate", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writePDF(w, fmt.Sprintf("certificate-%s.pdf", certificate.VerificationID), document)
+}
+
+// VerifyCertificate handles the public check of a certificate's verification ID
+func (h *CertificateHandler) VerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	verification, err := h.certificateService.VerifyCertificate(vars["verification_id"])
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "No certificate was issued with this verification ID", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to verify certificate", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, verification)
+}
+
+// authorizeResult checks that the caller owns the result in the URL or may view the
+// results of its test, and writes the error response if not
+func (h *CertificateHandler) authorizeResult(w http.ResponseWriter, r *http.Request) (int, bool, bool) {
+	vars := mux.Vars(r)
+	resultID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid result ID", http.StatusBadRequest)
+		return 0, false, false
+	}
+
+	result, err := h.resultService.GetResult(resultID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		return 0, false, false
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false, false
+	}
+
+	isStaff := canAccessTest(r, h.policy, h.testService, result.TestID, 0, auth.ActionViewResults)
+
+	// Allow staff who view the test's results to download its results' documents
+	if result.UserID != userID && !isStaff {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return 0, false, false
+	}
+
+	return resultID, isStaff, true
+}
+
+// writePDF sends a PDF document as a file download
+func writePDF(w http.ResponseWriter, filename string, document []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
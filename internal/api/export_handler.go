@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportHandler handles result export requests
+type ExportHandler struct {
+	exportService models.ExportService
+	testService   models.TestService
+	policy        *auth.Policy
+}
+
+// NewExportHandler creates a new result export handler
+func NewExportHandler(exportService models.ExportService, testService models.TestService, policy *auth.Policy) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		testService:   testService,
+		policy:        policy,
+	}
+}
+
+// ExportResults handles downloading the results of a test as a spreadsheet
+func (h *ExportHandler) ExportResults(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, "results", h.exportService.ExportResults)
+}
+
+// ExportAnswers handles downloading every answer of a test as a spreadsheet
+func (h *ExportHandler) ExportAnswers(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, "answers", h.exportService.ExportAnswers)
+}
+
+// export runs an export and sends it as a file download
+func (h *ExportHandler) export(w http.ResponseWriter, r *http.Request, name string, run func(int, models.ExportFormat, io.Writer) error) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who view the test's results can export results
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
+		return
+	}
+
+	format := models.ExportFormatCSV
+	if formatStr := r.URL.Query().Get("format"); formatStr != "" {
+		format = models.ExportFormat(formatStr)
+	}
+
+	download := &downloadWriter{
+		w:           w,
+		contentType: format.ContentType(),
+		filename:    fmt.Sprintf("test-%d-%s.%s", testID, name, format),
+	}
+	if err := run(testID, format, download); err != nil {
+		if download.started {
+			// The file is already partly sent, so the error can only be logged
+			fmt.Printf("Warning: failed to export %s of test %d: %v\n", name, testID, err)
+			return
+		}
+		switch err {
+		case auth.ErrInvalidExportFormat:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to export "+name, http.StatusInternalServerError)
+		}
+	}
+}
+
+// downloadWriter sends the download headers with the first write, so that errors
+// raised before any output can still be answered with a JSON error
+type downloadWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+// Write writes export output to the response
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	if !d.started {
+		d.started = true
+		d.w.Header().Set("Content-Type", d.contentType)
+		d.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", d.filename))
+		d.w.WriteHeader(http.StatusOK)
+	}
+	return d.w.Write(p)
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GradebookHandler handles course and gradebook requests
+type GradebookHandler struct {
+	gradebookService models.GradebookService
+	testService      models.TestService
+	policy           *auth.Policy
+}
+
+// NewGradebookHandler creates a new course gradebook handler
+func NewGradebookHandler(gradebookService models.GradebookService, testService models.TestService, policy *auth.Policy) *GradebookHandler {
+	return &GradebookHandler{
+		gradebookService: gradebookService,
+		testService:      testService,
+		policy:           policy,
+	}
+}
+
+// CourseCategoryRequest represents a weighted category in a course request
+type CourseCategoryRequest struct {
+	Name       string  `json:"name"`
+	Weight     float64 `json:"weight"`
+	DropLowest int     `json:"drop_lowest"`
+	TestIDs    []int   `json:"test_ids"`
+}
+
+// CourseRequest represents a course creation or update request
+type CourseRequest struct {
+	Name           string                  `json:"name"`
+	Description    string                  `json:"description"`
+	GradingScaleID *int                    `json:"grading_scale_id"` // nil for the default scale
+	Categories     []CourseCategoryRequest `json:"categories"`
+}
+
+// categories converts the request categories to course categories
+func (req *CourseRequest) categories() []*models.CourseCategory {
+	categories := make([]*models.CourseCategory, 0, len(req.Categories))
+	for _, category := range req.Categories {
+		categories = append(categories, &models.CourseCategory{
+			Name:       utils.SanitizeHTML(utils.SanitizeString(category.Name)),
+			Weight:     category.Weight,
+			DropLowest: category.DropLowest,
+			TestIDs:    category.TestIDs,
+		})
+	}
+	return categories
+}
+
+// validate sanitizes and validates the course name and description
+func (req *CourseRequest) validate() string {
+	req.Name = utils.SanitizeHTML(utils.SanitizeString(req.Name))
+	req.Description = utils.SanitizeHTML(utils.SanitizeString(req.Description))
+
+	if !utils.ValidateTextLength(req.Name, 1, 100) {
+		return "Course name must be 1-100 characters"
+	}
+	if !utils.ValidateTextLength(req.Description, 0, 1000) {
+		return "Course description must be 0-1000 characters"
+	}
+	return ""
+}
+
+// CreateCourse handles course creation
+func (h *GradebookHandler) CreateCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermCoursesManage, models.PermCoursesManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req CourseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	canViewResults := testAccessCheck(r, h.policy, h.testService, auth.ActionViewResults)
+	course, err := h.gradebookService.CreateCourse(userID, req.Name, req.Description, req.GradingScaleID, req.categories(), canViewResults)
+	if err != nil {
+		switch err {
+		case auth.ErrForbidden:
+			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		case auth.ErrInvalidCourseCategories, auth.ErrInvalidCourseGradingScale:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to create course", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteCreatedResponse(w, course)
+}
+
+// ListCourses handles listing courses: managers see the courses they own, and holders of
+// courses.manage_all see every course
+func (h *GradebookHandler) ListCourses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermCoursesManage, models.PermCoursesManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Get pagination parameters
+	limit := 20
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	ownerID := userID
+	if auth.HasPermission(r, models.PermCoursesManageAll) {
+		ownerID = 0
+		if o := r.URL.Query().Get("owner"); o != "" {
+			if parsed, err := strconv.Atoi(o); err == nil {
+				ownerID = parsed
+			}
+		}
+	}
+
+	courses, err := h.gradebookService.ListCourses(ownerID, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to list courses", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, courses)
+}
+
+// GetCourse handles getting a course by ID
+func (h *GradebookHandler) GetCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	course, ok := h.authorizeCourse(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteSuccessResponse(w, course)
+}
+
+// UpdateCourse handles updating a course and replacing its categories
+func (h *GradebookHandler) UpdateCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	course, ok := h.authorizeCourse(w, r)
+	if !ok {
+		return
+	}
+
+	var req CourseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	canViewResults := testAccessCheck(r, h.policy, h.testService, auth.ActionViewResults)
+	course, err := h.gradebookService.UpdateCourse(course.ID, req.Name, req.Description, req.GradingScaleID, req.categories(), canViewResults)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Course not found", http.StatusNotFound)
+		case auth.ErrForbidden:
+			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		case auth.ErrInvalidCourseCategories, auth.ErrInvalidCourseGradingScale:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update course", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, course)
+}
+
+// DeleteCourse handles deleting a course; its tests and results are kept
+func (h *GradebookHandler) DeleteCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	course, ok := h.authorizeCourse(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.gradebookService.DeleteCourse(course.ID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Course not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to delete course", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// GetGradebook handles getting the running grades of every student in a course
+func (h *GradebookHandler) GetGradebook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	course, ok := h.authorizeCourse(w, r)
+	if !ok {
+		return
+	}
+
+	canViewResults := testAccessCheck(r, h.policy, h.testService, auth.ActionViewResults)
+	gradebook, err := h.gradebookService.GetGradebook(course.ID, canViewResults)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Course not found", http.StatusNotFound)
+		case auth.ErrForbidden:
+			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		default:
+			utils.WriteErrorResponse(w, "Failed to compute gradebook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, gradebook)
+}
+
+// GetMyGrade handles getting the current user's running grade in a course
+func (h *GradebookHandler) GetMyGrade(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	courseID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	grade, err := h.gradebookService.GetStudentGrade(courseID, userID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Course not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to compute course grade", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, grade)
+}
+
+// ExportGradebook handles downloading the gradebook of a course as a spreadsheet
+func (h *GradebookHandler) ExportGradebook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	course, ok := h.authorizeCourse(w, r)
+	if !ok {
+		return
+	}
+
+	format := models.ExportFormatCSV
+	if formatStr := r.URL.Query().Get("format"); formatStr != "" {
+		format = models.ExportFormat(formatStr)
+	}
+
+	download := &downloadWriter{
+		w:           w,
+		contentType: format.ContentType(),
+		filename:    fmt.Sprintf("course-%d-gradebook.%s", course.ID, format),
+	}
+	canViewResults := testAccessCheck(r, h.policy, h.testService, auth.ActionViewResults)
+	if err := h.gradebookService.ExportGradebook(course.ID, format, download, canViewResults); err != nil {
+		if download.started {
+			// The file is already partly sent, so the error can only be logged
+			fmt.Printf("Warning: failed to export gradebook of course %d: %v\n", course.ID, err)
+			return
+		}
+		switch err {
+		case auth.ErrInvalidExportFormat:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Course not found", http.StatusNotFound)
+		case auth.ErrForbidden:
+			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		default:
+			utils.WriteErrorResponse(w, "Failed to export gradebook", http.StatusInternalServerError)
+		}
+	}
+}
+
+// authorizeCourse checks that the caller may manage the course in the URL: its owner, or
+// a holder of courses.manage_all. It writes the error response if not.
+func (h *GradebookHandler) authorizeCourse(w http.ResponseWriter, r *http.Request) (*models.Course, bool) {
+	vars := mux.Vars(r)
+	courseID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid course ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if _, ok := auth.GetUserIDFromContext(r); !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if !auth.HasAnyPermission(r, models.PermCoursesManage, models.PermCoursesManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	course, err := h.gradebookService.GetCourse(courseID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Course not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve course", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if !authorizeCourse(w, r, h.policy, course) {
+		return nil, false
+	}
+
+	return course, true
+}
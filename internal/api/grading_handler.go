@@ -0,0 +1,425 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GradingHandler handles grading scale-related requests
+type GradingHandler struct {
+	gradingService models.GradingScaleService
+	testService    models.TestService
+	policy         *auth.Policy
+}
+
+// NewGradingHandler creates a new grading scale handler
+func NewGradingHandler(gradingService models.GradingScaleService, testService models.TestService, policy *auth.Policy) *GradingHandler {
+	return &GradingHandler{
+		gradingService: gradingService,
+		testService:    testService,
+		policy:         policy,
+	}
+}
+
+// GradingBandRequest represents a grade band in a grading scale request
+type GradingBandRequest struct {
+	Label         string   `json:"label"`
+	MinPercentage float64  `json:"min_percentage"`
+	GPAPoints     *float64 `json:"gpa_points"`
+}
+
+// GradingScaleRequest represents a grading scale creation or update request
+type GradingScaleRequest struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	TestID      *int                 `json:"test_id"` // ignored on update
+	Bands       []GradingBandRequest `json:"bands"`
+}
+
+// AssignGradingScaleRequest represents a request to switch the grading scale of a test
+type AssignGradingScaleRequest struct {
+	GradingScaleID *int `json:"grading_scale_id"`
+	Recompute      bool `json:"recompute"`
+}
+
+// bands converts the request bands to grading bands
+func (req *GradingScaleRequest) bands() []*models.GradingBand {
+	bands := make([]*models.GradingBand, 0, len(req.Bands))
+	for _, band := range req.Bands {
+		bands = append(bands, &models.GradingBand{
+			Label:         utils.SanitizeString(band.Label),
+			MinPercentage: band.MinPercentage,
+			GPAPoints:     band.GPAPoints,
+		})
+	}
+	return bands
+}
+
+// validate sanitizes and validates the scale name and description
+func (req *GradingScaleRequest) validate() string {
+	req.Name = utils.SanitizeHTML(utils.SanitizeString(req.Name))
+	req.Description = utils.SanitizeHTML(utils.SanitizeString(req.Description))
+
+	if !utils.ValidateTextLength(req.Name, 1, 100) {
+		return "Grading scale name must be 1-100 characters"
+	}
+	if !utils.ValidateTextLength(req.Description, 0, 1000) {
+		return "Grading scale description must be 0-1000 characters"
+	}
+	return ""
+}
+
+// CreateScale handles grading scale creation
+func (h *GradingHandler) CreateScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermTestsCreate, models.PermTestsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req GradingScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// Global scales affect every test, so only their managers can define them
+	if req.TestID == nil && !auth.HasPermission(r, models.PermGradingScalesManage) {
+		utils.WriteErrorResponse(w, "Only grading scale managers can create global grading scales", http.StatusForbidden)
+		return
+	}
+
+	// A test's own scale is part of the test, so only its authors can define it
+	if req.TestID != nil && !authorizeTest(w, r, h.policy, h.testService, *req.TestID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	scale, err := h.gradingService.CreateScale(userID, req.Name, req.Description, req.TestID, req.bands())
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidGradingScale:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to create grading scale", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteCreatedResponse(w, scale)
+}
+
+// ListScales handles listing global grading scales and, with ?test_id=, those of a test
+func (h *GradingHandler) ListScales(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermTestsCreate, models.PermTestsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	testID := 0
+	if testIDStr := r.URL.Query().Get("test_id"); testIDStr != "" {
+		var err error
+		testID, err = strconv.Atoi(testIDStr)
+		if err != nil || testID <= 0 {
+			utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+			return
+		}
+		if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+			return
+		}
+	}
+
+	scales, err := h.gradingService.ListScales(testID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to retrieve grading scales", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, scales)
+}
+
+// GetScale handles getting a grading scale by ID
+func (h *GradingHandler) GetScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	scaleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid grading scale ID", http.StatusBadRequest)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermTestsCreate, models.PermTestsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	scale, err := h.gradingService.GetScale(scaleID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Grading scale not found", http.StatusNotFound)
+		return
+	}
+	if scale.TestID != nil && !authorizeTest(w, r, h.policy, h.testService, *scale.TestID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	utils.WriteSuccessResponse(w, scale)
+}
+
+// UpdateScale handles updating a grading scale and its bands
+func (h *GradingHandler) UpdateScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	scaleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid grading scale ID", http.StatusBadRequest)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermTestsCreate, models.PermTestsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	existing, err := h.gradingService.GetScale(scaleID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Grading scale not found", http.StatusNotFound)
+		return
+	}
+	if existing.TestID == nil && !auth.HasPermission(r, models.PermGradingScalesManage) {
+		utils.WriteErrorResponse(w, "Only grading scale managers can change global grading scales", http.StatusForbidden)
+		return
+	}
+	if existing.TestID != nil && !authorizeTest(w, r, h.policy, h.testService, *existing.TestID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req GradingScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	scale, err := h.gradingService.UpdateScale(scaleID, req.Name, req.Description, req.bands())
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Grading scale not found", http.StatusNotFound)
+		case auth.ErrInvalidGradingScale:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update grading scale", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, scale)
+}
+
+// DeleteScale handles deleting a grading scale
+func (h *GradingHandler) DeleteScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	scaleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid grading scale ID", http.StatusBadRequest)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermTestsCreate, models.PermTestsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	existing, err := h.gradingService.GetScale(scaleID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Grading scale not found", http.StatusNotFound)
+		return
+	}
+	if existing.TestID == nil && !auth.HasPermission(r, models.PermGradingScalesManage) {
+		utils.WriteErrorResponse(w, "Only grading scale managers can delete global grading scales", http.StatusForbidden)
+		return
+	}
+	if existing.TestID != nil && !authorizeTest(w, r, h.policy, h.testService, *existing.TestID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	if err := h.gradingService.DeleteScale(scaleID); err != nil {
+		utils.WriteErrorResponse(w, "Failed to delete grading scale", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// SetDefaultScale handles making a global grading scale the default
+func (h *GradingHandler) SetDefaultScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	scaleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid grading scale ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only grading scale managers can change the default scale
+	if !auth.HasPermission(r, models.PermGradingScalesManage) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.gradingService.SetDefaultScale(scaleID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Grading scale not found", http.StatusNotFound)
+		case auth.ErrGradingScaleNotForTest:
+			utils.WriteErrorResponse(w, "Only global grading scales can be the default", http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to set default grading scale", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, map[string]string{
+		"message": "Default grading scale updated",
+	})
+}
+
+// AssignScale handles switching the grading scale of a test, optionally regrading its results
+func (h *GradingHandler) AssignScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req AssignGradingScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Recomputing changes results, which takes the right to grade them as well
+	if req.Recompute && !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionGrade) {
+		return
+	}
+
+	test, err := h.gradingService.AssignScale(testID, req.GradingScaleID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test or grading scale not found", http.StatusNotFound)
+		case auth.ErrGradingScaleNotForTest:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to assign grading scale", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"test": test,
+	}
+
+	if req.Recompute {
+		updated, err := h.gradingService.RecomputeGrades(testID)
+		if err != nil {
+			utils.WriteErrorResponse(w, "Failed to recompute grades", http.StatusInternalServerError)
+			return
+		}
+		response["results_updated"] = updated
+	}
+
+	utils.WriteSuccessResponse(w, response)
+}
+
+// RecomputeGrades handles regrading all stored results of a test with its current scale
+func (h *GradingHandler) RecomputeGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionGrade) {
+		return
+	}
+
+	updated, err := h.gradingService.RecomputeGrades(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to recompute grades", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, map[string]interface{}{
+		"results_updated": updated,
+	})
+}
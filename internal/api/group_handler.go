@@ -0,0 +1,607 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GroupHandler handles class, group and enrolment requests
+type GroupHandler struct {
+	groupService models.GroupService
+	testService  models.TestService
+	policy       *auth.Policy
+}
+
+// NewGroupHandler creates a new group handler
+func NewGroupHandler(groupService models.GroupService, testService models.TestService, policy *auth.Policy) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+		testService:  testService,
+		policy:       policy,
+	}
+}
+
+// GroupRequest represents a group creation or update request
+type GroupRequest struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Kind        models.GroupKind `json:"kind"` // class (default) or group
+}
+
+// JoinGroupRequest represents a request to join a group with its invite code
+type JoinGroupRequest struct {
+	InviteCode string `json:"invite_code"`
+}
+
+// AddMemberRequest represents a request to enroll a user in a group
+type AddMemberRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// DirectoryGroupRequest represents a request to link a group to a directory group
+type DirectoryGroupRequest struct {
+	DirectoryGroup string `json:"directory_group"` // group common name; empty removes the link
+}
+
+// AssignTestGroupsRequest represents a request to set the groups a test is assigned to
+type AssignTestGroupsRequest struct {
+	GroupIDs []int `json:"group_ids"`
+}
+
+// validate sanitizes and validates the group name and description
+func (req *GroupRequest) validate() string {
+	req.Name = utils.SanitizeHTML(utils.SanitizeString(req.Name))
+	req.Description = utils.SanitizeHTML(utils.SanitizeString(req.Description))
+
+	if !utils.ValidateTextLength(req.Name, 1, 100) {
+		return "Group name must be 1-100 characters"
+	}
+	if !utils.ValidateTextLength(req.Description, 0, 1000) {
+		return "Group description must be 0-1000 characters"
+	}
+	return ""
+}
+
+// CreateGroup handles group creation; the creator owns the group
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermGroupsManage, models.PermGroupsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(userID, req.Name, req.Description, req.Kind)
+	if err != nil {
+		switch err {
+		case auth.ErrInvalidGroupKind:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to create group", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteCreatedResponse(w, group)
+}
+
+// ListGroups handles listing groups: managers see the groups they own, and holders of
+// groups.manage_all see every group
+func (h *GroupHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !auth.HasAnyPermission(r, models.PermGroupsManage, models.PermGroupsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Get pagination parameters
+	limit := 20
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	ownerID := userID
+	if auth.HasPermission(r, models.PermGroupsManageAll) {
+		ownerID = 0
+		if o := r.URL.Query().Get("owner"); o != "" {
+			if parsed, err := strconv.Atoi(o); err == nil {
+				ownerID = parsed
+			}
+		}
+	}
+
+	groups, err := h.groupService.ListGroups(ownerID, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to list groups", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, groups)
+}
+
+// GetMyGroups handles listing the groups the current user is enrolled in
+func (h *GroupHandler) GetMyGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groups, err := h.groupService.GetUserGroups(userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to retrieve groups", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, groups)
+}
+
+// JoinGroup handles enrolling the current user in a group with its invite code
+func (h *GroupHandler) JoinGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req JoinGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.groupService.JoinGroup(userID, req.InviteCode)
+	if err != nil {
+		switch err {
+		case auth.ErrInvalidInviteCode:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to join group", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, group)
+}
+
+// GetGroup handles getting a group by ID
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteSuccessResponse(w, group)
+}
+
+// UpdateGroup handles updating a group's name, description and kind
+func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var req GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.groupService.UpdateGroup(group.ID, req.Name, req.Description, req.Kind)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		case auth.ErrInvalidGroupKind:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update group", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, updated)
+}
+
+// DeleteGroup handles deleting a group
+func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.DeleteGroup(group.ID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to delete group", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// RegenerateInviteCode handles replacing a group's invite code, which also reopens enrolment
+func (h *GroupHandler) RegenerateInviteCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.groupService.RegenerateInviteCode(group.ID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to generate invite code", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, updated)
+}
+
+// CloseInviteCode handles closing enrolment by invite code for a group
+func (h *GroupHandler) CloseInviteCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.CloseInviteCode(group.ID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to close invite code", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// LinkDirectoryGroup handles linking a group to a directory group, whose members are
+// enrolled when they sign in with the directory
+func (h *GroupHandler) LinkDirectoryGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var req DirectoryGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.groupService.LinkDirectoryGroup(group.ID, req.DirectoryGroup)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		case auth.ErrInvalidDirectoryGroup:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to link directory group", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, updated)
+}
+
+// GetMembers handles listing the members of a group
+func (h *GroupHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := h.groupService.GetMembers(group.ID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve members", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, members)
+}
+
+// AddMember handles enrolling a user in a group
+func (h *GroupHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, ok := h.authorizeGroup(w, r)
+	if !ok {
+		return
+	}
+
+	var req AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.groupService.AddMember(group.ID, req.UserID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group or user not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to add member", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	members, err := h.groupService.GetMembers(group.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to retrieve members", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteCreatedResponse(w, members)
+}
+
+// RemoveMember handles removing a user from a group; members may also remove themselves
+func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if memberID != userID {
+		group, ok := h.authorizeGroup(w, r)
+		if !ok {
+			return
+		}
+		groupID = group.ID
+	}
+
+	if err := h.groupService.RemoveMember(groupID, memberID); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to remove member", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// GetTestGroups handles listing the groups a test is assigned to
+func (h *GroupHandler) GetTestGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	groups, err := h.groupService.GetTestGroups(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve test groups", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, groups)
+}
+
+// AssignTestGroups handles setting the groups a test is offered to
+func (h *GroupHandler) AssignTestGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req AssignTestGroupsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	groups, err := h.groupService.AssignTest(testID, req.GroupIDs)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidGroupAssignment:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to assign test", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, groups)
+}
+
+// authorizeGroup checks that the caller owns the group in the URL or is an admin, and
+// writes the error response if not
+func (h *GroupHandler) authorizeGroup(w http.ResponseWriter, r *http.Request) (*models.Group, bool) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid group ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if !auth.HasAnyPermission(r, models.PermGroupsManage, models.PermGroupsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	group, err := h.groupService.GetGroup(groupID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Group not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve group", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	// Teachers manage the groups they own; groups.manage_all covers every group
+	if group.OwnerID != userID && !auth.HasPermission(r, models.PermGroupsManageAll) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return group, true
+}
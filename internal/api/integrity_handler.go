@@ -0,0 +1,100 @@
+package api
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// IntegrityHandler handles result ledger verification requests
+type IntegrityHandler struct {
+	integrityService models.IntegrityService
+	resultService    models.TestResultService
+	testService      models.TestService
+	policy           *auth.Policy
+}
+
+// NewIntegrityHandler creates a new result integrity handler
+func NewIntegrityHandler(integrityService models.IntegrityService, resultService models.TestResultService, testService models.TestService, policy *auth.Policy) *IntegrityHandler {
+	return &IntegrityHandler{
+		integrityService: integrityService,
+		resultService:    resultService,
+		testService:      testService,
+		policy:           policy,
+	}
+}
+
+// VerifyAll handles verifying the whole result ledger against the stored results
+func (h *IntegrityHandler) VerifyAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only auditors can verify every result
+	if !auth.HasPermission(r, models.PermIntegrityAudit) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	report, err := h.integrityService.VerifyAll()
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to verify results", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, report)
+}
+
+// VerifyResult handles verifying the ledger history and signature of one result
+func (h *IntegrityHandler) VerifyResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	resultID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid result ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.resultService.GetResult(resultID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		return
+	}
+
+	// Only staff who view the test's results can verify results
+	if !authorizeTest(w, r, h.policy, h.testService, result.TestID, 0, auth.ActionViewResults) {
+		return
+	}
+
+	integrity, err := h.integrityService.VerifyResult(resultID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to verify result", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, integrity)
+}
+
+// GetPublicKey handles getting the public key that result signatures can be checked with
+func (h *IntegrityHandler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, h.integrityService.GetSigningKey())
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"net/http"
+)
+
+// JWKSHandler publishes the public keys access tokens are signed with
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+// NewJWKSHandler creates a new JWKS handler
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// GetJWKS handles getting the JSON Web Key Set. Other services use it to verify GoCBT
+// access tokens, picking the key by the token's kid header; a short cache lets them
+// pick up new keys soon after a rotation.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
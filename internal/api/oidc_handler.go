@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+)
+
+// OIDCHandler handles OpenID Connect single sign-on requests
+type OIDCHandler struct {
+	oidcService      models.OIDCService
+	tokenService     models.TokenService
+	twoFactorService models.TwoFactorService
+}
+
+// NewOIDCHandler creates a new single sign-on handler
+func NewOIDCHandler(oidcService models.OIDCService, tokenService models.TokenService, twoFactorService models.TwoFactorService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:      oidcService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+	}
+}
+
+// OIDCStartResponse represents the identity provider URL to send the user to
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCCallbackRequest represents the code and state the identity provider redirected
+// back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// StartLogin handles starting a single sign-on login
+func (h *OIDCHandler) StartLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authorizationURL, err := h.oidcService.StartLogin()
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCNotConfigured):
+			writeErrorResponse(w, "Single sign-on is not configured", http.StatusNotFound)
+		case errors.Is(err, auth.ErrOIDCLoginFailed):
+			writeErrorResponse(w, "Identity provider is unavailable", http.StatusBadGateway)
+		default:
+			writeErrorResponse(w, "Failed to start single sign-on", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OIDCStartResponse{AuthorizationURL: authorizationURL})
+}
+
+// Callback handles completing a single sign-on login. It returns the same tokens, or
+// two-factor challenge, as a password login; a provider that reports a multi-factor
+// sign-in in its amr claim skips the second step.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if utils.IsEmpty(req.Code) || utils.IsEmpty(req.State) {
+		writeErrorResponse(w, "Code and state are required", http.StatusBadRequest)
+		return
+	}
+
+	user, amr, err := h.oidcService.CompleteLogin(req.Code, req.State)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCNotConfigured):
+			writeErrorResponse(w, "Single sign-on is not configured", http.StatusNotFound)
+		case errors.Is(err, auth.ErrInvalidOIDCState), errors.Is(err, auth.ErrOIDCLoginFailed),
+			errors.Is(err, auth.ErrOIDCEmailNotVerified):
+			writeErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, auth.ErrOIDCDomainNotAllowed), errors.Is(err, auth.ErrOIDCAccountNotFound):
+			writeErrorResponse(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, auth.ErrUserNotActive):
+			writeErrorResponse(w, "Account is not active", http.StatusForbidden)
+		case errors.Is(err, auth.ErrEmailExists):
+			writeErrorResponse(w, "An account with this email already exists; sign in with your password", http.StatusConflict)
+		default:
+			writeErrorResponse(w, "Single sign-on failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeLoginResponse(w, h.tokenService, h.twoFactorService, user, amr, http.StatusOK)
+}
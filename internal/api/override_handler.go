@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// OverrideHandler handles manual score override requests
+type OverrideHandler struct {
+	overrideService models.ScoreOverrideService
+	testService     models.TestService
+	policy          *auth.Policy
+}
+
+// NewOverrideHandler creates a new score override handler
+func NewOverrideHandler(overrideService models.ScoreOverrideService, testService models.TestService, policy *auth.Policy) *OverrideHandler {
+	return &OverrideHandler{
+		overrideService: overrideService,
+		testService:     testService,
+		policy:          policy,
+	}
+}
+
+// ScoreOverrideRequest represents a manual score override request
+type ScoreOverrideRequest struct {
+	Marks      int                       `json:"marks"` // ignored when voiding a question
+	ReasonCode models.OverrideReasonCode `json:"reason_code"`
+	Reason     string                    `json:"reason"`
+}
+
+// ScoreOverrideResponse represents an override together with the rescoring it caused
+type ScoreOverrideResponse struct {
+	Override *models.ScoreOverride `json:"override"`
+	Regrade  *models.RegradeReport `json:"regrade"`
+}
+
+// OverrideAnswer handles overriding the marks awarded for an answer
+func (h *OverrideHandler) OverrideAnswer(w http.ResponseWriter, r *http.Request) {
+	h.createOverride(w, r, models.OverrideScopeAnswer, "Invalid answer ID", "Answer not found",
+		func(actorID, targetID int, req *ScoreOverrideRequest) (*models.ScoreOverride, *models.RegradeReport, error) {
+			return h.overrideService.OverrideAnswer(actorID, targetID, req.Marks, req.ReasonCode, req.Reason)
+		})
+}
+
+// OverrideResult handles overriding the marks obtained for a result
+func (h *OverrideHandler) OverrideResult(w http.ResponseWriter, r *http.Request) {
+	h.createOverride(w, r, models.OverrideScopeResult, "Invalid result ID", "Result not found",
+		func(actorID, targetID int, req *ScoreOverrideRequest) (*models.ScoreOverride, *models.RegradeReport, error) {
+			return h.overrideService.OverrideResult(actorID, targetID, req.Marks, req.ReasonCode, req.Reason)
+		})
+}
+
+// VoidQuestion handles voiding a question for every candidate
+func (h *OverrideHandler) VoidQuestion(w http.ResponseWriter, r *http.Request) {
+	h.createOverride(w, r, models.OverrideScopeQuestion, "Invalid question ID", "Question not found",
+		func(actorID, targetID int, req *ScoreOverrideRequest) (*models.ScoreOverride, *models.RegradeReport, error) {
+			return h.overrideService.VoidQuestion(actorID, targetID, req.ReasonCode, req.Reason)
+		})
+}
+
+// createOverride handles the shared parsing, validation and error mapping of override requests
+func (h *OverrideHandler) createOverride(w http.ResponseWriter, r *http.Request, scope models.OverrideScope, invalidIDMessage, notFoundMessage string,
+	create func(actorID, targetID int, req *ScoreOverrideRequest) (*models.ScoreOverride, *models.RegradeReport, error)) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	targetID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, invalidIDMessage, http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	testID, err := h.overrideService.GetTargetTestID(scope, targetID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, notFoundMessage, http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to apply score override", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Only staff who grade the test's results can override scores
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionGrade) {
+		return
+	}
+
+	var req ScoreOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Reason = utils.SanitizeHTML(utils.SanitizeString(req.Reason))
+	if !utils.ValidateTextLength(req.Reason, 1, 1000) {
+		utils.WriteErrorResponse(w, "Override reason must be 1-1000 characters", http.StatusBadRequest)
+		return
+	}
+
+	override, report, err := create(userID, targetID, &req)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, notFoundMessage, http.StatusNotFound)
+		case auth.ErrInvalidOverrideMarks, auth.ErrInvalidReasonCode:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to apply score override", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteCreatedResponse(w, &ScoreOverrideResponse{
+		Override: override,
+		Regrade:  report,
+	})
+}
+
+// RevokeOverride handles revoking a score override
+func (h *OverrideHandler) RevokeOverride(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	overrideID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid override ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	existing, err := h.overrideService.GetOverride(overrideID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Override not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to revoke score override", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Only staff who grade the test's results can revoke overrides
+	if !authorizeTest(w, r, h.policy, h.testService, existing.TestID, 0, auth.ActionGrade) {
+		return
+	}
+
+	override, report, err := h.overrideService.RevokeOverride(userID, overrideID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Override not found", http.StatusNotFound)
+		case auth.ErrOverrideRevoked:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+		default:
+			utils.WriteErrorResponse(w, "Failed to revoke score override", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, &ScoreOverrideResponse{
+		Override: override,
+		Regrade:  report,
+	})
+}
+
+// GetTestOverrides handles listing the score overrides of a test
+func (h *OverrideHandler) GetTestOverrides(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who view the test's results can view overrides
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
+		return
+	}
+
+	includeRevoked, _ := strconv.ParseBool(r.URL.Query().Get("include_revoked"))
+
+	overrides, err := h.overrideService.GetTestOverrides(testID, includeRevoked)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to retrieve score overrides", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, overrides)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+)
+
+// authorizeTest checks that the requesting user may take an action on a test, or on a
+// session or result of that test belonging to the candidate. It writes the error
+// response and returns false if not.
+func authorizeTest(w http.ResponseWriter, r *http.Request, policy *auth.Policy, testService models.TestService, testID, candidateID int, action auth.Action) bool {
+	principal, ok := auth.PrincipalFromRequest(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	ownership, err := testService.GetOwnership(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve test", http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	resource := auth.Resource{
+		OwnerID:     ownership.OwnerID,
+		CoOwnerIDs:  ownership.CoOwnerIDs,
+		CandidateID: candidateID,
+	}
+	if err := policy.Authorize(principal, action, resource); err != nil {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// canAccessTest reports whether the requesting user may take an action on a test without
+// writing a response, for handlers that only trim what they return
+func canAccessTest(r *http.Request, policy *auth.Policy, testService models.TestService, testID, candidateID int, action auth.Action) bool {
+	principal, ok := auth.PrincipalFromRequest(r)
+	if !ok {
+		return false
+	}
+
+	ownership, err := testService.GetOwnership(testID)
+	if err != nil {
+		return false
+	}
+
+	return policy.Can(principal, action, auth.Resource{
+		OwnerID:     ownership.OwnerID,
+		CoOwnerIDs:  ownership.CoOwnerIDs,
+		CandidateID: candidateID,
+	})
+}
+
+// authorizeCourse checks that the requesting user may manage a course and read its
+// gradebook. It writes the error response and returns false if not; the tests the
+// course counts are checked separately with testAccessCheck.
+func authorizeCourse(w http.ResponseWriter, r *http.Request, policy *auth.Policy, course *models.Course) bool {
+	principal, ok := auth.PrincipalFromRequest(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	if err := policy.Authorize(principal, auth.ActionManageCourse, auth.Resource{OwnerID: course.CreatedBy}); err != nil {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// testAccessCheck returns canAccessTest for the requesting user as a function of the test,
+// for services that check every test a resource such as a course refers to
+func testAccessCheck(r *http.Request, policy *auth.Policy, testService models.TestService, action auth.Action) func(testID int) bool {
+	return func(testID int) bool {
+		return canAccessTest(r, policy, testService, testID, 0, action)
+	}
+}
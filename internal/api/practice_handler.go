@@ -0,0 +1,116 @@
+package api
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// PracticeHandler handles practice mode requests
+type PracticeHandler struct {
+	practiceService models.PracticeService
+	testService     models.TestService
+	policy          *auth.Policy
+}
+
+// NewPracticeHandler creates a new practice mode handler
+func NewPracticeHandler(practiceService models.PracticeService, testService models.TestService, policy *auth.Policy) *PracticeHandler {
+	return &PracticeHandler{
+		practiceService: practiceService,
+		testService:     testService,
+		policy:          policy,
+	}
+}
+
+// GetMyAttempts handles getting the current user's practice attempts
+func (h *PracticeHandler) GetMyAttempts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Optionally limit the attempts to one test
+	testID := 0
+	if t := r.URL.Query().Get("test_id"); t != "" {
+		parsed, err := strconv.Atoi(t)
+		if err != nil || parsed <= 0 {
+			utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+			return
+		}
+		testID = parsed
+	}
+
+	// Get pagination parameters
+	limit := 20
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	attempts, err := h.practiceService.GetUserAttempts(userID, testID, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to get practice attempts", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, attempts)
+}
+
+// GetTestAttempts handles getting all practice attempts at a test
+func (h *PracticeHandler) GetTestAttempts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who view the test's results can view everyone's practice attempts
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
+		return
+	}
+
+	// Get pagination parameters
+	limit := 20
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	attempts, err := h.practiceService.GetTestAttempts(testID, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to get practice attempts", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, attempts)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"gocbt/internal/auth"
 	"gocbt/internal/models"
 	"gocbt/internal/utils"
 	"net/http"
@@ -13,24 +14,34 @@ import (
 // QuestionHandler handles question-related requests
 type QuestionHandler struct {
 	questionService models.QuestionService
+	testService     models.TestService
+	policy          *auth.Policy
 }
 
 // NewQuestionHandler creates a new question handler
-func NewQuestionHandler(questionService models.QuestionService) *QuestionHandler {
+func NewQuestionHandler(questionService models.QuestionService, testService models.TestService, policy *auth.Policy) *QuestionHandler {
 	return &QuestionHandler{
 		questionService: questionService,
+		testService:     testService,
+		policy:          policy,
 	}
 }
 
+// maxFeedbackLength limits explanations and feedback texts
+const maxFeedbackLength = 5000
+
 // CreateQuestionRequest represents a question creation request
 type CreateQuestionRequest struct {
-	TestID       int                    `json:"test_id"`
-	QuestionText string                 `json:"question_text"`
-	QuestionType models.QuestionType    `json:"question_type"`
-	Marks        int                    `json:"marks"`
-	OrderIndex   int                    `json:"order_index"`
-	Options      []CreateOptionRequest  `json:"options,omitempty"`
-	Answers      []CreateAnswerRequest  `json:"answers,omitempty"`
+	TestID            int                   `json:"test_id"`
+	QuestionText      string                `json:"question_text"`
+	QuestionType      models.QuestionType   `json:"question_type"`
+	Marks             int                   `json:"marks"`
+	OrderIndex        int                   `json:"order_index"`
+	Explanation       string                `json:"explanation,omitempty"`
+	CorrectFeedback   string                `json:"correct_feedback,omitempty"`
+	IncorrectFeedback string                `json:"incorrect_feedback,omitempty"`
+	Options           []CreateOptionRequest `json:"options,omitempty"`
+	Answers           []CreateAnswerRequest `json:"answers,omitempty"`
 }
 
 // CreateOptionRequest represents an option creation request
@@ -38,6 +49,24 @@ type CreateOptionRequest struct {
 	OptionText string `json:"option_text"`
 	IsCorrect  bool   `json:"is_correct"`
 	OrderIndex int    `json:"order_index"`
+	Feedback   string `json:"feedback,omitempty"`
+}
+
+// UpdateFeedbackRequest represents a question explanation and feedback update request
+type UpdateFeedbackRequest struct {
+	Explanation       string `json:"explanation"`
+	CorrectFeedback   string `json:"correct_feedback"`
+	IncorrectFeedback string `json:"incorrect_feedback"`
+}
+
+// validFeedback checks the length of an explanation and its feedback texts
+func validFeedback(texts ...string) bool {
+	for _, text := range texts {
+		if len(text) > maxFeedbackLength {
+			return false
+		}
+	}
+	return true
 }
 
 // CreateAnswerRequest represents a correct answer creation request
@@ -59,6 +88,19 @@ func (h *QuestionHandler) CreateQuestion(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !authorizeTest(w, r, h.policy, h.testService, req.TestID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	feedbackTexts := []string{req.Explanation, req.CorrectFeedback, req.IncorrectFeedback}
+	for _, optionReq := range req.Options {
+		feedbackTexts = append(feedbackTexts, optionReq.Feedback)
+	}
+	if !validFeedback(feedbackTexts...) {
+		utils.WriteErrorResponse(w, "Explanation and feedback must be at most 5000 characters", http.StatusBadRequest)
+		return
+	}
+
 	question, err := h.questionService.CreateQuestion(req.TestID, req.QuestionText,
 		req.QuestionType, req.Marks, req.OrderIndex)
 	if err != nil {
@@ -66,11 +108,24 @@ func (h *QuestionHandler) CreateQuestion(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Add the explanation and answer feedback
+	if req.Explanation != "" || req.CorrectFeedback != "" || req.IncorrectFeedback != "" {
+		question, err = h.questionService.UpdateFeedback(question.ID, models.QuestionFeedback{
+			Explanation:       req.Explanation,
+			CorrectFeedback:   req.CorrectFeedback,
+			IncorrectFeedback: req.IncorrectFeedback,
+		})
+		if err != nil {
+			utils.WriteErrorResponse(w, "Failed to save question feedback", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Add options for multiple choice questions
 	if req.QuestionType == models.QuestionTypeMultipleChoice || req.QuestionType == models.QuestionTypeTrueFalse {
 		for _, optionReq := range req.Options {
 			option, err := h.questionService.AddOption(question.ID, optionReq.OptionText,
-				optionReq.IsCorrect, optionReq.OrderIndex)
+				optionReq.IsCorrect, optionReq.OrderIndex, optionReq.Feedback)
 			if err != nil {
 				utils.WriteErrorResponse(w, "Failed to create question option", http.StatusInternalServerError)
 				return
@@ -109,9 +164,9 @@ func (h *QuestionHandler) GetQuestion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	question, err := h.questionService.GetQuestion(questionID)
-	if err != nil {
-		utils.WriteErrorResponse(w, "Question not found", http.StatusNotFound)
+	// Questions include their answer keys, so only the test's owners may read them here
+	question, ok := h.authorizeQuestion(w, r, questionID)
+	if !ok {
 		return
 	}
 
@@ -132,6 +187,10 @@ func (h *QuestionHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if _, ok := h.authorizeQuestion(w, r, questionID); !ok {
+		return
+	}
+
 	var req struct {
 		QuestionText string `json:"question_text"`
 		Marks        int    `json:"marks"`
@@ -153,6 +212,84 @@ func (h *QuestionHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request)
 	utils.WriteSuccessResponse(w, question)
 }
 
+// UpdateFeedback handles replacing the explanation and answer feedback of a question
+func (h *QuestionHandler) UpdateFeedback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	questionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.authorizeQuestion(w, r, questionID); !ok {
+		return
+	}
+
+	var req UpdateFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !validFeedback(req.Explanation, req.CorrectFeedback, req.IncorrectFeedback) {
+		utils.WriteErrorResponse(w, "Explanation and feedback must be at most 5000 characters", http.StatusBadRequest)
+		return
+	}
+
+	question, err := h.questionService.UpdateFeedback(questionID, models.QuestionFeedback{
+		Explanation:       req.Explanation,
+		CorrectFeedback:   req.CorrectFeedback,
+		IncorrectFeedback: req.IncorrectFeedback,
+	})
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Question not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update question feedback", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, question)
+}
+
+// DeleteFeedback handles removing the explanation and answer feedback of a question
+func (h *QuestionHandler) DeleteFeedback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	questionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.authorizeQuestion(w, r, questionID); !ok {
+		return
+	}
+
+	if _, err := h.questionService.UpdateFeedback(questionID, models.QuestionFeedback{}); err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Question not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to delete question feedback", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
 // DeleteQuestion handles question deletion
 func (h *QuestionHandler) DeleteQuestion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -167,6 +304,10 @@ func (h *QuestionHandler) DeleteQuestion(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if _, ok := h.authorizeQuestion(w, r, questionID); !ok {
+		return
+	}
+
 	if err := h.questionService.DeleteQuestion(questionID); err != nil {
 		utils.WriteErrorResponse(w, "Failed to delete question", http.StatusInternalServerError)
 		return
@@ -189,14 +330,23 @@ func (h *QuestionHandler) AddOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.authorizeQuestion(w, r, questionID); !ok {
+		return
+	}
+
 	var req CreateOptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if !validFeedback(req.Feedback) {
+		utils.WriteErrorResponse(w, "Feedback must be at most 5000 characters", http.StatusBadRequest)
+		return
+	}
+
 	option, err := h.questionService.AddOption(questionID, req.OptionText,
-		req.IsCorrect, req.OrderIndex)
+		req.IsCorrect, req.OrderIndex, req.Feedback)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Failed to add option", http.StatusInternalServerError)
 		return
@@ -219,14 +369,23 @@ func (h *QuestionHandler) UpdateOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeOption(w, r, optionID) {
+		return
+	}
+
 	var req CreateOptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if !validFeedback(req.Feedback) {
+		utils.WriteErrorResponse(w, "Feedback must be at most 5000 characters", http.StatusBadRequest)
+		return
+	}
+
 	option, err := h.questionService.UpdateOption(optionID, req.OptionText,
-		req.IsCorrect, req.OrderIndex)
+		req.IsCorrect, req.OrderIndex, req.Feedback)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Failed to update option", http.StatusInternalServerError)
 		return
@@ -249,6 +408,10 @@ func (h *QuestionHandler) DeleteOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeOption(w, r, optionID) {
+		return
+	}
+
 	if err := h.questionService.DeleteOption(optionID); err != nil {
 		utils.WriteErrorResponse(w, "Failed to delete option", http.StatusInternalServerError)
 		return
@@ -271,6 +434,10 @@ func (h *QuestionHandler) AddCorrectAnswer(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if _, ok := h.authorizeQuestion(w, r, questionID); !ok {
+		return
+	}
+
 	var req CreateAnswerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
@@ -286,3 +453,48 @@ func (h *QuestionHandler) AddCorrectAnswer(w http.ResponseWriter, r *http.Reques
 
 	utils.WriteCreatedResponse(w, answer)
 }
+
+// authorizeQuestion loads a question and checks that the requesting user may edit its test
+func (h *QuestionHandler) authorizeQuestion(w http.ResponseWriter, r *http.Request, questionID int) (*models.Question, bool) {
+	question, err := h.questionService.GetQuestion(questionID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Question not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve question", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, question.TestID, 0, auth.ActionEditTest) {
+		return nil, false
+	}
+
+	return question, true
+}
+
+// authorizeOption checks that an option belongs to the question in the path and that the
+// requesting user may edit its test
+func (h *QuestionHandler) authorizeOption(w http.ResponseWriter, r *http.Request, optionID int) bool {
+	vars := mux.Vars(r)
+	questionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid question ID", http.StatusBadRequest)
+		return false
+	}
+
+	question, ok := h.authorizeQuestion(w, r, questionID)
+	if !ok {
+		return false
+	}
+
+	for _, option := range question.Options {
+		if option.ID == optionID {
+			return true
+		}
+	}
+
+	utils.WriteErrorResponse(w, "Option not found", http.StatusNotFound)
+	return false
+}
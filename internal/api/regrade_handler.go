@@ -0,0 +1,110 @@
+package api
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// RegradeHandler handles regrading requests
+type RegradeHandler struct {
+	regradeService  models.RegradeService
+	questionService models.QuestionService
+	testService     models.TestService
+	policy          *auth.Policy
+}
+
+// NewRegradeHandler creates a new regrade handler
+func NewRegradeHandler(regradeService models.RegradeService, questionService models.QuestionService, testService models.TestService, policy *auth.Policy) *RegradeHandler {
+	return &RegradeHandler{
+		regradeService:  regradeService,
+		questionService: questionService,
+		testService:     testService,
+		policy:          policy,
+	}
+}
+
+// RegradeTest handles rescoring all stored answers of a test
+func (h *RegradeHandler) RegradeTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who grade the test's results can regrade
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionGrade) {
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	report, err := h.regradeService.RegradeTest(testID, dryRun)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to regrade test", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, report)
+}
+
+// RegradeQuestion handles rescoring the stored answers of a single question
+func (h *RegradeHandler) RegradeQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	questionID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	question, err := h.questionService.GetQuestion(questionID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Question not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to regrade question", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Only staff who grade the test's results can regrade
+	if !authorizeTest(w, r, h.policy, h.testService, question.TestID, 0, auth.ActionGrade) {
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	report, err := h.regradeService.RegradeQuestion(questionID, dryRun)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Question not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to regrade question", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, report)
+}
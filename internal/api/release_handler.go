@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ReleaseHandler handles result release-related requests
+type ReleaseHandler struct {
+	releaseService models.ReleaseService
+	testService    models.TestService
+	policy         *auth.Policy
+}
+
+// NewReleaseHandler creates a new result release handler
+func NewReleaseHandler(releaseService models.ReleaseService, testService models.TestService, policy *auth.Policy) *ReleaseHandler {
+	return &ReleaseHandler{
+		releaseService: releaseService,
+		testService:    testService,
+		policy:         policy,
+	}
+}
+
+// UpdateReleaseSettingsRequest represents a result release settings update request
+type UpdateReleaseSettingsRequest struct {
+	ScoreRelease       models.ScoreReleasePolicy `json:"score_release"`
+	ShowCorrectAnswers bool                      `json:"show_correct_answers"`
+	ShowExplanations   bool                      `json:"show_explanations"`
+}
+
+// GetSettings handles getting the result release settings for a test
+func (h *ReleaseHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can view release settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	settings, err := h.releaseService.GetSettings(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get release settings", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
+
+// UpdateSettings handles creating or replacing the result release settings for a test
+func (h *ReleaseHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change release settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req UpdateReleaseSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.releaseService.UpdateSettings(testID, req.ScoreRelease, req.ShowCorrectAnswers, req.ShowExplanations)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidReleasePolicy:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update release settings", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
+
+// DeleteSettings handles resetting a test to the default release settings
+func (h *ReleaseHandler) DeleteSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change release settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	if err := h.releaseService.DeleteSettings(testID); err != nil {
+		utils.WriteErrorResponse(w, "Failed to delete release settings", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// ReleaseResults handles manually releasing the results of a test
+func (h *ReleaseHandler) ReleaseResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.setReleased(w, r, true)
+}
+
+// WithdrawResults handles withdrawing a manual release of the results of a test
+func (h *ReleaseHandler) WithdrawResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.setReleased(w, r, false)
+}
+
+// setReleased releases or withdraws the results of the test in the path
+func (h *ReleaseHandler) setReleased(w http.ResponseWriter, r *http.Request, release bool) {
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only staff who grade the test's results can release results
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionGrade) {
+		return
+	}
+
+	var settings *models.TestReleaseSettings
+	if release {
+		settings, err = h.releaseService.ReleaseResults(testID)
+	} else {
+		settings, err = h.releaseService.WithdrawResults(testID)
+	}
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update result release", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
@@ -12,13 +12,21 @@ import (
 
 // ResultHandler handles test result-related requests
 type ResultHandler struct {
-	resultService models.TestResultService
+	resultService  models.TestResultService
+	releaseService models.ReleaseService
+	testService    models.TestService
+	sessionService models.TestSessionService
+	policy         *auth.Policy
 }
 
 // NewResultHandler creates a new result handler
-func NewResultHandler(resultService models.TestResultService) *ResultHandler {
+func NewResultHandler(resultService models.TestResultService, releaseService models.ReleaseService, testService models.TestService, sessionService models.TestSessionService, policy *auth.Policy) *ResultHandler {
 	return &ResultHandler{
-		resultService: resultService,
+		resultService:  resultService,
+		releaseService: releaseService,
+		testService:    testService,
+		sessionService: sessionService,
+		policy:         policy,
 	}
 }
 
@@ -42,18 +50,16 @@ func (h *ResultHandler) GetResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user can access this result
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	// Candidates see their own results; the test's owners see every result
+	if !authorizeTest(w, r, h.policy, h.testService, result.TestID, result.UserID, auth.ActionViewResult) {
 		return
 	}
+	isStaff := canAccessTest(r, h.policy, h.testService, result.TestID, 0, auth.ActionViewResults)
 
-	if result.UserID != userID {
-		// Allow teachers/admins to view any result
-		userRole, ok := auth.GetUserRoleFromContext(r)
-		if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+	// Candidates only see scores their test has released
+	if !isStaff {
+		if err := h.releaseService.WithholdUnreleased(result); err != nil {
+			utils.WriteErrorResponse(w, "Failed to get result", http.StatusInternalServerError)
 			return
 		}
 	}
@@ -94,6 +100,19 @@ func (h *ResultHandler) GetUserResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Candidates only see scores their test has released, unless they may view all of
+	// the test's results anyway
+	var withheld []*models.TestResult
+	for _, result := range results {
+		if !canAccessTest(r, h.policy, h.testService, result.TestID, 0, auth.ActionViewResults) {
+			withheld = append(withheld, result)
+		}
+	}
+	if err := h.releaseService.WithholdUnreleased(withheld...); err != nil {
+		utils.WriteErrorResponse(w, "Failed to get user results", http.StatusInternalServerError)
+		return
+	}
+
 	utils.WriteSuccessResponse(w, results)
 }
 
@@ -111,10 +130,8 @@ func (h *ResultHandler) GetTestResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only teachers and admins can view test results
-	userRole, ok := auth.GetUserRoleFromContext(r)
-	if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+	// Only the test's owners and admins can view test results
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
 		return
 	}
 
@@ -155,10 +172,8 @@ func (h *ResultHandler) GetTestStatistics(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Only teachers and admins can view test statistics
-	userRole, ok := auth.GetUserRoleFromContext(r)
-	if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+	// Only the test's owners and admins can view test statistics
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionViewResults) {
 		return
 	}
 
@@ -185,16 +200,32 @@ func (h *ResultHandler) CalculateResult(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Only teachers and admins can manually calculate results
-	userRole, ok := auth.GetUserRoleFromContext(r)
-	if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+	session, err := h.sessionService.GetSessionByID(sessionID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Session not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to retrieve session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Only the test's owners and admins can manually calculate results
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, 0, auth.ActionGrade) {
 		return
 	}
 
 	result, err := h.resultService.CalculateResult(sessionID)
 	if err != nil {
-		utils.WriteErrorResponse(w, "Failed to calculate result", http.StatusInternalServerError)
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Session not found", http.StatusNotFound)
+		case auth.ErrPracticeTest:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to calculate result", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -221,21 +252,61 @@ func (h *ResultHandler) GetResultBySession(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Check if user can access this result
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	// Candidates see their own results; the test's owners see every result
+	if !authorizeTest(w, r, h.policy, h.testService, result.TestID, result.UserID, auth.ActionViewResult) {
 		return
 	}
+	isStaff := canAccessTest(r, h.policy, h.testService, result.TestID, 0, auth.ActionViewResults)
 
-	if result.UserID != userID {
-		// Allow teachers/admins to view any result
-		userRole, ok := auth.GetUserRoleFromContext(r)
-		if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+	// Candidates only see scores their test has released
+	if !isStaff {
+		if err := h.releaseService.WithholdUnreleased(result); err != nil {
+			utils.WriteErrorResponse(w, "Failed to get result", http.StatusInternalServerError)
 			return
 		}
 	}
 
 	utils.WriteSuccessResponse(w, result)
 }
+
+// GetReview handles getting a candidate's answers next to the correct ones
+func (h *ResultHandler) GetReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	resultID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid result ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.resultService.GetResult(resultID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		return
+	}
+
+	// Candidates review their own results; the test's owners review every result
+	if !authorizeTest(w, r, h.policy, h.testService, result.TestID, result.UserID, auth.ActionViewResult) {
+		return
+	}
+	isStaff := canAccessTest(r, h.policy, h.testService, result.TestID, 0, auth.ActionViewResults)
+
+	review, err := h.releaseService.GetReview(resultID, isStaff)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Result not found", http.StatusNotFound)
+		case auth.ErrResultNotReleased:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusForbidden)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get result review", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, review)
+}
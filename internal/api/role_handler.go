@@ -0,0 +1,317 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// RoleHandler handles role and role assignment requests
+type RoleHandler struct {
+	roleService models.RoleService
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(roleService models.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+	}
+}
+
+// RoleRequest represents a role creation or update request
+type RoleRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// UserRolesRequest represents a request to set the roles assigned to a user
+type UserRolesRequest struct {
+	RoleIDs []int `json:"role_ids"`
+}
+
+// RoleTwoFactorRequest represents a request to make two-factor authentication mandatory
+// for a role's users
+type RoleTwoFactorRequest struct {
+	Required bool `json:"required"`
+}
+
+// validate sanitizes and validates the role description
+func (req *RoleRequest) validate() string {
+	req.Description = utils.SanitizeHTML(utils.SanitizeString(req.Description))
+
+	if !utils.ValidateTextLength(req.Description, 0, 500) {
+		return "Role description must be 0-500 characters"
+	}
+	return ""
+}
+
+// ListPermissions handles listing every permission a role can be built from
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, models.AllPermissions)
+}
+
+// ListRoles handles listing built-in and institution roles
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	roles, err := h.roleService.ListRoles()
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to list roles", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, roles)
+}
+
+// CreateRole handles institution role creation
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleService.CreateRole(req.Name, req.Description, req.Permissions)
+	if err != nil {
+		writeRoleError(w, err, "Failed to create role")
+		return
+	}
+
+	utils.WriteCreatedResponse(w, role)
+}
+
+// GetRole handles getting a role with its permissions
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleService.GetRole(roleID)
+	if err != nil {
+		writeRoleError(w, err, "Failed to get role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, role)
+}
+
+// UpdateRole handles renaming an institution role and replacing its permissions
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		utils.WriteErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(roleID, req.Name, req.Description, req.Permissions)
+	if err != nil {
+		writeRoleError(w, err, "Failed to update role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, role)
+}
+
+// DeleteRole handles institution role deletion
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.roleService.DeleteRole(roleID); err != nil {
+		writeRoleError(w, err, "Failed to delete role")
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// SetTwoFactorRequired handles making two-factor authentication mandatory, or optional
+// again, for a role's users
+func (h *RoleHandler) SetTwoFactorRequired(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roleID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	var req RoleTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleService.SetTwoFactorRequired(roleID, req.Required)
+	if err != nil {
+		writeRoleError(w, err, "Failed to update role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, role)
+}
+
+// GetUserRoles handles getting the roles assigned to a user and their permissions
+func (h *RoleHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userRoles, err := h.roleService.GetUserRoles(userID)
+	if err != nil {
+		if err == auth.ErrUserNotFound {
+			utils.WriteErrorResponse(w, "User not found", http.StatusNotFound)
+			return
+		}
+		utils.WriteErrorResponse(w, "Failed to get user roles", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, userRoles)
+}
+
+// SetUserRoles handles replacing the institution roles assigned to a user
+func (h *RoleHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UserRolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userRoles, err := h.roleService.SetUserRoles(userID, req.RoleIDs)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "User not found", http.StatusNotFound)
+		case auth.ErrInvalidRoleAssignment:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to set user roles", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, userRoles)
+}
+
+// GetMyPermissions handles getting the permissions of the authenticated user
+func (h *RoleHandler) GetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userRoles, err := h.roleService.GetUserRoles(userID)
+	if err != nil {
+		if err == auth.ErrUserNotFound {
+			utils.WriteErrorResponse(w, "User not found", http.StatusNotFound)
+			return
+		}
+		utils.WriteErrorResponse(w, "Failed to get permissions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, userRoles)
+}
+
+// writeRoleError maps role service errors to responses
+func writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case auth.ErrUserNotFound:
+		utils.WriteErrorResponse(w, "Role not found", http.StatusNotFound)
+	case auth.ErrInvalidRoleName, auth.ErrInvalidPermission:
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case auth.ErrRoleExists, auth.ErrSystemRole:
+		utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+	default:
+		utils.WriteErrorResponse(w, fallback, http.StatusInternalServerError)
+	}
+}
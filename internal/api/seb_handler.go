@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// SEBHandler handles Safe Exam Browser-related requests
+type SEBHandler struct {
+	sebService  models.SEBService
+	testService models.TestService
+	policy      *auth.Policy
+}
+
+// NewSEBHandler creates a new Safe Exam Browser handler
+func NewSEBHandler(sebService models.SEBService, testService models.TestService, policy *auth.Policy) *SEBHandler {
+	return &SEBHandler{
+		sebService:  sebService,
+		testService: testService,
+		policy:      policy,
+	}
+}
+
+// UpdateSEBSettingsRequest represents a Safe Exam Browser settings update request
+type UpdateSEBSettingsRequest struct {
+	Enabled         bool     `json:"enabled"`
+	ConfigKeys      []string `json:"config_keys"`
+	BrowserExamKeys []string `json:"browser_exam_keys"`
+	QuitPassword    *string  `json:"quit_password,omitempty"`
+}
+
+// GetSettings handles getting the Safe Exam Browser settings for a test
+func (h *SEBHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can view SEB settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	settings, err := h.sebService.GetSettings(testID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Safe Exam Browser settings not found", http.StatusNotFound)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
+
+// UpdateSettings handles creating or replacing the Safe Exam Browser settings for a test
+func (h *SEBHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change SEB settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	var req UpdateSEBSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.QuitPassword != nil && !utils.ValidateTextLength(*req.QuitPassword, 0, 128) {
+		utils.WriteErrorResponse(w, "Quit password must be 0-128 characters", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.sebService.UpdateSettings(testID, req.Enabled, req.ConfigKeys,
+		req.BrowserExamKeys, req.QuitPassword)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidSEBKeyValue:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update Safe Exam Browser settings", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, settings)
+}
+
+// DeleteSettings handles removing the Safe Exam Browser requirement from a test
+func (h *SEBHandler) DeleteSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	// Only the test's authors can change SEB settings
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	if err := h.sebService.DeleteSettings(testID); err != nil {
+		utils.WriteErrorResponse(w, "Failed to delete Safe Exam Browser settings", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// DownloadConfig handles downloading the .seb configuration file for a test
+func (h *SEBHandler) DownloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	config, err := h.sebService.GenerateConfig(testID)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		default:
+			utils.WriteErrorResponse(w, "Failed to generate Safe Exam Browser configuration", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/seb")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"test-%d.seb\"", testID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(config)
+}
@@ -15,18 +15,25 @@ import (
 // SessionHandler handles test session-related requests
 type SessionHandler struct {
 	sessionService models.TestSessionService
+	releaseService models.ReleaseService
+	testService    models.TestService
+	policy         *auth.Policy
 }
 
 // NewSessionHandler creates a new session handler
-func NewSessionHandler(sessionService models.TestSessionService) *SessionHandler {
+func NewSessionHandler(sessionService models.TestSessionService, releaseService models.ReleaseService, testService models.TestService, policy *auth.Policy) *SessionHandler {
 	return &SessionHandler{
 		sessionService: sessionService,
+		releaseService: releaseService,
+		testService:    testService,
+		policy:         policy,
 	}
 }
 
 // StartSessionRequest represents a session start request
 type StartSessionRequest struct {
-	TestID int `json:"test_id"`
+	TestID     int    `json:"test_id"`
+	AccessCode string `json:"access_code,omitempty"`
 }
 
 // SubmitAnswerRequest represents an answer submission request
@@ -66,12 +73,30 @@ func (h *SessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, err := h.sessionService.StartSession(userID, req.TestID)
+	session, err := h.sessionService.StartSession(userID, req.TestID, req.AccessCode, utils.ClientIP(r))
 	if err != nil {
-		utils.WriteErrorResponse(w, "Failed to start session", http.StatusInternalServerError)
+		switch err {
+		case auth.ErrAccessCodeRequired, auth.ErrInvalidAccessCode, auth.ErrIPNotAllowed, auth.ErrTestNotAssigned:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusForbidden)
+		case auth.ErrAdaptivePoolNotCalibrated:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+		default:
+			utils.WriteErrorResponse(w, "Failed to start session", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	// A resumed adaptive session carries the steps answered so far
+	if session.Adaptive != nil {
+		visible, ok := h.answerScoresVisible(w, r, session)
+		if !ok {
+			return
+		}
+		if !visible {
+			session.Adaptive.Withhold()
+		}
+	}
+
 	response := &SessionResponse{
 		TestSession:   session,
 		RemainingTime: session.GetRemainingTime(),
@@ -96,22 +121,11 @@ func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user owns this session
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	// Candidates see their own sessions; the test's owners see every session
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, session.UserID, auth.ActionViewResult) {
 		return
 	}
 
-	if session.UserID != userID {
-		// Allow teachers/admins to view any session
-		userRole, ok := auth.GetUserRoleFromContext(r)
-		if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
-			return
-		}
-	}
-
 	response := &SessionResponse{
 		TestSession:   session,
 		RemainingTime: session.GetRemainingTime(),
@@ -173,23 +187,35 @@ func (h *SessionHandler) SubmitAnswer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok || session.UserID != userID {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, session.UserID, auth.ActionTakeTest) {
 		return
 	}
 
 	answer, err := h.sessionService.SubmitAnswer(sessionToken, req.QuestionID, req.AnswerText, req.SelectedOptionID)
 	if err != nil {
-		utils.WriteErrorResponse(w, fmt.Sprintf("Failed to submit answer: %v", err), http.StatusInternalServerError)
+		switch err {
+		case auth.ErrNotCurrentQuestion:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+		default:
+			utils.WriteErrorResponse(w, fmt.Sprintf("Failed to submit answer: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	visible, ok := h.answerScoresVisible(w, r, session)
+	if !ok {
 		return
 	}
+	if !visible {
+		answer.Withhold()
+	}
 
 	utils.WriteSuccessResponse(w, answer)
 }
 
-// GetSessionAnswers handles getting all answers for a session
-func (h *SessionHandler) GetSessionAnswers(w http.ResponseWriter, r *http.Request) {
+// GetAdaptiveState handles getting the question sequence, ability trajectory and
+// current question of an adaptive session
+func (h *SessionHandler) GetAdaptiveState(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -198,26 +224,58 @@ func (h *SessionHandler) GetSessionAnswers(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	sessionToken := vars["token"]
 
-	// Verify user owns this session or is teacher/admin
+	// Verify user owns this session or owns the test
 	session, err := h.sessionService.GetSession(sessionToken)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Session not found", http.StatusNotFound)
 		return
 	}
 
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, session.UserID, auth.ActionViewResult) {
 		return
 	}
 
-	if session.UserID != userID {
-		// Allow teachers/admins to view any session answers
-		userRole, ok := auth.GetUserRoleFromContext(r)
-		if !ok || (userRole != models.RoleTeacher && userRole != models.RoleAdmin) {
-			utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
-			return
+	state, err := h.sessionService.GetAdaptiveState(sessionToken)
+	if err != nil {
+		switch err {
+		case auth.ErrNotAdaptiveTest:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to get adaptive state", http.StatusInternalServerError)
 		}
+		return
+	}
+
+	visible, ok := h.answerScoresVisible(w, r, session)
+	if !ok {
+		return
+	}
+	if !visible {
+		state.Withhold()
+	}
+
+	utils.WriteSuccessResponse(w, state)
+}
+
+// GetSessionAnswers handles getting all answers for a session
+func (h *SessionHandler) GetSessionAnswers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	sessionToken := vars["token"]
+
+	// Verify user owns this session or owns the test
+	session, err := h.sessionService.GetSession(sessionToken)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, session.UserID, auth.ActionViewResult) {
+		return
 	}
 
 	answers, err := h.sessionService.GetSessionAnswers(sessionToken)
@@ -226,6 +284,16 @@ func (h *SessionHandler) GetSessionAnswers(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	visible, ok := h.answerScoresVisible(w, r, session)
+	if !ok {
+		return
+	}
+	if !visible {
+		for _, answer := range answers {
+			answer.Withhold()
+		}
+	}
+
 	utils.WriteSuccessResponse(w, answers)
 }
 
@@ -246,9 +314,7 @@ func (h *SessionHandler) SubmitSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok || session.UserID != userID {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, session.UserID, auth.ActionTakeTest) {
 		return
 	}
 
@@ -284,9 +350,7 @@ func (h *SessionHandler) UpdateProgress(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	userID, ok := auth.GetUserIDFromContext(r)
-	if !ok || session.UserID != userID {
-		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+	if !authorizeTest(w, r, h.policy, h.testService, session.TestID, session.UserID, auth.ActionTakeTest) {
 		return
 	}
 
@@ -333,3 +397,19 @@ func (h *SessionHandler) GetUserSessions(w http.ResponseWriter, r *http.Request)
 
 	utils.WriteSuccessResponse(w, sessions)
 }
+
+// answerScoresVisible reports whether the caller may see which of a session's answers were
+// correct: staff who view the test's results always can, candidates once the test's release
+// settings allow it. It writes the error response and returns false if the check fails.
+func (h *SessionHandler) answerScoresVisible(w http.ResponseWriter, r *http.Request, session *models.TestSession) (bool, bool) {
+	if canAccessTest(r, h.policy, h.testService, session.TestID, 0, auth.ActionViewResults) {
+		return true, true
+	}
+
+	visible, err := h.releaseService.AnswerScoresVisible(session)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to check result release", http.StatusInternalServerError)
+		return false, false
+	}
+	return visible, true
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resumingSessionService returns the candidate's adaptive session in progress from
+// StartSession, as when a candidate comes back to it
+type resumingSessionService struct {
+	models.TestSessionService
+	session *models.TestSession
+}
+
+func (s *resumingSessionService) StartSession(userID, testID int, accessCode, clientIP string) (*models.TestSession, error) {
+	return s.session, nil
+}
+
+// fixedReleaseService reports whether answer scores are released
+type fixedReleaseService struct {
+	models.ReleaseService
+	visible bool
+}
+
+func (s *fixedReleaseService) AnswerScoresVisible(session *models.TestSession) (bool, error) {
+	return s.visible, nil
+}
+
+// ownedTestService reports every test as owned by the same teacher
+type ownedTestService struct {
+	models.TestService
+	ownerID int
+}
+
+func (s *ownedTestService) GetOwnership(testID int) (*models.TestOwnership, error) {
+	return &models.TestOwnership{TestID: testID, OwnerID: s.ownerID}, nil
+}
+
+// resumedAdaptiveSession builds a session of candidate 5 with one answered adaptive step
+func resumedAdaptiveSession() *models.TestSession {
+	correct, theta, thetaSE := true, 0.6055, 0.839
+	return &models.TestSession{
+		ID:        2,
+		TestID:    1,
+		UserID:    5,
+		Status:    models.SessionStatusInProgress,
+		ExpiresAt: time.Now().Add(time.Hour),
+		Adaptive: &models.AdaptiveState{
+			SessionID: 2,
+			Steps: []*models.AdaptiveStep{
+				{StepNumber: 1, QuestionID: 5, IsCorrect: &correct, Theta: &theta, ThetaSE: &thetaSE},
+				{StepNumber: 2, QuestionID: 6},
+			},
+			Theta:   theta,
+			ThetaSE: thetaSE,
+		},
+	}
+}
+
+func TestStartSessionWithholdsResumedAdaptiveState(t *testing.T) {
+	tests := []struct {
+		name     string
+		released bool
+		withheld bool
+	}{
+		{"unreleased", false, true},
+		{"released", true, false},
+	}
+
+	for _, test := range tests {
+		handler := NewSessionHandler(
+			&resumingSessionService{session: resumedAdaptiveSession()},
+			&fixedReleaseService{visible: test.released},
+			&ownedTestService{ownerID: 1},
+			auth.NewPolicy(),
+		)
+
+		r := httptest.NewRequest(http.MethodPost, "/sessions/start", strings.NewReader(`{"test_id": 1}`))
+		r = r.WithContext(context.WithValue(r.Context(), auth.ClaimsContextKey, &auth.Claims{UserID: 5, Role: models.RoleStudent}))
+		w := httptest.NewRecorder()
+		handler.StartSession(w, r)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("StartSession(%s) status = %d, expected %d", test.name, w.Code, http.StatusCreated)
+		}
+
+		var response struct {
+			Data struct {
+				Adaptive *models.AdaptiveState `json:"adaptive"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("StartSession(%s) response error = %v", test.name, err)
+		}
+
+		state := response.Data.Adaptive
+		step := state.Steps[0]
+		hidden := step.IsCorrect == nil && step.Theta == nil && step.ThetaSE == nil && state.Theta == 0 && state.ThetaSE == 0
+		if hidden != test.withheld || state.ScoreWithheld != test.withheld {
+			t.Errorf("StartSession(%s) adaptive = %+v, step 1 = %+v, expected withheld %v", test.name, *state, *step, test.withheld)
+		}
+	}
+}
@@ -16,26 +16,29 @@ import (
 type TestHandler struct {
 	testService     models.TestService
 	questionService models.QuestionService
+	policy          *auth.Policy
 }
 
 // NewTestHandler creates a new test handler
-func NewTestHandler(testService models.TestService, questionService models.QuestionService) *TestHandler {
+func NewTestHandler(testService models.TestService, questionService models.QuestionService, policy *auth.Policy) *TestHandler {
 	return &TestHandler{
 		testService:     testService,
 		questionService: questionService,
+		policy:          policy,
 	}
 }
 
 // CreateTestRequest represents a test creation request
 type CreateTestRequest struct {
-	Title           string     `json:"title"`
-	Description     string     `json:"description"`
-	Instructions    string     `json:"instructions"`
-	DurationMinutes int        `json:"duration_minutes"`
-	TotalMarks      int        `json:"total_marks"`
-	PassingMarks    int        `json:"passing_marks"`
-	StartTime       *time.Time `json:"start_time"`
-	EndTime         *time.Time `json:"end_time"`
+	Title           string          `json:"title"`
+	Description     string          `json:"description"`
+	Instructions    string          `json:"instructions"`
+	DurationMinutes int             `json:"duration_minutes"`
+	TotalMarks      int             `json:"total_marks"`
+	PassingMarks    int             `json:"passing_marks"`
+	StartTime       *time.Time      `json:"start_time"`
+	EndTime         *time.Time      `json:"end_time"`
+	Mode            models.TestMode `json:"mode,omitempty"` // graded (default), practice or adaptive
 }
 
 // CreateTest handles test creation
@@ -45,12 +48,17 @@ func (h *TestHandler) CreateTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := auth.GetUserIDFromContext(r)
+	principal, ok := auth.PrincipalFromRequest(r)
 	if !ok {
 		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	if !h.policy.Can(principal, auth.ActionCreateTest, auth.Resource{}) {
+		utils.WriteErrorResponse(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	var req CreateTestRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
@@ -101,6 +109,12 @@ func (h *TestHandler) CreateTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate mode
+	if req.Mode != "" && !req.Mode.IsValid() {
+		utils.WriteErrorResponse(w, "Test mode must be graded, practice or adaptive", http.StatusBadRequest)
+		return
+	}
+
 	// Check for SQL injection patterns
 	if !utils.ValidateNoSQLInjection(req.Title) || !utils.ValidateNoSQLInjection(req.Description) ||
 		!utils.ValidateNoSQLInjection(req.Instructions) {
@@ -108,9 +122,9 @@ func (h *TestHandler) CreateTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	test, err := h.testService.CreateTest(userID, req.Title, req.Description,
+	test, err := h.testService.CreateTest(principal.UserID, req.Title, req.Description,
 		req.Instructions, req.DurationMinutes, req.TotalMarks, req.PassingMarks,
-		req.StartTime, req.EndTime)
+		req.StartTime, req.EndTime, req.Mode)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Failed to create test", http.StatusInternalServerError)
 		return
@@ -156,6 +170,10 @@ func (h *TestHandler) UpdateTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
 	var req CreateTestRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
@@ -164,7 +182,7 @@ func (h *TestHandler) UpdateTest(w http.ResponseWriter, r *http.Request) {
 
 	test, err := h.testService.UpdateTest(testID, req.Title, req.Description,
 		req.Instructions, req.DurationMinutes, req.TotalMarks, req.PassingMarks,
-		req.StartTime, req.EndTime)
+		req.StartTime, req.EndTime, req.Mode)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Failed to update test", http.StatusInternalServerError)
 		return
@@ -187,6 +205,10 @@ func (h *TestHandler) DeleteTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionDeleteTest) {
+		return
+	}
+
 	if err := h.testService.DeleteTest(testID); err != nil {
 		utils.WriteErrorResponse(w, "Failed to delete test", http.StatusInternalServerError)
 		return
@@ -283,11 +305,104 @@ func (h *TestHandler) GetTestQuestions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	test, err := h.testService.GetTest(testID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		return
+	}
+
 	questions, err := h.questionService.GetTestQuestions(testID)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Failed to get test questions", http.StatusInternalServerError)
 		return
 	}
 
+	// Questions include their answer keys, so everyone but the test's owners gets them
+	// as candidates see them
+	if !canAccessTest(r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		// Adaptive tests hand out one question at a time; the pool stays hidden
+		if test.IsAdaptive() {
+			questions = nil
+		}
+		views := make([]*models.Question, 0, len(questions))
+		for _, question := range questions {
+			views = append(views, question.CandidateView())
+		}
+		utils.WriteSuccessResponse(w, views)
+		return
+	}
+
 	utils.WriteSuccessResponse(w, questions)
 }
+
+// CoOwnersRequest represents a request to replace the co-owners of a test
+type CoOwnersRequest struct {
+	UserIDs []int `json:"user_ids"`
+}
+
+// GetCoOwners handles getting the creator and co-owners of a test
+func (h *TestHandler) GetCoOwners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionEditTest) {
+		return
+	}
+
+	ownership, err := h.testService.GetOwnership(testID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to get co-owners", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, ownership)
+}
+
+// SetCoOwners handles replacing the co-owners of a test
+func (h *TestHandler) SetCoOwners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	testID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid test ID", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeTest(w, r, h.policy, h.testService, testID, 0, auth.ActionManageCoOwners) {
+		return
+	}
+
+	var req CoOwnersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ownership, err := h.testService.SetCoOwners(testID, req.UserIDs)
+	if err != nil {
+		switch err {
+		case auth.ErrUserNotFound:
+			utils.WriteErrorResponse(w, "Test not found", http.StatusNotFound)
+		case auth.ErrInvalidCoOwner:
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		default:
+			utils.WriteErrorResponse(w, "Failed to update co-owners", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, ownership)
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// TwoFactorHandler handles two-factor authentication settings requests
+type TwoFactorHandler struct {
+	twoFactorService models.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a new two-factor handler
+func NewTwoFactorHandler(twoFactorService models.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP code or a recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse represents newly generated recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// GetStatus handles getting the authenticated user's two-factor settings
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.twoFactorService.GetStatus(userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Failed to get two-factor settings", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, status)
+}
+
+// StartEnrolment handles generating an authenticator secret and its provisioning URI
+func (h *TwoFactorHandler) StartEnrolment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrolment, err := h.twoFactorService.StartEnrolment(userID)
+	if err != nil {
+		writeTwoFactorError(w, err, "Failed to start two-factor enrolment")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, enrolment)
+}
+
+// ConfirmEnrolment handles enabling two-factor authentication with a code from the new
+// authenticator. The response carries the recovery codes.
+func (h *TwoFactorHandler) ConfirmEnrolment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactorService.ConfirmEnrolment(userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes handles replacing the authenticated user's recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err, "Failed to generate recovery codes")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable handles turning off two-factor authentication for the authenticated user
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := auth.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.twoFactorService.Disable(userID, req.Code); err != nil {
+		writeTwoFactorError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// ResetUser handles removing a user's authenticator, for a user who lost both it and
+// their recovery codes
+func (h *TwoFactorHandler) ResetUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.twoFactorService.Reset(userID); err != nil {
+		writeTwoFactorError(w, err, "Failed to reset two-factor authentication")
+		return
+	}
+
+	utils.WriteNoContentResponse(w)
+}
+
+// writeTwoFactorError maps two-factor service errors to responses
+func writeTwoFactorError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case auth.ErrUserNotFound:
+		utils.WriteErrorResponse(w, "User not found", http.StatusNotFound)
+	case auth.ErrInvalidTwoFactorCode, auth.ErrTwoFactorNotEnrolled:
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case auth.ErrTwoFactorAlreadyEnabled, auth.ErrTwoFactorNotEnabled, auth.ErrTwoFactorMandatory:
+		utils.WriteErrorResponse(w, err.Error(), http.StatusConflict)
+	default:
+		utils.WriteErrorResponse(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gocbt/internal/config"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// accountTokenIssuer tells account tokens apart from access tokens, which are signed with
+// other keys anyway
+const accountTokenIssuer = "gocbt-account"
+
+// AccountTokenClaims are the contents of a signed password reset or email verification token
+type AccountTokenClaims struct {
+	Purpose string `json:"purpose"`
+	UserID  int    `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// AccountTokenSigner signs the tokens of password reset and email verification links with
+// an HMAC key of their own, so a leaked link cannot be altered to act on another account
+// or used as an access token
+type AccountTokenSigner struct {
+	key []byte
+}
+
+// LoadAccountTokenSigner loads the account token key from the configured file. When the
+// file does not exist and create is true, a new key is generated and saved there.
+func LoadAccountTokenSigner(cfg *config.AccountConfig, create bool) (*AccountTokenSigner, error) {
+	data, err := os.ReadFile(cfg.TokenKeyFile)
+	if errors.Is(err, os.ErrNotExist) && create {
+		return createAccountTokenKey(cfg.TokenKeyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) < 32 {
+		return nil, fmt.Errorf("%s does not contain a hex key of at least 32 bytes", cfg.TokenKeyFile)
+	}
+
+	return NewAccountTokenSigner(key), nil
+}
+
+// createAccountTokenKey generates a key and saves it readable by the owner only
+func createAccountTokenKey(path string) (*AccountTokenSigner, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+
+	return NewAccountTokenSigner(key), nil
+}
+
+// NewAccountTokenSigner creates a signer for a key
+func NewAccountTokenSigner(key []byte) *AccountTokenSigner {
+	return &AccountTokenSigner{key: key}
+}
+
+// Sign returns a token for a purpose and user that expires at the given time. The
+// returned claims carry the token's ID (jti), under which its use is recorded.
+func (s *AccountTokenSigner) Sign(purpose string, userID int, expiresAt time.Time) (string, *AccountTokenClaims, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &AccountTokenClaims{
+		Purpose: purpose,
+		UserID:  userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    accountTokenIssuer,
+			Subject:   fmt.Sprintf("user:%d", userID),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.key)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// Verify checks a token's signature, expiry and purpose and returns its claims
+func (s *AccountTokenSigner) Verify(tokenString, purpose string) (*AccountTokenClaims, error) {
+	if tokenString == "" || len(tokenString) > 1024 {
+		return nil, ErrInvalidAccountToken
+	}
+
+	claims := &AccountTokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.key, nil
+	}, jwt.WithValidMethods([]string{AlgorithmHS256}), jwt.WithIssuer(accountTokenIssuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidAccountToken
+	}
+
+	// A verification link must not be usable to reset a password, and the other way round
+	if claims.Purpose != purpose || claims.ID == "" || claims.UserID == 0 {
+		return nil, ErrInvalidAccountToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"gocbt/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccountTokenSigner(t *testing.T) {
+	signer := NewAccountTokenSigner([]byte("account-token-test-key-of-32-bytes"))
+
+	token, claims, err := signer.Sign("password_reset", 7, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	verified, err := signer.Verify(token, "password_reset")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if verified.ID != claims.ID || verified.UserID != 7 {
+		t.Errorf("Verify() = %s for user %d, expected %s for user 7", verified.ID, verified.UserID, claims.ID)
+	}
+
+	if _, err := signer.Verify(token, "email_verification"); err != ErrInvalidAccountToken {
+		t.Errorf("Verify() for another purpose error = %v, expected %v", err, ErrInvalidAccountToken)
+	}
+
+	other := NewAccountTokenSigner([]byte("another-account-token-key-32-bytes"))
+	if _, err := other.Verify(token, "password_reset"); err != ErrInvalidAccountToken {
+		t.Errorf("Verify() with another key error = %v, expected %v", err, ErrInvalidAccountToken)
+	}
+
+	expired, _, err := signer.Sign("password_reset", 7, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := signer.Verify(expired, "password_reset"); err != ErrInvalidAccountToken {
+		t.Errorf("Verify() of an expired token error = %v, expected %v", err, ErrInvalidAccountToken)
+	}
+}
+
+func TestLoadAccountTokenSigner(t *testing.T) {
+	cfg := &config.AccountConfig{TokenKeyFile: filepath.Join(t.TempDir(), "account_token.key")}
+
+	if _, err := LoadAccountTokenSigner(cfg, false); err == nil {
+		t.Error("LoadAccountTokenSigner() without a key file succeeded, expected an error")
+	}
+
+	created, err := LoadAccountTokenSigner(cfg, true)
+	if err != nil {
+		t.Fatalf("LoadAccountTokenSigner() error = %v", err)
+	}
+	info, err := os.Stat(cfg.TokenKeyFile)
+	if err != nil {
+		t.Fatalf("key file was not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, expected 0600", info.Mode().Perm())
+	}
+
+	// Tokens signed before a restart still verify with the saved key
+	token, _, err := created.Sign("email_verification", 3, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	loaded, err := LoadAccountTokenSigner(cfg, true)
+	if err != nil {
+		t.Fatalf("LoadAccountTokenSigner() error = %v", err)
+	}
+	if _, err := loaded.Verify(token, "email_verification"); err != nil {
+		t.Errorf("Verify() with the loaded key error = %v", err)
+	}
+}
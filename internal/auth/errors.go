@@ -20,3 +20,136 @@ var (
 	ErrUsernameExists           = errors.New("username already exists")
 	ErrEmailExists              = errors.New("email already exists")
 )
+
+// Token errors
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or has expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has been revoked; sign in again")
+)
+
+// Single sign-on errors
+var (
+	ErrOIDCNotConfigured    = errors.New("single sign-on is not configured")
+	ErrInvalidOIDCState     = errors.New("single sign-on request is invalid or has expired; start again")
+	ErrOIDCLoginFailed      = errors.New("identity provider sign-in failed")
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not confirm a verified email address")
+	ErrOIDCDomainNotAllowed = errors.New("accounts of this email domain cannot sign in")
+	ErrOIDCAccountNotFound  = errors.New("no account is linked to this identity")
+)
+
+// Directory errors
+var (
+	ErrDirectoryUnavailable     = errors.New("directory server is unavailable")
+	ErrDirectoryAccountConflict = errors.New("an account with this username already exists and is not linked to the directory")
+	ErrDirectoryAccountNotFound = errors.New("no account is linked to this directory user")
+)
+
+// Two-factor errors
+var (
+	ErrInvalidTwoFactorChallenge = errors.New("two-factor sign-in is invalid or has expired; sign in again")
+	ErrInvalidTwoFactorCode      = errors.New("invalid two-factor code")
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorMandatory        = errors.New("two-factor authentication is required for your role and cannot be turned off")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled      = errors.New("start two-factor enrolment first")
+	ErrTwoFactorRequired         = errors.New("two-factor authentication is required for your role; sign in again")
+)
+
+// Account recovery errors
+var (
+	ErrInvalidAccountToken  = errors.New("link is invalid or has expired; request a new one")
+	ErrTooManyAccountEmails = errors.New("too many emails requested; try again later")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+	ErrTooManyLoginAttempts = errors.New("too many failed sign-in attempts; try again later")
+)
+
+// Exam access errors
+var (
+	ErrAccessCodeRequired = errors.New("access code is required for this test")
+	ErrInvalidAccessCode  = errors.New("invalid access code")
+	ErrIPNotAllowed       = errors.New("test cannot be started from this network")
+	ErrInvalidCIDR        = errors.New("invalid CIDR block")
+	ErrSEBRequired        = errors.New("this test must be taken in Safe Exam Browser")
+	ErrInvalidSEBKey      = errors.New("safe exam browser configuration is not allowed for this test")
+	ErrInvalidSEBKeyValue = errors.New("safe exam browser keys must be 64 hexadecimal characters")
+)
+
+// Grading scale errors
+var (
+	ErrInvalidGradingScale    = errors.New("grading scale bands are invalid")
+	ErrGradingScaleNotForTest = errors.New("grading scale belongs to another test")
+)
+
+// Score override errors
+var (
+	ErrInvalidOverrideMarks = errors.New("override marks are out of range")
+	ErrInvalidReasonCode    = errors.New("invalid override reason code")
+	ErrOverrideRevoked      = errors.New("override has already been revoked")
+)
+
+// Result release errors
+var (
+	ErrInvalidReleasePolicy = errors.New("invalid score release policy")
+	ErrResultNotReleased    = errors.New("results for this test have not been released yet")
+)
+
+// Practice mode errors
+var (
+	ErrPracticeTest    = errors.New("practice tests do not have graded results")
+	ErrNotPracticeTest = errors.New("test is not a practice test")
+)
+
+// IRT calibration errors
+var (
+	ErrInvalidIRTModel    = errors.New("IRT model must be rasch or 2pl")
+	ErrNotEnoughResponses = errors.New("not enough graded results to calibrate the test")
+)
+
+// Adaptive testing errors
+var (
+	ErrNotAdaptiveTest           = errors.New("test is not an adaptive test")
+	ErrInvalidAdaptiveSettings   = errors.New("adaptive settings are invalid")
+	ErrAdaptivePoolNotCalibrated = errors.New("adaptive test has no calibrated questions")
+	ErrNotCurrentQuestion        = errors.New("only the current adaptive question can be answered")
+	ErrAdaptiveCalibration       = errors.New("adaptive tests cannot be calibrated from their own results, calibrate them from a pilot test")
+)
+
+// Export errors
+var (
+	ErrInvalidExportFormat = errors.New("export format must be csv or xlsx")
+)
+
+// Certificate errors
+var (
+	ErrInvalidCertificateTemplate = errors.New("certificate template needs a title and body")
+	ErrResultNotPassed            = errors.New("certificates are only issued for passed results")
+)
+
+// Gradebook errors
+var (
+	ErrInvalidCourseCategories   = errors.New("course categories need unique names, weights totalling 100 and graded tests that appear in one category only")
+	ErrInvalidCourseGradingScale = errors.New("course grading scale must be an existing global scale")
+)
+
+// Group errors
+var (
+	ErrInvalidGroupKind       = errors.New("group kind must be class or group")
+	ErrInvalidInviteCode      = errors.New("invite code is not valid")
+	ErrInvalidGroupAssignment = errors.New("tests can only be assigned to existing groups")
+	ErrTestNotAssigned        = errors.New("test is not assigned to any of your groups")
+	ErrInvalidDirectoryGroup  = errors.New("directory group must be a common name of at most 255 characters")
+)
+
+// Ownership errors
+var (
+	ErrInvalidCoOwner = errors.New("co-owners must be existing staff other than the test's creator")
+)
+
+// Role errors
+var (
+	ErrInvalidRoleName       = errors.New("role name must be 2-50 lowercase letters, digits or underscores")
+	ErrRoleExists            = errors.New("role name already exists")
+	ErrInvalidPermission     = errors.New("unknown permission")
+	ErrSystemRole            = errors.New("built-in roles cannot be changed, deleted or assigned")
+	ErrInvalidRoleAssignment = errors.New("only existing institution roles can be assigned")
+)
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"gocbt/internal/config"
@@ -15,40 +17,64 @@ type Claims struct {
 	UserID   int             `json:"user_id"`
 	Username string          `json:"username"`
 	Role     models.UserRole `json:"role"`
+	// AMR lists the authentication methods of the login the token was issued for, such as
+	// pwd, otp and mfa (RFC 8176)
+	AMR []string `json:"amr,omitempty"`
 	jwt.RegisteredClaims
+
+	// Permissions are looked up for each request rather than signed into the token, so
+	// role changes take effect immediately
+	Permissions []models.Permission `json:"-"`
 }
 
 // JWTManager handles JWT operations
 type JWTManager struct {
-	secretKey  string
+	keys       *KeySet
 	expiration time.Duration
 }
 
-// NewJWTManager creates a new JWT manager
-func NewJWTManager(cfg *config.JWTConfig) *JWTManager {
+// NewJWTManager creates a new JWT manager that signs tokens with the current key of a
+// key set
+func NewJWTManager(cfg *config.JWTConfig, keys *KeySet) *JWTManager {
 	return &JWTManager{
-		secretKey:  cfg.Secret,
+		keys:       keys,
 		expiration: cfg.Expiration,
 	}
 }
 
-// GenerateToken generates a JWT token for a user
-func (j *JWTManager) GenerateToken(user *models.User) (string, error) {
+// GenerateToken generates a short-lived access token for a user. The returned claims
+// carry the token's ID (jti) and expiry, which logout needs to revoke it. The amr claim
+// records how the user signed in.
+func (j *JWTManager) GenerateToken(user *models.User, amr []string) (string, *Claims, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+		AMR:      amr,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.expiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "gocbt",
 			Subject:   fmt.Sprintf("user:%d", user.ID),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	key := j.keys.current()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
 // ValidateToken validates a JWT token and returns the claims
@@ -64,18 +90,24 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method - only allow HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		// Find the key the token names
+		keyID, ok := token.Header["kid"].(string)
+		if !ok || keyID == "" {
+			return nil, errors.New("missing key ID")
+		}
+		key, err := j.keys.find(keyID)
+		if err != nil {
+			return nil, err
 		}
 
-		// Ensure it's specifically HS256
-		if token.Method.Alg() != "HS256" {
+		// Only accept the algorithm of that key, so a public key is never used as an
+		// HMAC secret
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected algorithm: %v", token.Method.Alg())
 		}
 
-		return []byte(j.secretKey), nil
-	})
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256, AlgorithmHS256}))
 
 	if err != nil {
 		return nil, err
@@ -100,42 +132,17 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 			return nil, errors.New("invalid issuer")
 		}
 
+		// Tokens without an ID cannot be revoked, so they are not accepted
+		if claims.ID == "" {
+			return nil, errors.New("missing token ID")
+		}
+
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
-// RefreshToken generates a new token with extended expiration
-func (j *JWTManager) RefreshToken(tokenString string) (string, error) {
-	claims, err := j.ValidateToken(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	// Check if token is close to expiration (within 1 hour)
-	if time.Until(claims.ExpiresAt.Time) > time.Hour {
-		return "", errors.New("token is not close to expiration")
-	}
-
-	// Create new claims with extended expiration
-	newClaims := &Claims{
-		UserID:   claims.UserID,
-		Username: claims.Username,
-		Role:     claims.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "gocbt",
-			Subject:   claims.Subject,
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims)
-	return token.SignedString([]byte(j.secretKey))
-}
-
 // ExtractUserID extracts user ID from token string
 func (j *JWTManager) ExtractUserID(tokenString string) (int, error) {
 	claims, err := j.ValidateToken(tokenString)
@@ -153,3 +160,12 @@ func (j *JWTManager) ExtractUserRole(tokenString string) (models.UserRole, error
 	}
 	return claims.Role, nil
 }
+
+// generateTokenID generates a random 128-bit token ID (jti)
+func generateTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gocbt/internal/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// AlgorithmEdDSA signs tokens with Ed25519 keys
+	AlgorithmEdDSA = "EdDSA"
+	// AlgorithmRS256 signs tokens with RSA keys
+	AlgorithmRS256 = "RS256"
+	// AlgorithmHS256 signs tokens with the shared JWT_SECRET; tokens cannot be verified
+	// by other services without it
+	AlgorithmHS256 = "HS256"
+
+	// rsaKeyBits is the size of generated RSA keys
+	rsaKeyBits = 2048
+	// minSecretLength is the shortest HS256 secret accepted
+	minSecretLength = 32
+	// reloadInterval limits how often an unknown key ID triggers reading the key directory
+	reloadInterval = 10 * time.Second
+	// createdHeader is the PEM header recording when a generated key was created
+	createdHeader = "Created"
+)
+
+// signingKey is a key tokens are signed or verified with
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   crypto.PrivateKey
+	public    crypto.PublicKey
+	createdAt time.Time
+	path      string
+}
+
+// KeySet holds the keys access tokens are signed with. With EdDSA or RS256 the keys are
+// PEM files in the key directory: the newest signs new tokens and older ones verify the
+// tokens they signed until those expire. Rotate generates a new key once the newest is
+// older than the rotation interval, so other services fetch the public keys from the
+// JWKS endpoint instead of sharing a secret.
+type KeySet struct {
+	cfg        *config.JWTConfig
+	mu         sync.RWMutex
+	keys       []*signingKey // Oldest first
+	lastReload time.Time
+}
+
+// JWK represents a public key in a JSON Web Key Set
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet loads the token signing keys. With EdDSA or RS256, a key is generated when
+// the key directory has none; with HS256 the configured secret is used.
+func LoadKeySet(cfg *config.JWTConfig) (*KeySet, error) {
+	set := &KeySet{cfg: cfg}
+
+	switch cfg.Algorithm {
+	case AlgorithmHS256:
+		if len(cfg.Secret) < minSecretLength {
+			return nil, fmt.Errorf("JWT_SECRET must be at least %d characters for HS256", minSecretLength)
+		}
+		set.keys = []*signingKey{{
+			id:      "hs256",
+			method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.Secret),
+			public:  []byte(cfg.Secret),
+		}}
+		return set, nil
+	case AlgorithmEdDSA, AlgorithmRS256:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	if err := os.MkdirAll(cfg.KeyDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := set.reload(); err != nil {
+		return nil, err
+	}
+	if set.needsKey(false) {
+		if err := set.generate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// Rotate reloads the key directory, which other instances may share, generates a new
+// signing key when the newest is older than the rotation interval and deletes keys that
+// no unexpired token can have been signed with
+func (k *KeySet) Rotate() error {
+	if k.cfg.Algorithm == AlgorithmHS256 {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.reload(); err != nil {
+		return err
+	}
+
+	if k.needsKey(true) {
+		if err := k.generate(); err != nil {
+			return err
+		}
+	}
+
+	// Keys are only deleted when rotation manages them
+	if k.cfg.RotationInterval <= 0 {
+		return nil
+	}
+	return k.prune()
+}
+
+// needsKey checks if a new signing key must be generated: when there is none, when the
+// configured algorithm changed, or when rotating and the newest key is due to be replaced
+func (k *KeySet) needsKey(rotating bool) bool {
+	if len(k.keys) == 0 {
+		return true
+	}
+	newest := k.keys[len(k.keys)-1]
+	if newest.method.Alg() != k.cfg.Algorithm {
+		return true
+	}
+	return rotating && k.cfg.RotationInterval > 0 && time.Since(newest.createdAt) >= k.cfg.RotationInterval
+}
+
+// JWKS returns the public keys that tokens may currently be signed with
+func (k *KeySet) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		}
+	}
+	return jwks
+}
+
+// current returns the key new tokens are signed with
+func (k *KeySet) current() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[len(k.keys)-1]
+}
+
+// find returns the key with the given ID. An unknown ID may belong to a key another
+// instance just generated, so the key directory is read again, at most every few seconds.
+func (k *KeySet) find(keyID string) (*signingKey, error) {
+	if key := k.lookup(keyID); key != nil {
+		return key, nil
+	}
+
+	if k.cfg.Algorithm != AlgorithmHS256 {
+		k.mu.Lock()
+		if time.Since(k.lastReload) >= reloadInterval {
+			if err := k.reload(); err != nil {
+				k.mu.Unlock()
+				return nil, err
+			}
+		}
+		k.mu.Unlock()
+
+		if key := k.lookup(keyID); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key ID %q", keyID)
+}
+
+// lookup returns the loaded key with the given ID, if any
+func (k *KeySet) lookup(keyID string) *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.id == keyID {
+			return key
+		}
+	}
+	return nil
+}
+
+// reload reads every PEM key in the key directory; the caller holds the lock
+func (k *KeySet) reload() error {
+	paths, err := filepath.Glob(filepath.Join(k.cfg.KeyDir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := loadSigningKey(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted by another instance while reading
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].createdAt.Equal(keys[j].createdAt) {
+			return keys[i].id < keys[j].id
+		}
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	if len(keys) > 0 || len(k.keys) == 0 {
+		k.keys = keys
+	}
+	k.lastReload = time.Now()
+	return nil
+}
+
+// generate creates a key for the configured algorithm and saves it in the key directory,
+// readable by the owner only; the caller holds the lock
+func (k *KeySet) generate() error {
+	var privateKey crypto.PrivateKey
+	var err error
+	if k.cfg.Algorithm == AlgorithmRS256 {
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	} else {
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return err
+	}
+
+	key, err := newSigningKey(privateKey, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{createdHeader: key.createdAt.Format(time.RFC3339)},
+		Bytes:   der,
+	})
+
+	key.path = filepath.Join(k.cfg.KeyDir, key.id+".pem")
+	if err := os.WriteFile(key.path, data, 0600); err != nil {
+		return err
+	}
+
+	k.keys = append(k.keys, key)
+	return nil
+}
+
+// prune deletes keys that were replaced longer ago than an access token lives, since no
+// valid token can still be signed with them; the caller holds the lock
+func (k *KeySet) prune() error {
+	kept := make([]*signingKey, 0, len(k.keys))
+	for i, key := range k.keys {
+		if i < len(k.keys)-1 {
+			replacedAt := k.keys[i+1].createdAt
+			if time.Since(replacedAt) > k.cfg.Expiration+time.Minute {
+				if err := os.Remove(key.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				continue
+			}
+		}
+		kept = append(kept, key)
+	}
+	k.keys = kept
+	return nil
+}
+
+// loadSigningKey loads a PKCS#8 Ed25519 or RSA private key from a PEM file. Its creation
+// time is read from the PEM header written by generate, or else the file's modification
+// time, so keys can also be provisioned by hand.
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s does not contain a PEM private key", path)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, block.Headers[createdHeader])
+	if err != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		createdAt = info.ModTime()
+	}
+
+	key, err := newSigningKey(privateKey, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key.path = path
+	return key, nil
+}
+
+// newSigningKey creates a signing key for an Ed25519 or RSA private key. Its ID is a
+// short fingerprint of the public key.
+func newSigningKey(privateKey crypto.PrivateKey, createdAt time.Time) (*signingKey, error) {
+	key := &signingKey{private: privateKey, createdAt: createdAt}
+	switch private := privateKey.(type) {
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.public = private.Public()
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < rsaKeyBits {
+			return nil, fmt.Errorf("RSA keys must be at least %d bits", rsaKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+		key.public = private.Public()
+	default:
+		return nil, errors.New("only Ed25519 and RSA keys are supported")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.public)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := sha256.Sum256(der)
+	key.id = hex.EncodeToString(fingerprint[:8])
+	return key, nil
+}
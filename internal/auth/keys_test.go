@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"encoding/pem"
+	"gocbt/internal/config"
+	"gocbt/internal/models"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newKeyConfig configures keys of an algorithm in a fresh directory, rotated hourly and
+// verifying access tokens that live for an hour
+func newKeyConfig(t *testing.T, algorithm string) *config.JWTConfig {
+	t.Helper()
+
+	return &config.JWTConfig{
+		Algorithm:        algorithm,
+		KeyDir:           t.TempDir(),
+		RotationInterval: time.Hour,
+		Expiration:       time.Hour,
+	}
+}
+
+// newKeyManager loads the key set of a configuration and a JWT manager signing with it
+func newKeyManager(t *testing.T, cfg *config.JWTConfig) (*KeySet, *JWTManager) {
+	t.Helper()
+
+	keys, err := LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+	return keys, NewJWTManager(cfg, keys)
+}
+
+// backdate rewrites the creation time recorded in a generated key's PEM file, as if the
+// key had been created the given time ago
+func backdate(t *testing.T, key *signingKey, age time.Duration) {
+	t.Helper()
+
+	data, err := os.ReadFile(key.path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("%s does not contain a PEM block", key.path)
+	}
+	block.Headers[createdHeader] = time.Now().Add(-age).UTC().Format(time.RFC3339)
+	if err := os.WriteFile(key.path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+var keyTestUser = &models.User{ID: 7, Username: "student", Role: models.RoleStudent}
+
+func TestKeySetRotationGracePeriod(t *testing.T) {
+	cfg := newKeyConfig(t, AlgorithmEdDSA)
+	keys, manager := newKeyManager(t, cfg)
+
+	previous := keys.current()
+	token, _, err := manager.GenerateToken(keyTestUser, nil)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	// The key is long due for rotation, and a new one signs from now on
+	backdate(t, previous, 24*time.Hour)
+	if err := keys.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if keys.current().id == previous.id {
+		t.Fatalf("Rotate() kept signing with key %s, expected a new key", previous.id)
+	}
+
+	if _, err := manager.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken() with the previous key error = %v, expected nil during the grace period", err)
+	}
+
+	// Once the new key is older than an access token lives, nothing valid can be signed
+	// with the previous one and the next rotation deletes it
+	backdate(t, keys.current(), cfg.Expiration+2*time.Minute)
+	if err := keys.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if keys.lookup(previous.id) != nil {
+		t.Fatalf("Rotate() kept key %s, expected it pruned", previous.id)
+	}
+	if _, err := os.Stat(previous.path); !os.IsNotExist(err) {
+		t.Errorf("Rotate() left %s on disk, expected it deleted", previous.path)
+	}
+
+	if _, err := manager.ValidateToken(token); err == nil {
+		t.Error("ValidateToken() with a pruned key succeeded, expected an error")
+	}
+}
+
+func TestValidateTokenRejectsUnknownKeysAndAlgorithms(t *testing.T) {
+	keys, manager := newKeyManager(t, newKeyConfig(t, AlgorithmEdDSA))
+	_, stranger := newKeyManager(t, newKeyConfig(t, AlgorithmEdDSA))
+
+	key := keys.current()
+	claims := &Claims{
+		UserID: keyTestUser.ID,
+		Role:   keyTestUser.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "forged",
+			Issuer:    "gocbt",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	// sign signs the claims with a method and key, naming the key ID in the header
+	sign := func(method jwt.SigningMethod, keyID string, signingKey interface{}) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = keyID
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("SignedString() error = %v", err)
+		}
+		return signed
+	}
+
+	unknown, _, err := stranger.GenerateToken(keyTestUser, nil)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"unknown kid", unknown},
+		{"unknown kid signed with a known key", sign(jwt.SigningMethodEdDSA, "unknown", key.private)},
+		{"missing kid", sign(jwt.SigningMethodEdDSA, "", key.private)},
+		{"HS256 with the public key as secret", sign(jwt.SigningMethodHS256, key.id, []byte(key.public.(ed25519.PublicKey)))},
+		{"none", sign(jwt.SigningMethodNone, key.id, jwt.UnsafeAllowNoneSignatureType)},
+	}
+
+	for _, test := range tests {
+		if _, err := manager.ValidateToken(test.token); err == nil {
+			t.Errorf("ValidateToken(%s) succeeded, expected an error", test.name)
+		}
+	}
+
+	if _, err := manager.ValidateToken(sign(jwt.SigningMethodEdDSA, key.id, key.private)); err != nil {
+		t.Errorf("ValidateToken(known key) error = %v, expected nil", err)
+	}
+}
+
+func TestLoadKeySetSecretLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		valid  bool
+	}{
+		{"empty", "", false},
+		{"31 characters", strings.Repeat("s", minSecretLength-1), false},
+		{"32 characters", strings.Repeat("s", minSecretLength), true},
+	}
+
+	for _, test := range tests {
+		_, err := LoadKeySet(&config.JWTConfig{Algorithm: AlgorithmHS256, Secret: test.secret, Expiration: time.Hour})
+		if (err == nil) != test.valid {
+			t.Errorf("LoadKeySet(%s) error = %v, expected valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestJWKSExposesPublicKeysOnly(t *testing.T) {
+	// Members of a JWK that carry private key material (RFC 7518)
+	privateMembers := []string{"d", "p", "q", "dp", "dq", "qi", "oth", "k"}
+
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		keys, _ := newKeyManager(t, newKeyConfig(t, algorithm))
+
+		data, err := json.Marshal(keys.JWKS())
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var jwks struct {
+			Keys []map[string]interface{} `json:"keys"`
+		}
+		if err := json.Unmarshal(data, &jwks); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+
+		if len(jwks.Keys) != 1 || jwks.Keys[0]["kid"] != keys.current().id || jwks.Keys[0]["alg"] != algorithm {
+			t.Errorf("JWKS(%s) = %s, expected the signing key", algorithm, data)
+			continue
+		}
+		for _, member := range privateMembers {
+			if _, ok := jwks.Keys[0][member]; ok {
+				t.Errorf("JWKS(%s) exposes %q, expected public members only", algorithm, member)
+			}
+		}
+	}
+
+	// A shared secret has no public part to publish
+	secret := strings.Repeat("s", minSecretLength)
+	keys, err := LoadKeySet(&config.JWTConfig{Algorithm: AlgorithmHS256, Secret: secret, Expiration: time.Hour})
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+	if jwks := keys.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("JWKS(%s) = %+v, expected no keys", AlgorithmHS256, jwks.Keys)
+	}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"gocbt/internal/config"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapTimeout limits each connection to the directory
+const ldapTimeout = 10 * time.Second
+
+// LDAPIdentity represents a directory user whose password was verified
+type LDAPIdentity struct {
+	DN        string
+	ID        string // Stable identifier from the configured ID attribute, or the DN
+	Username  string
+	Email     string
+	FirstName string
+	LastName  string
+	Groups    []string // Lowercase common names of the user's groups
+}
+
+// LDAPClient verifies passwords against an LDAP or Active Directory server. The user is
+// looked up with the service account, then the password is checked by binding as the
+// user's entry; the directory never reveals password hashes.
+type LDAPClient struct {
+	cfg       *config.LDAPConfig
+	tlsConfig *tls.Config
+}
+
+// NewLDAPClient creates a new LDAP client. A nil TLS configuration verifies the server's
+// certificate against the system roots.
+func NewLDAPClient(cfg *config.LDAPConfig, tlsConfig *tls.Config) *LDAPClient {
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if parsed, err := url.Parse(cfg.URL); err == nil {
+			tlsConfig.ServerName = parsed.Hostname()
+		}
+	}
+	return &LDAPClient{
+		cfg:       cfg,
+		tlsConfig: tlsConfig,
+	}
+}
+
+// Issuer identifies the directory in linked identities. It is derived from the base DN,
+// not the server URL, so that links survive moving to another domain controller.
+func (c *LDAPClient) Issuer() string {
+	return "ldap:" + strings.ToLower(c.cfg.BaseDN)
+}
+
+// Authenticate verifies a username and password against the directory. It returns
+// ErrInvalidCredentials when the user is unknown or the password is wrong, and an error
+// wrapping ErrDirectoryUnavailable when the directory cannot be reached.
+func (c *LDAPClient) Authenticate(username, password string) (*LDAPIdentity, error) {
+	// An empty password would be an unauthenticated bind, which many servers accept
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	defer conn.Close()
+
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("%w: service account bind failed: %v", ErrDirectoryUnavailable, err)
+		}
+	}
+
+	entry, err := c.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+
+	identity := &LDAPIdentity{
+		DN:        entry.DN,
+		ID:        entry.DN,
+		Username:  entry.GetAttributeValue(c.cfg.UsernameAttribute),
+		Email:     entry.GetAttributeValue(c.cfg.EmailAttribute),
+		FirstName: entry.GetAttributeValue(c.cfg.FirstNameAttribute),
+		LastName:  entry.GetAttributeValue(c.cfg.LastNameAttribute),
+		Groups:    groupNames(entry.GetAttributeValues(c.cfg.GroupAttribute)),
+	}
+	if c.cfg.IDAttribute != "" {
+		if raw := entry.GetRawAttributeValue(c.cfg.IDAttribute); len(raw) > 0 {
+			identity.ID = attributeID(raw)
+		}
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+
+	return identity, nil
+}
+
+// connect opens a connection to the directory, upgrading it with StartTLS if configured
+func (c *LDAPClient) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(c.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(c.tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(c.tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// findUser searches the directory for the entry of the user signing in
+func (c *LDAPClient) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(c.cfg.UserFilter, "{username}", ldap.EscapeFilter(username))
+
+	attributes := []string{c.cfg.UsernameAttribute, c.cfg.EmailAttribute, c.cfg.FirstNameAttribute,
+		c.cfg.LastNameAttribute, c.cfg.GroupAttribute}
+	if c.cfg.IDAttribute != "" {
+		attributes = append(attributes, c.cfg.IDAttribute)
+	}
+
+	request := ldap.NewSearchRequest(c.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout/time.Second), false, filter, attributes, nil)
+
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("%w: user search failed: %v", ErrDirectoryUnavailable, err)
+	}
+
+	switch {
+	case result == nil || len(result.Entries) == 0:
+		return nil, ErrInvalidCredentials
+	case len(result.Entries) > 1:
+		// A filter matching several entries cannot tell which account is signing in
+		return nil, fmt.Errorf("%w: user filter matched more than one entry", ErrDirectoryUnavailable)
+	default:
+		return result.Entries[0], nil
+	}
+}
+
+// groupNames returns the lowercase common names of groups given by DN, such as
+// cn=Year 10 Maths,ou=Groups,dc=school,dc=example
+func groupNames(groupDNs []string) []string {
+	names := make([]string, 0, len(groupDNs))
+	for _, groupDN := range groupDNs {
+		parsed, err := ldap.ParseDN(groupDN)
+		if err != nil || len(parsed.RDNs) == 0 {
+			continue
+		}
+		for _, attribute := range parsed.RDNs[0].Attributes {
+			if strings.EqualFold(attribute.Type, "cn") {
+				names = append(names, strings.ToLower(attribute.Value))
+			}
+		}
+	}
+	return names
+}
+
+// attributeID returns an identifier attribute as text. Binary values, such as Active
+// Directory's objectGUID, are hex encoded.
+func attributeID(raw []byte) string {
+	if utf8.Valid(raw) {
+		printable := true
+		for _, r := range string(raw) {
+			if !unicode.IsPrint(r) {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return string(raw)
+		}
+	}
+	return hex.EncodeToString(raw)
+}
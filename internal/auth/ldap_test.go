@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"errors"
+	"gocbt/internal/auth/ldaptest"
+	"gocbt/internal/config"
+	"reflect"
+	"testing"
+)
+
+const (
+	testBaseDN  = "dc=school,dc=example"
+	testBindDN  = "cn=gocbt,ou=services,dc=school,dc=example"
+	testAliceDN = "uid=alice,ou=people,dc=school,dc=example"
+)
+
+// newTestLDAPClient starts a directory with a service account and one user, and a client
+// for it
+func newTestLDAPClient(t *testing.T) (*ldaptest.Server, *LDAPClient) {
+	t.Helper()
+
+	server := ldaptest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddEntry(testBindDN, "service-secret", map[string][]string{"cn": {"gocbt"}})
+	server.AddEntry(testAliceDN, "alice-secret", map[string][]string{
+		"objectClass": {"inetOrgPerson"},
+		"uid":         {"alice"},
+		"entryUUID":   {"6f1c0d52-3a8e-4b8f-9d0e-0c4a9d1b2e77"},
+		"mail":        {"alice@school.example"},
+		"givenName":   {"Alice"},
+		"sn":          {"Smith"},
+		"memberOf": {
+			"cn=Teachers,ou=groups,dc=school,dc=example",
+			"cn=Year 10 Maths,ou=groups,dc=school,dc=example",
+		},
+	})
+
+	client := NewLDAPClient(&config.LDAPConfig{
+		URL:                server.URL(),
+		BindDN:             testBindDN,
+		BindPassword:       "service-secret",
+		BaseDN:             testBaseDN,
+		UserFilter:         "(&(objectClass=inetOrgPerson)(uid={username}))",
+		IDAttribute:        "entryUUID",
+		UsernameAttribute:  "uid",
+		EmailAttribute:     "mail",
+		FirstNameAttribute: "givenName",
+		LastNameAttribute:  "sn",
+		GroupAttribute:     "memberOf",
+	}, nil)
+
+	return server, client
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	_, client := newTestLDAPClient(t)
+
+	identity, err := client.Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	expected := &LDAPIdentity{
+		DN:        testAliceDN,
+		ID:        "6f1c0d52-3a8e-4b8f-9d0e-0c4a9d1b2e77",
+		Username:  "alice",
+		Email:     "alice@school.example",
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Groups:    []string{"teachers", "year 10 maths"},
+	}
+	if !reflect.DeepEqual(identity, expected) {
+		t.Errorf("Authenticate() = %+v, expected %+v", identity, expected)
+	}
+}
+
+func TestLDAPRejectedCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"wrong password", "alice", "wrong"},
+		{"unknown user", "bob", "alice-secret"},
+		{"empty password", "alice", ""},
+		{"filter injection", "*", "alice-secret"},
+		{"service account", "gocbt", "service-secret"},
+	}
+
+	_, client := newTestLDAPClient(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := client.Authenticate(test.username, test.password); err != ErrInvalidCredentials {
+				t.Errorf("Authenticate() error = %v, expected %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestLDAPDirectoryUnavailable(t *testing.T) {
+	server, client := newTestLDAPClient(t)
+	server.Close()
+
+	if _, err := client.Authenticate("alice", "alice-secret"); !errors.Is(err, ErrDirectoryUnavailable) {
+		t.Errorf("Authenticate() error = %v, expected %v", err, ErrDirectoryUnavailable)
+	}
+}
+
+func TestLDAPWrongServiceAccountPassword(t *testing.T) {
+	_, client := newTestLDAPClient(t)
+	client.cfg.BindPassword = "wrong"
+
+	if _, err := client.Authenticate("alice", "alice-secret"); !errors.Is(err, ErrDirectoryUnavailable) {
+		t.Errorf("Authenticate() error = %v, expected %v", err, ErrDirectoryUnavailable)
+	}
+}
+
+func TestLDAPIssuerIgnoresServerURL(t *testing.T) {
+	_, client := newTestLDAPClient(t)
+	moved := NewLDAPClient(&config.LDAPConfig{URL: "ldaps://dc2.school.example", BaseDN: "DC=School,DC=Example"}, nil)
+
+	if client.Issuer() != moved.Issuer() {
+		t.Errorf("Issuer() = %q and %q, expected them to match", client.Issuer(), moved.Issuer())
+	}
+}
@@ -0,0 +1,415 @@
+// Package ldaptest provides a local LDAP directory for tests, in the manner of
+// net/http/httptest. It speaks enough of LDAPv3 for password sign-in: simple binds,
+// searches with and, or, not, equality and presence filters, and unbind. Other operations
+// are answered with an error.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// LDAP protocol operation tags
+const (
+	bindRequest       = 0
+	bindResponse      = 1
+	unbindRequest     = 2
+	searchRequest     = 3
+	searchResultEntry = 4
+	searchResultDone  = 5
+	extendedRequest   = 23
+	extendedResponse  = 24
+)
+
+// LDAP result codes
+const (
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultAuthMethodNotSupp  = 7
+	resultInvalidCredentials = 49
+	resultUnwillingToPerform = 53
+)
+
+// Search filter and scope values
+const (
+	filterAnd        = 0
+	filterOr         = 1
+	filterNot        = 2
+	filterEquality   = 3
+	filterPresent    = 7
+	scopeBaseObject  = 0
+	scopeSingleLevel = 1
+)
+
+// Entry is a directory entry. Entries with a password can be bound to.
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a running directory
+type Server struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries []*Entry
+	conns   map[net.Conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewServer starts a directory listening on a local port. The caller should call Close
+// when finished.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("ldaptest: failed to listen: " + err.Error())
+	}
+
+	s := &Server{
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// URL returns the ldap:// URL of the directory
+func (s *Server) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// AddEntry adds an entry to the directory. An empty password makes the entry impossible
+// to bind to, like a group or organizational unit.
+func (s *Server) AddEntry(dn, password string, attributes map[string][]string) *Entry {
+	entry := &Entry{DN: dn, Password: password, Attributes: attributes}
+
+	s.mu.Lock()
+	s.entries = append(s.entries, entry)
+	s.mu.Unlock()
+
+	return entry
+}
+
+// SetAttribute replaces the values of an attribute of the entry with the given DN
+func (s *Server) SetAttribute(dn, attribute string, values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if normalizeDN(entry.DN) == normalizeDN(dn) {
+			if entry.Attributes == nil {
+				entry.Attributes = make(map[string][]string)
+			}
+			entry.Attributes[attribute] = values
+		}
+	}
+}
+
+// Close stops the directory and closes open connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// serve accepts connections until the listener is closed
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// handle answers the requests of one connection until the client unbinds or disconnects
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		messageID, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+
+		op := packet.Children[1]
+		if op.ClassType != ber.ClassApplication {
+			return
+		}
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case bindRequest:
+			responses = []*ber.Packet{s.bind(op)}
+		case searchRequest:
+			responses = s.search(op)
+		case unbindRequest:
+			return
+		case extendedRequest:
+			// StartTLS and other extended operations are not supported
+			responses = []*ber.Packet{result(extendedResponse, resultProtocolError, "unsupported extended operation")}
+		default:
+			return
+		}
+
+		for _, response := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+			envelope.AppendChild(response)
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind checks a simple bind. Anonymous binds are accepted.
+func (s *Server) bind(op *ber.Packet) *ber.Packet {
+	if len(op.Children) < 3 {
+		return result(bindResponse, resultProtocolError, "malformed bind request")
+	}
+
+	name := stringValue(op.Children[1])
+	credentials := op.Children[2]
+	if credentials.ClassType != ber.ClassContext || credentials.Tag != 0 {
+		return result(bindResponse, resultAuthMethodNotSupp, "only simple binds are supported")
+	}
+	password := stringValue(credentials)
+
+	if name == "" && password == "" {
+		return result(bindResponse, resultSuccess, "")
+	}
+	if password == "" {
+		return result(bindResponse, resultUnwillingToPerform, "unauthenticated bind is not allowed")
+	}
+
+	entry := s.entry(name)
+	if entry == nil || entry.Password == "" || entry.Password != password {
+		return result(bindResponse, resultInvalidCredentials, "invalid credentials")
+	}
+	return result(bindResponse, resultSuccess, "")
+}
+
+// search returns the entries matching a search request followed by its result
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{result(searchResultDone, resultProtocolError, "malformed search request")}
+	}
+
+	base := normalizeDN(stringValue(op.Children[0]))
+	scope := intValue(op.Children[1])
+	sizeLimit := intValue(op.Children[3])
+	filter := op.Children[6]
+
+	var attributes []string
+	for _, attribute := range op.Children[7].Children {
+		attributes = append(attributes, stringValue(attribute))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		if !inScope(normalizeDN(entry.DN), base, scope) || !matches(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && len(responses) == sizeLimit {
+			return append(responses, result(searchResultDone, resultSizeLimitExceeded, "size limit exceeded"))
+		}
+		responses = append(responses, entryPacket(entry, attributes))
+	}
+
+	return append(responses, result(searchResultDone, resultSuccess, ""))
+}
+
+// entry finds the entry with the given DN
+func (s *Server) entry(dn string) *Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if normalizeDN(entry.DN) == normalizeDN(dn) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// inScope checks if an entry is within the scope of a search from the base DN
+func inScope(dn, base string, scope int) bool {
+	switch scope {
+	case scopeBaseObject:
+		return dn == base
+	case scopeSingleLevel:
+		index := strings.Index(dn, ",")
+		return index >= 0 && dn[index+1:] == base
+	default:
+		return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+// matches evaluates a search filter against an entry. Values are compared without regard
+// to case, as the common directory attributes are.
+func matches(entry *Entry, filter *ber.Packet) bool {
+	if filter.ClassType != ber.ClassContext {
+		return false
+	}
+
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matches(entry, child) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(entry, filter.Children[0])
+	case filterEquality:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := stringValue(filter.Children[1])
+		for _, value := range attributeValues(entry, stringValue(filter.Children[0])) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		name := stringValue(filter)
+		return strings.EqualFold(name, "objectClass") || len(attributeValues(entry, name)) > 0
+	default:
+		return false
+	}
+}
+
+// attributeValues returns the values of an entry's attribute, matching its name without
+// regard to case
+func attributeValues(entry *Entry, name string) []string {
+	for attribute, values := range entry.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// entryPacket encodes an entry with the requested attributes, named as requested
+func entryPacket(entry *Entry, requested []string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, searchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+
+	if len(requested) == 0 {
+		for attribute := range entry.Attributes {
+			requested = append(requested, attribute)
+		}
+	}
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, name := range requested {
+		values := attributeValues(entry, name)
+		if len(values) == 0 {
+			continue
+		}
+
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	packet.AppendChild(list)
+
+	return packet
+}
+
+// result encodes an LDAPResult for the given response operation
+func result(tag ber.Tag, code int64, message string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return packet
+}
+
+// stringValue returns the content of a primitive packet as a string
+func stringValue(packet *ber.Packet) string {
+	if value, ok := packet.Value.(string); ok {
+		return value
+	}
+	if packet.Data != nil {
+		return packet.Data.String()
+	}
+	return ""
+}
+
+// intValue returns the value of an integer or enumerated packet
+func intValue(packet *ber.Packet) int {
+	if value, ok := packet.Value.(int64); ok {
+		return int(value)
+	}
+	return 0
+}
+
+// normalizeDN lowercases a DN and removes spaces around its separators, so that DNs
+// written differently compare equal
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		if index := strings.Index(part, "="); index >= 0 {
+			part = strings.TrimSpace(part[:index]) + "=" + strings.TrimSpace(part[index+1:])
+		}
+		parts[i] = strings.ToLower(strings.TrimSpace(part))
+	}
+	return strings.Join(parts, ",")
+}
@@ -17,15 +17,29 @@ const (
 	ClaimsContextKey ContextKey = "claims"
 )
 
+// PermissionLoader loads the permissions a user currently holds
+type PermissionLoader interface {
+	GetUserPermissions(userID int) ([]models.Permission, error)
+}
+
+// RevocationChecker checks access token IDs (jti) against the logout denylist
+type RevocationChecker interface {
+	IsTokenRevoked(tokenID string) (bool, error)
+}
+
 // Middleware provides authentication middleware
 type Middleware struct {
-	jwtManager *JWTManager
+	jwtManager  *JWTManager
+	permissions PermissionLoader
+	revocations RevocationChecker
 }
 
 // NewMiddleware creates a new authentication middleware
-func NewMiddleware(jwtManager *JWTManager) *Middleware {
+func NewMiddleware(jwtManager *JWTManager, permissions PermissionLoader, revocations RevocationChecker) *Middleware {
 	return &Middleware{
-		jwtManager: jwtManager,
+		jwtManager:  jwtManager,
+		permissions: permissions,
+		revocations: revocations,
 	}
 }
 
@@ -59,6 +73,23 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		// Reject tokens that were logged out before they expired
+		revoked, err := m.isRevoked(claims)
+		if err != nil {
+			http.Error(w, "Failed to check token", http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			http.Error(w, "Token revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// Load the user's current permissions
+		if err := m.loadPermissions(claims); err != nil {
+			http.Error(w, "Failed to load permissions", http.StatusInternalServerError)
+			return
+		}
+
 		// Add claims to context
 		ctx := context.WithValue(r.Context(), ClaimsContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -95,6 +126,48 @@ func (m *Middleware) RequireRole(roles ...models.UserRole) func(http.Handler) ht
 	}
 }
 
+// RequirePermission is a middleware that requires a permission
+func (m *Middleware) RequirePermission(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(ClaimsContextKey).(*Claims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !models.HasPermission(claims.Permissions, permission) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isRevoked checks if the token's ID is on the logout denylist
+func (m *Middleware) isRevoked(claims *Claims) (bool, error) {
+	if m.revocations == nil {
+		return false, nil
+	}
+	return m.revocations.IsTokenRevoked(claims.ID)
+}
+
+// loadPermissions sets the permissions the user currently holds on their claims
+func (m *Middleware) loadPermissions(claims *Claims) error {
+	if m.permissions == nil {
+		return nil
+	}
+
+	permissions, err := m.permissions.GetUserPermissions(claims.UserID)
+	if err != nil {
+		return err
+	}
+	claims.Permissions = permissions
+	return nil
+}
+
 // RequireTeacherOrAdmin is a convenience middleware for teacher/admin access
 func (m *Middleware) RequireTeacherOrAdmin(next http.Handler) http.Handler {
 	return m.RequireRole(models.RoleTeacher, models.RoleAdmin)(next)
@@ -114,7 +187,7 @@ func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 			if tokenString != "" {
 				// Validate token if present
-				if claims, err := m.jwtManager.ValidateToken(tokenString); err == nil {
+				if claims, err := m.jwtManager.ValidateToken(tokenString); err == nil && m.isUsable(claims) {
 					// Add claims to context if valid
 					ctx := context.WithValue(r.Context(), ClaimsContextKey, claims)
 					r = r.WithContext(ctx)
@@ -126,6 +199,15 @@ func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
 	})
 }
 
+// isUsable checks that an optional token was not revoked and loads its permissions
+func (m *Middleware) isUsable(claims *Claims) bool {
+	revoked, err := m.isRevoked(claims)
+	if err != nil || revoked {
+		return false
+	}
+	return m.loadPermissions(claims) == nil
+}
+
 // GetUserFromContext extracts user claims from request context
 func GetUserFromContext(r *http.Request) (*Claims, bool) {
 	claims, ok := r.Context().Value(ClaimsContextKey).(*Claims)
@@ -149,3 +231,26 @@ func GetUserRoleFromContext(r *http.Request) (models.UserRole, bool) {
 	}
 	return claims.Role, true
 }
+
+// HasPermission checks if the user of a request holds a permission
+func HasPermission(r *http.Request, permission models.Permission) bool {
+	claims, ok := GetUserFromContext(r)
+	if !ok {
+		return false
+	}
+	return models.HasPermission(claims.Permissions, permission)
+}
+
+// HasAnyPermission checks if the user of a request holds at least one of the permissions
+func HasAnyPermission(r *http.Request, permissions ...models.Permission) bool {
+	claims, ok := GetUserFromContext(r)
+	if !ok {
+		return false
+	}
+	for _, permission := range permissions {
+		if models.HasPermission(claims.Permissions, permission) {
+			return true
+		}
+	}
+	return false
+}
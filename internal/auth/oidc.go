@@ -0,0 +1,416 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gocbt/internal/config"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcKeyRefreshInterval limits how often an unknown key ID triggers fetching the
+// provider's keys again
+const oidcKeyRefreshInterval = time.Minute
+
+// OIDCIdentity represents the user an identity provider vouched for in an ID token
+type OIDCIdentity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+	HostedDomain      string   // Google Workspace domain, if any
+	Roles             []string // Values of the configured role claim
+	AMR               []string // Authentication methods the provider used, if it says
+}
+
+// OIDCClient is an OpenID Connect relying party using the authorization code flow with
+// PKCE. The provider's endpoints are discovered from its issuer URL on first use, and its
+// signing keys are fetched from its JWKS and refreshed when it starts using a new key.
+type OIDCClient struct {
+	cfg        *config.OIDCConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// oidcDiscovery is the part of the provider's discovery document the client uses
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcTokenResponse is the provider's token endpoint response
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// NewOIDCClient creates a new OpenID Connect client. A nil HTTP client uses one with a
+// 10 second timeout.
+func NewOIDCClient(cfg *config.OIDCConfig, httpClient *http.Client) *OIDCClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCClient{
+		cfg:        cfg,
+		httpClient: httpClient,
+	}
+}
+
+// AuthorizationURL returns the provider URL the user signs in at. The state, nonce and
+// PKCE code challenge are echoed back or bound into the ID token so that the callback can
+// be matched to this request.
+func (c *OIDCClient) AuthorizationURL(state, nonce, codeChallenge string) (string, error) {
+	discovery, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	endpoint, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.cfg.ClientID)
+	query.Set("redirect_uri", c.cfg.RedirectURL)
+	query.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+
+	return endpoint.String(), nil
+}
+
+// Exchange redeems an authorization code and the PKCE code verifier for an ID token
+func (c *OIDCClient) Exchange(code, codeVerifier string) (string, error) {
+	discovery, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", c.cfg.ClientID)
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %q: %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token endpoint returned no ID token")
+	}
+
+	return token.IDToken, nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, expiry and nonce and
+// returns the identity it asserts
+func (c *OIDCClient) VerifyIDToken(rawToken, nonce string) (*OIDCIdentity, error) {
+	discovery, err := c.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		return c.publicKey(keyID)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// A token issued to several audiences must name this client as the authorized party
+	if audience, _ := claims.GetAudience(); len(audience) > 1 {
+		if azp, _ := claims["azp"].(string); azp != c.cfg.ClientID {
+			return nil, errors.New("ID token was issued to another party")
+		}
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+
+	identity := &OIDCIdentity{
+		Issuer:            discovery.Issuer,
+		Subject:           stringClaim(claims, "sub"),
+		Email:             stringClaim(claims, "email"),
+		Name:              stringClaim(claims, "name"),
+		GivenName:         stringClaim(claims, "given_name"),
+		FamilyName:        stringClaim(claims, "family_name"),
+		PreferredUsername: stringClaim(claims, "preferred_username"),
+		HostedDomain:      stringClaim(claims, "hd"),
+		Roles:             stringsClaim(claims, c.cfg.RoleClaim),
+		AMR:               stringsClaim(claims, "amr"),
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+// discover fetches the provider's discovery document, once
+func (c *OIDCClient) discover() (*oidcDiscovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(c.cfg.IssuerURL, "/")
+	var discovery oidcDiscovery
+	if err := c.getJSON(issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("identity provider reports issuer %q, expected %q", discovery.Issuer, c.cfg.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("identity provider discovery document is incomplete")
+	}
+
+	c.discovery = &discovery
+	return c.discovery, nil
+}
+
+// publicKey returns the provider key with the given ID, fetching the provider's keys
+// again when it is unknown, at most once a minute
+func (c *OIDCClient) publicKey(keyID string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[keyID]; ok {
+		return key, nil
+	}
+
+	if c.keys == nil || time.Since(c.keysFetchedAt) >= oidcKeyRefreshInterval {
+		if err := c.fetchKeys(); err != nil {
+			return nil, err
+		}
+		if key, ok := c.keys[keyID]; ok {
+			return key, nil
+		}
+	}
+
+	// Providers with a single key may leave out the key ID
+	if keyID == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown identity provider key %q", keyID)
+}
+
+// fetchKeys fetches the provider's signing keys; the caller holds the lock
+func (c *OIDCClient) fetchKeys() error {
+	var jwks struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := c.getJSON(c.discovery.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch identity provider keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+	return nil
+}
+
+// getJSON fetches a JSON document from the provider
+func (c *OIDCClient) getJSON(url string, target interface{}) error {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// publicKey decodes an RSA, EC or Ed25519 public key from a JSON Web Key
+func (k JWK) publicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// NewPKCEVerifier generates a random PKCE code verifier
+func NewPKCEVerifier() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// PKCEChallenge returns the S256 code challenge of a PKCE code verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// stringClaim returns a string claim, or an empty string
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringsClaim returns a claim holding a string or a list of strings. A dotted name such
+// as realm_access.roles reads a nested claim.
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	var value interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
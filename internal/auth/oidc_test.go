@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"gocbt/internal/auth/oidctest"
+	"gocbt/internal/config"
+	"net/url"
+	"testing"
+)
+
+const testClientID = "gocbt"
+
+// newTestOIDCClient starts a mock identity provider and a client for it
+func newTestOIDCClient(t *testing.T) (*oidctest.Provider, *OIDCClient) {
+	t.Helper()
+
+	provider := oidctest.NewProvider(testClientID)
+	t.Cleanup(provider.Close)
+
+	client := NewOIDCClient(&config.OIDCConfig{
+		IssuerURL:   provider.Issuer(),
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:3000/auth/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+		RoleClaim:   "realm_access.roles",
+	}, nil)
+
+	return provider, client
+}
+
+// signIn runs the authorization code flow against the mock provider and returns the ID
+// token, exchanging the code with the given verifier
+func signIn(t *testing.T, provider *oidctest.Provider, client *OIDCClient, nonce, verifier string) (string, error) {
+	t.Helper()
+
+	challengeVerifier, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatalf("NewPKCEVerifier() error = %v", err)
+	}
+	if verifier == "" {
+		verifier = challengeVerifier
+	}
+
+	authorizationURL, err := client.AuthorizationURL("state-1", nonce, PKCEChallenge(challengeVerifier))
+	if err != nil {
+		t.Fatalf("AuthorizationURL() error = %v", err)
+	}
+
+	code, state, err := provider.Authorize(authorizationURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("provider returned state %q, expected state-1", state)
+	}
+
+	return client.Exchange(code, verifier)
+}
+
+func TestOIDCAuthorizationURL(t *testing.T) {
+	provider, client := newTestOIDCClient(t)
+
+	authorizationURL, err := client.AuthorizationURL("state-1", "nonce-1", PKCEChallenge("verifier"))
+	if err != nil {
+		t.Fatalf("AuthorizationURL() error = %v", err)
+	}
+
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("AuthorizationURL() returned invalid URL: %v", err)
+	}
+	if parsed.Scheme+"://"+parsed.Host+parsed.Path != provider.Issuer()+"/authorize" {
+		t.Errorf("AuthorizationURL() = %s, expected the provider's authorization endpoint", authorizationURL)
+	}
+
+	expected := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          "http://localhost:3000/auth/callback",
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        PKCEChallenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	query := parsed.Query()
+	for name, value := range expected {
+		if query.Get(name) != value {
+			t.Errorf("AuthorizationURL() %s = %q, expected %q", name, query.Get(name), value)
+		}
+	}
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	provider, client := newTestOIDCClient(t)
+	provider.Claims = map[string]interface{}{
+		"sub":                "a1b2c3",
+		"email":              "Jane.Doe@school.example",
+		"given_name":         "Jane",
+		"family_name":        "Doe",
+		"preferred_username": "jane.doe",
+		"realm_access":       map[string]interface{}{"roles": []string{"staff", "offline_access"}},
+	}
+
+	idToken, err := signIn(t, provider, client, "nonce-1", "")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	identity, err := client.VerifyIDToken(idToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+
+	if identity.Issuer != provider.Issuer() || identity.Subject != "a1b2c3" {
+		t.Errorf("identity = %s/%s, expected %s/a1b2c3", identity.Issuer, identity.Subject, provider.Issuer())
+	}
+	if identity.Email != "Jane.Doe@school.example" || !identity.EmailVerified {
+		t.Errorf("email = %q (verified %v), expected a verified Jane.Doe@school.example", identity.Email, identity.EmailVerified)
+	}
+	if identity.GivenName != "Jane" || identity.FamilyName != "Doe" || identity.PreferredUsername != "jane.doe" {
+		t.Errorf("names = %q %q %q, expected Jane Doe jane.doe", identity.GivenName, identity.FamilyName, identity.PreferredUsername)
+	}
+	if len(identity.Roles) != 2 || identity.Roles[0] != "staff" {
+		t.Errorf("roles = %v, expected [staff offline_access]", identity.Roles)
+	}
+}
+
+func TestOIDCRejectsWrongPKCEVerifier(t *testing.T) {
+	provider, client := newTestOIDCClient(t)
+
+	if _, err := signIn(t, provider, client, "nonce-1", "another-verifier"); err == nil {
+		t.Error("Exchange() with the wrong code verifier succeeded, expected an error")
+	}
+}
+
+func TestOIDCCodeCanOnlyBeUsedOnce(t *testing.T) {
+	provider, client := newTestOIDCClient(t)
+
+	verifier, _ := NewPKCEVerifier()
+	authorizationURL, err := client.AuthorizationURL("state-1", "nonce-1", PKCEChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthorizationURL() error = %v", err)
+	}
+	code, _, err := provider.Authorize(authorizationURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	if _, err := client.Exchange(code, verifier); err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if _, err := client.Exchange(code, verifier); err == nil {
+		t.Error("second Exchange() of the same code succeeded, expected an error")
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*oidctest.Provider)
+		nonce     string
+	}{
+		{"wrong nonce", func(p *oidctest.Provider) { p.Nonce = "replayed" }, "nonce-1"},
+		{"wrong audience", func(p *oidctest.Provider) { p.Audience = "another-client" }, "nonce-1"},
+		{"expired", func(p *oidctest.Provider) { p.Claims["exp"] = 1000 }, "nonce-1"},
+		{"wrong issuer", func(p *oidctest.Provider) { p.Claims["iss"] = "https://evil.example" }, "nonce-1"},
+		{"missing subject", func(p *oidctest.Provider) { p.Claims["sub"] = "" }, "nonce-1"},
+		{"unauthorized party", func(p *oidctest.Provider) {
+			p.Claims["aud"] = []string{testClientID, "another-client"}
+			p.Claims["azp"] = "another-client"
+		}, "nonce-1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, client := newTestOIDCClient(t)
+			test.configure(provider)
+
+			idToken, err := signIn(t, provider, client, test.nonce, "")
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+
+			if _, err := client.VerifyIDToken(idToken, test.nonce); err == nil {
+				t.Errorf("VerifyIDToken() accepted a token with %s", test.name)
+			}
+		})
+	}
+}
+
+func TestOIDCRejectsForgedSignature(t *testing.T) {
+	provider, client := newTestOIDCClient(t)
+
+	// A token signed by another provider under the same issuer and key ID
+	forger := oidctest.NewProvider(testClientID)
+	defer forger.Close()
+	forger.Claims["iss"] = provider.Issuer()
+
+	idToken, err := forger.IDToken("nonce-1")
+	if err != nil {
+		t.Fatalf("IDToken() error = %v", err)
+	}
+
+	if _, err := client.VerifyIDToken(idToken, "nonce-1"); err == nil {
+		t.Error("VerifyIDToken() accepted a token with a forged signature")
+	}
+}
@@ -1,12 +1,18 @@
 package auth
 
 import (
+	"sync"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
 // PasswordManager handles password hashing and verification
 type PasswordManager struct {
 	cost int
+
+	// dummyHash is compared against when there is no real hash to check
+	dummyOnce sync.Once
+	dummyHash []byte
 }
 
 // NewPasswordManager creates a new password manager
@@ -31,6 +37,15 @@ func (p *PasswordManager) VerifyPassword(password, hash string) bool {
 	return err == nil
 }
 
+// SimulateVerification takes as long as verifying a password, without checking one. Sign-in
+// calls it for unknown usernames so that response times do not reveal which accounts exist.
+func (p *PasswordManager) SimulateVerification(password string) {
+	p.dummyOnce.Do(func() {
+		p.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("gocbt-dummy-password"), p.cost)
+	})
+	bcrypt.CompareHashAndPassword(p.dummyHash, []byte(password))
+}
+
 // ValidatePasswordStrength validates password strength
 func (p *PasswordManager) ValidatePasswordStrength(password string) error {
 	if len(password) < 8 {
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"gocbt/internal/models"
+	"net/http"
+)
+
+// Action represents something a user may try to do with a test or its results
+type Action string
+
+const (
+	// ActionCreateTest creates a new test
+	ActionCreateTest Action = "create_test"
+	// ActionEditTest changes a test, its questions or its settings
+	ActionEditTest Action = "edit_test"
+	// ActionDeleteTest deletes a test
+	ActionDeleteTest Action = "delete_test"
+	// ActionManageCoOwners changes who a test is shared with
+	ActionManageCoOwners Action = "manage_co_owners"
+	// ActionViewResults views every result and the statistics of a test
+	ActionViewResults Action = "view_results"
+	// ActionViewResult views a single result or the answers of a session
+	ActionViewResult Action = "view_result"
+	// ActionViewSession follows a candidate's session while it is taken
+	ActionViewSession Action = "view_session"
+	// ActionGrade calculates or recalculates results
+	ActionGrade Action = "grade"
+	// ActionTakeTest answers and submits a session
+	ActionTakeTest Action = "take_test"
+	// ActionManageCourse changes, deletes or reads the gradebook of a course; the
+	// resource's owner is the course creator
+	ActionManageCourse Action = "manage_course"
+)
+
+// Principal is the user an action is evaluated for, with the permissions they hold
+type Principal struct {
+	UserID      int
+	Role        models.UserRole
+	Permissions []models.Permission
+}
+
+// PrincipalFromRequest builds a principal from the authenticated user of a request
+func PrincipalFromRequest(r *http.Request) (Principal, bool) {
+	claims, ok := GetUserFromContext(r)
+	if !ok {
+		return Principal{}, false
+	}
+	return Principal{UserID: claims.UserID, Role: claims.Role, Permissions: claims.Permissions}, true
+}
+
+// Resource describes the ownership of the test an action applies to. CandidateID is the
+// user who took the session or owns the result, if any.
+type Resource struct {
+	OwnerID     int
+	CoOwnerIDs  []int
+	CandidateID int
+}
+
+// Policy decides which actions a user may take on a test, its questions and its results
+// based on their permissions and the test's ownership. Permissions such as tests.create
+// apply to the tests a user owns or co-owns; the *_all permissions apply to every test.
+type Policy struct{}
+
+// NewPolicy creates a new policy
+func NewPolicy() *Policy {
+	return &Policy{}
+}
+
+// Can reports whether the principal may take the action on the resource
+func (p *Policy) Can(principal Principal, action Action, resource Resource) bool {
+	switch action {
+	case ActionCreateTest:
+		return principal.has(models.PermTestsCreate)
+	case ActionEditTest, ActionDeleteTest:
+		return principal.has(models.PermTestsManageAll) ||
+			(principal.has(models.PermTestsCreate) && resource.isOwnedBy(principal.UserID))
+	case ActionManageCoOwners:
+		return principal.has(models.PermTestsManageAll) ||
+			(principal.has(models.PermTestsCreate) && principal.UserID != 0 && resource.OwnerID == principal.UserID)
+	case ActionViewResults:
+		return principal.has(models.PermResultsViewAll) ||
+			(principal.has(models.PermResultsView) && resource.isOwnedBy(principal.UserID))
+	case ActionGrade:
+		return principal.has(models.PermResultsGradeAll) ||
+			(principal.has(models.PermResultsGrade) && resource.isOwnedBy(principal.UserID))
+	case ActionViewResult:
+		if resource.isCandidate(principal.UserID) {
+			return true
+		}
+		return p.Can(principal, ActionViewResults, resource)
+	case ActionViewSession:
+		if resource.isCandidate(principal.UserID) || principal.has(models.PermSessionsMonitor) {
+			return true
+		}
+		return p.Can(principal, ActionViewResults, resource)
+	case ActionTakeTest:
+		// No one may answer on behalf of a candidate, not even an admin
+		return resource.isCandidate(principal.UserID)
+	case ActionManageCourse:
+		return principal.has(models.PermCoursesManageAll) ||
+			(principal.has(models.PermCoursesManage) && principal.UserID != 0 && resource.OwnerID == principal.UserID)
+	default:
+		return false
+	}
+}
+
+// Authorize returns ErrForbidden unless the principal may take the action on the resource
+func (p *Policy) Authorize(principal Principal, action Action, resource Resource) error {
+	if !p.Can(principal, action, resource) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// has checks if the principal holds a permission
+func (p Principal) has(permission models.Permission) bool {
+	return models.HasPermission(p.Permissions, permission)
+}
+
+// isOwnedBy checks if the user created the test or is one of its co-owners
+func (r Resource) isOwnedBy(userID int) bool {
+	if userID == 0 {
+		return false
+	}
+	if r.OwnerID == userID {
+		return true
+	}
+	for _, coOwnerID := range r.CoOwnerIDs {
+		if coOwnerID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// isCandidate checks if the user took the session or owns the result
+func (r Resource) isCandidate(userID int) bool {
+	return userID != 0 && r.CandidateID == userID
+}
@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"gocbt/internal/models"
+	"testing"
+)
+
+var (
+	teacherPermissions = []models.Permission{
+		models.PermTestsCreate, models.PermResultsView, models.PermResultsGrade,
+		models.PermGroupsManage, models.PermCoursesManage,
+	}
+
+	owner     = Principal{UserID: 1, Role: models.RoleTeacher, Permissions: teacherPermissions}
+	coOwner   = Principal{UserID: 2, Role: models.RoleTeacher, Permissions: teacherPermissions}
+	teacher   = Principal{UserID: 3, Role: models.RoleTeacher, Permissions: teacherPermissions}
+	admin     = Principal{UserID: 4, Role: models.RoleAdmin, Permissions: models.AllPermissions}
+	candidate = Principal{UserID: 5, Role: models.RoleStudent}
+	student   = Principal{UserID: 6, Role: models.RoleStudent}
+	anonymous = Principal{}
+
+	// Institution roles given to teachers and students on top of their account role
+	proctor = Principal{UserID: 7, Role: models.RoleTeacher, Permissions: []models.Permission{
+		models.PermSessionsMonitor,
+	}}
+	grader = Principal{UserID: 8, Role: models.RoleTeacher, Permissions: []models.Permission{
+		models.PermResultsViewAll, models.PermResultsGradeAll,
+	}}
+	departmentHead = Principal{UserID: 9, Role: models.RoleTeacher, Permissions: []models.Permission{
+		models.PermTestsManageAll, models.PermResultsViewAll,
+	}}
+	auditor = Principal{UserID: 10, Role: models.RoleTeacher, Permissions: []models.Permission{
+		models.PermResultsViewAll, models.PermIntegrityAudit,
+	}}
+	assistant = Principal{UserID: 11, Role: models.RoleStudent, Permissions: []models.Permission{
+		models.PermResultsView, models.PermResultsGrade, models.PermSessionsMonitor,
+	}}
+
+	ownedTest = Resource{OwnerID: 1, CoOwnerIDs: []int{2, 11}, CandidateID: 5}
+)
+
+func TestPolicyCreateTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"teacher", teacher, true},
+		{"admin", admin, true},
+		{"student", student, false},
+		{"proctor", proctor, false},
+		{"department head", departmentHead, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionCreateTest, Resource{})
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionCreateTest, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyEditTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"owner", owner, true},
+		{"co-owner", coOwner, true},
+		{"admin", admin, true},
+		{"department head", departmentHead, true},
+		{"other teacher", teacher, false},
+		{"assistant co-owner", assistant, false},
+		{"grader", grader, false},
+		{"candidate", candidate, false},
+		{"student", student, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, action := range []Action{ActionEditTest, ActionDeleteTest} {
+		for _, test := range tests {
+			result := policy.Can(test.principal, action, ownedTest)
+			if result != test.expected {
+				t.Errorf("Can(%s, %s) = %v, expected %v", test.name, action, result, test.expected)
+			}
+		}
+	}
+}
+
+func TestPolicyViewResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"owner", owner, true},
+		{"co-owner", coOwner, true},
+		{"assistant co-owner", assistant, true},
+		{"admin", admin, true},
+		{"grader", grader, true},
+		{"department head", departmentHead, true},
+		{"auditor", auditor, true},
+		{"other teacher", teacher, false},
+		{"proctor", proctor, false},
+		{"candidate", candidate, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionViewResults, ownedTest)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionViewResults, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyGrade(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"owner", owner, true},
+		{"co-owner", coOwner, true},
+		{"assistant co-owner", assistant, true},
+		{"admin", admin, true},
+		{"grader", grader, true},
+		{"department head", departmentHead, false},
+		{"auditor", auditor, false},
+		{"other teacher", teacher, false},
+		{"candidate", candidate, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionGrade, ownedTest)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionGrade, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyOwnershipRequiresPermission(t *testing.T) {
+	// A co-owner whose roles no longer grant tests.create gets no access, for instance
+	// after being demoted to a student account
+	demoted := Principal{UserID: 2, Role: models.RoleStudent}
+
+	if NewPolicy().Can(demoted, ActionEditTest, ownedTest) {
+		t.Errorf("Can(demoted co-owner, %s) = true, expected false", ActionEditTest)
+	}
+}
+
+func TestPolicyManageCoOwners(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"owner", owner, true},
+		{"admin", admin, true},
+		{"department head", departmentHead, true},
+		{"co-owner", coOwner, false},
+		{"other teacher", teacher, false},
+		{"student", student, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionManageCoOwners, ownedTest)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionManageCoOwners, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyViewResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"candidate", candidate, true},
+		{"owner", owner, true},
+		{"co-owner", coOwner, true},
+		{"admin", admin, true},
+		{"auditor", auditor, true},
+		{"proctor", proctor, false},
+		{"other teacher", teacher, false},
+		{"other student", student, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionViewResult, ownedTest)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionViewResult, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyViewSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"candidate", candidate, true},
+		{"owner", owner, true},
+		{"proctor", proctor, true},
+		{"admin", admin, true},
+		{"other teacher", teacher, false},
+		{"other student", student, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionViewSession, ownedTest)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionViewSession, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyTakeTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"candidate", candidate, true},
+		{"owner", owner, false},
+		{"admin", admin, false},
+		{"proctor", proctor, false},
+		{"other student", student, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionTakeTest, ownedTest)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionTakeTest, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyManageCourse(t *testing.T) {
+	courseHead := Principal{UserID: 12, Role: models.RoleTeacher, Permissions: []models.Permission{
+		models.PermCoursesManageAll,
+	}}
+	ownedCourse := Resource{OwnerID: 1}
+
+	tests := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{"owner", owner, true},
+		{"admin", admin, true},
+		{"courses.manage_all", courseHead, true},
+		{"test co-owner", coOwner, false}, // courses have no co-owners
+		{"other teacher", teacher, false},
+		{"tests.manage_all only", departmentHead, false},
+		{"candidate", candidate, false},
+		{"anonymous", anonymous, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionManageCourse, ownedCourse)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s) = %v, expected %v", test.name, ActionManageCourse, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyViewResultsViaCourse(t *testing.T) {
+	// A gradebook shows every result of the course tests, so it needs both the course and
+	// the results of each test it counts
+	ownCourse := Resource{OwnerID: 3}
+	ownCourseTest := Resource{OwnerID: 3}
+
+	tests := []struct {
+		name      string
+		principal Principal
+		course    Resource
+		test      Resource
+		expected  bool
+	}{
+		{"own course, own test", teacher, ownCourse, ownCourseTest, true},
+		{"own course, another teacher's test", teacher, ownCourse, ownedTest, false},
+		{"test co-owner, another teacher's course", coOwner, ownCourse, ownedTest, false},
+		{"admin", admin, ownCourse, ownedTest, true},
+		{"results.view_all without the course", departmentHead, ownCourse, ownedTest, false},
+	}
+
+	policy := NewPolicy()
+	for _, test := range tests {
+		result := policy.Can(test.principal, ActionManageCourse, test.course) &&
+			policy.Can(test.principal, ActionViewResults, test.test)
+		if result != test.expected {
+			t.Errorf("Can(%s, %s via course) = %v, expected %v", test.name, ActionViewResults, result, test.expected)
+		}
+	}
+}
+
+func TestPolicyUnknownAction(t *testing.T) {
+	if NewPolicy().Can(admin, Action("unknown"), ownedTest) {
+		t.Error("Can(admin, unknown) = true, expected false")
+	}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy := NewPolicy()
+
+	if err := policy.Authorize(owner, ActionEditTest, ownedTest); err != nil {
+		t.Errorf("Authorize(owner, %s) = %v, expected nil", ActionEditTest, err)
+	}
+	if err := policy.Authorize(teacher, ActionEditTest, ownedTest); err != ErrForbidden {
+		t.Errorf("Authorize(other teacher, %s) = %v, expected %v", ActionEditTest, err, ErrForbidden)
+	}
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gocbt/internal/config"
+	"os"
+)
+
+// ResultSigner signs result records with the server's Ed25519 key
+type ResultSigner struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	keyID      string
+}
+
+// LoadResultSigner loads the result signing key from the configured PEM file. When the
+// file does not exist and create is true, a new key is generated and saved there.
+func LoadResultSigner(cfg *config.IntegrityConfig, create bool) (*ResultSigner, error) {
+	data, err := os.ReadFile(cfg.SigningKeyFile)
+	if errors.Is(err, os.ErrNotExist) && create {
+		return createResultSigningKey(cfg.SigningKeyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s does not contain a PEM private key", cfg.SigningKeyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain an Ed25519 key", cfg.SigningKeyFile)
+	}
+
+	return newResultSigner(privateKey), nil
+}
+
+// createResultSigningKey generates a signing key and saves it readable by the owner only
+func createResultSigningKey(path string) (*ResultSigner, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+
+	return newResultSigner(privateKey), nil
+}
+
+// newResultSigner creates a signer for a private key
+func newResultSigner(privateKey ed25519.PrivateKey) *ResultSigner {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	fingerprint := sha256.Sum256(publicKey)
+	return &ResultSigner{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		keyID:      hex.EncodeToString(fingerprint[:8]),
+	}
+}
+
+// Sign returns the base64 signature of a message
+func (s *ResultSigner) Sign(message []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, message))
+}
+
+// Verify checks a base64 signature of a message
+func (s *ResultSigner) Verify(message []byte, signature string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(s.publicKey, message, decoded)
+}
+
+// PublicKey returns the base64 Ed25519 public key, for checking signatures elsewhere
+func (s *ResultSigner) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.publicKey)
+}
+
+// KeyID returns a short fingerprint of the public key
+func (s *ResultSigner) KeyID() string {
+	return s.keyID
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists eight-digit codes; six-digit codes are their last six digits
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(test.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode() error = %v", err)
+		}
+		if code != test.expected {
+			t.Errorf("TOTPCode() at %d = %s, expected %s", test.unix, code, test.expected)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		name     string
+		step     int64
+		accepted bool
+	}{
+		{"current step", step, true},
+		{"previous step", step - 1, true},
+		{"next step", step + 1, true},
+		{"two steps old", step - 2, false},
+		{"two steps ahead", step + 2, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, test.step)
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+
+			matched, ok := ValidateTOTP(rfc6238Secret, code, now)
+			if ok != test.accepted {
+				t.Fatalf("ValidateTOTP() accepted = %v, expected %v", ok, test.accepted)
+			}
+			if ok && matched != test.step {
+				t.Errorf("ValidateTOTP() step = %d, expected %d", matched, test.step)
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, "12345", now); ok {
+		t.Error("ValidateTOTP() accepted a five-digit code")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+
+	parsed, err := url.Parse(TOTPProvisioningURI(secret, "GoCBT", "jane.doe@school.example"))
+	if err != nil {
+		t.Fatalf("TOTPProvisioningURI() is not a URL: %v", err)
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/GoCBT:jane.doe@school.example" {
+		t.Errorf("TOTPProvisioningURI() = %s, expected otpauth://totp/GoCBT:jane.doe@school.example", parsed)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != secret || query.Get("issuer") != "GoCBT" || query.Get("digits") != "6" {
+		t.Errorf("TOTPProvisioningURI() query = %v", query)
+	}
+}
@@ -9,10 +9,15 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	App      AppConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	OIDC      OIDCConfig
+	LDAP      LDAPConfig
+	Integrity IntegrityConfig
+	Account   AccountConfig
+	Mail      MailConfig
+	App       AppConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -22,6 +27,8 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// TrustProxyHeaders enables X-Forwarded-For handling when running behind a reverse proxy
+	TrustProxyHeaders bool
 }
 
 // DatabaseConfig holds database-related configuration
@@ -38,8 +45,103 @@ type DatabaseConfig struct {
 
 // JWTConfig holds JWT-related configuration
 type JWTConfig struct {
-	Secret     string
-	Expiration time.Duration
+	Algorithm         string        // EdDSA or RS256 with keys from KeyDir, or HS256 with Secret
+	Secret            string        // Shared HS256 secret, at least 32 characters
+	KeyDir            string        // Directory of PEM signing keys, created on first start if missing
+	RotationInterval  time.Duration // Age at which a new signing key is generated; 0 disables rotation
+	Expiration        time.Duration // Lifetime of access tokens
+	RefreshExpiration time.Duration // Lifetime of refresh tokens, renewed on every refresh
+}
+
+// OIDCConfig holds OpenID Connect single sign-on configuration. Single sign-on is
+// enabled when IssuerURL is set.
+type OIDCConfig struct {
+	IssuerURL    string // Issuer of the identity provider, e.g. https://accounts.google.com
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // Frontend page the provider redirects back to with the code
+	Scopes       []string
+	// AllowedDomains restricts sign-in to email addresses of these domains, e.g. the
+	// school's Google Workspace domain; empty allows every domain
+	AllowedDomains []string
+	// AutoProvision creates an account on first sign-in; otherwise only linked accounts
+	// can sign in
+	AutoProvision bool
+	// LinkByEmail links a first sign-in to the existing account with the same verified email
+	LinkByEmail bool
+	// RoleClaim names the ID token claim holding the user's groups or roles; a dotted
+	// path such as realm_access.roles reads nested claims
+	RoleClaim string
+	// RoleMapping maps claim values to account roles; the highest matching role applies
+	RoleMapping map[string]string
+	// DefaultRole is given to new accounts matching no mapping
+	DefaultRole string
+}
+
+// LDAPConfig holds LDAP or Active Directory authentication configuration. Directory
+// sign-in is enabled when URL is set.
+type LDAPConfig struct {
+	URL          string // e.g. ldaps://dc.school.example:636 or ldap://ldap.school.example:389
+	StartTLS     bool   // Upgrade ldap:// connections with StartTLS
+	BindDN       string // Service account used to look users up; empty searches anonymously
+	BindPassword string
+	BaseDN       string // Subtree searched for users
+	// UserFilter finds the user signing in; {username} is replaced by the escaped username
+	UserFilter string
+	// IDAttribute holds a value that stays the same when a user is renamed or moved, such
+	// as entryUUID or objectGUID; empty uses the entry's DN
+	IDAttribute        string
+	UsernameAttribute  string
+	EmailAttribute     string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	// GroupAttribute lists the groups a user belongs to by DN, such as memberOf; groups
+	// are matched by their common name
+	GroupAttribute string
+	// AutoProvision creates an account on a directory user's first sign-in
+	AutoProvision bool
+	// LinkByUsername links a first sign-in to the existing local account with the same username
+	LinkByUsername bool
+	// RoleMapping maps group common names to account roles; when set, the role is synced on
+	// every sign-in and the highest matching role applies
+	RoleMapping map[string]string
+	// DefaultRole is given to directory users matching no mapping
+	DefaultRole string
+	// LocalLoginRoles are the roles whose local accounts may still sign in with a local
+	// password, such as break-glass administrators
+	LocalLoginRoles []string
+}
+
+// IntegrityConfig holds tamper-evident result configuration
+type IntegrityConfig struct {
+	SigningKeyFile string // Ed25519 private key in PEM, created on first start if missing
+}
+
+// AccountConfig holds password reset, email verification and login throttling configuration
+type AccountConfig struct {
+	TokenKeyFile string // HMAC key signing reset and verification links, created on first start if missing
+	// PasswordResetExpiration is how long a password reset link works
+	PasswordResetExpiration time.Duration
+	// EmailVerificationExpiration is how long an email verification link works
+	EmailVerificationExpiration time.Duration
+	// LoginMaxFailures is how many failed sign-ins a username may have within
+	// LoginFailureWindow before further attempts are refused
+	LoginMaxFailures int
+	// LoginMaxFailuresPerIP is how many failed sign-ins an IP address may have within
+	// LoginFailureWindow, across all usernames
+	LoginMaxFailuresPerIP int
+	LoginFailureWindow    time.Duration
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver       string // smtp, file to write messages to OutboxDir, or log to print them
+	From         string // Sender address, e.g. GoCBT <no-reply@school.example>
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Empty sends without authentication
+	SMTPPassword string
+	OutboxDir    string // Directory the file driver writes .eml files to
 }
 
 // AppConfig holds application-specific configuration
@@ -47,17 +149,23 @@ type AppConfig struct {
 	Environment string
 	LogLevel    string
 	CORSOrigins []string
+	FrontendURL string
+	// CertificateVerifyURL is printed on certificates, followed by the verification ID
+	CertificateVerifyURL string
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer string
 }
 
 // Load loads configuration from environment variables with defaults
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host:         getEnv("SERVER_HOST", "localhost"),
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
-			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
-			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			Host:              getEnv("SERVER_HOST", "localhost"),
+			Port:              getEnv("SERVER_PORT", "8080"),
+			ReadTimeout:       getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:      getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
+			IdleTimeout:       getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			TrustProxyHeaders: getBoolEnv("SERVER_TRUST_PROXY_HEADERS", false),
 		},
 		Database: DatabaseConfig{
 			Driver:   getEnv("DB_DRIVER", "postgres"),
@@ -70,13 +178,72 @@ func Load() *Config {
 			FilePath: getEnv("DB_FILEPATH", "./gocbt.db"),
 		},
 		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-			Expiration: getDurationEnv("JWT_EXPIRATION", 24*time.Hour),
+			Algorithm:         getEnv("JWT_ALGORITHM", "EdDSA"),
+			Secret:            getEnv("JWT_SECRET", ""),
+			KeyDir:            getEnv("JWT_KEY_DIR", "./jwt_keys"),
+			RotationInterval:  getDurationEnv("JWT_KEY_ROTATION", 30*24*time.Hour),
+			Expiration:        getDurationEnv("JWT_EXPIRATION", 15*time.Minute),
+			RefreshExpiration: getDurationEnv("JWT_REFRESH_EXPIRATION", 30*24*time.Hour),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:      getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:       getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:   getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:    getEnv("OIDC_REDIRECT_URL", getEnv("FRONTEND_URL", "http://localhost:3000")+"/auth/callback"),
+			Scopes:         getListEnv("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			AllowedDomains: getListEnv("OIDC_ALLOWED_DOMAINS", nil),
+			AutoProvision:  getBoolEnv("OIDC_AUTO_PROVISION", true),
+			LinkByEmail:    getBoolEnv("OIDC_LINK_BY_EMAIL", false),
+			RoleClaim:      getEnv("OIDC_ROLE_CLAIM", "groups"),
+			RoleMapping:    getMapEnv("OIDC_ROLE_MAPPING"),
+			DefaultRole:    getEnv("OIDC_DEFAULT_ROLE", "student"),
+		},
+		LDAP: LDAPConfig{
+			URL:                getEnv("LDAP_URL", ""),
+			StartTLS:           getBoolEnv("LDAP_START_TLS", false),
+			BindDN:             getEnv("LDAP_BIND_DN", ""),
+			BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:             getEnv("LDAP_BASE_DN", ""),
+			UserFilter:         getEnv("LDAP_USER_FILTER", "(uid={username})"),
+			IDAttribute:        getEnv("LDAP_ID_ATTRIBUTE", ""),
+			UsernameAttribute:  getEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+			EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			FirstNameAttribute: getEnv("LDAP_FIRST_NAME_ATTRIBUTE", "givenName"),
+			LastNameAttribute:  getEnv("LDAP_LAST_NAME_ATTRIBUTE", "sn"),
+			GroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			AutoProvision:      getBoolEnv("LDAP_AUTO_PROVISION", true),
+			LinkByUsername:     getBoolEnv("LDAP_LINK_BY_USERNAME", false),
+			RoleMapping:        getMapEnv("LDAP_ROLE_MAPPING"),
+			DefaultRole:        getEnv("LDAP_DEFAULT_ROLE", "student"),
+			LocalLoginRoles:    getListEnv("LDAP_LOCAL_LOGIN_ROLES", []string{"admin"}),
+		},
+		Integrity: IntegrityConfig{
+			SigningKeyFile: getEnv("RESULT_SIGNING_KEY_FILE", "./result_signing.key"),
+		},
+		Account: AccountConfig{
+			TokenKeyFile:                getEnv("ACCOUNT_TOKEN_KEY_FILE", "./account_token.key"),
+			PasswordResetExpiration:     getDurationEnv("PASSWORD_RESET_EXPIRATION", time.Hour),
+			EmailVerificationExpiration: getDurationEnv("EMAIL_VERIFICATION_EXPIRATION", 72*time.Hour),
+			LoginMaxFailures:            getIntEnv("LOGIN_MAX_FAILURES", 5),
+			LoginMaxFailuresPerIP:       getIntEnv("LOGIN_MAX_FAILURES_PER_IP", 50),
+			LoginFailureWindow:          getDurationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "GoCBT <no-reply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			OutboxDir:    getEnv("MAIL_OUTBOX_DIR", "./mail_outbox"),
 		},
 		App: AppConfig{
-			Environment: getEnv("APP_ENV", "development"),
-			LogLevel:    getEnv("LOG_LEVEL", "info"),
-			CORSOrigins: getCORSOrigins(),
+			Environment:          getEnv("APP_ENV", "development"),
+			LogLevel:             getEnv("LOG_LEVEL", "info"),
+			CORSOrigins:          getCORSOrigins(),
+			FrontendURL:          getEnv("FRONTEND_URL", "http://localhost:3000"),
+			CertificateVerifyURL: getEnv("CERTIFICATE_VERIFY_URL", "http://localhost:8080/api/v1/certificates/verify"),
+			TOTPIssuer:           getEnv("TOTP_ISSUER", "GoCBT"),
 		},
 	}
 }
@@ -109,6 +276,46 @@ func getIntEnv(key string, fallback int) int {
 	return fallback
 }
 
+// getBoolEnv gets a boolean environment variable with a fallback value
+func getBoolEnv(key string, fallback bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return fallback
+}
+
+// getListEnv gets a comma-separated list environment variable with a fallback value
+func getListEnv(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// getMapEnv gets a comma-separated list of key:value pairs from an environment variable.
+// Keys may contain colons; the value follows the last one.
+func getMapEnv(key string) map[string]string {
+	pairs := make(map[string]string)
+	for _, item := range getListEnv(key, nil) {
+		index := strings.LastIndex(item, ":")
+		if index < 0 {
+			continue
+		}
+		pairs[strings.TrimSpace(item[:index])] = strings.TrimSpace(item[index+1:])
+	}
+	return pairs
+}
+
 // getCORSOrigins parses CORS origins from environment variable
 func getCORSOrigins() []string {
 	corsOrigins := getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:8080")
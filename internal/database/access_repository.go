@@ -0,0 +1,150 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"strings"
+	"time"
+)
+
+// TestAccessRepository implements the models.TestAccessRepository interface
+type TestAccessRepository struct {
+	db *DB
+}
+
+// NewTestAccessRepository creates a new test access repository
+func NewTestAccessRepository(db *DB) models.TestAccessRepository {
+	return &TestAccessRepository{db: db}
+}
+
+// GetPolicy retrieves the access policy for a test
+func (r *TestAccessRepository) GetPolicy(testID int) (*models.TestAccessPolicy, error) {
+	query := `
+		SELECT test_id, access_code, code_rotation_minutes, allowed_cidrs, created_at, updated_at
+		FROM test_access_policies WHERE test_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT test_id, access_code, code_rotation_minutes, allowed_cidrs, created_at, updated_at
+			FROM test_access_policies WHERE test_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, testID)
+	return models.ScanTestAccessPolicy(row)
+}
+
+// UpsertPolicy creates or replaces the access policy for a test
+func (r *TestAccessRepository) UpsertPolicy(policy *models.TestAccessPolicy) error {
+	query := `
+		INSERT INTO test_access_policies (test_id, access_code, code_rotation_minutes, allowed_cidrs, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (test_id) DO UPDATE
+		SET access_code = excluded.access_code, code_rotation_minutes = excluded.code_rotation_minutes,
+			allowed_cidrs = excluded.allowed_cidrs, updated_at = excluded.updated_at
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO test_access_policies (test_id, access_code, code_rotation_minutes, allowed_cidrs, updated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (test_id) DO UPDATE
+			SET access_code = excluded.access_code, code_rotation_minutes = excluded.code_rotation_minutes,
+				allowed_cidrs = excluded.allowed_cidrs, updated_at = excluded.updated_at
+		`
+	}
+
+	policy.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, policy.TestID, policy.AccessCode, policy.CodeRotationMinutes,
+		strings.Join(policy.AllowedCIDRs, ","), policy.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if policy.CreatedAt.IsZero() {
+		policy.CreatedAt = policy.UpdatedAt
+	}
+	return nil
+}
+
+// DeletePolicy removes the access policy for a test
+func (r *TestAccessRepository) DeletePolicy(testID int) error {
+	query := "DELETE FROM test_access_policies WHERE test_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM test_access_policies WHERE test_id = $1"
+	}
+
+	_, err := r.db.Exec(query, testID)
+	return err
+}
+
+// CreateFailure records a rejected session start
+func (r *TestAccessRepository) CreateFailure(failure *models.AccessGateFailure) error {
+	query := `
+		INSERT INTO access_gate_failures (test_id, user_id, ip_address, reason)
+		VALUES (?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO access_gate_failures (test_id, user_id, ip_address, reason)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at
+		`
+	}
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, failure.TestID, failure.UserID, failure.IPAddress,
+			failure.Reason).Scan(&failure.ID, &failure.CreatedAt)
+		return err
+	}
+
+	result, err := r.db.Exec(query, failure.TestID, failure.UserID, failure.IPAddress,
+		failure.Reason)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	failure.ID = int(id)
+	failure.CreatedAt = time.Now()
+	return nil
+}
+
+// GetFailuresByTest retrieves rejected session starts for a test with pagination
+func (r *TestAccessRepository) GetFailuresByTest(testID int, limit, offset int) ([]*models.AccessGateFailure, error) {
+	query := `
+		SELECT id, test_id, user_id, ip_address, reason, created_at
+		FROM access_gate_failures WHERE test_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, test_id, user_id, ip_address, reason, created_at
+			FROM access_gate_failures WHERE test_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
+		`
+	}
+
+	rows, err := r.db.Query(query, testID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []*models.AccessGateFailure
+	for rows.Next() {
+		failure, err := models.ScanAccessGateFailure(rows)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			failures = append(failures, failure)
+		}
+	}
+
+	return failures, rows.Err()
+}
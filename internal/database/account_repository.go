@@ -0,0 +1,157 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// AccountRepository implements the models.AccountRepository interface
+type AccountRepository struct {
+	db *DB
+}
+
+// NewAccountRepository creates a new account repository
+func NewAccountRepository(db *DB) models.AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// CreateAccountToken stores the record of a newly signed account token
+func (r *AccountRepository) CreateAccountToken(token *models.AccountToken) error {
+	query := `
+		INSERT INTO account_tokens (id, user_id, purpose, email, expires_at, used_at, created_at)
+		VALUES (?, ?, ?, ?, ?, NULL, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO account_tokens (id, user_id, purpose, email, expires_at, used_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, NULL, $6)
+		`
+	}
+
+	token.UsedAt = nil
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(query, token.ID, token.UserID, token.Purpose, token.Email, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+// GetAccountToken retrieves the record of an account token by its ID
+func (r *AccountRepository) GetAccountToken(id string) (*models.AccountToken, error) {
+	query := "SELECT id, user_id, purpose, email, expires_at, used_at, created_at FROM account_tokens WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT id, user_id, purpose, email, expires_at, used_at, created_at FROM account_tokens WHERE id = $1"
+	}
+
+	return models.ScanAccountToken(r.db.QueryRow(query, id))
+}
+
+// UseAccountToken marks an account token as used, reporting false if it already was
+func (r *AccountRepository) UseAccountToken(id string, usedAt time.Time) (bool, error) {
+	query := "UPDATE account_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE account_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL"
+	}
+
+	result, err := r.db.Exec(query, usedAt, id)
+	if err != nil {
+		return false, err
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return used > 0, nil
+}
+
+// InvalidateAccountTokens marks a user's unused tokens of a purpose as used, so that only
+// the newest link works
+func (r *AccountRepository) InvalidateAccountTokens(userID int, purpose string, usedAt time.Time) error {
+	query := "UPDATE account_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE account_tokens SET used_at = $1 WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL"
+	}
+
+	_, err := r.db.Exec(query, usedAt, userID, purpose)
+	return err
+}
+
+// CountAccountTokensSince counts the tokens of a purpose issued to a user since the given time
+func (r *AccountRepository) CountAccountTokensSince(userID int, purpose string, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM account_tokens WHERE user_id = ? AND purpose = ? AND created_at >= ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT COUNT(*) FROM account_tokens WHERE user_id = $1 AND purpose = $2 AND created_at >= $3"
+	}
+
+	var count int
+	err := r.db.QueryRow(query, userID, purpose, since).Scan(&count)
+	return count, err
+}
+
+// DeleteExpiredAccountTokens deletes account tokens that expired before the given time
+func (r *AccountRepository) DeleteExpiredAccountTokens(before time.Time) error {
+	query := "DELETE FROM account_tokens WHERE expires_at < ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM account_tokens WHERE expires_at < $1"
+	}
+
+	_, err := r.db.Exec(query, before)
+	return err
+}
+
+// RecordFailedLogin records a failed sign-in for a username from an IP address
+func (r *AccountRepository) RecordFailedLogin(username, ipAddress string, attemptedAt time.Time) error {
+	query := "INSERT INTO failed_logins (username, ip_address, attempted_at) VALUES (?, ?, ?)"
+	if r.db.Driver == "postgres" {
+		query = "INSERT INTO failed_logins (username, ip_address, attempted_at) VALUES ($1, $2, $3)"
+	}
+
+	_, err := r.db.Exec(query, username, ipAddress, attemptedAt)
+	return err
+}
+
+// CountFailedLogins counts the failed sign-ins for a username since the given time
+func (r *AccountRepository) CountFailedLogins(username string, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM failed_logins WHERE username = ? AND attempted_at >= ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT COUNT(*) FROM failed_logins WHERE username = $1 AND attempted_at >= $2"
+	}
+
+	var count int
+	err := r.db.QueryRow(query, username, since).Scan(&count)
+	return count, err
+}
+
+// CountFailedLoginsFromIP counts the failed sign-ins from an IP address since the given time
+func (r *AccountRepository) CountFailedLoginsFromIP(ipAddress string, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM failed_logins WHERE ip_address = ? AND attempted_at >= ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT COUNT(*) FROM failed_logins WHERE ip_address = $1 AND attempted_at >= $2"
+	}
+
+	var count int
+	err := r.db.QueryRow(query, ipAddress, since).Scan(&count)
+	return count, err
+}
+
+// ClearFailedLogins deletes the failed sign-ins of a username
+func (r *AccountRepository) ClearFailedLogins(username string) error {
+	query := "DELETE FROM failed_logins WHERE username = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM failed_logins WHERE username = $1"
+	}
+
+	_, err := r.db.Exec(query, username)
+	return err
+}
+
+// DeleteFailedLoginsBefore deletes failed sign-ins older than the given time
+func (r *AccountRepository) DeleteFailedLoginsBefore(before time.Time) error {
+	query := "DELETE FROM failed_logins WHERE attempted_at < ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM failed_logins WHERE attempted_at < $1"
+	}
+
+	_, err := r.db.Exec(query, before)
+	return err
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// AdaptiveRepository implements the models.AdaptiveRepository interface
+type AdaptiveRepository struct {
+	db *DB
+}
+
+// NewAdaptiveRepository creates a new adaptive testing repository
+func NewAdaptiveRepository(db *DB) models.AdaptiveRepository {
+	return &AdaptiveRepository{db: db}
+}
+
+// GetSettings retrieves the adaptive settings for a test
+func (r *AdaptiveRepository) GetSettings(testID int) (*models.AdaptiveSettings, error) {
+	query := `
+		SELECT test_id, min_questions, max_questions, target_se, passing_theta, created_at, updated_at
+		FROM test_adaptive_settings WHERE test_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT test_id, min_questions, max_questions, target_se, passing_theta, created_at, updated_at
+			FROM test_adaptive_settings WHERE test_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, testID)
+	return models.ScanAdaptiveSettings(row)
+}
+
+// UpsertSettings creates or replaces the adaptive settings for a test
+func (r *AdaptiveRepository) UpsertSettings(settings *models.AdaptiveSettings) error {
+	query := `
+		INSERT INTO test_adaptive_settings (test_id, min_questions, max_questions, target_se, passing_theta, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (test_id) DO UPDATE
+		SET min_questions = excluded.min_questions, max_questions = excluded.max_questions,
+			target_se = excluded.target_se, passing_theta = excluded.passing_theta, updated_at = excluded.updated_at
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO test_adaptive_settings (test_id, min_questions, max_questions, target_se, passing_theta, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (test_id) DO UPDATE
+			SET min_questions = excluded.min_questions, max_questions = excluded.max_questions,
+				target_se = excluded.target_se, passing_theta = excluded.passing_theta, updated_at = excluded.updated_at
+		`
+	}
+
+	settings.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, settings.TestID, settings.MinQuestions, settings.MaxQuestions,
+		settings.TargetSE, settings.PassingTheta, settings.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if settings.CreatedAt.IsZero() {
+		settings.CreatedAt = settings.UpdatedAt
+	}
+	return nil
+}
+
+// DeleteSettings removes the adaptive settings for a test
+func (r *AdaptiveRepository) DeleteSettings(testID int) error {
+	query := "DELETE FROM test_adaptive_settings WHERE test_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM test_adaptive_settings WHERE test_id = $1"
+	}
+
+	_, err := r.db.Exec(query, testID)
+	return err
+}
+
+// CreateStep records a question administered in an adaptive session
+func (r *AdaptiveRepository) CreateStep(step *models.AdaptiveStep) error {
+	query := `
+		INSERT INTO session_adaptive_steps (session_id, step_number, question_id, is_correct, theta, theta_se, answered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO session_adaptive_steps (session_id, step_number, question_id, is_correct, theta, theta_se, answered_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at
+		`
+	}
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, step.SessionID, step.StepNumber, step.QuestionID,
+			step.IsCorrect, step.Theta, step.ThetaSE, step.AnsweredAt).Scan(&step.ID, &step.CreatedAt)
+		return err
+	}
+
+	res, err := r.db.Exec(query, step.SessionID, step.StepNumber, step.QuestionID,
+		step.IsCorrect, step.Theta, step.ThetaSE, step.AnsweredAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	step.ID = int(id)
+	step.CreatedAt = time.Now()
+	return nil
+}
+
+// UpdateStep records the answer and ability estimate of an adaptive step
+func (r *AdaptiveRepository) UpdateStep(step *models.AdaptiveStep) error {
+	query := `
+		UPDATE session_adaptive_steps
+		SET is_correct = ?, theta = ?, theta_se = ?, answered_at = ?
+		WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			UPDATE session_adaptive_steps
+			SET is_correct = $1, theta = $2, theta_se = $3, answered_at = $4
+			WHERE id = $5
+		`
+	}
+
+	_, err := r.db.Exec(query, step.IsCorrect, step.Theta, step.ThetaSE, step.AnsweredAt, step.ID)
+	return err
+}
+
+// GetSteps retrieves the steps of an adaptive session in order
+func (r *AdaptiveRepository) GetSteps(sessionID int) ([]*models.AdaptiveStep, error) {
+	query := `
+		SELECT id, session_id, step_number, question_id, is_correct, theta, theta_se, created_at, answered_at
+		FROM session_adaptive_steps WHERE session_id = ? ORDER BY step_number ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, session_id, step_number, question_id, is_correct, theta, theta_se, created_at, answered_at
+			FROM session_adaptive_steps WHERE session_id = $1 ORDER BY step_number ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []*models.AdaptiveStep{}
+	for rows.Next() {
+		step, err := models.ScanAdaptiveStep(rows)
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			steps = append(steps, step)
+		}
+	}
+
+	return steps, rows.Err()
+}
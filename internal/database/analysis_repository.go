@@ -0,0 +1,56 @@
+package database
+
+import (
+	"gocbt/internal/models"
+)
+
+// AnalysisRepository implements the models.AnalysisRepository interface
+type AnalysisRepository struct {
+	db *DB
+}
+
+// NewAnalysisRepository creates a new test analysis repository
+func NewAnalysisRepository(db *DB) models.AnalysisRepository {
+	return &AnalysisRepository{db: db}
+}
+
+// GetItemResponses retrieves every answer of every graded result of a test. Results
+// without any answers are returned once with empty answer columns.
+func (r *AnalysisRepository) GetItemResponses(testID int) ([]*models.ItemResponse, error) {
+	query := `
+		SELECT tr.id, tr.marks_obtained, tr.percentage, ua.question_id, ua.selected_option_id, ua.answer_text, ua.is_correct, ua.marks_awarded
+		FROM test_results tr
+		LEFT JOIN user_answers ua ON ua.session_id = tr.session_id
+		WHERE tr.test_id = ?
+		ORDER BY tr.id ASC, ua.question_id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT tr.id, tr.marks_obtained, tr.percentage, ua.question_id, ua.selected_option_id, ua.answer_text, ua.is_correct, ua.marks_awarded
+			FROM test_results tr
+			LEFT JOIN user_answers ua ON ua.session_id = tr.session_id
+			WHERE tr.test_id = $1
+			ORDER BY tr.id ASC, ua.question_id ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var responses []*models.ItemResponse
+	for rows.Next() {
+		response, err := models.ScanItemResponse(rows)
+		if err != nil {
+			return nil, err
+		}
+		if response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	return responses, rows.Err()
+}
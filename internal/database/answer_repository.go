@@ -123,6 +123,40 @@ func (r *UserAnswerRepository) GetBySession(sessionID int) ([]*models.UserAnswer
 	return answers, rows.Err()
 }
 
+// GetByQuestion retrieves all answers given to a question across sessions
+func (r *UserAnswerRepository) GetByQuestion(questionID int) ([]*models.UserAnswer, error) {
+	query := `
+		SELECT id, session_id, question_id, answer_text, selected_option_id, is_correct, marks_awarded, answered_at
+		FROM user_answers WHERE question_id = ? ORDER BY session_id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, session_id, question_id, answer_text, selected_option_id, is_correct, marks_awarded, answered_at
+			FROM user_answers WHERE question_id = $1 ORDER BY session_id ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var answers []*models.UserAnswer
+	for rows.Next() {
+		answer, err := models.ScanUserAnswer(rows)
+		if err != nil {
+			return nil, err
+		}
+		if answer != nil {
+			answers = append(answers, answer)
+		}
+	}
+
+	return answers, rows.Err()
+}
+
 // Update updates a user answer
 func (r *UserAnswerRepository) Update(answer *models.UserAnswer) error {
 	query := `
@@ -154,3 +188,14 @@ func (r *UserAnswerRepository) Delete(id int) error {
 	_, err := r.db.Exec(query, id)
 	return err
 }
+
+// DeleteBySession deletes all answers of a session
+func (r *UserAnswerRepository) DeleteBySession(sessionID int) error {
+	query := "DELETE FROM user_answers WHERE session_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM user_answers WHERE session_id = $1"
+	}
+
+	_, err := r.db.Exec(query, sessionID)
+	return err
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// CertificateRepository implements the models.CertificateRepository interface
+type CertificateRepository struct {
+	db *DB
+}
+
+// NewCertificateRepository creates a new certificate repository
+func NewCertificateRepository(db *DB) models.CertificateRepository {
+	return &CertificateRepository{db: db}
+}
+
+// GetTemplate retrieves the certificate template for a test
+func (r *CertificateRepository) GetTemplate(testID int) (*models.CertificateTemplate, error) {
+	query := `
+		SELECT test_id, title, body, signatory_name, signatory_title, created_at, updated_at
+		FROM certificate_templates WHERE test_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT test_id, title, body, signatory_name, signatory_title, created_at, updated_at
+			FROM certificate_templates WHERE test_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, testID)
+	return models.ScanCertificateTemplate(row)
+}
+
+// UpsertTemplate creates or replaces the certificate template for a test
+func (r *CertificateRepository) UpsertTemplate(template *models.CertificateTemplate) error {
+	query := `
+		INSERT INTO certificate_templates (test_id, title, body, signatory_name, signatory_title, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (test_id) DO UPDATE
+		SET title = excluded.title, body = excluded.body, signatory_name = excluded.signatory_name,
+			signatory_title = excluded.signatory_title, updated_at = excluded.updated_at
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO certificate_templates (test_id, title, body, signatory_name, signatory_title, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (test_id) DO UPDATE
+			SET title = excluded.title, body = excluded.body, signatory_name = excluded.signatory_name,
+				signatory_title = excluded.signatory_title, updated_at = excluded.updated_at
+		`
+	}
+
+	template.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, template.TestID, template.Title, template.Body,
+		template.SignatoryName, template.SignatoryTitle, template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = template.UpdatedAt
+	}
+	return nil
+}
+
+// DeleteTemplate removes the certificate template for a test
+func (r *CertificateRepository) DeleteTemplate(testID int) error {
+	query := "DELETE FROM certificate_templates WHERE test_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM certificate_templates WHERE test_id = $1"
+	}
+
+	_, err := r.db.Exec(query, testID)
+	return err
+}
+
+// Create records an issued certificate
+func (r *CertificateRepository) Create(certificate *models.Certificate) error {
+	query := `
+		INSERT INTO certificates (verification_id, result_id, test_id, user_id, candidate_name, test_title, percentage, grade, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO certificates (verification_id, result_id, test_id, user_id, candidate_name, test_title, percentage, grade, completed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id, issued_at
+		`
+	}
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, certificate.VerificationID, certificate.ResultID, certificate.TestID,
+			certificate.UserID, certificate.CandidateName, certificate.TestTitle, certificate.Percentage,
+			certificate.Grade, certificate.CompletedAt).Scan(&certificate.ID, &certificate.IssuedAt)
+		return err
+	}
+
+	res, err := r.db.Exec(query, certificate.VerificationID, certificate.ResultID, certificate.TestID,
+		certificate.UserID, certificate.CandidateName, certificate.TestTitle, certificate.Percentage,
+		certificate.Grade, certificate.CompletedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	certificate.ID = int(id)
+	certificate.IssuedAt = time.Now()
+	return nil
+}
+
+// GetByResultID retrieves the certificate issued for a result
+func (r *CertificateRepository) GetByResultID(resultID int) (*models.Certificate, error) {
+	query := `
+		SELECT id, verification_id, result_id, test_id, user_id, candidate_name, test_title, percentage, grade, completed_at, issued_at
+		FROM certificates WHERE result_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, verification_id, result_id, test_id, user_id, candidate_name, test_title, percentage, grade, completed_at, issued_at
+			FROM certificates WHERE result_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, resultID)
+	return models.ScanCertificate(row)
+}
+
+// GetByVerificationID retrieves a certificate by its verification ID
+func (r *CertificateRepository) GetByVerificationID(verificationID string) (*models.Certificate, error) {
+	query := `
+		SELECT id, verification_id, result_id, test_id, user_id, candidate_name, test_title, percentage, grade, completed_at, issued_at
+		FROM certificates WHERE verification_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, verification_id, result_id, test_id, user_id, candidate_name, test_title, percentage, grade, completed_at, issued_at
+			FROM certificates WHERE verification_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, verificationID)
+	return models.ScanCertificate(row)
+}
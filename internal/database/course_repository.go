@@ -0,0 +1,364 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// CourseRepository implements the models.CourseRepository interface
+type CourseRepository struct {
+	db *DB
+}
+
+// NewCourseRepository creates a new course repository
+func NewCourseRepository(db *DB) models.CourseRepository {
+	return &CourseRepository{db: db}
+}
+
+// Create creates a new course
+func (r *CourseRepository) Create(course *models.Course) error {
+	query := `
+		INSERT INTO courses (name, description, created_by, grading_scale_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO courses (name, description, created_by, grading_scale_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+		`
+	}
+
+	now := time.Now()
+	course.CreatedAt = now
+	course.UpdatedAt = now
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, course.Name, course.Description, course.CreatedBy, course.GradingScaleID,
+			course.CreatedAt, course.UpdatedAt).Scan(&course.ID)
+		return err
+	}
+
+	result, err := r.db.Exec(query, course.Name, course.Description, course.CreatedBy, course.GradingScaleID,
+		course.CreatedAt, course.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	course.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves a course by ID
+func (r *CourseRepository) GetByID(id int) (*models.Course, error) {
+	query := `
+		SELECT id, name, description, created_by, grading_scale_id, created_at, updated_at
+		FROM courses WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, name, description, created_by, grading_scale_id, created_at, updated_at
+			FROM courses WHERE id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, id)
+	return models.ScanCourse(row)
+}
+
+// List retrieves courses ordered by name with pagination; an owner ID of 0 lists every course
+func (r *CourseRepository) List(ownerID int, limit, offset int) ([]*models.Course, error) {
+	query := `
+		SELECT id, name, description, created_by, grading_scale_id, created_at, updated_at
+		FROM courses WHERE (? = 0 OR created_by = ?)
+		ORDER BY name ASC, id ASC LIMIT ? OFFSET ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, name, description, created_by, grading_scale_id, created_at, updated_at
+			FROM courses WHERE ($1 = 0 OR created_by = $2)
+			ORDER BY name ASC, id ASC LIMIT $3 OFFSET $4
+		`
+	}
+
+	rows, err := r.db.Query(query, ownerID, ownerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []*models.Course
+	for rows.Next() {
+		course, err := models.ScanCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		if course != nil {
+			courses = append(courses, course)
+		}
+	}
+
+	return courses, rows.Err()
+}
+
+// Update updates a course's name, description and grading scale
+func (r *CourseRepository) Update(course *models.Course) error {
+	query := `
+		UPDATE courses SET name = ?, description = ?, grading_scale_id = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			UPDATE courses SET name = $1, description = $2, grading_scale_id = $3, updated_at = $4
+			WHERE id = $5
+		`
+	}
+
+	course.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, course.Name, course.Description, course.GradingScaleID, course.UpdatedAt, course.ID)
+	return err
+}
+
+// Delete deletes a course with its categories and test assignments
+func (r *CourseRepository) Delete(id int) error {
+	queries := []string{
+		"DELETE FROM course_tests WHERE course_id = ?",
+		"DELETE FROM course_categories WHERE course_id = ?",
+		"DELETE FROM courses WHERE id = ?",
+	}
+
+	if r.db.Driver == "postgres" {
+		queries = []string{
+			"DELETE FROM course_tests WHERE course_id = $1",
+			"DELETE FROM course_categories WHERE course_id = $1",
+			"DELETE FROM courses WHERE id = $1",
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceCategories replaces all categories of a course and the tests assigned to them
+func (r *CourseRepository) ReplaceCategories(courseID int, categories []*models.CourseCategory) error {
+	deleteTestsQuery := "DELETE FROM course_tests WHERE course_id = ?"
+	deleteCategoriesQuery := "DELETE FROM course_categories WHERE course_id = ?"
+	insertCategoryQuery := `
+		INSERT INTO course_categories (course_id, name, weight, drop_lowest, position)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	insertTestQuery := "INSERT INTO course_tests (course_id, category_id, test_id) VALUES (?, ?, ?)"
+
+	if r.db.Driver == "postgres" {
+		deleteTestsQuery = "DELETE FROM course_tests WHERE course_id = $1"
+		deleteCategoriesQuery = "DELETE FROM course_categories WHERE course_id = $1"
+		insertCategoryQuery = `
+			INSERT INTO course_categories (course_id, name, weight, drop_lowest, position)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id
+		`
+		insertTestQuery = "INSERT INTO course_tests (course_id, category_id, test_id) VALUES ($1, $2, $3)"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteTestsQuery, courseID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(deleteCategoriesQuery, courseID); err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		category.CourseID = courseID
+
+		if r.db.Driver == "postgres" {
+			err := tx.QueryRow(insertCategoryQuery, category.CourseID, category.Name, category.Weight,
+				category.DropLowest, category.Position).Scan(&category.ID)
+			if err != nil {
+				return err
+			}
+		} else {
+			result, err := tx.Exec(insertCategoryQuery, category.CourseID, category.Name, category.Weight,
+				category.DropLowest, category.Position)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			category.ID = int(id)
+		}
+
+		for _, testID := range category.TestIDs {
+			if _, err := tx.Exec(insertTestQuery, courseID, category.ID, testID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCategories retrieves the categories of a course in order, with their test IDs
+func (r *CourseRepository) GetCategories(courseID int) ([]*models.CourseCategory, error) {
+	categoryQuery := `
+		SELECT id, course_id, name, weight, drop_lowest, position
+		FROM course_categories WHERE course_id = ? ORDER BY position ASC, id ASC
+	`
+	testQuery := `
+		SELECT ct.category_id, ct.test_id
+		FROM course_tests ct
+		JOIN tests t ON t.id = ct.test_id
+		WHERE ct.course_id = ? ORDER BY ct.test_id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		categoryQuery = `
+			SELECT id, course_id, name, weight, drop_lowest, position
+			FROM course_categories WHERE course_id = $1 ORDER BY position ASC, id ASC
+		`
+		testQuery = `
+			SELECT ct.category_id, ct.test_id
+			FROM course_tests ct
+			JOIN tests t ON t.id = ct.test_id
+			WHERE ct.course_id = $1 ORDER BY ct.test_id ASC
+		`
+	}
+
+	rows, err := r.db.Query(categoryQuery, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.CourseCategory
+	byID := make(map[int]*models.CourseCategory)
+	for rows.Next() {
+		category, err := models.ScanCourseCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		if category != nil {
+			category.TestIDs = []int{}
+			categories = append(categories, category)
+			byID[category.ID] = category
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	testRows, err := r.db.Query(testQuery, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer testRows.Close()
+
+	for testRows.Next() {
+		var categoryID, testID int
+		if err := testRows.Scan(&categoryID, &testID); err != nil {
+			return nil, err
+		}
+		if category, ok := byID[categoryID]; ok {
+			category.TestIDs = append(category.TestIDs, testID)
+		}
+	}
+
+	return categories, testRows.Err()
+}
+
+// GetResults retrieves the graded results of every student on the tests of a course
+func (r *CourseRepository) GetResults(courseID int) ([]*models.CourseResultRow, error) {
+	query := `
+		SELECT tr.id, tr.test_id, tr.user_id, u.username, u.first_name, u.last_name, u.email, tr.percentage, tr.completed_at
+		FROM test_results tr
+		JOIN course_tests ct ON ct.test_id = tr.test_id
+		JOIN users u ON u.id = tr.user_id
+		WHERE ct.course_id = ?
+		ORDER BY u.last_name ASC, u.first_name ASC, u.username ASC, tr.test_id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT tr.id, tr.test_id, tr.user_id, u.username, u.first_name, u.last_name, u.email, tr.percentage, tr.completed_at
+			FROM test_results tr
+			JOIN course_tests ct ON ct.test_id = tr.test_id
+			JOIN users u ON u.id = tr.user_id
+			WHERE ct.course_id = $1
+			ORDER BY u.last_name ASC, u.first_name ASC, u.username ASC, tr.test_id ASC
+		`
+	}
+
+	return r.queryResults(query, courseID)
+}
+
+// GetUserResults retrieves the graded results of one student on the tests of a course
+func (r *CourseRepository) GetUserResults(courseID, userID int) ([]*models.CourseResultRow, error) {
+	query := `
+		SELECT tr.id, tr.test_id, tr.user_id, u.username, u.first_name, u.last_name, u.email, tr.percentage, tr.completed_at
+		FROM test_results tr
+		JOIN course_tests ct ON ct.test_id = tr.test_id
+		JOIN users u ON u.id = tr.user_id
+		WHERE ct.course_id = ? AND tr.user_id = ?
+		ORDER BY tr.test_id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT tr.id, tr.test_id, tr.user_id, u.username, u.first_name, u.last_name, u.email, tr.percentage, tr.completed_at
+			FROM test_results tr
+			JOIN course_tests ct ON ct.test_id = tr.test_id
+			JOIN users u ON u.id = tr.user_id
+			WHERE ct.course_id = $1 AND tr.user_id = $2
+			ORDER BY tr.test_id ASC
+		`
+	}
+
+	return r.queryResults(query, courseID, userID)
+}
+
+// queryResults runs a course result query
+func (r *CourseRepository) queryResults(query string, args ...interface{}) ([]*models.CourseResultRow, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.CourseResultRow
+	for rows.Next() {
+		result, err := models.ScanCourseResultRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, rows.Err()
+}
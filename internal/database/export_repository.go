@@ -0,0 +1,108 @@
+package database
+
+import (
+	"gocbt/internal/models"
+)
+
+// ExportRepository implements the models.ExportRepository interface
+type ExportRepository struct {
+	db *DB
+}
+
+// NewExportRepository creates a new export repository
+func NewExportRepository(db *DB) models.ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// GetResultRows retrieves every graded result of a test with its candidate, ordered by name
+func (r *ExportRepository) GetResultRows(testID int) ([]*models.ResultExportRow, error) {
+	query := `
+		SELECT tr.id, tr.session_id, tr.test_id, tr.user_id, tr.total_questions, tr.answered_questions, tr.correct_answers, tr.total_marks, tr.marks_obtained, tr.percentage, tr.grade, tr.grade_points, tr.is_passed, tr.time_taken, tr.has_override, tr.theta, tr.theta_se, tr.completed_at,
+			u.username, u.first_name, u.last_name, u.email
+		FROM test_results tr
+		JOIN users u ON u.id = tr.user_id
+		WHERE tr.test_id = ?
+		ORDER BY u.last_name ASC, u.first_name ASC, tr.id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT tr.id, tr.session_id, tr.test_id, tr.user_id, tr.total_questions, tr.answered_questions, tr.correct_answers, tr.total_marks, tr.marks_obtained, tr.percentage, tr.grade, tr.grade_points, tr.is_passed, tr.time_taken, tr.has_override, tr.theta, tr.theta_se, tr.completed_at,
+				u.username, u.first_name, u.last_name, u.email
+			FROM test_results tr
+			JOIN users u ON u.id = tr.user_id
+			WHERE tr.test_id = $1
+			ORDER BY u.last_name ASC, u.first_name ASC, tr.id ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.ResultExportRow
+	for rows.Next() {
+		result, err := models.ScanResultExportRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, rows.Err()
+}
+
+// StreamAnswers calls fn for every stored answer of every session of a test, ordered by
+// session and question, without loading them all into memory
+func (r *ExportRepository) StreamAnswers(testID int, fn func(*models.AnswerExportRow) error) error {
+	query := `
+		SELECT ua.id, ua.session_id, ts.user_id, u.username, q.id, q.order_index, q.question_text, q.question_type,
+			ua.answer_text, ua.selected_option_id, qo.option_text, ua.is_correct, ua.marks_awarded, ua.answered_at
+		FROM user_answers ua
+		JOIN test_sessions ts ON ts.id = ua.session_id
+		JOIN users u ON u.id = ts.user_id
+		JOIN questions q ON q.id = ua.question_id
+		LEFT JOIN question_options qo ON qo.id = ua.selected_option_id
+		WHERE ts.test_id = ?
+		ORDER BY ua.session_id ASC, q.order_index ASC, q.id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT ua.id, ua.session_id, ts.user_id, u.username, q.id, q.order_index, q.question_text, q.question_type,
+				ua.answer_text, ua.selected_option_id, qo.option_text, ua.is_correct, ua.marks_awarded, ua.answered_at
+			FROM user_answers ua
+			JOIN test_sessions ts ON ts.id = ua.session_id
+			JOIN users u ON u.id = ts.user_id
+			JOIN questions q ON q.id = ua.question_id
+			LEFT JOIN question_options qo ON qo.id = ua.selected_option_id
+			WHERE ts.test_id = $1
+			ORDER BY ua.session_id ASC, q.order_index ASC, q.id ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, testID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		answer, err := models.ScanAnswerExportRow(rows)
+		if err != nil {
+			return err
+		}
+		if answer == nil {
+			continue
+		}
+		if err := fn(answer); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// GradingScaleRepository implements the models.GradingScaleRepository interface
+type GradingScaleRepository struct {
+	db *DB
+}
+
+// NewGradingScaleRepository creates a new grading scale repository
+func NewGradingScaleRepository(db *DB) models.GradingScaleRepository {
+	return &GradingScaleRepository{db: db}
+}
+
+// Create creates a new grading scale
+func (r *GradingScaleRepository) Create(scale *models.GradingScale) error {
+	query := `
+		INSERT INTO grading_scales (name, description, test_id, created_by, is_default, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO grading_scales (name, description, test_id, created_by, is_default, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+		`
+	}
+
+	now := time.Now()
+	scale.CreatedAt = now
+	scale.UpdatedAt = now
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, scale.Name, scale.Description, scale.TestID, scale.CreatedBy,
+			scale.IsDefault, scale.CreatedAt, scale.UpdatedAt).Scan(&scale.ID)
+		return err
+	}
+
+	result, err := r.db.Exec(query, scale.Name, scale.Description, scale.TestID, scale.CreatedBy,
+		scale.IsDefault, scale.CreatedAt, scale.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	scale.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves a grading scale by ID
+func (r *GradingScaleRepository) GetByID(id int) (*models.GradingScale, error) {
+	query := `
+		SELECT id, name, description, test_id, created_by, is_default, created_at, updated_at
+		FROM grading_scales WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, name, description, test_id, created_by, is_default, created_at, updated_at
+			FROM grading_scales WHERE id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, id)
+	return models.ScanGradingScale(row)
+}
+
+// GetDefault retrieves the global default grading scale
+func (r *GradingScaleRepository) GetDefault() (*models.GradingScale, error) {
+	query := `
+		SELECT id, name, description, test_id, created_by, is_default, created_at, updated_at
+		FROM grading_scales WHERE is_default = TRUE AND test_id IS NULL
+		ORDER BY updated_at DESC LIMIT 1
+	`
+
+	row := r.db.QueryRow(query)
+	return models.ScanGradingScale(row)
+}
+
+// List retrieves the global grading scales, plus the scales of a test when testID is set
+func (r *GradingScaleRepository) List(testID int) ([]*models.GradingScale, error) {
+	query := `
+		SELECT id, name, description, test_id, created_by, is_default, created_at, updated_at
+		FROM grading_scales WHERE test_id IS NULL OR test_id = ?
+		ORDER BY test_id IS NULL, name ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, name, description, test_id, created_by, is_default, created_at, updated_at
+			FROM grading_scales WHERE test_id IS NULL OR test_id = $1
+			ORDER BY test_id IS NULL, name ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scales []*models.GradingScale
+	for rows.Next() {
+		scale, err := models.ScanGradingScale(rows)
+		if err != nil {
+			return nil, err
+		}
+		if scale != nil {
+			scales = append(scales, scale)
+		}
+	}
+
+	return scales, rows.Err()
+}
+
+// Update updates a grading scale's name and description
+func (r *GradingScaleRepository) Update(scale *models.GradingScale) error {
+	query := `
+		UPDATE grading_scales SET name = ?, description = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			UPDATE grading_scales SET name = $1, description = $2, updated_at = $3
+			WHERE id = $4
+		`
+	}
+
+	scale.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, scale.Name, scale.Description, scale.UpdatedAt, scale.ID)
+	return err
+}
+
+// Delete deletes a grading scale
+func (r *GradingScaleRepository) Delete(id int) error {
+	query := "DELETE FROM grading_scales WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM grading_scales WHERE id = $1"
+	}
+
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// SetDefault marks a global grading scale as the default and clears the flag on all others
+func (r *GradingScaleRepository) SetDefault(id int) error {
+	clearQuery := "UPDATE grading_scales SET is_default = FALSE WHERE is_default = TRUE AND id <> ?"
+	setQuery := "UPDATE grading_scales SET is_default = TRUE, updated_at = ? WHERE id = ?"
+
+	if r.db.Driver == "postgres" {
+		clearQuery = "UPDATE grading_scales SET is_default = FALSE WHERE is_default = TRUE AND id <> $1"
+		setQuery = "UPDATE grading_scales SET is_default = TRUE, updated_at = $1 WHERE id = $2"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(clearQuery, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(setQuery, time.Now(), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceBands replaces all bands of a grading scale
+func (r *GradingScaleRepository) ReplaceBands(scaleID int, bands []*models.GradingBand) error {
+	deleteQuery := "DELETE FROM grading_bands WHERE scale_id = ?"
+	insertQuery := `
+		INSERT INTO grading_bands (scale_id, label, min_percentage, gpa_points)
+		VALUES (?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		deleteQuery = "DELETE FROM grading_bands WHERE scale_id = $1"
+		insertQuery = `
+			INSERT INTO grading_bands (scale_id, label, min_percentage, gpa_points)
+			VALUES ($1, $2, $3, $4) RETURNING id
+		`
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteQuery, scaleID); err != nil {
+		return err
+	}
+
+	for _, band := range bands {
+		band.ScaleID = scaleID
+
+		if r.db.Driver == "postgres" {
+			err := tx.QueryRow(insertQuery, band.ScaleID, band.Label, band.MinPercentage, band.GPAPoints).Scan(&band.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, err := tx.Exec(insertQuery, band.ScaleID, band.Label, band.MinPercentage, band.GPAPoints)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		band.ID = int(id)
+	}
+
+	return tx.Commit()
+}
+
+// GetBands retrieves the bands of a grading scale, highest cut-off first
+func (r *GradingScaleRepository) GetBands(scaleID int) ([]*models.GradingBand, error) {
+	query := `
+		SELECT id, scale_id, label, min_percentage, gpa_points
+		FROM grading_bands WHERE scale_id = ? ORDER BY min_percentage DESC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, scale_id, label, min_percentage, gpa_points
+			FROM grading_bands WHERE scale_id = $1 ORDER BY min_percentage DESC
+		`
+	}
+
+	rows, err := r.db.Query(query, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bands []*models.GradingBand
+	for rows.Next() {
+		band, err := models.ScanGradingBand(rows)
+		if err != nil {
+			return nil, err
+		}
+		if band != nil {
+			bands = append(bands, band)
+		}
+	}
+
+	return bands, rows.Err()
+}
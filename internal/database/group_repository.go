@@ -0,0 +1,445 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// GroupRepository implements the models.GroupRepository interface
+type GroupRepository struct {
+	db *DB
+}
+
+// NewGroupRepository creates a new group repository
+func NewGroupRepository(db *DB) models.GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+// Create creates a new group
+func (r *GroupRepository) Create(group *models.Group) error {
+	query := `
+		INSERT INTO class_groups (name, description, kind, owner_id, invite_code, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO class_groups (name, description, kind, owner_id, invite_code, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+		`
+	}
+
+	now := time.Now()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, group.Name, group.Description, group.Kind, group.OwnerID, group.InviteCode,
+			group.CreatedAt, group.UpdatedAt).Scan(&group.ID)
+		return err
+	}
+
+	result, err := r.db.Exec(query, group.Name, group.Description, group.Kind, group.OwnerID, group.InviteCode,
+		group.CreatedAt, group.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	group.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves a group by ID
+func (r *GroupRepository) GetByID(id int) (*models.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+			(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+		FROM class_groups g WHERE g.id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+				(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+			FROM class_groups g WHERE g.id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, id)
+	return models.ScanGroup(row)
+}
+
+// GetByInviteCode retrieves a group by its invite code
+func (r *GroupRepository) GetByInviteCode(code string) (*models.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+			(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+		FROM class_groups g WHERE g.invite_code = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+				(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+			FROM class_groups g WHERE g.invite_code = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, code)
+	return models.ScanGroup(row)
+}
+
+// List retrieves groups ordered by name with pagination; an owner ID of 0 lists every group
+func (r *GroupRepository) List(ownerID int, limit, offset int) ([]*models.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+			(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+		FROM class_groups g WHERE (? = 0 OR g.owner_id = ?)
+		ORDER BY g.name ASC, g.id ASC LIMIT ? OFFSET ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+				(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+			FROM class_groups g WHERE ($1 = 0 OR g.owner_id = $2)
+			ORDER BY g.name ASC, g.id ASC LIMIT $3 OFFSET $4
+		`
+	}
+
+	return r.queryGroups(query, ownerID, ownerID, limit, offset)
+}
+
+// GetByMember retrieves the groups a user is enrolled in
+func (r *GroupRepository) GetByMember(userID int) ([]*models.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+			(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+		FROM class_groups g
+		JOIN group_members gm ON gm.group_id = g.id
+		WHERE gm.user_id = ?
+		ORDER BY g.name ASC, g.id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+				(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+			FROM class_groups g
+			JOIN group_members gm ON gm.group_id = g.id
+			WHERE gm.user_id = $1
+			ORDER BY g.name ASC, g.id ASC
+		`
+	}
+
+	return r.queryGroups(query, userID)
+}
+
+// Update updates a group's name, description and kind
+func (r *GroupRepository) Update(group *models.Group) error {
+	query := `
+		UPDATE class_groups SET name = ?, description = ?, kind = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			UPDATE class_groups SET name = $1, description = $2, kind = $3, updated_at = $4
+			WHERE id = $5
+		`
+	}
+
+	group.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, group.Name, group.Description, group.Kind, group.UpdatedAt, group.ID)
+	return err
+}
+
+// Delete deletes a group with its members and test assignments
+func (r *GroupRepository) Delete(id int) error {
+	queries := []string{
+		"DELETE FROM test_groups WHERE group_id = ?",
+		"DELETE FROM group_members WHERE group_id = ?",
+		"DELETE FROM class_groups WHERE id = ?",
+	}
+
+	if r.db.Driver == "postgres" {
+		queries = []string{
+			"DELETE FROM test_groups WHERE group_id = $1",
+			"DELETE FROM group_members WHERE group_id = $1",
+			"DELETE FROM class_groups WHERE id = $1",
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetInviteCode sets or, with nil, clears the invite code of a group
+func (r *GroupRepository) SetInviteCode(groupID int, code *string) error {
+	query := "UPDATE class_groups SET invite_code = ?, updated_at = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE class_groups SET invite_code = $1, updated_at = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, code, time.Now(), groupID)
+	return err
+}
+
+// SetDirectoryGroup links a group to a directory group or, with nil, unlinks it
+func (r *GroupRepository) SetDirectoryGroup(groupID int, directoryGroup *string) error {
+	query := "UPDATE class_groups SET directory_group = ?, updated_at = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE class_groups SET directory_group = $1, updated_at = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, directoryGroup, time.Now(), groupID)
+	return err
+}
+
+// SyncDirectoryMembers enrolls a user in the groups linked to the given directory groups
+// and removes them from linked groups they no longer belong to. Groups without a
+// directory link are left untouched.
+func (r *GroupRepository) SyncDirectoryMembers(userID int, directoryGroups []string) error {
+	selectQuery := "SELECT id, directory_group FROM class_groups WHERE directory_group IS NOT NULL"
+	insertQuery := `
+		INSERT INTO group_members (group_id, user_id, joined_at) VALUES (?, ?, ?)
+		ON CONFLICT (group_id, user_id) DO NOTHING
+	`
+	deleteQuery := "DELETE FROM group_members WHERE group_id = ? AND user_id = ?"
+
+	if r.db.Driver == "postgres" {
+		insertQuery = `
+			INSERT INTO group_members (group_id, user_id, joined_at) VALUES ($1, $2, $3)
+			ON CONFLICT (group_id, user_id) DO NOTHING
+		`
+		deleteQuery = "DELETE FROM group_members WHERE group_id = $1 AND user_id = $2"
+	}
+
+	member := make(map[string]bool, len(directoryGroups))
+	for _, name := range directoryGroups {
+		member[name] = true
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(selectQuery)
+	if err != nil {
+		return err
+	}
+	linked := make(map[int]string)
+	for rows.Next() {
+		var groupID int
+		var name string
+		if err := rows.Scan(&groupID, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		linked[groupID] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for groupID, name := range linked {
+		if member[name] {
+			_, err = tx.Exec(insertQuery, groupID, userID, now)
+		} else {
+			_, err = tx.Exec(deleteQuery, groupID, userID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddMember enrolls a user in a group; enrolling an existing member does nothing
+func (r *GroupRepository) AddMember(groupID, userID int) error {
+	query := `
+		INSERT INTO group_members (group_id, user_id, joined_at) VALUES (?, ?, ?)
+		ON CONFLICT (group_id, user_id) DO NOTHING
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO group_members (group_id, user_id, joined_at) VALUES ($1, $2, $3)
+			ON CONFLICT (group_id, user_id) DO NOTHING
+		`
+	}
+
+	_, err := r.db.Exec(query, groupID, userID, time.Now())
+	return err
+}
+
+// RemoveMember removes a user from a group
+func (r *GroupRepository) RemoveMember(groupID, userID int) error {
+	query := "DELETE FROM group_members WHERE group_id = ? AND user_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM group_members WHERE group_id = $1 AND user_id = $2"
+	}
+
+	_, err := r.db.Exec(query, groupID, userID)
+	return err
+}
+
+// GetMembers retrieves the members of a group ordered by name
+func (r *GroupRepository) GetMembers(groupID int) ([]*models.GroupMember, error) {
+	query := `
+		SELECT gm.group_id, gm.user_id, u.username, u.first_name, u.last_name, u.email, u.role, gm.joined_at
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = ?
+		ORDER BY u.last_name ASC, u.first_name ASC, u.username ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT gm.group_id, gm.user_id, u.username, u.first_name, u.last_name, u.email, u.role, gm.joined_at
+			FROM group_members gm
+			JOIN users u ON u.id = gm.user_id
+			WHERE gm.group_id = $1
+			ORDER BY u.last_name ASC, u.first_name ASC, u.username ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*models.GroupMember{}
+	for rows.Next() {
+		member, err := models.ScanGroupMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil {
+			members = append(members, member)
+		}
+	}
+
+	return members, rows.Err()
+}
+
+// GetByTest retrieves the groups a test is assigned to
+func (r *GroupRepository) GetByTest(testID int) ([]*models.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+			(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+		FROM class_groups g
+		JOIN test_groups tg ON tg.group_id = g.id
+		WHERE tg.test_id = ?
+		ORDER BY g.name ASC, g.id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT g.id, g.name, g.description, g.kind, g.owner_id, g.invite_code, g.directory_group, g.created_at, g.updated_at,
+				(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+			FROM class_groups g
+			JOIN test_groups tg ON tg.group_id = g.id
+			WHERE tg.test_id = $1
+			ORDER BY g.name ASC, g.id ASC
+		`
+	}
+
+	return r.queryGroups(query, testID)
+}
+
+// ReplaceTestGroups replaces the groups a test is assigned to
+func (r *GroupRepository) ReplaceTestGroups(testID int, groupIDs []int) error {
+	deleteQuery := "DELETE FROM test_groups WHERE test_id = ?"
+	insertQuery := "INSERT INTO test_groups (test_id, group_id) VALUES (?, ?)"
+
+	if r.db.Driver == "postgres" {
+		deleteQuery = "DELETE FROM test_groups WHERE test_id = $1"
+		insertQuery = "INSERT INTO test_groups (test_id, group_id) VALUES ($1, $2)"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteQuery, testID); err != nil {
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		if _, err := tx.Exec(insertQuery, testID, groupID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// IsTestAssigned checks if a test is assigned to any group the user is enrolled in
+func (r *GroupRepository) IsTestAssigned(testID, userID int) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM test_groups tg
+		JOIN group_members gm ON gm.group_id = tg.group_id
+		WHERE tg.test_id = ? AND gm.user_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT COUNT(*) FROM test_groups tg
+			JOIN group_members gm ON gm.group_id = tg.group_id
+			WHERE tg.test_id = $1 AND gm.user_id = $2
+		`
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, testID, userID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// queryGroups runs a group query and scans its rows
+func (r *GroupRepository) queryGroups(query string, args ...interface{}) ([]*models.Group, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*models.Group{}
+	for rows.Next() {
+		group, err := models.ScanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, rows.Err()
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// IdentityRepository implements the models.IdentityRepository interface
+type IdentityRepository struct {
+	db *DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *DB) models.IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// GetIdentity retrieves the identity an issuer assigned a subject
+func (r *IdentityRepository) GetIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM user_identities WHERE issuer = ? AND subject = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+			FROM user_identities WHERE issuer = $1 AND subject = $2
+		`
+	}
+
+	return models.ScanUserIdentity(r.db.QueryRow(query, issuer, subject))
+}
+
+// CreateIdentity links an external account to a user
+func (r *IdentityRepository) CreateIdentity(identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email, created_at, last_login_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO user_identities (user_id, issuer, subject, email, created_at, last_login_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+		`
+	}
+
+	identity.CreatedAt = time.Now()
+	identity.LastLoginAt = identity.CreatedAt
+
+	if r.db.Driver == "postgres" {
+		return r.db.QueryRow(query, identity.UserID, identity.Issuer, identity.Subject, identity.Email,
+			identity.CreatedAt, identity.LastLoginAt).Scan(&identity.ID)
+	}
+
+	result, err := r.db.Exec(query, identity.UserID, identity.Issuer, identity.Subject, identity.Email,
+		identity.CreatedAt, identity.LastLoginAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	identity.ID = int(id)
+	return nil
+}
+
+// TouchIdentity records a sign-in with an identity and the email the source reported
+func (r *IdentityRepository) TouchIdentity(id int, email string, loginAt time.Time) error {
+	query := "UPDATE user_identities SET email = ?, last_login_at = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE user_identities SET email = $1, last_login_at = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, email, loginAt, id)
+	return err
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"gocbt/internal/models"
+)
+
+// IntegrityRepository implements the models.IntegrityRepository interface
+type IntegrityRepository struct {
+	db *DB
+}
+
+// NewIntegrityRepository creates a new result ledger repository
+func NewIntegrityRepository(db *DB) models.IntegrityRepository {
+	return &IntegrityRepository{db: db}
+}
+
+// AppendEntry adds an entry to the result ledger. The unique sequence and previous hash
+// make a concurrent append on the same head fail instead of forking the chain.
+func (r *IntegrityRepository) AppendEntry(entry *models.ResultLedgerEntry) error {
+	query := `
+		INSERT INTO result_ledger (sequence, result_id, test_id, action, reason, payload, payload_hash, prev_hash, entry_hash, signature, key_id, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO result_ledger (sequence, result_id, test_id, action, reason, payload, payload_hash, prev_hash, entry_hash, signature, key_id, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING id
+		`
+	}
+
+	if r.db.Driver == "postgres" {
+		return r.db.QueryRow(query, entry.Sequence, entry.ResultID, entry.TestID, entry.Action, entry.Reason, entry.Payload,
+			entry.PayloadHash, entry.PrevHash, entry.EntryHash, entry.Signature, entry.KeyID, entry.RecordedAt).Scan(&entry.ID)
+	}
+
+	res, err := r.db.Exec(query, entry.Sequence, entry.ResultID, entry.TestID, entry.Action, entry.Reason, entry.Payload,
+		entry.PayloadHash, entry.PrevHash, entry.EntryHash, entry.Signature, entry.KeyID, entry.RecordedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = int(id)
+	return nil
+}
+
+// GetHead retrieves the last entry of the result ledger
+func (r *IntegrityRepository) GetHead() (*models.ResultLedgerEntry, error) {
+	query := `
+		SELECT id, sequence, result_id, test_id, action, reason, payload, payload_hash, prev_hash, entry_hash, signature, key_id, recorded_at
+		FROM result_ledger ORDER BY sequence DESC LIMIT 1
+	`
+
+	row := r.db.QueryRow(query)
+	return models.ScanResultLedgerEntry(row)
+}
+
+// GetEntriesForResult retrieves the ledger entries of a result, oldest first
+func (r *IntegrityRepository) GetEntriesForResult(resultID int) ([]*models.ResultLedgerEntry, error) {
+	query := `
+		SELECT id, sequence, result_id, test_id, action, reason, payload, payload_hash, prev_hash, entry_hash, signature, key_id, recorded_at
+		FROM result_ledger WHERE result_id = ? ORDER BY sequence ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, sequence, result_id, test_id, action, reason, payload, payload_hash, prev_hash, entry_hash, signature, key_id, recorded_at
+			FROM result_ledger WHERE result_id = $1 ORDER BY sequence ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, resultID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.ResultLedgerEntry
+	for rows.Next() {
+		entry, err := models.ScanResultLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, rows.Err()
+}
+
+// StreamEntries calls fn for every ledger entry in chain order
+func (r *IntegrityRepository) StreamEntries(fn func(*models.ResultLedgerEntry) error) error {
+	query := `
+		SELECT id, sequence, result_id, test_id, action, reason, payload, payload_hash, prev_hash, entry_hash, signature, key_id, recorded_at
+		FROM result_ledger ORDER BY sequence ASC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := models.ScanResultLedgerEntry(rows)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetSignedResult retrieves a result with its signature
+func (r *IntegrityRepository) GetSignedResult(resultID int) (*models.SignedResult, error) {
+	query := `
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at, signature
+		FROM test_results WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at, signature
+			FROM test_results WHERE id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, resultID)
+	return models.ScanSignedResult(row)
+}
+
+// StreamSignedResults calls fn for every stored result with its signature
+func (r *IntegrityRepository) StreamSignedResults(fn func(*models.SignedResult) error) error {
+	query := `
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at, signature
+		FROM test_results ORDER BY id ASC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result, err := models.ScanSignedResult(rows)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			continue
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetLatestEntriesByTest retrieves the last ledger entry of every result recorded for a test
+func (r *IntegrityRepository) GetLatestEntriesByTest(testID int) ([]*models.ResultLedgerEntry, error) {
+	query := `
+		SELECT l.id, l.sequence, l.result_id, l.test_id, l.action, l.reason, l.payload, l.payload_hash, l.prev_hash, l.entry_hash, l.signature, l.key_id, l.recorded_at
+		FROM result_ledger l
+		WHERE l.test_id = ? AND l.sequence = (SELECT MAX(sequence) FROM result_ledger WHERE result_id = l.result_id)
+		ORDER BY l.sequence ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT l.id, l.sequence, l.result_id, l.test_id, l.action, l.reason, l.payload, l.payload_hash, l.prev_hash, l.entry_hash, l.signature, l.key_id, l.recorded_at
+			FROM result_ledger l
+			WHERE l.test_id = $1 AND l.sequence = (SELECT MAX(sequence) FROM result_ledger WHERE result_id = l.result_id)
+			ORDER BY l.sequence ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.ResultLedgerEntry
+	for rows.Next() {
+		entry, err := models.ScanResultLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, rows.Err()
+}
+
+// UpdateSignature stores the signature of a result
+func (r *IntegrityRepository) UpdateSignature(resultID int, signature string) error {
+	query := "UPDATE test_results SET signature = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE test_results SET signature = $1 WHERE id = $2"
+	}
+
+	_, err := r.db.Exec(query, signature, resultID)
+	return err
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// OIDCRepository implements the models.OIDCRepository interface
+type OIDCRepository struct {
+	db *DB
+}
+
+// NewOIDCRepository creates a new single sign-on repository
+func NewOIDCRepository(db *DB) models.OIDCRepository {
+	return &OIDCRepository{db: db}
+}
+
+// CreateLoginState stores a new single sign-on request
+func (r *OIDCRepository) CreateLoginState(state *models.OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (state_hash, nonce, code_verifier, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO oidc_login_states (state_hash, nonce, code_verifier, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+	}
+
+	state.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(query, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt)
+	return err
+}
+
+// ConsumeLoginState retrieves and deletes a single sign-on request in one transaction, so
+// that each state can complete only one login
+func (r *OIDCRepository) ConsumeLoginState(stateHash string) (*models.OIDCLoginState, error) {
+	selectQuery := `
+		SELECT state_hash, nonce, code_verifier, expires_at, created_at
+		FROM oidc_login_states WHERE state_hash = ?
+	`
+	deleteQuery := "DELETE FROM oidc_login_states WHERE state_hash = ?"
+
+	if r.db.Driver == "postgres" {
+		selectQuery = `
+			SELECT state_hash, nonce, code_verifier, expires_at, created_at
+			FROM oidc_login_states WHERE state_hash = $1 FOR UPDATE
+		`
+		deleteQuery = "DELETE FROM oidc_login_states WHERE state_hash = $1"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	state, err := models.ScanOIDCLoginState(tx.QueryRow(selectQuery, stateHash))
+	if err != nil || state == nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(deleteQuery, stateHash)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		// Another request consumed the same state first
+		return nil, nil
+	}
+
+	return state, tx.Commit()
+}
+
+// DeleteExpiredLoginStates deletes single sign-on requests that expired before the given
+// time without being completed
+func (r *OIDCRepository) DeleteExpiredLoginStates(before time.Time) error {
+	query := "DELETE FROM oidc_login_states WHERE expires_at < ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM oidc_login_states WHERE expires_at < $1"
+	}
+
+	_, err := r.db.Exec(query, before)
+	return err
+}
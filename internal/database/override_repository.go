@@ -0,0 +1,147 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// ScoreOverrideRepository implements the models.ScoreOverrideRepository interface
+type ScoreOverrideRepository struct {
+	db *DB
+}
+
+// NewScoreOverrideRepository creates a new score override repository
+func NewScoreOverrideRepository(db *DB) models.ScoreOverrideRepository {
+	return &ScoreOverrideRepository{db: db}
+}
+
+// Create records a new score override
+func (r *ScoreOverrideRepository) Create(override *models.ScoreOverride) error {
+	query := `
+		INSERT INTO score_overrides (scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO score_overrides (scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id
+		`
+	}
+
+	override.CreatedAt = time.Now()
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, override.Scope, override.TestID, override.QuestionID, override.AnswerID,
+			override.ResultID, override.SessionID, override.OriginalValue, override.NewValue,
+			override.ReasonCode, override.Reason, override.CreatedBy, override.CreatedAt).Scan(&override.ID)
+		return err
+	}
+
+	result, err := r.db.Exec(query, override.Scope, override.TestID, override.QuestionID, override.AnswerID,
+		override.ResultID, override.SessionID, override.OriginalValue, override.NewValue,
+		override.ReasonCode, override.Reason, override.CreatedBy, override.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	override.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves a score override by ID
+func (r *ScoreOverrideRepository) GetByID(id int) (*models.ScoreOverride, error) {
+	query := `
+		SELECT id, scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at, revoked_by, revoked_at
+		FROM score_overrides WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at, revoked_by, revoked_at
+			FROM score_overrides WHERE id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, id)
+	return models.ScanScoreOverride(row)
+}
+
+// GetByTest retrieves the score overrides of a test, oldest first
+func (r *ScoreOverrideRepository) GetByTest(testID int, includeRevoked bool) ([]*models.ScoreOverride, error) {
+	query := `
+		SELECT id, scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at, revoked_by, revoked_at
+		FROM score_overrides WHERE test_id = ? AND (? OR revoked_at IS NULL)
+		ORDER BY created_at ASC, id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at, revoked_by, revoked_at
+			FROM score_overrides WHERE test_id = $1 AND ($2 OR revoked_at IS NULL)
+			ORDER BY created_at ASC, id ASC
+		`
+	}
+
+	return r.query(query, testID, includeRevoked)
+}
+
+// GetActiveForSession retrieves the active overrides that affect a session: its own
+// answer and result overrides plus the voided questions of its test
+func (r *ScoreOverrideRepository) GetActiveForSession(testID, sessionID int) ([]*models.ScoreOverride, error) {
+	query := `
+		SELECT id, scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at, revoked_by, revoked_at
+		FROM score_overrides
+		WHERE revoked_at IS NULL AND (session_id = ? OR (scope = 'question' AND test_id = ?))
+		ORDER BY created_at ASC, id ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, scope, test_id, question_id, answer_id, result_id, session_id, original_value, new_value, reason_code, reason, created_by, created_at, revoked_by, revoked_at
+			FROM score_overrides
+			WHERE revoked_at IS NULL AND (session_id = $1 OR (scope = 'question' AND test_id = $2))
+			ORDER BY created_at ASC, id ASC
+		`
+	}
+
+	return r.query(query, sessionID, testID)
+}
+
+// Revoke marks a score override as revoked, keeping it for the audit trail
+func (r *ScoreOverrideRepository) Revoke(id, revokedBy int) error {
+	query := "UPDATE score_overrides SET revoked_by = ?, revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE score_overrides SET revoked_by = $1, revoked_at = $2 WHERE id = $3 AND revoked_at IS NULL"
+	}
+
+	_, err := r.db.Exec(query, revokedBy, time.Now(), id)
+	return err
+}
+
+// query runs a score override query and scans all rows
+func (r *ScoreOverrideRepository) query(query string, args ...interface{}) ([]*models.ScoreOverride, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*models.ScoreOverride
+	for rows.Next() {
+		override, err := models.ScanScoreOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		if override != nil {
+			overrides = append(overrides, override)
+		}
+	}
+
+	return overrides, rows.Err()
+}
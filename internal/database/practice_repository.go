@@ -0,0 +1,129 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// PracticeResultRepository implements the models.PracticeResultRepository interface
+type PracticeResultRepository struct {
+	db *DB
+}
+
+// NewPracticeResultRepository creates a new practice result repository
+func NewPracticeResultRepository(db *DB) models.PracticeResultRepository {
+	return &PracticeResultRepository{db: db}
+}
+
+// Create creates a new practice result
+func (r *PracticeResultRepository) Create(result *models.PracticeResult) error {
+	query := `
+		INSERT INTO practice_results (session_id, test_id, user_id, attempt_number, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, time_taken)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO practice_results (session_id, test_id, user_id, attempt_number, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, time_taken)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id, completed_at
+		`
+	}
+
+	if r.db.Driver == "postgres" {
+		err := r.db.QueryRow(query, result.SessionID, result.TestID, result.UserID,
+			result.AttemptNumber, result.TotalQuestions, result.AnsweredQuestions,
+			result.CorrectAnswers, result.TotalMarks, result.MarksObtained,
+			result.Percentage, result.TimeTaken).Scan(&result.ID, &result.CompletedAt)
+		return err
+	}
+
+	res, err := r.db.Exec(query, result.SessionID, result.TestID, result.UserID,
+		result.AttemptNumber, result.TotalQuestions, result.AnsweredQuestions,
+		result.CorrectAnswers, result.TotalMarks, result.MarksObtained,
+		result.Percentage, result.TimeTaken)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	result.ID = int(id)
+	result.CompletedAt = time.Now()
+	return nil
+}
+
+// GetByUser retrieves the practice results of a user, newest first, optionally for one test
+func (r *PracticeResultRepository) GetByUser(userID, testID int, limit, offset int) ([]*models.PracticeResult, error) {
+	query := `
+		SELECT id, session_id, test_id, user_id, attempt_number, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, time_taken, completed_at
+		FROM practice_results WHERE user_id = ? AND (? = 0 OR test_id = ?)
+		ORDER BY completed_at DESC, id DESC LIMIT ? OFFSET ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, session_id, test_id, user_id, attempt_number, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, time_taken, completed_at
+			FROM practice_results WHERE user_id = $1 AND ($2 = 0 OR test_id = $3)
+			ORDER BY completed_at DESC, id DESC LIMIT $4 OFFSET $5
+		`
+	}
+
+	return r.query(query, userID, testID, testID, limit, offset)
+}
+
+// GetByTest retrieves the practice results of a test, newest first
+func (r *PracticeResultRepository) GetByTest(testID int, limit, offset int) ([]*models.PracticeResult, error) {
+	query := `
+		SELECT id, session_id, test_id, user_id, attempt_number, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, time_taken, completed_at
+		FROM practice_results WHERE test_id = ?
+		ORDER BY completed_at DESC, id DESC LIMIT ? OFFSET ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, session_id, test_id, user_id, attempt_number, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, time_taken, completed_at
+			FROM practice_results WHERE test_id = $1
+			ORDER BY completed_at DESC, id DESC LIMIT $2 OFFSET $3
+		`
+	}
+
+	return r.query(query, testID, limit, offset)
+}
+
+// CountByUserAndTest counts the practice attempts of a user at a test
+func (r *PracticeResultRepository) CountByUserAndTest(userID, testID int) (int, error) {
+	query := "SELECT COUNT(*) FROM practice_results WHERE user_id = ? AND test_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT COUNT(*) FROM practice_results WHERE user_id = $1 AND test_id = $2"
+	}
+
+	var count int
+	err := r.db.QueryRow(query, userID, testID).Scan(&count)
+	return count, err
+}
+
+// query runs a practice result query and scans all rows
+func (r *PracticeResultRepository) query(query string, args ...interface{}) ([]*models.PracticeResult, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.PracticeResult
+	for rows.Next() {
+		result, err := models.ScanPracticeResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, rows.Err()
+}
@@ -18,27 +18,31 @@ func NewQuestionRepository(db *DB) models.QuestionRepository {
 // Create creates a new question
 func (r *QuestionRepository) Create(question *models.Question) error {
 	query := `
-		INSERT INTO questions (test_id, question_text, question_type, marks, order_index)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO questions (test_id, question_text, question_type, marks, order_index,
+			explanation, correct_feedback, incorrect_feedback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			INSERT INTO questions (test_id, question_text, question_type, marks, order_index)
-			VALUES ($1, $2, $3, $4, $5)
+			INSERT INTO questions (test_id, question_text, question_type, marks, order_index,
+				explanation, correct_feedback, incorrect_feedback)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 			RETURNING id, created_at, updated_at
 		`
 	}
 
 	if r.db.Driver == "postgres" {
 		err := r.db.QueryRow(query, question.TestID, question.QuestionText,
-			question.QuestionType, question.Marks, question.OrderIndex).Scan(
+			question.QuestionType, question.Marks, question.OrderIndex, question.Explanation,
+			question.CorrectFeedback, question.IncorrectFeedback).Scan(
 			&question.ID, &question.CreatedAt, &question.UpdatedAt)
 		return err
 	}
 
 	result, err := r.db.Exec(query, question.TestID, question.QuestionText,
-		question.QuestionType, question.Marks, question.OrderIndex)
+		question.QuestionType, question.Marks, question.OrderIndex, question.Explanation,
+		question.CorrectFeedback, question.IncorrectFeedback)
 	if err != nil {
 		return err
 	}
@@ -57,13 +61,17 @@ func (r *QuestionRepository) Create(question *models.Question) error {
 // GetByID retrieves a question by ID
 func (r *QuestionRepository) GetByID(id int) (*models.Question, error) {
 	query := `
-		SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at
+		SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at,
+			explanation, correct_feedback, incorrect_feedback,
+			irt_model, irt_difficulty, irt_discrimination, irt_responses, irt_calibrated_at
 		FROM questions WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at
+			SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at,
+				explanation, correct_feedback, incorrect_feedback,
+				irt_model, irt_difficulty, irt_discrimination, irt_responses, irt_calibrated_at
 			FROM questions WHERE id = $1
 		`
 	}
@@ -75,13 +83,17 @@ func (r *QuestionRepository) GetByID(id int) (*models.Question, error) {
 // GetByTestID retrieves questions by test ID
 func (r *QuestionRepository) GetByTestID(testID int) ([]*models.Question, error) {
 	query := `
-		SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at
+		SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at,
+			explanation, correct_feedback, incorrect_feedback,
+			irt_model, irt_difficulty, irt_discrimination, irt_responses, irt_calibrated_at
 		FROM questions WHERE test_id = ? ORDER BY order_index ASC
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at
+			SELECT id, test_id, question_text, question_type, marks, order_index, created_at, updated_at,
+				explanation, correct_feedback, incorrect_feedback,
+				irt_model, irt_difficulty, irt_discrimination, irt_responses, irt_calibrated_at
 			FROM questions WHERE test_id = $1 ORDER BY order_index ASC
 		`
 	}
@@ -110,21 +122,24 @@ func (r *QuestionRepository) GetByTestID(testID int) ([]*models.Question, error)
 func (r *QuestionRepository) Update(question *models.Question) error {
 	query := `
 		UPDATE questions 
-		SET question_text = ?, question_type = ?, marks = ?, order_index = ?, updated_at = ?
+		SET question_text = ?, question_type = ?, marks = ?, order_index = ?, updated_at = ?,
+			explanation = ?, correct_feedback = ?, incorrect_feedback = ?
 		WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
 			UPDATE questions 
-			SET question_text = $1, question_type = $2, marks = $3, order_index = $4, updated_at = $5
-			WHERE id = $6
+			SET question_text = $1, question_type = $2, marks = $3, order_index = $4, updated_at = $5,
+				explanation = $6, correct_feedback = $7, incorrect_feedback = $8
+			WHERE id = $9
 		`
 	}
 
 	question.UpdatedAt = time.Now()
 	_, err := r.db.Exec(query, question.QuestionText, question.QuestionType,
-		question.Marks, question.OrderIndex, question.UpdatedAt, question.ID)
+		question.Marks, question.OrderIndex, question.UpdatedAt, question.Explanation,
+		question.CorrectFeedback, question.IncorrectFeedback, question.ID)
 	return err
 }
 
@@ -142,26 +157,26 @@ func (r *QuestionRepository) Delete(id int) error {
 // CreateOption creates a new question option
 func (r *QuestionRepository) CreateOption(option *models.QuestionOption) error {
 	query := `
-		INSERT INTO question_options (question_id, option_text, is_correct, order_index)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO question_options (question_id, option_text, is_correct, order_index, feedback)
+		VALUES (?, ?, ?, ?, ?)
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			INSERT INTO question_options (question_id, option_text, is_correct, order_index)
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO question_options (question_id, option_text, is_correct, order_index, feedback)
+			VALUES ($1, $2, $3, $4, $5)
 			RETURNING id, created_at
 		`
 	}
 
 	if r.db.Driver == "postgres" {
 		err := r.db.QueryRow(query, option.QuestionID, option.OptionText,
-			option.IsCorrect, option.OrderIndex).Scan(&option.ID, &option.CreatedAt)
+			option.IsCorrect, option.OrderIndex, option.Feedback).Scan(&option.ID, &option.CreatedAt)
 		return err
 	}
 
 	result, err := r.db.Exec(query, option.QuestionID, option.OptionText,
-		option.IsCorrect, option.OrderIndex)
+		option.IsCorrect, option.OrderIndex, option.Feedback)
 	if err != nil {
 		return err
 	}
@@ -179,13 +194,13 @@ func (r *QuestionRepository) CreateOption(option *models.QuestionOption) error {
 // GetOptionsByQuestionID retrieves options by question ID
 func (r *QuestionRepository) GetOptionsByQuestionID(questionID int) ([]*models.QuestionOption, error) {
 	query := `
-		SELECT id, question_id, option_text, is_correct, order_index, created_at
+		SELECT id, question_id, option_text, is_correct, order_index, feedback, created_at
 		FROM question_options WHERE question_id = ? ORDER BY order_index ASC
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, question_id, option_text, is_correct, order_index, created_at
+			SELECT id, question_id, option_text, is_correct, order_index, feedback, created_at
 			FROM question_options WHERE question_id = $1 ORDER BY order_index ASC
 		`
 	}
@@ -214,20 +229,20 @@ func (r *QuestionRepository) GetOptionsByQuestionID(questionID int) ([]*models.Q
 func (r *QuestionRepository) UpdateOption(option *models.QuestionOption) error {
 	query := `
 		UPDATE question_options 
-		SET option_text = ?, is_correct = ?, order_index = ?
+		SET option_text = ?, is_correct = ?, order_index = ?, feedback = ?
 		WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
 			UPDATE question_options 
-			SET option_text = $1, is_correct = $2, order_index = $3
-			WHERE id = $4
+			SET option_text = $1, is_correct = $2, order_index = $3, feedback = $4
+			WHERE id = $5
 		`
 	}
 
 	_, err := r.db.Exec(query, option.OptionText, option.IsCorrect,
-		option.OrderIndex, option.ID)
+		option.OrderIndex, option.Feedback, option.ID)
 	return err
 }
 
@@ -343,3 +358,23 @@ func (r *QuestionRepository) DeleteCorrectAnswer(id int) error {
 	_, err := r.db.Exec(query, id)
 	return err
 }
+
+// UpdateIRTParameters stores the calibrated IRT parameters of a question
+func (r *QuestionRepository) UpdateIRTParameters(questionID int, params *models.ItemParameters) error {
+	query := `
+		UPDATE questions
+		SET irt_model = ?, irt_difficulty = ?, irt_discrimination = ?, irt_responses = ?, irt_calibrated_at = ?
+		WHERE id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			UPDATE questions
+			SET irt_model = $1, irt_difficulty = $2, irt_discrimination = $3, irt_responses = $4, irt_calibrated_at = $5
+			WHERE id = $6
+		`
+	}
+
+	_, err := r.db.Exec(query, params.Model, params.Difficulty, params.Discrimination, params.Responses, params.CalibratedAt, questionID)
+	return err
+}
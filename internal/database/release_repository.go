@@ -0,0 +1,80 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// ReleaseRepository implements the models.ReleaseRepository interface
+type ReleaseRepository struct {
+	db *DB
+}
+
+// NewReleaseRepository creates a new result release settings repository
+func NewReleaseRepository(db *DB) models.ReleaseRepository {
+	return &ReleaseRepository{db: db}
+}
+
+// GetSettings retrieves the result release settings for a test
+func (r *ReleaseRepository) GetSettings(testID int) (*models.TestReleaseSettings, error) {
+	query := `
+		SELECT test_id, score_release, released_at, show_correct_answers, show_explanations, created_at, updated_at
+		FROM test_release_settings WHERE test_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT test_id, score_release, released_at, show_correct_answers, show_explanations, created_at, updated_at
+			FROM test_release_settings WHERE test_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, testID)
+	return models.ScanTestReleaseSettings(row)
+}
+
+// UpsertSettings creates or replaces the result release settings for a test
+func (r *ReleaseRepository) UpsertSettings(settings *models.TestReleaseSettings) error {
+	query := `
+		INSERT INTO test_release_settings (test_id, score_release, released_at, show_correct_answers, show_explanations, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (test_id) DO UPDATE
+		SET score_release = excluded.score_release, released_at = excluded.released_at,
+			show_correct_answers = excluded.show_correct_answers, show_explanations = excluded.show_explanations,
+			updated_at = excluded.updated_at
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO test_release_settings (test_id, score_release, released_at, show_correct_answers, show_explanations, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (test_id) DO UPDATE
+			SET score_release = excluded.score_release, released_at = excluded.released_at,
+				show_correct_answers = excluded.show_correct_answers, show_explanations = excluded.show_explanations,
+				updated_at = excluded.updated_at
+		`
+	}
+
+	settings.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, settings.TestID, settings.ScoreRelease, settings.ReleasedAt,
+		settings.ShowCorrectAnswers, settings.ShowExplanations, settings.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if settings.CreatedAt.IsZero() {
+		settings.CreatedAt = settings.UpdatedAt
+	}
+	return nil
+}
+
+// DeleteSettings removes the result release settings for a test
+func (r *ReleaseRepository) DeleteSettings(testID int) error {
+	query := "DELETE FROM test_release_settings WHERE test_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM test_release_settings WHERE test_id = $1"
+	}
+
+	_, err := r.db.Exec(query, testID)
+	return err
+}
@@ -18,14 +18,14 @@ func NewTestResultRepository(db *DB) models.TestResultRepository {
 // Create creates a new test result
 func (r *TestResultRepository) Create(result *models.TestResult) error {
 	query := `
-		INSERT INTO test_results (session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO test_results (session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			INSERT INTO test_results (session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			INSERT INTO test_results (session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 			RETURNING id, completed_at
 		`
 	}
@@ -34,14 +34,14 @@ func (r *TestResultRepository) Create(result *models.TestResult) error {
 		err := r.db.QueryRow(query, result.SessionID, result.TestID, result.UserID,
 			result.TotalQuestions, result.AnsweredQuestions, result.CorrectAnswers,
 			result.TotalMarks, result.MarksObtained, result.Percentage, result.Grade,
-			result.IsPassed, result.TimeTaken).Scan(&result.ID, &result.CompletedAt)
+			result.GradePoints, result.IsPassed, result.TimeTaken, result.HasOverride, result.Theta, result.ThetaSE).Scan(&result.ID, &result.CompletedAt)
 		return err
 	}
 
 	res, err := r.db.Exec(query, result.SessionID, result.TestID, result.UserID,
 		result.TotalQuestions, result.AnsweredQuestions, result.CorrectAnswers,
 		result.TotalMarks, result.MarksObtained, result.Percentage, result.Grade,
-		result.IsPassed, result.TimeTaken)
+		result.GradePoints, result.IsPassed, result.TimeTaken, result.HasOverride, result.Theta, result.ThetaSE)
 	if err != nil {
 		return err
 	}
@@ -59,13 +59,13 @@ func (r *TestResultRepository) Create(result *models.TestResult) error {
 // GetByID retrieves a test result by ID
 func (r *TestResultRepository) GetByID(id int) (*models.TestResult, error) {
 	query := `
-		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 		FROM test_results WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 			FROM test_results WHERE id = $1
 		`
 	}
@@ -77,13 +77,13 @@ func (r *TestResultRepository) GetByID(id int) (*models.TestResult, error) {
 // GetBySessionID retrieves a test result by session ID
 func (r *TestResultRepository) GetBySessionID(sessionID int) (*models.TestResult, error) {
 	query := `
-		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 		FROM test_results WHERE session_id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 			FROM test_results WHERE session_id = $1
 		`
 	}
@@ -95,13 +95,13 @@ func (r *TestResultRepository) GetBySessionID(sessionID int) (*models.TestResult
 // GetByUserAndTest retrieves a test result by user and test
 func (r *TestResultRepository) GetByUserAndTest(userID, testID int) (*models.TestResult, error) {
 	query := `
-		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 		FROM test_results WHERE user_id = ? AND test_id = ? ORDER BY completed_at DESC LIMIT 1
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 			FROM test_results WHERE user_id = $1 AND test_id = $2 ORDER BY completed_at DESC LIMIT 1
 		`
 	}
@@ -113,13 +113,13 @@ func (r *TestResultRepository) GetByUserAndTest(userID, testID int) (*models.Tes
 // GetByUser retrieves test results by user with pagination
 func (r *TestResultRepository) GetByUser(userID int, limit, offset int) ([]*models.TestResult, error) {
 	query := `
-		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 		FROM test_results WHERE user_id = ? ORDER BY completed_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 			FROM test_results WHERE user_id = $1 ORDER BY completed_at DESC LIMIT $2 OFFSET $3
 		`
 	}
@@ -147,13 +147,13 @@ func (r *TestResultRepository) GetByUser(userID int, limit, offset int) ([]*mode
 // GetByTest retrieves test results by test with pagination
 func (r *TestResultRepository) GetByTest(testID int, limit, offset int) ([]*models.TestResult, error) {
 	query := `
-		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+		SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 		FROM test_results WHERE test_id = ? ORDER BY completed_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, is_passed, time_taken, completed_at
+			SELECT id, session_id, test_id, user_id, total_questions, answered_questions, correct_answers, total_marks, marks_obtained, percentage, grade, grade_points, is_passed, time_taken, has_override, theta, theta_se, completed_at
 			FROM test_results WHERE test_id = $1 ORDER BY completed_at DESC LIMIT $2 OFFSET $3
 		`
 	}
@@ -182,21 +182,44 @@ func (r *TestResultRepository) GetByTest(testID int, limit, offset int) ([]*mode
 func (r *TestResultRepository) Update(result *models.TestResult) error {
 	query := `
 		UPDATE test_results 
-		SET total_questions = ?, answered_questions = ?, correct_answers = ?, total_marks = ?, marks_obtained = ?, percentage = ?, grade = ?, is_passed = ?, time_taken = ?
+		SET total_questions = ?, answered_questions = ?, correct_answers = ?, total_marks = ?, marks_obtained = ?, percentage = ?, grade = ?, grade_points = ?, is_passed = ?, time_taken = ?, has_override = ?, theta = ?, theta_se = ?
 		WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
 			UPDATE test_results 
-			SET total_questions = $1, answered_questions = $2, correct_answers = $3, total_marks = $4, marks_obtained = $5, percentage = $6, grade = $7, is_passed = $8, time_taken = $9
-			WHERE id = $10
+			SET total_questions = $1, answered_questions = $2, correct_answers = $3, total_marks = $4, marks_obtained = $5, percentage = $6, grade = $7, grade_points = $8, is_passed = $9, time_taken = $10, has_override = $11, theta = $12, theta_se = $13
+			WHERE id = $14
 		`
 	}
 
 	_, err := r.db.Exec(query, result.TotalQuestions, result.AnsweredQuestions,
 		result.CorrectAnswers, result.TotalMarks, result.MarksObtained, result.Percentage,
-		result.Grade, result.IsPassed, result.TimeTaken, result.ID)
+		result.Grade, result.GradePoints, result.IsPassed, result.TimeTaken, result.HasOverride,
+		result.Theta, result.ThetaSE, result.ID)
+	return err
+}
+
+// UpdateGrade updates only the grade of a test result
+func (r *TestResultRepository) UpdateGrade(id int, grade *string, gradePoints *float64) error {
+	query := "UPDATE test_results SET grade = ?, grade_points = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE test_results SET grade = $1, grade_points = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, grade, gradePoints, id)
+	return err
+}
+
+// UpdateAbility updates only the IRT ability estimate of a test result
+func (r *TestResultRepository) UpdateAbility(id int, theta, thetaSE *float64) error {
+	query := "UPDATE test_results SET theta = ?, theta_se = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE test_results SET theta = $1, theta_se = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, theta, thetaSE, id)
 	return err
 }
 
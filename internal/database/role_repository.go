@@ -0,0 +1,373 @@
+package database
+
+import (
+	"database/sql"
+	"gocbt/internal/models"
+	"time"
+)
+
+// RoleRepository implements the models.RoleRepository interface
+type RoleRepository struct {
+	db *DB
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *DB) models.RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// Create creates a new role with its permissions
+func (r *RoleRepository) Create(role *models.Role) error {
+	query := `
+		INSERT INTO roles (name, description, is_system, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO roles (name, description, is_system, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id
+		`
+	}
+
+	now := time.Now()
+	role.CreatedAt = now
+	role.UpdatedAt = now
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if r.db.Driver == "postgres" {
+		err := tx.QueryRow(query, role.Name, role.Description, role.IsSystem, role.CreatedAt, role.UpdatedAt).Scan(&role.ID)
+		if err != nil {
+			return err
+		}
+	} else {
+		result, err := tx.Exec(query, role.Name, role.Description, role.IsSystem, role.CreatedAt, role.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		role.ID = int(id)
+	}
+
+	if err := r.insertPermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a role by ID with its permissions
+func (r *RoleRepository) GetByID(id int) (*models.Role, error) {
+	query := "SELECT id, name, description, is_system, require_two_factor, created_at, updated_at FROM roles WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT id, name, description, is_system, require_two_factor, created_at, updated_at FROM roles WHERE id = $1"
+	}
+
+	role, err := models.ScanRole(r.db.QueryRow(query, id))
+	if err != nil || role == nil {
+		return role, err
+	}
+
+	return role, r.loadPermissions(role)
+}
+
+// GetByName retrieves a role by name with its permissions
+func (r *RoleRepository) GetByName(name string) (*models.Role, error) {
+	query := "SELECT id, name, description, is_system, require_two_factor, created_at, updated_at FROM roles WHERE name = ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT id, name, description, is_system, require_two_factor, created_at, updated_at FROM roles WHERE name = $1"
+	}
+
+	role, err := models.ScanRole(r.db.QueryRow(query, name))
+	if err != nil || role == nil {
+		return role, err
+	}
+
+	return role, r.loadPermissions(role)
+}
+
+// List retrieves every role, built-in roles first
+func (r *RoleRepository) List() ([]*models.Role, error) {
+	query := `
+		SELECT id, name, description, is_system, require_two_factor, created_at, updated_at
+		FROM roles ORDER BY is_system DESC, name ASC
+	`
+
+	return r.queryRoles(query)
+}
+
+// Update updates a role's name and description and replaces its permissions
+func (r *RoleRepository) Update(role *models.Role) error {
+	updateQuery := "UPDATE roles SET name = ?, description = ?, updated_at = ? WHERE id = ?"
+	deleteQuery := "DELETE FROM role_permissions WHERE role_id = ?"
+
+	if r.db.Driver == "postgres" {
+		updateQuery = "UPDATE roles SET name = $1, description = $2, updated_at = $3 WHERE id = $4"
+		deleteQuery = "DELETE FROM role_permissions WHERE role_id = $1"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	role.UpdatedAt = time.Now()
+	if _, err := tx.Exec(updateQuery, role.Name, role.Description, role.UpdatedAt, role.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(deleteQuery, role.ID); err != nil {
+		return err
+	}
+
+	if err := r.insertPermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete deletes a role with its permissions and assignments
+func (r *RoleRepository) Delete(id int) error {
+	queries := []string{
+		"DELETE FROM user_roles WHERE role_id = ?",
+		"DELETE FROM role_permissions WHERE role_id = ?",
+		"DELETE FROM roles WHERE id = ?",
+	}
+
+	if r.db.Driver == "postgres" {
+		queries = []string{
+			"DELETE FROM user_roles WHERE role_id = $1",
+			"DELETE FROM role_permissions WHERE role_id = $1",
+			"DELETE FROM roles WHERE id = $1",
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserRoles retrieves the roles assigned to a user
+func (r *RoleRepository) GetUserRoles(userID int) ([]*models.Role, error) {
+	query := `
+		SELECT ro.id, ro.name, ro.description, ro.is_system, ro.require_two_factor, ro.created_at, ro.updated_at
+		FROM roles ro
+		JOIN user_roles ur ON ur.role_id = ro.id
+		WHERE ur.user_id = ?
+		ORDER BY ro.name ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT ro.id, ro.name, ro.description, ro.is_system, ro.require_two_factor, ro.created_at, ro.updated_at
+			FROM roles ro
+			JOIN user_roles ur ON ur.role_id = ro.id
+			WHERE ur.user_id = $1
+			ORDER BY ro.name ASC
+		`
+	}
+
+	return r.queryRoles(query, userID)
+}
+
+// ReplaceUserRoles replaces the roles assigned to a user
+func (r *RoleRepository) ReplaceUserRoles(userID int, roleIDs []int) error {
+	deleteQuery := "DELETE FROM user_roles WHERE user_id = ?"
+	insertQuery := "INSERT INTO user_roles (user_id, role_id, created_at) VALUES (?, ?, ?)"
+
+	if r.db.Driver == "postgres" {
+		deleteQuery = "DELETE FROM user_roles WHERE user_id = $1"
+		insertQuery = "INSERT INTO user_roles (user_id, role_id, created_at) VALUES ($1, $2, $3)"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteQuery, userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, roleID := range roleIDs {
+		if _, err := tx.Exec(insertQuery, userID, roleID, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserPermissions retrieves the permissions of a user's account role and of the roles
+// assigned to them
+func (r *RoleRepository) GetUserPermissions(userID int) ([]models.Permission, error) {
+	query := `
+		SELECT DISTINCT rp.permission
+		FROM role_permissions rp
+		JOIN roles ro ON ro.id = rp.role_id
+		WHERE ro.id IN (SELECT role_id FROM user_roles WHERE user_id = ?)
+			OR (ro.is_system = ? AND ro.name = (SELECT role FROM users WHERE id = ?))
+		ORDER BY rp.permission ASC
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT DISTINCT rp.permission
+			FROM role_permissions rp
+			JOIN roles ro ON ro.id = rp.role_id
+			WHERE ro.id IN (SELECT role_id FROM user_roles WHERE user_id = $1)
+				OR (ro.is_system = $2 AND ro.name = (SELECT role FROM users WHERE id = $3))
+			ORDER BY rp.permission ASC
+		`
+	}
+
+	rows, err := r.db.Query(query, userID, true, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// SetTwoFactorRequired sets whether a role makes two-factor authentication mandatory
+func (r *RoleRepository) SetTwoFactorRequired(roleID int, required bool) error {
+	query := "UPDATE roles SET require_two_factor = ?, updated_at = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE roles SET require_two_factor = $1, updated_at = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, required, time.Now(), roleID)
+	return err
+}
+
+// IsTwoFactorRequired checks if a user's account role or one of the roles assigned to them
+// makes two-factor authentication mandatory
+func (r *RoleRepository) IsTwoFactorRequired(userID int) (bool, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM roles ro
+		WHERE ro.require_two_factor = ?
+			AND (ro.id IN (SELECT role_id FROM user_roles WHERE user_id = ?)
+				OR (ro.is_system = ? AND ro.name = (SELECT role FROM users WHERE id = ?)))
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT COUNT(*)
+			FROM roles ro
+			WHERE ro.require_two_factor = $1
+				AND (ro.id IN (SELECT role_id FROM user_roles WHERE user_id = $2)
+					OR (ro.is_system = $3 AND ro.name = (SELECT role FROM users WHERE id = $4)))
+		`
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, true, userID, true, userID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// insertPermissions adds permissions to a role inside a transaction
+func (r *RoleRepository) insertPermissions(tx *sql.Tx, roleID int, permissions []models.Permission) error {
+	query := "INSERT INTO role_permissions (role_id, permission) VALUES (?, ?)"
+	if r.db.Driver == "postgres" {
+		query = "INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2)"
+	}
+
+	for _, permission := range permissions {
+		if _, err := tx.Exec(query, roleID, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadPermissions loads the permissions of a role
+func (r *RoleRepository) loadPermissions(role *models.Role) error {
+	query := "SELECT permission FROM role_permissions WHERE role_id = ? ORDER BY permission ASC"
+	if r.db.Driver == "postgres" {
+		query = "SELECT permission FROM role_permissions WHERE role_id = $1 ORDER BY permission ASC"
+	}
+
+	rows, err := r.db.Query(query, role.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	role.Permissions = []models.Permission{}
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission); err != nil {
+			return err
+		}
+		role.Permissions = append(role.Permissions, permission)
+	}
+
+	return rows.Err()
+}
+
+// queryRoles runs a role query and loads the permissions of each role
+func (r *RoleRepository) queryRoles(query string, args ...interface{}) ([]*models.Role, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*models.Role{}
+	for rows.Next() {
+		role, err := models.ScanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			roles = append(roles, role)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		if err := r.loadPermissions(role); err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
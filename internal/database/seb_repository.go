@@ -0,0 +1,80 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"strings"
+	"time"
+)
+
+// SEBRepository implements the models.SEBRepository interface
+type SEBRepository struct {
+	db *DB
+}
+
+// NewSEBRepository creates a new Safe Exam Browser settings repository
+func NewSEBRepository(db *DB) models.SEBRepository {
+	return &SEBRepository{db: db}
+}
+
+// GetSettings retrieves the Safe Exam Browser settings for a test
+func (r *SEBRepository) GetSettings(testID int) (*models.TestSEBSettings, error) {
+	query := `
+		SELECT test_id, enabled, config_keys, browser_exam_keys, quit_password_hash, created_at, updated_at
+		FROM test_seb_settings WHERE test_id = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT test_id, enabled, config_keys, browser_exam_keys, quit_password_hash, created_at, updated_at
+			FROM test_seb_settings WHERE test_id = $1
+		`
+	}
+
+	row := r.db.QueryRow(query, testID)
+	return models.ScanTestSEBSettings(row)
+}
+
+// UpsertSettings creates or replaces the Safe Exam Browser settings for a test
+func (r *SEBRepository) UpsertSettings(settings *models.TestSEBSettings) error {
+	query := `
+		INSERT INTO test_seb_settings (test_id, enabled, config_keys, browser_exam_keys, quit_password_hash, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (test_id) DO UPDATE
+		SET enabled = excluded.enabled, config_keys = excluded.config_keys, browser_exam_keys = excluded.browser_exam_keys,
+			quit_password_hash = excluded.quit_password_hash, updated_at = excluded.updated_at
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO test_seb_settings (test_id, enabled, config_keys, browser_exam_keys, quit_password_hash, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (test_id) DO UPDATE
+			SET enabled = excluded.enabled, config_keys = excluded.config_keys, browser_exam_keys = excluded.browser_exam_keys,
+				quit_password_hash = excluded.quit_password_hash, updated_at = excluded.updated_at
+		`
+	}
+
+	settings.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, settings.TestID, settings.Enabled,
+		strings.Join(settings.ConfigKeys, ","), strings.Join(settings.BrowserExamKeys, ","),
+		settings.QuitPasswordHash, settings.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if settings.CreatedAt.IsZero() {
+		settings.CreatedAt = settings.UpdatedAt
+	}
+	return nil
+}
+
+// DeleteSettings removes the Safe Exam Browser settings for a test
+func (r *SEBRepository) DeleteSettings(testID int) error {
+	query := "DELETE FROM test_seb_settings WHERE test_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM test_seb_settings WHERE test_id = $1"
+	}
+
+	_, err := r.db.Exec(query, testID)
+	return err
+}
@@ -18,14 +18,14 @@ func NewTestRepository(db *DB) models.TestRepository {
 // Create creates a new test
 func (r *TestRepository) Create(test *models.Test) error {
 	query := `
-		INSERT INTO tests (title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO tests (title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			INSERT INTO tests (title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			INSERT INTO tests (title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 			RETURNING id, created_at, updated_at
 		`
 	}
@@ -33,14 +33,14 @@ func (r *TestRepository) Create(test *models.Test) error {
 	if r.db.Driver == "postgres" {
 		err := r.db.QueryRow(query, test.Title, test.Description, test.CreatedBy,
 			test.DurationMinutes, test.TotalMarks, test.PassingMarks, test.Instructions,
-			test.IsActive, test.StartTime, test.EndTime).Scan(
+			test.IsActive, test.StartTime, test.EndTime, test.GradingScaleID, test.Mode).Scan(
 			&test.ID, &test.CreatedAt, &test.UpdatedAt)
 		return err
 	}
 
 	result, err := r.db.Exec(query, test.Title, test.Description, test.CreatedBy,
 		test.DurationMinutes, test.TotalMarks, test.PassingMarks, test.Instructions,
-		test.IsActive, test.StartTime, test.EndTime)
+		test.IsActive, test.StartTime, test.EndTime, test.GradingScaleID, test.Mode)
 	if err != nil {
 		return err
 	}
@@ -59,13 +59,13 @@ func (r *TestRepository) Create(test *models.Test) error {
 // GetByID retrieves a test by ID
 func (r *TestRepository) GetByID(id int) (*models.Test, error) {
 	query := `
-		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 		FROM tests WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 			FROM tests WHERE id = $1
 		`
 	}
@@ -78,22 +78,22 @@ func (r *TestRepository) GetByID(id int) (*models.Test, error) {
 func (r *TestRepository) Update(test *models.Test) error {
 	query := `
 		UPDATE tests 
-		SET title = ?, description = ?, duration_minutes = ?, total_marks = ?, passing_marks = ?, instructions = ?, is_active = ?, start_time = ?, end_time = ?, updated_at = ?
+		SET title = ?, description = ?, duration_minutes = ?, total_marks = ?, passing_marks = ?, instructions = ?, is_active = ?, start_time = ?, end_time = ?, grading_scale_id = ?, mode = ?, updated_at = ?
 		WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
 			UPDATE tests 
-			SET title = $1, description = $2, duration_minutes = $3, total_marks = $4, passing_marks = $5, instructions = $6, is_active = $7, start_time = $8, end_time = $9, updated_at = $10
-			WHERE id = $11
+			SET title = $1, description = $2, duration_minutes = $3, total_marks = $4, passing_marks = $5, instructions = $6, is_active = $7, start_time = $8, end_time = $9, grading_scale_id = $10, mode = $11, updated_at = $12
+			WHERE id = $13
 		`
 	}
 
 	test.UpdatedAt = time.Now()
 	_, err := r.db.Exec(query, test.Title, test.Description, test.DurationMinutes,
 		test.TotalMarks, test.PassingMarks, test.Instructions, test.IsActive,
-		test.StartTime, test.EndTime, test.UpdatedAt, test.ID)
+		test.StartTime, test.EndTime, test.GradingScaleID, test.Mode, test.UpdatedAt, test.ID)
 	return err
 }
 
@@ -111,13 +111,13 @@ func (r *TestRepository) Delete(id int) error {
 // List retrieves a list of tests with pagination
 func (r *TestRepository) List(limit, offset int) ([]*models.Test, error) {
 	query := `
-		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 		FROM tests ORDER BY created_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 			FROM tests ORDER BY created_at DESC LIMIT $1 OFFSET $2
 		`
 	}
@@ -145,13 +145,13 @@ func (r *TestRepository) List(limit, offset int) ([]*models.Test, error) {
 // GetByCreator retrieves tests by creator with pagination
 func (r *TestRepository) GetByCreator(creatorID int, limit, offset int) ([]*models.Test, error) {
 	query := `
-		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 		FROM tests WHERE created_by = ? ORDER BY created_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 			FROM tests WHERE created_by = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
 		`
 	}
@@ -179,13 +179,13 @@ func (r *TestRepository) GetByCreator(creatorID int, limit, offset int) ([]*mode
 // GetActiveTests retrieves active tests with pagination
 func (r *TestRepository) GetActiveTests(limit, offset int) ([]*models.Test, error) {
 	query := `
-		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 		FROM tests WHERE is_active = true ORDER BY created_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 			FROM tests WHERE is_active = true ORDER BY created_at DESC LIMIT $1 OFFSET $2
 		`
 	}
@@ -210,30 +210,41 @@ func (r *TestRepository) GetActiveTests(limit, offset int) ([]*models.Test, erro
 	return tests, rows.Err()
 }
 
-// GetAvailableTests retrieves tests available for a user (active and within time window)
+// GetAvailableTests retrieves tests available for a user (active, within time window and
+// assigned to one of the user's groups)
 func (r *TestRepository) GetAvailableTests(userID int, limit, offset int) ([]*models.Test, error) {
 	now := time.Now()
 	query := `
-		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+		SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 		FROM tests 
 		WHERE is_active = true 
 		AND (start_time IS NULL OR start_time <= ?)
 		AND (end_time IS NULL OR end_time >= ?)
+		AND id IN (
+			SELECT tg.test_id FROM test_groups tg
+			JOIN group_members gm ON gm.group_id = tg.group_id
+			WHERE gm.user_id = ?
+		)
 		ORDER BY created_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, created_at, updated_at
+			SELECT id, title, description, created_by, duration_minutes, total_marks, passing_marks, instructions, is_active, start_time, end_time, grading_scale_id, mode, created_at, updated_at
 			FROM tests 
 			WHERE is_active = true 
 			AND (start_time IS NULL OR start_time <= $1)
 			AND (end_time IS NULL OR end_time >= $2)
-			ORDER BY created_at DESC LIMIT $3 OFFSET $4
+			AND id IN (
+				SELECT tg.test_id FROM test_groups tg
+				JOIN group_members gm ON gm.group_id = tg.group_id
+				WHERE gm.user_id = $3
+			)
+			ORDER BY created_at DESC LIMIT $4 OFFSET $5
 		`
 	}
 
-	rows, err := r.db.Query(query, now, now, limit, offset)
+	rows, err := r.db.Query(query, now, now, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -252,3 +263,58 @@ func (r *TestRepository) GetAvailableTests(userID int, limit, offset int) ([]*mo
 
 	return tests, rows.Err()
 }
+
+// GetCoOwners retrieves the IDs of the teachers a test is shared with
+func (r *TestRepository) GetCoOwners(testID int) ([]int, error) {
+	query := "SELECT user_id FROM test_co_owners WHERE test_id = ? ORDER BY user_id ASC"
+	if r.db.Driver == "postgres" {
+		query = "SELECT user_id FROM test_co_owners WHERE test_id = $1 ORDER BY user_id ASC"
+	}
+
+	rows, err := r.db.Query(query, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// ReplaceCoOwners replaces the teachers a test is shared with
+func (r *TestRepository) ReplaceCoOwners(testID int, userIDs []int) error {
+	deleteQuery := "DELETE FROM test_co_owners WHERE test_id = ?"
+	insertQuery := "INSERT INTO test_co_owners (test_id, user_id, created_at) VALUES (?, ?, ?)"
+
+	if r.db.Driver == "postgres" {
+		deleteQuery = "DELETE FROM test_co_owners WHERE test_id = $1"
+		insertQuery = "INSERT INTO test_co_owners (test_id, user_id, created_at) VALUES ($1, $2, $3)"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(deleteQuery, testID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, userID := range userIDs {
+		if _, err := tx.Exec(insertQuery, testID, userID, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
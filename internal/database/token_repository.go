@@ -0,0 +1,262 @@
+package database
+
+import (
+	"gocbt/internal/models"
+	"time"
+)
+
+// TokenRepository implements the models.TokenRepository interface
+type TokenRepository struct {
+	db *DB
+}
+
+// NewTokenRepository creates a new token repository
+func NewTokenRepository(db *DB) models.TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *TokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_token_id, access_expires_at, amr, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_token_id, access_expires_at, amr, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
+		`
+	}
+
+	token.CreatedAt = time.Now()
+
+	if r.db.Driver == "postgres" {
+		return r.db.QueryRow(query, token.UserID, token.TokenHash, token.FamilyID, token.AccessTokenID,
+			token.AccessExpiresAt, models.JoinAuthMethods(token.AMR), token.ExpiresAt, token.CreatedAt).Scan(&token.ID)
+	}
+
+	result, err := r.db.Exec(query, token.UserID, token.TokenHash, token.FamilyID, token.AccessTokenID,
+		token.AccessExpiresAt, models.JoinAuthMethods(token.AMR), token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = int(id)
+	return nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by the hash of its value
+func (r *TokenRepository) GetRefreshTokenByHash(tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, family_id, access_token_id, access_expires_at, amr, expires_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT id, user_id, token_hash, family_id, access_token_id, access_expires_at, amr, expires_at, revoked_at, created_at
+			FROM refresh_tokens WHERE token_hash = $1
+		`
+	}
+
+	return models.ScanRefreshToken(r.db.QueryRow(query, tokenHash))
+}
+
+// RotateRefreshToken revokes a refresh token and stores its replacement in one
+// transaction. It reports false, storing nothing, if the old token was already revoked,
+// which happens when the same token is refreshed twice concurrently.
+func (r *TokenRepository) RotateRefreshToken(oldID int, token *models.RefreshToken) (bool, error) {
+	revokeQuery := "UPDATE refresh_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+	insertQuery := `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_token_id, access_expires_at, amr, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		revokeQuery = "UPDATE refresh_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL"
+		insertQuery = `
+			INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_token_id, access_expires_at, amr, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
+		`
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	token.CreatedAt = time.Now()
+
+	result, err := tx.Exec(revokeQuery, token.CreatedAt, oldID)
+	if err != nil {
+		return false, err
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if revoked == 0 {
+		return false, nil
+	}
+
+	if r.db.Driver == "postgres" {
+		err := tx.QueryRow(insertQuery, token.UserID, token.TokenHash, token.FamilyID, token.AccessTokenID,
+			token.AccessExpiresAt, models.JoinAuthMethods(token.AMR), token.ExpiresAt, token.CreatedAt).Scan(&token.ID)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		result, err := tx.Exec(insertQuery, token.UserID, token.TokenHash, token.FamilyID, token.AccessTokenID,
+			token.AccessExpiresAt, models.JoinAuthMethods(token.AMR), token.ExpiresAt, token.CreatedAt)
+		if err != nil {
+			return false, err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return false, err
+		}
+		token.ID = int(id)
+	}
+
+	return true, tx.Commit()
+}
+
+// RevokeFamily revokes every refresh token of a family and denylists the access tokens
+// issued with them that have not expired yet
+func (r *TokenRepository) RevokeFamily(familyID string, revokedAt time.Time) error {
+	denyQuery := `
+		INSERT INTO revoked_access_tokens (token_id, user_id, expires_at, revoked_at)
+		SELECT access_token_id, user_id, access_expires_at, ?
+		FROM refresh_tokens WHERE family_id = ? AND access_expires_at > ?
+		ON CONFLICT (token_id) DO NOTHING
+	`
+	revokeQuery := "UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL"
+
+	if r.db.Driver == "postgres" {
+		denyQuery = `
+			INSERT INTO revoked_access_tokens (token_id, user_id, expires_at, revoked_at)
+			SELECT access_token_id, user_id, access_expires_at, $1
+			FROM refresh_tokens WHERE family_id = $2 AND access_expires_at > $3
+			ON CONFLICT (token_id) DO NOTHING
+		`
+		revokeQuery = "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(denyQuery, revokedAt, familyID, revokedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(revokeQuery, revokedAt, familyID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeUserTokens revokes every refresh token of a user and denylists the access tokens
+// issued with them that have not expired yet
+func (r *TokenRepository) RevokeUserTokens(userID int, revokedAt time.Time) error {
+	denyQuery := `
+		INSERT INTO revoked_access_tokens (token_id, user_id, expires_at, revoked_at)
+		SELECT access_token_id, user_id, access_expires_at, ?
+		FROM refresh_tokens WHERE user_id = ? AND access_expires_at > ?
+		ON CONFLICT (token_id) DO NOTHING
+	`
+	revokeQuery := "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"
+
+	if r.db.Driver == "postgres" {
+		denyQuery = `
+			INSERT INTO revoked_access_tokens (token_id, user_id, expires_at, revoked_at)
+			SELECT access_token_id, user_id, access_expires_at, $1
+			FROM refresh_tokens WHERE user_id = $2 AND access_expires_at > $3
+			ON CONFLICT (token_id) DO NOTHING
+		`
+		revokeQuery = "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(denyQuery, revokedAt, userID, revokedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(revokeQuery, revokedAt, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeAccessToken adds an access token ID to the denylist until the token expires
+func (r *TokenRepository) RevokeAccessToken(tokenID string, userID int, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_access_tokens (token_id, user_id, expires_at, revoked_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (token_id) DO NOTHING
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO revoked_access_tokens (token_id, user_id, expires_at, revoked_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (token_id) DO NOTHING
+		`
+	}
+
+	_, err := r.db.Exec(query, tokenID, userID, expiresAt, time.Now())
+	return err
+}
+
+// IsAccessTokenRevoked checks if an access token ID is on the denylist
+func (r *TokenRepository) IsAccessTokenRevoked(tokenID string) (bool, error) {
+	query := "SELECT COUNT(*) FROM revoked_access_tokens WHERE token_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT COUNT(*) FROM revoked_access_tokens WHERE token_id = $1"
+	}
+
+	var count int
+	if err := r.db.QueryRow(query, tokenID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired deletes refresh tokens and denylist entries that expired before the
+// given time, since expired tokens are rejected anyway
+func (r *TokenRepository) DeleteExpired(before time.Time) error {
+	queries := []string{
+		"DELETE FROM refresh_tokens WHERE expires_at < ?",
+		"DELETE FROM revoked_access_tokens WHERE expires_at < ?",
+	}
+
+	if r.db.Driver == "postgres" {
+		queries = []string{
+			"DELETE FROM refresh_tokens WHERE expires_at < $1",
+			"DELETE FROM revoked_access_tokens WHERE expires_at < $1",
+		}
+	}
+
+	for _, query := range queries {
+		if _, err := r.db.Exec(query, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"database/sql"
+	"gocbt/internal/models"
+	"time"
+)
+
+// TwoFactorRepository implements the models.TwoFactorRepository interface
+type TwoFactorRepository struct {
+	db *DB
+}
+
+// NewTwoFactorRepository creates a new two-factor repository
+func NewTwoFactorRepository(db *DB) models.TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetTwoFactor retrieves a user's authenticator, enabled or pending
+func (r *TwoFactorRepository) GetTwoFactor(userID int) (*models.TwoFactor, error) {
+	query := "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_two_factor WHERE user_id = ?"
+	if r.db.Driver == "postgres" {
+		query = "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_two_factor WHERE user_id = $1"
+	}
+
+	return models.ScanTwoFactor(r.db.QueryRow(query, userID))
+}
+
+// SaveSecret stores a pending authenticator secret, replacing any earlier pending one
+func (r *TwoFactorRepository) SaveSecret(userID int, secret string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret, enabled_at, last_used_step, created_at)
+		VALUES (?, ?, NULL, 0, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled_at = NULL,
+			last_used_step = 0, created_at = excluded.created_at
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO user_two_factor (user_id, secret, enabled_at, last_used_step, created_at)
+			VALUES ($1, $2, NULL, 0, $3)
+			ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled_at = NULL,
+				last_used_step = 0, created_at = excluded.created_at
+		`
+	}
+
+	_, err := r.db.Exec(query, userID, secret, time.Now())
+	return err
+}
+
+// Enable enables a pending authenticator, recording the time step of the code that
+// confirmed it, and stores the user's first recovery codes in one transaction
+func (r *TwoFactorRepository) Enable(userID int, step int64, codeHashes []string) error {
+	query := "UPDATE user_two_factor SET enabled_at = ?, last_used_step = ? WHERE user_id = ? AND enabled_at IS NULL"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE user_two_factor SET enabled_at = $1, last_used_step = $2 WHERE user_id = $3 AND enabled_at IS NULL"
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, time.Now(), step, userID)
+	if err != nil {
+		return err
+	}
+	enabled, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if enabled == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := r.replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records that a code of a time step was used. It reports false if a code of
+// that step or a later one was used already, so that a code cannot be replayed.
+func (r *TwoFactorRepository) UseStep(userID int, step int64) (bool, error) {
+	query := "UPDATE user_two_factor SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE user_two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $3"
+	}
+
+	result, err := r.db.Exec(query, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
+
+// Delete removes a user's authenticator and recovery codes
+func (r *TwoFactorRepository) Delete(userID int) error {
+	queries := []string{
+		"DELETE FROM two_factor_recovery_codes WHERE user_id = ?",
+		"DELETE FROM two_factor_challenges WHERE user_id = ?",
+		"DELETE FROM user_two_factor WHERE user_id = ?",
+	}
+
+	if r.db.Driver == "postgres" {
+		queries = []string{
+			"DELETE FROM two_factor_recovery_codes WHERE user_id = $1",
+			"DELETE FROM two_factor_challenges WHERE user_id = $1",
+			"DELETE FROM user_two_factor WHERE user_id = $1",
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes replaces all of a user's recovery codes
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes replaces all of a user's recovery codes within a transaction
+func (r *TwoFactorRepository) replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	deleteQuery := "DELETE FROM two_factor_recovery_codes WHERE user_id = ?"
+	insertQuery := "INSERT INTO two_factor_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)"
+
+	if r.db.Driver == "postgres" {
+		deleteQuery = "DELETE FROM two_factor_recovery_codes WHERE user_id = $1"
+		insertQuery = "INSERT INTO two_factor_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)"
+	}
+
+	if _, err := tx.Exec(deleteQuery, userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec(insertQuery, userID, codeHash, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false if the user
+// has no unused code with that hash.
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	query := "UPDATE two_factor_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE two_factor_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+	}
+
+	result, err := r.db.Exec(query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return used > 0, nil
+}
+
+// CountRecoveryCodes counts a user's unused recovery codes
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+	query := "SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = ? AND used_at IS NULL"
+	if r.db.Driver == "postgres" {
+		query = "SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+	}
+
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+// CreateChallenge stores a login challenge
+func (r *TwoFactorRepository) CreateChallenge(challenge *models.TwoFactorChallenge) error {
+	query := `
+		INSERT INTO two_factor_challenges (token_hash, user_id, amr, attempts, expires_at, created_at)
+		VALUES (?, ?, ?, 0, ?, ?)
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			INSERT INTO two_factor_challenges (token_hash, user_id, amr, attempts, expires_at, created_at)
+			VALUES ($1, $2, $3, 0, $4, $5)
+		`
+	}
+
+	challenge.Attempts = 0
+	challenge.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(query, challenge.TokenHash, challenge.UserID, models.JoinAuthMethods(challenge.AMR),
+		challenge.ExpiresAt, challenge.CreatedAt)
+	return err
+}
+
+// GetChallenge retrieves a login challenge by the hash of its token
+func (r *TwoFactorRepository) GetChallenge(tokenHash string) (*models.TwoFactorChallenge, error) {
+	query := `
+		SELECT token_hash, user_id, amr, attempts, expires_at, created_at
+		FROM two_factor_challenges WHERE token_hash = ?
+	`
+
+	if r.db.Driver == "postgres" {
+		query = `
+			SELECT token_hash, user_id, amr, attempts, expires_at, created_at
+			FROM two_factor_challenges WHERE token_hash = $1
+		`
+	}
+
+	return models.ScanTwoFactorChallenge(r.db.QueryRow(query, tokenHash))
+}
+
+// RecordFailedAttempt counts a wrong code entered for a login challenge
+func (r *TwoFactorRepository) RecordFailedAttempt(tokenHash string) error {
+	query := "UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE token_hash = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE token_hash = $1"
+	}
+
+	_, err := r.db.Exec(query, tokenHash)
+	return err
+}
+
+// ConsumeChallenge deletes a login challenge once it has been completed. It reports false
+// if the challenge was already consumed, which happens when it is completed twice
+// concurrently.
+func (r *TwoFactorRepository) ConsumeChallenge(tokenHash string) (bool, error) {
+	query := "DELETE FROM two_factor_challenges WHERE token_hash = ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM two_factor_challenges WHERE token_hash = $1"
+	}
+
+	result, err := r.db.Exec(query, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// DeleteExpiredChallenges deletes login challenges that expired before the given time
+func (r *TwoFactorRepository) DeleteExpiredChallenges(before time.Time) error {
+	query := "DELETE FROM two_factor_challenges WHERE expires_at < ?"
+	if r.db.Driver == "postgres" {
+		query = "DELETE FROM two_factor_challenges WHERE expires_at < $1"
+	}
+
+	_, err := r.db.Exec(query, before)
+	return err
+}
@@ -18,27 +18,27 @@ func NewUserRepository(db *DB) models.UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash, first_name, last_name, role, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO users (username, email, password_hash, first_name, last_name, role, is_active, email_verified)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			INSERT INTO users (username, email, password_hash, first_name, last_name, role, is_active)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			INSERT INTO users (username, email, password_hash, first_name, last_name, role, is_active, email_verified)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 			RETURNING id, created_at, updated_at
 		`
 	}
 
 	if r.db.Driver == "postgres" {
 		err := r.db.QueryRow(query, user.Username, user.Email, user.PasswordHash,
-			user.FirstName, user.LastName, user.Role, user.IsActive).Scan(
+			user.FirstName, user.LastName, user.Role, user.IsActive, user.EmailVerified).Scan(
 			&user.ID, &user.CreatedAt, &user.UpdatedAt)
 		return err
 	}
 
 	result, err := r.db.Exec(query, user.Username, user.Email, user.PasswordHash,
-		user.FirstName, user.LastName, user.Role, user.IsActive)
+		user.FirstName, user.LastName, user.Role, user.IsActive, user.EmailVerified)
 	if err != nil {
 		return err
 	}
@@ -57,13 +57,13 @@ func (r *UserRepository) Create(user *models.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 		FROM users WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 			FROM users WHERE id = $1
 		`
 	}
@@ -75,13 +75,13 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 // GetByUsername retrieves a user by username
 func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 		FROM users WHERE username = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 			FROM users WHERE username = $1
 		`
 	}
@@ -93,13 +93,13 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 		FROM users WHERE email = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 			FROM users WHERE email = $1
 		`
 	}
@@ -112,21 +112,32 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 func (r *UserRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users 
-		SET username = ?, email = ?, first_name = ?, last_name = ?, role = ?, is_active = ?, updated_at = ?
+		SET username = ?, email = ?, first_name = ?, last_name = ?, role = ?, is_active = ?, email_verified = ?, updated_at = ?
 		WHERE id = ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
 			UPDATE users 
-			SET username = $1, email = $2, first_name = $3, last_name = $4, role = $5, is_active = $6, updated_at = $7
-			WHERE id = $8
+			SET username = $1, email = $2, first_name = $3, last_name = $4, role = $5, is_active = $6, email_verified = $7, updated_at = $8
+			WHERE id = $9
 		`
 	}
 
 	user.UpdatedAt = time.Now()
 	_, err := r.db.Exec(query, user.Username, user.Email, user.FirstName,
-		user.LastName, user.Role, user.IsActive, user.UpdatedAt, user.ID)
+		user.LastName, user.Role, user.IsActive, user.EmailVerified, user.UpdatedAt, user.ID)
+	return err
+}
+
+// UpdatePassword replaces a user's password hash
+func (r *UserRepository) UpdatePassword(id int, passwordHash string) error {
+	query := "UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?"
+	if r.db.Driver == "postgres" {
+		query = "UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3"
+	}
+
+	_, err := r.db.Exec(query, passwordHash, time.Now(), id)
 	return err
 }
 
@@ -144,13 +155,13 @@ func (r *UserRepository) Delete(id int) error {
 // List retrieves a list of users with pagination
 func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 		FROM users ORDER BY created_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 			FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2
 		`
 	}
@@ -178,13 +189,13 @@ func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 // GetByRole retrieves users by role with pagination
 func (r *UserRepository) GetByRole(role models.UserRole, limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 		FROM users WHERE role = ? ORDER BY created_at DESC LIMIT ? OFFSET ?
 	`
 
 	if r.db.Driver == "postgres" {
 		query = `
-			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, created_at, updated_at
+			SELECT id, username, email, password_hash, first_name, last_name, role, is_active, email_verified, created_at, updated_at
 			FROM users WHERE role = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
 		`
 	}
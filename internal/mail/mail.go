@@ -0,0 +1,208 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gocbt/internal/config"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mail drivers
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message is a plain text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(message *Message) error
+}
+
+// New creates the mailer of the configured driver
+func New(cfg *config.MailConfig) (Mailer, error) {
+	if _, err := netmail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %v", cfg.From, err)
+	}
+
+	switch cfg.Driver {
+	case DriverSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("SMTP host is required for the smtp mail driver")
+		}
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.From, cfg.OutboxDir), nil
+	case DriverLog:
+		return NewLogMailer(cfg.From, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// SMTPMailer delivers email through an SMTP server. Connections are upgraded with
+// STARTTLS when the server offers it.
+type SMTPMailer struct {
+	cfg *config.MailConfig
+}
+
+// NewSMTPMailer creates a new SMTP mailer
+func NewSMTPMailer(cfg *config.MailConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+// Send delivers a message to the SMTP server
+func (m *SMTPMailer) Send(message *Message) error {
+	data, err := compose(m.cfg.From, message)
+	if err != nil {
+		return err
+	}
+
+	var smtpAuth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		smtpAuth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+	}
+
+	// compose has already checked both addresses
+	from, _ := netmail.ParseAddress(m.cfg.From)
+	to, _ := netmail.ParseAddress(message.To)
+
+	addr := net.JoinHostPort(m.cfg.SMTPHost, m.cfg.SMTPPort)
+	return smtp.SendMail(addr, smtpAuth, from.Address, []string{to.Address}, data)
+}
+
+// FileMailer writes each message as an .eml file to an outbox directory, for development
+// and for handing mail to another system
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer creates a new file mailer. The directory is created on first use.
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+// Send writes a message to a new file in the outbox directory
+func (m *FileMailer) Send(message *Message) error {
+	data, err := compose(m.from, message)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+	// Messages carry sign-in links, so they are readable by the owner only
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0600)
+}
+
+// LogMailer prints messages to a logger instead of sending them, for development. The
+// links in them can be followed from the server log.
+type LogMailer struct {
+	from   string
+	logger *log.Logger
+}
+
+// NewLogMailer creates a new log mailer. A nil logger uses the standard logger.
+func NewLogMailer(from string, logger *log.Logger) *LogMailer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogMailer{from: from, logger: logger}
+}
+
+// Send prints a message to the log
+func (m *LogMailer) Send(message *Message) error {
+	if _, err := compose(m.from, message); err != nil {
+		return err
+	}
+
+	m.logger.Printf("Email to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// backgroundMailer sends messages without waiting for delivery
+type backgroundMailer struct {
+	mailer  Mailer
+	onError func(message *Message, err error)
+}
+
+// Background wraps a mailer so that Send returns at once and delivery happens in the
+// background; failures are passed to onError. A request that sends mail then takes as long
+// as one that does not, so response times do not reveal which accounts exist.
+func Background(mailer Mailer, onError func(message *Message, err error)) Mailer {
+	return &backgroundMailer{mailer: mailer, onError: onError}
+}
+
+// Send starts delivering a message
+func (m *backgroundMailer) Send(message *Message) error {
+	go func() {
+		if err := m.mailer.Send(message); err != nil && m.onError != nil {
+			m.onError(message, err)
+		}
+	}()
+	return nil
+}
+
+// compose formats a message with its headers, refusing addresses and subjects that could
+// inject headers of their own
+func compose(from string, message *Message) ([]byte, error) {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %v", from, err)
+	}
+	recipient, err := netmail.ParseAddress(message.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address %q: %v", message.To, err)
+	}
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return nil, errors.New("subject must be a single line")
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\n", sender.String())
+	fmt.Fprintf(&buffer, "To: %s\r\n", recipient.String())
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	writer := quotedprintable.NewWriter(&buffer)
+	body := strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,56 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComposeRejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Message
+	}{
+		{"recipient with a header", &Message{To: "ada@school.example\r\nBcc: eve@example.com", Subject: "Hello"}},
+		{"subject with a header", &Message{To: "ada@school.example", Subject: "Hello\r\nBcc: eve@example.com"}},
+		{"invalid recipient", &Message{To: "not an address", Subject: "Hello"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := compose("GoCBT <no-reply@school.example>", test.message); err == nil {
+				t.Error("compose() succeeded, expected an error")
+			}
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer := NewFileMailer("GoCBT <no-reply@school.example>", dir)
+
+	err := mailer.Send(&Message{
+		To:      "ada@school.example",
+		Subject: "Reset your GoCBT password",
+		Body:    "Open this link:\nhttp://localhost:3000/reset-password?token=abc\n",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 || !strings.HasSuffix(files[0].Name(), ".eml") {
+		t.Fatalf("outbox holds %v (error %v), expected one .eml file", files, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	for _, expected := range []string{"To: <ada@school.example>\r\n", "Subject: Reset your GoCBT password\r\n", "reset-password?token=3Dabc"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("message does not contain %q:\n%s", expected, data)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// SEBValidator enforces Safe Exam Browser requirements on test session routes
+type SEBValidator struct {
+	sebService     models.SEBService
+	sessionService models.TestSessionService
+}
+
+// NewSEBValidator creates a new Safe Exam Browser validator
+func NewSEBValidator(sebService models.SEBService, sessionService models.TestSessionService) *SEBValidator {
+	return &SEBValidator{
+		sebService:     sebService,
+		sessionService: sessionService,
+	}
+}
+
+// RequireSEB is a middleware that validates SEB headers for tests that require Safe Exam Browser
+func (v *SEBValidator) RequireSEB(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testID, ok := v.resolveTestID(r)
+		if !ok {
+			// Routes that are not tied to a single test are not gated
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err := v.sebService.ValidateRequest(testID, requestURL(r),
+			r.Header.Get(models.SEBConfigKeyHashHeader), r.Header.Get(models.SEBRequestHashHeader))
+		if err != nil {
+			switch err {
+			case auth.ErrSEBRequired, auth.ErrInvalidSEBKey:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, "Failed to validate Safe Exam Browser request", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resolveTestID finds the test a session request belongs to, either from the
+// session token in the path or from the test_id of a session start request
+func (v *SEBValidator) resolveTestID(r *http.Request) (int, bool) {
+	if token := mux.Vars(r)["token"]; token != "" {
+		session, err := v.sessionService.GetSession(token)
+		if err != nil || session == nil {
+			return 0, false
+		}
+		return session.TestID, true
+	}
+
+	if r.Method != http.MethodPost || r.Body == nil {
+		return 0, false
+	}
+
+	// Peek at the body and restore it for the handler
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return 0, false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		TestID int `json:"test_id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.TestID <= 0 {
+		return 0, false
+	}
+	return req.TestID, true
+}
+
+// requestURL reconstructs the absolute URL SEB hashed for this request
+func requestURL(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
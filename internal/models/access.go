@@ -0,0 +1,207 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// AccessGateReason represents why a session start was rejected by an access gate
+type AccessGateReason string
+
+const (
+	AccessGateMissingCode AccessGateReason = "missing_access_code"
+	AccessGateInvalidCode AccessGateReason = "invalid_access_code"
+	AccessGateIPDenied    AccessGateReason = "ip_not_allowed"
+)
+
+// TestAccessPolicy represents the optional gates checked before a test session starts
+type TestAccessPolicy struct {
+	TestID              int       `json:"test_id" db:"test_id"`
+	AccessCode          *string   `json:"access_code,omitempty" db:"access_code"`
+	CodeRotationMinutes int       `json:"code_rotation_minutes" db:"code_rotation_minutes"`
+	AllowedCIDRs        []string  `json:"allowed_cidrs" db:"allowed_cidrs"` // stored comma-separated
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AccessGateFailure represents an audited session start rejected by an access gate
+type AccessGateFailure struct {
+	ID        int              `json:"id" db:"id"`
+	TestID    int              `json:"test_id" db:"test_id"`
+	UserID    int              `json:"user_id" db:"user_id"`
+	IPAddress string           `json:"ip_address" db:"ip_address"`
+	Reason    AccessGateReason `json:"reason" db:"reason"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// CurrentAccessCode represents the access code a proctor announces in the exam hall
+type CurrentAccessCode struct {
+	TestID     int        `json:"test_id"`
+	Code       string     `json:"code"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"` // nil for static codes
+}
+
+// TestAccessRepository defines the interface for test access gate data operations
+type TestAccessRepository interface {
+	GetPolicy(testID int) (*TestAccessPolicy, error)
+	UpsertPolicy(policy *TestAccessPolicy) error
+	DeletePolicy(testID int) error
+	CreateFailure(failure *AccessGateFailure) error
+	GetFailuresByTest(testID int, limit, offset int) ([]*AccessGateFailure, error)
+}
+
+// TestAccessService defines the interface for test access gate business logic
+type TestAccessService interface {
+	GetPolicy(testID int) (*TestAccessPolicy, error)
+	UpdatePolicy(testID int, accessCode *string, codeRotationMinutes int, allowedCIDRs []string) (*TestAccessPolicy, error)
+	DeletePolicy(testID int) error
+	GetCurrentAccessCode(testID int) (*CurrentAccessCode, error)
+	CheckAccess(testID, userID int, accessCode, clientIP string) error
+	GetFailures(testID int, limit, offset int) ([]*AccessGateFailure, error)
+}
+
+// accessCodeDigits is the length of generated rotating access codes
+const accessCodeDigits = 6
+
+// RequiresAccessCode checks if the policy requires an access code
+func (p *TestAccessPolicy) RequiresAccessCode() bool {
+	return p.AccessCode != nil && *p.AccessCode != ""
+}
+
+// IsRotating checks if the access code rotates on a schedule
+func (p *TestAccessPolicy) IsRotating() bool {
+	return p.RequiresAccessCode() && p.CodeRotationMinutes > 0
+}
+
+// rotationWindow returns the rotation window index for the given time
+func (p *TestAccessPolicy) rotationWindow(t time.Time) int64 {
+	return t.Unix() / int64(p.CodeRotationMinutes*60)
+}
+
+// codeForWindow derives the rotating code for a window from the stored seed
+func (p *TestAccessPolicy) codeForWindow(window int64) string {
+	mac := hmac.New(sha256.New, []byte(*p.AccessCode))
+	var buf [12]byte
+	binary.BigEndian.PutUint32(buf[:4], uint32(p.TestID))
+	binary.BigEndian.PutUint64(buf[4:], uint64(window))
+	mac.Write(buf[:])
+	sum := mac.Sum(nil)
+
+	value := binary.BigEndian.Uint32(sum[:4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", accessCodeDigits, value%1000000)
+}
+
+// CodeAt returns the access code valid at the given time and when it stops being valid
+func (p *TestAccessPolicy) CodeAt(t time.Time) (string, *time.Time) {
+	if !p.RequiresAccessCode() {
+		return "", nil
+	}
+	if !p.IsRotating() {
+		return *p.AccessCode, nil
+	}
+
+	window := p.rotationWindow(t)
+	validUntil := time.Unix((window+1)*int64(p.CodeRotationMinutes*60), 0)
+	return p.codeForWindow(window), &validUntil
+}
+
+// ValidateCode checks an access code against the policy. Rotating codes from the
+// previous window are still accepted so candidates are not rejected mid-announcement.
+func (p *TestAccessPolicy) ValidateCode(code string, now time.Time) bool {
+	if !p.RequiresAccessCode() {
+		return true
+	}
+
+	code = strings.TrimSpace(code)
+	if !p.IsRotating() {
+		return subtle.ConstantTimeCompare([]byte(code), []byte(*p.AccessCode)) == 1
+	}
+
+	window := p.rotationWindow(now)
+	for _, w := range []int64{window, window - 1} {
+		if subtle.ConstantTimeCompare([]byte(code), []byte(p.codeForWindow(w))) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP checks if the client IP is inside one of the allowed CIDR blocks
+func (p *TestAccessPolicy) AllowsIP(clientIP string) bool {
+	if len(p.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+
+	for _, cidr := range p.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanTestAccessPolicy scans database row into TestAccessPolicy struct
+func ScanTestAccessPolicy(row interface {
+	Scan(dest ...interface{}) error
+}) (*TestAccessPolicy, error) {
+	policy := &TestAccessPolicy{}
+	var allowedCIDRs sql.NullString
+	err := row.Scan(
+		&policy.TestID,
+		&policy.AccessCode,
+		&policy.CodeRotationMinutes,
+		&allowedCIDRs,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	policy.AllowedCIDRs = []string{}
+	if allowedCIDRs.Valid && allowedCIDRs.String != "" {
+		policy.AllowedCIDRs = strings.Split(allowedCIDRs.String, ",")
+	}
+	return policy, nil
+}
+
+// ScanAccessGateFailure scans database row into AccessGateFailure struct
+func ScanAccessGateFailure(row interface {
+	Scan(dest ...interface{}) error
+}) (*AccessGateFailure, error) {
+	failure := &AccessGateFailure{}
+	err := row.Scan(
+		&failure.ID,
+		&failure.TestID,
+		&failure.UserID,
+		&failure.IPAddress,
+		&failure.Reason,
+		&failure.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return failure, nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Account token purposes
+const (
+	AccountTokenPasswordReset     = "password_reset"
+	AccountTokenEmailVerification = "email_verification"
+)
+
+// AccountToken is the record of a signed password reset or email verification token. The
+// token itself is only sent by email; the record makes it single-use and ties it to the
+// address it was sent to.
+type AccountToken struct {
+	ID        string     `json:"id" db:"id"` // Token ID (jti)
+	UserID    int        `json:"user_id" db:"user_id"`
+	Purpose   string     `json:"purpose" db:"purpose"`
+	Email     string     `json:"email" db:"email"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AccountRepository defines the interface for account token and failed login data operations
+type AccountRepository interface {
+	CreateAccountToken(token *AccountToken) error
+	GetAccountToken(id string) (*AccountToken, error)
+	UseAccountToken(id string, usedAt time.Time) (bool, error)
+	InvalidateAccountTokens(userID int, purpose string, usedAt time.Time) error
+	CountAccountTokensSince(userID int, purpose string, since time.Time) (int, error)
+	DeleteExpiredAccountTokens(before time.Time) error
+	RecordFailedLogin(username, ipAddress string, attemptedAt time.Time) error
+	CountFailedLogins(username string, since time.Time) (int, error)
+	CountFailedLoginsFromIP(ipAddress string, since time.Time) (int, error)
+	ClearFailedLogins(username string) error
+	DeleteFailedLoginsBefore(before time.Time) error
+}
+
+// AccountService defines the interface for password reset, email verification and login
+// throttling business logic
+type AccountService interface {
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	SendEmailVerification(userID int) error
+	VerifyEmail(token string) (*User, error)
+	CheckLogin(username, ipAddress string) error
+	RecordLoginFailure(username, ipAddress string) error
+	RecordLoginSuccess(username string) error
+}
+
+// IsUsed checks if the token was already used or replaced by a newer one
+func (t *AccountToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
+
+// IsExpired checks if the token has expired
+func (t *AccountToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// ScanAccountToken scans database row into AccountToken struct
+func ScanAccountToken(row interface {
+	Scan(dest ...interface{}) error
+}) (*AccountToken, error) {
+	token := &AccountToken{}
+	var usedAt sql.NullTime
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.Email,
+		&token.ExpiresAt,
+		&usedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"time"
+)
+
+// Default stopping rules of adaptive tests without their own settings
+const (
+	DefaultAdaptiveMinQuestions = 5
+	DefaultAdaptiveMaxQuestions = 20
+	DefaultAdaptiveTargetSE     = 0.3
+	DefaultAdaptivePassingTheta = 0.0
+	MaxAdaptiveQuestions        = 200
+	MaxAdaptivePassingTheta     = 4.0 // largest passing theta, either side of average ability
+)
+
+// AdaptiveStopReason represents why an adaptive session stopped asking questions
+type AdaptiveStopReason string
+
+const (
+	AdaptiveStopPrecision     AdaptiveStopReason = "precision"      // standard error reached the target
+	AdaptiveStopLength        AdaptiveStopReason = "length"         // maximum number of questions asked
+	AdaptiveStopPoolExhausted AdaptiveStopReason = "pool_exhausted" // no calibrated questions left
+)
+
+// AdaptiveSettings represents the stopping rules and the pass mark of an adaptive test.
+// The session stops once the ability standard error is at most TargetSE after at least
+// MinQuestions, or after MaxQuestions, whichever comes first. The candidate passes when
+// the final ability estimate is at least PassingTheta.
+type AdaptiveSettings struct {
+	TestID       int       `json:"test_id" db:"test_id"`
+	MinQuestions int       `json:"min_questions" db:"min_questions"`
+	MaxQuestions int       `json:"max_questions" db:"max_questions"`
+	TargetSE     float64   `json:"target_se" db:"target_se"`
+	PassingTheta float64   `json:"passing_theta" db:"passing_theta"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AdaptiveStep represents one question administered in an adaptive session and the
+// ability estimate after answering it
+type AdaptiveStep struct {
+	ID         int        `json:"id" db:"id"`
+	SessionID  int        `json:"session_id" db:"session_id"`
+	StepNumber int        `json:"step_number" db:"step_number"`
+	QuestionID int        `json:"question_id" db:"question_id"`
+	IsCorrect  *bool      `json:"is_correct" db:"is_correct"` // nil until answered
+	Theta      *float64   `json:"theta" db:"theta"`
+	ThetaSE    *float64   `json:"theta_se" db:"theta_se"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	AnsweredAt *time.Time `json:"answered_at" db:"answered_at"`
+}
+
+// AdaptiveState represents the progress of an adaptive session
+type AdaptiveState struct {
+	SessionID    int                `json:"session_id"`
+	Steps        []*AdaptiveStep    `json:"steps"`
+	Theta        float64            `json:"theta"`
+	ThetaSE      float64            `json:"theta_se"`
+	NextQuestion *Question          `json:"next_question,omitempty"` // nil once stopped
+	Finished     bool               `json:"finished"`
+	StopReason   AdaptiveStopReason `json:"stop_reason,omitempty"`
+
+	// ScoreWithheld is set when the estimates are hidden until the score is released
+	ScoreWithheld bool `json:"score_withheld,omitempty"`
+}
+
+// AdaptiveRepository defines the interface for adaptive testing data operations
+type AdaptiveRepository interface {
+	GetSettings(testID int) (*AdaptiveSettings, error)
+	UpsertSettings(settings *AdaptiveSettings) error
+	DeleteSettings(testID int) error
+	CreateStep(step *AdaptiveStep) error
+	UpdateStep(step *AdaptiveStep) error
+	GetSteps(sessionID int) ([]*AdaptiveStep, error)
+}
+
+// AdaptiveService defines the interface for adaptive testing business logic
+type AdaptiveService interface {
+	GetSettings(testID int) (*AdaptiveSettings, error)
+	UpdateSettings(testID, minQuestions, maxQuestions int, targetSE, passingTheta float64) (*AdaptiveSettings, error)
+	DeleteSettings(testID int) error
+	Begin(session *TestSession) (*AdaptiveState, error)
+	RecordAnswer(session *TestSession, questionID int, isCorrect bool) (*AdaptiveState, error)
+	GetState(session *TestSession) (*AdaptiveState, error)
+}
+
+// DefaultAdaptiveSettings returns the stopping rules used when a test has no settings
+func DefaultAdaptiveSettings(testID int) *AdaptiveSettings {
+	return &AdaptiveSettings{
+		TestID:       testID,
+		MinQuestions: DefaultAdaptiveMinQuestions,
+		MaxQuestions: DefaultAdaptiveMaxQuestions,
+		TargetSE:     DefaultAdaptiveTargetSE,
+		PassingTheta: DefaultAdaptivePassingTheta,
+	}
+}
+
+// IsValid checks if the stopping rules and the pass mark are consistent
+func (s *AdaptiveSettings) IsValid() bool {
+	return s.MinQuestions >= 1 && s.MinQuestions <= s.MaxQuestions &&
+		s.MaxQuestions <= MaxAdaptiveQuestions && s.TargetSE > 0 && s.TargetSE < 2 &&
+		math.Abs(s.PassingTheta) <= MaxAdaptivePassingTheta
+}
+
+// PendingStep returns the step whose question has not been answered yet, if any
+func (s *AdaptiveState) PendingStep() *AdaptiveStep {
+	if len(s.Steps) == 0 {
+		return nil
+	}
+	if last := s.Steps[len(s.Steps)-1]; last.IsCorrect == nil {
+		return last
+	}
+	return nil
+}
+
+// ScanAdaptiveSettings scans database row into AdaptiveSettings struct
+func ScanAdaptiveSettings(row interface {
+	Scan(dest ...interface{}) error
+}) (*AdaptiveSettings, error) {
+	settings := &AdaptiveSettings{}
+	err := row.Scan(
+		&settings.TestID,
+		&settings.MinQuestions,
+		&settings.MaxQuestions,
+		&settings.TargetSE,
+		&settings.PassingTheta,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ScanAdaptiveStep scans database row into AdaptiveStep struct
+func ScanAdaptiveStep(row interface {
+	Scan(dest ...interface{}) error
+}) (*AdaptiveStep, error) {
+	step := &AdaptiveStep{}
+	err := row.Scan(
+		&step.ID,
+		&step.SessionID,
+		&step.StepNumber,
+		&step.QuestionID,
+		&step.IsCorrect,
+		&step.Theta,
+		&step.ThetaSE,
+		&step.CreatedAt,
+		&step.AnsweredAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return step, nil
+}
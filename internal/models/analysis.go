@@ -0,0 +1,170 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// ItemAnalysisGroupFraction is the share of candidates in the upper and lower groups
+const ItemAnalysisGroupFraction = 0.27
+
+// ItemAnalysis represents the per-question analysis of a test
+type ItemAnalysis struct {
+	TestID     int               `json:"test_id"`
+	Candidates int               `json:"candidates"`
+	GroupSize  int               `json:"group_size"` // candidates in each of the upper and lower 27% groups
+	MeanScore  float64           `json:"mean_score"`
+	Items      []*ItemStatistics `json:"items"`
+}
+
+// ItemStatistics represents the classical item statistics of one question
+type ItemStatistics struct {
+	QuestionID          int                     `json:"question_id"`
+	QuestionText        string                  `json:"question_text"`
+	QuestionType        QuestionType            `json:"question_type"`
+	Marks               int                     `json:"marks"`
+	Responses           int                     `json:"responses"`
+	Omits               int                     `json:"omits"`
+	OmitRate            float64                 `json:"omit_rate"`
+	Difficulty          float64                 `json:"difficulty"`           // p-value: share of candidates answering correctly
+	PointBiserial       *float64                `json:"point_biserial"`       // nil when every candidate scored the same
+	UpperDifficulty     float64                 `json:"upper_difficulty"`     // p-value in the upper group
+	LowerDifficulty     float64                 `json:"lower_difficulty"`     // p-value in the lower group
+	DiscriminationIndex float64                 `json:"discrimination_index"` // upper minus lower p-value
+	Distractors         []*DistractorStatistics `json:"distractors,omitempty"`
+}
+
+// DistractorStatistics represents how often an option was chosen
+type DistractorStatistics struct {
+	OptionID        int     `json:"option_id"`
+	OptionText      string  `json:"option_text"`
+	IsCorrect       bool    `json:"is_correct"`
+	Count           int     `json:"count"`
+	Proportion      float64 `json:"proportion"`
+	UpperCount      int     `json:"upper_count"`
+	UpperProportion float64 `json:"upper_proportion"`
+	LowerCount      int     `json:"lower_count"`
+	LowerProportion float64 `json:"lower_proportion"`
+}
+
+// Histogram bin limits for the score distribution
+const (
+	DefaultHistogramBins = 10
+	MaxHistogramBins     = 100
+)
+
+// ReliabilityReport represents the score distribution and reliability of a test
+type ReliabilityReport struct {
+	TestID       int                `json:"test_id"`
+	Candidates   int                `json:"candidates"`
+	Items        int                `json:"items"`
+	Distribution *ScoreDistribution `json:"distribution"`
+	Reliability  *ReliabilityStats  `json:"reliability"`
+}
+
+// ScoreDistribution represents descriptive statistics of the percentage scores
+type ScoreDistribution struct {
+	Mean          float64         `json:"mean"`
+	Median        float64         `json:"median"`
+	StdDev        float64         `json:"std_dev"` // population standard deviation
+	Minimum       float64         `json:"minimum"`
+	Maximum       float64         `json:"maximum"`
+	LowerQuartile float64         `json:"lower_quartile"`
+	UpperQuartile float64         `json:"upper_quartile"`
+	Histogram     []*HistogramBin `json:"histogram"`
+}
+
+// HistogramBin represents the number of scores in a percentage range. Every bin
+// includes its lower bound; only the last one also includes its upper bound.
+type HistogramBin struct {
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Count      int     `json:"count"`
+	Proportion float64 `json:"proportion"`
+}
+
+// ReliabilityStats represents the internal consistency of a test. Each value is
+// nil when it cannot be computed, e.g. with fewer than two questions or when
+// every candidate has the same score.
+type ReliabilityStats struct {
+	KR20          *float64 `json:"kr20"`           // on correct/incorrect item scores
+	CronbachAlpha *float64 `json:"cronbach_alpha"` // on awarded marks
+	SEM           *float64 `json:"sem"`            // standard error of measurement, in percentage points
+}
+
+// ItemResponse represents one candidate's answer joined with their result.
+// Results without answers have a nil QuestionID.
+type ItemResponse struct {
+	ResultID         int
+	MarksObtained    int
+	Percentage       float64
+	QuestionID       *int
+	SelectedOptionID *int
+	AnswerText       *string
+	IsCorrect        *bool
+	MarksAwarded     int
+}
+
+// AnalysisRepository defines the interface for test analysis data operations
+type AnalysisRepository interface {
+	GetItemResponses(testID int) ([]*ItemResponse, error)
+}
+
+// AnalysisService defines the interface for test analysis business logic
+type AnalysisService interface {
+	GetItemAnalysis(testID int) (*ItemAnalysis, error)
+	GetReliabilityReport(testID int, bins int) (*ReliabilityReport, error)
+}
+
+// IsOmitted checks if the candidate left the question without an answer
+func (r *ItemResponse) IsOmitted() bool {
+	if r.QuestionID == nil {
+		return true
+	}
+	return r.SelectedOptionID == nil && (r.AnswerText == nil || *r.AnswerText == "")
+}
+
+// ScanItemResponse scans database row into ItemResponse struct
+func ScanItemResponse(row interface {
+	Scan(dest ...interface{}) error
+}) (*ItemResponse, error) {
+	response := &ItemResponse{}
+	var questionID, selectedOptionID sql.NullInt64
+	var answerText sql.NullString
+	var isCorrect sql.NullBool
+	var marksAwarded sql.NullInt64
+	err := row.Scan(
+		&response.ResultID,
+		&response.MarksObtained,
+		&response.Percentage,
+		&questionID,
+		&selectedOptionID,
+		&answerText,
+		&isCorrect,
+		&marksAwarded,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if questionID.Valid {
+		id := int(questionID.Int64)
+		response.QuestionID = &id
+	}
+	if selectedOptionID.Valid {
+		id := int(selectedOptionID.Int64)
+		response.SelectedOptionID = &id
+	}
+	if answerText.Valid {
+		response.AnswerText = &answerText.String
+	}
+	if isCorrect.Valid {
+		response.IsCorrect = &isCorrect.Bool
+	}
+	if marksAwarded.Valid {
+		response.MarksAwarded = int(marksAwarded.Int64)
+	}
+	return response, nil
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Defaults of tests without a certificate template
+const (
+	DefaultCertificateTitle = "Certificate of Achievement"
+	DefaultCertificateBody  = "has passed {test} with a score of {score} on {date}."
+)
+
+// CertificateTemplate represents the wording of the certificates issued for a test. The
+// body is printed below the candidate's name; the title and body may use the {name},
+// {test}, {score}, {grade}, {date} and {id} placeholders.
+type CertificateTemplate struct {
+	TestID         int       `json:"test_id" db:"test_id"`
+	Title          string    `json:"title" db:"title"`
+	Body           string    `json:"body" db:"body"`
+	SignatoryName  string    `json:"signatory_name" db:"signatory_name"`
+	SignatoryTitle string    `json:"signatory_title" db:"signatory_title"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Certificate represents a certificate issued for a passed result. The details are
+// recorded as printed, so verification confirms exactly what the certificate shows.
+type Certificate struct {
+	ID             int       `json:"id" db:"id"`
+	VerificationID string    `json:"verification_id" db:"verification_id"`
+	ResultID       int       `json:"result_id" db:"result_id"`
+	TestID         int       `json:"test_id" db:"test_id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	CandidateName  string    `json:"candidate_name" db:"candidate_name"`
+	TestTitle      string    `json:"test_title" db:"test_title"`
+	Percentage     float64   `json:"percentage" db:"percentage"`
+	Grade          *string   `json:"grade" db:"grade"`
+	CompletedAt    time.Time `json:"completed_at" db:"completed_at"`
+	IssuedAt       time.Time `json:"issued_at" db:"issued_at"`
+}
+
+// CertificateVerification represents the public answer to a certificate check
+type CertificateVerification struct {
+	VerificationID string     `json:"verification_id"`
+	Valid          bool       `json:"valid"`
+	Reason         string     `json:"reason,omitempty"` // why a certificate on record is no longer valid
+	CandidateName  string     `json:"candidate_name,omitempty"`
+	TestTitle      string     `json:"test_title,omitempty"`
+	Percentage     *float64   `json:"percentage,omitempty"`
+	Grade          *string    `json:"grade,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	IssuedAt       *time.Time `json:"issued_at,omitempty"`
+}
+
+// CertificateRepository defines the interface for certificate data operations
+type CertificateRepository interface {
+	GetTemplate(testID int) (*CertificateTemplate, error)
+	UpsertTemplate(template *CertificateTemplate) error
+	DeleteTemplate(testID int) error
+	Create(certificate *Certificate) error
+	GetByResultID(resultID int) (*Certificate, error)
+	GetByVerificationID(verificationID string) (*Certificate, error)
+}
+
+// CertificateService defines the interface for score report and certificate business logic
+type CertificateService interface {
+	GetTemplate(testID int) (*CertificateTemplate, error)
+	UpdateTemplate(testID int, title, body, signatoryName, signatoryTitle string) (*CertificateTemplate, error)
+	DeleteTemplate(testID int) error
+	GetScoreReport(resultID int, fullAccess bool) ([]byte, error)
+	GetCertificate(resultID int, fullAccess bool) (*Certificate, []byte, error)
+	VerifyCertificate(verificationID string) (*CertificateVerification, error)
+}
+
+// DefaultCertificateTemplate returns the template of tests without one
+func DefaultCertificateTemplate(testID int) *CertificateTemplate {
+	return &CertificateTemplate{
+		TestID: testID,
+		Title:  DefaultCertificateTitle,
+		Body:   DefaultCertificateBody,
+	}
+}
+
+// IsValid checks if the template has a title and body within the column limits
+func (t *CertificateTemplate) IsValid() bool {
+	title := strings.TrimSpace(t.Title)
+	return title != "" && len(title) <= 200 && strings.TrimSpace(t.Body) != "" && len(t.Body) <= 2000 &&
+		len(t.SignatoryName) <= 200 && len(t.SignatoryTitle) <= 200
+}
+
+// ScanCertificateTemplate scans database row into CertificateTemplate struct
+func ScanCertificateTemplate(row interface {
+	Scan(dest ...interface{}) error
+}) (*CertificateTemplate, error) {
+	template := &CertificateTemplate{}
+	err := row.Scan(
+		&template.TestID,
+		&template.Title,
+		&template.Body,
+		&template.SignatoryName,
+		&template.SignatoryTitle,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// ScanCertificate scans database row into Certificate struct
+func ScanCertificate(row interface {
+	Scan(dest ...interface{}) error
+}) (*Certificate, error) {
+	certificate := &Certificate{}
+	err := row.Scan(
+		&certificate.ID,
+		&certificate.VerificationID,
+		&certificate.ResultID,
+		&certificate.TestID,
+		&certificate.UserID,
+		&certificate.CandidateName,
+		&certificate.TestTitle,
+		&certificate.Percentage,
+		&certificate.Grade,
+		&certificate.CompletedAt,
+		&certificate.IssuedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return certificate, nil
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"io"
+	"time"
+)
+
+// ExportFormat represents the file format of an export
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatXLSX ExportFormat = "xlsx"
+)
+
+// IsValid checks if the export format is supported
+func (f ExportFormat) IsValid() bool {
+	return f == ExportFormatCSV || f == ExportFormatXLSX
+}
+
+// ContentType returns the MIME type of the export format
+func (f ExportFormat) ContentType() string {
+	if f == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ResultExportRow represents a graded result with the candidate it belongs to
+type ResultExportRow struct {
+	Result    *TestResult
+	Username  string
+	FirstName string
+	LastName  string
+	Email     string
+}
+
+// AnswerExportRow represents one stored answer with its candidate and question
+type AnswerExportRow struct {
+	AnswerID         int
+	SessionID        int
+	UserID           int
+	Username         string
+	QuestionID       int
+	OrderIndex       int
+	QuestionText     string
+	QuestionType     QuestionType
+	AnswerText       *string
+	SelectedOptionID *int
+	SelectedOption   *string
+	IsCorrect        *bool
+	MarksAwarded     int
+	AnsweredAt       time.Time
+}
+
+// ExportRepository defines the interface for export data operations
+type ExportRepository interface {
+	GetResultRows(testID int) ([]*ResultExportRow, error)
+	StreamAnswers(testID int, fn func(*AnswerExportRow) error) error
+}
+
+// ExportService defines the interface for export business logic
+type ExportService interface {
+	ExportResults(testID int, format ExportFormat, w io.Writer) error
+	ExportAnswers(testID int, format ExportFormat, w io.Writer) error
+}
+
+// ScanResultExportRow scans database row into ResultExportRow struct
+func ScanResultExportRow(row interface {
+	Scan(dest ...interface{}) error
+}) (*ResultExportRow, error) {
+	result := &TestResult{}
+	exportRow := &ResultExportRow{Result: result}
+	err := row.Scan(
+		&result.ID,
+		&result.SessionID,
+		&result.TestID,
+		&result.UserID,
+		&result.TotalQuestions,
+		&result.AnsweredQuestions,
+		&result.CorrectAnswers,
+		&result.TotalMarks,
+		&result.MarksObtained,
+		&result.Percentage,
+		&result.Grade,
+		&result.GradePoints,
+		&result.IsPassed,
+		&result.TimeTaken,
+		&result.HasOverride,
+		&result.Theta,
+		&result.ThetaSE,
+		&result.CompletedAt,
+		&exportRow.Username,
+		&exportRow.FirstName,
+		&exportRow.LastName,
+		&exportRow.Email,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return exportRow, nil
+}
+
+// ScanAnswerExportRow scans database row into AnswerExportRow struct
+func ScanAnswerExportRow(row interface {
+	Scan(dest ...interface{}) error
+}) (*AnswerExportRow, error) {
+	answer := &AnswerExportRow{}
+	var answerText, selectedOption sql.NullString
+	var selectedOptionID sql.NullInt64
+	var isCorrect sql.NullBool
+
+	err := row.Scan(
+		&answer.AnswerID,
+		&answer.SessionID,
+		&answer.UserID,
+		&answer.Username,
+		&answer.QuestionID,
+		&answer.OrderIndex,
+		&answer.QuestionText,
+		&answer.QuestionType,
+		&answerText,
+		&selectedOptionID,
+		&selectedOption,
+		&isCorrect,
+		&answer.MarksAwarded,
+		&answer.AnsweredAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if answerText.Valid {
+		answer.AnswerText = &answerText.String
+	}
+	if selectedOptionID.Valid {
+		id := int(selectedOptionID.Int64)
+		answer.SelectedOptionID = &id
+	}
+	if selectedOption.Valid {
+		answer.SelectedOption = &selectedOption.String
+	}
+	if isCorrect.Valid {
+		answer.IsCorrect = &isCorrect.Bool
+	}
+
+	return answer, nil
+}
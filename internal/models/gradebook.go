@@ -0,0 +1,193 @@
+package models
+
+import (
+	"database/sql"
+	"io"
+	"time"
+)
+
+// Course represents a course whose tests are grouped into weighted grade categories
+type Course struct {
+	ID             int       `json:"id" db:"id"`
+	Name           string    `json:"name" db:"name"`
+	Description    string    `json:"description" db:"description"`
+	CreatedBy      int       `json:"created_by" db:"created_by"`             // the course owner
+	GradingScaleID *int      `json:"grading_scale_id" db:"grading_scale_id"` // nil for the default scale
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// Related data (not stored in database)
+	Categories []*CourseCategory `json:"categories"`
+}
+
+// CourseCategory represents a weighted group of tests, such as quizzes or the final exam
+type CourseCategory struct {
+	ID         int     `json:"id" db:"id"`
+	CourseID   int     `json:"course_id" db:"course_id"`
+	Name       string  `json:"name" db:"name"`
+	Weight     float64 `json:"weight" db:"weight"`           // percent of the course grade
+	DropLowest int     `json:"drop_lowest" db:"drop_lowest"` // lowest test scores left out
+	Position   int     `json:"position" db:"position"`
+	TestIDs    []int   `json:"test_ids"`
+}
+
+// CourseResultRow represents the graded result of a student on a course test
+type CourseResultRow struct {
+	ResultID    int
+	TestID      int
+	UserID      int
+	Username    string
+	FirstName   string
+	LastName    string
+	Email       string
+	Percentage  float64
+	CompletedAt time.Time
+}
+
+// Gradebook represents the running course grades of every student with a graded result
+type Gradebook struct {
+	Course      *Course          `json:"course"`
+	Tests       []*GradebookTest `json:"tests"`
+	Students    []*StudentGrade  `json:"students"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// GradebookTest represents a test column of the gradebook
+type GradebookTest struct {
+	TestID     int    `json:"test_id"`
+	Title      string `json:"title"`
+	CategoryID int    `json:"category_id"`
+}
+
+// StudentGrade represents a student's running weighted grade in a course. The percentage
+// is weighted over the categories with at least one counted score, so it reflects the
+// work completed so far; WeightCompleted is the share of the course grade it covers.
+type StudentGrade struct {
+	UserID          int              `json:"user_id"`
+	Username        string           `json:"username"`
+	FirstName       string           `json:"first_name"`
+	LastName        string           `json:"last_name"`
+	Email           string           `json:"email"`
+	Categories      []*CategoryGrade `json:"categories"`
+	Percentage      *float64         `json:"percentage"`
+	Grade           *string          `json:"grade"`
+	GradePoints     *float64         `json:"grade_points"`
+	WeightCompleted float64          `json:"weight_completed"`
+}
+
+// CategoryGrade represents a student's average in one course category
+type CategoryGrade struct {
+	CategoryID int               `json:"category_id"`
+	Name       string            `json:"name"`
+	Weight     float64           `json:"weight"`
+	Percentage *float64          `json:"percentage"` // nil until a score counts
+	Scores     []*GradebookScore `json:"scores"`
+}
+
+// GradebookScore represents a student's counting result on one test of a category
+type GradebookScore struct {
+	TestID     int      `json:"test_id"`
+	ResultID   *int     `json:"result_id"` // nil while the test has not been taken
+	Percentage *float64 `json:"percentage"`
+	Dropped    bool     `json:"dropped"`  // left out by the category's drop-lowest rule
+	Withheld   bool     `json:"withheld"` // taken, but the score is not released yet
+}
+
+// CourseRepository defines the interface for course and gradebook data operations
+type CourseRepository interface {
+	Create(course *Course) error
+	GetByID(id int) (*Course, error)
+	List(ownerID int, limit, offset int) ([]*Course, error)
+	Update(course *Course) error
+	Delete(id int) error
+	ReplaceCategories(courseID int, categories []*CourseCategory) error
+	GetCategories(courseID int) ([]*CourseCategory, error)
+	GetResults(courseID int) ([]*CourseResultRow, error)
+	GetUserResults(courseID, userID int) ([]*CourseResultRow, error)
+}
+
+// GradebookService defines the interface for course and gradebook business logic.
+// canViewResults reports whether the requesting user may view every result of a test;
+// a course may only count, and a gradebook only show, tests it allows.
+type GradebookService interface {
+	CreateCourse(creatorID int, name, description string, gradingScaleID *int, categories []*CourseCategory, canViewResults func(testID int) bool) (*Course, error)
+	GetCourse(courseID int) (*Course, error)
+	ListCourses(ownerID int, limit, offset int) ([]*Course, error)
+	UpdateCourse(courseID int, name, description string, gradingScaleID *int, categories []*CourseCategory, canViewResults func(testID int) bool) (*Course, error)
+	DeleteCourse(courseID int) error
+	GetGradebook(courseID int, canViewResults func(testID int) bool) (*Gradebook, error)
+	GetStudentGrade(courseID, userID int) (*StudentGrade, error)
+	ExportGradebook(courseID int, format ExportFormat, w io.Writer, canViewResults func(testID int) bool) error
+}
+
+// ScanCourse scans database row into Course struct
+func ScanCourse(row interface {
+	Scan(dest ...interface{}) error
+}) (*Course, error) {
+	course := &Course{}
+	var description sql.NullString
+	err := row.Scan(
+		&course.ID,
+		&course.Name,
+		&description,
+		&course.CreatedBy,
+		&course.GradingScaleID,
+		&course.CreatedAt,
+		&course.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	course.Description = description.String
+	return course, nil
+}
+
+// ScanCourseCategory scans database row into CourseCategory struct
+func ScanCourseCategory(row interface {
+	Scan(dest ...interface{}) error
+}) (*CourseCategory, error) {
+	category := &CourseCategory{}
+	err := row.Scan(
+		&category.ID,
+		&category.CourseID,
+		&category.Name,
+		&category.Weight,
+		&category.DropLowest,
+		&category.Position,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+// ScanCourseResultRow scans database row into CourseResultRow struct
+func ScanCourseResultRow(row interface {
+	Scan(dest ...interface{}) error
+}) (*CourseResultRow, error) {
+	result := &CourseResultRow{}
+	err := row.Scan(
+		&result.ResultID,
+		&result.TestID,
+		&result.UserID,
+		&result.Username,
+		&result.FirstName,
+		&result.LastName,
+		&result.Email,
+		&result.Percentage,
+		&result.CompletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// GradingScale represents a named set of grade bands, defined globally or for a single test
+type GradingScale struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	TestID      *int      `json:"test_id" db:"test_id"` // nil for global scales
+	CreatedBy   int       `json:"created_by" db:"created_by"`
+	IsDefault   bool      `json:"is_default" db:"is_default"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// Related data (not stored in database)
+	Bands []*GradingBand `json:"bands"`
+}
+
+// GradingBand represents a grade awarded from a percentage cut-off upwards
+type GradingBand struct {
+	ID            int      `json:"id" db:"id"`
+	ScaleID       int      `json:"scale_id" db:"scale_id"`
+	Label         string   `json:"label" db:"label"`
+	MinPercentage float64  `json:"min_percentage" db:"min_percentage"`
+	GPAPoints     *float64 `json:"gpa_points" db:"gpa_points"`
+}
+
+// GradingScaleRepository defines the interface for grading scale data operations
+type GradingScaleRepository interface {
+	Create(scale *GradingScale) error
+	GetByID(id int) (*GradingScale, error)
+	GetDefault() (*GradingScale, error)
+	List(testID int) ([]*GradingScale, error)
+	Update(scale *GradingScale) error
+	Delete(id int) error
+	SetDefault(id int) error
+	ReplaceBands(scaleID int, bands []*GradingBand) error
+	GetBands(scaleID int) ([]*GradingBand, error)
+}
+
+// GradingScaleService defines the interface for grading scale business logic
+type GradingScaleService interface {
+	CreateScale(creatorID int, name, description string, testID *int, bands []*GradingBand) (*GradingScale, error)
+	GetScale(scaleID int) (*GradingScale, error)
+	ListScales(testID int) ([]*GradingScale, error)
+	UpdateScale(scaleID int, name, description string, bands []*GradingBand) (*GradingScale, error)
+	DeleteScale(scaleID int) error
+	SetDefaultScale(scaleID int) error
+	AssignScale(testID int, scaleID *int) (*Test, error)
+	GetScaleForTest(test *Test) (*GradingScale, error)
+	RecomputeGrades(testID int) (int, error)
+}
+
+// DefaultGradingScale returns the built-in letter grade scale used when no scale is configured
+func DefaultGradingScale() *GradingScale {
+	return &GradingScale{
+		Name: "Default",
+		Bands: []*GradingBand{
+			{Label: "A+", MinPercentage: 90},
+			{Label: "A", MinPercentage: 80},
+			{Label: "B+", MinPercentage: 70},
+			{Label: "B", MinPercentage: 60},
+			{Label: "C", MinPercentage: 50},
+			{Label: "D", MinPercentage: 40},
+			{Label: "F", MinPercentage: 0},
+		},
+	}
+}
+
+// SortBands orders the bands from the highest cut-off to the lowest
+func (s *GradingScale) SortBands() {
+	sort.SliceStable(s.Bands, func(i, j int) bool {
+		return s.Bands[i].MinPercentage > s.Bands[j].MinPercentage
+	})
+}
+
+// BandFor returns the band a percentage falls into, or nil if no band covers it
+func (s *GradingScale) BandFor(percentage float64) *GradingBand {
+	var match *GradingBand
+	for _, band := range s.Bands {
+		if percentage >= band.MinPercentage && (match == nil || band.MinPercentage > match.MinPercentage) {
+			match = band
+		}
+	}
+	return match
+}
+
+// ApplyTo sets the grade and GPA points of a result from this scale
+func (s *GradingScale) ApplyTo(result *TestResult) {
+	band := s.BandFor(result.Percentage)
+	if band == nil {
+		result.Grade = nil
+		result.GradePoints = nil
+		return
+	}
+
+	grade := band.Label
+	result.Grade = &grade
+	result.GradePoints = band.GPAPoints
+}
+
+// ScanGradingScale scans database row into GradingScale struct
+func ScanGradingScale(row interface {
+	Scan(dest ...interface{}) error
+}) (*GradingScale, error) {
+	scale := &GradingScale{}
+	var description sql.NullString
+	err := row.Scan(
+		&scale.ID,
+		&scale.Name,
+		&description,
+		&scale.TestID,
+		&scale.CreatedBy,
+		&scale.IsDefault,
+		&scale.CreatedAt,
+		&scale.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	scale.Description = description.String
+	return scale, nil
+}
+
+// ScanGradingBand scans database row into GradingBand struct
+func ScanGradingBand(row interface {
+	Scan(dest ...interface{}) error
+}) (*GradingBand, error) {
+	band := &GradingBand{}
+	err := row.Scan(
+		&band.ID,
+		&band.ScaleID,
+		&band.Label,
+		&band.MinPercentage,
+		&band.GPAPoints,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return band, nil
+}
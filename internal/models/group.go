@@ -0,0 +1,147 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GroupKind represents whether a group is a class or another grouping of students
+type GroupKind string
+
+const (
+	GroupKindClass GroupKind = "class"
+	GroupKindGroup GroupKind = "group"
+)
+
+// Group represents a class or group of students owned by a teacher. Tests assigned to a
+// group are offered to its members only.
+type Group struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Kind        GroupKind `json:"kind" db:"kind"`
+	OwnerID     int       `json:"owner_id" db:"owner_id"`
+	InviteCode  *string   `json:"invite_code,omitempty" db:"invite_code"` // nil when enrolment by code is closed
+	// DirectoryGroup is the common name of the directory group whose members are enrolled
+	// when they sign in; nil when membership is managed by hand
+	DirectoryGroup *string   `json:"directory_group,omitempty" db:"directory_group"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// Related data (not stored in database)
+	MemberCount int `json:"member_count"`
+}
+
+// GroupMember represents a user enrolled in a group
+type GroupMember struct {
+	GroupID   int       `json:"group_id" db:"group_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Role      UserRole  `json:"role"`
+	JoinedAt  time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// GroupRepository defines the interface for group and enrolment data operations
+type GroupRepository interface {
+	Create(group *Group) error
+	GetByID(id int) (*Group, error)
+	GetByInviteCode(code string) (*Group, error)
+	List(ownerID int, limit, offset int) ([]*Group, error)
+	GetByMember(userID int) ([]*Group, error)
+	Update(group *Group) error
+	Delete(id int) error
+	SetInviteCode(groupID int, code *string) error
+	SetDirectoryGroup(groupID int, directoryGroup *string) error
+	SyncDirectoryMembers(userID int, directoryGroups []string) error
+	AddMember(groupID, userID int) error
+	RemoveMember(groupID, userID int) error
+	GetMembers(groupID int) ([]*GroupMember, error)
+	GetByTest(testID int) ([]*Group, error)
+	ReplaceTestGroups(testID int, groupIDs []int) error
+	IsTestAssigned(testID, userID int) (bool, error)
+}
+
+// GroupService defines the interface for group and enrolment business logic
+type GroupService interface {
+	CreateGroup(ownerID int, name, description string, kind GroupKind) (*Group, error)
+	GetGroup(groupID int) (*Group, error)
+	ListGroups(ownerID int, limit, offset int) ([]*Group, error)
+	GetUserGroups(userID int) ([]*Group, error)
+	UpdateGroup(groupID int, name, description string, kind GroupKind) (*Group, error)
+	DeleteGroup(groupID int) error
+	RegenerateInviteCode(groupID int) (*Group, error)
+	CloseInviteCode(groupID int) error
+	LinkDirectoryGroup(groupID int, directoryGroup string) (*Group, error)
+	JoinGroup(userID int, inviteCode string) (*Group, error)
+	AddMember(groupID, userID int) error
+	RemoveMember(groupID, userID int) error
+	GetMembers(groupID int) ([]*GroupMember, error)
+	GetTestGroups(testID int) ([]*Group, error)
+	AssignTest(testID int, groupIDs []int) ([]*Group, error)
+	CheckAssignment(testID, userID int) error
+}
+
+// IsValid checks if the group kind is valid
+func (k GroupKind) IsValid() bool {
+	switch k {
+	case GroupKindClass, GroupKindGroup:
+		return true
+	default:
+		return false
+	}
+}
+
+// ScanGroup scans database row into Group struct
+func ScanGroup(row interface {
+	Scan(dest ...interface{}) error
+}) (*Group, error) {
+	group := &Group{}
+	var description sql.NullString
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&description,
+		&group.Kind,
+		&group.OwnerID,
+		&group.InviteCode,
+		&group.DirectoryGroup,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+		&group.MemberCount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	group.Description = description.String
+	return group, nil
+}
+
+// ScanGroupMember scans database row into GroupMember struct
+func ScanGroupMember(row interface {
+	Scan(dest ...interface{}) error
+}) (*GroupMember, error) {
+	member := &GroupMember{}
+	err := row.Scan(
+		&member.GroupID,
+		&member.UserID,
+		&member.Username,
+		&member.FirstName,
+		&member.LastName,
+		&member.Email,
+		&member.Role,
+		&member.JoinedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return member, nil
+}
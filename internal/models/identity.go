@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserIdentity links an account at an external identity source, such as an OpenID
+// Connect provider or an LDAP directory, to a user. The issuer names the source and the
+// subject is the identifier it assigns the account.
+type UserIdentity struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Issuer      string    `json:"issuer" db:"issuer"`
+	Subject     string    `json:"subject" db:"subject"`
+	Email       string    `json:"email" db:"email"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// IdentityRepository defines the interface for external identity link data operations
+type IdentityRepository interface {
+	GetIdentity(issuer, subject string) (*UserIdentity, error)
+	CreateIdentity(identity *UserIdentity) error
+	TouchIdentity(id int, email string, loginAt time.Time) error
+}
+
+// Authenticator checks a username and password against one source of accounts, such as
+// local password hashes or an LDAP directory. It returns auth.ErrInvalidCredentials when
+// the source does not know the user or the password is wrong, so that the next
+// authenticator can be tried.
+type Authenticator interface {
+	Authenticate(username, password string) (*User, error)
+}
+
+// ScanUserIdentity scans database row into UserIdentity struct
+func ScanUserIdentity(row interface {
+	Scan(dest ...interface{}) error
+}) (*UserIdentity, error) {
+	identity := &UserIdentity{}
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// LedgerAction represents the kind of change a result ledger entry records
+type LedgerAction string
+
+const (
+	LedgerActionCreate LedgerAction = "create"
+	LedgerActionUpdate LedgerAction = "update"
+	LedgerActionDelete LedgerAction = "delete"
+)
+
+// Reasons recorded with result ledger entries
+const (
+	LedgerReasonCalculated   = "calculated"
+	LedgerReasonRegraded     = "regraded"
+	LedgerReasonGradeChanged = "grade_recomputed"
+	LedgerReasonCalibrated   = "ability_rescored"
+	LedgerReasonTestDeleted  = "test_deleted"
+	LedgerReasonSealed       = "sealed" // existing result recorded when the ledger was adopted
+)
+
+// LedgerGenesisHash is the previous hash of the first ledger entry
+const LedgerGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Integrity problems found by verification
+const (
+	IntegrityBrokenChain     = "broken_chain"     // an entry was changed, removed or inserted
+	IntegrityBadSignature    = "bad_signature"    // an entry or result signature does not match
+	IntegrityNotRecorded     = "not_recorded"     // the result was created outside the application
+	IntegrityModified        = "modified"         // the result differs from its last recorded state
+	IntegrityDeleted         = "deleted"          // the result was deleted outside the application
+	IntegrityDeletedRecorded = "deleted_recorded" // the result exists but was recorded as deleted
+)
+
+// ResultLedgerEntry represents one link of the hash chain over result changes
+type ResultLedgerEntry struct {
+	ID          int          `json:"id" db:"id"`
+	Sequence    int          `json:"sequence" db:"sequence"`
+	ResultID    int          `json:"result_id" db:"result_id"`
+	TestID      int          `json:"test_id" db:"test_id"`
+	Action      LedgerAction `json:"action" db:"action"`
+	Reason      string       `json:"reason" db:"reason"`
+	Payload     string       `json:"payload" db:"payload"`
+	PayloadHash string       `json:"payload_hash" db:"payload_hash"`
+	PrevHash    string       `json:"prev_hash" db:"prev_hash"`
+	EntryHash   string       `json:"entry_hash" db:"entry_hash"`
+	Signature   string       `json:"signature" db:"signature"`
+	KeyID       string       `json:"key_id" db:"key_id"`
+	RecordedAt  time.Time    `json:"recorded_at" db:"recorded_at"`
+}
+
+// SignedResult represents a stored result with its signature
+type SignedResult struct {
+	Result    *TestResult
+	Signature *string
+}
+
+// ResultPayload is the canonical form of a result that is hashed and signed
+type ResultPayload struct {
+	ID                int      `json:"id"`
+	SessionID         int      `json:"session_id"`
+	TestID            int      `json:"test_id"`
+	UserID            int      `json:"user_id"`
+	TotalQuestions    int      `json:"total_questions"`
+	AnsweredQuestions int      `json:"answered_questions"`
+	CorrectAnswers    int      `json:"correct_answers"`
+	TotalMarks        int      `json:"total_marks"`
+	MarksObtained     int      `json:"marks_obtained"`
+	Percentage        float64  `json:"percentage"`
+	Grade             *string  `json:"grade"`
+	GradePoints       *float64 `json:"grade_points"`
+	IsPassed          bool     `json:"is_passed"`
+	TimeTaken         *int     `json:"time_taken"`
+	HasOverride       bool     `json:"has_override"`
+	Theta             *float64 `json:"theta"`
+	ThetaSE           *float64 `json:"theta_se"`
+	CompletedAt       string   `json:"completed_at"`
+}
+
+// IntegrityIssue represents one problem found by integrity verification
+type IntegrityIssue struct {
+	Problem  string `json:"problem"`
+	ResultID *int   `json:"result_id,omitempty"`
+	Sequence *int   `json:"sequence,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// IntegrityReport represents the outcome of verifying the whole result ledger
+type IntegrityReport struct {
+	Valid      bool              `json:"valid"`
+	Entries    int               `json:"entries"`
+	Results    int               `json:"results"`
+	HeadHash   string            `json:"head_hash"` // record it elsewhere to detect truncation later
+	KeyID      string            `json:"key_id"`
+	Issues     []*IntegrityIssue `json:"issues"`
+	VerifiedAt time.Time         `json:"verified_at"`
+}
+
+// ResultIntegrity represents the outcome of verifying one result
+type ResultIntegrity struct {
+	ResultID  int                  `json:"result_id"`
+	Valid     bool                 `json:"valid"`
+	Payload   string               `json:"payload"`   // canonical JSON that the signature covers
+	Signature *string              `json:"signature"` // base64 Ed25519 signature
+	PublicKey string               `json:"public_key"`
+	KeyID     string               `json:"key_id"`
+	History   []*ResultLedgerEntry `json:"history"`
+	Issues    []*IntegrityIssue    `json:"issues"`
+}
+
+// SigningKeyInfo represents the public half of the result signing key
+type SigningKeyInfo struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	KeyID     string `json:"key_id"`
+}
+
+// IntegrityRepository defines the interface for result ledger data operations
+type IntegrityRepository interface {
+	AppendEntry(entry *ResultLedgerEntry) error
+	GetHead() (*ResultLedgerEntry, error)
+	GetEntriesForResult(resultID int) ([]*ResultLedgerEntry, error)
+	StreamEntries(fn func(*ResultLedgerEntry) error) error
+	GetSignedResult(resultID int) (*SignedResult, error)
+	StreamSignedResults(fn func(*SignedResult) error) error
+	GetLatestEntriesByTest(testID int) ([]*ResultLedgerEntry, error)
+	UpdateSignature(resultID int, signature string) error
+}
+
+// IntegrityService defines the interface for tamper-evident result business logic
+type IntegrityService interface {
+	RecordResult(resultID int, action LedgerAction, reason string) error
+	RecordTestDeletion(testID int) error
+	SealUnrecorded() (int, error)
+	VerifyAll() (*IntegrityReport, error)
+	VerifyResult(resultID int) (*ResultIntegrity, error)
+	GetSigningKey() *SigningKeyInfo
+}
+
+// NewResultPayload returns the canonical JSON of a result
+func NewResultPayload(result *TestResult) string {
+	payload, _ := json.Marshal(&ResultPayload{
+		ID:                result.ID,
+		SessionID:         result.SessionID,
+		TestID:            result.TestID,
+		UserID:            result.UserID,
+		TotalQuestions:    result.TotalQuestions,
+		AnsweredQuestions: result.AnsweredQuestions,
+		CorrectAnswers:    result.CorrectAnswers,
+		TotalMarks:        result.TotalMarks,
+		MarksObtained:     result.MarksObtained,
+		Percentage:        result.Percentage,
+		Grade:             result.Grade,
+		GradePoints:       result.GradePoints,
+		IsPassed:          result.IsPassed,
+		TimeTaken:         result.TimeTaken,
+		HasOverride:       result.HasOverride,
+		Theta:             result.Theta,
+		ThetaSE:           result.ThetaSE,
+		CompletedAt:       result.CompletedAt.UTC().Format(time.RFC3339Nano),
+	})
+	return string(payload)
+}
+
+// ScanResultLedgerEntry scans database row into ResultLedgerEntry struct
+func ScanResultLedgerEntry(row interface {
+	Scan(dest ...interface{}) error
+}) (*ResultLedgerEntry, error) {
+	entry := &ResultLedgerEntry{}
+	err := row.Scan(
+		&entry.ID,
+		&entry.Sequence,
+		&entry.ResultID,
+		&entry.TestID,
+		&entry.Action,
+		&entry.Reason,
+		&entry.Payload,
+		&entry.PayloadHash,
+		&entry.PrevHash,
+		&entry.EntryHash,
+		&entry.Signature,
+		&entry.KeyID,
+		&entry.RecordedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ScanSignedResult scans database row into SignedResult struct
+func ScanSignedResult(row interface {
+	Scan(dest ...interface{}) error
+}) (*SignedResult, error) {
+	result := &TestResult{}
+	signed := &SignedResult{Result: result}
+	var signature sql.NullString
+	err := row.Scan(
+		&result.ID,
+		&result.SessionID,
+		&result.TestID,
+		&result.UserID,
+		&result.TotalQuestions,
+		&result.AnsweredQuestions,
+		&result.CorrectAnswers,
+		&result.TotalMarks,
+		&result.MarksObtained,
+		&result.Percentage,
+		&result.Grade,
+		&result.GradePoints,
+		&result.IsPassed,
+		&result.TimeTaken,
+		&result.HasOverride,
+		&result.Theta,
+		&result.ThetaSE,
+		&result.CompletedAt,
+		&signature,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if signature.Valid {
+		signed.Signature = &signature.String
+	}
+	return signed, nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IRTModel represents an item response theory model
+type IRTModel string
+
+const (
+	IRTModelRasch IRTModel = "rasch" // one-parameter logistic model, discrimination fixed at 1
+	IRTModel2PL   IRTModel = "2pl"   // two-parameter logistic model
+)
+
+// DefaultMinCalibrationResponses is the number of graded results a test needs before
+// its questions are calibrated
+const DefaultMinCalibrationResponses = 30
+
+// ItemParameters represents the calibrated IRT parameters of a question
+type ItemParameters struct {
+	Model          IRTModel  `json:"model" db:"irt_model"`
+	Difficulty     float64   `json:"difficulty" db:"irt_difficulty"`
+	Discrimination float64   `json:"discrimination" db:"irt_discrimination"`
+	Responses      int       `json:"responses" db:"irt_responses"`
+	CalibratedAt   time.Time `json:"calibrated_at" db:"irt_calibrated_at"`
+}
+
+// CalibrationReport represents the outcome of calibrating the questions of one test
+type CalibrationReport struct {
+	TestID     int      `json:"test_id"`
+	Model      IRTModel `json:"model"`
+	Candidates int      `json:"candidates"`
+	Calibrated int      `json:"calibrated"` // questions given new parameters
+	Skipped    int      `json:"skipped"`    // questions everyone answered correctly or incorrectly
+	Scored     int      `json:"scored"`     // results given a new theta
+	Iterations int      `json:"iterations"`
+	Converged  bool     `json:"converged"`
+}
+
+// CalibrationService defines the interface for IRT calibration business logic
+type CalibrationService interface {
+	CalibrateTest(testID int, model IRTModel, minResponses int) (*CalibrationReport, error)
+	CalibrateFromPilot(testID, pilotTestID int, model IRTModel, minResponses int) (*CalibrationReport, error)
+	CalibrateAll(model IRTModel, minResponses int) ([]*CalibrationReport, error)
+}
+
+// IsValid checks if the IRT model is valid
+func (m IRTModel) IsValid() bool {
+	return m == IRTModelRasch || m == IRTModel2PL
+}
+
+// scanItemParameters builds the IRT parameters of a question from nullable columns.
+// Returns nil when the question has not been calibrated.
+func scanItemParameters(model sql.NullString, difficulty, discrimination sql.NullFloat64, responses sql.NullInt64, calibratedAt sql.NullTime) *ItemParameters {
+	if !model.Valid || !difficulty.Valid || !discrimination.Valid {
+		return nil
+	}
+
+	params := &ItemParameters{
+		Model:          IRTModel(model.String),
+		Difficulty:     difficulty.Float64,
+		Discrimination: discrimination.Float64,
+		Responses:      int(responses.Int64),
+	}
+	if calibratedAt.Valid {
+		params.CalibratedAt = calibratedAt.Time
+	}
+	return params
+}
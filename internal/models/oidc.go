@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OIDCLoginState represents a single sign-on request waiting for the identity provider's
+// callback. Only the SHA-256 hash of the state is kept; the nonce and PKCE code verifier
+// are needed to redeem the authorization code.
+type OIDCLoginState struct {
+	StateHash    string    `json:"-" db:"state_hash"`
+	Nonce        string    `json:"-" db:"nonce"`
+	CodeVerifier string    `json:"-" db:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// OIDCRepository defines the interface for single sign-on data operations
+type OIDCRepository interface {
+	CreateLoginState(state *OIDCLoginState) error
+	ConsumeLoginState(stateHash string) (*OIDCLoginState, error)
+	DeleteExpiredLoginStates(before time.Time) error
+}
+
+// OIDCService defines the interface for OpenID Connect single sign-on business logic
+type OIDCService interface {
+	StartLogin() (string, error)
+	CompleteLogin(code, state string) (*User, []string, error)
+}
+
+// IsExpired checks if the single sign-on request has expired
+func (s *OIDCLoginState) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// ScanOIDCLoginState scans database row into OIDCLoginState struct
+func ScanOIDCLoginState(row interface {
+	Scan(dest ...interface{}) error
+}) (*OIDCLoginState, error) {
+	state := &OIDCLoginState{}
+	err := row.Scan(
+		&state.StateHash,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+		&state.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return state, nil
+}
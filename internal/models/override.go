@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OverrideScope represents what a manual score override applies to
+type OverrideScope string
+
+const (
+	OverrideScopeAnswer   OverrideScope = "answer"
+	OverrideScopeResult   OverrideScope = "result"
+	OverrideScopeQuestion OverrideScope = "question" // voids the question for everyone
+)
+
+// OverrideReasonCode represents why a teacher overrode a score
+type OverrideReasonCode string
+
+const (
+	OverrideReasonDisputedQuestion OverrideReasonCode = "disputed_question"
+	OverrideReasonMarkingError     OverrideReasonCode = "marking_error"
+	OverrideReasonFaultyQuestion   OverrideReasonCode = "faulty_question"
+	OverrideReasonTechnicalIssue   OverrideReasonCode = "technical_issue"
+	OverrideReasonAccommodation    OverrideReasonCode = "accommodation"
+	OverrideReasonOther            OverrideReasonCode = "other"
+)
+
+// ScoreOverride represents a manual change to the marks of an answer, a result or a question
+type ScoreOverride struct {
+	ID            int                `json:"id" db:"id"`
+	Scope         OverrideScope      `json:"scope" db:"scope"`
+	TestID        int                `json:"test_id" db:"test_id"`
+	QuestionID    *int               `json:"question_id,omitempty" db:"question_id"`
+	AnswerID      *int               `json:"answer_id,omitempty" db:"answer_id"`
+	ResultID      *int               `json:"result_id,omitempty" db:"result_id"`
+	SessionID     *int               `json:"session_id,omitempty" db:"session_id"`
+	OriginalValue *int               `json:"original_value" db:"original_value"`
+	NewValue      int                `json:"new_value" db:"new_value"`
+	ReasonCode    OverrideReasonCode `json:"reason_code" db:"reason_code"`
+	Reason        string             `json:"reason" db:"reason"`
+	CreatedBy     int                `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+	RevokedBy     *int               `json:"revoked_by,omitempty" db:"revoked_by"`
+	RevokedAt     *time.Time         `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// ScoreOverrideRepository defines the interface for score override data operations
+type ScoreOverrideRepository interface {
+	Create(override *ScoreOverride) error
+	GetByID(id int) (*ScoreOverride, error)
+	GetByTest(testID int, includeRevoked bool) ([]*ScoreOverride, error)
+	GetActiveForSession(testID, sessionID int) ([]*ScoreOverride, error)
+	Revoke(id, revokedBy int) error
+}
+
+// ScoreOverrideService defines the interface for score override business logic
+type ScoreOverrideService interface {
+	OverrideAnswer(actorID, answerID, marks int, reasonCode OverrideReasonCode, reason string) (*ScoreOverride, *RegradeReport, error)
+	OverrideResult(actorID, resultID, marks int, reasonCode OverrideReasonCode, reason string) (*ScoreOverride, *RegradeReport, error)
+	VoidQuestion(actorID, questionID int, reasonCode OverrideReasonCode, reason string) (*ScoreOverride, *RegradeReport, error)
+	RevokeOverride(actorID, overrideID int) (*ScoreOverride, *RegradeReport, error)
+	GetTestOverrides(testID int, includeRevoked bool) ([]*ScoreOverride, error)
+	GetOverride(overrideID int) (*ScoreOverride, error)
+	GetTargetTestID(scope OverrideScope, targetID int) (int, error)
+}
+
+// IsValidOverrideReasonCode checks if the reason code is valid
+func IsValidOverrideReasonCode(code OverrideReasonCode) bool {
+	switch code {
+	case OverrideReasonDisputedQuestion, OverrideReasonMarkingError, OverrideReasonFaultyQuestion,
+		OverrideReasonTechnicalIssue, OverrideReasonAccommodation, OverrideReasonOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsActive checks if the override has not been revoked
+func (o *ScoreOverride) IsActive() bool {
+	return o.RevokedAt == nil
+}
+
+// ScanScoreOverride scans database row into ScoreOverride struct
+func ScanScoreOverride(row interface {
+	Scan(dest ...interface{}) error
+}) (*ScoreOverride, error) {
+	override := &ScoreOverride{}
+	err := row.Scan(
+		&override.ID,
+		&override.Scope,
+		&override.TestID,
+		&override.QuestionID,
+		&override.AnswerID,
+		&override.ResultID,
+		&override.SessionID,
+		&override.OriginalValue,
+		&override.NewValue,
+		&override.ReasonCode,
+		&override.Reason,
+		&override.CreatedBy,
+		&override.CreatedAt,
+		&override.RevokedBy,
+		&override.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return override, nil
+}
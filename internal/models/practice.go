@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PracticeResult represents one attempt at a practice test. Practice attempts are
+// stored apart from graded test results and never count towards test statistics.
+type PracticeResult struct {
+	ID                int       `json:"id" db:"id"`
+	SessionID         int       `json:"session_id" db:"session_id"`
+	TestID            int       `json:"test_id" db:"test_id"`
+	UserID            int       `json:"user_id" db:"user_id"`
+	AttemptNumber     int       `json:"attempt_number" db:"attempt_number"`
+	TotalQuestions    int       `json:"total_questions" db:"total_questions"`
+	AnsweredQuestions int       `json:"answered_questions" db:"answered_questions"`
+	CorrectAnswers    int       `json:"correct_answers" db:"correct_answers"`
+	TotalMarks        int       `json:"total_marks" db:"total_marks"`
+	MarksObtained     int       `json:"marks_obtained" db:"marks_obtained"`
+	Percentage        float64   `json:"percentage" db:"percentage"`
+	TimeTaken         *int      `json:"time_taken" db:"time_taken"` // in seconds
+	CompletedAt       time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// PracticeResultRepository defines the interface for practice result data operations
+type PracticeResultRepository interface {
+	Create(result *PracticeResult) error
+	GetByUser(userID, testID int, limit, offset int) ([]*PracticeResult, error) // testID 0 for all tests
+	GetByTest(testID int, limit, offset int) ([]*PracticeResult, error)
+	CountByUserAndTest(userID, testID int) (int, error)
+}
+
+// PracticeService defines the interface for practice mode business logic
+type PracticeService interface {
+	RecordAttempt(sessionID int) (*PracticeResult, error)
+	GetUserAttempts(userID, testID int, limit, offset int) ([]*PracticeResult, error)
+	GetTestAttempts(testID int, limit, offset int) ([]*PracticeResult, error)
+}
+
+// ScanPracticeResult scans database row into PracticeResult struct
+func ScanPracticeResult(row interface {
+	Scan(dest ...interface{}) error
+}) (*PracticeResult, error) {
+	result := &PracticeResult{}
+	err := row.Scan(
+		&result.ID,
+		&result.SessionID,
+		&result.TestID,
+		&result.UserID,
+		&result.AttemptNumber,
+		&result.TotalQuestions,
+		&result.AnsweredQuestions,
+		&result.CorrectAnswers,
+		&result.TotalMarks,
+		&result.MarksObtained,
+		&result.Percentage,
+		&result.TimeTaken,
+		&result.CompletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return result, nil
+}
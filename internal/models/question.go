@@ -24,12 +24,21 @@ type Question struct {
 	OrderIndex   int          `json:"order_index" db:"order_index"`
 	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
-	
+	QuestionFeedback
+	IRT *ItemParameters `json:"irt,omitempty"` // nil until calibrated
+
 	// Related data (not stored in database)
 	Options        []*QuestionOption `json:"options,omitempty"`
 	CorrectAnswers []*CorrectAnswer  `json:"correct_answers,omitempty"`
 }
 
+// QuestionFeedback represents the explanation and feedback authors write for a question
+type QuestionFeedback struct {
+	Explanation       string `json:"explanation" db:"explanation"`
+	CorrectFeedback   string `json:"correct_feedback" db:"correct_feedback"`
+	IncorrectFeedback string `json:"incorrect_feedback" db:"incorrect_feedback"`
+}
+
 // QuestionOption represents an option for multiple choice questions
 type QuestionOption struct {
 	ID         int       `json:"id" db:"id"`
@@ -37,9 +46,17 @@ type QuestionOption struct {
 	OptionText string    `json:"option_text" db:"option_text"`
 	IsCorrect  bool      `json:"is_correct" db:"is_correct"`
 	OrderIndex int       `json:"order_index" db:"order_index"`
+	Feedback   string    `json:"feedback" db:"feedback"` // shown when the option is selected
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
+// AnswerFeedback represents the feedback shown to a candidate for one answer
+type AnswerFeedback struct {
+	Explanation    string `json:"explanation,omitempty"`
+	Feedback       string `json:"feedback,omitempty"`        // correct or incorrect answer feedback
+	OptionFeedback string `json:"option_feedback,omitempty"` // feedback of the selected option
+}
+
 // CorrectAnswer represents correct answers for non-multiple choice questions
 type CorrectAnswer struct {
 	ID              int       `json:"id" db:"id"`
@@ -64,6 +81,7 @@ type QuestionRepository interface {
 	GetCorrectAnswersByQuestionID(questionID int) ([]*CorrectAnswer, error)
 	UpdateCorrectAnswer(answer *CorrectAnswer) error
 	DeleteCorrectAnswer(id int) error
+	UpdateIRTParameters(questionID int, params *ItemParameters) error
 }
 
 // QuestionService defines the interface for question business logic
@@ -72,9 +90,10 @@ type QuestionService interface {
 	GetQuestion(questionID int) (*Question, error)
 	GetTestQuestions(testID int) ([]*Question, error)
 	UpdateQuestion(questionID int, questionText string, marks, orderIndex int) (*Question, error)
+	UpdateFeedback(questionID int, feedback QuestionFeedback) (*Question, error)
 	DeleteQuestion(questionID int) error
-	AddOption(questionID int, optionText string, isCorrect bool, orderIndex int) (*QuestionOption, error)
-	UpdateOption(optionID int, optionText string, isCorrect bool, orderIndex int) (*QuestionOption, error)
+	AddOption(questionID int, optionText string, isCorrect bool, orderIndex int, feedback string) (*QuestionOption, error)
+	UpdateOption(optionID int, optionText string, isCorrect bool, orderIndex int, feedback string) (*QuestionOption, error)
 	DeleteOption(optionID int) error
 	AddCorrectAnswer(questionID int, answerText string, isCaseSensitive bool) (*CorrectAnswer, error)
 	UpdateCorrectAnswer(answerID int, answerText string, isCaseSensitive bool) (*CorrectAnswer, error)
@@ -91,11 +110,61 @@ func (qt QuestionType) IsValid() bool {
 	}
 }
 
+// FeedbackFor builds the feedback for an answer to the question. The selected
+// option may be nil for unanswered and short answer questions.
+func (q *Question) FeedbackFor(isCorrect bool, selectedOption *QuestionOption) *AnswerFeedback {
+	feedback := &AnswerFeedback{
+		Explanation: q.Explanation,
+		Feedback:    q.IncorrectFeedback,
+	}
+	if isCorrect {
+		feedback.Feedback = q.CorrectFeedback
+	}
+	if selectedOption != nil {
+		feedback.OptionFeedback = selectedOption.Feedback
+	}
+
+	if feedback.Explanation == "" && feedback.Feedback == "" && feedback.OptionFeedback == "" {
+		return nil
+	}
+	return feedback
+}
+
+// CandidateView returns a copy of the question as shown to candidates, without its
+// answer key, feedback or IRT parameters
+func (q *Question) CandidateView() *Question {
+	view := &Question{
+		ID:           q.ID,
+		TestID:       q.TestID,
+		QuestionText: q.QuestionText,
+		QuestionType: q.QuestionType,
+		Marks:        q.Marks,
+		OrderIndex:   q.OrderIndex,
+		CreatedAt:    q.CreatedAt,
+		UpdatedAt:    q.UpdatedAt,
+	}
+	for _, option := range q.Options {
+		view.Options = append(view.Options, &QuestionOption{
+			ID:         option.ID,
+			QuestionID: option.QuestionID,
+			OptionText: option.OptionText,
+			OrderIndex: option.OrderIndex,
+			CreatedAt:  option.CreatedAt,
+		})
+	}
+	return view
+}
+
 // ScanQuestion scans database row into Question struct
 func ScanQuestion(row interface {
 	Scan(dest ...interface{}) error
 }) (*Question, error) {
 	question := &Question{}
+	var explanation, correctFeedback, incorrectFeedback sql.NullString
+	var irtModel sql.NullString
+	var irtDifficulty, irtDiscrimination sql.NullFloat64
+	var irtResponses sql.NullInt64
+	var irtCalibratedAt sql.NullTime
 	err := row.Scan(
 		&question.ID,
 		&question.TestID,
@@ -105,6 +174,14 @@ func ScanQuestion(row interface {
 		&question.OrderIndex,
 		&question.CreatedAt,
 		&question.UpdatedAt,
+		&explanation,
+		&correctFeedback,
+		&incorrectFeedback,
+		&irtModel,
+		&irtDifficulty,
+		&irtDiscrimination,
+		&irtResponses,
+		&irtCalibratedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -112,6 +189,10 @@ func ScanQuestion(row interface {
 		}
 		return nil, err
 	}
+	question.Explanation = explanation.String
+	question.CorrectFeedback = correctFeedback.String
+	question.IncorrectFeedback = incorrectFeedback.String
+	question.IRT = scanItemParameters(irtModel, irtDifficulty, irtDiscrimination, irtResponses, irtCalibratedAt)
 	return question, nil
 }
 
@@ -120,12 +201,14 @@ func ScanQuestionOption(row interface {
 	Scan(dest ...interface{}) error
 }) (*QuestionOption, error) {
 	option := &QuestionOption{}
+	var feedback sql.NullString
 	err := row.Scan(
 		&option.ID,
 		&option.QuestionID,
 		&option.OptionText,
 		&option.IsCorrect,
 		&option.OrderIndex,
+		&feedback,
 		&option.CreatedAt,
 	)
 	if err != nil {
@@ -134,6 +217,7 @@ func ScanQuestionOption(row interface {
 		}
 		return nil, err
 	}
+	option.Feedback = feedback.String
 	return option, nil
 }
 
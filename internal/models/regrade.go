@@ -0,0 +1,38 @@
+package models
+
+// RegradeReport summarises a regrade of stored answers after answer-key corrections
+type RegradeReport struct {
+	TestID            int              `json:"test_id"`
+	QuestionID        *int             `json:"question_id,omitempty"` // set for single-question regrades
+	DryRun            bool             `json:"dry_run"`
+	AnswersRescored   int              `json:"answers_rescored"`
+	AnswersChanged    int              `json:"answers_changed"`
+	ResultsRecomputed int              `json:"results_recomputed"`
+	PassStatusChanges int              `json:"pass_status_changes"`
+	Changes           []*RegradeChange `json:"changes"`
+}
+
+// RegradeChange describes how a candidate's result changed in a regrade
+type RegradeChange struct {
+	ResultID          int     `json:"result_id"`
+	SessionID         int     `json:"session_id"`
+	UserID            int     `json:"user_id"`
+	Username          string  `json:"username"`
+	OldMarksObtained  int     `json:"old_marks_obtained"`
+	NewMarksObtained  int     `json:"new_marks_obtained"`
+	OldPercentage     float64 `json:"old_percentage"`
+	NewPercentage     float64 `json:"new_percentage"`
+	OldGrade          *string `json:"old_grade"`
+	NewGrade          *string `json:"new_grade"`
+	OldIsPassed       bool    `json:"old_is_passed"`
+	NewIsPassed       bool    `json:"new_is_passed"`
+	PassStatusChanged bool    `json:"pass_status_changed"`
+	ChangedQuestions  []int   `json:"changed_questions"`
+}
+
+// RegradeService defines the interface for rescoring stored answers
+type RegradeService interface {
+	RegradeTest(testID int, dryRun bool) (*RegradeReport, error)
+	RegradeQuestion(questionID int, dryRun bool) (*RegradeReport, error)
+	RegradeSession(sessionID int, dryRun bool) (*RegradeReport, error)
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScoreReleasePolicy represents when candidates can see their results
+type ScoreReleasePolicy string
+
+const (
+	ScoreReleaseImmediate   ScoreReleasePolicy = "immediate"
+	ScoreReleaseAfterWindow ScoreReleasePolicy = "after_window"
+	ScoreReleaseManual      ScoreReleasePolicy = "manual"
+)
+
+// TestReleaseSettings represents what candidates see of their results, and when
+type TestReleaseSettings struct {
+	TestID             int                `json:"test_id" db:"test_id"`
+	ScoreRelease       ScoreReleasePolicy `json:"score_release" db:"score_release"`
+	ReleasedAt         *time.Time         `json:"released_at" db:"released_at"` // set by a manual release
+	ShowCorrectAnswers bool               `json:"show_correct_answers" db:"show_correct_answers"`
+	ShowExplanations   bool               `json:"show_explanations" db:"show_explanations"`
+	CreatedAt          time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// ResultReview represents a candidate's answers shown next to the correct ones
+type ResultReview struct {
+	Result             *TestResult   `json:"result"`
+	ShowCorrectAnswers bool          `json:"show_correct_answers"`
+	ShowExplanations   bool          `json:"show_explanations"`
+	Items              []*ReviewItem `json:"items"`
+}
+
+// ReviewItem represents one question in a result review
+type ReviewItem struct {
+	QuestionID       int             `json:"question_id"`
+	QuestionText     string          `json:"question_text"`
+	QuestionType     QuestionType    `json:"question_type"`
+	Marks            int             `json:"marks"`
+	Options          []*ReviewOption `json:"options,omitempty"`
+	AnswerText       *string         `json:"answer_text"`
+	SelectedOptionID *int            `json:"selected_option_id"`
+	IsCorrect        *bool           `json:"is_correct"`
+	MarksAwarded     int             `json:"marks_awarded"`
+	CorrectAnswers   []string        `json:"correct_answers,omitempty"` // accepted short answers
+	Feedback         *AnswerFeedback `json:"feedback,omitempty"`        // only set when explanations are shown
+}
+
+// ReviewOption represents an answer option in a result review
+type ReviewOption struct {
+	ID         int    `json:"id"`
+	OptionText string `json:"option_text"`
+	IsCorrect  *bool  `json:"is_correct,omitempty"` // only set when correct answers are shown
+}
+
+// ReleaseRepository defines the interface for result release settings data operations
+type ReleaseRepository interface {
+	GetSettings(testID int) (*TestReleaseSettings, error)
+	UpsertSettings(settings *TestReleaseSettings) error
+	DeleteSettings(testID int) error
+}
+
+// ReleaseService defines the interface for result release business logic
+type ReleaseService interface {
+	GetSettings(testID int) (*TestReleaseSettings, error)
+	UpdateSettings(testID int, scoreRelease ScoreReleasePolicy, showCorrectAnswers, showExplanations bool) (*TestReleaseSettings, error)
+	DeleteSettings(testID int) error
+	ReleaseResults(testID int) (*TestReleaseSettings, error)
+	WithdrawResults(testID int) (*TestReleaseSettings, error)
+	WithholdUnreleased(results ...*TestResult) error
+	AnswerScoresVisible(session *TestSession) (bool, error)
+	GetReview(resultID int, fullAccess bool) (*ResultReview, error)
+}
+
+// DefaultReleaseSettings returns the settings of tests without release settings,
+// which show scores immediately and keep the answer key hidden
+func DefaultReleaseSettings(testID int) *TestReleaseSettings {
+	return &TestReleaseSettings{
+		TestID:       testID,
+		ScoreRelease: ScoreReleaseImmediate,
+	}
+}
+
+// IsValidScoreReleasePolicy checks if the score release policy is valid
+func IsValidScoreReleasePolicy(policy ScoreReleasePolicy) bool {
+	switch policy {
+	case ScoreReleaseImmediate, ScoreReleaseAfterWindow, ScoreReleaseManual:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsReleased checks if results of the test are visible to candidates at the given time.
+// A manual release always counts; tests without an end time never close on their own.
+func (s *TestReleaseSettings) IsReleased(test *Test, now time.Time) bool {
+	if s.ReleasedAt != nil && !now.Before(*s.ReleasedAt) {
+		return true
+	}
+
+	switch s.ScoreRelease {
+	case ScoreReleaseImmediate:
+		return true
+	case ScoreReleaseAfterWindow:
+		return test.EndTime != nil && now.After(*test.EndTime)
+	default:
+		return false
+	}
+}
+
+// Withhold hides the score of a result that has not been released to the candidate
+func (r *TestResult) Withhold() {
+	r.CorrectAnswers = 0
+	r.MarksObtained = 0
+	r.Percentage = 0
+	r.Grade = nil
+	r.GradePoints = nil
+	r.IsPassed = false
+	r.HasOverride = false
+	r.Theta = nil
+	r.ThetaSE = nil
+	r.Overrides = nil
+	r.ScoreWithheld = true
+}
+
+// Withhold hides whether an answer is correct and the marks it earned from a candidate
+// whose score has not been released
+func (a *UserAnswer) Withhold() {
+	a.IsCorrect = nil
+	a.MarksAwarded = 0
+	if a.Adaptive != nil {
+		a.Adaptive.Withhold()
+	}
+}
+
+// Withhold hides whether each answered step of an adaptive session was correct and the
+// ability estimates that follow from it
+func (s *AdaptiveState) Withhold() {
+	for _, step := range s.Steps {
+		step.IsCorrect = nil
+		step.Theta = nil
+		step.ThetaSE = nil
+	}
+	s.Theta = 0
+	s.ThetaSE = 0
+	s.ScoreWithheld = true
+}
+
+// ScanTestReleaseSettings scans database row into TestReleaseSettings struct
+func ScanTestReleaseSettings(row interface {
+	Scan(dest ...interface{}) error
+}) (*TestReleaseSettings, error) {
+	settings := &TestReleaseSettings{}
+	err := row.Scan(
+		&settings.TestID,
+		&settings.ScoreRelease,
+		&settings.ReleasedAt,
+		&settings.ShowCorrectAnswers,
+		&settings.ShowExplanations,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
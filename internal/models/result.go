@@ -19,14 +19,22 @@ type TestResult struct {
 	MarksObtained     int       `json:"marks_obtained" db:"marks_obtained"`
 	Percentage        float64   `json:"percentage" db:"percentage"`
 	Grade             *string   `json:"grade" db:"grade"`
+	GradePoints       *float64  `json:"grade_points" db:"grade_points"`
 	IsPassed          bool      `json:"is_passed" db:"is_passed"`
 	TimeTaken         *int      `json:"time_taken" db:"time_taken"` // in seconds
+	HasOverride       bool      `json:"has_override" db:"has_override"`
+	Theta             *float64  `json:"theta" db:"theta"`       // IRT ability, set once every question is calibrated
+	ThetaSE           *float64  `json:"theta_se" db:"theta_se"` // standard error of theta
 	CompletedAt       time.Time `json:"completed_at" db:"completed_at"`
 
 	// Related data (not stored in database)
-	Test    *Test        `json:"test,omitempty"`
-	User    *User        `json:"user,omitempty"`
-	Session *TestSession `json:"session,omitempty"`
+	Test      *Test            `json:"test,omitempty"`
+	User      *User            `json:"user,omitempty"`
+	Session   *TestSession     `json:"session,omitempty"`
+	Overrides []*ScoreOverride `json:"overrides,omitempty"`
+
+	// Set when the score is hidden from the candidate by the test's release settings
+	ScoreWithheld bool `json:"score_withheld,omitempty"`
 }
 
 // TestResultRepository defines the interface for test result data operations
@@ -38,6 +46,8 @@ type TestResultRepository interface {
 	GetByUser(userID int, limit, offset int) ([]*TestResult, error)
 	GetByTest(testID int, limit, offset int) ([]*TestResult, error)
 	Update(result *TestResult) error
+	UpdateGrade(id int, grade *string, gradePoints *float64) error
+	UpdateAbility(id int, theta, thetaSE *float64) error
 	Delete(id int) error
 	GetTestStatistics(testID int) (*TestStatistics, error)
 }
@@ -65,24 +75,9 @@ type TestStatistics struct {
 	AverageTimeTaken  int     `json:"average_time_taken"` // in seconds
 }
 
-// CalculateGrade calculates the grade based on percentage
+// CalculateGrade calculates the grade based on percentage using the default grading scale
 func (r *TestResult) CalculateGrade() string {
-	switch {
-	case r.Percentage >= 90:
-		return "A+"
-	case r.Percentage >= 80:
-		return "A"
-	case r.Percentage >= 70:
-		return "B+"
-	case r.Percentage >= 60:
-		return "B"
-	case r.Percentage >= 50:
-		return "C"
-	case r.Percentage >= 40:
-		return "D"
-	default:
-		return "F"
-	}
+	return DefaultGradingScale().BandFor(r.Percentage).Label
 }
 
 // GetTimeTakenFormatted returns formatted time taken (e.g., "1h 30m 45s")
@@ -122,8 +117,12 @@ func ScanTestResult(row interface {
 		&result.MarksObtained,
 		&result.Percentage,
 		&result.Grade,
+		&result.GradePoints,
 		&result.IsPassed,
 		&result.TimeTaken,
+		&result.HasOverride,
+		&result.Theta,
+		&result.ThetaSE,
 		&result.CompletedAt,
 	)
 	if err != nil {
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Permission represents something a role allows its users to do
+type Permission string
+
+const (
+	// PermTestsCreate allows creating tests and editing the tests a user owns or co-owns
+	PermTestsCreate Permission = "tests.create"
+	// PermTestsManageAll allows editing and deleting any test
+	PermTestsManageAll Permission = "tests.manage_all"
+	// PermResultsView allows viewing the results of the tests a user owns or co-owns
+	PermResultsView Permission = "results.view"
+	// PermResultsViewAll allows viewing the results of any test
+	PermResultsViewAll Permission = "results.view_all"
+	// PermResultsGrade allows grading, overriding and releasing the results of the tests a
+	// user owns or co-owns
+	PermResultsGrade Permission = "results.grade"
+	// PermResultsGradeAll allows grading the results of any test
+	PermResultsGradeAll Permission = "results.grade_all"
+	// PermSessionsMonitor allows following candidates' sessions and access codes
+	PermSessionsMonitor Permission = "sessions.monitor"
+	// PermGroupsManage allows creating classes and groups and managing the ones a user owns
+	PermGroupsManage Permission = "groups.manage"
+	// PermGroupsManageAll allows managing any class or group
+	PermGroupsManageAll Permission = "groups.manage_all"
+	// PermCoursesManage allows creating courses and managing the ones a user owns
+	PermCoursesManage Permission = "courses.manage"
+	// PermCoursesManageAll allows managing any course and its gradebook
+	PermCoursesManageAll Permission = "courses.manage_all"
+	// PermGradingScalesManage allows managing global grading scales and the default scale
+	PermGradingScalesManage Permission = "grading_scales.manage"
+	// PermIntegrityAudit allows verifying the whole result ledger
+	PermIntegrityAudit Permission = "integrity.audit"
+	// PermRolesManage allows managing roles and assigning them to users
+	PermRolesManage Permission = "roles.manage"
+)
+
+// AllPermissions lists every permission a role can be built from
+var AllPermissions = []Permission{
+	PermTestsCreate,
+	PermTestsManageAll,
+	PermResultsView,
+	PermResultsViewAll,
+	PermResultsGrade,
+	PermResultsGradeAll,
+	PermSessionsMonitor,
+	PermGroupsManage,
+	PermGroupsManageAll,
+	PermCoursesManage,
+	PermCoursesManageAll,
+	PermGradingScalesManage,
+	PermIntegrityAudit,
+	PermRolesManage,
+}
+
+// Role represents a named set of permissions. Built-in roles match the student, teacher
+// and admin account roles; institutions define further roles such as proctor or auditor
+// and assign them to users on top of their account role.
+type Role struct {
+	ID          int    `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	IsSystem    bool   `json:"is_system" db:"is_system"`
+	// RequireTwoFactor makes two-factor authentication mandatory for the role's users
+	RequireTwoFactor bool         `json:"require_two_factor" db:"require_two_factor"`
+	Permissions      []Permission `json:"permissions"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// UserRoles represents the roles assigned to a user and the permissions they add up to
+type UserRoles struct {
+	UserID      int          `json:"user_id"`
+	AccountRole UserRole     `json:"account_role"`
+	Roles       []*Role      `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// RoleRepository defines the interface for role and role assignment data operations
+type RoleRepository interface {
+	Create(role *Role) error
+	GetByID(id int) (*Role, error)
+	GetByName(name string) (*Role, error)
+	List() ([]*Role, error)
+	Update(role *Role) error
+	Delete(id int) error
+	GetUserRoles(userID int) ([]*Role, error)
+	ReplaceUserRoles(userID int, roleIDs []int) error
+	GetUserPermissions(userID int) ([]Permission, error)
+	SetTwoFactorRequired(roleID int, required bool) error
+	IsTwoFactorRequired(userID int) (bool, error)
+}
+
+// RoleService defines the interface for role and role assignment business logic
+type RoleService interface {
+	CreateRole(name, description string, permissions []Permission) (*Role, error)
+	GetRole(roleID int) (*Role, error)
+	ListRoles() ([]*Role, error)
+	UpdateRole(roleID int, name, description string, permissions []Permission) (*Role, error)
+	DeleteRole(roleID int) error
+	GetUserRoles(userID int) (*UserRoles, error)
+	SetUserRoles(userID int, roleIDs []int) (*UserRoles, error)
+	GetUserPermissions(userID int) ([]Permission, error)
+	SetTwoFactorRequired(roleID int, required bool) (*Role, error)
+}
+
+// IsValid checks if the permission is known
+func (p Permission) IsValid() bool {
+	for _, permission := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission checks if a list of permissions contains the given permission
+func HasPermission(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanRole scans database row into Role struct
+func ScanRole(row interface {
+	Scan(dest ...interface{}) error
+}) (*Role, error) {
+	role := &Role{}
+	var description sql.NullString
+	err := row.Scan(
+		&role.ID,
+		&role.Name,
+		&description,
+		&role.IsSystem,
+		&role.RequireTwoFactor,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	role.Description = description.String
+	return role, nil
+}
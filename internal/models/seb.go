@@ -0,0 +1,120 @@
+package models
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Safe Exam Browser request headers
+const (
+	SEBConfigKeyHashHeader = "X-SafeExamBrowser-ConfigKeyHash"
+	SEBRequestHashHeader   = "X-SafeExamBrowser-RequestHash"
+)
+
+// TestSEBSettings represents the Safe Exam Browser requirements of a test
+type TestSEBSettings struct {
+	TestID           int       `json:"test_id" db:"test_id"`
+	Enabled          bool      `json:"enabled" db:"enabled"`
+	ConfigKeys       []string  `json:"config_keys" db:"config_keys"`             // stored comma-separated
+	BrowserExamKeys  []string  `json:"browser_exam_keys" db:"browser_exam_keys"` // stored comma-separated
+	QuitPasswordHash *string   `json:"-" db:"quit_password_hash"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+
+	// Derived data (not stored in database)
+	HasQuitPassword bool `json:"has_quit_password"`
+}
+
+// SEBRepository defines the interface for Safe Exam Browser settings data operations
+type SEBRepository interface {
+	GetSettings(testID int) (*TestSEBSettings, error)
+	UpsertSettings(settings *TestSEBSettings) error
+	DeleteSettings(testID int) error
+}
+
+// SEBService defines the interface for Safe Exam Browser business logic
+type SEBService interface {
+	GetSettings(testID int) (*TestSEBSettings, error)
+	UpdateSettings(testID int, enabled bool, configKeys, browserExamKeys []string, quitPassword *string) (*TestSEBSettings, error)
+	DeleteSettings(testID int) error
+	ValidateRequest(testID int, requestURL, configKeyHash, requestHash string) error
+	GenerateConfig(testID int) ([]byte, error)
+}
+
+// SEBKeyHash computes the hash SEB sends for a request URL and key
+func SEBKeyHash(requestURL, key string) string {
+	sum := sha256.Sum256([]byte(requestURL + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchesAnyKey checks if the header hash matches any allowed key for the URL
+func matchesAnyKey(requestURL, headerHash string, keys []string) bool {
+	headerHash = strings.ToLower(strings.TrimSpace(headerHash))
+	if headerHash == "" {
+		return false
+	}
+
+	for _, key := range keys {
+		expected := SEBKeyHash(requestURL, key)
+		if subtle.ConstantTimeCompare([]byte(headerHash), []byte(expected)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidConfigKeyHash checks the X-SafeExamBrowser-ConfigKeyHash header value
+func (s *TestSEBSettings) ValidConfigKeyHash(requestURL, headerHash string) bool {
+	if len(s.ConfigKeys) == 0 {
+		return true
+	}
+	return matchesAnyKey(requestURL, headerHash, s.ConfigKeys)
+}
+
+// ValidRequestHash checks the X-SafeExamBrowser-RequestHash header value
+func (s *TestSEBSettings) ValidRequestHash(requestURL, headerHash string) bool {
+	if len(s.BrowserExamKeys) == 0 {
+		return true
+	}
+	return matchesAnyKey(requestURL, headerHash, s.BrowserExamKeys)
+}
+
+// splitKeys splits a comma-separated key column
+func splitKeys(value sql.NullString) []string {
+	if !value.Valid || value.String == "" {
+		return []string{}
+	}
+	return strings.Split(value.String, ",")
+}
+
+// ScanTestSEBSettings scans database row into TestSEBSettings struct
+func ScanTestSEBSettings(row interface {
+	Scan(dest ...interface{}) error
+}) (*TestSEBSettings, error) {
+	settings := &TestSEBSettings{}
+	var configKeys, browserExamKeys sql.NullString
+	err := row.Scan(
+		&settings.TestID,
+		&settings.Enabled,
+		&configKeys,
+		&browserExamKeys,
+		&settings.QuitPasswordHash,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	settings.ConfigKeys = splitKeys(configKeys)
+	settings.BrowserExamKeys = splitKeys(browserExamKeys)
+	settings.HasQuitPassword = settings.QuitPasswordHash != nil && *settings.QuitPasswordHash != ""
+	return settings, nil
+}
@@ -30,11 +30,12 @@ type TestSession struct {
 	CurrentQuestionIndex int           `json:"current_question_index" db:"current_question_index"`
 	CreatedAt            time.Time     `json:"created_at" db:"created_at"`
 	UpdatedAt            time.Time     `json:"updated_at" db:"updated_at"`
-	
+
 	// Related data (not stored in database)
-	Test    *Test        `json:"test,omitempty"`
-	User    *User        `json:"user,omitempty"`
-	Answers []*UserAnswer `json:"answers,omitempty"`
+	Test     *Test          `json:"test,omitempty"`
+	User     *User          `json:"user,omitempty"`
+	Answers  []*UserAnswer  `json:"answers,omitempty"`
+	Adaptive *AdaptiveState `json:"adaptive,omitempty"` // adaptive tests only
 }
 
 // UserAnswer represents a user's answer to a question
@@ -47,10 +48,12 @@ type UserAnswer struct {
 	IsCorrect        *bool     `json:"is_correct" db:"is_correct"`
 	MarksAwarded     int       `json:"marks_awarded" db:"marks_awarded"`
 	AnsweredAt       time.Time `json:"answered_at" db:"answered_at"`
-	
+
 	// Related data (not stored in database)
 	Question       *Question       `json:"question,omitempty"`
 	SelectedOption *QuestionOption `json:"selected_option,omitempty"`
+	Feedback       *AnswerFeedback `json:"feedback,omitempty"` // practice tests only
+	Adaptive       *AdaptiveState  `json:"adaptive,omitempty"` // adaptive tests only
 }
 
 // TestSessionRepository defines the interface for test session data operations
@@ -72,19 +75,23 @@ type UserAnswerRepository interface {
 	GetByID(id int) (*UserAnswer, error)
 	GetBySessionAndQuestion(sessionID, questionID int) (*UserAnswer, error)
 	GetBySession(sessionID int) ([]*UserAnswer, error)
+	GetByQuestion(questionID int) ([]*UserAnswer, error)
 	Update(answer *UserAnswer) error
 	Delete(id int) error
+	DeleteBySession(sessionID int) error
 }
 
 // TestSessionService defines the interface for test session business logic
 type TestSessionService interface {
-	StartSession(userID, testID int) (*TestSession, error)
+	StartSession(userID, testID int, accessCode, clientIP string) (*TestSession, error)
 	GetSession(sessionToken string) (*TestSession, error)
+	GetSessionByID(sessionID int) (*TestSession, error)
 	SubmitAnswer(sessionToken string, questionID int, answerText *string, selectedOptionID *int) (*UserAnswer, error)
 	GetSessionAnswers(sessionToken string) ([]*UserAnswer, error)
 	SubmitSession(sessionToken string) (*TestSession, error)
 	GetUserSessions(userID int, limit, offset int) ([]*TestSession, error)
 	UpdateSessionProgress(sessionToken string, currentQuestionIndex int) error
+	GetAdaptiveState(sessionToken string) (*AdaptiveState, error)
 }
 
 // IsExpired checks if the session has expired
@@ -102,11 +109,11 @@ func (s *TestSession) GetRemainingTime() int {
 	if s.IsExpired() {
 		return 0
 	}
-	
+
 	if s.TimeRemaining != nil {
 		return *s.TimeRemaining
 	}
-	
+
 	remaining := int(time.Until(s.ExpiresAt).Seconds())
 	if remaining < 0 {
 		return 0
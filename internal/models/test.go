@@ -5,6 +5,15 @@ import (
 	"time"
 )
 
+// TestMode represents how a test is taken and scored
+type TestMode string
+
+const (
+	TestModeGraded   TestMode = "graded"
+	TestModePractice TestMode = "practice" // instant feedback, unlimited attempts, no graded results
+	TestModeAdaptive TestMode = "adaptive" // questions chosen from a calibrated pool by ability estimate
+)
+
 // Test represents a test in the system
 type Test struct {
 	ID             int       `json:"id" db:"id"`
@@ -18,6 +27,8 @@ type Test struct {
 	IsActive       bool      `json:"is_active" db:"is_active"`
 	StartTime      *time.Time `json:"start_time" db:"start_time"`
 	EndTime        *time.Time `json:"end_time" db:"end_time"`
+	GradingScaleID *int       `json:"grading_scale_id" db:"grading_scale_id"`
+	Mode           TestMode   `json:"mode" db:"mode"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 	
@@ -36,18 +47,49 @@ type TestRepository interface {
 	GetByCreator(creatorID int, limit, offset int) ([]*Test, error)
 	GetActiveTests(limit, offset int) ([]*Test, error)
 	GetAvailableTests(userID int, limit, offset int) ([]*Test, error)
+	GetCoOwners(testID int) ([]int, error)
+	ReplaceCoOwners(testID int, userIDs []int) error
 }
 
 // TestService defines the interface for test business logic
 type TestService interface {
-	CreateTest(creatorID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time) (*Test, error)
+	CreateTest(creatorID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time, mode TestMode) (*Test, error)
 	GetTest(testID int) (*Test, error)
-	UpdateTest(testID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time) (*Test, error)
+	UpdateTest(testID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time, mode TestMode) (*Test, error)
 	DeleteTest(testID int) error
 	ListTests(creatorID int, limit, offset int) ([]*Test, error)
 	GetAvailableTests(userID int, limit, offset int) ([]*Test, error)
 	ActivateTest(testID int) error
 	DeactivateTest(testID int) error
+	GetOwnership(testID int) (*TestOwnership, error)
+	SetCoOwners(testID int, userIDs []int) (*TestOwnership, error)
+}
+
+// TestOwnership represents who owns a test: its creator and the teachers it is shared with
+type TestOwnership struct {
+	TestID     int   `json:"test_id"`
+	OwnerID    int   `json:"owner_id"`
+	CoOwnerIDs []int `json:"co_owner_ids"`
+}
+
+// IsValid checks if the test mode is valid
+func (m TestMode) IsValid() bool {
+	switch m {
+	case TestModeGraded, TestModePractice, TestModeAdaptive:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPractice checks if the test is a practice quiz
+func (t *Test) IsPractice() bool {
+	return t.Mode == TestModePractice
+}
+
+// IsAdaptive checks if the test is delivered adaptively
+func (t *Test) IsAdaptive() bool {
+	return t.Mode == TestModeAdaptive
 }
 
 // IsAvailable checks if the test is currently available for taking
@@ -81,6 +123,7 @@ func ScanTest(row interface {
 	Scan(dest ...interface{}) error
 }) (*Test, error) {
 	test := &Test{}
+	var mode sql.NullString
 	err := row.Scan(
 		&test.ID,
 		&test.Title,
@@ -93,6 +136,8 @@ func ScanTest(row interface {
 		&test.IsActive,
 		&test.StartTime,
 		&test.EndTime,
+		&test.GradingScaleID,
+		&mode,
 		&test.CreatedAt,
 		&test.UpdatedAt,
 	)
@@ -102,5 +147,9 @@ func ScanTest(row interface {
 		}
 		return nil, err
 	}
+	test.Mode = TestModeGraded
+	if mode.Valid && mode.String != "" {
+		test.Mode = TestMode(mode.String)
+	}
 	return test, nil
 }
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshToken represents a stored refresh token. Only the SHA-256 hash of the token is
+// kept; every token rotated from one login shares its family, and the access token issued
+// with it is recorded so that revoking the family also revokes that access token.
+type RefreshToken struct {
+	ID              int        `json:"id" db:"id"`
+	UserID          int        `json:"user_id" db:"user_id"`
+	TokenHash       string     `json:"-" db:"token_hash"`
+	FamilyID        string     `json:"family_id" db:"family_id"`
+	AccessTokenID   string     `json:"-" db:"access_token_id"`
+	AccessExpiresAt time.Time  `json:"-" db:"access_expires_at"`
+	AMR             []string   `json:"amr" db:"amr"` // authentication methods of the login
+	ExpiresAt       time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TokenPair represents the access and refresh tokens returned on login and refresh
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// TokenRepository defines the interface for refresh token and access token denylist
+// data operations
+type TokenRepository interface {
+	CreateRefreshToken(token *RefreshToken) error
+	GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error)
+	RotateRefreshToken(oldID int, token *RefreshToken) (bool, error)
+	RevokeFamily(familyID string, revokedAt time.Time) error
+	RevokeUserTokens(userID int, revokedAt time.Time) error
+	RevokeAccessToken(tokenID string, userID int, expiresAt time.Time) error
+	IsAccessTokenRevoked(tokenID string) (bool, error)
+	DeleteExpired(before time.Time) error
+}
+
+// TokenService defines the interface for token issuing, rotation and revocation
+type TokenService interface {
+	IssueTokens(user *User, amr []string) (*TokenPair, error)
+	Refresh(refreshToken string) (*TokenPair, error)
+	Logout(userID int, accessTokenID string, accessExpiresAt time.Time, refreshToken string) error
+	LogoutAll(userID int, accessTokenID string, accessExpiresAt time.Time) error
+	IsTokenRevoked(tokenID string) (bool, error)
+}
+
+// IsRevoked checks if the refresh token was rotated, logged out or revoked
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// IsExpired checks if the refresh token has expired
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// ScanRefreshToken scans database row into RefreshToken struct
+func ScanRefreshToken(row interface {
+	Scan(dest ...interface{}) error
+}) (*RefreshToken, error) {
+	token := &RefreshToken{}
+	var revokedAt sql.NullTime
+	var amr string
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.FamilyID,
+		&token.AccessTokenID,
+		&token.AccessExpiresAt,
+		&amr,
+		&token.ExpiresAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	token.AMR = SplitAuthMethods(amr)
+	return token, nil
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Authentication method references (RFC 8176) carried in the amr claim of access tokens
+const (
+	AuthMethodPassword = "pwd" // local or directory password
+	AuthMethodOTP      = "otp" // TOTP or single-use recovery code
+	AuthMethodMFA      = "mfa" // more than one factor was used
+)
+
+// TwoFactor represents a user's TOTP authenticator. The secret is stored when enrolment
+// starts and the authenticator is enabled once a code from it has been confirmed.
+type TwoFactor struct {
+	UserID       int        `json:"user_id" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty" db:"enabled_at"`
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TwoFactorChallenge represents a login that passed its first step and waits for a
+// two-factor code. Only the SHA-256 hash of the challenge token is kept.
+type TwoFactorChallenge struct {
+	TokenHash string    `json:"-" db:"token_hash"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	AMR       []string  `json:"amr" db:"amr"` // methods of the first step
+	Attempts  int       `json:"attempts" db:"attempts"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// TwoFactorLogin is returned instead of tokens when a login needs a second step. When
+// enrolment is required, the user must set up an authenticator with the challenge token
+// before a code can complete the login.
+type TwoFactorLogin struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	EnrolmentRequired bool      `json:"enrolment_required"`
+}
+
+// TwoFactorLoginResult represents a login completed with a two-factor code. Recovery
+// codes are only set when the login also completed enrolment.
+type TwoFactorLoginResult struct {
+	User          *User
+	AMR           []string
+	RecoveryCodes []string
+}
+
+// TwoFactorEnrolment represents a new authenticator secret waiting to be confirmed. The
+// provisioning URI is shown as a QR code for authenticator apps to scan; the secret can be
+// typed in instead.
+type TwoFactorEnrolment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorStatus represents a user's two-factor settings
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Required          bool       `json:"required"` // one of the user's roles requires it
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorRepository defines the interface for two-factor authentication data operations
+type TwoFactorRepository interface {
+	GetTwoFactor(userID int) (*TwoFactor, error)
+	SaveSecret(userID int, secret string) error
+	Enable(userID int, step int64, codeHashes []string) error
+	UseStep(userID int, step int64) (bool, error)
+	Delete(userID int) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int) (int, error)
+	CreateChallenge(challenge *TwoFactorChallenge) error
+	GetChallenge(tokenHash string) (*TwoFactorChallenge, error)
+	RecordFailedAttempt(tokenHash string) error
+	ConsumeChallenge(tokenHash string) (bool, error)
+	DeleteExpiredChallenges(before time.Time) error
+}
+
+// TwoFactorService defines the interface for two-factor authentication business logic
+type TwoFactorService interface {
+	BeginLogin(user *User, amr []string) (*TwoFactorLogin, error)
+	GetChallengeUser(challengeToken string) (*User, error)
+	StartLoginEnrolment(challengeToken string) (*TwoFactorEnrolment, error)
+	CompleteLogin(challengeToken, code string) (*TwoFactorLoginResult, error)
+	GetStatus(userID int) (*TwoFactorStatus, error)
+	StartEnrolment(userID int) (*TwoFactorEnrolment, error)
+	ConfirmEnrolment(userID int, code string) ([]string, error)
+	RegenerateRecoveryCodes(userID int, code string) ([]string, error)
+	Disable(userID int, code string) error
+	Reset(userID int) error
+}
+
+// IsEnabled checks if enrolment of the authenticator was confirmed
+func (t *TwoFactor) IsEnabled() bool {
+	return t.EnabledAt != nil
+}
+
+// IsExpired checks if the challenge has expired
+func (c *TwoFactorChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// HasAuthMethod checks if a list of authentication method references contains a method
+func HasAuthMethod(amr []string, method string) bool {
+	for _, m := range amr {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinAuthMethods stores authentication method references as a comma-separated list
+func JoinAuthMethods(amr []string) string {
+	return strings.Join(amr, ",")
+}
+
+// SplitAuthMethods reads authentication method references stored by JoinAuthMethods
+func SplitAuthMethods(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// ScanTwoFactor scans database row into TwoFactor struct
+func ScanTwoFactor(row interface {
+	Scan(dest ...interface{}) error
+}) (*TwoFactor, error) {
+	twoFactor := &TwoFactor{}
+	var enabledAt sql.NullTime
+	err := row.Scan(
+		&twoFactor.UserID,
+		&twoFactor.Secret,
+		&enabledAt,
+		&twoFactor.LastUsedStep,
+		&twoFactor.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if enabledAt.Valid {
+		twoFactor.EnabledAt = &enabledAt.Time
+	}
+	return twoFactor, nil
+}
+
+// ScanTwoFactorChallenge scans database row into TwoFactorChallenge struct
+func ScanTwoFactorChallenge(row interface {
+	Scan(dest ...interface{}) error
+}) (*TwoFactorChallenge, error) {
+	challenge := &TwoFactorChallenge{}
+	var amr string
+	err := row.Scan(
+		&challenge.TokenHash,
+		&challenge.UserID,
+		&amr,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	challenge.AMR = SplitAuthMethods(amr)
+	return challenge, nil
+}
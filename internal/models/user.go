@@ -16,16 +16,17 @@ const (
 
 // User represents a user in the system
 type User struct {
-	ID           int       `json:"id" db:"id"`
-	Username     string    `json:"username" db:"username"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"` // Never expose password hash in JSON
-	FirstName    string    `json:"first_name" db:"first_name"`
-	LastName     string    `json:"last_name" db:"last_name"`
-	Role         UserRole  `json:"role" db:"role"`
-	IsActive     bool      `json:"is_active" db:"is_active"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID            int       `json:"id" db:"id"`
+	Username      string    `json:"username" db:"username"`
+	Email         string    `json:"email" db:"email"`
+	PasswordHash  string    `json:"-" db:"password_hash"` // Never expose password hash in JSON
+	FirstName     string    `json:"first_name" db:"first_name"`
+	LastName      string    `json:"last_name" db:"last_name"`
+	Role          UserRole  `json:"role" db:"role"`
+	IsActive      bool      `json:"is_active" db:"is_active"`
+	EmailVerified bool      `json:"email_verified" db:"email_verified"` // Confirmed through a verification link
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // UserRepository defines the interface for user data operations
@@ -35,6 +36,7 @@ type UserRepository interface {
 	GetByUsername(username string) (*User, error)
 	GetByEmail(email string) (*User, error)
 	Update(user *User) error
+	UpdatePassword(id int, passwordHash string) error
 	Delete(id int) error
 	List(limit, offset int) ([]*User, error)
 	GetByRole(role UserRole, limit, offset int) ([]*User, error)
@@ -91,6 +93,7 @@ func ScanUser(row interface {
 		&user.LastName,
 		&user.Role,
 		&user.IsActive,
+		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"net"
+	"strings"
+	"time"
+)
+
+// TestAccessService implements the models.TestAccessService interface
+type TestAccessService struct {
+	accessRepo models.TestAccessRepository
+	testRepo   models.TestRepository
+}
+
+// NewTestAccessService creates a new test access service
+func NewTestAccessService(accessRepo models.TestAccessRepository, testRepo models.TestRepository) models.TestAccessService {
+	return &TestAccessService{
+		accessRepo: accessRepo,
+		testRepo:   testRepo,
+	}
+}
+
+// GetPolicy retrieves the access policy for a test
+func (s *TestAccessService) GetPolicy(testID int) (*models.TestAccessPolicy, error) {
+	policy, err := s.accessRepo.GetPolicy(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if policy == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return policy, nil
+}
+
+// UpdatePolicy creates or replaces the access policy for a test
+func (s *TestAccessService) UpdatePolicy(testID int, accessCode *string, codeRotationMinutes int, allowedCIDRs []string) (*models.TestAccessPolicy, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	// Validate input
+	if codeRotationMinutes < 0 || codeRotationMinutes > 24*60 {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	if accessCode != nil {
+		trimmed := strings.TrimSpace(*accessCode)
+		if trimmed == "" {
+			accessCode = nil
+		} else {
+			accessCode = &trimmed
+		}
+	}
+
+	if accessCode == nil && codeRotationMinutes > 0 {
+		return nil, auth.ErrAccessCodeRequired
+	}
+
+	// Normalise CIDR blocks so single addresses can be given without a prefix length
+	cidrs := make([]string, 0, len(allowedCIDRs))
+	for _, cidr := range allowedCIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, auth.ErrInvalidCIDR
+			}
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, auth.ErrInvalidCIDR
+		}
+		cidrs = append(cidrs, network.String())
+	}
+
+	policy := &models.TestAccessPolicy{
+		TestID:              testID,
+		AccessCode:          accessCode,
+		CodeRotationMinutes: codeRotationMinutes,
+		AllowedCIDRs:        cidrs,
+	}
+
+	if err := s.accessRepo.UpsertPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// DeletePolicy removes all access gates from a test
+func (s *TestAccessService) DeletePolicy(testID int) error {
+	return s.accessRepo.DeletePolicy(testID)
+}
+
+// GetCurrentAccessCode returns the code the proctor should announce right now
+func (s *TestAccessService) GetCurrentAccessCode(testID int) (*models.CurrentAccessCode, error) {
+	policy, err := s.GetPolicy(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !policy.RequiresAccessCode() {
+		return nil, auth.ErrUserNotFound
+	}
+
+	code, validUntil := policy.CodeAt(time.Now())
+	return &models.CurrentAccessCode{
+		TestID:     testID,
+		Code:       code,
+		ValidUntil: validUntil,
+	}, nil
+}
+
+// CheckAccess evaluates the access gates of a test and records any failure
+func (s *TestAccessService) CheckAccess(testID, userID int, accessCode, clientIP string) error {
+	policy, err := s.accessRepo.GetPolicy(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// Tests without a policy are open to every authenticated user
+	if policy == nil {
+		return nil
+	}
+
+	var reason models.AccessGateReason
+	var gateErr error
+
+	switch {
+	case !policy.AllowsIP(clientIP):
+		reason, gateErr = models.AccessGateIPDenied, auth.ErrIPNotAllowed
+	case policy.RequiresAccessCode() && strings.TrimSpace(accessCode) == "":
+		reason, gateErr = models.AccessGateMissingCode, auth.ErrAccessCodeRequired
+	case !policy.ValidateCode(accessCode, time.Now()):
+		reason, gateErr = models.AccessGateInvalidCode, auth.ErrInvalidAccessCode
+	default:
+		return nil
+	}
+
+	failure := &models.AccessGateFailure{
+		TestID:    testID,
+		UserID:    userID,
+		IPAddress: clientIP,
+		Reason:    reason,
+	}
+	if err := s.accessRepo.CreateFailure(failure); err != nil {
+		// Log error but still reject the session start
+		fmt.Printf("Warning: Failed to record access gate failure for test %d: %v\n", testID, err)
+	}
+
+	return gateErr
+}
+
+// GetFailures retrieves audited access gate failures for a test
+func (s *TestAccessService) GetFailures(testID int, limit, offset int) ([]*models.AccessGateFailure, error) {
+	return s.accessRepo.GetFailuresByTest(testID, limit, offset)
+}
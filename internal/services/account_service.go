@@ -0,0 +1,323 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/mail"
+	"gocbt/internal/models"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// accountEmailLimit is how many emails of one kind an account is sent per
+	// accountEmailWindow, so that the reset form cannot be used to flood someone's inbox
+	accountEmailLimit  = 3
+	accountEmailWindow = time.Hour
+)
+
+// AccountService implements the models.AccountService interface. Password reset and
+// email verification links carry a signed token whose record makes it single-use; a new
+// link replaces the earlier ones of its kind. Failed sign-ins are counted per username and
+// per IP address to throttle password guessing.
+type AccountService struct {
+	accountRepo     models.AccountRepository
+	userRepo        models.UserRepository
+	tokenRepo       models.TokenRepository
+	passwordManager *auth.PasswordManager
+	signer          *auth.AccountTokenSigner
+	mailer          mail.Mailer
+	cfg             *config.AccountConfig
+	frontendURL     string
+}
+
+// NewAccountService creates a new account service. Links in emails point to pages of the
+// frontend at the given URL.
+func NewAccountService(accountRepo models.AccountRepository, userRepo models.UserRepository, tokenRepo models.TokenRepository,
+	passwordManager *auth.PasswordManager, signer *auth.AccountTokenSigner, mailer mail.Mailer, cfg *config.AccountConfig, frontendURL string) models.AccountService {
+	return &AccountService{
+		accountRepo:     accountRepo,
+		userRepo:        userRepo,
+		tokenRepo:       tokenRepo,
+		passwordManager: passwordManager,
+		signer:          signer,
+		mailer:          mailer,
+		cfg:             cfg,
+		frontendURL:     strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// RequestPasswordReset emails a password reset link to the account with the given
+// address. Unknown addresses, inactive accounts and accounts without a local password
+// succeed without sending anything, so that the result does not reveal which accounts exist.
+func (s *AccountService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(strings.TrimSpace(email))
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	// Accounts that sign in through single sign-on or the directory have no password here
+	if user == nil || !user.IsActive || user.PasswordHash == "" {
+		return nil
+	}
+
+	sent, err := s.accountRepo.CountAccountTokensSince(user.ID, models.AccountTokenPasswordReset, time.Now().Add(-accountEmailWindow))
+	if err != nil {
+		return err
+	}
+	if sent >= accountEmailLimit {
+		return nil
+	}
+
+	return s.sendToken(user, models.AccountTokenPasswordReset, s.cfg.PasswordResetExpiration, "/reset-password",
+		func(link, validFor string) *mail.Message {
+			return &mail.Message{
+				To:      user.Email,
+				Subject: "Reset your GoCBT password",
+				Body: fmt.Sprintf("Hello %s,\n\n"+
+					"Someone asked to reset the password of your GoCBT account %s. To choose a new password, "+
+					"open this link within %s:\n\n%s\n\n"+
+					"If you did not ask for this, you can ignore this email; your password has not changed.\n",
+					user.FirstName, user.Username, validFor, link),
+			}
+		})
+}
+
+// ResetPassword sets a new password with a password reset token. The account is signed
+// out everywhere, and its email address counts as verified since the link reached it.
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	record, user, err := s.checkToken(token, models.AccountTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	// Check the password before using the token, so that a rejected password does not
+	// spend the link
+	if err := s.passwordManager.ValidatePasswordStrength(newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := s.passwordManager.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	used, err := s.accountRepo.UseAccountToken(record.ID, now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return auth.ErrInvalidAccountToken
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		if err := s.userRepo.Update(user); err != nil {
+			return err
+		}
+	}
+
+	// Whoever knew the old password must not stay signed in
+	if err := s.tokenRepo.RevokeUserTokens(user.ID, now); err != nil {
+		return err
+	}
+
+	return s.accountRepo.ClearFailedLogins(loginName(user.Username))
+}
+
+// SendEmailVerification emails a link that confirms the user's email address
+func (s *AccountService) SendEmailVerification(userID int) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if user == nil {
+		return auth.ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return auth.ErrEmailAlreadyVerified
+	}
+
+	sent, err := s.accountRepo.CountAccountTokensSince(user.ID, models.AccountTokenEmailVerification, time.Now().Add(-accountEmailWindow))
+	if err != nil {
+		return err
+	}
+	if sent >= accountEmailLimit {
+		return auth.ErrTooManyAccountEmails
+	}
+
+	return s.sendToken(user, models.AccountTokenEmailVerification, s.cfg.EmailVerificationExpiration, "/verify-email",
+		func(link, validFor string) *mail.Message {
+			return &mail.Message{
+				To:      user.Email,
+				Subject: "Confirm your GoCBT email address",
+				Body: fmt.Sprintf("Hello %s,\n\n"+
+					"Please confirm that this is the email address of your GoCBT account %s by opening this "+
+					"link within %s:\n\n%s\n\n"+
+					"If you did not create this account, you can ignore this email.\n",
+					user.FirstName, user.Username, validFor, link),
+			}
+		})
+}
+
+// VerifyEmail marks the user's email address as verified with an email verification
+// token. The token only works while the account still has the address it was sent to.
+func (s *AccountService) VerifyEmail(token string) (*models.User, error) {
+	record, user, err := s.checkToken(token, models.AccountTokenEmailVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := s.accountRepo.UseAccountToken(record.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, auth.ErrInvalidAccountToken
+	}
+
+	user.EmailVerified = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CheckLogin refuses a sign-in when its username or IP address had too many recent
+// failures. Usernames are counted whether or not the account exists, so being refused
+// reveals nothing about it.
+func (s *AccountService) CheckLogin(username, ipAddress string) error {
+	since := time.Now().Add(-s.cfg.LoginFailureWindow)
+
+	if s.cfg.LoginMaxFailures > 0 {
+		failures, err := s.accountRepo.CountFailedLogins(loginName(username), since)
+		if err != nil {
+			return err
+		}
+		if failures >= s.cfg.LoginMaxFailures {
+			return auth.ErrTooManyLoginAttempts
+		}
+	}
+
+	if s.cfg.LoginMaxFailuresPerIP > 0 {
+		failures, err := s.accountRepo.CountFailedLoginsFromIP(ipAddress, since)
+		if err != nil {
+			return err
+		}
+		if failures >= s.cfg.LoginMaxFailuresPerIP {
+			return auth.ErrTooManyLoginAttempts
+		}
+	}
+
+	return nil
+}
+
+// RecordLoginFailure counts a sign-in with a wrong username or password
+func (s *AccountService) RecordLoginFailure(username, ipAddress string) error {
+	now := time.Now()
+
+	// Failures outside the window no longer count; clear them out as new ones are recorded
+	if err := s.accountRepo.DeleteFailedLoginsBefore(now.Add(-s.cfg.LoginFailureWindow)); err != nil {
+		return err
+	}
+
+	return s.accountRepo.RecordFailedLogin(loginName(username), truncate(ipAddress, 45), now)
+}
+
+// RecordLoginSuccess forgets the failed sign-ins of a username once its password is right
+func (s *AccountService) RecordLoginSuccess(username string) error {
+	return s.accountRepo.ClearFailedLogins(loginName(username))
+}
+
+// sendToken signs a token for a user, records it in place of their earlier tokens of the
+// purpose and emails the message built around the link to the given frontend page
+func (s *AccountService) sendToken(user *models.User, purpose string, expiration time.Duration, page string,
+	message func(link, validFor string) *mail.Message) error {
+	now := time.Now()
+
+	// Expired tokens are rejected anyway; clear them out as new ones are issued
+	if err := s.accountRepo.DeleteExpiredAccountTokens(now); err != nil {
+		return err
+	}
+
+	// Only the newest link works
+	if err := s.accountRepo.InvalidateAccountTokens(user.ID, purpose, now); err != nil {
+		return err
+	}
+
+	token, claims, err := s.signer.Sign(purpose, user.ID, now.Add(expiration))
+	if err != nil {
+		return err
+	}
+
+	record := &models.AccountToken{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := s.accountRepo.CreateAccountToken(record); err != nil {
+		return err
+	}
+
+	link := s.frontendURL + page + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(message(link, describeDuration(expiration)))
+}
+
+// checkToken verifies a token of a purpose and returns its unused record and the account
+// it belongs to, which must still have the address the token was sent to
+func (s *AccountService) checkToken(token, purpose string) (*models.AccountToken, *models.User, error) {
+	claims, err := s.signer.Verify(token, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record, err := s.accountRepo.GetAccountToken(claims.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if record == nil || record.IsUsed() || record.IsExpired() || record.UserID != claims.UserID || record.Purpose != purpose {
+		return nil, nil, auth.ErrInvalidAccountToken
+	}
+
+	user, err := s.userRepo.GetByID(record.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if user == nil || !user.IsActive || !strings.EqualFold(user.Email, record.Email) {
+		return nil, nil, auth.ErrInvalidAccountToken
+	}
+
+	return record, user, nil
+}
+
+// loginName normalises a username for counting failed sign-ins
+func loginName(username string) string {
+	return truncate(strings.ToLower(strings.TrimSpace(username)), 100)
+}
+
+// describeDuration describes how long a link works, such as "1 hour" or "30 minutes"
+func describeDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		if hours := int(d / time.Hour); hours != 1 {
+			return fmt.Sprintf("%d hours", hours)
+		}
+		return "1 hour"
+	case d >= time.Minute:
+		if minutes := int(d / time.Minute); minutes != 1 {
+			return fmt.Sprintf("%d minutes", minutes)
+		}
+		return "1 minute"
+	default:
+		return d.String()
+	}
+}
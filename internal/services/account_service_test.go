@@ -0,0 +1,278 @@
+package services
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"gocbt/internal/mail"
+	"gocbt/internal/models"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingMailer keeps sent messages instead of delivering them
+type recordingMailer struct {
+	messages []*mail.Message
+}
+
+func (m *recordingMailer) Send(message *mail.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// linkPattern finds the link in an account email
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+// accountTestEnv is an account service with a student account, backed by a fresh SQLite
+// database
+type accountTestEnv struct {
+	cfg       *config.AccountConfig
+	userRepo  models.UserRepository
+	tokens    models.TokenService
+	passwords *auth.PasswordManager
+	mailer    *recordingMailer
+	service   models.AccountService
+	student   *models.User
+}
+
+func newAccountTestEnv(t *testing.T) *accountTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	cfg := &config.AccountConfig{
+		PasswordResetExpiration:     time.Hour,
+		EmailVerificationExpiration: 72 * time.Hour,
+		LoginMaxFailures:            3,
+		LoginMaxFailuresPerIP:       10,
+		LoginFailureWindow:          15 * time.Minute,
+	}
+
+	userRepo := database.NewUserRepository(db)
+	tokenRepo := database.NewTokenRepository(db)
+	passwords := auth.NewPasswordManager()
+	mailer := &recordingMailer{}
+	signer := auth.NewAccountTokenSigner([]byte("account-token-test-key-of-32-bytes"))
+
+	hash, err := passwords.HashPassword("Original-secret-1")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	student := &models.User{
+		Username:     "ada",
+		Email:        "ada@school.example",
+		PasswordHash: hash,
+		FirstName:    "Ada",
+		LastName:     "Lovelace",
+		Role:         models.RoleStudent,
+		IsActive:     true,
+	}
+	if err := userRepo.Create(student); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	return &accountTestEnv{
+		cfg:       cfg,
+		userRepo:  userRepo,
+		tokens:    NewTokenService(tokenRepo, userRepo, database.NewRoleRepository(db), newTestJWT(t), time.Hour),
+		passwords: passwords,
+		mailer:    mailer,
+		service: NewAccountService(database.NewAccountRepository(db), userRepo, tokenRepo, passwords, signer, mailer, cfg,
+			"http://localhost:3000/"),
+		student: student,
+	}
+}
+
+// lastToken returns the token of the link in the last email sent, checking the page it
+// points to
+func (e *accountTestEnv) lastToken(t *testing.T, page string) string {
+	t.Helper()
+
+	if len(e.mailer.messages) == 0 {
+		t.Fatal("no email was sent")
+	}
+	message := e.mailer.messages[len(e.mailer.messages)-1]
+	link, err := url.Parse(linkPattern.FindString(message.Body))
+	if err != nil || link.Path != page {
+		t.Fatalf("email body %q has no link to %s", message.Body, page)
+	}
+	return link.Query().Get("token")
+}
+
+func TestPasswordReset(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	tokens, err := env.tokens.IssueTokens(env.student, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("IssueTokens() error = %v", err)
+	}
+
+	if err := env.service.RequestPasswordReset(" ada@school.example "); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if to := env.mailer.messages[0].To; to != env.student.Email {
+		t.Errorf("reset email sent to %q, expected %q", to, env.student.Email)
+	}
+	token := env.lastToken(t, "/reset-password")
+
+	// A rejected password does not spend the link
+	if err := env.service.ResetPassword(token, "short"); err != auth.ErrPasswordTooShort {
+		t.Errorf("ResetPassword() with a weak password error = %v, expected %v", err, auth.ErrPasswordTooShort)
+	}
+	if err := env.service.ResetPassword(token, "Replacement-secret-2"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if err := env.service.ResetPassword(token, "Another-secret-3"); err != auth.ErrInvalidAccountToken {
+		t.Errorf("ResetPassword() with a used token error = %v, expected %v", err, auth.ErrInvalidAccountToken)
+	}
+
+	user, err := env.userRepo.GetByID(env.student.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !env.passwords.VerifyPassword("Replacement-secret-2", user.PasswordHash) {
+		t.Error("password was not changed")
+	}
+	if !user.EmailVerified {
+		t.Error("email is not verified after a reset through it")
+	}
+
+	// Logins from before the reset are signed out
+	if _, err := env.tokens.Refresh(tokens.RefreshToken); err != auth.ErrRefreshTokenReused {
+		t.Errorf("Refresh() after a reset error = %v, expected %v", err, auth.ErrRefreshTokenReused)
+	}
+}
+
+func TestPasswordResetRevealsNothing(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	if err := env.service.RequestPasswordReset("nobody@school.example"); err != nil {
+		t.Errorf("RequestPasswordReset() for an unknown address error = %v, expected none", err)
+	}
+	if len(env.mailer.messages) != 0 {
+		t.Errorf("%d emails sent for an unknown address, expected none", len(env.mailer.messages))
+	}
+
+	// Requests beyond the limit succeed without sending more email
+	for i := 0; i < accountEmailLimit+2; i++ {
+		if err := env.service.RequestPasswordReset(env.student.Email); err != nil {
+			t.Fatalf("RequestPasswordReset() error = %v", err)
+		}
+	}
+	if len(env.mailer.messages) != accountEmailLimit {
+		t.Errorf("%d emails sent, expected %d", len(env.mailer.messages), accountEmailLimit)
+	}
+}
+
+func TestPasswordResetTokens(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	if err := env.service.RequestPasswordReset(env.student.Email); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	first := env.lastToken(t, "/reset-password")
+	if err := env.service.RequestPasswordReset(env.student.Email); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	second := env.lastToken(t, "/reset-password")
+
+	if err := env.service.ResetPassword(first, "Replacement-secret-2"); err != auth.ErrInvalidAccountToken {
+		t.Errorf("ResetPassword() with a replaced token error = %v, expected %v", err, auth.ErrInvalidAccountToken)
+	}
+
+	tampered := second[:len(second)-2] + "xx"
+	if strings.HasSuffix(second, "xx") {
+		tampered = second[:len(second)-2] + "yy"
+	}
+	if err := env.service.ResetPassword(tampered, "Replacement-secret-2"); err != auth.ErrInvalidAccountToken {
+		t.Errorf("ResetPassword() with a tampered token error = %v, expected %v", err, auth.ErrInvalidAccountToken)
+	}
+
+	// A verification link cannot reset a password
+	if err := env.service.SendEmailVerification(env.student.ID); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	if err := env.service.ResetPassword(env.lastToken(t, "/verify-email"), "Replacement-secret-2"); err != auth.ErrInvalidAccountToken {
+		t.Errorf("ResetPassword() with a verification token error = %v, expected %v", err, auth.ErrInvalidAccountToken)
+	}
+
+	if err := env.service.ResetPassword(second, "Replacement-secret-2"); err != nil {
+		t.Errorf("ResetPassword() with the newest token error = %v", err)
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	if err := env.service.SendEmailVerification(env.student.ID); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	token := env.lastToken(t, "/verify-email")
+
+	// The link stops working once the account's address changes
+	env.student.Email = "ada.lovelace@school.example"
+	if err := env.userRepo.Update(env.student); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := env.service.VerifyEmail(token); err != auth.ErrInvalidAccountToken {
+		t.Errorf("VerifyEmail() for an old address error = %v, expected %v", err, auth.ErrInvalidAccountToken)
+	}
+
+	if err := env.service.SendEmailVerification(env.student.ID); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	user, err := env.service.VerifyEmail(env.lastToken(t, "/verify-email"))
+	if err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if !user.EmailVerified || user.Email != "ada.lovelace@school.example" {
+		t.Errorf("VerifyEmail() = %q verified %v, expected the new address verified", user.Email, user.EmailVerified)
+	}
+
+	if err := env.service.SendEmailVerification(env.student.ID); err != auth.ErrEmailAlreadyVerified {
+		t.Errorf("SendEmailVerification() when verified error = %v, expected %v", err, auth.ErrEmailAlreadyVerified)
+	}
+}
+
+func TestLoginThrottling(t *testing.T) {
+	env := newAccountTestEnv(t)
+
+	// Unknown usernames are throttled like existing ones, ignoring case
+	for i := 0; i < env.cfg.LoginMaxFailures; i++ {
+		if err := env.service.CheckLogin("Nobody", "192.0.2.1"); err != nil {
+			t.Fatalf("CheckLogin() attempt %d error = %v", i+1, err)
+		}
+		if err := env.service.RecordLoginFailure("Nobody", "192.0.2.1"); err != nil {
+			t.Fatalf("RecordLoginFailure() error = %v", err)
+		}
+	}
+	if err := env.service.CheckLogin("nobody", "198.51.100.1"); err != auth.ErrTooManyLoginAttempts {
+		t.Errorf("CheckLogin() after too many failures error = %v, expected %v", err, auth.ErrTooManyLoginAttempts)
+	}
+
+	// A successful sign-in clears the username's failures
+	for i := 0; i < env.cfg.LoginMaxFailures; i++ {
+		if err := env.service.RecordLoginFailure("ada", "203.0.113.1"); err != nil {
+			t.Fatalf("RecordLoginFailure() error = %v", err)
+		}
+	}
+	if err := env.service.RecordLoginSuccess("ada"); err != nil {
+		t.Fatalf("RecordLoginSuccess() error = %v", err)
+	}
+
+	// One address guessing across many usernames is throttled too
+	for i := 0; i < env.cfg.LoginMaxFailuresPerIP-env.cfg.LoginMaxFailures; i++ {
+		if err := env.service.RecordLoginFailure("user"+string(rune('a'+i)), "192.0.2.1"); err != nil {
+			t.Fatalf("RecordLoginFailure() error = %v", err)
+		}
+	}
+	if err := env.service.CheckLogin("ada", "198.51.100.1"); err != nil {
+		t.Errorf("CheckLogin() from another address error = %v, expected none", err)
+	}
+	if err := env.service.CheckLogin("ada", "192.0.2.1"); err != auth.ErrTooManyLoginAttempts {
+		t.Errorf("CheckLogin() from a throttled address error = %v, expected %v", err, auth.ErrTooManyLoginAttempts)
+	}
+}
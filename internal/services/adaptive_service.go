@@ -0,0 +1,392 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"math"
+	"time"
+)
+
+// AdaptiveService implements the models.AdaptiveService interface
+type AdaptiveService struct {
+	adaptiveRepo models.AdaptiveRepository
+	testRepo     models.TestRepository
+	questionRepo models.QuestionRepository
+}
+
+// NewAdaptiveService creates a new adaptive testing service
+func NewAdaptiveService(adaptiveRepo models.AdaptiveRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository) models.AdaptiveService {
+	return &AdaptiveService{
+		adaptiveRepo: adaptiveRepo,
+		testRepo:     testRepo,
+		questionRepo: questionRepo,
+	}
+}
+
+// GetSettings retrieves the stopping rules of an adaptive test, or the defaults
+func (s *AdaptiveService) GetSettings(testID int) (*models.AdaptiveSettings, error) {
+	if _, err := s.getTest(testID); err != nil {
+		return nil, err
+	}
+	return s.settingsFor(testID)
+}
+
+// UpdateSettings creates or replaces the stopping rules and pass mark of an adaptive test
+func (s *AdaptiveService) UpdateSettings(testID, minQuestions, maxQuestions int, targetSE, passingTheta float64) (*models.AdaptiveSettings, error) {
+	if _, err := s.getTest(testID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.adaptiveRepo.GetSettings(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &models.AdaptiveSettings{TestID: testID}
+	}
+
+	settings.MinQuestions = minQuestions
+	settings.MaxQuestions = maxQuestions
+	settings.TargetSE = targetSE
+	settings.PassingTheta = passingTheta
+	if !settings.IsValid() {
+		return nil, auth.ErrInvalidAdaptiveSettings
+	}
+
+	if err := s.adaptiveRepo.UpsertSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// DeleteSettings resets an adaptive test to the default stopping rules
+func (s *AdaptiveService) DeleteSettings(testID int) error {
+	return s.adaptiveRepo.DeleteSettings(testID)
+}
+
+// Begin selects the first question of a new adaptive session. Sessions that already
+// have questions are returned as they are.
+func (s *AdaptiveService) Begin(session *models.TestSession) (*models.AdaptiveState, error) {
+	state, pool, err := s.loadState(session)
+	if err != nil {
+		return nil, err
+	}
+	if len(pool) == 0 {
+		return nil, auth.ErrAdaptivePoolNotCalibrated
+	}
+	if len(state.Steps) > 0 {
+		return state, nil
+	}
+
+	if err := s.administerNext(state, pool); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// RecordAnswer scores the current question of an adaptive session, updates the ability
+// estimate and either selects the next question or stops the session
+func (s *AdaptiveService) RecordAnswer(session *models.TestSession, questionID int, isCorrect bool) (*models.AdaptiveState, error) {
+	state, pool, err := s.loadState(session)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := state.PendingStep()
+	if pending == nil || pending.QuestionID != questionID {
+		return nil, auth.ErrNotCurrentQuestion
+	}
+
+	// Re-estimate ability from every answered step
+	now := time.Now()
+	pending.IsCorrect = &isCorrect
+	pending.AnsweredAt = &now
+	state.Theta, state.ThetaSE = trajectoryAbility(state.Steps, pool)
+	theta, thetaSE := roundStatistic(state.Theta), roundStatistic(state.ThetaSE)
+	pending.Theta, pending.ThetaSE = &theta, &thetaSE
+	if err := s.adaptiveRepo.UpdateStep(pending); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.settingsFor(session.TestID)
+	if err != nil {
+		return nil, err
+	}
+
+	state.NextQuestion = nil
+	state.StopReason = stopReason(settings, state, pool)
+	if state.StopReason != "" {
+		state.Finished = true
+		return state, nil
+	}
+
+	if err := s.administerNext(state, pool); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// GetState retrieves the question sequence, ability trajectory and current question
+// of an adaptive session
+func (s *AdaptiveService) GetState(session *models.TestSession) (*models.AdaptiveState, error) {
+	state, pool, err := s.loadState(session)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending := state.PendingStep(); pending != nil {
+		question, err := s.presentQuestion(pool[pending.QuestionID])
+		if err != nil {
+			return nil, err
+		}
+		state.NextQuestion = question
+		return state, nil
+	}
+
+	if len(state.Steps) > 0 {
+		settings, err := s.settingsFor(session.TestID)
+		if err != nil {
+			return nil, err
+		}
+		state.StopReason = stopReason(settings, state, pool)
+		state.Finished = state.StopReason != ""
+	}
+
+	return state, nil
+}
+
+// loadState loads the steps of a session and the calibrated question pool of its test
+func (s *AdaptiveService) loadState(session *models.TestSession) (*models.AdaptiveState, map[int]*models.Question, error) {
+	test, err := s.getTest(session.TestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !test.IsAdaptive() {
+		return nil, nil, auth.ErrNotAdaptiveTest
+	}
+
+	questions, err := s.questionRepo.GetByTestID(session.TestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := make(map[int]*models.Question)
+	for _, question := range questions {
+		if question.IRT != nil {
+			pool[question.ID] = question
+		}
+	}
+
+	steps, err := s.adaptiveRepo.GetSteps(session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := &models.AdaptiveState{SessionID: session.ID, Steps: steps}
+	state.Theta, state.ThetaSE = trajectoryAbility(steps, pool)
+	return state, pool, nil
+}
+
+// administerNext selects the most informative unused question at the current ability
+// estimate and records it as the next step
+func (s *AdaptiveService) administerNext(state *models.AdaptiveState, pool map[int]*models.Question) error {
+	question := selectQuestion(pool, state.Steps, state.Theta)
+	if question == nil {
+		state.Finished = true
+		state.StopReason = models.AdaptiveStopPoolExhausted
+		return nil
+	}
+
+	step := &models.AdaptiveStep{
+		SessionID:  state.SessionID,
+		StepNumber: len(state.Steps) + 1,
+		QuestionID: question.ID,
+	}
+	if err := s.adaptiveRepo.CreateStep(step); err != nil {
+		return err
+	}
+	state.Steps = append(state.Steps, step)
+
+	next, err := s.presentQuestion(question)
+	if err != nil {
+		return err
+	}
+	state.NextQuestion = next
+	return nil
+}
+
+// presentQuestion returns a copy of a question with its options, without the answer
+// key, feedback or IRT parameters, for showing to a candidate
+func (s *AdaptiveService) presentQuestion(question *models.Question) (*models.Question, error) {
+	if question == nil {
+		return nil, nil
+	}
+
+	options, err := s.questionRepo.GetOptionsByQuestionID(question.ID)
+	if err != nil {
+		return nil, err
+	}
+	withOptions := *question
+	withOptions.Options = options
+
+	return withOptions.CandidateView(), nil
+}
+
+// settingsFor returns the stopping rules of a test, or the defaults
+func (s *AdaptiveService) settingsFor(testID int) (*models.AdaptiveSettings, error) {
+	return adaptiveSettingsFor(s.adaptiveRepo, testID)
+}
+
+// adaptiveSettingsFor loads the settings of an adaptive test, or the defaults
+func adaptiveSettingsFor(adaptiveRepo models.AdaptiveRepository, testID int) (*models.AdaptiveSettings, error) {
+	settings, err := adaptiveRepo.GetSettings(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if settings == nil {
+		return models.DefaultAdaptiveSettings(testID), nil
+	}
+	return settings, nil
+}
+
+// getTest retrieves a test, mapping a missing test to auth.ErrUserNotFound
+func (s *AdaptiveService) getTest(testID int) (*models.Test, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return test, nil
+}
+
+// trajectoryAbility estimates ability from the answered steps of a session. Before the
+// first answer it returns the prior: a mean of zero and a standard error of one.
+func trajectoryAbility(steps []*models.AdaptiveStep, pool map[int]*models.Question) (float64, float64) {
+	var items []irtItem
+	var responses []float64
+	for _, step := range steps {
+		question, ok := pool[step.QuestionID]
+		if step.IsCorrect == nil || !ok {
+			continue
+		}
+		items = append(items, irtItem{difficulty: question.IRT.Difficulty, discrimination: question.IRT.Discrimination})
+		if *step.IsCorrect {
+			responses = append(responses, 1)
+		} else {
+			responses = append(responses, 0)
+		}
+	}
+
+	if len(items) == 0 {
+		return 0, 1
+	}
+	return estimateAbility(items, responses)
+}
+
+// selectQuestion returns the unused question with the most Fisher information at theta
+func selectQuestion(pool map[int]*models.Question, steps []*models.AdaptiveStep, theta float64) *models.Question {
+	used := make(map[int]bool)
+	for _, step := range steps {
+		used[step.QuestionID] = true
+	}
+
+	var best *models.Question
+	bestInformation := -1.0
+	for _, question := range pool {
+		if used[question.ID] {
+			continue
+		}
+
+		item := irtItem{difficulty: question.IRT.Difficulty, discrimination: question.IRT.Discrimination}
+		p := irtProbability(theta, item)
+		information := item.discrimination * item.discrimination * p * (1 - p)
+
+		// Ties go to the question that comes first in the test
+		if information > bestInformation+1e-12 ||
+			(math.Abs(information-bestInformation) <= 1e-12 && best != nil && question.OrderIndex < best.OrderIndex) {
+			best, bestInformation = question, information
+		}
+	}
+
+	return best
+}
+
+// stopReason checks the stopping rules after the last answered step. It returns an
+// empty reason while the session should continue.
+func stopReason(settings *models.AdaptiveSettings, state *models.AdaptiveState, pool map[int]*models.Question) models.AdaptiveStopReason {
+	answered := 0
+	for _, step := range state.Steps {
+		if step.IsCorrect != nil {
+			answered++
+		}
+	}
+
+	switch {
+	case answered >= settings.MaxQuestions:
+		return models.AdaptiveStopLength
+	case answered >= settings.MinQuestions && state.ThetaSE <= settings.TargetSE:
+		return models.AdaptiveStopPrecision
+	case len(state.Steps) >= len(pool):
+		return models.AdaptiveStopPoolExhausted
+	default:
+		return ""
+	}
+}
+
+// administeredForm returns the test and questions a session's result is scored on. An
+// adaptive session is scored on the questions it answered; other tests are scored on
+// every question. Adaptive results are then judged on ability by scoreAdaptive.
+func administeredForm(test *models.Test, questions []*models.Question, answers []*models.UserAnswer) (*models.Test, []*models.Question) {
+	if !test.IsAdaptive() {
+		return test, questions
+	}
+
+	answered := make(map[int]bool)
+	for _, answer := range answers {
+		answered[answer.QuestionID] = true
+	}
+
+	var administered []*models.Question
+	administeredMarks := 0
+	for _, question := range questions {
+		if answered[question.ID] {
+			administered = append(administered, question)
+			administeredMarks += question.Marks
+		}
+	}
+
+	form := *test
+	form.TotalMarks = administeredMarks
+	return &form, administered
+}
+
+// scoreAdaptive judges an adaptive result on the final ability estimate, since the number
+// of marks depends on which questions a candidate was asked. The percentage is the
+// expected score on the whole calibrated pool at that ability, and the result passes when
+// theta reaches the test's passing theta. A result without an estimate does not pass.
+func scoreAdaptive(result *models.TestResult, pool []*models.Question, settings *models.AdaptiveSettings) {
+	result.IsPassed = false
+	if result.Theta == nil {
+		return
+	}
+
+	expected := 0.0
+	calibrated := 0
+	for _, question := range pool {
+		if question.IRT == nil {
+			continue
+		}
+		expected += irtProbability(*result.Theta, irtItem{difficulty: question.IRT.Difficulty, discrimination: question.IRT.Discrimination})
+		calibrated++
+	}
+	if calibrated > 0 {
+		result.Percentage = roundStatistic(expected / float64(calibrated) * 100)
+	}
+	result.IsPassed = *result.Theta >= settings.PassingTheta
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"gocbt/internal/models"
+	"testing"
+)
+
+// adaptivePool builds a calibrated question pool, keyed by question ID, from
+// difficulty and discrimination pairs; question i+1 has order index i
+func adaptivePool(parameters ...[2]float64) map[int]*models.Question {
+	pool := make(map[int]*models.Question, len(parameters))
+	for i, params := range parameters {
+		pool[i+1] = &models.Question{
+			ID:         i + 1,
+			OrderIndex: i,
+			IRT:        &models.ItemParameters{Difficulty: params[0], Discrimination: params[1]},
+		}
+	}
+	return pool
+}
+
+// adaptiveSteps builds the steps of a session; a nil result marks an unanswered step
+func adaptiveSteps(questionIDs []int, correct ...*bool) []*models.AdaptiveStep {
+	steps := make([]*models.AdaptiveStep, len(questionIDs))
+	for i, questionID := range questionIDs {
+		steps[i] = &models.AdaptiveStep{StepNumber: i + 1, QuestionID: questionID}
+		if i < len(correct) {
+			steps[i].IsCorrect = correct[i]
+		}
+	}
+	return steps
+}
+
+func TestSelectQuestion(t *testing.T) {
+	pool := adaptivePool(
+		[2]float64{-2, 1},  // 1
+		[2]float64{0, 1},   // 2
+		[2]float64{0, 2},   // 3: most informative around 0
+		[2]float64{1.5, 1}, // 4
+		[2]float64{2, 1},   // 5
+	)
+	tied := adaptivePool([2]float64{0, 1}, [2]float64{0, 1}, [2]float64{0, 1})
+
+	tests := []struct {
+		name     string
+		pool     map[int]*models.Question
+		used     []int
+		theta    float64
+		expected int // 0 when no question is left
+	}{
+		{"most informative at theta", pool, nil, 0, 3},
+		{"steeper item wins near its difficulty", pool, nil, 0.3, 3},
+		{"closest difficulty when far from the steep item", pool, nil, 1.8, 5},
+		{"low ability", pool, nil, -2.5, 1},
+		{"asked questions are not repeated", pool, []int{3}, 0, 2},
+		{"next best after several", pool, []int{3, 2}, 0, 4},
+		{"ties go to the first question", tied, nil, 0, 1},
+		{"ties skip asked questions", tied, []int{1}, 0, 2},
+		{"pool exhausted", pool, []int{1, 2, 3, 4, 5}, 0, 0},
+		{"empty pool", map[int]*models.Question{}, nil, 0, 0},
+	}
+
+	for _, test := range tests {
+		question := selectQuestion(test.pool, adaptiveSteps(test.used), test.theta)
+		selected := 0
+		if question != nil {
+			selected = question.ID
+		}
+		if selected != test.expected {
+			t.Errorf("selectQuestion(%s) = %d, expected %d", test.name, selected, test.expected)
+		}
+	}
+}
+
+func TestStopReason(t *testing.T) {
+	correct, wrong := true, false
+	settings := &models.AdaptiveSettings{MinQuestions: 2, MaxQuestions: 4, TargetSE: 0.5}
+	pool := adaptivePool([2]float64{-1, 1}, [2]float64{0, 1}, [2]float64{1, 1}, [2]float64{2, 1}, [2]float64{3, 1})
+
+	tests := []struct {
+		name     string
+		steps    []*models.AdaptiveStep
+		thetaSE  float64
+		pool     map[int]*models.Question
+		expected models.AdaptiveStopReason
+	}{
+		{"continue", adaptiveSteps([]int{1}, &correct), 0.8, pool, ""},
+		{"precise before the minimum", adaptiveSteps([]int{1}, &correct), 0.4, pool, ""},
+		{"precise after the minimum", adaptiveSteps([]int{1, 2}, &correct, &wrong), 0.4, pool, models.AdaptiveStopPrecision},
+		{"exactly the target", adaptiveSteps([]int{1, 2}, &correct, &wrong), 0.5, pool, models.AdaptiveStopPrecision},
+		{"imprecise after the minimum", adaptiveSteps([]int{1, 2, 3}, &correct, &wrong, &correct), 0.6, pool, ""},
+		{"maximum length", adaptiveSteps([]int{1, 2, 3, 4}, &correct, &wrong, &correct, &wrong), 0.6, pool, models.AdaptiveStopLength},
+		{"length before precision", adaptiveSteps([]int{1, 2, 3, 4}, &correct, &wrong, &correct, &wrong), 0.4, pool, models.AdaptiveStopLength},
+		{"unanswered steps do not count", adaptiveSteps([]int{1, 2}, &correct), 0.4, pool, ""},
+		{"pool exhausted", adaptiveSteps([]int{1, 2}, &correct, &wrong), 0.9, adaptivePool([2]float64{0, 1}, [2]float64{1, 1}), models.AdaptiveStopPoolExhausted},
+	}
+
+	for _, test := range tests {
+		state := &models.AdaptiveState{Steps: test.steps, ThetaSE: test.thetaSE}
+		reason := stopReason(settings, state, test.pool)
+		if reason != test.expected {
+			t.Errorf("stopReason(%s) = %q, expected %q", test.name, reason, test.expected)
+		}
+	}
+}
+
+func TestScoreAdaptive(t *testing.T) {
+	var pool []*models.Question
+	for _, question := range adaptivePool([2]float64{-1, 1}, [2]float64{0, 1}, [2]float64{1, 1}) {
+		pool = append(pool, question)
+	}
+	pool = append(pool, &models.Question{ID: 4}) // not calibrated, left out of the expected score
+
+	tests := []struct {
+		name         string
+		theta        *float64
+		passingTheta float64
+		percentage   float64
+		passed       bool
+	}{
+		{"average ability", floatPointer(0), 0, 50, true},
+		{"above the pass mark", floatPointer(1), 0.5, 70.3952, true},
+		{"below the pass mark", floatPointer(-0.5), 0, 39.4142, false},
+		{"high pass mark", floatPointer(1), 1.5, 70.3952, false},
+		{"no estimate", nil, -4, 80, false}, // the tallied percentage is kept
+	}
+
+	for _, test := range tests {
+		result := &models.TestResult{Percentage: 80, IsPassed: true, Theta: test.theta}
+		scoreAdaptive(result, pool, &models.AdaptiveSettings{PassingTheta: test.passingTheta})
+		if result.Percentage != test.percentage || result.IsPassed != test.passed {
+			t.Errorf("scoreAdaptive(%s) = %v%%, passed %v, expected %v%%, passed %v", test.name, result.Percentage, result.IsPassed, test.percentage, test.passed)
+		}
+	}
+}
+
+func TestAdaptiveSettingsPassingTheta(t *testing.T) {
+	tests := []struct {
+		passingTheta float64
+		expected     bool
+	}{
+		{0, true},
+		{-models.MaxAdaptivePassingTheta, true},
+		{models.MaxAdaptivePassingTheta, true},
+		{models.MaxAdaptivePassingTheta + 0.1, false},
+		{-models.MaxAdaptivePassingTheta - 0.1, false},
+	}
+
+	for _, test := range tests {
+		settings := models.DefaultAdaptiveSettings(1)
+		settings.PassingTheta = test.passingTheta
+		if settings.IsValid() != test.expected {
+			t.Errorf("IsValid(passing theta %v) = %v, expected %v", test.passingTheta, !test.expected, test.expected)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"math"
+	"sort"
+)
+
+// AnalysisService implements the models.AnalysisService interface
+type AnalysisService struct {
+	analysisRepo models.AnalysisRepository
+	testRepo     models.TestRepository
+	questionRepo models.QuestionRepository
+}
+
+// NewAnalysisService creates a new test analysis service
+func NewAnalysisService(analysisRepo models.AnalysisRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository) models.AnalysisService {
+	return &AnalysisService{
+		analysisRepo: analysisRepo,
+		testRepo:     testRepo,
+		questionRepo: questionRepo,
+	}
+}
+
+// candidateResponses holds the answers of one graded result
+type candidateResponses struct {
+	resultID   int
+	score      float64
+	percentage float64
+	answers    map[int]*models.ItemResponse // by question ID
+}
+
+// isCorrect checks if the candidate answered the question correctly
+func (c *candidateResponses) isCorrect(questionID int) bool {
+	answer, ok := c.answers[questionID]
+	return ok && !answer.IsOmitted() && answer.IsCorrect != nil && *answer.IsCorrect
+}
+
+// marksAwarded returns the marks the candidate was awarded for the question
+func (c *candidateResponses) marksAwarded(questionID int) float64 {
+	answer, ok := c.answers[questionID]
+	if !ok || answer.IsOmitted() {
+		return 0
+	}
+	return float64(answer.MarksAwarded)
+}
+
+// selected checks if the candidate chose the option
+func (c *candidateResponses) selected(questionID, optionID int) bool {
+	answer, ok := c.answers[questionID]
+	return ok && answer.SelectedOptionID != nil && *answer.SelectedOptionID == optionID
+}
+
+// GetItemAnalysis computes difficulty, discrimination, omit rates and distractor
+// statistics for every question of a test from its graded results
+func (s *AnalysisService) GetItemAnalysis(testID int) (*models.ItemAnalysis, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	questions, err := s.questionRepo.GetByTestID(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := s.analysisRepo.GetItemResponses(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := groupCandidates(responses)
+	groupSize := upperLowerGroupSize(len(candidates))
+	upper := candidates[:groupSize]
+	lower := candidates[len(candidates)-groupSize:]
+	mean, stdDev := scoreMoments(candidates)
+
+	analysis := &models.ItemAnalysis{
+		TestID:     testID,
+		Candidates: len(candidates),
+		GroupSize:  groupSize,
+		MeanScore:  roundStatistic(mean),
+		Items:      []*models.ItemStatistics{},
+	}
+
+	for _, question := range questions {
+		var options []*models.QuestionOption
+		if question.QuestionType == models.QuestionTypeMultipleChoice || question.QuestionType == models.QuestionTypeTrueFalse {
+			options, err = s.questionRepo.GetOptionsByQuestionID(question.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		item := analyzeItem(question, options, candidates, upper, lower, mean, stdDev)
+		analysis.Items = append(analysis.Items, item)
+	}
+
+	return analysis, nil
+}
+
+// GetReliabilityReport computes the score distribution, a histogram with the given
+// number of bins and the KR-20, Cronbach's alpha and SEM of a test from its graded results
+func (s *AnalysisService) GetReliabilityReport(testID int, bins int) (*models.ReliabilityReport, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	if bins <= 0 {
+		bins = models.DefaultHistogramBins
+	}
+	if bins > models.MaxHistogramBins {
+		bins = models.MaxHistogramBins
+	}
+
+	questions, err := s.questionRepo.GetByTestID(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := s.analysisRepo.GetItemResponses(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := groupCandidates(responses)
+	percentages := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		percentages[i] = candidate.percentage
+	}
+
+	report := &models.ReliabilityReport{
+		TestID:       testID,
+		Candidates:   len(candidates),
+		Items:        len(questions),
+		Distribution: scoreDistribution(percentages, bins),
+		Reliability:  &models.ReliabilityStats{},
+	}
+
+	report.Reliability.KR20 = internalConsistency(questions, candidates, func(c *candidateResponses, q *models.Question) float64 {
+		if c.isCorrect(q.ID) {
+			return 1
+		}
+		return 0
+	})
+	report.Reliability.CronbachAlpha = internalConsistency(questions, candidates, func(c *candidateResponses, q *models.Question) float64 {
+		return c.marksAwarded(q.ID)
+	})
+
+	report.Reliability.SEM = standardErrorOfMeasurement(percentages, report.Reliability.CronbachAlpha)
+
+	return report, nil
+}
+
+// scoreDistribution computes descriptive statistics and a histogram of percentage scores
+func scoreDistribution(percentages []float64, bins int) *models.ScoreDistribution {
+	sorted := append([]float64(nil), percentages...)
+	sort.Float64s(sorted)
+
+	mean, stdDev := moments(sorted)
+	distribution := &models.ScoreDistribution{
+		Mean:          roundStatistic(mean),
+		Median:        roundStatistic(quantile(sorted, 0.5)),
+		StdDev:        roundStatistic(stdDev),
+		LowerQuartile: roundStatistic(quantile(sorted, 0.25)),
+		UpperQuartile: roundStatistic(quantile(sorted, 0.75)),
+		Histogram:     make([]*models.HistogramBin, bins),
+	}
+	if len(sorted) > 0 {
+		distribution.Minimum = roundStatistic(sorted[0])
+		distribution.Maximum = roundStatistic(sorted[len(sorted)-1])
+	}
+
+	width := 100 / float64(bins)
+	for i := range distribution.Histogram {
+		distribution.Histogram[i] = &models.HistogramBin{
+			Lower: roundStatistic(float64(i) * width),
+			Upper: roundStatistic(float64(i+1) * width),
+		}
+	}
+	for _, percentage := range sorted {
+		i := int(percentage / width)
+		if i < 0 {
+			i = 0
+		}
+		if i >= bins {
+			i = bins - 1
+		}
+		distribution.Histogram[i].Count++
+	}
+	for _, bin := range distribution.Histogram {
+		if len(sorted) > 0 {
+			bin.Proportion = roundStatistic(float64(bin.Count) / float64(len(sorted)))
+		}
+	}
+
+	return distribution
+}
+
+// quantile returns the q-th quantile of sorted values, interpolating linearly between ranks
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// internalConsistency computes coefficient alpha for the given item scores:
+// k/(k-1) * (1 - sum of item variances / total score variance). With correct/incorrect
+// item scores this is KR-20. Returns nil with fewer than two items or no score variance.
+func internalConsistency(questions []*models.Question, candidates []*candidateResponses, itemScore func(*candidateResponses, *models.Question) float64) *float64 {
+	k := len(questions)
+	if k < 2 || len(candidates) < 2 {
+		return nil
+	}
+
+	totals := make([]float64, len(candidates))
+	var itemVariances float64
+	for _, question := range questions {
+		scores := make([]float64, len(candidates))
+		for i, candidate := range candidates {
+			scores[i] = itemScore(candidate, question)
+			totals[i] += scores[i]
+		}
+		_, stdDev := moments(scores)
+		itemVariances += stdDev * stdDev
+	}
+
+	_, totalStdDev := moments(totals)
+	if totalStdDev == 0 {
+		return nil
+	}
+
+	alpha := roundStatistic(float64(k) / float64(k-1) * (1 - itemVariances/(totalStdDev*totalStdDev)))
+	return &alpha
+}
+
+// groupCandidates collects the answers of each result, ordered from the highest score down
+func groupCandidates(responses []*models.ItemResponse) []*candidateResponses {
+	byResult := make(map[int]*candidateResponses)
+	var candidates []*candidateResponses
+
+	for _, response := range responses {
+		candidate, ok := byResult[response.ResultID]
+		if !ok {
+			candidate = &candidateResponses{
+				resultID:   response.ResultID,
+				score:      float64(response.MarksObtained),
+				percentage: response.Percentage,
+				answers:    make(map[int]*models.ItemResponse),
+			}
+			byResult[response.ResultID] = candidate
+			candidates = append(candidates, candidate)
+		}
+		if response.QuestionID != nil {
+			candidate.answers[*response.QuestionID] = response
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].resultID < candidates[j].resultID
+	})
+
+	return candidates
+}
+
+// standardErrorOfMeasurement returns the SEM of percentage scores, SD * sqrt(1 - reliability),
+// or nil when the reliability is unknown
+func standardErrorOfMeasurement(percentages []float64, reliability *float64) *float64 {
+	if reliability == nil {
+		return nil
+	}
+
+	_, stdDev := moments(percentages)
+	sem := roundStatistic(stdDev * math.Sqrt(1-*reliability))
+	return &sem
+}
+
+// upperLowerGroupSize returns the size of the upper and lower 27% groups
+func upperLowerGroupSize(candidates int) int {
+	size := int(math.Round(models.ItemAnalysisGroupFraction * float64(candidates)))
+	if size < 1 && candidates >= 2 {
+		size = 1
+	}
+	if size*2 > candidates {
+		size = candidates / 2
+	}
+	return size
+}
+
+// scoreMoments returns the mean and population standard deviation of the total scores
+func scoreMoments(candidates []*candidateResponses) (float64, float64) {
+	scores := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] = candidate.score
+	}
+	return moments(scores)
+}
+
+// moments returns the mean and population standard deviation of values
+func moments(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
+
+// analyzeItem computes the statistics of one question. Omitted answers count as incorrect.
+func analyzeItem(question *models.Question, options []*models.QuestionOption, candidates, upper, lower []*candidateResponses, mean, stdDev float64) *models.ItemStatistics {
+	item := &models.ItemStatistics{
+		QuestionID:   question.ID,
+		QuestionText: question.QuestionText,
+		QuestionType: question.QuestionType,
+		Marks:        question.Marks,
+	}
+
+	if len(candidates) == 0 {
+		return item
+	}
+
+	correct := 0
+	var correctScoreSum float64
+	for _, candidate := range candidates {
+		if answer, ok := candidate.answers[question.ID]; ok && !answer.IsOmitted() {
+			item.Responses++
+		}
+		if candidate.isCorrect(question.ID) {
+			correct++
+			correctScoreSum += candidate.score
+		}
+	}
+
+	total := float64(len(candidates))
+	p := float64(correct) / total
+	item.Omits = len(candidates) - item.Responses
+	item.OmitRate = roundStatistic(float64(item.Omits) / total)
+	item.Difficulty = roundStatistic(p)
+
+	// Point-biserial correlation between answering correctly and the total score
+	if stdDev > 0 && correct > 0 && correct < len(candidates) {
+		correctMean := correctScoreSum / float64(correct)
+		pointBiserial := roundStatistic((correctMean - mean) / stdDev * math.Sqrt(p/(1-p)))
+		item.PointBiserial = &pointBiserial
+	}
+
+	item.UpperDifficulty = roundStatistic(groupProportion(upper, func(c *candidateResponses) bool {
+		return c.isCorrect(question.ID)
+	}))
+	item.LowerDifficulty = roundStatistic(groupProportion(lower, func(c *candidateResponses) bool {
+		return c.isCorrect(question.ID)
+	}))
+	item.DiscriminationIndex = roundStatistic(item.UpperDifficulty - item.LowerDifficulty)
+
+	for _, option := range options {
+		chose := func(c *candidateResponses) bool {
+			return c.selected(question.ID, option.ID)
+		}
+
+		distractor := &models.DistractorStatistics{
+			OptionID:        option.ID,
+			OptionText:      option.OptionText,
+			IsCorrect:       option.IsCorrect,
+			Count:           groupCount(candidates, chose),
+			UpperCount:      groupCount(upper, chose),
+			LowerCount:      groupCount(lower, chose),
+			UpperProportion: roundStatistic(groupProportion(upper, chose)),
+			LowerProportion: roundStatistic(groupProportion(lower, chose)),
+		}
+		distractor.Proportion = roundStatistic(float64(distractor.Count) / total)
+		item.Distractors = append(item.Distractors, distractor)
+	}
+
+	return item
+}
+
+// groupCount counts the candidates of a group that match
+func groupCount(group []*candidateResponses, match func(*candidateResponses) bool) int {
+	count := 0
+	for _, candidate := range group {
+		if match(candidate) {
+			count++
+		}
+	}
+	return count
+}
+
+// groupProportion returns the share of a group that matches, or 0 for an empty group
+func groupProportion(group []*candidateResponses, match func(*candidateResponses) bool) float64 {
+	if len(group) == 0 {
+		return 0
+	}
+	return float64(groupCount(group, match)) / float64(len(group))
+}
+
+// roundStatistic rounds a statistic to four decimal places
+func roundStatistic(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
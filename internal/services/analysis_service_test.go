@@ -0,0 +1,281 @@
+package services
+
+import (
+	"gocbt/internal/models"
+	"strconv"
+	"testing"
+)
+
+// analysisMarks are the marks of the four questions of the analysis fixture
+var analysisMarks = []int{1, 1, 1, 2}
+
+// analysisMatrix is a small graded test worked by hand: 1 correct, 0 incorrect,
+// -1 omitted and -2 never answered, by result ID and question. Total scores are
+// 5, 3, 4, 1, 1 and 0 out of 5.
+var analysisMatrix = map[int][]int{
+	1: {1, 1, 1, 1},
+	2: {1, 1, 1, 0},
+	3: {1, 1, 0, 1},
+	4: {1, 0, 0, -1},
+	5: {0, 1, 0, 0},
+	6: {0, 0, -2, 0},
+}
+
+// analysisFixture returns the questions of the fixture and its candidates, ordered from
+// the highest score down. Question j has ID j+1, and its option 10*(j+1)+1 is correct.
+func analysisFixture() ([]*models.Question, []*candidateResponses) {
+	questions := make([]*models.Question, len(analysisMarks))
+	for j, marks := range analysisMarks {
+		questions[j] = &models.Question{ID: j + 1, QuestionType: models.QuestionTypeMultipleChoice, Marks: marks, OrderIndex: j}
+	}
+
+	var responses []*models.ItemResponse
+	for resultID := 1; resultID <= len(analysisMatrix); resultID++ {
+		row := analysisMatrix[resultID]
+		score := 0
+		for j, answer := range row {
+			if answer == 1 {
+				score += analysisMarks[j]
+			}
+		}
+
+		for j, answer := range row {
+			if answer == -2 {
+				continue
+			}
+			questionID := j + 1
+			correct := answer == 1
+			response := &models.ItemResponse{
+				ResultID:      resultID,
+				MarksObtained: score,
+				Percentage:    float64(score) / 5 * 100,
+				QuestionID:    &questionID,
+				IsCorrect:     &correct,
+			}
+			if answer >= 0 {
+				optionID := 10*questionID + 2
+				if correct {
+					optionID = 10*questionID + 1
+					response.MarksAwarded = analysisMarks[j]
+				}
+				response.SelectedOptionID = &optionID
+			}
+			responses = append(responses, response)
+		}
+	}
+
+	return questions, groupCandidates(responses)
+}
+
+func TestGroupCandidatesOrder(t *testing.T) {
+	_, candidates := analysisFixture()
+
+	expected := []int{1, 3, 2, 4, 5, 6} // ties in score keep result ID order
+	for i, candidate := range candidates {
+		if candidate.resultID != expected[i] {
+			t.Errorf("groupCandidates() position %d = result %d, expected result %d", i, candidate.resultID, expected[i])
+		}
+	}
+}
+
+func TestUpperLowerGroupSize(t *testing.T) {
+	tests := []struct {
+		candidates int
+		expected   int
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 1},
+		{3, 1},
+		{6, 2},
+		{10, 3},
+		{100, 27},
+	}
+
+	for _, test := range tests {
+		result := upperLowerGroupSize(test.candidates)
+		if result != test.expected {
+			t.Errorf("upperLowerGroupSize(%d) = %d, expected %d", test.candidates, result, test.expected)
+		}
+	}
+}
+
+func TestAnalyzeItem(t *testing.T) {
+	questions, candidates := analysisFixture()
+	groupSize := upperLowerGroupSize(len(candidates))
+	upper, lower := candidates[:groupSize], candidates[len(candidates)-groupSize:]
+	mean, stdDev := scoreMoments(candidates)
+
+	tests := []struct {
+		question        int
+		difficulty      float64
+		pointBiserial   float64
+		upperDifficulty float64
+		lowerDifficulty float64
+		discrimination  float64
+		omits           int
+		omitRate        float64
+	}{
+		{1, 0.6667, 0.7222, 1, 0, 1, 0, 0},
+		{2, 0.6667, 0.7222, 1, 0.5, 0.5, 0, 0},
+		{3, 0.3333, 0.6565, 0.5, 0, 0.5, 1, 0.1667},
+		{4, 0.3333, 0.8535, 1, 0, 1, 1, 0.1667},
+	}
+
+	for _, test := range tests {
+		item := analyzeItem(questions[test.question-1], nil, candidates, upper, lower, mean, stdDev)
+		if item.Difficulty != test.difficulty {
+			t.Errorf("analyzeItem(Q%d) difficulty = %v, expected %v", test.question, item.Difficulty, test.difficulty)
+		}
+		if item.PointBiserial == nil || *item.PointBiserial != test.pointBiserial {
+			t.Errorf("analyzeItem(Q%d) point-biserial = %v, expected %v", test.question, item.PointBiserial, test.pointBiserial)
+		}
+		if item.UpperDifficulty != test.upperDifficulty || item.LowerDifficulty != test.lowerDifficulty ||
+			item.DiscriminationIndex != test.discrimination {
+			t.Errorf("analyzeItem(Q%d) upper, lower, discrimination = %v, %v, %v, expected %v, %v, %v", test.question,
+				item.UpperDifficulty, item.LowerDifficulty, item.DiscriminationIndex, test.upperDifficulty, test.lowerDifficulty, test.discrimination)
+		}
+		if item.Omits != test.omits || item.OmitRate != test.omitRate || item.Responses != len(candidates)-test.omits {
+			t.Errorf("analyzeItem(Q%d) omits = %d (%v), expected %d (%v)", test.question, item.Omits, item.OmitRate, test.omits, test.omitRate)
+		}
+	}
+}
+
+func TestAnalyzeItemDistractors(t *testing.T) {
+	questions, candidates := analysisFixture()
+	upper, lower := candidates[:2], candidates[4:]
+	mean, stdDev := scoreMoments(candidates)
+	options := []*models.QuestionOption{
+		{ID: 21, OptionText: "right", IsCorrect: true},
+		{ID: 22, OptionText: "wrong"},
+	}
+
+	item := analyzeItem(questions[1], options, candidates, upper, lower, mean, stdDev)
+
+	tests := []struct {
+		count, upperCount, lowerCount int
+		proportion                    float64
+	}{
+		{4, 2, 1, 0.6667},
+		{2, 0, 1, 0.3333},
+	}
+	for i, test := range tests {
+		distractor := item.Distractors[i]
+		if distractor.Count != test.count || distractor.UpperCount != test.upperCount ||
+			distractor.LowerCount != test.lowerCount || distractor.Proportion != test.proportion {
+			t.Errorf("analyzeItem() option %d = %+v, expected %+v", distractor.OptionID, *distractor, test)
+		}
+	}
+}
+
+func TestAnalyzeItemWithoutVariance(t *testing.T) {
+	questions, candidates := analysisFixture()
+	mean, stdDev := scoreMoments(candidates)
+
+	// Mark every answer to question 1 wrong, so nobody answered it correctly
+	for _, candidate := range candidates {
+		if answer, ok := candidate.answers[1]; ok {
+			wrong := false
+			answer.IsCorrect = &wrong
+		}
+	}
+
+	item := analyzeItem(questions[0], nil, candidates, candidates[:2], candidates[4:], mean, stdDev)
+	if item.Difficulty != 0 || item.PointBiserial != nil {
+		t.Errorf("analyzeItem() = difficulty %v, point-biserial %v, expected 0 and nil", item.Difficulty, item.PointBiserial)
+	}
+
+	empty := analyzeItem(questions[0], nil, nil, nil, nil, 0, 0)
+	if empty.Difficulty != 0 || empty.PointBiserial != nil || empty.Responses != 0 {
+		t.Errorf("analyzeItem(no candidates) = %+v, expected empty statistics", *empty)
+	}
+}
+
+func TestInternalConsistency(t *testing.T) {
+	questions, candidates := analysisFixture()
+	correct := func(c *candidateResponses, q *models.Question) float64 {
+		if c.isCorrect(q.ID) {
+			return 1
+		}
+		return 0
+	}
+	marks := func(c *candidateResponses, q *models.Question) float64 {
+		return c.marksAwarded(q.ID)
+	}
+
+	tests := []struct {
+		name       string
+		questions  []*models.Question
+		candidates []*candidateResponses
+		itemScore  func(*candidateResponses, *models.Question) float64
+		expected   *float64
+	}{
+		// Item variances 4 * 2/9 over a total score variance of 2
+		{"KR-20", questions, candidates, correct, floatPointer(0.7407)},
+		// Item variances 3 * 2/9 + 8/9 over a total score variance of 29/9
+		{"Cronbach's alpha", questions, candidates, marks, floatPointer(0.6897)},
+		{"one item", questions[:1], candidates, correct, nil},
+		{"one candidate", questions, candidates[:1], correct, nil},
+		{"no score variance", questions, candidates[3:5], marks, nil}, // results 4 and 5 both scored 1
+	}
+
+	for _, test := range tests {
+		result := internalConsistency(test.questions, test.candidates, test.itemScore)
+		if (result == nil) != (test.expected == nil) || (result != nil && *result != *test.expected) {
+			t.Errorf("internalConsistency(%s) = %v, expected %v", test.name, formatFloatPointer(result), formatFloatPointer(test.expected))
+		}
+	}
+}
+
+func TestStandardErrorOfMeasurement(t *testing.T) {
+	// Percentages 100, 60, 80, 20, 20 and 0 have a standard deviation of 35.9011
+	percentages := []float64{100, 60, 80, 20, 20, 0}
+
+	tests := []struct {
+		name        string
+		reliability *float64
+		expected    *float64
+	}{
+		{"fixture alpha", floatPointer(0.6897), floatPointer(19.9986)},
+		{"perfect reliability", floatPointer(1), floatPointer(0)},
+		{"no reliability", floatPointer(0), floatPointer(35.9011)},
+		{"unknown reliability", nil, nil},
+	}
+
+	for _, test := range tests {
+		result := standardErrorOfMeasurement(percentages, test.reliability)
+		if (result == nil) != (test.expected == nil) || (result != nil && *result != *test.expected) {
+			t.Errorf("standardErrorOfMeasurement(%s) = %v, expected %v", test.name, formatFloatPointer(result), formatFloatPointer(test.expected))
+		}
+	}
+}
+
+func TestScoreDistribution(t *testing.T) {
+	distribution := scoreDistribution([]float64{100, 60, 80, 20, 20, 0}, 5)
+
+	if distribution.Mean != 46.6667 || distribution.Median != 40 || distribution.StdDev != 35.9011 ||
+		distribution.LowerQuartile != 20 || distribution.UpperQuartile != 75 ||
+		distribution.Minimum != 0 || distribution.Maximum != 100 {
+		t.Errorf("scoreDistribution() = %+v, expected mean 46.6667, median 40, SD 35.9011, quartiles 20 and 75, range 0-100", *distribution)
+	}
+
+	expectedCounts := []int{1, 2, 0, 1, 2} // 100% falls in the last bin
+	for i, bin := range distribution.Histogram {
+		if bin.Count != expectedCounts[i] || bin.Lower != float64(20*i) || bin.Upper != float64(20*(i+1)) {
+			t.Errorf("scoreDistribution() bin %d = %+v, expected %d in %d-%d", i, *bin, expectedCounts[i], 20*i, 20*(i+1))
+		}
+	}
+}
+
+// floatPointer returns a pointer to a statistic
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+// formatFloatPointer formats an optional statistic for test messages
+func formatFloatPointer(value *float64) string {
+	if value == nil {
+		return "nil"
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
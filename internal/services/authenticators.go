@@ -0,0 +1,257 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/models"
+	"strings"
+	"time"
+)
+
+// LocalAuthenticator checks passwords against the hashes stored with local accounts
+type LocalAuthenticator struct {
+	userRepo        models.UserRepository
+	passwordManager *auth.PasswordManager
+	allowedRoles    []models.UserRole
+}
+
+// NewLocalAuthenticator creates a new local password authenticator. When allowed roles are
+// given, only accounts with one of those roles may sign in with a local password.
+func NewLocalAuthenticator(userRepo models.UserRepository, passwordManager *auth.PasswordManager, allowedRoles []models.UserRole) models.Authenticator {
+	return &LocalAuthenticator{
+		userRepo:        userRepo,
+		passwordManager: passwordManager,
+		allowedRoles:    allowedRoles,
+	}
+}
+
+// Authenticate verifies a username and password against the local account. Unknown
+// usernames take as long to reject as wrong passwords, and whether an account is active is
+// only reported once its password is right, so that failures do not reveal which accounts
+// exist.
+func (a *LocalAuthenticator) Authenticate(username, password string) (*models.User, error) {
+	user, err := a.userRepo.GetByUsername(username)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Accounts without a password, or that must sign in through the directory, are unknown here
+	if user == nil || user.PasswordHash == "" || !a.isAllowed(user.Role) {
+		a.passwordManager.SimulateVerification(password)
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	// Verify password
+	if !a.passwordManager.VerifyPassword(password, user.PasswordHash) {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	// Check if user is active
+	if !user.IsActive {
+		return nil, auth.ErrUserNotActive
+	}
+
+	return user, nil
+}
+
+// isAllowed checks if accounts with the role may sign in with a local password
+func (a *LocalAuthenticator) isAllowed(role models.UserRole) bool {
+	if len(a.allowedRoles) == 0 {
+		return true
+	}
+	for _, allowed := range a.allowedRoles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// DirectoryAuthenticator checks passwords against an LDAP or Active Directory server. On
+// each sign-in the account's name, email, role and directory-linked classes are brought
+// in line with the directory.
+type DirectoryAuthenticator struct {
+	client       *auth.LDAPClient
+	userRepo     models.UserRepository
+	identityRepo models.IdentityRepository
+	groupRepo    models.GroupRepository
+	cfg          *config.LDAPConfig
+	roleMapping  map[string]string
+}
+
+// NewDirectoryAuthenticator creates a new directory authenticator
+func NewDirectoryAuthenticator(client *auth.LDAPClient, userRepo models.UserRepository, identityRepo models.IdentityRepository,
+	groupRepo models.GroupRepository, cfg *config.LDAPConfig) models.Authenticator {
+	// Group names from the directory are lowercased, so the mapping is too
+	roleMapping := make(map[string]string, len(cfg.RoleMapping))
+	for group, role := range cfg.RoleMapping {
+		roleMapping[strings.ToLower(group)] = role
+	}
+
+	return &DirectoryAuthenticator{
+		client:       client,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		groupRepo:    groupRepo,
+		cfg:          cfg,
+		roleMapping:  roleMapping,
+	}
+}
+
+// Authenticate verifies a username and password against the directory and returns the
+// linked account, creating it on first sign-in if configured
+func (a *DirectoryAuthenticator) Authenticate(username, password string) (*models.User, error) {
+	identity, err := a.client.Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := a.client.Issuer()
+	linked, err := a.identityRepo.GetIdentity(issuer, identity.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var user *models.User
+	if linked != nil {
+		user, err = a.userRepo.GetByID(linked.UserID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if user == nil {
+			return nil, auth.ErrDirectoryAccountNotFound
+		}
+	} else {
+		user, err = a.linkUser(identity)
+		if err != nil {
+			return nil, err
+		}
+
+		linked = &models.UserIdentity{
+			UserID:  user.ID,
+			Issuer:  issuer,
+			Subject: identity.ID,
+			Email:   identity.Email,
+		}
+		if err := a.identityRepo.CreateIdentity(linked); err != nil {
+			return nil, err
+		}
+	}
+
+	if !user.IsActive {
+		return nil, auth.ErrUserNotActive
+	}
+
+	if err := a.syncUser(user, identity); err != nil {
+		return nil, err
+	}
+
+	if err := a.groupRepo.SyncDirectoryMembers(user.ID, identity.Groups); err != nil {
+		return nil, err
+	}
+
+	if err := a.identityRepo.TouchIdentity(linked.ID, identity.Email, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// linkUser finds or creates the account for a directory user's first sign-in
+func (a *DirectoryAuthenticator) linkUser(identity *auth.LDAPIdentity) (*models.User, error) {
+	username := usernameFrom(identity.Username)
+	if len(username) < 3 {
+		return nil, auth.ErrDirectoryAccountNotFound
+	}
+
+	existing, err := a.userRepo.GetByUsername(username)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	switch {
+	case existing != nil && a.cfg.LinkByUsername:
+		return existing, nil
+	case existing != nil:
+		// Without linking, signing in would take over an unrelated local account
+		return nil, auth.ErrDirectoryAccountConflict
+	case !a.cfg.AutoProvision:
+		return nil, auth.ErrDirectoryAccountNotFound
+	}
+
+	email := strings.TrimSpace(identity.Email)
+	if email == "" {
+		// Email addresses are required and unique; .invalid can never be delivered to
+		email = username + "@directory.invalid"
+	}
+	taken, err := a.userRepo.GetByEmail(email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if taken != nil {
+		return nil, auth.ErrEmailExists
+	}
+
+	firstName := truncate(strings.TrimSpace(identity.FirstName), 50)
+	if firstName == "" {
+		firstName = username
+	}
+
+	// The account has no password, so it can only sign in through the directory
+	user := &models.User{
+		Username:  username,
+		Email:     email,
+		FirstName: firstName,
+		LastName:  truncate(strings.TrimSpace(identity.LastName), 50),
+		Role:      mapRole(identity.Groups, a.roleMapping, models.UserRole(a.cfg.DefaultRole)),
+		IsActive:  true,
+	}
+
+	if err := a.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// syncUser updates the account's name, email and role from the directory, saving it only
+// when something changed
+func (a *DirectoryAuthenticator) syncUser(user *models.User, identity *auth.LDAPIdentity) error {
+	changed := false
+
+	if firstName := truncate(strings.TrimSpace(identity.FirstName), 50); firstName != "" && firstName != user.FirstName {
+		user.FirstName = firstName
+		changed = true
+	}
+	if lastName := truncate(strings.TrimSpace(identity.LastName), 50); lastName != "" && lastName != user.LastName {
+		user.LastName = lastName
+		changed = true
+	}
+
+	if email := strings.TrimSpace(identity.Email); email != "" && !strings.EqualFold(email, user.Email) {
+		taken, err := a.userRepo.GetByEmail(email)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		// Keep the current address rather than fail the sign-in over another account's
+		if taken == nil {
+			user.Email = email
+			user.EmailVerified = false
+			changed = true
+		}
+	}
+
+	// Roles are only managed by the directory when a mapping is configured
+	if len(a.roleMapping) > 0 {
+		if role := mapRole(identity.Groups, a.roleMapping, models.UserRole(a.cfg.DefaultRole)); role != user.Role {
+			user.Role = role
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return a.userRepo.Update(user)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/auth/ldaptest"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"testing"
+)
+
+const ldapPeopleDN = "ou=people,dc=school,dc=example"
+
+// directoryTestEnv is a user service signing in against an in-process directory, with
+// local passwords allowed for administrators, backed by a fresh SQLite database
+type directoryTestEnv struct {
+	directory *ldaptest.Server
+	cfg       *config.LDAPConfig
+	userRepo  models.UserRepository
+	groupRepo models.GroupRepository
+	users     models.UserService
+	passwords *auth.PasswordManager
+}
+
+func newDirectoryTestEnv(t *testing.T) *directoryTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	directory := ldaptest.NewServer()
+	t.Cleanup(directory.Close)
+
+	cfg := &config.LDAPConfig{
+		URL:                directory.URL(),
+		BaseDN:             "dc=school,dc=example",
+		UserFilter:         "(uid={username})",
+		IDAttribute:        "entryUUID",
+		UsernameAttribute:  "uid",
+		EmailAttribute:     "mail",
+		FirstNameAttribute: "givenName",
+		LastNameAttribute:  "sn",
+		GroupAttribute:     "memberOf",
+		AutoProvision:      true,
+		RoleMapping:        map[string]string{"Teachers": "teacher"},
+		DefaultRole:        "student",
+	}
+
+	userRepo := database.NewUserRepository(db)
+	groupRepo := database.NewGroupRepository(db)
+	passwords := auth.NewPasswordManager()
+	users := NewUserService(userRepo, passwords,
+		NewDirectoryAuthenticator(auth.NewLDAPClient(cfg, nil), userRepo, database.NewIdentityRepository(db), groupRepo, cfg),
+		NewLocalAuthenticator(userRepo, passwords, []models.UserRole{models.RoleAdmin}),
+	)
+
+	return &directoryTestEnv{
+		directory: directory,
+		cfg:       cfg,
+		userRepo:  userRepo,
+		groupRepo: groupRepo,
+		users:     users,
+		passwords: passwords,
+	}
+}
+
+// addDirectoryUser adds a user to the directory with the given group common names
+func (e *directoryTestEnv) addDirectoryUser(uid, password string, groups ...string) string {
+	dn := "uid=" + uid + "," + ldapPeopleDN
+
+	memberOf := make([]string, 0, len(groups))
+	for _, group := range groups {
+		memberOf = append(memberOf, "cn="+group+",ou=groups,dc=school,dc=example")
+	}
+
+	e.directory.AddEntry(dn, password, map[string][]string{
+		"uid":       {uid},
+		"entryUUID": {"uuid-" + uid},
+		"mail":      {uid + "@school.example"},
+		"givenName": {"First " + uid},
+		"sn":        {"Last " + uid},
+		"memberOf":  memberOf,
+	})
+	return dn
+}
+
+// addLocalUser creates a local account with a password
+func (e *directoryTestEnv) addLocalUser(t *testing.T, username, password string, role models.UserRole) *models.User {
+	t.Helper()
+
+	hash, err := e.passwords.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	user := &models.User{
+		Username:     username,
+		Email:        username + "@local.example",
+		PasswordHash: hash,
+		FirstName:    "Local",
+		LastName:     username,
+		Role:         role,
+		IsActive:     true,
+	}
+	if err := e.userRepo.Create(user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return user
+}
+
+// linkedClass creates a class linked to a directory group
+func (e *directoryTestEnv) linkedClass(t *testing.T, ownerID int, directoryGroup string) *models.Group {
+	t.Helper()
+
+	group := &models.Group{Name: directoryGroup, Kind: models.GroupKindClass, OwnerID: ownerID}
+	if err := e.groupRepo.Create(group); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := e.groupRepo.SetDirectoryGroup(group.ID, &directoryGroup); err != nil {
+		t.Fatalf("SetDirectoryGroup() error = %v", err)
+	}
+	return group
+}
+
+// memberGroups returns the IDs of the groups a user belongs to
+func (e *directoryTestEnv) memberGroups(t *testing.T, userID int) map[int]bool {
+	t.Helper()
+
+	groups, err := e.groupRepo.GetByMember(userID)
+	if err != nil {
+		t.Fatalf("GetByMember() error = %v", err)
+	}
+
+	ids := make(map[int]bool, len(groups))
+	for _, group := range groups {
+		ids[group.ID] = true
+	}
+	return ids
+}
+
+func TestDirectoryLoginProvisionsUser(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	env.addDirectoryUser("jdoe", "directory-secret", "Teachers")
+
+	user, err := env.users.Login("jdoe", "directory-secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if user.Username != "jdoe" || user.Email != "jdoe@school.example" || user.FirstName != "First jdoe" {
+		t.Errorf("Login() provisioned %+v", user)
+	}
+	if user.Role != models.RoleTeacher {
+		t.Errorf("Login() role = %s, expected %s", user.Role, models.RoleTeacher)
+	}
+	if user.PasswordHash != "" {
+		t.Error("provisioned account has a local password")
+	}
+
+	again, err := env.users.Login("jdoe", "directory-secret")
+	if err != nil {
+		t.Fatalf("second Login() error = %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("second Login() returned user %d, expected %d", again.ID, user.ID)
+	}
+
+	if _, err := env.users.Login("jdoe", "wrong"); err != auth.ErrInvalidCredentials {
+		t.Errorf("Login() with wrong password error = %v, expected %v", err, auth.ErrInvalidCredentials)
+	}
+}
+
+func TestDirectoryLoginSyncsRoleAndProfile(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	dn := env.addDirectoryUser("jdoe", "directory-secret", "Teachers")
+
+	if _, err := env.users.Login("jdoe", "directory-secret"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	env.directory.SetAttribute(dn, "memberOf")
+	env.directory.SetAttribute(dn, "sn", "Married")
+
+	user, err := env.users.Login("jdoe", "directory-secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if user.Role != models.RoleStudent || user.LastName != "Married" {
+		t.Errorf("Login() = role %s, last name %q; expected student, Married", user.Role, user.LastName)
+	}
+
+	stored, err := env.userRepo.GetByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.Role != models.RoleStudent || stored.LastName != "Married" {
+		t.Errorf("stored user = role %s, last name %q; expected student, Married", stored.Role, stored.LastName)
+	}
+}
+
+func TestDirectoryLoginSyncsClasses(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	teacher := env.addLocalUser(t, "teacher", "Teacher-secret-1", models.RoleTeacher)
+	maths := env.linkedClass(t, teacher.ID, "year 10 maths")
+	science := env.linkedClass(t, teacher.ID, "year 10 science")
+
+	manual := &models.Group{Name: "Chess club", Kind: models.GroupKindGroup, OwnerID: teacher.ID}
+	if err := env.groupRepo.Create(manual); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	dn := env.addDirectoryUser("student1", "directory-secret", "Year 10 Maths", "Year 10 Science")
+	user, err := env.users.Login("student1", "directory-secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if err := env.groupRepo.AddMember(manual.ID, user.ID); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+
+	groups := env.memberGroups(t, user.ID)
+	if !groups[maths.ID] || !groups[science.ID] {
+		t.Fatalf("member of %v, expected both linked classes", groups)
+	}
+
+	// Leaving a directory group removes the class but keeps hand-managed groups
+	env.directory.SetAttribute(dn, "memberOf", "cn=Year 10 Maths,ou=groups,dc=school,dc=example")
+	if _, err := env.users.Login("student1", "directory-secret"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	groups = env.memberGroups(t, user.ID)
+	if !groups[maths.ID] || groups[science.ID] || !groups[manual.ID] {
+		t.Errorf("member of %v, expected maths and chess club only", groups)
+	}
+}
+
+func TestLocalLoginFallback(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	env.addLocalUser(t, "admin", "Admin-secret-1", models.RoleAdmin)
+	env.addLocalUser(t, "localteacher", "Teacher-secret-1", models.RoleTeacher)
+
+	user, err := env.users.Login("admin", "Admin-secret-1")
+	if err != nil {
+		t.Fatalf("Login() for local admin error = %v", err)
+	}
+	if user.Role != models.RoleAdmin {
+		t.Errorf("Login() role = %s, expected admin", user.Role)
+	}
+
+	// Other roles must sign in through the directory
+	if _, err := env.users.Login("localteacher", "Teacher-secret-1"); err != auth.ErrInvalidCredentials {
+		t.Errorf("Login() for local teacher error = %v, expected %v", err, auth.ErrInvalidCredentials)
+	}
+}
+
+func TestLocalAdminLoginWhileDirectoryIsDown(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	env.addLocalUser(t, "admin", "Admin-secret-1", models.RoleAdmin)
+	env.addDirectoryUser("jdoe", "directory-secret")
+	env.directory.Close()
+
+	if _, err := env.users.Login("admin", "Admin-secret-1"); err != nil {
+		t.Errorf("Login() for local admin error = %v", err)
+	}
+
+	if _, err := env.users.Login("jdoe", "directory-secret"); err != auth.ErrDirectoryUnavailable {
+		t.Errorf("Login() for directory user error = %v, expected %v", err, auth.ErrDirectoryUnavailable)
+	}
+}
+
+func TestDirectoryLoginDoesNotTakeOverLocalAccount(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	env.addLocalUser(t, "jdoe", "Local-secret-1", models.RoleStudent)
+	env.addDirectoryUser("jdoe", "directory-secret")
+
+	if _, err := env.users.Login("jdoe", "directory-secret"); err != auth.ErrDirectoryAccountConflict {
+		t.Errorf("Login() error = %v, expected %v", err, auth.ErrDirectoryAccountConflict)
+	}
+
+	env.cfg.LinkByUsername = true
+	user, err := env.users.Login("jdoe", "directory-secret")
+	if err != nil {
+		t.Fatalf("Login() with linking error = %v", err)
+	}
+	if user.Email != "jdoe@school.example" {
+		t.Errorf("linked account email = %q, expected it synced from the directory", user.Email)
+	}
+}
+
+func TestDirectoryLoginWithoutProvisioning(t *testing.T) {
+	env := newDirectoryTestEnv(t)
+	env.cfg.AutoProvision = false
+	env.addDirectoryUser("jdoe", "directory-secret")
+
+	if _, err := env.users.Login("jdoe", "directory-secret"); err != auth.ErrDirectoryAccountNotFound {
+		t.Errorf("Login() error = %v, expected %v", err, auth.ErrDirectoryAccountNotFound)
+	}
+}
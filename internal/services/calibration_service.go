@@ -0,0 +1,285 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"strings"
+	"time"
+)
+
+// CalibrationService implements the models.CalibrationService interface
+type CalibrationService struct {
+	analysisRepo     models.AnalysisRepository
+	testRepo         models.TestRepository
+	questionRepo     models.QuestionRepository
+	resultRepo       models.TestResultRepository
+	integrityService models.IntegrityService
+}
+
+// NewCalibrationService creates a new IRT calibration service
+func NewCalibrationService(analysisRepo models.AnalysisRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository, resultRepo models.TestResultRepository, integrityService models.IntegrityService) models.CalibrationService {
+	return &CalibrationService{
+		analysisRepo:     analysisRepo,
+		testRepo:         testRepo,
+		questionRepo:     questionRepo,
+		resultRepo:       resultRepo,
+		integrityService: integrityService,
+	}
+}
+
+// CalibrateTest estimates IRT parameters for the questions of a test from its graded
+// results, stores them with the questions and re-scores the theta of every result
+func (s *CalibrationService) CalibrateTest(testID int, model models.IRTModel, minResponses int) (*models.CalibrationReport, error) {
+	if !model.IsValid() {
+		return nil, auth.ErrInvalidIRTModel
+	}
+
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	// Adaptive candidates skip most questions, which would read as wrong answers;
+	// adaptive pools are calibrated from a pilot test instead
+	if test.IsAdaptive() {
+		return nil, auth.ErrAdaptiveCalibration
+	}
+
+	questions, err := s.questionRepo.GetByTestID(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := s.analysisRepo.GetItemResponses(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := groupCandidates(responses)
+	if len(candidates) == 0 || len(candidates) < minResponses || len(questions) < 2 {
+		return nil, auth.ErrNotEnoughResponses
+	}
+
+	// Score every answer as correct or incorrect; omitted questions are incorrect
+	matrix := make([][]float64, len(candidates))
+	for i, candidate := range candidates {
+		matrix[i] = make([]float64, len(questions))
+		for j, question := range questions {
+			if candidate.isCorrect(question.ID) {
+				matrix[i][j] = 1
+			}
+		}
+	}
+
+	estimates, iterations, converged := calibrateItems(matrix, model)
+	report := &models.CalibrationReport{
+		TestID:     testID,
+		Model:      model,
+		Candidates: len(candidates),
+		Iterations: iterations,
+		Converged:  converged,
+	}
+
+	now := time.Now()
+	for j, question := range questions {
+		if estimates[j] == nil {
+			report.Skipped++
+			continue
+		}
+
+		params := &models.ItemParameters{
+			Model:          model,
+			Difficulty:     roundStatistic(estimates[j].difficulty),
+			Discrimination: roundStatistic(estimates[j].discrimination),
+			Responses:      len(candidates),
+			CalibratedAt:   now,
+		}
+		if err := s.questionRepo.UpdateIRTParameters(question.ID, params); err != nil {
+			return nil, err
+		}
+		question.IRT = params
+		report.Calibrated++
+	}
+
+	// Re-score every result on the new scale
+	for _, candidate := range candidates {
+		theta, thetaSE := estimateTheta(questions, candidate.isCorrect)
+		if theta == nil {
+			continue
+		}
+		if err := s.resultRepo.UpdateAbility(candidate.resultID, theta, thetaSE); err != nil {
+			return nil, err
+		}
+		if err := s.integrityService.RecordResult(candidate.resultID, models.LedgerActionUpdate, models.LedgerReasonCalibrated); err != nil {
+			fmt.Printf("Warning: Failed to record re-scored result %d in the ledger: %v\n", candidate.resultID, err)
+		}
+		report.Scored++
+	}
+
+	return report, nil
+}
+
+// CalibrateFromPilot calibrates the question pool of an adaptive test from a graded pilot
+// test: the pilot is calibrated from its results, and each pool question takes the
+// parameters of the pilot question with the same type and text. The ability of the
+// adaptive test's results is then re-scored on the questions each candidate was asked.
+func (s *CalibrationService) CalibrateFromPilot(testID, pilotTestID int, model models.IRTModel, minResponses int) (*models.CalibrationReport, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	if !test.IsAdaptive() {
+		return nil, auth.ErrNotAdaptiveTest
+	}
+
+	pilotReport, err := s.CalibrateTest(pilotTestID, model, minResponses)
+	if err != nil {
+		return nil, err
+	}
+
+	pilotQuestions, err := s.questionRepo.GetByTestID(pilotTestID)
+	if err != nil {
+		return nil, err
+	}
+	pilotParams := make(map[string]*models.ItemParameters, len(pilotQuestions))
+	for _, question := range pilotQuestions {
+		if question.IRT != nil {
+			pilotParams[pilotQuestionKey(question)] = question.IRT
+		}
+	}
+
+	questions, err := s.questionRepo.GetByTestID(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.CalibrationReport{
+		TestID:     testID,
+		Model:      model,
+		Candidates: pilotReport.Candidates,
+		Iterations: pilotReport.Iterations,
+		Converged:  pilotReport.Converged,
+	}
+
+	for _, question := range questions {
+		params, ok := pilotParams[pilotQuestionKey(question)]
+		if !ok {
+			report.Skipped++
+			continue
+		}
+
+		copied := *params
+		if err := s.questionRepo.UpdateIRTParameters(question.ID, &copied); err != nil {
+			return nil, err
+		}
+		question.IRT = &copied
+		report.Calibrated++
+	}
+
+	responses, err := s.analysisRepo.GetItemResponses(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-score every result on the questions its candidate was asked
+	for _, candidate := range groupCandidates(responses) {
+		var administered []*models.Question
+		for _, question := range questions {
+			if _, ok := candidate.answers[question.ID]; ok {
+				administered = append(administered, question)
+			}
+		}
+
+		theta, thetaSE := estimateTheta(administered, candidate.isCorrect)
+		if theta == nil {
+			continue
+		}
+		if err := s.resultRepo.UpdateAbility(candidate.resultID, theta, thetaSE); err != nil {
+			return nil, err
+		}
+		if err := s.integrityService.RecordResult(candidate.resultID, models.LedgerActionUpdate, models.LedgerReasonCalibrated); err != nil {
+			fmt.Printf("Warning: Failed to record re-scored result %d in the ledger: %v\n", candidate.resultID, err)
+		}
+		report.Scored++
+	}
+
+	return report, nil
+}
+
+// pilotQuestionKey identifies the same question in a pilot test and an adaptive pool
+func pilotQuestionKey(question *models.Question) string {
+	return string(question.QuestionType) + "\x00" + strings.TrimSpace(question.QuestionText)
+}
+
+// CalibrateAll calibrates every graded test with enough results
+func (s *CalibrationService) CalibrateAll(model models.IRTModel, minResponses int) ([]*models.CalibrationReport, error) {
+	if !model.IsValid() {
+		return nil, auth.ErrInvalidIRTModel
+	}
+
+	var reports []*models.CalibrationReport
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		tests, err := s.testRepo.List(pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, test := range tests {
+			if test.IsPractice() || test.IsAdaptive() {
+				continue
+			}
+
+			report, err := s.CalibrateTest(test.ID, model, minResponses)
+			if err != nil {
+				if err == auth.ErrNotEnoughResponses {
+					continue
+				}
+				return nil, err
+			}
+			reports = append(reports, report)
+		}
+
+		if len(tests) < pageSize {
+			return reports, nil
+		}
+	}
+}
+
+// estimateTheta returns the ability estimate and standard error for answers to the
+// questions, or nil when any of the questions has not been calibrated
+func estimateTheta(questions []*models.Question, isCorrect func(questionID int) bool) (*float64, *float64) {
+	if len(questions) == 0 {
+		return nil, nil
+	}
+
+	items := make([]irtItem, len(questions))
+	responses := make([]float64, len(questions))
+	for j, question := range questions {
+		if question.IRT == nil {
+			return nil, nil
+		}
+		items[j] = irtItem{difficulty: question.IRT.Difficulty, discrimination: question.IRT.Discrimination}
+		if isCorrect(question.ID) {
+			responses[j] = 1
+		}
+	}
+
+	theta, thetaSE := estimateAbility(items, responses)
+	theta, thetaSE = roundStatistic(theta), roundStatistic(thetaSE)
+	return &theta, &thetaSE
+}
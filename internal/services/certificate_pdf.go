@@ -0,0 +1,211 @@
+package services
+
+import (
+	"fmt"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of the generated PDFs, in points
+const (
+	pdfMargin     = 56.0
+	pdfLineHeight = 16.0
+	pdfDateFormat = "2 January 2006"
+)
+
+// renderScoreReport lays out the score report of a result on A4 portrait pages
+func renderScoreReport(test *models.Test, user *models.User, review *models.ResultReview) []byte {
+	result := review.Result
+	doc := utils.NewPDFDocument("Score Report - " + test.Title)
+	var pages []*utils.PDFPage
+
+	newPage := func() (*utils.PDFPage, float64) {
+		page := doc.AddPage(utils.A4Width, utils.A4Height)
+		pages = append(pages, page)
+		return page, utils.A4Height - 72
+	}
+	page, y := newPage()
+
+	page.Text(pdfMargin, y, utils.FontBold, 22, "Score Report")
+	y -= 28
+	for _, line := range utils.WrapText(utils.FontBold, 14, test.Title, utils.A4Width-2*pdfMargin) {
+		page.Text(pdfMargin, y, utils.FontBold, 14, line)
+		y -= 18
+	}
+	y -= 4
+	page.Line(pdfMargin, y, utils.A4Width-pdfMargin, y, 0.75)
+	y -= 24
+
+	candidate := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if candidate == "" {
+		candidate = user.Username
+	} else {
+		candidate += " (" + user.Username + ")"
+	}
+
+	outcome := "Not passed"
+	if result.IsPassed {
+		outcome = "Passed"
+	}
+
+	details := [][2]string{
+		{"Candidate", candidate},
+		{"Completed", result.CompletedAt.UTC().Format(pdfDateFormat + ", 15:04 MST")},
+		{"Score", fmt.Sprintf("%d / %d marks (%s)", result.MarksObtained, result.TotalMarks, formatPercentage(result.Percentage))},
+		{"Result", outcome},
+		{"Correct answers", fmt.Sprintf("%d of %d (%d answered)", result.CorrectAnswers, result.TotalQuestions, result.AnsweredQuestions)},
+	}
+	if result.Grade != nil {
+		details = append(details, [2]string{"Grade", *result.Grade})
+	}
+	if result.TimeTaken != nil {
+		details = append(details, [2]string{"Time taken", formatDuration(*result.TimeTaken)})
+	}
+	if result.Theta != nil && result.ThetaSE != nil {
+		details = append(details, [2]string{"Ability (theta)", fmt.Sprintf("%.2f (standard error %.2f)", *result.Theta, *result.ThetaSE)})
+	}
+	for _, detail := range details {
+		page.Text(pdfMargin, y, utils.FontBold, 11, detail[0])
+		page.Text(pdfMargin+130, y, utils.FontRegular, 11, detail[1])
+		y -= pdfLineHeight
+	}
+	if result.HasOverride {
+		y -= 4
+		page.Text(pdfMargin, y, utils.FontRegular, 9, "The total includes manual score adjustments that are not shown per question.")
+		y -= pdfLineHeight
+	}
+
+	// Per-question breakdown
+	marksX := utils.A4Width - pdfMargin - 70
+	questionWidth := marksX - 60 - (pdfMargin + 28)
+	tableHeader := func() {
+		page.Text(pdfMargin, y, utils.FontBold, 10, "#")
+		page.Text(pdfMargin+28, y, utils.FontBold, 10, "Question")
+		page.TextRight(marksX, y, utils.FontBold, 10, "Marks")
+		page.TextRight(utils.A4Width-pdfMargin, y, utils.FontBold, 10, "Correct")
+		y -= 6
+		page.Line(pdfMargin, y, utils.A4Width-pdfMargin, y, 0.5)
+		y -= 14
+	}
+
+	y -= 16
+	page.Text(pdfMargin, y, utils.FontBold, 14, "Questions")
+	y -= 22
+	tableHeader()
+
+	for i, item := range review.Items {
+		if y < 72 {
+			page, y = newPage()
+			tableHeader()
+		}
+
+		correct := "-"
+		if item.IsCorrect != nil {
+			correct = "No"
+			if *item.IsCorrect {
+				correct = "Yes"
+			}
+		}
+
+		page.Text(pdfMargin, y, utils.FontRegular, 10, strconv.Itoa(i+1))
+		page.Text(pdfMargin+28, y, utils.FontRegular, 10, truncateText(utils.FontRegular, 10, item.QuestionText, questionWidth))
+		page.TextRight(marksX, y, utils.FontRegular, 10, fmt.Sprintf("%d / %d", item.MarksAwarded, item.Marks))
+		page.TextRight(utils.A4Width-pdfMargin, y, utils.FontRegular, 10, correct)
+		y -= pdfLineHeight
+	}
+
+	generated := time.Now().UTC().Format(pdfDateFormat)
+	for i, p := range pages {
+		p.Text(pdfMargin, 36, utils.FontRegular, 8, "Generated by GoCBT on "+generated)
+		p.TextRight(utils.A4Width-pdfMargin, 36, utils.FontRegular, 8, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+
+	return doc.Bytes()
+}
+
+// renderCertificate lays out a certificate on an A4 landscape page
+func renderCertificate(template *models.CertificateTemplate, certificate *models.Certificate, verifyURL string) []byte {
+	width, height := utils.A4Height, utils.A4Width
+	grade := ""
+	if certificate.Grade != nil {
+		grade = *certificate.Grade
+	}
+	fill := strings.NewReplacer(
+		"{name}", certificate.CandidateName,
+		"{test}", certificate.TestTitle,
+		"{score}", formatPercentage(certificate.Percentage),
+		"{grade}", grade,
+		"{date}", certificate.CompletedAt.UTC().Format(pdfDateFormat),
+		"{id}", certificate.VerificationID,
+	)
+
+	doc := utils.NewPDFDocument(fill.Replace(template.Title) + " - " + certificate.CandidateName)
+	page := doc.AddPage(width, height)
+
+	page.Rect(24, 24, width-48, height-48, 3)
+	page.Rect(32, 32, width-64, height-64, 0.75)
+
+	y := height - 130
+	for _, line := range utils.WrapText(utils.FontBold, 32, fill.Replace(template.Title), width-2*pdfMargin-40) {
+		page.TextCentered(y, utils.FontBold, 32, line)
+		y -= 40
+	}
+
+	y -= 30
+	page.TextCentered(y, utils.FontBold, 26, certificate.CandidateName)
+	y -= 12
+	nameWidth := utils.TextWidth(utils.FontBold, 26, certificate.CandidateName) + 40
+	page.Line((width-nameWidth)/2, y, (width+nameWidth)/2, y, 0.75)
+	y -= 40
+
+	for _, line := range utils.WrapText(utils.FontRegular, 15, fill.Replace(template.Body), width-2*pdfMargin-120) {
+		page.TextCentered(y, utils.FontRegular, 15, line)
+		y -= 22
+	}
+
+	// Issue date on the left, signatory on the right
+	baseline := 130.0
+	page.Text(pdfMargin+24, baseline, utils.FontRegular, 11, "Issued "+certificate.IssuedAt.UTC().Format(pdfDateFormat))
+	if template.SignatoryName != "" {
+		right := width - pdfMargin - 24
+		page.Line(right-200, baseline+18, right, baseline+18, 0.75)
+		page.TextRight(right, baseline, utils.FontBold, 11, template.SignatoryName)
+		if template.SignatoryTitle != "" {
+			page.TextRight(right, baseline-15, utils.FontRegular, 10, template.SignatoryTitle)
+		}
+	}
+
+	page.TextCentered(70, utils.FontRegular, 9, "Verification ID: "+certificate.VerificationID)
+	if verifyURL != "" {
+		page.TextCentered(57, utils.FontRegular, 9, "Check this certificate at "+verifyURL+"/"+certificate.VerificationID)
+	}
+
+	return doc.Bytes()
+}
+
+// formatPercentage formats a percentage with at most one decimal
+func formatPercentage(percentage float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(percentage, 'f', 1, 64), ".0") + "%"
+}
+
+// formatDuration formats a number of seconds as minutes and seconds
+func formatDuration(seconds int) string {
+	return fmt.Sprintf("%dm %02ds", seconds/60, seconds%60)
+}
+
+// truncateText shortens text with an ellipsis so that it fits maxWidth
+func truncateText(font utils.PDFFont, size float64, text string, maxWidth float64) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utils.TextWidth(font, size, text) <= maxWidth {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && utils.TextWidth(font, size, string(runes)+"...") > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "..."
+}
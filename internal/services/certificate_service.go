@@ -0,0 +1,294 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"strings"
+)
+
+// verificationAlphabet is Crockford's base32, which leaves out letters that are easily
+// confused with digits when a verification ID is typed in
+const verificationAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// CertificateService implements the models.CertificateService interface
+type CertificateService struct {
+	certificateRepo models.CertificateRepository
+	resultRepo      models.TestResultRepository
+	testRepo        models.TestRepository
+	userRepo        models.UserRepository
+	releaseService  models.ReleaseService
+	verifyURL       string
+}
+
+// NewCertificateService creates a new certificate service. Certificates point readers to
+// verifyURL followed by the verification ID.
+func NewCertificateService(certificateRepo models.CertificateRepository, resultRepo models.TestResultRepository, testRepo models.TestRepository, userRepo models.UserRepository, releaseService models.ReleaseService, verifyURL string) models.CertificateService {
+	return &CertificateService{
+		certificateRepo: certificateRepo,
+		resultRepo:      resultRepo,
+		testRepo:        testRepo,
+		userRepo:        userRepo,
+		releaseService:  releaseService,
+		verifyURL:       strings.TrimRight(verifyURL, "/"),
+	}
+}
+
+// GetTemplate retrieves the certificate template for a test, or the default template
+func (s *CertificateService) GetTemplate(testID int) (*models.CertificateTemplate, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return s.effectiveTemplate(testID)
+}
+
+// UpdateTemplate creates or replaces the certificate template for a test
+func (s *CertificateService) UpdateTemplate(testID int, title, body, signatoryName, signatoryTitle string) (*models.CertificateTemplate, error) {
+	template, err := s.GetTemplate(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Title = strings.TrimSpace(title)
+	template.Body = strings.TrimSpace(body)
+	template.SignatoryName = strings.TrimSpace(signatoryName)
+	template.SignatoryTitle = strings.TrimSpace(signatoryTitle)
+	if !template.IsValid() {
+		return nil, auth.ErrInvalidCertificateTemplate
+	}
+
+	if err := s.certificateRepo.UpsertTemplate(template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// DeleteTemplate removes the certificate template for a test, restoring the default
+func (s *CertificateService) DeleteTemplate(testID int) error {
+	return s.certificateRepo.DeleteTemplate(testID)
+}
+
+// GetScoreReport renders the PDF score report of a result. Without full access
+// (teachers and admins have it) the report is only available once results are released.
+func (s *CertificateService) GetScoreReport(resultID int, fullAccess bool) ([]byte, error) {
+	review, err := s.releaseService.GetReview(resultID, fullAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	test, err := s.testRepo.GetByID(review.Result.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	user, err := s.userRepo.GetByID(review.Result.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return renderScoreReport(test, user, review), nil
+}
+
+// GetCertificate issues the certificate of a passed result, or returns the one already
+// issued, and renders it as a PDF. Without full access the certificate is only
+// available once results are released.
+func (s *CertificateService) GetCertificate(resultID int, fullAccess bool) (*models.Certificate, []byte, error) {
+	result, err := s.resultRepo.GetByID(resultID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if result == nil {
+		return nil, nil, auth.ErrUserNotFound
+	}
+
+	if !fullAccess {
+		if err := s.releaseService.WithholdUnreleased(result); err != nil {
+			return nil, nil, err
+		}
+		if result.ScoreWithheld {
+			return nil, nil, auth.ErrResultNotReleased
+		}
+	}
+
+	if !result.IsPassed {
+		return nil, nil, auth.ErrResultNotPassed
+	}
+
+	certificate, err := s.certificateRepo.GetByResultID(resultID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if certificate == nil {
+		certificate, err = s.issueCertificate(result)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	template, err := s.effectiveTemplate(certificate.TestID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certificate, renderCertificate(template, certificate, s.verifyURL), nil
+}
+
+// VerifyCertificate looks up a certificate by its verification ID. A certificate on
+// record is no longer valid if its result has since been regraded to a fail.
+func (s *CertificateService) VerifyCertificate(verificationID string) (*models.CertificateVerification, error) {
+	verificationID = normalizeVerificationID(verificationID)
+	if verificationID == "" {
+		return nil, auth.ErrUserNotFound
+	}
+
+	certificate, err := s.certificateRepo.GetByVerificationID(verificationID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if certificate == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	verification := &models.CertificateVerification{
+		VerificationID: certificate.VerificationID,
+		Valid:          true,
+		CandidateName:  certificate.CandidateName,
+		TestTitle:      certificate.TestTitle,
+		Percentage:     &certificate.Percentage,
+		Grade:          certificate.Grade,
+		CompletedAt:    &certificate.CompletedAt,
+		IssuedAt:       &certificate.IssuedAt,
+	}
+
+	result, err := s.resultRepo.GetByID(certificate.ResultID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	switch {
+	case result == nil:
+		verification.Valid = false
+		verification.Reason = "the result was withdrawn and the certificate revoked"
+	case !result.IsPassed:
+		verification.Valid = false
+		verification.Reason = "the result was regraded and is no longer a pass"
+	}
+
+	return verification, nil
+}
+
+// issueCertificate records a new certificate for a passed result
+func (s *CertificateService) issueCertificate(result *models.TestResult) (*models.Certificate, error) {
+	test, err := s.testRepo.GetByID(result.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	user, err := s.userRepo.GetByID(result.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	verificationID, err := generateVerificationID()
+	if err != nil {
+		return nil, err
+	}
+
+	certificate := &models.Certificate{
+		VerificationID: verificationID,
+		ResultID:       result.ID,
+		TestID:         result.TestID,
+		UserID:         result.UserID,
+		CandidateName:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+		TestTitle:      test.Title,
+		Percentage:     roundStatistic(result.Percentage),
+		Grade:          result.Grade,
+		CompletedAt:    result.CompletedAt,
+	}
+	if certificate.CandidateName == "" {
+		certificate.CandidateName = user.Username
+	}
+
+	if err := s.certificateRepo.Create(certificate); err != nil {
+		// Another request may have issued the certificate first
+		existing, getErr := s.certificateRepo.GetByResultID(result.ID)
+		if getErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	return certificate, nil
+}
+
+// effectiveTemplate returns the stored certificate template of a test, or the default
+func (s *CertificateService) effectiveTemplate(testID int) (*models.CertificateTemplate, error) {
+	template, err := s.certificateRepo.GetTemplate(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if template == nil {
+		return models.DefaultCertificateTemplate(testID), nil
+	}
+	return template, nil
+}
+
+// generateVerificationID generates a random verification ID of 80 bits, written as
+// four groups of four characters
+func generateVerificationID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	var id strings.Builder
+	for i, b := range bytes {
+		if i > 0 && i%4 == 0 {
+			id.WriteByte('-')
+		}
+		id.WriteByte(verificationAlphabet[b%32])
+	}
+	return id.String(), nil
+}
+
+// normalizeVerificationID converts a verification ID as typed by a reader to its stored
+// form, accepting lower case, missing dashes and the letters O, I and L for digits
+func normalizeVerificationID(verificationID string) string {
+	var chars []byte
+	for _, r := range strings.ToUpper(verificationID) {
+		switch {
+		case r == 'O':
+			chars = append(chars, '0')
+		case r == 'I' || r == 'L':
+			chars = append(chars, '1')
+		case r < 128 && strings.ContainsRune(verificationAlphabet, r):
+			chars = append(chars, byte(r))
+		case r == '-' || r == ' ':
+		default:
+			return ""
+		}
+	}
+	if len(chars) != 16 {
+		return ""
+	}
+
+	return string(chars[0:4]) + "-" + string(chars[4:8]) + "-" + string(chars[8:12]) + "-" + string(chars[12:16])
+}
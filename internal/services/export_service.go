@@ -0,0 +1,177 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"gocbt/internal/utils"
+	"io"
+)
+
+// ExportService implements the models.ExportService interface
+type ExportService struct {
+	exportRepo   models.ExportRepository
+	testRepo     models.TestRepository
+	questionRepo models.QuestionRepository
+	overrideRepo models.ScoreOverrideRepository
+}
+
+// NewExportService creates a new export service
+func NewExportService(exportRepo models.ExportRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository, overrideRepo models.ScoreOverrideRepository) models.ExportService {
+	return &ExportService{
+		exportRepo:   exportRepo,
+		testRepo:     testRepo,
+		questionRepo: questionRepo,
+		overrideRepo: overrideRepo,
+	}
+}
+
+// ExportResults writes the graded results of a test with one row per candidate and one
+// column per question. Nothing is written to w if the export cannot be started.
+func (s *ExportService) ExportResults(testID int, format models.ExportFormat, w io.Writer) error {
+	test, err := s.getTest(testID, format)
+	if err != nil {
+		return err
+	}
+
+	questions, err := s.questionRepo.GetByTestID(testID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.exportRepo.GetResultRows(testID)
+	if err != nil {
+		return err
+	}
+
+	adjustments, err := loadScoreAdjustments(s.overrideRepo, testID)
+	if err != nil {
+		return err
+	}
+
+	// Marks per question of every graded session, after manual overrides
+	graded := make(map[int]map[int]int)
+	for _, row := range rows {
+		graded[row.Result.SessionID] = make(map[int]int)
+	}
+	err = s.exportRepo.StreamAnswers(testID, func(answer *models.AnswerExportRow) error {
+		if marks, ok := graded[answer.SessionID]; ok {
+			_, marks[answer.QuestionID] = adjustments.adjustAnswer(exportedAnswer(answer), answer.IsCorrect != nil && *answer.IsCorrect, answer.MarksAwarded)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer, err := newTableWriter(format, w, test.Title)
+	if err != nil {
+		return err
+	}
+
+	header := []interface{}{"Result ID", "User ID", "Username", "First Name", "Last Name", "Email"}
+	for i := range questions {
+		header = append(header, fmt.Sprintf("Q%d", i+1))
+	}
+	header = append(header, "Answered", "Correct", "Marks Obtained", "Total Marks", "Percentage",
+		"Grade", "Grade Points", "Passed", "Theta", "Theta SE", "Time Taken (s)", "Completed At")
+	if err := writer.WriteRow(header...); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		result := row.Result
+		cells := []interface{}{result.ID, result.UserID, row.Username, row.FirstName, row.LastName, row.Email}
+		for _, question := range questions {
+			_, answered := graded[result.SessionID][question.ID]
+			// Adaptive candidates are only credited for voided questions they were given
+			if voidMarks, ok := adjustments.voidedQuestions[question.ID]; ok && (answered || !test.IsAdaptive()) {
+				cells = append(cells, voidMarks)
+			} else if marks, ok := graded[result.SessionID][question.ID]; ok {
+				cells = append(cells, marks)
+			} else {
+				cells = append(cells, nil) // not answered
+			}
+		}
+		cells = append(cells, result.AnsweredQuestions, result.CorrectAnswers, result.MarksObtained, result.TotalMarks,
+			roundStatistic(result.Percentage), result.Grade, result.GradePoints, result.IsPassed,
+			result.Theta, result.ThetaSE, result.TimeTaken, result.CompletedAt)
+		if err := writer.WriteRow(cells...); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// ExportAnswers streams every stored answer of every session of a test, one row per answer
+func (s *ExportService) ExportAnswers(testID int, format models.ExportFormat, w io.Writer) error {
+	test, err := s.getTest(testID, format)
+	if err != nil {
+		return err
+	}
+
+	adjustments, err := loadScoreAdjustments(s.overrideRepo, testID)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newTableWriter(format, w, test.Title)
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteRow("Answer ID", "Session ID", "User ID", "Username", "Question ID", "Question Number",
+		"Question", "Question Type", "Answer Text", "Selected Option ID", "Selected Option", "Correct",
+		"Marks Awarded", "Final Marks", "Answered At")
+	if err != nil {
+		return err
+	}
+
+	err = s.exportRepo.StreamAnswers(testID, func(answer *models.AnswerExportRow) error {
+		_, finalMarks := adjustments.adjustAnswer(exportedAnswer(answer), answer.IsCorrect != nil && *answer.IsCorrect, answer.MarksAwarded)
+		return writer.WriteRow(answer.AnswerID, answer.SessionID, answer.UserID, answer.Username,
+			answer.QuestionID, answer.OrderIndex, answer.QuestionText, string(answer.QuestionType),
+			answer.AnswerText, answer.SelectedOptionID, answer.SelectedOption, answer.IsCorrect,
+			answer.MarksAwarded, finalMarks, answer.AnsweredAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// getTest validates the export format and loads the test being exported
+func (s *ExportService) getTest(testID int, format models.ExportFormat) (*models.Test, error) {
+	if !format.IsValid() {
+		return nil, auth.ErrInvalidExportFormat
+	}
+
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return test, nil
+}
+
+// newTableWriter creates a table writer for the export format
+func newTableWriter(format models.ExportFormat, w io.Writer, sheetName string) (utils.TableWriter, error) {
+	if format == models.ExportFormatXLSX {
+		return utils.NewXLSXWriter(w, sheetName)
+	}
+	return utils.NewCSVWriter(w), nil
+}
+
+// exportedAnswer returns the fields of an exported answer that manual overrides refer to
+func exportedAnswer(answer *models.AnswerExportRow) *models.UserAnswer {
+	return &models.UserAnswer{ID: answer.AnswerID, SessionID: answer.SessionID, QuestionID: answer.QuestionID}
+}
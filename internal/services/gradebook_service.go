@@ -0,0 +1,484 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GradebookService implements the models.GradebookService interface
+type GradebookService struct {
+	courseRepo     models.CourseRepository
+	testRepo       models.TestRepository
+	userRepo       models.UserRepository
+	gradingService models.GradingScaleService
+	releaseService models.ReleaseService
+}
+
+// NewGradebookService creates a new course gradebook service
+func NewGradebookService(courseRepo models.CourseRepository, testRepo models.TestRepository, userRepo models.UserRepository, gradingService models.GradingScaleService, releaseService models.ReleaseService) models.GradebookService {
+	return &GradebookService{
+		courseRepo:     courseRepo,
+		testRepo:       testRepo,
+		userRepo:       userRepo,
+		gradingService: gradingService,
+		releaseService: releaseService,
+	}
+}
+
+// CreateCourse creates a new course with its weighted categories
+func (s *GradebookService) CreateCourse(creatorID int, name, description string, gradingScaleID *int, categories []*models.CourseCategory, canViewResults func(testID int) bool) (*models.Course, error) {
+	if err := s.checkGradingScale(gradingScaleID); err != nil {
+		return nil, err
+	}
+
+	normalized, err := s.normalizeCategories(categories, canViewResults)
+	if err != nil {
+		return nil, err
+	}
+
+	course := &models.Course{
+		Name:           strings.TrimSpace(name),
+		Description:    description,
+		CreatedBy:      creatorID,
+		GradingScaleID: gradingScaleID,
+	}
+
+	if err := s.courseRepo.Create(course); err != nil {
+		return nil, err
+	}
+
+	if err := s.courseRepo.ReplaceCategories(course.ID, normalized); err != nil {
+		return nil, err
+	}
+	course.Categories = normalized
+
+	return course, nil
+}
+
+// GetCourse retrieves a course with its categories
+func (s *GradebookService) GetCourse(courseID int) (*models.Course, error) {
+	course, err := s.courseRepo.GetByID(courseID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if course == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	categories, err := s.courseRepo.GetCategories(course.ID)
+	if err != nil {
+		return nil, err
+	}
+	course.Categories = categories
+
+	return course, nil
+}
+
+// ListCourses retrieves courses with their categories, with pagination; an owner ID of 0
+// lists every course
+func (s *GradebookService) ListCourses(ownerID int, limit, offset int) ([]*models.Course, error) {
+	courses, err := s.courseRepo.List(ownerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, course := range courses {
+		categories, err := s.courseRepo.GetCategories(course.ID)
+		if err != nil {
+			return nil, err
+		}
+		course.Categories = categories
+	}
+
+	return courses, nil
+}
+
+// UpdateCourse updates a course and replaces its categories
+func (s *GradebookService) UpdateCourse(courseID int, name, description string, gradingScaleID *int, categories []*models.CourseCategory, canViewResults func(testID int) bool) (*models.Course, error) {
+	course, err := s.GetCourse(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkGradingScale(gradingScaleID); err != nil {
+		return nil, err
+	}
+
+	normalized, err := s.normalizeCategories(categories, canViewResults)
+	if err != nil {
+		return nil, err
+	}
+
+	course.Name = strings.TrimSpace(name)
+	course.Description = description
+	course.GradingScaleID = gradingScaleID
+
+	if err := s.courseRepo.Update(course); err != nil {
+		return nil, err
+	}
+
+	if err := s.courseRepo.ReplaceCategories(course.ID, normalized); err != nil {
+		return nil, err
+	}
+	course.Categories = normalized
+
+	return course, nil
+}
+
+// DeleteCourse deletes a course; the tests and results of the course are kept
+func (s *GradebookService) DeleteCourse(courseID int) error {
+	if _, err := s.GetCourse(courseID); err != nil {
+		return err
+	}
+
+	return s.courseRepo.Delete(courseID)
+}
+
+// GetGradebook computes the running course grade of every student with a graded result
+// on a course test. Teachers see every score, released or not, so the requesting user
+// must be allowed to view the results of every course test.
+func (s *GradebookService) GetGradebook(courseID int, canViewResults func(testID int) bool) (*models.Gradebook, error) {
+	course, err := s.GetCourse(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := s.gradingService.GetScaleForTest(&models.Test{GradingScaleID: course.GradingScaleID})
+	if err != nil {
+		return nil, err
+	}
+
+	tests, err := s.gradebookTests(course)
+	if err != nil {
+		return nil, err
+	}
+	for _, test := range tests {
+		if !canViewResults(test.TestID) {
+			return nil, auth.ErrForbidden
+		}
+	}
+
+	rows, err := s.courseRepo.GetResults(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are ordered by student, so each student's results are consecutive
+	gradebook := &models.Gradebook{
+		Course:      course,
+		Tests:       tests,
+		Students:    []*models.StudentGrade{},
+		GeneratedAt: time.Now(),
+	}
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].UserID == rows[start].UserID {
+			end++
+		}
+		gradebook.Students = append(gradebook.Students, computeStudentGrade(course, scale, rows[start:end], nil))
+		start = end
+	}
+
+	return gradebook, nil
+}
+
+// GetStudentGrade computes one student's running course grade. Scores of tests whose
+// results are not released yet are withheld and do not count.
+func (s *GradebookService) GetStudentGrade(courseID, userID int) (*models.StudentGrade, error) {
+	course, err := s.GetCourse(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := s.gradingService.GetScaleForTest(&models.Test{GradingScaleID: course.GradingScaleID})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.courseRepo.GetUserResults(courseID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.TestResult, len(rows))
+	for i, row := range rows {
+		results[i] = &models.TestResult{ID: row.ResultID, TestID: row.TestID, UserID: row.UserID, Percentage: row.Percentage}
+	}
+	if err := s.releaseService.WithholdUnreleased(results...); err != nil {
+		return nil, err
+	}
+	withheld := make(map[int]bool)
+	for _, result := range results {
+		if result.ScoreWithheld {
+			withheld[result.ID] = true
+		}
+	}
+
+	grade := computeStudentGrade(course, scale, rows, withheld)
+	if len(rows) == 0 {
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if user == nil {
+			return nil, auth.ErrUserNotFound
+		}
+		grade.UserID = user.ID
+		grade.Username = user.Username
+		grade.FirstName = user.FirstName
+		grade.LastName = user.LastName
+		grade.Email = user.Email
+	}
+
+	return grade, nil
+}
+
+// ExportGradebook writes the gradebook of a course with one row per student, for import
+// into a school's records system. Nothing is written to w if the export cannot be started.
+func (s *GradebookService) ExportGradebook(courseID int, format models.ExportFormat, w io.Writer, canViewResults func(testID int) bool) error {
+	if !format.IsValid() {
+		return auth.ErrInvalidExportFormat
+	}
+
+	gradebook, err := s.GetGradebook(courseID, canViewResults)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newTableWriter(format, w, gradebook.Course.Name)
+	if err != nil {
+		return err
+	}
+
+	header := []interface{}{"User ID", "Username", "First Name", "Last Name", "Email"}
+	for _, test := range gradebook.Tests {
+		header = append(header, test.Title)
+	}
+	for _, category := range gradebook.Course.Categories {
+		header = append(header, fmt.Sprintf("%s (%s%%)", category.Name, strconv.FormatFloat(category.Weight, 'f', -1, 64)))
+	}
+	header = append(header, "Course Percentage", "Grade", "Grade Points", "Weight Completed")
+	if err := writer.WriteRow(header...); err != nil {
+		return err
+	}
+
+	for _, student := range gradebook.Students {
+		scores := make(map[int]*models.GradebookScore)
+		for _, category := range student.Categories {
+			for _, score := range category.Scores {
+				scores[score.TestID] = score
+			}
+		}
+
+		cells := []interface{}{student.UserID, student.Username, student.FirstName, student.LastName, student.Email}
+		for _, test := range gradebook.Tests {
+			if score, ok := scores[test.TestID]; ok && score.Percentage != nil {
+				cells = append(cells, *score.Percentage)
+			} else {
+				cells = append(cells, nil) // not taken
+			}
+		}
+		for _, category := range student.Categories {
+			cells = append(cells, category.Percentage)
+		}
+		cells = append(cells, student.Percentage, student.Grade, student.GradePoints, student.WeightCompleted)
+		if err := writer.WriteRow(cells...); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// gradebookTests lists the test columns of a course in category order
+func (s *GradebookService) gradebookTests(course *models.Course) ([]*models.GradebookTest, error) {
+	tests := []*models.GradebookTest{}
+	for _, category := range course.Categories {
+		for _, testID := range category.TestIDs {
+			test, err := s.testRepo.GetByID(testID)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+			if test == nil {
+				continue
+			}
+			tests = append(tests, &models.GradebookTest{TestID: test.ID, Title: test.Title, CategoryID: category.ID})
+		}
+	}
+	return tests, nil
+}
+
+// checkGradingScale checks that a course grading scale exists and is global
+func (s *GradebookService) checkGradingScale(gradingScaleID *int) error {
+	if gradingScaleID == nil {
+		return nil
+	}
+
+	scale, err := s.gradingService.GetScale(*gradingScaleID)
+	if err != nil {
+		if err == auth.ErrUserNotFound {
+			return auth.ErrInvalidCourseGradingScale
+		}
+		return err
+	}
+	if scale.TestID != nil {
+		return auth.ErrInvalidCourseGradingScale
+	}
+	return nil
+}
+
+// normalizeCategories validates course categories: each needs a unique name, a weight and
+// at least one graded test more than it drops, no test may be in two categories, and the
+// weights must total 100. Every test must be one whose results the user may view.
+func (s *GradebookService) normalizeCategories(categories []*models.CourseCategory, canViewResults func(testID int) bool) ([]*models.CourseCategory, error) {
+	if len(categories) == 0 {
+		return nil, auth.ErrInvalidCourseCategories
+	}
+
+	names := make(map[string]bool)
+	assigned := make(map[int]bool)
+	totalWeight := 0.0
+	normalized := make([]*models.CourseCategory, 0, len(categories))
+	for i, category := range categories {
+		if category == nil {
+			return nil, auth.ErrInvalidCourseCategories
+		}
+
+		name := strings.TrimSpace(category.Name)
+		if len(name) < 1 || len(name) > 100 || names[strings.ToLower(name)] {
+			return nil, auth.ErrInvalidCourseCategories
+		}
+		if category.Weight <= 0 || category.Weight > 100 {
+			return nil, auth.ErrInvalidCourseCategories
+		}
+		if category.DropLowest < 0 || len(category.TestIDs) == 0 || category.DropLowest >= len(category.TestIDs) {
+			return nil, auth.ErrInvalidCourseCategories
+		}
+
+		for _, testID := range category.TestIDs {
+			if assigned[testID] {
+				return nil, auth.ErrInvalidCourseCategories
+			}
+			test, err := s.testRepo.GetByID(testID)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+			if test == nil || test.IsPractice() {
+				return nil, auth.ErrInvalidCourseCategories
+			}
+			if !canViewResults(testID) {
+				return nil, auth.ErrForbidden
+			}
+			assigned[testID] = true
+		}
+
+		names[strings.ToLower(name)] = true
+		totalWeight += category.Weight
+		normalized = append(normalized, &models.CourseCategory{
+			Name:       name,
+			Weight:     category.Weight,
+			DropLowest: category.DropLowest,
+			Position:   i,
+			TestIDs:    append([]int(nil), category.TestIDs...),
+		})
+	}
+
+	if math.Abs(totalWeight-100) > 0.01 {
+		return nil, auth.ErrInvalidCourseCategories
+	}
+
+	return normalized, nil
+}
+
+// computeStudentGrade computes a student's running grade from their results on the tests of
+// a course. The latest result of a test is its counting attempt. Each category averages its
+// counted scores after dropping the lowest, keeping at least one; the course percentage
+// weights the categories that have a counted score.
+func computeStudentGrade(course *models.Course, scale *models.GradingScale, rows []*models.CourseResultRow, withheld map[int]bool) *models.StudentGrade {
+	grade := &models.StudentGrade{Categories: []*models.CategoryGrade{}}
+	counting := make(map[int]*models.CourseResultRow)
+	for _, row := range rows {
+		grade.UserID = row.UserID
+		grade.Username = row.Username
+		grade.FirstName = row.FirstName
+		grade.LastName = row.LastName
+		grade.Email = row.Email
+		if current, ok := counting[row.TestID]; !ok || row.CompletedAt.After(current.CompletedAt) {
+			counting[row.TestID] = row
+		}
+	}
+
+	weightedSum := 0.0
+	for _, category := range course.Categories {
+		categoryGrade := &models.CategoryGrade{
+			CategoryID: category.ID,
+			Name:       category.Name,
+			Weight:     category.Weight,
+			Scores:     []*models.GradebookScore{},
+		}
+
+		var counted []*models.GradebookScore
+		for _, testID := range category.TestIDs {
+			score := &models.GradebookScore{TestID: testID}
+			if row, ok := counting[testID]; ok {
+				resultID := row.ResultID
+				score.ResultID = &resultID
+				if withheld[row.ResultID] {
+					score.Withheld = true
+				} else {
+					percentage := roundStatistic(row.Percentage)
+					score.Percentage = &percentage
+					counted = append(counted, score)
+				}
+			}
+			categoryGrade.Scores = append(categoryGrade.Scores, score)
+		}
+
+		// Drop the lowest scores taken so far, always keeping at least one
+		sort.SliceStable(counted, func(i, j int) bool {
+			return *counted[i].Percentage < *counted[j].Percentage
+		})
+		drop := category.DropLowest
+		if drop > len(counted)-1 {
+			drop = len(counted) - 1
+		}
+		for i := 0; i < drop; i++ {
+			counted[i].Dropped = true
+		}
+
+		if kept := counted[max(drop, 0):]; len(kept) > 0 {
+			sum := 0.0
+			for _, score := range kept {
+				sum += *score.Percentage
+			}
+			percentage := roundStatistic(sum / float64(len(kept)))
+			categoryGrade.Percentage = &percentage
+			weightedSum += percentage * category.Weight
+			grade.WeightCompleted += category.Weight
+		}
+
+		grade.Categories = append(grade.Categories, categoryGrade)
+	}
+
+	if grade.WeightCompleted > 0 {
+		percentage := roundStatistic(weightedSum / grade.WeightCompleted)
+		grade.Percentage = &percentage
+
+		if band := scale.BandFor(percentage); band != nil {
+			label := band.Label
+			grade.Grade = &label
+			grade.GradePoints = band.GPAPoints
+		}
+	}
+	grade.WeightCompleted = roundStatistic(grade.WeightCompleted)
+
+	return grade
+}
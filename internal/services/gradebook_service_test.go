@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"gocbt/internal/auth"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"testing"
+)
+
+// gradebookTestEnv is a gradebook service with two teachers, the first owning a graded
+// test, backed by a fresh SQLite database
+type gradebookTestEnv struct {
+	service  models.GradebookService
+	testRepo models.TestRepository
+	policy   *auth.Policy
+	owner    auth.Principal
+	other    auth.Principal
+	ownTest  *models.Test
+}
+
+func newGradebookTestEnv(t *testing.T) *gradebookTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	userRepo := database.NewUserRepository(db)
+	testRepo := database.NewTestRepository(db)
+	resultRepo := database.NewTestResultRepository(db)
+	gradingService := NewGradingScaleService(database.NewGradingScaleRepository(db), testRepo, resultRepo, nil)
+
+	env := &gradebookTestEnv{
+		service:  NewGradebookService(database.NewCourseRepository(db), testRepo, userRepo, gradingService, nil),
+		testRepo: testRepo,
+		policy:   auth.NewPolicy(),
+	}
+
+	teacherPermissions := []models.Permission{models.PermTestsCreate, models.PermResultsView, models.PermCoursesManage}
+	for i, principal := range []*auth.Principal{&env.owner, &env.other} {
+		user := &models.User{
+			Username:  []string{"owner", "other"}[i],
+			Email:     []string{"owner", "other"}[i] + "@school.example",
+			FirstName: "Ada",
+			LastName:  "Lovelace",
+			Role:      models.RoleTeacher,
+			IsActive:  true,
+		}
+		if err := userRepo.Create(user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		*principal = auth.Principal{UserID: user.ID, Role: user.Role, Permissions: teacherPermissions}
+	}
+
+	env.ownTest = &models.Test{
+		Title:           "Midterm",
+		CreatedBy:       env.owner.UserID,
+		DurationMinutes: 60,
+		TotalMarks:      10,
+		PassingMarks:    5,
+		IsActive:        true,
+		Mode:            models.TestModeGraded,
+	}
+	if err := testRepo.Create(env.ownTest); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	return env
+}
+
+// canViewResults checks, as the API does, whether the principal may view every result of a test
+func (e *gradebookTestEnv) canViewResults(principal auth.Principal) func(testID int) bool {
+	return func(testID int) bool {
+		test, err := e.testRepo.GetByID(testID)
+		if err != nil || test == nil {
+			return false
+		}
+		coOwnerIDs, err := e.testRepo.GetCoOwners(testID)
+		if err != nil {
+			return false
+		}
+		return e.policy.Can(principal, auth.ActionViewResults, auth.Resource{OwnerID: test.CreatedBy, CoOwnerIDs: coOwnerIDs})
+	}
+}
+
+// categories puts the given tests in a single category weighing the whole course
+func (e *gradebookTestEnv) categories(testIDs ...int) []*models.CourseCategory {
+	return []*models.CourseCategory{{Name: "Exams", Weight: 100, TestIDs: testIDs}}
+}
+
+func TestGradebookCourseWithAnotherTeachersTest(t *testing.T) {
+	env := newGradebookTestEnv(t)
+
+	// The other teacher cannot count the owner's test in a course of their own
+	_, err := env.service.CreateCourse(env.other.UserID, "Biology", "", nil, env.categories(env.ownTest.ID), env.canViewResults(env.other))
+	if err != auth.ErrForbidden {
+		t.Errorf("CreateCourse() error = %v, expected %v", err, auth.ErrForbidden)
+	}
+
+	otherTest := &models.Test{Title: "Quiz", CreatedBy: env.other.UserID, DurationMinutes: 10, TotalMarks: 5, PassingMarks: 3, Mode: models.TestModeGraded}
+	if err := env.testRepo.Create(otherTest); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	course, err := env.service.CreateCourse(env.other.UserID, "Biology", "", nil, env.categories(otherTest.ID), env.canViewResults(env.other))
+	if err != nil {
+		t.Fatalf("CreateCourse() error = %v", err)
+	}
+
+	// Nor add it to their course later
+	_, err = env.service.UpdateCourse(course.ID, "Biology", "", nil, env.categories(otherTest.ID, env.ownTest.ID), env.canViewResults(env.other))
+	if err != auth.ErrForbidden {
+		t.Errorf("UpdateCourse() error = %v, expected %v", err, auth.ErrForbidden)
+	}
+}
+
+func TestGradebookRequiresViewResultsOnEveryTest(t *testing.T) {
+	env := newGradebookTestEnv(t)
+
+	course, err := env.service.CreateCourse(env.owner.UserID, "Biology", "", nil, env.categories(env.ownTest.ID), env.canViewResults(env.owner))
+	if err != nil {
+		t.Fatalf("CreateCourse() error = %v", err)
+	}
+
+	if _, err := env.service.GetGradebook(course.ID, env.canViewResults(env.owner)); err != nil {
+		t.Errorf("GetGradebook(owner) error = %v, expected nil", err)
+	}
+
+	if _, err := env.service.GetGradebook(course.ID, env.canViewResults(env.other)); err != auth.ErrForbidden {
+		t.Errorf("GetGradebook(other teacher) error = %v, expected %v", err, auth.ErrForbidden)
+	}
+
+	var buf bytes.Buffer
+	err = env.service.ExportGradebook(course.ID, models.ExportFormatCSV, &buf, env.canViewResults(env.other))
+	if err != auth.ErrForbidden || buf.Len() != 0 {
+		t.Errorf("ExportGradebook(other teacher) = %d bytes, error %v, expected nothing and %v", buf.Len(), err, auth.ErrForbidden)
+	}
+}
+
+func TestGradebookListCoursesByOwner(t *testing.T) {
+	env := newGradebookTestEnv(t)
+
+	if _, err := env.service.CreateCourse(env.owner.UserID, "Biology", "", nil, env.categories(env.ownTest.ID), env.canViewResults(env.owner)); err != nil {
+		t.Fatalf("CreateCourse() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ownerID  int
+		expected int
+	}{
+		{"owner", env.owner.UserID, 1},
+		{"other teacher", env.other.UserID, 0},
+		{"every course", 0, 1},
+	}
+
+	for _, test := range tests {
+		courses, err := env.service.ListCourses(test.ownerID, 20, 0)
+		if err != nil || len(courses) != test.expected {
+			t.Errorf("ListCourses(%s) = %d courses, %v, expected %d", test.name, len(courses), err, test.expected)
+		}
+	}
+}
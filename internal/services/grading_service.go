@@ -0,0 +1,309 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"strings"
+)
+
+// recomputeBatchSize is the number of results regraded per page
+const recomputeBatchSize = 100
+
+// GradingScaleService implements the models.GradingScaleService interface
+type GradingScaleService struct {
+	scaleRepo        models.GradingScaleRepository
+	testRepo         models.TestRepository
+	resultRepo       models.TestResultRepository
+	integrityService models.IntegrityService
+}
+
+// NewGradingScaleService creates a new grading scale service
+func NewGradingScaleService(scaleRepo models.GradingScaleRepository, testRepo models.TestRepository, resultRepo models.TestResultRepository, integrityService models.IntegrityService) models.GradingScaleService {
+	return &GradingScaleService{
+		scaleRepo:        scaleRepo,
+		testRepo:         testRepo,
+		resultRepo:       resultRepo,
+		integrityService: integrityService,
+	}
+}
+
+// CreateScale creates a new grading scale with its bands
+func (s *GradingScaleService) CreateScale(creatorID int, name, description string, testID *int, bands []*models.GradingBand) (*models.GradingScale, error) {
+	if testID != nil {
+		test, err := s.testRepo.GetByID(*testID)
+		if err != nil {
+			return nil, err
+		}
+		if test == nil {
+			return nil, auth.ErrUserNotFound
+		}
+	}
+
+	normalized, err := normalizeGradingBands(bands)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := &models.GradingScale{
+		Name:        strings.TrimSpace(name),
+		Description: description,
+		TestID:      testID,
+		CreatedBy:   creatorID,
+	}
+
+	if err := s.scaleRepo.Create(scale); err != nil {
+		return nil, err
+	}
+
+	if err := s.scaleRepo.ReplaceBands(scale.ID, normalized); err != nil {
+		return nil, err
+	}
+	scale.Bands = normalized
+
+	return scale, nil
+}
+
+// GetScale retrieves a grading scale with its bands
+func (s *GradingScaleService) GetScale(scaleID int) (*models.GradingScale, error) {
+	scale, err := s.scaleRepo.GetByID(scaleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if scale == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	bands, err := s.scaleRepo.GetBands(scale.ID)
+	if err != nil {
+		return nil, err
+	}
+	scale.Bands = bands
+
+	return scale, nil
+}
+
+// ListScales retrieves the global grading scales and those of a test
+func (s *GradingScaleService) ListScales(testID int) ([]*models.GradingScale, error) {
+	scales, err := s.scaleRepo.List(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scale := range scales {
+		bands, err := s.scaleRepo.GetBands(scale.ID)
+		if err != nil {
+			return nil, err
+		}
+		scale.Bands = bands
+	}
+
+	return scales, nil
+}
+
+// UpdateScale updates a grading scale and replaces its bands
+func (s *GradingScaleService) UpdateScale(scaleID int, name, description string, bands []*models.GradingBand) (*models.GradingScale, error) {
+	scale, err := s.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeGradingBands(bands)
+	if err != nil {
+		return nil, err
+	}
+
+	scale.Name = strings.TrimSpace(name)
+	scale.Description = description
+
+	if err := s.scaleRepo.Update(scale); err != nil {
+		return nil, err
+	}
+
+	if err := s.scaleRepo.ReplaceBands(scale.ID, normalized); err != nil {
+		return nil, err
+	}
+	scale.Bands = normalized
+
+	return scale, nil
+}
+
+// DeleteScale deletes a grading scale; tests using it fall back to the default scale
+func (s *GradingScaleService) DeleteScale(scaleID int) error {
+	if _, err := s.GetScale(scaleID); err != nil {
+		return err
+	}
+	return s.scaleRepo.Delete(scaleID)
+}
+
+// SetDefaultScale makes a global grading scale the default for tests without a scale
+func (s *GradingScaleService) SetDefaultScale(scaleID int) error {
+	scale, err := s.GetScale(scaleID)
+	if err != nil {
+		return err
+	}
+
+	// Only global scales can act as the default
+	if scale.TestID != nil {
+		return auth.ErrGradingScaleNotForTest
+	}
+
+	return s.scaleRepo.SetDefault(scaleID)
+}
+
+// AssignScale sets the grading scale used by a test; nil reverts to the default scale
+func (s *GradingScaleService) AssignScale(testID int, scaleID *int) (*models.Test, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	if scaleID != nil {
+		scale, err := s.GetScale(*scaleID)
+		if err != nil {
+			return nil, err
+		}
+		if scale.TestID != nil && *scale.TestID != testID {
+			return nil, auth.ErrGradingScaleNotForTest
+		}
+	}
+
+	test.GradingScaleID = scaleID
+	if err := s.testRepo.Update(test); err != nil {
+		return nil, err
+	}
+
+	return test, nil
+}
+
+// GetScaleForTest resolves the grading scale of a test: its own scale, then the
+// global default, then the built-in letter grades
+func (s *GradingScaleService) GetScaleForTest(test *models.Test) (*models.GradingScale, error) {
+	if test.GradingScaleID != nil {
+		scale, err := s.GetScale(*test.GradingScaleID)
+		if err == nil {
+			return scale, nil
+		}
+		if err != auth.ErrUserNotFound {
+			return nil, err
+		}
+	}
+
+	scale, err := s.scaleRepo.GetDefault()
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if scale != nil {
+		bands, err := s.scaleRepo.GetBands(scale.ID)
+		if err != nil {
+			return nil, err
+		}
+		scale.Bands = bands
+		return scale, nil
+	}
+
+	return models.DefaultGradingScale(), nil
+}
+
+// RecomputeGrades regrades all stored results of a test with its current scale and returns
+// the number of results whose grade changed
+func (s *GradingScaleService) RecomputeGrades(testID int) (int, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return 0, err
+	}
+	if test == nil {
+		return 0, auth.ErrUserNotFound
+	}
+
+	scale, err := s.GetScaleForTest(test)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for offset := 0; ; offset += recomputeBatchSize {
+		results, err := s.resultRepo.GetByTest(testID, recomputeBatchSize, offset)
+		if err != nil {
+			return updated, err
+		}
+
+		for _, result := range results {
+			previousGrade, previousPoints := result.Grade, result.GradePoints
+			scale.ApplyTo(result)
+
+			// Leave results the scale does not change out of the ledger
+			if sameGrade(result.Grade, previousGrade) && samePoints(result.GradePoints, previousPoints) {
+				continue
+			}
+
+			if err := s.resultRepo.UpdateGrade(result.ID, result.Grade, result.GradePoints); err != nil {
+				return updated, err
+			}
+			if err := s.integrityService.RecordResult(result.ID, models.LedgerActionUpdate, models.LedgerReasonGradeChanged); err != nil {
+				fmt.Printf("Warning: Failed to record regraded result %d in the ledger: %v\n", result.ID, err)
+			}
+			updated++
+		}
+
+		if len(results) < recomputeBatchSize {
+			break
+		}
+	}
+
+	return updated, nil
+}
+
+// normalizeGradingBands validates bands and orders them from the highest cut-off down.
+// Every percentage must fall into a band, so a band starting at 0 is required.
+func normalizeGradingBands(bands []*models.GradingBand) ([]*models.GradingBand, error) {
+	if len(bands) == 0 {
+		return nil, auth.ErrInvalidGradingScale
+	}
+
+	seen := make(map[float64]bool)
+	hasZero := false
+	normalized := make([]*models.GradingBand, 0, len(bands))
+	for _, band := range bands {
+		if band == nil {
+			return nil, auth.ErrInvalidGradingScale
+		}
+
+		label := strings.TrimSpace(band.Label)
+		if len(label) < 1 || len(label) > 20 {
+			return nil, auth.ErrInvalidGradingScale
+		}
+		if band.MinPercentage < 0 || band.MinPercentage > 100 || seen[band.MinPercentage] {
+			return nil, auth.ErrInvalidGradingScale
+		}
+		if band.GPAPoints != nil && (*band.GPAPoints < 0 || *band.GPAPoints > 99.99) {
+			return nil, auth.ErrInvalidGradingScale
+		}
+
+		seen[band.MinPercentage] = true
+		if band.MinPercentage == 0 {
+			hasZero = true
+		}
+		normalized = append(normalized, &models.GradingBand{
+			Label:         label,
+			MinPercentage: band.MinPercentage,
+			GPAPoints:     band.GPAPoints,
+		})
+	}
+
+	if !hasZero {
+		return nil, auth.ErrInvalidGradingScale
+	}
+
+	scale := &models.GradingScale{Bands: normalized}
+	scale.SortBands()
+	return scale.Bands, nil
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"strings"
+)
+
+// inviteCodeLength is the length of generated group invite codes (40 bits)
+const inviteCodeLength = 8
+
+// GroupService implements the models.GroupService interface
+type GroupService struct {
+	groupRepo models.GroupRepository
+	testRepo  models.TestRepository
+	userRepo  models.UserRepository
+}
+
+// NewGroupService creates a new group service
+func NewGroupService(groupRepo models.GroupRepository, testRepo models.TestRepository, userRepo models.UserRepository) models.GroupService {
+	return &GroupService{
+		groupRepo: groupRepo,
+		testRepo:  testRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// CreateGroup creates a new group that students can join with its invite code
+func (s *GroupService) CreateGroup(ownerID int, name, description string, kind models.GroupKind) (*models.Group, error) {
+	if kind == "" {
+		kind = models.GroupKindClass
+	}
+	if !kind.IsValid() {
+		return nil, auth.ErrInvalidGroupKind
+	}
+
+	code, err := s.newInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	group := &models.Group{
+		Name:        strings.TrimSpace(name),
+		Description: strings.TrimSpace(description),
+		Kind:        kind,
+		OwnerID:     ownerID,
+		InviteCode:  &code,
+	}
+
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// GetGroup retrieves a group by ID
+func (s *GroupService) GetGroup(groupID int) (*models.Group, error) {
+	group, err := s.groupRepo.GetByID(groupID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if group == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return group, nil
+}
+
+// ListGroups retrieves groups with pagination; an owner ID of 0 lists every group
+func (s *GroupService) ListGroups(ownerID int, limit, offset int) ([]*models.Group, error) {
+	return s.groupRepo.List(ownerID, limit, offset)
+}
+
+// GetUserGroups retrieves the groups a user is enrolled in, without their invite codes
+func (s *GroupService) GetUserGroups(userID int) ([]*models.Group, error) {
+	groups, err := s.groupRepo.GetByMember(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		group.InviteCode = nil
+	}
+	return groups, nil
+}
+
+// UpdateGroup updates a group's name, description and kind
+func (s *GroupService) UpdateGroup(groupID int, name, description string, kind models.GroupKind) (*models.Group, error) {
+	group, err := s.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the current kind unless a new one is given
+	if kind == "" {
+		kind = group.Kind
+	}
+	if !kind.IsValid() {
+		return nil, auth.ErrInvalidGroupKind
+	}
+
+	group.Name = strings.TrimSpace(name)
+	group.Description = strings.TrimSpace(description)
+	group.Kind = kind
+
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// DeleteGroup deletes a group; its tests are no longer offered to its members
+func (s *GroupService) DeleteGroup(groupID int) error {
+	if _, err := s.GetGroup(groupID); err != nil {
+		return err
+	}
+
+	return s.groupRepo.Delete(groupID)
+}
+
+// RegenerateInviteCode replaces the invite code of a group, opening enrolment if it was
+// closed. The old code stops working; existing members stay enrolled.
+func (s *GroupService) RegenerateInviteCode(groupID int) (*models.Group, error) {
+	group, err := s.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.newInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.groupRepo.SetInviteCode(groupID, &code); err != nil {
+		return nil, err
+	}
+	group.InviteCode = &code
+
+	return group, nil
+}
+
+// CloseInviteCode removes the invite code of a group so that no one else can join with it
+func (s *GroupService) CloseInviteCode(groupID int) error {
+	if _, err := s.GetGroup(groupID); err != nil {
+		return err
+	}
+
+	return s.groupRepo.SetInviteCode(groupID, nil)
+}
+
+// LinkDirectoryGroup links a group to a directory group by common name, so that directory
+// users are enrolled or removed when they sign in. An empty name removes the link and
+// leaves the current members enrolled.
+func (s *GroupService) LinkDirectoryGroup(groupID int, directoryGroup string) (*models.Group, error) {
+	group, err := s.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(strings.TrimSpace(directoryGroup))
+	if len(name) > 255 || strings.Contains(name, "=") {
+		return nil, auth.ErrInvalidDirectoryGroup
+	}
+
+	var link *string
+	if name != "" {
+		link = &name
+	}
+
+	if err := s.groupRepo.SetDirectoryGroup(groupID, link); err != nil {
+		return nil, err
+	}
+	group.DirectoryGroup = link
+
+	return group, nil
+}
+
+// JoinGroup enrolls a user in the group with the given invite code
+func (s *GroupService) JoinGroup(userID int, inviteCode string) (*models.Group, error) {
+	code := normalizeInviteCode(inviteCode)
+	if len(code) != inviteCodeLength {
+		return nil, auth.ErrInvalidInviteCode
+	}
+
+	group, err := s.groupRepo.GetByInviteCode(code)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if group == nil {
+		return nil, auth.ErrInvalidInviteCode
+	}
+
+	if err := s.groupRepo.AddMember(group.ID, userID); err != nil {
+		return nil, err
+	}
+
+	group, err = s.GetGroup(group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Members don't need the code, and shouldn't pass it on after enrolment closes
+	group.InviteCode = nil
+	return group, nil
+}
+
+// AddMember enrolls a user in a group directly
+func (s *GroupService) AddMember(groupID, userID int) error {
+	if _, err := s.GetGroup(groupID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if user == nil {
+		return auth.ErrUserNotFound
+	}
+
+	return s.groupRepo.AddMember(groupID, userID)
+}
+
+// RemoveMember removes a user from a group
+func (s *GroupService) RemoveMember(groupID, userID int) error {
+	if _, err := s.GetGroup(groupID); err != nil {
+		return err
+	}
+
+	return s.groupRepo.RemoveMember(groupID, userID)
+}
+
+// GetMembers retrieves the members of a group
+func (s *GroupService) GetMembers(groupID int) ([]*models.GroupMember, error) {
+	if _, err := s.GetGroup(groupID); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetMembers(groupID)
+}
+
+// GetTestGroups retrieves the groups a test is assigned to
+func (s *GroupService) GetTestGroups(testID int) ([]*models.Group, error) {
+	if err := s.checkTest(testID); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetByTest(testID)
+}
+
+// AssignTest replaces the groups a test is assigned to. A test without groups is not
+// offered to anyone.
+func (s *GroupService) AssignTest(testID int, groupIDs []int) ([]*models.Group, error) {
+	if err := s.checkTest(testID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	unique := make([]int, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		if seen[groupID] {
+			continue
+		}
+		group, err := s.groupRepo.GetByID(groupID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if group == nil {
+			return nil, auth.ErrInvalidGroupAssignment
+		}
+		seen[groupID] = true
+		unique = append(unique, groupID)
+	}
+
+	if err := s.groupRepo.ReplaceTestGroups(testID, unique); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.GetByTest(testID)
+}
+
+// CheckAssignment checks that a test is assigned to one of the user's groups
+func (s *GroupService) CheckAssignment(testID, userID int) error {
+	assigned, err := s.groupRepo.IsTestAssigned(testID, userID)
+	if err != nil {
+		return err
+	}
+	if !assigned {
+		return auth.ErrTestNotAssigned
+	}
+	return nil
+}
+
+// checkTest checks that a test exists
+func (s *GroupService) checkTest(testID int) error {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if test == nil {
+		return auth.ErrUserNotFound
+	}
+	return nil
+}
+
+// newInviteCode generates an invite code that no other group uses
+func (s *GroupService) newInviteCode() (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		bytes := make([]byte, inviteCodeLength)
+		if _, err := rand.Read(bytes); err != nil {
+			return "", err
+		}
+
+		var code strings.Builder
+		for _, b := range bytes {
+			code.WriteByte(verificationAlphabet[b%32])
+		}
+
+		existing, err := s.groupRepo.GetByInviteCode(code.String())
+		if err != nil && err != sql.ErrNoRows {
+			return "", err
+		}
+		if existing == nil {
+			return code.String(), nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a unique invite code")
+}
+
+// normalizeInviteCode converts an invite code as typed by a student to its stored form,
+// accepting lower case, spaces and dashes and the letters O, I and L for digits
+func normalizeInviteCode(code string) string {
+	var chars []byte
+	for _, r := range strings.ToUpper(code) {
+		switch {
+		case r == 'O':
+			chars = append(chars, '0')
+		case r == 'I' || r == 'L':
+			chars = append(chars, '1')
+		case r < 128 && strings.ContainsRune(verificationAlphabet, r):
+			chars = append(chars, byte(r))
+		case r == '-' || r == ' ':
+		default:
+			return ""
+		}
+	}
+	return string(chars)
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ledgerAppendAttempts is how often an append is retried when another process extended
+// the ledger between reading its head and writing the new entry
+const ledgerAppendAttempts = 5
+
+// IntegrityService implements the models.IntegrityService interface
+type IntegrityService struct {
+	integrityRepo models.IntegrityRepository
+	signer        *auth.ResultSigner
+	mu            sync.Mutex // serializes recording so entries link to the current head
+}
+
+// NewIntegrityService creates a new result integrity service
+func NewIntegrityService(integrityRepo models.IntegrityRepository, signer *auth.ResultSigner) models.IntegrityService {
+	return &IntegrityService{
+		integrityRepo: integrityRepo,
+		signer:        signer,
+	}
+}
+
+// RecordResult appends the stored state of a result to the ledger and signs the result.
+// The result is read back from the database so the recorded payload matches exactly what
+// verification will read later. The lock is held from reading the result to signing it,
+// so concurrent changes to the same result are recorded and signed in the order they
+// were read.
+func (s *IntegrityService) RecordResult(resultID int, action models.LedgerAction, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signed, err := s.integrityRepo.GetSignedResult(resultID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if signed == nil {
+		return auth.ErrUserNotFound
+	}
+
+	payload := models.NewResultPayload(signed.Result)
+	if err := s.appendEntry(resultID, signed.Result.TestID, action, reason, payload); err != nil {
+		return err
+	}
+
+	if action == models.LedgerActionDelete {
+		return nil
+	}
+	return s.integrityRepo.UpdateSignature(resultID, s.signer.Sign([]byte(payload)))
+}
+
+// RecordTestDeletion records the deletion of the results of a deleted test. It runs after
+// the test is deleted and only records results that are gone, as SQLite without foreign
+// key enforcement leaves them in place.
+func (s *IntegrityService) RecordTestDeletion(testID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.integrityRepo.GetLatestEntriesByTest(testID)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Action == models.LedgerActionDelete {
+			continue
+		}
+
+		signed, err := s.integrityRepo.GetSignedResult(entry.ResultID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if signed != nil {
+			continue
+		}
+
+		// The last recorded state is the state the result was deleted in
+		if err := s.appendEntry(entry.ResultID, testID, models.LedgerActionDelete, models.LedgerReasonTestDeleted, entry.Payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SealUnrecorded records and signs every result that has no ledger entry yet, such as
+// results stored before the ledger was introduced. It returns the number sealed.
+func (s *IntegrityService) SealUnrecorded() (int, error) {
+	recorded := make(map[int]bool)
+	err := s.integrityRepo.StreamEntries(func(entry *models.ResultLedgerEntry) error {
+		recorded[entry.ResultID] = true
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var unrecorded []int
+	err = s.integrityRepo.StreamSignedResults(func(signed *models.SignedResult) error {
+		if !recorded[signed.Result.ID] {
+			unrecorded = append(unrecorded, signed.Result.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, resultID := range unrecorded {
+		if err := s.RecordResult(resultID, models.LedgerActionCreate, models.LedgerReasonSealed); err != nil {
+			return i, err
+		}
+	}
+
+	return len(unrecorded), nil
+}
+
+// VerifyAll walks the whole ledger, checking every link, hash and signature, and then
+// compares every stored result with its last recorded state
+func (s *IntegrityService) VerifyAll() (*models.IntegrityReport, error) {
+	report := &models.IntegrityReport{
+		HeadHash: models.LedgerGenesisHash,
+		KeyID:    s.signer.KeyID(),
+		Issues:   []*models.IntegrityIssue{},
+	}
+
+	latest := make(map[int]*models.ResultLedgerEntry)
+	expectedSequence := 1
+	err := s.integrityRepo.StreamEntries(func(entry *models.ResultLedgerEntry) error {
+		report.Entries++
+		sequence := entry.Sequence
+		if entry.Sequence != expectedSequence {
+			report.Issues = append(report.Issues, &models.IntegrityIssue{
+				Problem:  models.IntegrityBrokenChain,
+				Sequence: &sequence,
+				Detail:   fmt.Sprintf("expected entry %d, found entry %d", expectedSequence, entry.Sequence),
+			})
+		}
+		if entry.PrevHash != report.HeadHash {
+			report.Issues = append(report.Issues, &models.IntegrityIssue{
+				Problem:  models.IntegrityBrokenChain,
+				Sequence: &sequence,
+				Detail:   "entry does not link to the previous entry",
+			})
+		}
+		report.Issues = append(report.Issues, s.checkEntry(entry)...)
+
+		report.HeadHash = entry.EntryHash
+		expectedSequence = entry.Sequence + 1
+		latest[entry.ResultID] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.integrityRepo.StreamSignedResults(func(signed *models.SignedResult) error {
+		report.Results++
+		report.Issues = append(report.Issues, s.checkResult(signed, latest[signed.Result.ID])...)
+		delete(latest, signed.Result.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Results recorded as present that no longer exist were deleted behind our back
+	var deleted []int
+	for resultID, entry := range latest {
+		if entry.Action != models.LedgerActionDelete {
+			deleted = append(deleted, resultID)
+		}
+	}
+	sort.Ints(deleted)
+	for i := range deleted {
+		report.Issues = append(report.Issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityDeleted,
+			ResultID: &deleted[i],
+			Detail:   "result is recorded in the ledger but no longer exists",
+		})
+	}
+
+	report.Valid = len(report.Issues) == 0
+	report.VerifiedAt = time.Now()
+	return report, nil
+}
+
+// VerifyResult checks the ledger entries and signature of one result. Links between
+// entries of different results are only checked by VerifyAll.
+func (s *IntegrityService) VerifyResult(resultID int) (*models.ResultIntegrity, error) {
+	history, err := s.integrityRepo.GetEntriesForResult(resultID)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := s.integrityRepo.GetSignedResult(resultID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if signed == nil && len(history) == 0 {
+		return nil, auth.ErrUserNotFound
+	}
+
+	integrity := &models.ResultIntegrity{
+		ResultID:  resultID,
+		PublicKey: s.signer.PublicKey(),
+		KeyID:     s.signer.KeyID(),
+		History:   history,
+		Issues:    []*models.IntegrityIssue{},
+	}
+	if integrity.History == nil {
+		integrity.History = []*models.ResultLedgerEntry{}
+	}
+
+	var last *models.ResultLedgerEntry
+	for _, entry := range history {
+		integrity.Issues = append(integrity.Issues, s.checkEntry(entry)...)
+		last = entry
+	}
+
+	if signed != nil {
+		integrity.Payload = models.NewResultPayload(signed.Result)
+		integrity.Signature = signed.Signature
+		integrity.Issues = append(integrity.Issues, s.checkResult(signed, last)...)
+	} else if last.Action != models.LedgerActionDelete {
+		integrity.Issues = append(integrity.Issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityDeleted,
+			ResultID: &integrity.ResultID,
+			Detail:   "result is recorded in the ledger but no longer exists",
+		})
+	}
+
+	integrity.Valid = len(integrity.Issues) == 0
+	return integrity, nil
+}
+
+// GetSigningKey returns the public half of the result signing key
+func (s *IntegrityService) GetSigningKey() *models.SigningKeyInfo {
+	return &models.SigningKeyInfo{
+		Algorithm: "Ed25519",
+		PublicKey: s.signer.PublicKey(),
+		KeyID:     s.signer.KeyID(),
+	}
+}
+
+// appendEntry links a new entry to the head of the ledger, signs it and stores it; the
+// caller holds the lock
+func (s *IntegrityService) appendEntry(resultID, testID int, action models.LedgerAction, reason, payload string) error {
+	var err error
+	for attempt := 0; attempt < ledgerAppendAttempts; attempt++ {
+		var head *models.ResultLedgerEntry
+		head, err = s.integrityRepo.GetHead()
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		entry := &models.ResultLedgerEntry{
+			Sequence:    1,
+			ResultID:    resultID,
+			TestID:      testID,
+			Action:      action,
+			Reason:      reason,
+			Payload:     payload,
+			PayloadHash: hashHex(payload),
+			PrevHash:    models.LedgerGenesisHash,
+			KeyID:       s.signer.KeyID(),
+			RecordedAt:  time.Now().UTC().Truncate(time.Second),
+		}
+		if head != nil {
+			entry.Sequence = head.Sequence + 1
+			entry.PrevHash = head.EntryHash
+		}
+		entry.EntryHash = ledgerEntryHash(entry)
+		entry.Signature = s.signer.Sign([]byte(entry.EntryHash))
+
+		// The unique sequence rejects the entry if another process appended first
+		if err = s.integrityRepo.AppendEntry(entry); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// checkEntry recomputes the hashes of a ledger entry and checks its signature
+func (s *IntegrityService) checkEntry(entry *models.ResultLedgerEntry) []*models.IntegrityIssue {
+	var issues []*models.IntegrityIssue
+	sequence := entry.Sequence
+	resultID := entry.ResultID
+
+	if hashHex(entry.Payload) != entry.PayloadHash || ledgerEntryHash(entry) != entry.EntryHash {
+		issues = append(issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityBrokenChain,
+			ResultID: &resultID,
+			Sequence: &sequence,
+			Detail:   "entry content does not match its hash",
+		})
+	}
+
+	if entry.KeyID != s.signer.KeyID() {
+		issues = append(issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityBadSignature,
+			ResultID: &resultID,
+			Sequence: &sequence,
+			Detail:   fmt.Sprintf("entry was signed with unknown key %s", entry.KeyID),
+		})
+	} else if !s.signer.Verify([]byte(entry.EntryHash), entry.Signature) {
+		issues = append(issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityBadSignature,
+			ResultID: &resultID,
+			Sequence: &sequence,
+			Detail:   "entry signature does not match",
+		})
+	}
+
+	return issues
+}
+
+// checkResult compares a stored result with its last ledger entry and checks its signature
+func (s *IntegrityService) checkResult(signed *models.SignedResult, last *models.ResultLedgerEntry) []*models.IntegrityIssue {
+	resultID := signed.Result.ID
+	payload := models.NewResultPayload(signed.Result)
+
+	if last == nil {
+		return []*models.IntegrityIssue{{
+			Problem:  models.IntegrityNotRecorded,
+			ResultID: &resultID,
+			Detail:   "result has no ledger entry",
+		}}
+	}
+
+	var issues []*models.IntegrityIssue
+	if last.Action == models.LedgerActionDelete {
+		issues = append(issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityDeletedRecorded,
+			ResultID: &resultID,
+			Detail:   "result exists but its last ledger entry records its deletion",
+		})
+	} else if hashHex(payload) != last.PayloadHash {
+		issues = append(issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityModified,
+			ResultID: &resultID,
+			Detail:   "result differs from its last recorded state",
+		})
+	}
+
+	if signed.Signature == nil || !s.signer.Verify([]byte(payload), *signed.Signature) {
+		issues = append(issues, &models.IntegrityIssue{
+			Problem:  models.IntegrityBadSignature,
+			ResultID: &resultID,
+			Detail:   "result signature is missing or does not match",
+		})
+	}
+
+	return issues
+}
+
+// ledgerEntryHash computes the hash that links an entry into the chain
+func ledgerEntryHash(entry *models.ResultLedgerEntry) string {
+	return hashHex(fmt.Sprintf("%s\n%d\n%d\n%d\n%s\n%s\n%s\n%s",
+		entry.PrevHash, entry.Sequence, entry.ResultID, entry.TestID, entry.Action, entry.Reason, entry.PayloadHash,
+		entry.RecordedAt.UTC().Format(time.RFC3339)))
+}
+
+// hashHex returns the hex SHA-256 of a string
+func hashHex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"gocbt/internal/models"
+	"math"
+)
+
+// Bounds and convergence settings of the IRT estimation
+const (
+	irtMaxIterations     = 500
+	irtConvergence       = 0.001
+	irtLogitBound        = 6.0
+	irtMinDiscrimination = 0.2
+	irtMaxDiscrimination = 4.0
+	irtMaxStep           = 1.0 // largest change of an estimate in one Newton step
+	irtSlopePriorSD      = 0.5 // standard deviation of the lognormal 2PL discrimination prior
+	irtQuadraturePoints  = 41
+)
+
+// irtItem holds the parameters of one item during estimation
+type irtItem struct {
+	difficulty     float64
+	discrimination float64
+}
+
+// irtProbability returns the probability of a correct answer under the logistic model
+func irtProbability(theta float64, item irtItem) float64 {
+	return 1 / (1 + math.Exp(-item.discrimination*(theta-item.difficulty)))
+}
+
+// clampLogit keeps an estimate within the logit bounds
+func clampLogit(value float64) float64 {
+	return math.Max(-irtLogitBound, math.Min(irtLogitBound, value))
+}
+
+// clampDiscrimination keeps a 2PL discrimination within its bounds
+func clampDiscrimination(value float64) float64 {
+	return math.Max(irtMinDiscrimination, math.Min(irtMaxDiscrimination, value))
+}
+
+// dampStep limits a Newton step so that poorly determined estimates cannot run away
+func dampStep(step float64) float64 {
+	return math.Max(-irtMaxStep, math.Min(irtMaxStep, step))
+}
+
+// logit returns the log odds of a proportion, kept away from 0 and 1
+func logit(p float64) float64 {
+	p = math.Max(0.01, math.Min(0.99, p))
+	return math.Log(p / (1 - p))
+}
+
+// estimateAbility returns the maximum a posteriori ability estimate and its standard
+// error for scored responses (1 correct, 0 incorrect), with a standard normal prior.
+// The prior keeps the estimate finite for all-correct and all-incorrect response patterns.
+func estimateAbility(items []irtItem, responses []float64) (float64, float64) {
+	theta := 0.0
+	for iteration := 0; iteration < irtMaxIterations; iteration++ {
+		gradient, information := -theta, 1.0
+		for j, item := range items {
+			p := irtProbability(theta, item)
+			gradient += item.discrimination * (responses[j] - p)
+			information += item.discrimination * item.discrimination * p * (1 - p)
+		}
+
+		step := dampStep(gradient / information)
+		theta = clampLogit(theta + step)
+		if math.Abs(step) < irtConvergence {
+			break
+		}
+	}
+
+	information := 1.0
+	for _, item := range items {
+		p := irtProbability(theta, item)
+		information += item.discrimination * item.discrimination * p * (1 - p)
+	}
+
+	return theta, 1 / math.Sqrt(information)
+}
+
+// calibrateItems estimates item parameters from a candidates-by-items matrix of scored
+// responses. Items answered correctly or incorrectly by every candidate carry no
+// information and are skipped; the returned items are nil for skipped columns.
+// It also returns the number of iterations and whether the estimation converged.
+func calibrateItems(matrix [][]float64, model models.IRTModel) ([]*irtItem, int, bool) {
+	if len(matrix) == 0 {
+		return nil, 0, false
+	}
+
+	if model == models.IRTModel2PL {
+		return calibrate2PL(matrix)
+	}
+	return calibrateRasch(matrix)
+}
+
+// calibrateRasch estimates Rasch difficulties by joint maximum likelihood. Candidates
+// with a perfect or zero score are left out, and the scale is anchored at an average
+// item difficulty of zero.
+func calibrateRasch(matrix [][]float64) ([]*irtItem, int, bool) {
+	persons, items := informativeResponses(matrix)
+	estimates := make([]*irtItem, len(matrix[0]))
+	if len(persons) < 2 || len(items) < 2 {
+		return estimates, 0, false
+	}
+
+	// Start from the log odds of the raw proportions
+	thetas := make([]float64, len(persons))
+	for n, i := range persons {
+		var score float64
+		for _, j := range items {
+			score += matrix[i][j]
+		}
+		thetas[n] = logit(score / float64(len(items)))
+	}
+	difficulties := make([]float64, len(items))
+	for m, j := range items {
+		var correct float64
+		for _, i := range persons {
+			correct += matrix[i][j]
+		}
+		difficulties[m] = -logit(correct / float64(len(persons)))
+	}
+
+	iterations, converged := 0, false
+	for iterations < irtMaxIterations && !converged {
+		iterations++
+		change := 0.0
+
+		for n, i := range persons {
+			residual, information := 0.0, 0.0
+			for m, j := range items {
+				p := irtProbability(thetas[n], irtItem{difficulty: difficulties[m], discrimination: 1})
+				residual += matrix[i][j] - p
+				information += p * (1 - p)
+			}
+			thetas[n] = clampLogit(thetas[n] + dampStep(residual/information))
+		}
+
+		var mean float64
+		for m, j := range items {
+			residual, information := 0.0, 0.0
+			for n, i := range persons {
+				p := irtProbability(thetas[n], irtItem{difficulty: difficulties[m], discrimination: 1})
+				residual += matrix[i][j] - p
+				information += p * (1 - p)
+			}
+			step := dampStep(-residual / information)
+			difficulties[m] = clampLogit(difficulties[m] + step)
+			change = math.Max(change, math.Abs(step))
+			mean += difficulties[m]
+		}
+
+		mean /= float64(len(items))
+		for m := range difficulties {
+			difficulties[m] -= mean
+		}
+		for n := range thetas {
+			thetas[n] -= mean
+		}
+
+		converged = change < irtConvergence
+	}
+
+	// Correct the known bias of joint maximum likelihood difficulties
+	correction := float64(len(items)-1) / float64(len(items))
+	for m, j := range items {
+		estimates[j] = &irtItem{difficulty: difficulties[m] * correction, discrimination: 1}
+	}
+
+	return estimates, iterations, converged
+}
+
+// calibrate2PL estimates 2PL parameters by marginal maximum likelihood with the EM
+// algorithm, integrating ability over a standard normal distribution. A lognormal
+// prior on the discrimination keeps the estimates stable on small samples.
+func calibrate2PL(matrix [][]float64) ([]*irtItem, int, bool) {
+	items := informativeItems(matrix)
+	estimates := make([]*irtItem, len(matrix[0]))
+	if len(items) < 2 {
+		return estimates, 0, false
+	}
+
+	// Quadrature points and weights of the ability distribution
+	points := make([]float64, irtQuadraturePoints)
+	weights := make([]float64, irtQuadraturePoints)
+	var weightSum float64
+	for q := range points {
+		points[q] = -4 + 8*float64(q)/float64(irtQuadraturePoints-1)
+		weights[q] = math.Exp(-points[q] * points[q] / 2)
+		weightSum += weights[q]
+	}
+	for q := range weights {
+		weights[q] /= weightSum
+	}
+
+	params := make([]irtItem, len(items))
+	for m, j := range items {
+		var correct float64
+		for i := range matrix {
+			correct += matrix[i][j]
+		}
+		params[m] = irtItem{difficulty: -logit(correct / float64(len(matrix))), discrimination: 1}
+	}
+
+	expected := make([]float64, irtQuadraturePoints) // candidates at each point
+	correct := make([][]float64, len(items))         // correct answers at each point, by item
+	posterior := make([]float64, irtQuadraturePoints)
+	for m := range correct {
+		correct[m] = make([]float64, irtQuadraturePoints)
+	}
+
+	iterations, converged := 0, false
+	for iterations < irtMaxIterations && !converged {
+		iterations++
+
+		// E-step: expected number of candidates, and of correct answers, at each point
+		for q := range expected {
+			expected[q] = 0
+			for m := range correct {
+				correct[m][q] = 0
+			}
+		}
+		for i := range matrix {
+			var total float64
+			for q, theta := range points {
+				logLikelihood := 0.0
+				for m, j := range items {
+					p := irtProbability(theta, params[m])
+					if matrix[i][j] == 1 {
+						logLikelihood += math.Log(p)
+					} else {
+						logLikelihood += math.Log(1 - p)
+					}
+				}
+				posterior[q] = math.Exp(logLikelihood) * weights[q]
+				total += posterior[q]
+			}
+			if total == 0 {
+				continue
+			}
+			for q := range points {
+				share := posterior[q] / total
+				expected[q] += share
+				for m, j := range items {
+					correct[m][q] += share * matrix[i][j]
+				}
+			}
+		}
+
+		// M-step: one Newton step per item on the slope and intercept of a*theta + c
+		change := 0.0
+		variance := irtSlopePriorSD * irtSlopePriorSD
+		for m := range params {
+			a := params[m].discrimination
+			c := -a * params[m].difficulty
+
+			gradientA := -math.Log(a) / (variance * a)
+			gradientC := 0.0
+			hessianAA := -1 / (variance * a * a)
+			hessianAC, hessianCC := 0.0, 0.0
+			for q, theta := range points {
+				p := 1 / (1 + math.Exp(-(a*theta + c)))
+				residual := correct[m][q] - expected[q]*p
+				weight := expected[q] * p * (1 - p)
+				gradientA += residual * theta
+				gradientC += residual
+				hessianAA -= weight * theta * theta
+				hessianAC -= weight * theta
+				hessianCC -= weight
+			}
+
+			determinant := hessianAA*hessianCC - hessianAC*hessianAC
+			if determinant == 0 {
+				continue
+			}
+			stepA := dampStep(-(hessianCC*gradientA - hessianAC*gradientC) / determinant)
+			stepC := dampStep(-(hessianAA*gradientC - hessianAC*gradientA) / determinant)
+
+			a = clampDiscrimination(a + stepA)
+			c += stepC
+			difficulty := clampLogit(-c / a)
+
+			change = math.Max(change, math.Abs(a-params[m].discrimination))
+			change = math.Max(change, math.Abs(difficulty-params[m].difficulty))
+			params[m] = irtItem{difficulty: difficulty, discrimination: a}
+		}
+
+		converged = change < irtConvergence
+	}
+
+	for m, j := range items {
+		item := params[m]
+		estimates[j] = &item
+	}
+
+	return estimates, iterations, converged
+}
+
+// informativeItems returns the items answered correctly by some but not all candidates
+func informativeItems(matrix [][]float64) []int {
+	var items []int
+	for j := range matrix[0] {
+		var correct float64
+		for i := range matrix {
+			correct += matrix[i][j]
+		}
+		if correct > 0 && correct < float64(len(matrix)) {
+			items = append(items, j)
+		}
+	}
+	return items
+}
+
+// informativeResponses returns the candidates and items that carry information for
+// joint estimation, dropping extreme scores until none are left
+func informativeResponses(matrix [][]float64) ([]int, []int) {
+	persons := make([]int, len(matrix))
+	for i := range persons {
+		persons[i] = i
+	}
+	items := make([]int, len(matrix[0]))
+	for j := range items {
+		items[j] = j
+	}
+
+	for {
+		var keptItems []int
+		for _, j := range items {
+			var correct float64
+			for _, i := range persons {
+				correct += matrix[i][j]
+			}
+			if correct > 0 && correct < float64(len(persons)) {
+				keptItems = append(keptItems, j)
+			}
+		}
+
+		var keptPersons []int
+		for _, i := range persons {
+			var score float64
+			for _, j := range keptItems {
+				score += matrix[i][j]
+			}
+			if score > 0 && score < float64(len(keptItems)) {
+				keptPersons = append(keptPersons, i)
+			}
+		}
+
+		if len(keptItems) == len(items) && len(keptPersons) == len(persons) {
+			return persons, items
+		}
+		persons, items = keptPersons, keptItems
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"gocbt/internal/models"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// simulateResponses draws a candidates-by-items matrix of scored responses from the
+// logistic model, with abilities from a standard normal distribution
+func simulateResponses(items []irtItem, candidates int, seed int64) [][]float64 {
+	random := rand.New(rand.NewSource(seed))
+	matrix := make([][]float64, candidates)
+	for i := range matrix {
+		theta := random.NormFloat64()
+		matrix[i] = make([]float64, len(items))
+		for j, item := range items {
+			if random.Float64() < irtProbability(theta, item) {
+				matrix[i][j] = 1
+			}
+		}
+	}
+	return matrix
+}
+
+func TestEstimateAbility(t *testing.T) {
+	rasch := func(difficulties ...float64) []irtItem {
+		items := make([]irtItem, len(difficulties))
+		for j, difficulty := range difficulties {
+			items[j] = irtItem{difficulty: difficulty, discrimination: 1}
+		}
+		return items
+	}
+
+	tests := []struct {
+		name       string
+		items      []irtItem
+		responses  []float64
+		expectedTh float64
+		expectedSE float64
+	}{
+		{"no items", nil, nil, 0, 1},
+		{"one correct", rasch(0), []float64{1}, 0.4011, 0.8980},
+		{"one incorrect", rasch(0), []float64{0}, -0.4011, 0.8980},
+		{"two of three", rasch(-1, 0, 1), []float64{1, 1, 0}, 0.3048, 0.7823},
+		{"one of three", rasch(-1, 0, 1), []float64{1, 0, 0}, -0.3048, 0.7823},
+		{"2pl", []irtItem{{difficulty: 0, discrimination: 2}, {difficulty: 1, discrimination: 0.5}}, []float64{1, 0}, 0.4038, 0.7228},
+		{"all correct", rasch(-2, -1, 0, 1, 2), []float64{1, 1, 1, 1, 1}, 1.3771, 0.7583},
+	}
+
+	for _, test := range tests {
+		theta, thetaSE := estimateAbility(test.items, test.responses)
+		if math.Abs(theta-test.expectedTh) > 1e-4 || math.Abs(thetaSE-test.expectedSE) > 1e-4 {
+			t.Errorf("estimateAbility(%s) = %.4f, %.4f, expected %.4f, %.4f", test.name, theta, thetaSE, test.expectedTh, test.expectedSE)
+		}
+	}
+}
+
+func TestEstimateAbilityStaysWithinBounds(t *testing.T) {
+	items := make([]irtItem, 200)
+	responses := make([]float64, len(items))
+	for j := range items {
+		items[j] = irtItem{difficulty: 5, discrimination: irtMaxDiscrimination}
+		responses[j] = 1
+	}
+
+	theta, thetaSE := estimateAbility(items, responses)
+	if theta <= 0 || theta > irtLogitBound || math.IsNaN(thetaSE) || thetaSE <= 0 {
+		t.Errorf("estimateAbility(all correct on hard items) = %v, %v, expected 0 < theta <= %v", theta, thetaSE, irtLogitBound)
+	}
+}
+
+func TestClamping(t *testing.T) {
+	tests := []struct {
+		name     string
+		clamp    func(float64) float64
+		value    float64
+		expected float64
+	}{
+		{"clampLogit", clampLogit, 2.5, 2.5},
+		{"clampLogit", clampLogit, 9, irtLogitBound},
+		{"clampLogit", clampLogit, -9, -irtLogitBound},
+		{"clampDiscrimination", clampDiscrimination, 1.3, 1.3},
+		{"clampDiscrimination", clampDiscrimination, 0, irtMinDiscrimination},
+		{"clampDiscrimination", clampDiscrimination, 10, irtMaxDiscrimination},
+		{"dampStep", dampStep, 0.25, 0.25},
+		{"dampStep", dampStep, 3, irtMaxStep},
+		{"dampStep", dampStep, -3, -irtMaxStep},
+	}
+
+	for _, test := range tests {
+		result := test.clamp(test.value)
+		if result != test.expected {
+			t.Errorf("%s(%v) = %v, expected %v", test.name, test.value, result, test.expected)
+		}
+	}
+}
+
+func TestInformativeResponses(t *testing.T) {
+	tests := []struct {
+		name            string
+		matrix          [][]float64
+		expectedPersons []int
+		expectedItems   []int
+	}{
+		{
+			"all informative",
+			[][]float64{{1, 0}, {0, 1}},
+			[]int{0, 1}, []int{0, 1},
+		},
+		{
+			"all-correct and all-wrong items",
+			[][]float64{{1, 1, 0, 0}, {1, 0, 1, 0}, {1, 1, 0, 0}},
+			[]int{0, 1, 2}, []int{1, 2},
+		},
+		{
+			"perfect and zero scores",
+			[][]float64{{1, 1, 1}, {1, 0, 0}, {0, 1, 1}, {0, 0, 0}},
+			[]int{1, 2}, []int{0, 1, 2},
+		},
+		{
+			// Candidate 2 only has a perfect score once the all-correct item is dropped
+			"extreme after dropping an item",
+			[][]float64{{1, 1, 0}, {1, 0, 1}, {1, 1, 1}},
+			[]int{0, 1}, []int{1, 2},
+		},
+		{
+			// Dropping candidates 1 and 2 leaves the remaining items uninformative
+			"nothing left",
+			[][]float64{{1, 1, 0}, {1, 0, 0}, {1, 1, 1}},
+			nil, nil,
+		},
+	}
+
+	for _, test := range tests {
+		persons, items := informativeResponses(test.matrix)
+		if !reflect.DeepEqual(persons, test.expectedPersons) || !reflect.DeepEqual(items, test.expectedItems) {
+			t.Errorf("informativeResponses(%s) = %v, %v, expected %v, %v", test.name, persons, items, test.expectedPersons, test.expectedItems)
+		}
+	}
+}
+
+func TestCalibrateItemsRecoversParameters(t *testing.T) {
+	tests := []struct {
+		model     models.IRTModel
+		items     []irtItem
+		tolerance float64
+	}{
+		{
+			models.IRTModelRasch,
+			[]irtItem{{-1.5, 1}, {-0.5, 1}, {0, 1}, {0.5, 1}, {1.5, 1}},
+			0.15,
+		},
+		{
+			models.IRTModel2PL,
+			[]irtItem{{-1, 0.8}, {-0.5, 1.5}, {0, 1}, {0.5, 2}, {1, 1.2}},
+			0.25,
+		},
+	}
+
+	for _, test := range tests {
+		matrix := simulateResponses(test.items, 3000, 7)
+
+		estimates, iterations, converged := calibrateItems(matrix, test.model)
+		if !converged || iterations >= irtMaxIterations {
+			t.Errorf("calibrateItems(%s) converged = %v after %d iterations, expected convergence", test.model, converged, iterations)
+		}
+		for j, expected := range test.items {
+			estimate := estimates[j]
+			if estimate == nil {
+				t.Errorf("calibrateItems(%s) item %d was skipped", test.model, j)
+				continue
+			}
+			if math.Abs(estimate.difficulty-expected.difficulty) > test.tolerance ||
+				math.Abs(estimate.discrimination-expected.discrimination) > test.tolerance {
+				t.Errorf("calibrateItems(%s) item %d = %+v, expected %+v", test.model, j, *estimate, expected)
+			}
+		}
+	}
+}
+
+func TestCalibrateItemsSkipsUninformativeItems(t *testing.T) {
+	matrix := simulateResponses([]irtItem{{-1, 1}, {0, 1}, {1, 1}}, 500, 3)
+	for i := range matrix {
+		matrix[i] = append(matrix[i], 1, 0) // answered correctly by all, and by none
+	}
+
+	for _, model := range []models.IRTModel{models.IRTModelRasch, models.IRTModel2PL} {
+		estimates, _, _ := calibrateItems(matrix, model)
+		if len(estimates) != 5 {
+			t.Fatalf("calibrateItems(%s) returned %d items, expected 5", model, len(estimates))
+		}
+		for j, estimate := range estimates {
+			skipped := j >= 3
+			if (estimate == nil) != skipped {
+				t.Errorf("calibrateItems(%s) item %d skipped = %v, expected %v", model, j, estimate == nil, skipped)
+			}
+		}
+	}
+}
+
+func TestCalibrateItemsWithoutInformation(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+	}{
+		{"all correct", [][]float64{{1, 1}, {1, 1}}},
+		{"all wrong", [][]float64{{0, 0}, {0, 0}}},
+		{"one informative item", [][]float64{{1, 0}, {0, 0}}},
+	}
+
+	for _, test := range tests {
+		for _, model := range []models.IRTModel{models.IRTModelRasch, models.IRTModel2PL} {
+			estimates, iterations, converged := calibrateItems(test.matrix, model)
+			if converged || iterations != 0 {
+				t.Errorf("calibrateItems(%s, %s) = %d iterations, converged %v, expected none", test.name, model, iterations, converged)
+			}
+			for j, estimate := range estimates {
+				if estimate != nil {
+					t.Errorf("calibrateItems(%s, %s) item %d = %+v, expected skipped", test.name, model, j, *estimate)
+				}
+			}
+		}
+	}
+
+	if estimates, _, _ := calibrateItems(nil, models.IRTModelRasch); estimates != nil {
+		t.Errorf("calibrateItems(empty) = %v, expected nil", estimates)
+	}
+}
+
+func TestCalibrate2PLClampsDiscrimination(t *testing.T) {
+	// A Guttman pattern: every item separates the candidates perfectly
+	var matrix [][]float64
+	for score := 0; score <= 4; score++ {
+		for copies := 0; copies < 20; copies++ {
+			row := make([]float64, 4)
+			for j := 0; j < score; j++ {
+				row[j] = 1
+			}
+			matrix = append(matrix, row)
+		}
+	}
+
+	estimates, _, _ := calibrate2PL(matrix)
+	for j, estimate := range estimates {
+		if estimate == nil {
+			t.Fatalf("calibrate2PL() item %d was skipped", j)
+		}
+		if estimate.discrimination < irtMinDiscrimination || estimate.discrimination > irtMaxDiscrimination ||
+			math.Abs(estimate.difficulty) > irtLogitBound {
+			t.Errorf("calibrate2PL() item %d = %+v, expected parameters within bounds", j, *estimate)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/models"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// oidcLoginTimeout is how long a user has to sign in at the identity provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// OIDCService implements the models.OIDCService interface. A user signed in at the
+// identity provider is matched to an account by the provider's subject; the first
+// sign-in links an existing account with the same email if allowed, or else creates an
+// account with a role mapped from the provider's group or role claim.
+type OIDCService struct {
+	oidcRepo     models.OIDCRepository
+	identityRepo models.IdentityRepository
+	userRepo     models.UserRepository
+	client       *auth.OIDCClient
+	cfg          *config.OIDCConfig
+}
+
+// NewOIDCService creates a new single sign-on service. A nil client disables single
+// sign-on.
+func NewOIDCService(oidcRepo models.OIDCRepository, identityRepo models.IdentityRepository, userRepo models.UserRepository, client *auth.OIDCClient, cfg *config.OIDCConfig) models.OIDCService {
+	return &OIDCService{
+		oidcRepo:     oidcRepo,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		client:       client,
+		cfg:          cfg,
+	}
+}
+
+// StartLogin stores a new single sign-on request and returns the identity provider URL to
+// send the user to
+func (s *OIDCService) StartLogin() (string, error) {
+	if s.client == nil {
+		return "", auth.ErrOIDCNotConfigured
+	}
+
+	// Abandoned requests are never consumed; clear them out as new ones start
+	if err := s.oidcRepo.DeleteExpiredLoginStates(time.Now()); err != nil {
+		return "", err
+	}
+
+	state, err := generateRandomHex(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateRandomHex(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := auth.NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	authorizationURL, err := s.client.AuthorizationURL(state, nonce, auth.PKCEChallenge(verifier))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", auth.ErrOIDCLoginFailed, err)
+	}
+
+	loginState := &models.OIDCLoginState{
+		StateHash:    hashHex(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTimeout),
+	}
+	if err := s.oidcRepo.CreateLoginState(loginState); err != nil {
+		return "", err
+	}
+
+	return authorizationURL, nil
+}
+
+// CompleteLogin redeems the authorization code the identity provider redirected back
+// with and returns the account of the signed-in user and the authentication methods the
+// provider reports, so that a provider's own multi-factor sign-in is recognised
+func (s *OIDCService) CompleteLogin(code, state string) (*models.User, []string, error) {
+	if s.client == nil {
+		return nil, nil, auth.ErrOIDCNotConfigured
+	}
+
+	loginState, err := s.oidcRepo.ConsumeLoginState(hashHex(state))
+	if err != nil {
+		return nil, nil, err
+	}
+	if loginState == nil || loginState.IsExpired() {
+		return nil, nil, auth.ErrInvalidOIDCState
+	}
+
+	idToken, err := s.client.Exchange(code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", auth.ErrOIDCLoginFailed, err)
+	}
+
+	identity, err := s.client.VerifyIDToken(idToken, loginState.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", auth.ErrOIDCLoginFailed, err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" || !identity.EmailVerified {
+		return nil, nil, auth.ErrOIDCEmailNotVerified
+	}
+	if !s.isDomainAllowed(email) {
+		return nil, nil, auth.ErrOIDCDomainNotAllowed
+	}
+
+	user, err := s.findUser(identity, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !user.IsActive {
+		return nil, nil, auth.ErrUserNotActive
+	}
+
+	return user, providerAuthMethods(identity.AMR), nil
+}
+
+// findUser returns the account linked to an identity, linking or creating one on the
+// identity's first sign-in
+func (s *OIDCService) findUser(identity *auth.OIDCIdentity, email string) (*models.User, error) {
+	linked, err := s.identityRepo.GetIdentity(identity.Issuer, identity.Subject)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if linked != nil {
+		if err := s.identityRepo.TouchIdentity(linked.ID, email, time.Now()); err != nil {
+			return nil, err
+		}
+
+		user, err := s.userRepo.GetByID(linked.UserID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if user == nil {
+			return nil, auth.ErrOIDCAccountNotFound
+		}
+		return user, nil
+	}
+
+	existing, err := s.userRepo.GetByEmail(email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var user *models.User
+	switch {
+	case existing != nil && s.cfg.LinkByEmail:
+		user = existing
+	case existing != nil:
+		// Linking would let whoever controls the address at the provider take the account over
+		return nil, auth.ErrEmailExists
+	case s.cfg.AutoProvision:
+		user, err = s.provisionUser(identity, email)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, auth.ErrOIDCAccountNotFound
+	}
+
+	link := &models.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   email,
+	}
+	if err := s.identityRepo.CreateIdentity(link); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionUser creates an account for an identity's first sign-in. The account has no
+// password, so it can only sign in through the identity provider.
+func (s *OIDCService) provisionUser(identity *auth.OIDCIdentity, email string) (*models.User, error) {
+	username, err := s.availableUsername(identity, email)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := identity.GivenName, identity.FamilyName
+	if firstName == "" && lastName == "" {
+		if parts := strings.Fields(identity.Name); len(parts) > 0 {
+			firstName = parts[0]
+			lastName = strings.Join(parts[1:], " ")
+		}
+	}
+	if strings.TrimSpace(firstName) == "" {
+		firstName = username
+	}
+
+	user := &models.User{
+		Username:  username,
+		Email:     email,
+		FirstName: truncate(strings.TrimSpace(firstName), 50),
+		LastName:  truncate(strings.TrimSpace(lastName), 50),
+		Role:      mapRole(identity.Roles, s.cfg.RoleMapping, models.UserRole(s.cfg.DefaultRole)),
+		IsActive:  true,
+		// Sign-in requires the provider to have verified the address
+		EmailVerified: true,
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// availableUsername derives an unused username from the identity's preferred username or
+// its email address, adding a numeric suffix when taken
+func (s *OIDCService) availableUsername(identity *auth.OIDCIdentity, email string) (string, error) {
+	base := usernameFrom(identity.PreferredUsername)
+	if len(base) < 3 {
+		base = usernameFrom(email)
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	for attempt := 1; attempt <= 100; attempt++ {
+		candidate := base
+		if attempt > 1 {
+			suffix := fmt.Sprintf("_%d", attempt)
+			candidate = truncate(base, 50-len(suffix)) + suffix
+		}
+
+		existing, err := s.userRepo.GetByUsername(candidate)
+		if err != nil && err != sql.ErrNoRows {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", auth.ErrUsernameExists
+}
+
+// usernameFrom turns a name into a valid username by replacing characters other than
+// ASCII letters, digits and underscores
+func usernameFrom(name string) string {
+	// Drop the domain of names such as jane.doe@school.example
+	if index := strings.Index(name, "@"); index >= 0 {
+		name = name[:index]
+	}
+
+	var builder strings.Builder
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			builder.WriteRune(unicode.ToLower(r))
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	return truncate(strings.Trim(builder.String(), "_"), 50)
+}
+
+// isDomainAllowed checks if an email address belongs to an allowed domain
+func (s *OIDCService) isDomainAllowed(email string) bool {
+	if len(s.cfg.AllowedDomains) == 0 {
+		return true
+	}
+
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, allowed := range s.cfg.AllowedDomains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens a string to at most the given number of characters
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
+
+// roleRank orders account roles so that the highest mapped role applies
+var roleRank = map[models.UserRole]int{
+	models.RoleStudent: 1,
+	models.RoleTeacher: 2,
+	models.RoleAdmin:   3,
+}
+
+// mapRole returns the highest role that a group or role name of an external account is
+// mapped to, or the fallback role when none is mapped
+func mapRole(names []string, mapping map[string]string, fallback models.UserRole) models.UserRole {
+	role := fallback
+	matched := false
+	for _, name := range names {
+		mapped, ok := mapping[name]
+		if !ok {
+			continue
+		}
+		candidate := models.UserRole(mapped)
+		if !matched || roleRank[candidate] > roleRank[role] {
+			role = candidate
+			matched = true
+		}
+	}
+	return role
+}
+
+// providerAuthMethodPattern matches authentication method references such as pwd or mfa
+var providerAuthMethodPattern = regexp.MustCompile(`^[a-z]{2,10}$`)
+
+// providerAuthMethods keeps the well-formed authentication method references of an ID
+// token, at most eight, so that they fit where refresh tokens store them
+func providerAuthMethods(values []string) []string {
+	var amr []string
+	for _, value := range values {
+		if len(amr) == 8 {
+			break
+		}
+		if providerAuthMethodPattern.MatchString(value) && !models.HasAuthMethod(amr, value) {
+			amr = append(amr, value)
+		}
+	}
+	return amr
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"gocbt/internal/auth"
+	"gocbt/internal/auth/oidctest"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"testing"
+)
+
+// oidcTestEnv is a single sign-on service backed by a fresh SQLite database and a mock
+// identity provider
+type oidcTestEnv struct {
+	provider *oidctest.Provider
+	cfg      *config.OIDCConfig
+	userRepo models.UserRepository
+	service  models.OIDCService
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	provider := oidctest.NewProvider("gocbt")
+	t.Cleanup(provider.Close)
+
+	cfg := &config.OIDCConfig{
+		IssuerURL:     provider.Issuer(),
+		ClientID:      "gocbt",
+		RedirectURL:   "http://localhost:3000/auth/callback",
+		Scopes:        []string{"openid", "email", "profile"},
+		AutoProvision: true,
+		RoleClaim:     "groups",
+		RoleMapping:   map[string]string{"teachers": "teacher", "it-admins": "admin"},
+		DefaultRole:   "student",
+	}
+
+	userRepo := database.NewUserRepository(db)
+	service := NewOIDCService(database.NewOIDCRepository(db), database.NewIdentityRepository(db), userRepo, auth.NewOIDCClient(cfg, nil), cfg)
+
+	return &oidcTestEnv{provider: provider, cfg: cfg, userRepo: userRepo, service: service}
+}
+
+// login signs in at the mock provider with the given claims
+func (e *oidcTestEnv) login(t *testing.T, claims map[string]interface{}) (*models.User, error) {
+	t.Helper()
+
+	e.provider.Claims = claims
+
+	authorizationURL, err := e.service.StartLogin()
+	if err != nil {
+		t.Fatalf("StartLogin() error = %v", err)
+	}
+
+	code, state, err := e.provider.Authorize(authorizationURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	user, _, err := e.service.CompleteLogin(code, state)
+	return user, err
+}
+
+func TestOIDCProvisionsUser(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	user, err := env.login(t, map[string]interface{}{
+		"sub":                "student-1",
+		"email":              "Ada.Lovelace@school.example",
+		"given_name":         "Ada",
+		"family_name":        "Lovelace",
+		"preferred_username": "ada.lovelace",
+	})
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+
+	if user.ID == 0 || user.Username != "ada_lovelace" || user.Email != "ada.lovelace@school.example" {
+		t.Errorf("user = %d %q %q, expected a new ada_lovelace account", user.ID, user.Username, user.Email)
+	}
+	if user.FirstName != "Ada" || user.LastName != "Lovelace" || user.Role != models.RoleStudent {
+		t.Errorf("user = %q %q %s, expected student Ada Lovelace", user.FirstName, user.LastName, user.Role)
+	}
+	if user.PasswordHash != "" {
+		t.Error("provisioned user has a password hash, expected none")
+	}
+
+	// Signing in again returns the same account
+	again, err := env.login(t, map[string]interface{}{"sub": "student-1", "email": "ada.lovelace@school.example"})
+	if err != nil {
+		t.Fatalf("second CompleteLogin() error = %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("second sign-in returned user %d, expected %d", again.ID, user.ID)
+	}
+}
+
+func TestOIDCRoleMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   interface{}
+		expected models.UserRole
+	}{
+		{"no groups", nil, models.RoleStudent},
+		{"unmapped group", []string{"year-10"}, models.RoleStudent},
+		{"teacher group", []string{"year-10", "teachers"}, models.RoleTeacher},
+		{"highest role applies", []string{"it-admins", "teachers"}, models.RoleAdmin},
+		{"single value", "teachers", models.RoleTeacher},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newOIDCTestEnv(t)
+
+			claims := map[string]interface{}{}
+			if test.groups != nil {
+				claims["groups"] = test.groups
+			}
+
+			user, err := env.login(t, claims)
+			if err != nil {
+				t.Fatalf("CompleteLogin() error = %v", err)
+			}
+			if user.Role != test.expected {
+				t.Errorf("role = %s, expected %s", user.Role, test.expected)
+			}
+		})
+	}
+}
+
+func TestOIDCUsernameCollision(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	existing := &models.User{Username: "jdoe", Email: "jdoe@other.example", FirstName: "John", LastName: "Doe", Role: models.RoleStudent, IsActive: true}
+	if err := env.userRepo.Create(existing); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	user, err := env.login(t, map[string]interface{}{"sub": "jdoe-2", "email": "jdoe@school.example"})
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if user.Username != "jdoe_2" {
+		t.Errorf("username = %q, expected jdoe_2", user.Username)
+	}
+}
+
+func TestOIDCExistingEmail(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	existing := &models.User{Username: "teacher1", Email: "teacher@school.example", FirstName: "Grace", LastName: "Hopper", Role: models.RoleTeacher, IsActive: true}
+	if err := env.userRepo.Create(existing); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	claims := map[string]interface{}{"sub": "teacher-1", "email": "teacher@school.example"}
+
+	if _, err := env.login(t, claims); err != auth.ErrEmailExists {
+		t.Errorf("CompleteLogin() without email linking error = %v, expected %v", err, auth.ErrEmailExists)
+	}
+
+	env.cfg.LinkByEmail = true
+	user, err := env.login(t, claims)
+	if err != nil {
+		t.Fatalf("CompleteLogin() with email linking error = %v", err)
+	}
+	if user.ID != existing.ID || user.Role != models.RoleTeacher {
+		t.Errorf("user = %d %s, expected linked teacher %d", user.ID, user.Role, existing.ID)
+	}
+}
+
+func TestOIDCRejectedLogins(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*oidcTestEnv)
+		claims    map[string]interface{}
+		expected  error
+	}{
+		{
+			"domain not allowed",
+			func(e *oidcTestEnv) { e.cfg.AllowedDomains = []string{"school.example"} },
+			map[string]interface{}{"email": "someone@gmail.example"},
+			auth.ErrOIDCDomainNotAllowed,
+		},
+		{
+			"unverified email",
+			func(e *oidcTestEnv) {},
+			map[string]interface{}{"email_verified": false},
+			auth.ErrOIDCEmailNotVerified,
+		},
+		{
+			"provisioning disabled",
+			func(e *oidcTestEnv) { e.cfg.AutoProvision = false },
+			map[string]interface{}{},
+			auth.ErrOIDCAccountNotFound,
+		},
+		{
+			"token for another client",
+			func(e *oidcTestEnv) { e.provider.Audience = "another-client" },
+			map[string]interface{}{},
+			auth.ErrOIDCLoginFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newOIDCTestEnv(t)
+			test.configure(env)
+
+			if _, err := env.login(t, test.claims); !errors.Is(err, test.expected) {
+				t.Errorf("CompleteLogin() error = %v, expected %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestOIDCStateCanOnlyBeUsedOnce(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	authorizationURL, err := env.service.StartLogin()
+	if err != nil {
+		t.Fatalf("StartLogin() error = %v", err)
+	}
+	code, state, err := env.provider.Authorize(authorizationURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	if _, _, err := env.service.CompleteLogin(code, state); err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if _, _, err := env.service.CompleteLogin(code, state); err != auth.ErrInvalidOIDCState {
+		t.Errorf("second CompleteLogin() error = %v, expected %v", err, auth.ErrInvalidOIDCState)
+	}
+	if _, _, err := env.service.CompleteLogin(code, "forged-state"); err != auth.ErrInvalidOIDCState {
+		t.Errorf("CompleteLogin() with an unknown state error = %v, expected %v", err, auth.ErrInvalidOIDCState)
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+)
+
+// ScoreOverrideService implements the models.ScoreOverrideService interface
+type ScoreOverrideService struct {
+	overrideRepo   models.ScoreOverrideRepository
+	answerRepo     models.UserAnswerRepository
+	questionRepo   models.QuestionRepository
+	resultRepo     models.TestResultRepository
+	regradeService models.RegradeService
+}
+
+// NewScoreOverrideService creates a new score override service
+func NewScoreOverrideService(overrideRepo models.ScoreOverrideRepository, answerRepo models.UserAnswerRepository, questionRepo models.QuestionRepository, resultRepo models.TestResultRepository, regradeService models.RegradeService) models.ScoreOverrideService {
+	return &ScoreOverrideService{
+		overrideRepo:   overrideRepo,
+		answerRepo:     answerRepo,
+		questionRepo:   questionRepo,
+		resultRepo:     resultRepo,
+		regradeService: regradeService,
+	}
+}
+
+// OverrideAnswer sets the marks awarded for a single answer
+func (s *ScoreOverrideService) OverrideAnswer(actorID, answerID, marks int, reasonCode models.OverrideReasonCode, reason string) (*models.ScoreOverride, *models.RegradeReport, error) {
+	if !models.IsValidOverrideReasonCode(reasonCode) {
+		return nil, nil, auth.ErrInvalidReasonCode
+	}
+
+	answer, err := s.answerRepo.GetByID(answerID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if answer == nil {
+		return nil, nil, auth.ErrUserNotFound
+	}
+
+	question, err := s.questionRepo.GetByID(answer.QuestionID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if question == nil {
+		return nil, nil, auth.ErrUserNotFound
+	}
+
+	if marks < 0 || marks > question.Marks {
+		return nil, nil, auth.ErrInvalidOverrideMarks
+	}
+
+	originalValue := answer.MarksAwarded
+	override := &models.ScoreOverride{
+		Scope:         models.OverrideScopeAnswer,
+		TestID:        question.TestID,
+		QuestionID:    &question.ID,
+		AnswerID:      &answer.ID,
+		SessionID:     &answer.SessionID,
+		OriginalValue: &originalValue,
+		NewValue:      marks,
+		ReasonCode:    reasonCode,
+		Reason:        reason,
+		CreatedBy:     actorID,
+	}
+
+	return s.apply(override)
+}
+
+// OverrideResult sets the total marks obtained for a result, regardless of its answers
+func (s *ScoreOverrideService) OverrideResult(actorID, resultID, marks int, reasonCode models.OverrideReasonCode, reason string) (*models.ScoreOverride, *models.RegradeReport, error) {
+	if !models.IsValidOverrideReasonCode(reasonCode) {
+		return nil, nil, auth.ErrInvalidReasonCode
+	}
+
+	result, err := s.resultRepo.GetByID(resultID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if result == nil {
+		return nil, nil, auth.ErrUserNotFound
+	}
+
+	if marks < 0 || marks > result.TotalMarks {
+		return nil, nil, auth.ErrInvalidOverrideMarks
+	}
+
+	originalValue := result.MarksObtained
+	override := &models.ScoreOverride{
+		Scope:         models.OverrideScopeResult,
+		TestID:        result.TestID,
+		ResultID:      &result.ID,
+		SessionID:     &result.SessionID,
+		OriginalValue: &originalValue,
+		NewValue:      marks,
+		ReasonCode:    reasonCode,
+		Reason:        reason,
+		CreatedBy:     actorID,
+	}
+
+	return s.apply(override)
+}
+
+// VoidQuestion credits every candidate with the full marks of a question, so totals
+// and passing marks stay unchanged
+func (s *ScoreOverrideService) VoidQuestion(actorID, questionID int, reasonCode models.OverrideReasonCode, reason string) (*models.ScoreOverride, *models.RegradeReport, error) {
+	if !models.IsValidOverrideReasonCode(reasonCode) {
+		return nil, nil, auth.ErrInvalidReasonCode
+	}
+
+	question, err := s.questionRepo.GetByID(questionID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if question == nil {
+		return nil, nil, auth.ErrUserNotFound
+	}
+
+	override := &models.ScoreOverride{
+		Scope:      models.OverrideScopeQuestion,
+		TestID:     question.TestID,
+		QuestionID: &question.ID,
+		NewValue:   question.Marks,
+		ReasonCode: reasonCode,
+		Reason:     reason,
+		CreatedBy:  actorID,
+	}
+
+	return s.apply(override)
+}
+
+// RevokeOverride revokes an override and rescores what it affected
+func (s *ScoreOverrideService) RevokeOverride(actorID, overrideID int) (*models.ScoreOverride, *models.RegradeReport, error) {
+	override, err := s.overrideRepo.GetByID(overrideID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+	if override == nil {
+		return nil, nil, auth.ErrUserNotFound
+	}
+	if !override.IsActive() {
+		return nil, nil, auth.ErrOverrideRevoked
+	}
+
+	if err := s.overrideRepo.Revoke(override.ID, actorID); err != nil {
+		return nil, nil, err
+	}
+
+	report, err := s.rescore(override)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	override, err = s.overrideRepo.GetByID(overrideID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return override, report, nil
+}
+
+// GetTestOverrides retrieves the overrides recorded for a test
+func (s *ScoreOverrideService) GetTestOverrides(testID int, includeRevoked bool) ([]*models.ScoreOverride, error) {
+	return s.overrideRepo.GetByTest(testID, includeRevoked)
+}
+
+// GetOverride retrieves an override by ID
+func (s *ScoreOverrideService) GetOverride(overrideID int) (*models.ScoreOverride, error) {
+	override, err := s.overrideRepo.GetByID(overrideID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if override == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return override, nil
+}
+
+// GetTargetTestID returns the test of the answer, result or question an override of the
+// scope would apply to, so that callers can check who may override it
+func (s *ScoreOverrideService) GetTargetTestID(scope models.OverrideScope, targetID int) (int, error) {
+	switch scope {
+	case models.OverrideScopeAnswer:
+		answer, err := s.answerRepo.GetByID(targetID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+		if answer == nil {
+			return 0, auth.ErrUserNotFound
+		}
+		return s.GetTargetTestID(models.OverrideScopeQuestion, answer.QuestionID)
+	case models.OverrideScopeResult:
+		result, err := s.resultRepo.GetByID(targetID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+		if result == nil {
+			return 0, auth.ErrUserNotFound
+		}
+		return result.TestID, nil
+	default:
+		question, err := s.questionRepo.GetByID(targetID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+		if question == nil {
+			return 0, auth.ErrUserNotFound
+		}
+		return question.TestID, nil
+	}
+}
+
+// apply records an override and rescores what it affects
+func (s *ScoreOverrideService) apply(override *models.ScoreOverride) (*models.ScoreOverride, *models.RegradeReport, error) {
+	if err := s.overrideRepo.Create(override); err != nil {
+		return nil, nil, err
+	}
+
+	report, err := s.rescore(override)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return override, report, nil
+}
+
+// rescore regrades the answers and results an override affects. Overrides are applied
+// by the regrade itself, which is also how they survive later regrades.
+func (s *ScoreOverrideService) rescore(override *models.ScoreOverride) (*models.RegradeReport, error) {
+	switch override.Scope {
+	case models.OverrideScopeQuestion:
+		// Voids affect candidates who skipped the question too
+		return s.regradeService.RegradeTest(override.TestID, false)
+	case models.OverrideScopeResult:
+		return s.regradeService.RegradeSession(*override.SessionID, false)
+	default:
+		report, err := s.regradeService.RegradeSession(*override.SessionID, false)
+		if err == auth.ErrUserNotFound {
+			// The session has no result yet; rescore the answer on its own
+			return s.regradeService.RegradeQuestion(*override.QuestionID, false)
+		}
+		return report, err
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+)
+
+// PracticeService implements the models.PracticeService interface
+type PracticeService struct {
+	practiceRepo models.PracticeResultRepository
+	sessionRepo  models.TestSessionRepository
+	answerRepo   models.UserAnswerRepository
+	testRepo     models.TestRepository
+	questionRepo models.QuestionRepository
+}
+
+// NewPracticeService creates a new practice mode service
+func NewPracticeService(practiceRepo models.PracticeResultRepository, sessionRepo models.TestSessionRepository, answerRepo models.UserAnswerRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository) models.PracticeService {
+	return &PracticeService{
+		practiceRepo: practiceRepo,
+		sessionRepo:  sessionRepo,
+		answerRepo:   answerRepo,
+		testRepo:     testRepo,
+		questionRepo: questionRepo,
+	}
+}
+
+// RecordAttempt scores a submitted practice session and stores it as the user's next attempt
+func (s *PracticeService) RecordAttempt(sessionID int) (*models.PracticeResult, error) {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	test, err := s.testRepo.GetByID(session.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	if !test.IsPractice() {
+		return nil, auth.ErrNotPracticeTest
+	}
+
+	questions, err := s.questionRepo.GetByTestID(test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.answerRepo.GetBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	previousAttempts, err := s.practiceRepo.CountByUserAndTest(session.UserID, test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeTaken *int
+	if session.StartedAt != nil && session.SubmittedAt != nil {
+		duration := int(session.SubmittedAt.Sub(*session.StartedAt).Seconds())
+		timeTaken = &duration
+	}
+
+	// Score the attempt the same way as a graded result, without overrides
+	tally := &models.TestResult{}
+	tallyResult(tally, test, questions, answers, newScoreAdjustments(nil))
+
+	result := &models.PracticeResult{
+		SessionID:         sessionID,
+		TestID:            test.ID,
+		UserID:            session.UserID,
+		AttemptNumber:     previousAttempts + 1,
+		TotalQuestions:    tally.TotalQuestions,
+		AnsweredQuestions: tally.AnsweredQuestions,
+		CorrectAnswers:    tally.CorrectAnswers,
+		TotalMarks:        tally.TotalMarks,
+		MarksObtained:     tally.MarksObtained,
+		Percentage:        tally.Percentage,
+		TimeTaken:         timeTaken,
+	}
+
+	if err := s.practiceRepo.Create(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetUserAttempts retrieves the practice attempts of a user, optionally for one test
+func (s *PracticeService) GetUserAttempts(userID, testID int, limit, offset int) ([]*models.PracticeResult, error) {
+	return s.practiceRepo.GetByUser(userID, testID, limit, offset)
+}
+
+// GetTestAttempts retrieves all practice attempts at a test
+func (s *PracticeService) GetTestAttempts(testID int, limit, offset int) ([]*models.PracticeResult, error) {
+	return s.practiceRepo.GetByTest(testID, limit, offset)
+}
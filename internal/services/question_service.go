@@ -146,6 +146,31 @@ func (s *QuestionService) UpdateQuestion(questionID int, questionText string, ma
 	return question, nil
 }
 
+// UpdateFeedback replaces the explanation and answer feedback of a question
+func (s *QuestionService) UpdateFeedback(questionID int, feedback models.QuestionFeedback) (*models.Question, error) {
+	question, err := s.questionRepo.GetByID(questionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if question == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	question.Explanation = strings.TrimSpace(feedback.Explanation)
+	question.CorrectFeedback = strings.TrimSpace(feedback.CorrectFeedback)
+	question.IncorrectFeedback = strings.TrimSpace(feedback.IncorrectFeedback)
+
+	if err := s.questionRepo.Update(question); err != nil {
+		return nil, err
+	}
+
+	return question, nil
+}
+
 // DeleteQuestion deletes a question
 func (s *QuestionService) DeleteQuestion(questionID int) error {
 	// Check if question exists
@@ -161,7 +186,7 @@ func (s *QuestionService) DeleteQuestion(questionID int) error {
 }
 
 // AddOption adds an option to a question
-func (s *QuestionService) AddOption(questionID int, optionText string, isCorrect bool, orderIndex int) (*models.QuestionOption, error) {
+func (s *QuestionService) AddOption(questionID int, optionText string, isCorrect bool, orderIndex int, feedback string) (*models.QuestionOption, error) {
 	// Validate input
 	if strings.TrimSpace(optionText) == "" {
 		return nil, auth.ErrInvalidCredentials
@@ -172,6 +197,7 @@ func (s *QuestionService) AddOption(questionID int, optionText string, isCorrect
 		OptionText: strings.TrimSpace(optionText),
 		IsCorrect:  isCorrect,
 		OrderIndex: orderIndex,
+		Feedback:   strings.TrimSpace(feedback),
 	}
 
 	if err := s.questionRepo.CreateOption(option); err != nil {
@@ -182,7 +208,7 @@ func (s *QuestionService) AddOption(questionID int, optionText string, isCorrect
 }
 
 // UpdateOption updates a question option
-func (s *QuestionService) UpdateOption(optionID int, optionText string, isCorrect bool, orderIndex int) (*models.QuestionOption, error) {
+func (s *QuestionService) UpdateOption(optionID int, optionText string, isCorrect bool, orderIndex int, feedback string) (*models.QuestionOption, error) {
 	// Validate input
 	if strings.TrimSpace(optionText) == "" {
 		return nil, auth.ErrInvalidCredentials
@@ -193,6 +219,7 @@ func (s *QuestionService) UpdateOption(optionID int, optionText string, isCorrec
 		OptionText: strings.TrimSpace(optionText),
 		IsCorrect:  isCorrect,
 		OrderIndex: orderIndex,
+		Feedback:   strings.TrimSpace(feedback),
 	}
 
 	if err := s.questionRepo.UpdateOption(option); err != nil {
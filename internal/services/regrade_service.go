@@ -0,0 +1,357 @@
+package services
+
+import (
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"sort"
+)
+
+// RegradeService implements the models.RegradeService interface
+type RegradeService struct {
+	testRepo         models.TestRepository
+	questionRepo     models.QuestionRepository
+	answerRepo       models.UserAnswerRepository
+	resultRepo       models.TestResultRepository
+	userRepo         models.UserRepository
+	overrideRepo     models.ScoreOverrideRepository
+	adaptiveRepo     models.AdaptiveRepository
+	gradingService   models.GradingScaleService
+	integrityService models.IntegrityService
+}
+
+// NewRegradeService creates a new regrade service
+func NewRegradeService(testRepo models.TestRepository, questionRepo models.QuestionRepository, answerRepo models.UserAnswerRepository, resultRepo models.TestResultRepository, userRepo models.UserRepository, overrideRepo models.ScoreOverrideRepository, adaptiveRepo models.AdaptiveRepository, gradingService models.GradingScaleService, integrityService models.IntegrityService) models.RegradeService {
+	return &RegradeService{
+		testRepo:         testRepo,
+		questionRepo:     questionRepo,
+		answerRepo:       answerRepo,
+		resultRepo:       resultRepo,
+		userRepo:         userRepo,
+		overrideRepo:     overrideRepo,
+		adaptiveRepo:     adaptiveRepo,
+		gradingService:   gradingService,
+		integrityService: integrityService,
+	}
+}
+
+// regradeScope describes which answers a regrade rescores and which results it recomputes
+type regradeScope struct {
+	answers   []*models.UserAnswer
+	wholeTest bool // recompute every result of the test
+	sessionID int  // recompute this session's result even if none of its answers changed
+}
+
+// RegradeTest rescores every stored answer of a test and recomputes all of its results
+func (s *RegradeService) RegradeTest(testID int, dryRun bool) (*models.RegradeReport, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	questions, err := s.questionRepo.GetByTestID(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := regradeScope{wholeTest: true}
+	for _, question := range questions {
+		answers, err := s.answerRepo.GetByQuestion(question.ID)
+		if err != nil {
+			return nil, err
+		}
+		scope.answers = append(scope.answers, answers...)
+	}
+
+	return s.regrade(test, questions, scope, dryRun)
+}
+
+// RegradeQuestion rescores the stored answers of one question and recomputes the affected results
+func (s *RegradeService) RegradeQuestion(questionID int, dryRun bool) (*models.RegradeReport, error) {
+	question, err := s.questionRepo.GetByID(questionID)
+	if err != nil {
+		return nil, err
+	}
+	if question == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	test, err := s.testRepo.GetByID(question.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	questions, err := s.questionRepo.GetByTestID(test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.answerRepo.GetByQuestion(question.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.regrade(test, questions, regradeScope{answers: answers}, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	report.QuestionID = &question.ID
+
+	return report, nil
+}
+
+// RegradeSession rescores the stored answers of one session and recomputes its result
+func (s *RegradeService) RegradeSession(sessionID int, dryRun bool) (*models.RegradeReport, error) {
+	result, err := s.resultRepo.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	test, err := s.testRepo.GetByID(result.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	questions, err := s.questionRepo.GetByTestID(test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.answerRepo.GetBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.regrade(test, questions, regradeScope{answers: answers, sessionID: sessionID}, dryRun)
+}
+
+// regrade rescores the answers in scope against the current answer keys, then recomputes
+// the results they feed into with the manual overrides applied. A whole-test regrade
+// recomputes every result, so changes to the test itself (total or passing marks) are
+// picked up too. A dry run reports the changes without saving them.
+func (s *RegradeService) regrade(test *models.Test, questions []*models.Question, scope regradeScope, dryRun bool) (*models.RegradeReport, error) {
+	report := &models.RegradeReport{
+		TestID:  test.ID,
+		DryRun:  dryRun,
+		Changes: []*models.RegradeChange{},
+	}
+
+	adjustments, err := loadScoreAdjustments(s.overrideRepo, test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	questionsByID := make(map[int]*models.Question, len(questions))
+	for _, question := range questions {
+		questionsByID[question.ID] = question
+	}
+
+	// Rescore stored answers against the current answer keys
+	keys := make(map[int]*answerKey)
+	rescored := make(map[int]*models.UserAnswer)
+	changedQuestions := make(map[int][]int)
+	for _, answer := range scope.answers {
+		question, ok := questionsByID[answer.QuestionID]
+		if !ok {
+			continue
+		}
+
+		key, ok := keys[question.ID]
+		if !ok {
+			key, err = loadAnswerKey(s.questionRepo, question)
+			if err != nil {
+				return nil, err
+			}
+			keys[question.ID] = key
+		}
+
+		report.AnswersRescored++
+
+		// Stored answers keep their automatic score; voids and overrides are applied
+		// when the result is tallied
+		isCorrect, marksAwarded := key.score(answer.AnswerText, answer.SelectedOptionID)
+		if answer.IsCorrect != nil && *answer.IsCorrect == isCorrect && answer.MarksAwarded == marksAwarded {
+			continue
+		}
+
+		answer.IsCorrect = &isCorrect
+		answer.MarksAwarded = marksAwarded
+		rescored[answer.ID] = answer
+		changedQuestions[answer.SessionID] = append(changedQuestions[answer.SessionID], question.ID)
+		report.AnswersChanged++
+
+		if !dryRun {
+			if err := s.answerRepo.Update(answer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results, err := s.affectedResults(test.ID, changedQuestions, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := s.gradingService.GetScaleForTest(test)
+	if err != nil {
+		return nil, err
+	}
+
+	var adaptiveSettings *models.AdaptiveSettings
+	if test.IsAdaptive() {
+		adaptiveSettings, err = adaptiveSettingsFor(s.adaptiveRepo, test.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Recompute each affected result from its (rescored) answers
+	for _, result := range results {
+		answers, err := s.answerRepo.GetBySession(result.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		for i, answer := range answers {
+			if updated, ok := rescored[answer.ID]; ok {
+				answers[i] = updated
+			}
+		}
+
+		updated := *result
+		form, formQuestions := administeredForm(test, questions, answers)
+		tallyResult(&updated, form, formQuestions, answers, adjustments)
+		updated.Theta, updated.ThetaSE = answerAbility(formQuestions, answers)
+		if adaptiveSettings != nil {
+			scoreAdaptive(&updated, questions, adaptiveSettings)
+		}
+		scale.ApplyTo(&updated)
+		report.ResultsRecomputed++
+
+		scoreChanged := updated.MarksObtained != result.MarksObtained || updated.Percentage != result.Percentage ||
+			updated.IsPassed != result.IsPassed || !sameGrade(updated.Grade, result.Grade)
+		countsChanged := updated.TotalQuestions != result.TotalQuestions || updated.AnsweredQuestions != result.AnsweredQuestions ||
+			updated.CorrectAnswers != result.CorrectAnswers || updated.TotalMarks != result.TotalMarks ||
+			updated.HasOverride != result.HasOverride || !samePoints(updated.GradePoints, result.GradePoints) ||
+			!samePoints(updated.Theta, result.Theta) || !samePoints(updated.ThetaSE, result.ThetaSE)
+		if !scoreChanged && !countsChanged {
+			continue
+		}
+
+		if !dryRun {
+			if err := s.resultRepo.Update(&updated); err != nil {
+				return nil, err
+			}
+			// An unrecorded change would pass for tampering, so the regrade stops here
+			if err := s.integrityService.RecordResult(updated.ID, models.LedgerActionUpdate, models.LedgerReasonRegraded); err != nil {
+				return nil, fmt.Errorf("failed to record regraded result %d in the ledger: %w", updated.ID, err)
+			}
+		}
+
+		if !scoreChanged {
+			continue
+		}
+
+		change := &models.RegradeChange{
+			ResultID:          result.ID,
+			SessionID:         result.SessionID,
+			UserID:            result.UserID,
+			OldMarksObtained:  result.MarksObtained,
+			NewMarksObtained:  updated.MarksObtained,
+			OldPercentage:     result.Percentage,
+			NewPercentage:     updated.Percentage,
+			OldGrade:          result.Grade,
+			NewGrade:          updated.Grade,
+			OldIsPassed:       result.IsPassed,
+			NewIsPassed:       updated.IsPassed,
+			PassStatusChanged: updated.IsPassed != result.IsPassed,
+			ChangedQuestions:  changedQuestions[result.SessionID],
+		}
+		if change.ChangedQuestions == nil {
+			change.ChangedQuestions = []int{}
+		}
+		if user, err := s.userRepo.GetByID(result.UserID); err == nil && user != nil {
+			change.Username = user.Username
+		}
+		if change.PassStatusChanged {
+			report.PassStatusChanges++
+		}
+		report.Changes = append(report.Changes, change)
+	}
+
+	return report, nil
+}
+
+// affectedResults returns the results to recompute: all results of the test for a
+// whole-test regrade, otherwise those of sessions with rescored answers
+func (s *RegradeService) affectedResults(testID int, changedQuestions map[int][]int, scope regradeScope) ([]*models.TestResult, error) {
+	var results []*models.TestResult
+
+	if scope.wholeTest {
+		for offset := 0; ; offset += recomputeBatchSize {
+			page, err := s.resultRepo.GetByTest(testID, recomputeBatchSize, offset)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, page...)
+			if len(page) < recomputeBatchSize {
+				break
+			}
+		}
+		return results, nil
+	}
+
+	sessions := make(map[int]bool, len(changedQuestions)+1)
+	for sessionID := range changedQuestions {
+		sessions[sessionID] = true
+	}
+	if scope.sessionID != 0 {
+		sessions[scope.sessionID] = true
+	}
+
+	sessionIDs := make([]int, 0, len(sessions))
+	for sessionID := range sessions {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	sort.Ints(sessionIDs)
+
+	for _, sessionID := range sessionIDs {
+		result, err := s.resultRepo.GetBySessionID(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		// Sessions still in progress have no result yet
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// sameGrade reports whether two optional grades are equal
+func sameGrade(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// samePoints reports whether two optional GPA point values are equal
+func samePoints(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
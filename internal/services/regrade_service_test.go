@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// failingLedger records results in the ledger until told to fail
+type failingLedger struct {
+	models.IntegrityService
+	fail bool
+}
+
+func (l *failingLedger) RecordResult(resultID int, action models.LedgerAction, reason string) error {
+	if l.fail {
+		return errors.New("ledger unavailable")
+	}
+	return l.IntegrityService.RecordResult(resultID, action, reason)
+}
+
+// regradeTestEnv is a graded test with one calibrated two-mark question, answered wrongly
+// by a candidate whose result has been calculated, backed by a fresh SQLite database
+type regradeTestEnv struct {
+	regrade      models.RegradeService
+	overrides    models.ScoreOverrideService
+	questionRepo models.QuestionRepository
+	answerRepo   models.UserAnswerRepository
+	resultRepo   models.TestResultRepository
+	ledger       *failingLedger
+	teacher      *models.User
+	question     *models.Question
+	right        *models.QuestionOption
+	wrong        *models.QuestionOption
+	answer       *models.UserAnswer
+	result       *models.TestResult
+}
+
+func newRegradeTestEnv(t *testing.T) *regradeTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	userRepo := database.NewUserRepository(db)
+	testRepo := database.NewTestRepository(db)
+	questionRepo := database.NewQuestionRepository(db)
+	sessionRepo := database.NewTestSessionRepository(db)
+	answerRepo := database.NewUserAnswerRepository(db)
+	resultRepo := database.NewTestResultRepository(db)
+	overrideRepo := database.NewScoreOverrideRepository(db)
+	adaptiveRepo := database.NewAdaptiveRepository(db)
+
+	signer, err := auth.LoadResultSigner(&config.IntegrityConfig{SigningKeyFile: filepath.Join(t.TempDir(), "result.key")}, true)
+	if err != nil {
+		t.Fatalf("LoadResultSigner() error = %v", err)
+	}
+	ledger := &failingLedger{IntegrityService: NewIntegrityService(database.NewIntegrityRepository(db), signer)}
+	gradingService := NewGradingScaleService(database.NewGradingScaleRepository(db), testRepo, resultRepo, ledger)
+	regradeService := NewRegradeService(testRepo, questionRepo, answerRepo, resultRepo, userRepo, overrideRepo, adaptiveRepo, gradingService, ledger)
+	resultService := NewTestResultService(resultRepo, sessionRepo, answerRepo, testRepo, questionRepo, overrideRepo, adaptiveRepo, gradingService, ledger)
+
+	env := &regradeTestEnv{
+		regrade:      regradeService,
+		overrides:    NewScoreOverrideService(overrideRepo, answerRepo, questionRepo, resultRepo, regradeService),
+		questionRepo: questionRepo,
+		answerRepo:   answerRepo,
+		resultRepo:   resultRepo,
+		ledger:       ledger,
+	}
+
+	createUser := func(username string, role models.UserRole) *models.User {
+		user := &models.User{Username: username, Email: username + "@school.example", FirstName: "Alan", LastName: "Turing", Role: role, IsActive: true}
+		if err := userRepo.Create(user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return user
+	}
+	env.teacher = createUser("teacher", models.RoleTeacher)
+	candidate := createUser("candidate", models.RoleStudent)
+
+	test := &models.Test{Title: "Quiz", CreatedBy: env.teacher.ID, DurationMinutes: 10, TotalMarks: 2, PassingMarks: 1, IsActive: true, Mode: models.TestModeGraded}
+	if err := testRepo.Create(test); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	env.question = &models.Question{TestID: test.ID, QuestionText: "2 + 2?", QuestionType: models.QuestionTypeMultipleChoice, Marks: 2}
+	if err := questionRepo.Create(env.question); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := questionRepo.UpdateIRTParameters(env.question.ID, &models.ItemParameters{Difficulty: 0, Discrimination: 1}); err != nil {
+		t.Fatalf("UpdateIRTParameters() error = %v", err)
+	}
+	env.right = &models.QuestionOption{QuestionID: env.question.ID, OptionText: "4", IsCorrect: true}
+	env.wrong = &models.QuestionOption{QuestionID: env.question.ID, OptionText: "5", OrderIndex: 1}
+	for _, option := range []*models.QuestionOption{env.right, env.wrong} {
+		if err := questionRepo.CreateOption(option); err != nil {
+			t.Fatalf("CreateOption() error = %v", err)
+		}
+	}
+
+	now := time.Now()
+	session := &models.TestSession{
+		TestID:       test.ID,
+		UserID:       candidate.ID,
+		SessionToken: "regrade-session",
+		Status:       models.SessionStatusSubmitted,
+		StartedAt:    &now,
+		SubmittedAt:  &now,
+		ExpiresAt:    now.Add(time.Hour),
+	}
+	if err := sessionRepo.Create(session); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	incorrect := false
+	env.answer = &models.UserAnswer{SessionID: session.ID, QuestionID: env.question.ID, SelectedOptionID: &env.wrong.ID, IsCorrect: &incorrect}
+	if err := answerRepo.Create(env.answer); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	env.result, err = resultService.CalculateResult(session.ID)
+	if err != nil {
+		t.Fatalf("CalculateResult() error = %v", err)
+	}
+	return env
+}
+
+// storedAnswer reloads the candidate's answer as it is stored
+func (e *regradeTestEnv) storedAnswer(t *testing.T) *models.UserAnswer {
+	t.Helper()
+
+	answers, err := e.answerRepo.GetBySession(e.answer.SessionID)
+	if err != nil || len(answers) != 1 {
+		t.Fatalf("GetBySession() = %v, %v, expected one answer", answers, err)
+	}
+	return answers[0]
+}
+
+// storedResult reloads the candidate's result as it is stored
+func (e *regradeTestEnv) storedResult(t *testing.T) *models.TestResult {
+	t.Helper()
+
+	result, err := e.resultRepo.GetByID(e.result.ID)
+	if err != nil || result == nil {
+		t.Fatalf("GetByID() = %v, %v, expected the result", result, err)
+	}
+	return result
+}
+
+func TestRegradeKeepsAutomaticScoreOfOverriddenAnswers(t *testing.T) {
+	env := newRegradeTestEnv(t)
+
+	override, _, err := env.overrides.VoidQuestion(env.teacher.ID, env.question.ID, models.OverrideReasonFaultyQuestion, "two answers are right")
+	if err != nil {
+		t.Fatalf("VoidQuestion() error = %v", err)
+	}
+
+	answer := env.storedAnswer(t)
+	if answer.IsCorrect == nil || *answer.IsCorrect || answer.MarksAwarded != 0 {
+		t.Errorf("VoidQuestion() stored answer = %v, %d marks, expected the automatic score: incorrect, 0 marks", answer.IsCorrect, answer.MarksAwarded)
+	}
+	if result := env.storedResult(t); result.MarksObtained != 2 || !result.IsPassed || !result.HasOverride {
+		t.Errorf("VoidQuestion() result = %d marks, passed %v, override %v, expected 2, true, true", result.MarksObtained, result.IsPassed, result.HasOverride)
+	}
+
+	if _, _, err := env.overrides.RevokeOverride(env.teacher.ID, override.ID); err != nil {
+		t.Fatalf("RevokeOverride() error = %v", err)
+	}
+	if result := env.storedResult(t); result.MarksObtained != 0 || result.IsPassed || result.HasOverride {
+		t.Errorf("RevokeOverride() result = %d marks, passed %v, override %v, expected 0, false, false", result.MarksObtained, result.IsPassed, result.HasOverride)
+	}
+}
+
+func TestRegradeRecomputesAbility(t *testing.T) {
+	env := newRegradeTestEnv(t)
+
+	if env.result.Theta == nil || *env.result.Theta >= 0 {
+		t.Fatalf("CalculateResult() theta = %v, expected a negative estimate", formatFloatPointer(env.result.Theta))
+	}
+
+	// Accept the candidate's answer as well, as a corrected key would
+	env.wrong.IsCorrect = true
+	if err := env.questionRepo.UpdateOption(env.wrong); err != nil {
+		t.Fatalf("UpdateOption() error = %v", err)
+	}
+	if _, err := env.regrade.RegradeQuestion(env.question.ID, false); err != nil {
+		t.Fatalf("RegradeQuestion() error = %v", err)
+	}
+
+	result := env.storedResult(t)
+	if result.Theta == nil || *result.Theta <= 0 || result.ThetaSE == nil || *result.ThetaSE != *env.result.ThetaSE {
+		t.Errorf("RegradeQuestion() theta = %v (SE %v), expected a positive estimate with SE %v",
+			formatFloatPointer(result.Theta), formatFloatPointer(result.ThetaSE), formatFloatPointer(env.result.ThetaSE))
+	}
+}
+
+func TestRegradeFailsWhenTheLedgerFails(t *testing.T) {
+	env := newRegradeTestEnv(t)
+
+	env.wrong.IsCorrect = true
+	if err := env.questionRepo.UpdateOption(env.wrong); err != nil {
+		t.Fatalf("UpdateOption() error = %v", err)
+	}
+
+	env.ledger.fail = true
+	if report, err := env.regrade.RegradeQuestion(env.question.ID, false); err == nil {
+		t.Errorf("RegradeQuestion() = %+v, expected the ledger error", report)
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"time"
+)
+
+// ReleaseService implements the models.ReleaseService interface
+type ReleaseService struct {
+	releaseRepo  models.ReleaseRepository
+	testRepo     models.TestRepository
+	resultRepo   models.TestResultRepository
+	questionRepo models.QuestionRepository
+	answerRepo   models.UserAnswerRepository
+}
+
+// NewReleaseService creates a new result release service
+func NewReleaseService(releaseRepo models.ReleaseRepository, testRepo models.TestRepository, resultRepo models.TestResultRepository, questionRepo models.QuestionRepository, answerRepo models.UserAnswerRepository) models.ReleaseService {
+	return &ReleaseService{
+		releaseRepo:  releaseRepo,
+		testRepo:     testRepo,
+		resultRepo:   resultRepo,
+		questionRepo: questionRepo,
+		answerRepo:   answerRepo,
+	}
+}
+
+// GetSettings retrieves the result release settings for a test, or the defaults if none are set
+func (s *ReleaseService) GetSettings(testID int) (*models.TestReleaseSettings, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return s.effectiveSettings(testID)
+}
+
+// UpdateSettings creates or replaces the result release settings for a test
+func (s *ReleaseService) UpdateSettings(testID int, scoreRelease models.ScoreReleasePolicy, showCorrectAnswers, showExplanations bool) (*models.TestReleaseSettings, error) {
+	if !models.IsValidScoreReleasePolicy(scoreRelease) {
+		return nil, auth.ErrInvalidReleasePolicy
+	}
+
+	settings, err := s.GetSettings(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.ScoreRelease = scoreRelease
+	settings.ShowCorrectAnswers = showCorrectAnswers
+	settings.ShowExplanations = showExplanations
+
+	if err := s.releaseRepo.UpsertSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// DeleteSettings resets a test to the default release settings
+func (s *ReleaseService) DeleteSettings(testID int) error {
+	return s.releaseRepo.DeleteSettings(testID)
+}
+
+// ReleaseResults manually releases the results of a test to candidates now
+func (s *ReleaseService) ReleaseResults(testID int) (*models.TestReleaseSettings, error) {
+	settings, err := s.GetSettings(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	settings.ReleasedAt = &now
+
+	if err := s.releaseRepo.UpsertSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// WithdrawResults undoes a manual release
+func (s *ReleaseService) WithdrawResults(testID int) (*models.TestReleaseSettings, error) {
+	settings, err := s.GetSettings(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.ReleasedAt = nil
+
+	if err := s.releaseRepo.UpsertSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// WithholdUnreleased hides the scores of results whose test has not released them yet
+func (s *ReleaseService) WithholdUnreleased(results ...*models.TestResult) error {
+	now := time.Now()
+	released := make(map[int]bool)
+
+	for _, result := range results {
+		isReleased, ok := released[result.TestID]
+		if !ok {
+			var err error
+			isReleased, err = s.isReleased(result.TestID, now)
+			if err != nil {
+				return err
+			}
+			released[result.TestID] = isReleased
+		}
+
+		if !isReleased {
+			result.Withhold()
+		}
+	}
+
+	return nil
+}
+
+// AnswerScoresVisible reports whether a session's candidate may see which answers were
+// correct and the marks they earned. Practice quizzes always show them; other tests only
+// once the session is over and the test's scores are released, so nothing leaks while
+// the exam is running.
+func (s *ReleaseService) AnswerScoresVisible(session *models.TestSession) (bool, error) {
+	test, err := s.testRepo.GetByID(session.TestID)
+	if err != nil {
+		return false, err
+	}
+	if test == nil {
+		return false, auth.ErrUserNotFound
+	}
+	if test.IsPractice() {
+		return true, nil
+	}
+
+	over := session.IsExpired() || session.Status == models.SessionStatusCompleted ||
+		session.Status == models.SessionStatusSubmitted || session.Status == models.SessionStatusExpired
+	if !over {
+		return false, nil
+	}
+
+	return s.isReleased(session.TestID, time.Now())
+}
+
+// GetReview builds the answer review of a result. Without full access (teachers and
+// admins have it) the review follows the test's release settings.
+func (s *ReleaseService) GetReview(resultID int, fullAccess bool) (*models.ResultReview, error) {
+	result, err := s.resultRepo.GetByID(resultID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if result == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	test, err := s.testRepo.GetByID(result.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	settings, err := s.effectiveSettings(test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fullAccess && !settings.IsReleased(test, time.Now()) {
+		return nil, auth.ErrResultNotReleased
+	}
+
+	review := &models.ResultReview{
+		Result:             result,
+		ShowCorrectAnswers: fullAccess || settings.ShowCorrectAnswers,
+		ShowExplanations:   fullAccess || settings.ShowExplanations,
+		Items:              []*models.ReviewItem{},
+	}
+
+	questions, err := s.questionRepo.GetByTestID(test.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := s.answerRepo.GetBySession(result.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	answersByQuestion := make(map[int]*models.UserAnswer, len(answers))
+	for _, answer := range answers {
+		answersByQuestion[answer.QuestionID] = answer
+	}
+
+	// Adaptive candidates only review the questions they were asked, not the whole pool
+	_, questions = administeredForm(test, questions, answers)
+
+	for _, question := range questions {
+		item, err := s.reviewItem(question, answersByQuestion[question.ID], review.ShowCorrectAnswers, review.ShowExplanations)
+		if err != nil {
+			return nil, err
+		}
+		review.Items = append(review.Items, item)
+	}
+
+	return review, nil
+}
+
+// reviewItem builds the review of one question, hiding the answer key and feedback unless they are shown
+func (s *ReleaseService) reviewItem(question *models.Question, answer *models.UserAnswer, showCorrectAnswers, showExplanations bool) (*models.ReviewItem, error) {
+	item := &models.ReviewItem{
+		QuestionID:   question.ID,
+		QuestionText: question.QuestionText,
+		QuestionType: question.QuestionType,
+		Marks:        question.Marks,
+	}
+
+	if answer != nil {
+		item.AnswerText = answer.AnswerText
+		item.SelectedOptionID = answer.SelectedOptionID
+		item.IsCorrect = answer.IsCorrect
+		item.MarksAwarded = answer.MarksAwarded
+	}
+
+	var selectedOption *models.QuestionOption
+
+	switch question.QuestionType {
+	case models.QuestionTypeMultipleChoice, models.QuestionTypeTrueFalse:
+		options, err := s.questionRepo.GetOptionsByQuestionID(question.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, option := range options {
+			if item.SelectedOptionID != nil && *item.SelectedOptionID == option.ID {
+				selectedOption = option
+			}
+
+			reviewOption := &models.ReviewOption{
+				ID:         option.ID,
+				OptionText: option.OptionText,
+			}
+			if showCorrectAnswers {
+				isCorrect := option.IsCorrect
+				reviewOption.IsCorrect = &isCorrect
+			}
+			item.Options = append(item.Options, reviewOption)
+		}
+	case models.QuestionTypeShortAnswer:
+		if showCorrectAnswers {
+			correctAnswers, err := s.questionRepo.GetCorrectAnswersByQuestionID(question.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, correctAnswer := range correctAnswers {
+				item.CorrectAnswers = append(item.CorrectAnswers, correctAnswer.AnswerText)
+			}
+		}
+	}
+
+	if showExplanations {
+		item.Feedback = question.FeedbackFor(item.IsCorrect != nil && *item.IsCorrect, selectedOption)
+	}
+
+	return item, nil
+}
+
+// effectiveSettings returns the stored release settings of a test or the defaults
+func (s *ReleaseService) effectiveSettings(testID int) (*models.TestReleaseSettings, error) {
+	settings, err := s.releaseRepo.GetSettings(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if settings == nil {
+		return models.DefaultReleaseSettings(testID), nil
+	}
+	return settings, nil
+}
+
+// isReleased checks if the results of a test are visible to candidates
+func (s *ReleaseService) isReleased(testID int, now time.Time) (bool, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return false, err
+	}
+	if test == nil {
+		return false, auth.ErrUserNotFound
+	}
+
+	settings, err := s.effectiveSettings(testID)
+	if err != nil {
+		return false, err
+	}
+
+	return settings.IsReleased(test, now), nil
+}
@@ -2,27 +2,36 @@ package services
 
 import (
 	"database/sql"
+	"fmt"
 	"gocbt/internal/auth"
 	"gocbt/internal/models"
 )
 
 // TestResultService implements the models.TestResultService interface
 type TestResultService struct {
-	resultRepo   models.TestResultRepository
-	sessionRepo  models.TestSessionRepository
-	answerRepo   models.UserAnswerRepository
-	testRepo     models.TestRepository
-	questionRepo models.QuestionRepository
+	resultRepo       models.TestResultRepository
+	sessionRepo      models.TestSessionRepository
+	answerRepo       models.UserAnswerRepository
+	testRepo         models.TestRepository
+	questionRepo     models.QuestionRepository
+	overrideRepo     models.ScoreOverrideRepository
+	adaptiveRepo     models.AdaptiveRepository
+	gradingService   models.GradingScaleService
+	integrityService models.IntegrityService
 }
 
 // NewTestResultService creates a new test result service
-func NewTestResultService(resultRepo models.TestResultRepository, sessionRepo models.TestSessionRepository, answerRepo models.UserAnswerRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository) models.TestResultService {
+func NewTestResultService(resultRepo models.TestResultRepository, sessionRepo models.TestSessionRepository, answerRepo models.UserAnswerRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository, overrideRepo models.ScoreOverrideRepository, adaptiveRepo models.AdaptiveRepository, gradingService models.GradingScaleService, integrityService models.IntegrityService) models.TestResultService {
 	return &TestResultService{
-		resultRepo:   resultRepo,
-		sessionRepo:  sessionRepo,
-		answerRepo:   answerRepo,
-		testRepo:     testRepo,
-		questionRepo: questionRepo,
+		resultRepo:       resultRepo,
+		sessionRepo:      sessionRepo,
+		answerRepo:       answerRepo,
+		testRepo:         testRepo,
+		questionRepo:     questionRepo,
+		overrideRepo:     overrideRepo,
+		adaptiveRepo:     adaptiveRepo,
+		gradingService:   gradingService,
+		integrityService: integrityService,
 	}
 }
 
@@ -55,6 +64,11 @@ func (s *TestResultService) CalculateResult(sessionID int) (*models.TestResult,
 		return nil, auth.ErrUserNotFound
 	}
 
+	// Practice attempts never become graded results
+	if test.IsPractice() {
+		return nil, auth.ErrPracticeTest
+	}
+
 	// Get all questions for the test
 	questions, err := s.questionRepo.GetByTestID(session.TestID)
 	if err != nil {
@@ -67,28 +81,12 @@ func (s *TestResultService) CalculateResult(sessionID int) (*models.TestResult,
 		return nil, err
 	}
 
-	// Calculate statistics
-	totalQuestions := len(questions)
-	answeredQuestions := len(answers)
-	correctAnswers := 0
-	marksObtained := 0
-
-	for _, answer := range answers {
-		if answer.IsCorrect != nil && *answer.IsCorrect {
-			correctAnswers++
-		}
-		marksObtained += answer.MarksAwarded
-	}
-
-	// Calculate percentage
-	var percentage float64
-	if test.TotalMarks > 0 {
-		percentage = (float64(marksObtained) / float64(test.TotalMarks)) * 100
+	// Get manual overrides, such as voided questions, that already apply to the session
+	overrides, err := s.overrideRepo.GetActiveForSession(session.TestID, sessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Determine if passed
-	isPassed := marksObtained >= test.PassingMarks
-
 	// Calculate time taken
 	var timeTaken *int
 	if session.StartedAt != nil && session.SubmittedAt != nil {
@@ -96,30 +94,48 @@ func (s *TestResultService) CalculateResult(sessionID int) (*models.TestResult,
 		timeTaken = &duration
 	}
 
+	// Adaptive sessions are scored on the questions they were asked
+	pool := questions
+	test, questions = administeredForm(test, questions, answers)
+
 	// Create result
 	result := &models.TestResult{
-		SessionID:         sessionID,
-		TestID:            session.TestID,
-		UserID:            session.UserID,
-		TotalQuestions:    totalQuestions,
-		AnsweredQuestions: answeredQuestions,
-		CorrectAnswers:    correctAnswers,
-		TotalMarks:        test.TotalMarks,
-		MarksObtained:     marksObtained,
-		Percentage:        percentage,
-		IsPassed:          isPassed,
-		TimeTaken:         timeTaken,
-	}
-
-	// Calculate grade
-	grade := result.CalculateGrade()
-	result.Grade = &grade
+		SessionID: sessionID,
+		TestID:    session.TestID,
+		UserID:    session.UserID,
+		TimeTaken: timeTaken,
+	}
+	tallyResult(result, test, questions, answers, newScoreAdjustments(overrides))
+
+	// Estimate ability once every question of the test has been calibrated
+	result.Theta, result.ThetaSE = answerAbility(questions, answers)
+
+	// Adaptive results pass on ability rather than on the marks of the questions asked
+	if test.IsAdaptive() {
+		settings, err := adaptiveSettingsFor(s.adaptiveRepo, test.ID)
+		if err != nil {
+			return nil, err
+		}
+		scoreAdaptive(result, pool, settings)
+	}
+
+	// Calculate grade using the test's grading scale
+	scale, err := s.gradingService.GetScaleForTest(test)
+	if err != nil {
+		return nil, err
+	}
+	scale.ApplyTo(result)
 
 	// Save result
 	if err := s.resultRepo.Create(result); err != nil {
 		return nil, err
 	}
 
+	// Verification reports the result as not recorded if this fails
+	if err := s.integrityService.RecordResult(result.ID, models.LedgerActionCreate, models.LedgerReasonCalculated); err != nil {
+		fmt.Printf("Warning: Failed to record result %d in the ledger: %v\n", result.ID, err)
+	}
+
 	return result, nil
 }
 
@@ -137,6 +153,10 @@ func (s *TestResultService) GetResult(resultID int) (*models.TestResult, error)
 		return nil, auth.ErrUserNotFound
 	}
 
+	if err := s.attachOverrides(result); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
@@ -169,6 +189,10 @@ func (s *TestResultService) GetResultBySession(sessionID int) (*models.TestResul
 		return nil, auth.ErrUserNotFound
 	}
 
+	if err := s.attachOverrides(result); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
@@ -188,3 +212,85 @@ func (s *TestResultService) GetResultByUserAndTest(userID, testID int) (*models.
 
 	return result, nil
 }
+
+// attachOverrides loads the manual overrides that apply to a result for display
+func (s *TestResultService) attachOverrides(result *models.TestResult) error {
+	if !result.HasOverride {
+		return nil
+	}
+
+	overrides, err := s.overrideRepo.GetActiveForSession(result.TestID, result.SessionID)
+	if err != nil {
+		return err
+	}
+	result.Overrides = overrides
+	return nil
+}
+
+// answerAbility estimates a candidate's ability from the automatic scores of their answers
+// to the questions of the form they sat, or nil if any question is not calibrated
+func answerAbility(questions []*models.Question, answers []*models.UserAnswer) (*float64, *float64) {
+	correct := make(map[int]bool)
+	for _, answer := range answers {
+		correct[answer.QuestionID] = answer.IsCorrect != nil && *answer.IsCorrect
+	}
+	return estimateTheta(questions, func(questionID int) bool {
+		return correct[questionID]
+	})
+}
+
+// tallyResult fills in the score fields of a result from the session's answers and
+// the manual overrides that apply to it. questions is the form the candidate sat, so
+// voided questions that were never administered are not credited.
+func tallyResult(result *models.TestResult, test *models.Test, questions []*models.Question, answers []*models.UserAnswer, adjustments *scoreAdjustments) {
+	correctAnswers := 0
+	marksObtained := 0
+	hasOverride := false
+
+	for _, answer := range answers {
+		// Voided questions are credited below, answered or not
+		if _, voided := adjustments.voidedQuestions[answer.QuestionID]; voided {
+			continue
+		}
+
+		isCorrect := answer.IsCorrect != nil && *answer.IsCorrect
+		marks := answer.MarksAwarded
+		if overrideMarks, ok := adjustments.answerMarks[answer.ID]; ok {
+			isCorrect, marks = overrideMarks > 0, overrideMarks
+			hasOverride = true
+		}
+
+		if isCorrect {
+			correctAnswers++
+		}
+		marksObtained += marks
+	}
+
+	for _, question := range questions {
+		if voidMarks, voided := adjustments.voidedQuestions[question.ID]; voided {
+			correctAnswers++
+			marksObtained += voidMarks
+			hasOverride = true
+		}
+	}
+
+	if overrideMarks, ok := adjustments.resultMarks[result.ID]; ok && result.ID != 0 {
+		marksObtained = overrideMarks
+		hasOverride = true
+	}
+
+	// Calculate percentage
+	var percentage float64
+	if test.TotalMarks > 0 {
+		percentage = (float64(marksObtained) / float64(test.TotalMarks)) * 100
+	}
+
+	result.TotalQuestions = len(questions)
+	result.AnsweredQuestions = len(answers)
+	result.CorrectAnswers = correctAnswers
+	result.TotalMarks = test.TotalMarks
+	result.MarksObtained = marksObtained
+	result.Percentage = percentage
+	result.IsPassed = marksObtained >= test.PassingMarks
+	result.HasOverride = hasOverride
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"regexp"
+	"strings"
+)
+
+// roleNamePattern matches role names such as teaching_assistant
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// RoleService implements the models.RoleService interface
+type RoleService struct {
+	roleRepo models.RoleRepository
+	userRepo models.UserRepository
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(roleRepo models.RoleRepository, userRepo models.UserRepository) models.RoleService {
+	return &RoleService{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
+
+// CreateRole creates an institution role from a set of permissions
+func (s *RoleService) CreateRole(name, description string, permissions []models.Permission) (*models.Role, error) {
+	name = strings.TrimSpace(name)
+	if err := s.checkName(0, name); err != nil {
+		return nil, err
+	}
+
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Permissions: permissions,
+	}
+
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// GetRole retrieves a role by ID
+func (s *RoleService) GetRole(roleID int) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if role == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return role, nil
+}
+
+// ListRoles retrieves every role
+func (s *RoleService) ListRoles() ([]*models.Role, error) {
+	return s.roleRepo.List()
+}
+
+// UpdateRole renames an institution role and replaces its permissions. Built-in roles
+// cannot be changed, so an admin cannot lock everyone out of role management.
+func (s *RoleService) UpdateRole(roleID int, name, description string, permissions []models.Permission) (*models.Role, error) {
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, auth.ErrSystemRole
+	}
+
+	name = strings.TrimSpace(name)
+	if err := s.checkName(roleID, name); err != nil {
+		return nil, err
+	}
+
+	permissions, err = normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Name = name
+	role.Description = strings.TrimSpace(description)
+	role.Permissions = permissions
+
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// DeleteRole deletes an institution role; its users lose its permissions
+func (s *RoleService) DeleteRole(roleID int) error {
+	role, err := s.GetRole(roleID)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return auth.ErrSystemRole
+	}
+
+	return s.roleRepo.Delete(roleID)
+}
+
+// GetUserRoles retrieves the roles assigned to a user and their effective permissions
+func (s *RoleService) GetUserRoles(userID int) (*models.UserRoles, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.roleRepo.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserRoles{
+		UserID:      userID,
+		AccountRole: user.Role,
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+// SetUserRoles replaces the institution roles assigned to a user. Built-in roles follow
+// the user's account role and cannot be assigned.
+func (s *RoleService) SetUserRoles(userID int, roleIDs []int) (*models.UserRoles, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	seen := make(map[int]bool)
+	unique := make([]int, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		if seen[roleID] {
+			continue
+		}
+		role, err := s.roleRepo.GetByID(roleID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if role == nil || role.IsSystem {
+			return nil, auth.ErrInvalidRoleAssignment
+		}
+		seen[roleID] = true
+		unique = append(unique, roleID)
+	}
+
+	if err := s.roleRepo.ReplaceUserRoles(userID, unique); err != nil {
+		return nil, err
+	}
+
+	return s.GetUserRoles(userID)
+}
+
+// GetUserPermissions retrieves the permissions a user currently holds
+func (s *RoleService) GetUserPermissions(userID int) ([]models.Permission, error) {
+	return s.roleRepo.GetUserPermissions(userID)
+}
+
+// SetTwoFactorRequired makes two-factor authentication mandatory, or optional again, for
+// a role's users. Unlike other changes this is allowed for built-in roles, so that 2FA
+// can be required of every teacher or admin. Users who have not set it up yet are asked
+// to enrol at their next login.
+func (s *RoleService) SetTwoFactorRequired(roleID int, required bool) (*models.Role, error) {
+	if _, err := s.GetRole(roleID); err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.SetTwoFactorRequired(roleID, required); err != nil {
+		return nil, err
+	}
+
+	return s.GetRole(roleID)
+}
+
+// checkName checks that a role name is valid and not used by another role
+func (s *RoleService) checkName(roleID int, name string) error {
+	if !roleNamePattern.MatchString(name) {
+		return auth.ErrInvalidRoleName
+	}
+
+	existing, err := s.roleRepo.GetByName(name)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if existing != nil && existing.ID != roleID {
+		return auth.ErrRoleExists
+	}
+	return nil
+}
+
+// normalizePermissions checks that every permission is known and removes duplicates
+func normalizePermissions(permissions []models.Permission) ([]models.Permission, error) {
+	unique := make([]models.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			return nil, auth.ErrInvalidPermission
+		}
+		if !models.HasPermission(unique, permission) {
+			unique = append(unique, permission)
+		}
+	}
+	return unique, nil
+}
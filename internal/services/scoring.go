@@ -0,0 +1,140 @@
+package services
+
+import (
+	"gocbt/internal/models"
+	"strings"
+)
+
+// answerKey holds the options and accepted answers a question is scored against
+type answerKey struct {
+	question       *models.Question
+	options        []*models.QuestionOption
+	correctAnswers []*models.CorrectAnswer
+}
+
+// loadAnswerKey loads the current answer key of a question
+func loadAnswerKey(questionRepo models.QuestionRepository, question *models.Question) (*answerKey, error) {
+	key := &answerKey{question: question}
+
+	var err error
+	switch question.QuestionType {
+	case models.QuestionTypeMultipleChoice, models.QuestionTypeTrueFalse:
+		key.options, err = questionRepo.GetOptionsByQuestionID(question.ID)
+	case models.QuestionTypeShortAnswer:
+		key.correctAnswers, err = questionRepo.GetCorrectAnswersByQuestionID(question.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// score scores an answer based on the question type and correct answers
+func (k *answerKey) score(answerText *string, selectedOptionID *int) (bool, int) {
+	switch k.question.QuestionType {
+	case models.QuestionTypeMultipleChoice, models.QuestionTypeTrueFalse:
+		return k.scoreMultipleChoiceAnswer(selectedOptionID)
+	case models.QuestionTypeShortAnswer:
+		return k.scoreShortAnswer(answerText)
+	default:
+		return false, 0
+	}
+}
+
+// scoreMultipleChoiceAnswer scores a multiple choice answer
+func (k *answerKey) scoreMultipleChoiceAnswer(selectedOptionID *int) (bool, int) {
+	if selectedOptionID == nil {
+		return false, 0
+	}
+
+	for _, option := range k.options {
+		if option.ID == *selectedOptionID && option.IsCorrect {
+			return true, k.question.Marks
+		}
+	}
+
+	return false, 0
+}
+
+// scoreShortAnswer scores a short answer
+func (k *answerKey) scoreShortAnswer(answerText *string) (bool, int) {
+	if answerText == nil || strings.TrimSpace(*answerText) == "" {
+		return false, 0
+	}
+
+	userAnswer := strings.TrimSpace(*answerText)
+
+	for _, correctAnswer := range k.correctAnswers {
+		expectedAnswer := correctAnswer.AnswerText
+		if !correctAnswer.IsCaseSensitive {
+			userAnswer = strings.ToLower(userAnswer)
+			expectedAnswer = strings.ToLower(expectedAnswer)
+		}
+
+		if userAnswer == expectedAnswer {
+			return true, k.question.Marks
+		}
+	}
+
+	return false, 0
+}
+
+// scoreAdjustments holds the active manual overrides applied on top of automatic scoring
+type scoreAdjustments struct {
+	answerMarks     map[int]int // answer ID -> marks awarded
+	resultMarks     map[int]int // result ID -> marks obtained
+	voidedQuestions map[int]int // question ID -> marks credited to every candidate
+}
+
+// newScoreAdjustments indexes active overrides by target; overrides are expected oldest
+// first, so a later override of the same target replaces an earlier one
+func newScoreAdjustments(overrides []*models.ScoreOverride) *scoreAdjustments {
+	adjustments := &scoreAdjustments{
+		answerMarks:     make(map[int]int),
+		resultMarks:     make(map[int]int),
+		voidedQuestions: make(map[int]int),
+	}
+
+	for _, override := range overrides {
+		if !override.IsActive() {
+			continue
+		}
+		switch override.Scope {
+		case models.OverrideScopeAnswer:
+			if override.AnswerID != nil {
+				adjustments.answerMarks[*override.AnswerID] = override.NewValue
+			}
+		case models.OverrideScopeResult:
+			if override.ResultID != nil {
+				adjustments.resultMarks[*override.ResultID] = override.NewValue
+			}
+		case models.OverrideScopeQuestion:
+			if override.QuestionID != nil {
+				adjustments.voidedQuestions[*override.QuestionID] = override.NewValue
+			}
+		}
+	}
+
+	return adjustments
+}
+
+// loadScoreAdjustments loads the active overrides of a whole test
+func loadScoreAdjustments(overrideRepo models.ScoreOverrideRepository, testID int) (*scoreAdjustments, error) {
+	overrides, err := overrideRepo.GetByTest(testID, false)
+	if err != nil {
+		return nil, err
+	}
+	return newScoreAdjustments(overrides), nil
+}
+
+// adjustAnswer applies question voids and answer overrides to an automatically scored answer
+func (a *scoreAdjustments) adjustAnswer(answer *models.UserAnswer, isCorrect bool, marks int) (bool, int) {
+	if voidMarks, ok := a.voidedQuestions[answer.QuestionID]; ok {
+		return true, voidMarks
+	}
+	if overrideMarks, ok := a.answerMarks[answer.ID]; ok {
+		return overrideMarks > 0, overrideMarks
+	}
+	return isCorrect, marks
+}
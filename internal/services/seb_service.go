@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sebKeyPattern matches SEB Config Keys and Browser Exam Keys (SHA-256 hex)
+var sebKeyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// SEBService implements the models.SEBService interface
+type SEBService struct {
+	sebRepo     models.SEBRepository
+	testRepo    models.TestRepository
+	frontendURL string
+}
+
+// NewSEBService creates a new Safe Exam Browser service
+func NewSEBService(sebRepo models.SEBRepository, testRepo models.TestRepository, frontendURL string) models.SEBService {
+	return &SEBService{
+		sebRepo:     sebRepo,
+		testRepo:    testRepo,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// GetSettings retrieves the Safe Exam Browser settings for a test
+func (s *SEBService) GetSettings(testID int) (*models.TestSEBSettings, error) {
+	settings, err := s.sebRepo.GetSettings(testID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, auth.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if settings == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings creates or replaces the Safe Exam Browser settings for a test.
+// A nil quit password keeps the existing one, an empty one removes it.
+func (s *SEBService) UpdateSettings(testID int, enabled bool, configKeys, browserExamKeys []string, quitPassword *string) (*models.TestSEBSettings, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	normalizedConfigKeys, err := normalizeSEBKeys(configKeys)
+	if err != nil {
+		return nil, err
+	}
+	normalizedExamKeys, err := normalizeSEBKeys(browserExamKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// An enabled requirement needs at least one key to check requests against
+	if enabled && len(normalizedConfigKeys) == 0 && len(normalizedExamKeys) == 0 {
+		return nil, auth.ErrInvalidSEBKeyValue
+	}
+
+	settings := &models.TestSEBSettings{
+		TestID:          testID,
+		Enabled:         enabled,
+		ConfigKeys:      normalizedConfigKeys,
+		BrowserExamKeys: normalizedExamKeys,
+	}
+
+	existing, err := s.sebRepo.GetSettings(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if existing != nil {
+		settings.QuitPasswordHash = existing.QuitPasswordHash
+		settings.CreatedAt = existing.CreatedAt
+	}
+
+	if quitPassword != nil {
+		if *quitPassword == "" {
+			settings.QuitPasswordHash = nil
+		} else {
+			sum := sha256.Sum256([]byte(*quitPassword))
+			hash := hex.EncodeToString(sum[:])
+			settings.QuitPasswordHash = &hash
+		}
+	}
+	settings.HasQuitPassword = settings.QuitPasswordHash != nil
+
+	if err := s.sebRepo.UpsertSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// DeleteSettings removes the Safe Exam Browser requirement from a test
+func (s *SEBService) DeleteSettings(testID int) error {
+	return s.sebRepo.DeleteSettings(testID)
+}
+
+// ValidateRequest checks the SEB request headers of a request for a test
+func (s *SEBService) ValidateRequest(testID int, requestURL, configKeyHash, requestHash string) error {
+	settings, err := s.sebRepo.GetSettings(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// Tests without an enabled SEB requirement accept any browser
+	if settings == nil || !settings.Enabled {
+		return nil
+	}
+
+	if configKeyHash == "" && requestHash == "" {
+		return auth.ErrSEBRequired
+	}
+
+	if !settings.ValidConfigKeyHash(requestURL, configKeyHash) || !settings.ValidRequestHash(requestURL, requestHash) {
+		return auth.ErrInvalidSEBKey
+	}
+
+	return nil
+}
+
+// GenerateConfig builds an unencrypted .seb configuration (XML property list) for a test
+func (s *SEBService) GenerateConfig(testID int) ([]byte, error) {
+	test, err := s.testRepo.GetByID(testID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	settings, err := s.sebRepo.GetSettings(testID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	values := map[string]interface{}{
+		"originatorVersion":            "GoCBT",
+		"startURL":                     fmt.Sprintf("%s/tests/%d", s.frontendURL, test.ID),
+		"quitURL":                      fmt.Sprintf("%s/results", s.frontendURL),
+		"quitURLConfirm":               true,
+		"sendBrowserExamKey":           true,
+		"allowQuit":                    true,
+		"examSessionClearCookiesOnEnd": true,
+		"allowSpellCheck":              false,
+		"allowDictionaryLookup":        false,
+		"enableRightMouse":             false,
+		"allowPreferencesWindow":       false,
+		"URLFilterEnable":              false,
+	}
+	if settings != nil && settings.QuitPasswordHash != nil {
+		values["hashedQuitPassword"] = *settings.QuitPasswordHash
+	}
+
+	return encodePlist(values)
+}
+
+// normalizeSEBKeys validates and lowercases SEB keys, dropping blanks and duplicates
+func normalizeSEBKeys(keys []string) ([]string, error) {
+	normalized := make([]string, 0, len(keys))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" || seen[key] {
+			continue
+		}
+		if !sebKeyPattern.MatchString(key) {
+			return nil, auth.ErrInvalidSEBKeyValue
+		}
+		seen[key] = true
+		normalized = append(normalized, key)
+	}
+	return normalized, nil
+}
+
+// encodePlist encodes a flat dictionary of strings and booleans as an XML property list
+func encodePlist(values map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	buf.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+
+	for _, key := range keys {
+		buf.WriteString("\t<key>")
+		if err := xml.EscapeText(&buf, []byte(key)); err != nil {
+			return nil, err
+		}
+		buf.WriteString("</key>\n")
+
+		switch value := values[key].(type) {
+		case bool:
+			if value {
+				buf.WriteString("\t<true/>\n")
+			} else {
+				buf.WriteString("\t<false/>\n")
+			}
+		case string:
+			buf.WriteString("\t<string>")
+			if err := xml.EscapeText(&buf, []byte(value)); err != nil {
+				return nil, err
+			}
+			buf.WriteString("</string>\n")
+		default:
+			return nil, fmt.Errorf("unsupported plist value for key %s", key)
+		}
+	}
+
+	buf.WriteString("</dict>\n</plist>\n")
+	return buf.Bytes(), nil
+}
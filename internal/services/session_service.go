@@ -7,32 +7,39 @@ import (
 	"fmt"
 	"gocbt/internal/auth"
 	"gocbt/internal/models"
-	"strings"
 	"time"
 )
 
 // TestSessionService implements the models.TestSessionService interface
 type TestSessionService struct {
-	sessionRepo   models.TestSessionRepository
-	answerRepo    models.UserAnswerRepository
-	testRepo      models.TestRepository
-	questionRepo  models.QuestionRepository
-	resultService models.TestResultService
+	sessionRepo     models.TestSessionRepository
+	answerRepo      models.UserAnswerRepository
+	testRepo        models.TestRepository
+	questionRepo    models.QuestionRepository
+	resultService   models.TestResultService
+	accessService   models.TestAccessService
+	practiceService models.PracticeService
+	adaptiveService models.AdaptiveService
+	groupService    models.GroupService
 }
 
 // NewTestSessionService creates a new test session service
-func NewTestSessionService(sessionRepo models.TestSessionRepository, answerRepo models.UserAnswerRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository, resultService models.TestResultService) models.TestSessionService {
+func NewTestSessionService(sessionRepo models.TestSessionRepository, answerRepo models.UserAnswerRepository, testRepo models.TestRepository, questionRepo models.QuestionRepository, resultService models.TestResultService, accessService models.TestAccessService, practiceService models.PracticeService, adaptiveService models.AdaptiveService, groupService models.GroupService) models.TestSessionService {
 	return &TestSessionService{
-		sessionRepo:   sessionRepo,
-		answerRepo:    answerRepo,
-		testRepo:      testRepo,
-		questionRepo:  questionRepo,
-		resultService: resultService,
+		sessionRepo:     sessionRepo,
+		answerRepo:      answerRepo,
+		testRepo:        testRepo,
+		questionRepo:    questionRepo,
+		resultService:   resultService,
+		accessService:   accessService,
+		practiceService: practiceService,
+		adaptiveService: adaptiveService,
+		groupService:    groupService,
 	}
 }
 
 // StartSession starts a new test session for a user
-func (s *TestSessionService) StartSession(userID, testID int) (*models.TestSession, error) {
+func (s *TestSessionService) StartSession(userID, testID int, accessCode, clientIP string) (*models.TestSession, error) {
 	// Check if test exists and is available
 	test, err := s.testRepo.GetByID(testID)
 	if err != nil {
@@ -46,6 +53,20 @@ func (s *TestSessionService) StartSession(userID, testID int) (*models.TestSessi
 		return nil, fmt.Errorf("test is not available")
 	}
 
+	// Tests are only offered to the groups they are assigned to
+	if s.groupService != nil {
+		if err := s.groupService.CheckAssignment(testID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check access code and network gates
+	if s.accessService != nil {
+		if err := s.accessService.CheckAccess(testID, userID, accessCode, clientIP); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check if user already has a session for this test
 	existingSession, err := s.sessionRepo.GetByUserAndTest(userID, testID)
 	if err != nil && err != sql.ErrNoRows {
@@ -55,8 +76,26 @@ func (s *TestSessionService) StartSession(userID, testID int) (*models.TestSessi
 	if existingSession != nil {
 		// If session exists and is not expired, return it
 		if !existingSession.IsExpired() && existingSession.Status != models.SessionStatusSubmitted {
+			if test.IsAdaptive() && s.adaptiveService != nil {
+				state, err := s.adaptiveService.GetState(existingSession)
+				if err != nil {
+					return nil, err
+				}
+				existingSession.Adaptive = state
+			}
 			return existingSession, nil
 		}
+
+		// Practice tests allow unlimited attempts. Finished attempts are already
+		// recorded as practice results, so the old session makes way for a new one.
+		if test.IsPractice() {
+			if err := s.answerRepo.DeleteBySession(existingSession.ID); err != nil {
+				return nil, err
+			}
+			if err := s.sessionRepo.Delete(existingSession.ID); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Generate session token
@@ -83,6 +122,18 @@ func (s *TestSessionService) StartSession(userID, testID int) (*models.TestSessi
 		return nil, err
 	}
 
+	// Adaptive tests choose the first question as soon as the session exists
+	if test.IsAdaptive() && s.adaptiveService != nil {
+		state, err := s.adaptiveService.Begin(session)
+		if err != nil {
+			if deleteErr := s.sessionRepo.Delete(session.ID); deleteErr != nil {
+				fmt.Printf("Warning: Failed to delete adaptive session %d: %v\n", session.ID, deleteErr)
+			}
+			return nil, err
+		}
+		session.Adaptive = state
+	}
+
 	return session, nil
 }
 
@@ -109,6 +160,18 @@ func (s *TestSessionService) GetSession(sessionToken string) (*models.TestSessio
 	return session, nil
 }
 
+// GetSessionByID retrieves a session by ID
+func (s *TestSessionService) GetSessionByID(sessionID int) (*models.TestSession, error) {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if session == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return session, nil
+}
+
 // SubmitAnswer submits an answer for a question in a session
 func (s *TestSessionService) SubmitAnswer(sessionToken string, questionID int, answerText *string, selectedOptionID *int) (*models.UserAnswer, error) {
 	// Get session
@@ -142,6 +205,23 @@ func (s *TestSessionService) SubmitAnswer(sessionToken string, questionID int, a
 		return nil, fmt.Errorf("invalid question for this test")
 	}
 
+	test, err := s.testRepo.GetByID(session.TestID)
+	if err != nil {
+		return nil, err
+	}
+	adaptive := test != nil && test.IsAdaptive() && s.adaptiveService != nil
+
+	// Adaptive tests only accept an answer to the question they are currently asking
+	if adaptive {
+		state, err := s.adaptiveService.GetState(session)
+		if err != nil {
+			return nil, err
+		}
+		if pending := state.PendingStep(); pending == nil || pending.QuestionID != questionID {
+			return nil, auth.ErrNotCurrentQuestion
+		}
+	}
+
 	// Check if answer already exists
 	existingAnswer, err := s.answerRepo.GetBySessionAndQuestion(session.ID, questionID)
 	if err != nil && err != sql.ErrNoRows {
@@ -151,30 +231,52 @@ func (s *TestSessionService) SubmitAnswer(sessionToken string, questionID int, a
 	// Validate and score the answer
 	isCorrect, marksAwarded := s.scoreAnswer(question, answerText, selectedOptionID)
 
-	if existingAnswer != nil {
+	answer := existingAnswer
+	if answer != nil {
 		// Update existing answer
-		existingAnswer.AnswerText = answerText
-		existingAnswer.SelectedOptionID = selectedOptionID
-		existingAnswer.IsCorrect = &isCorrect
-		existingAnswer.MarksAwarded = marksAwarded
-		if err := s.answerRepo.Update(existingAnswer); err != nil {
+		answer.AnswerText = answerText
+		answer.SelectedOptionID = selectedOptionID
+		answer.IsCorrect = &isCorrect
+		answer.MarksAwarded = marksAwarded
+		if err := s.answerRepo.Update(answer); err != nil {
+			return nil, err
+		}
+	} else {
+		// Create new answer
+		answer = &models.UserAnswer{
+			SessionID:        session.ID,
+			QuestionID:       questionID,
+			AnswerText:       answerText,
+			SelectedOptionID: selectedOptionID,
+			IsCorrect:        &isCorrect,
+			MarksAwarded:     marksAwarded,
+		}
+
+		if err := s.answerRepo.Create(answer); err != nil {
 			return nil, err
 		}
-		return existingAnswer, nil
 	}
 
-	// Create new answer
-	answer := &models.UserAnswer{
-		SessionID:        session.ID,
-		QuestionID:       questionID,
-		AnswerText:       answerText,
-		SelectedOptionID: selectedOptionID,
-		IsCorrect:        &isCorrect,
-		MarksAwarded:     marksAwarded,
+	// Practice tests give instant feedback on every answer
+	if test != nil && test.IsPractice() {
+		if err := s.attachFeedback(question, answer); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.answerRepo.Create(answer); err != nil {
-		return nil, err
+	// Adaptive tests update the ability estimate and pick the next question, or stop
+	if adaptive {
+		state, err := s.adaptiveService.RecordAnswer(session, questionID, isCorrect)
+		if err != nil {
+			return nil, err
+		}
+		answer.Adaptive = state
+
+		if state.Finished {
+			if _, err := s.SubmitSession(sessionToken); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return answer, nil
@@ -210,6 +312,21 @@ func (s *TestSessionService) SubmitSession(sessionToken string) (*models.TestSes
 		return nil, err
 	}
 
+	test, err := s.testRepo.GetByID(session.TestID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Practice attempts are stored apart from graded results
+	if test != nil && test.IsPractice() {
+		if s.practiceService != nil {
+			if _, err := s.practiceService.RecordAttempt(session.ID); err != nil {
+				fmt.Printf("Warning: Failed to record practice attempt for session %d: %v\n", session.ID, err)
+			}
+		}
+		return session, nil
+	}
+
 	// Automatically calculate results if result service is available
 	if s.resultService != nil {
 		_, err := s.resultService.CalculateResult(session.ID)
@@ -223,6 +340,20 @@ func (s *TestSessionService) SubmitSession(sessionToken string) (*models.TestSes
 	return session, nil
 }
 
+// GetAdaptiveState retrieves the question sequence, ability trajectory and current
+// question of an adaptive session
+func (s *TestSessionService) GetAdaptiveState(sessionToken string) (*models.AdaptiveState, error) {
+	session, err := s.GetSession(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.adaptiveService == nil {
+		return nil, auth.ErrNotAdaptiveTest
+	}
+	return s.adaptiveService.GetState(session)
+}
+
 // GetUserSessions retrieves sessions for a user
 func (s *TestSessionService) GetUserSessions(userID int, limit, offset int) ([]*models.TestSession, error) {
 	return s.sessionRepo.GetUserSessions(userID, limit, offset)
@@ -252,64 +383,31 @@ func (s *TestSessionService) generateSessionToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// scoreAnswer scores an answer based on the question type and correct answers
-func (s *TestSessionService) scoreAnswer(question *models.Question, answerText *string, selectedOptionID *int) (bool, int) {
-	switch question.QuestionType {
-	case models.QuestionTypeMultipleChoice, models.QuestionTypeTrueFalse:
-		return s.scoreMultipleChoiceAnswer(question, selectedOptionID)
-	case models.QuestionTypeShortAnswer:
-		return s.scoreShortAnswer(question, answerText)
-	default:
-		return false, 0
-	}
-}
-
-// scoreMultipleChoiceAnswer scores a multiple choice answer
-func (s *TestSessionService) scoreMultipleChoiceAnswer(question *models.Question, selectedOptionID *int) (bool, int) {
-	if selectedOptionID == nil {
-		return false, 0
-	}
-
-	// Get options for the question
-	options, err := s.questionRepo.GetOptionsByQuestionID(question.ID)
-	if err != nil {
-		return false, 0
-	}
-
-	for _, option := range options {
-		if option.ID == *selectedOptionID && option.IsCorrect {
-			return true, question.Marks
+// attachFeedback adds the question's feedback for an answer, including that of the selected option
+func (s *TestSessionService) attachFeedback(question *models.Question, answer *models.UserAnswer) error {
+	var selectedOption *models.QuestionOption
+	if answer.SelectedOptionID != nil {
+		options, err := s.questionRepo.GetOptionsByQuestionID(question.ID)
+		if err != nil {
+			return err
+		}
+		for _, option := range options {
+			if option.ID == *answer.SelectedOptionID {
+				selectedOption = option
+				break
+			}
 		}
 	}
 
-	return false, 0
+	answer.Feedback = question.FeedbackFor(answer.IsCorrect != nil && *answer.IsCorrect, selectedOption)
+	return nil
 }
 
-// scoreShortAnswer scores a short answer
-func (s *TestSessionService) scoreShortAnswer(question *models.Question, answerText *string) (bool, int) {
-	if answerText == nil || strings.TrimSpace(*answerText) == "" {
-		return false, 0
-	}
-
-	// Get correct answers for the question
-	correctAnswers, err := s.questionRepo.GetCorrectAnswersByQuestionID(question.ID)
+// scoreAnswer scores an answer against the question's current answer key
+func (s *TestSessionService) scoreAnswer(question *models.Question, answerText *string, selectedOptionID *int) (bool, int) {
+	key, err := loadAnswerKey(s.questionRepo, question)
 	if err != nil {
 		return false, 0
 	}
-
-	userAnswer := strings.TrimSpace(*answerText)
-
-	for _, correctAnswer := range correctAnswers {
-		expectedAnswer := correctAnswer.AnswerText
-		if !correctAnswer.IsCaseSensitive {
-			userAnswer = strings.ToLower(userAnswer)
-			expectedAnswer = strings.ToLower(expectedAnswer)
-		}
-
-		if userAnswer == expectedAnswer {
-			return true, question.Marks
-		}
-	}
-
-	return false, 0
+	return key.score(answerText, selectedOptionID)
 }
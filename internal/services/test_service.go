@@ -2,6 +2,7 @@ package services
 
 import (
 	"database/sql"
+	"fmt"
 	"gocbt/internal/auth"
 	"gocbt/internal/models"
 	"strings"
@@ -10,18 +11,24 @@ import (
 
 // TestService implements the models.TestService interface
 type TestService struct {
-	testRepo models.TestRepository
+	testRepo         models.TestRepository
+	userRepo         models.UserRepository
+	roleRepo         models.RoleRepository
+	integrityService models.IntegrityService
 }
 
 // NewTestService creates a new test service
-func NewTestService(testRepo models.TestRepository) models.TestService {
+func NewTestService(testRepo models.TestRepository, userRepo models.UserRepository, roleRepo models.RoleRepository, integrityService models.IntegrityService) models.TestService {
 	return &TestService{
-		testRepo: testRepo,
+		testRepo:         testRepo,
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		integrityService: integrityService,
 	}
 }
 
 // CreateTest creates a new test
-func (s *TestService) CreateTest(creatorID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time) (*models.Test, error) {
+func (s *TestService) CreateTest(creatorID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time, mode models.TestMode) (*models.Test, error) {
 	// Validate input
 	if strings.TrimSpace(title) == "" {
 		return nil, auth.ErrInvalidCredentials
@@ -41,6 +48,14 @@ func (s *TestService) CreateTest(creatorID int, title, description, instructions
 		return nil, auth.ErrInvalidCredentials
 	}
 
+	// Tests are graded unless created as practice quizzes
+	if mode == "" {
+		mode = models.TestModeGraded
+	}
+	if !mode.IsValid() {
+		return nil, auth.ErrInvalidCredentials
+	}
+
 	test := &models.Test{
 		Title:           strings.TrimSpace(title),
 		Description:     strings.TrimSpace(description),
@@ -52,6 +67,7 @@ func (s *TestService) CreateTest(creatorID int, title, description, instructions
 		IsActive:        true,
 		StartTime:       startTime,
 		EndTime:         endTime,
+		Mode:            mode,
 	}
 
 	if err := s.testRepo.Create(test); err != nil {
@@ -79,7 +95,7 @@ func (s *TestService) GetTest(testID int) (*models.Test, error) {
 }
 
 // UpdateTest updates a test
-func (s *TestService) UpdateTest(testID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time) (*models.Test, error) {
+func (s *TestService) UpdateTest(testID int, title, description, instructions string, durationMinutes, totalMarks, passingMarks int, startTime, endTime *time.Time, mode models.TestMode) (*models.Test, error) {
 	// Get existing test
 	test, err := s.GetTest(testID)
 	if err != nil {
@@ -105,6 +121,14 @@ func (s *TestService) UpdateTest(testID int, title, description, instructions st
 		return nil, auth.ErrInvalidCredentials
 	}
 
+	// Keep the current mode unless a new one is given
+	if mode == "" {
+		mode = test.Mode
+	}
+	if !mode.IsValid() {
+		return nil, auth.ErrInvalidCredentials
+	}
+
 	// Update test fields
 	test.Title = strings.TrimSpace(title)
 	test.Description = strings.TrimSpace(description)
@@ -114,6 +138,7 @@ func (s *TestService) UpdateTest(testID int, title, description, instructions st
 	test.PassingMarks = passingMarks
 	test.StartTime = startTime
 	test.EndTime = endTime
+	test.Mode = mode
 
 	if err := s.testRepo.Update(test); err != nil {
 		return nil, err
@@ -130,7 +155,16 @@ func (s *TestService) DeleteTest(testID int) error {
 		return err
 	}
 
-	return s.testRepo.Delete(testID)
+	if err := s.testRepo.Delete(testID); err != nil {
+		return err
+	}
+
+	// Verification reports the test's results as deleted outside the application if this fails
+	if err := s.integrityService.RecordTestDeletion(testID); err != nil {
+		fmt.Printf("Warning: Failed to record deleted results of test %d in the ledger: %v\n", testID, err)
+	}
+
+	return nil
 }
 
 // ListTests retrieves tests by creator with pagination
@@ -141,7 +175,7 @@ func (s *TestService) ListTests(creatorID int, limit, offset int) ([]*models.Tes
 	return s.testRepo.GetByCreator(creatorID, limit, offset)
 }
 
-// GetAvailableTests retrieves tests available for a user
+// GetAvailableTests retrieves tests available for a user through the groups they are enrolled in
 func (s *TestService) GetAvailableTests(userID int, limit, offset int) ([]*models.Test, error) {
 	return s.testRepo.GetAvailableTests(userID, limit, offset)
 }
@@ -167,3 +201,70 @@ func (s *TestService) DeactivateTest(testID int) error {
 	test.IsActive = false
 	return s.testRepo.Update(test)
 }
+
+// GetOwnership retrieves the creator of a test and the teachers it is shared with
+func (s *TestService) GetOwnership(testID int) (*models.TestOwnership, error) {
+	test, err := s.GetTest(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	coOwnerIDs, err := s.testRepo.GetCoOwners(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TestOwnership{
+		TestID:     test.ID,
+		OwnerID:    test.CreatedBy,
+		CoOwnerIDs: coOwnerIDs,
+	}, nil
+}
+
+// SetCoOwners replaces the teachers a test is shared with. Co-owners can edit the test
+// and its questions, and view and grade its results.
+func (s *TestService) SetCoOwners(testID int, userIDs []int) (*models.TestOwnership, error) {
+	test, err := s.GetTest(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	coOwnerIDs := make([]int, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		if userID == test.CreatedBy {
+			return nil, auth.ErrInvalidCoOwner
+		}
+
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if user == nil {
+			return nil, auth.ErrInvalidCoOwner
+		}
+
+		// Co-owners need a role that lets them edit tests or work with results
+		permissions, err := s.roleRepo.GetUserPermissions(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !models.HasPermission(permissions, models.PermTestsCreate) &&
+			!models.HasPermission(permissions, models.PermResultsView) &&
+			!models.HasPermission(permissions, models.PermResultsGrade) {
+			return nil, auth.ErrInvalidCoOwner
+		}
+
+		seen[userID] = true
+		coOwnerIDs = append(coOwnerIDs, userID)
+	}
+
+	if err := s.testRepo.ReplaceCoOwners(testID, coOwnerIDs); err != nil {
+		return nil, err
+	}
+
+	return s.GetOwnership(testID)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"database/sql"
+	"gocbt/internal/auth"
+	"gocbt/internal/config"
+	"gocbt/internal/database"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDB opens a fresh, migrated SQLite database that is closed when the test ends
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db := &database.DB{DB: sqlDB, Driver: "sqlite"}
+	if err := db.RunMigrations(filepath.Join("..", "..", "migrations")); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db
+}
+
+// newTestJWT returns a JWT manager signing access tokens with a fixed HS256 secret
+func newTestJWT(t *testing.T) *auth.JWTManager {
+	t.Helper()
+
+	jwtConfig := &config.JWTConfig{
+		Algorithm:  auth.AlgorithmHS256,
+		Secret:     "services-test-secret-of-32-characters",
+		Expiration: time.Hour,
+	}
+	keys, err := auth.LoadKeySet(jwtConfig)
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+	return auth.NewJWTManager(jwtConfig, keys)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"time"
+)
+
+// TokenService implements the models.TokenService interface. Each login starts a family
+// of refresh tokens; refreshing rotates the token, and presenting a token that was already
+// rotated revokes the whole family, since either the client or an attacker holds a copy.
+type TokenService struct {
+	tokenRepo         models.TokenRepository
+	userRepo          models.UserRepository
+	roleRepo          models.RoleRepository
+	jwtManager        *auth.JWTManager
+	refreshExpiration time.Duration
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(tokenRepo models.TokenRepository, userRepo models.UserRepository, roleRepo models.RoleRepository, jwtManager *auth.JWTManager, refreshExpiration time.Duration) models.TokenService {
+	return &TokenService{
+		tokenRepo:         tokenRepo,
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		jwtManager:        jwtManager,
+		refreshExpiration: refreshExpiration,
+	}
+}
+
+// IssueTokens issues an access token and a new family of refresh tokens for a user who
+// just signed in with the given authentication methods
+func (s *TokenService) IssueTokens(user *models.User, amr []string) (*models.TokenPair, error) {
+	// Expired tokens are rejected anyway; clear them out as new ones are issued
+	if err := s.tokenRepo.DeleteExpired(time.Now()); err != nil {
+		return nil, err
+	}
+
+	familyID, err := generateRandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, record, err := s.newTokens(user, familyID, amr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenRepo.CreateRefreshToken(record); err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh token. The new
+// tokens keep the authentication methods of the login; a login without a second factor
+// ends once one of the user's roles requires two-factor authentication.
+func (s *TokenService) Refresh(refreshToken string) (*models.TokenPair, error) {
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(hashHex(refreshToken))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, auth.ErrInvalidRefreshToken
+	}
+
+	if stored.IsRevoked() {
+		if err := s.tokenRepo.RevokeFamily(stored.FamilyID, time.Now()); err != nil {
+			return nil, err
+		}
+		return nil, auth.ErrRefreshTokenReused
+	}
+	if stored.IsExpired() {
+		return nil, auth.ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		if err := s.tokenRepo.RevokeFamily(stored.FamilyID, time.Now()); err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, auth.ErrInvalidRefreshToken
+		}
+		return nil, auth.ErrUserNotActive
+	}
+
+	if !models.HasAuthMethod(stored.AMR, models.AuthMethodMFA) {
+		required, err := s.roleRepo.IsTwoFactorRequired(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			if err := s.tokenRepo.RevokeFamily(stored.FamilyID, time.Now()); err != nil {
+				return nil, err
+			}
+			return nil, auth.ErrTwoFactorRequired
+		}
+	}
+
+	pair, record, err := s.newTokens(user, stored.FamilyID, stored.AMR)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.tokenRepo.RotateRefreshToken(stored.ID, record)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request rotated the same token first
+		if err := s.tokenRepo.RevokeFamily(stored.FamilyID, time.Now()); err != nil {
+			return nil, err
+		}
+		return nil, auth.ErrRefreshTokenReused
+	}
+
+	return pair, nil
+}
+
+// Logout revokes the access token of the request and, if given, the refresh token family
+// of the same login
+func (s *TokenService) Logout(userID int, accessTokenID string, accessExpiresAt time.Time, refreshToken string) error {
+	if err := s.tokenRepo.RevokeAccessToken(accessTokenID, userID, accessExpiresAt); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(hashHex(refreshToken))
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	// Ignore refresh tokens that are unknown or belong to someone else
+	if stored == nil || stored.UserID != userID {
+		return nil
+	}
+
+	return s.tokenRepo.RevokeFamily(stored.FamilyID, time.Now())
+}
+
+// LogoutAll revokes every refresh token of a user and the access tokens issued with them,
+// signing the user out of all devices
+func (s *TokenService) LogoutAll(userID int, accessTokenID string, accessExpiresAt time.Time) error {
+	if err := s.tokenRepo.RevokeUserTokens(userID, time.Now()); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeAccessToken(accessTokenID, userID, accessExpiresAt)
+}
+
+// IsTokenRevoked checks if an access token was revoked before it expired
+func (s *TokenService) IsTokenRevoked(tokenID string) (bool, error) {
+	return s.tokenRepo.IsAccessTokenRevoked(tokenID)
+}
+
+// newTokens generates an access token and a refresh token in a family, returning the
+// refresh token record to store
+func (s *TokenService) newTokens(user *models.User, familyID string, amr []string) (*models.TokenPair, *models.RefreshToken, error) {
+	accessToken, claims, err := s.jwtManager.GenerateToken(user, amr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := generateRandomHex(32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := &models.RefreshToken{
+		UserID:          user.ID,
+		TokenHash:       hashHex(refreshToken),
+		FamilyID:        familyID,
+		AccessTokenID:   claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		AMR:             amr,
+		ExpiresAt:       time.Now().Add(s.refreshExpiration),
+	}
+
+	pair := &models.TokenPair{
+		AccessToken:      accessToken,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+	}
+
+	return pair, record, nil
+}
+
+// generateRandomHex generates a random value of the given number of bytes, hex encoded
+func generateRandomHex(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"testing"
+	"time"
+)
+
+// tokenTestEnv is a token service with a student account, backed by a fresh SQLite database
+type tokenTestEnv struct {
+	tokenRepo  models.TokenRepository
+	userRepo   models.UserRepository
+	roleRepo   models.RoleRepository
+	jwtManager *auth.JWTManager
+	tokens     models.TokenService
+	student    *models.User
+}
+
+func newTokenTestEnv(t *testing.T) *tokenTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	env := &tokenTestEnv{
+		tokenRepo:  database.NewTokenRepository(db),
+		userRepo:   database.NewUserRepository(db),
+		roleRepo:   database.NewRoleRepository(db),
+		jwtManager: newTestJWT(t),
+	}
+	env.tokens = NewTokenService(env.tokenRepo, env.userRepo, env.roleRepo, env.jwtManager, time.Hour)
+
+	env.student = &models.User{
+		Username:  "student",
+		Email:     "student@school.example",
+		FirstName: "Alan",
+		LastName:  "Turing",
+		Role:      models.RoleStudent,
+		IsActive:  true,
+	}
+	if err := env.userRepo.Create(env.student); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	return env
+}
+
+// login issues the student a fresh family of tokens
+func (e *tokenTestEnv) login(t *testing.T) *models.TokenPair {
+	t.Helper()
+
+	pair, err := e.tokens.IssueTokens(e.student, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("IssueTokens() error = %v", err)
+	}
+	return pair
+}
+
+// accessTokenRevoked reports whether the jti of an access token is on the denylist
+func (e *tokenTestEnv) accessTokenRevoked(t *testing.T, accessToken string) bool {
+	t.Helper()
+
+	claims, err := e.jwtManager.ValidateToken(accessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	revoked, err := e.tokens.IsTokenRevoked(claims.ID)
+	if err != nil {
+		t.Fatalf("IsTokenRevoked() error = %v", err)
+	}
+	return revoked
+}
+
+func TestTokenRefreshRotatesTokens(t *testing.T) {
+	env := newTokenTestEnv(t)
+	first := env.login(t)
+
+	second, err := env.tokens.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Errorf("Refresh() returned the tokens it was given, expected a new pair")
+	}
+
+	stored, err := env.tokenRepo.GetRefreshTokenByHash(hashHex(first.RefreshToken))
+	if err != nil || stored == nil {
+		t.Fatalf("GetRefreshTokenByHash() = %v, %v, expected the rotated token", stored, err)
+	}
+	if !stored.IsRevoked() {
+		t.Errorf("Refresh() left the old refresh token valid, expected it revoked")
+	}
+
+	rotated, err := env.tokenRepo.GetRefreshTokenByHash(hashHex(second.RefreshToken))
+	if err != nil || rotated == nil {
+		t.Fatalf("GetRefreshTokenByHash() = %v, %v, expected the new token", rotated, err)
+	}
+	if rotated.FamilyID != stored.FamilyID || rotated.IsRevoked() {
+		t.Errorf("Refresh() new token family %q, revoked %v, expected family %q, not revoked", rotated.FamilyID, rotated.IsRevoked(), stored.FamilyID)
+	}
+
+	if _, err := env.tokens.Refresh(second.RefreshToken); err != nil {
+		t.Errorf("Refresh(new token) error = %v, expected nil", err)
+	}
+}
+
+func TestTokenRefreshReplayRevokesFamily(t *testing.T) {
+	env := newTokenTestEnv(t)
+	first := env.login(t)
+	other := env.login(t)
+
+	second, err := env.tokens.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if _, err := env.tokens.Refresh(first.RefreshToken); err != auth.ErrRefreshTokenReused {
+		t.Fatalf("Refresh(replayed token) error = %v, expected %v", err, auth.ErrRefreshTokenReused)
+	}
+
+	// The token the replay raced with is revoked along with its access token
+	if _, err := env.tokens.Refresh(second.RefreshToken); err != auth.ErrRefreshTokenReused {
+		t.Errorf("Refresh(rotated token) error = %v, expected %v", err, auth.ErrRefreshTokenReused)
+	}
+	if !env.accessTokenRevoked(t, second.AccessToken) {
+		t.Errorf("Refresh(replayed token) left the family's access token valid, expected it revoked")
+	}
+
+	// Other logins of the same user are a different family
+	if env.accessTokenRevoked(t, other.AccessToken) {
+		t.Errorf("Refresh(replayed token) revoked another login's access token")
+	}
+	if _, err := env.tokens.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("Refresh(other login) error = %v, expected nil", err)
+	}
+}
+
+func TestTokenLogoutRevokesAccessToken(t *testing.T) {
+	env := newTokenTestEnv(t)
+	pair := env.login(t)
+
+	claims, err := env.jwtManager.ValidateToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if env.accessTokenRevoked(t, pair.AccessToken) {
+		t.Fatalf("IsTokenRevoked() = true before logout, expected false")
+	}
+
+	if err := env.tokens.Logout(env.student.ID, claims.ID, claims.ExpiresAt.Time, pair.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	if !env.accessTokenRevoked(t, pair.AccessToken) {
+		t.Errorf("Logout() left jti %q off the denylist", claims.ID)
+	}
+	if _, err := env.tokens.Refresh(pair.RefreshToken); err != auth.ErrRefreshTokenReused {
+		t.Errorf("Refresh() after logout error = %v, expected %v", err, auth.ErrRefreshTokenReused)
+	}
+}
+
+func TestTokenRefreshRejectsExpiredToken(t *testing.T) {
+	env := newTokenTestEnv(t)
+
+	// Refresh tokens of this service expire as soon as they are issued
+	expiring := NewTokenService(env.tokenRepo, env.userRepo, env.roleRepo, env.jwtManager, -time.Minute)
+	pair, err := expiring.IssueTokens(env.student, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("IssueTokens() error = %v", err)
+	}
+
+	if _, err := env.tokens.Refresh(pair.RefreshToken); err != auth.ErrInvalidRefreshToken {
+		t.Errorf("Refresh(expired token) error = %v, expected %v", err, auth.ErrInvalidRefreshToken)
+	}
+	if _, err := env.tokens.Refresh("unknown-refresh-token"); err != auth.ErrInvalidRefreshToken {
+		t.Errorf("Refresh(unknown token) error = %v, expected %v", err, auth.ErrInvalidRefreshToken)
+	}
+}
+
+func TestTokenRepositoryRotatesOnce(t *testing.T) {
+	env := newTokenTestEnv(t)
+	pair := env.login(t)
+
+	stored, err := env.tokenRepo.GetRefreshTokenByHash(hashHex(pair.RefreshToken))
+	if err != nil || stored == nil {
+		t.Fatalf("GetRefreshTokenByHash() = %v, %v, expected the issued token", stored, err)
+	}
+
+	replacement := func(hash string) *models.RefreshToken {
+		return &models.RefreshToken{
+			UserID:          env.student.ID,
+			TokenHash:       hash,
+			FamilyID:        stored.FamilyID,
+			AccessTokenID:   hash,
+			AccessExpiresAt: time.Now().Add(time.Hour),
+			ExpiresAt:       time.Now().Add(time.Hour),
+		}
+	}
+
+	if rotated, err := env.tokenRepo.RotateRefreshToken(stored.ID, replacement("first")); err != nil || !rotated {
+		t.Fatalf("RotateRefreshToken() = %v, %v, expected true", rotated, err)
+	}
+
+	// A second, concurrent refresh of the same token stores nothing
+	if rotated, err := env.tokenRepo.RotateRefreshToken(stored.ID, replacement("second")); err != nil || rotated {
+		t.Errorf("RotateRefreshToken(again) = %v, %v, expected false", rotated, err)
+	}
+	if token, err := env.tokenRepo.GetRefreshTokenByHash("second"); err != nil || token != nil {
+		t.Errorf("GetRefreshTokenByHash(second) = %v, %v, expected no token", token, err)
+	}
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"gocbt/internal/auth"
+	"gocbt/internal/models"
+	"strings"
+	"time"
+)
+
+const (
+	// twoFactorChallengeExpiration is how long a user has to enter a code after their password
+	twoFactorChallengeExpiration = 5 * time.Minute
+	// twoFactorMaxAttempts is how many wrong codes end a login challenge
+	twoFactorMaxAttempts = 5
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+)
+
+// recoveryCodeEncoding encodes recovery codes in lowercase base32, avoiding characters
+// that are easily confused such as 0 and o or 1 and l
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// TwoFactorService implements the models.TwoFactorService interface. A login that needs
+// a second step gets a short-lived challenge instead of tokens; a TOTP code or a
+// single-use recovery code completes it. Users whose role requires two-factor
+// authentication but who have no authenticator yet enrol with the challenge.
+type TwoFactorService struct {
+	twoFactorRepo models.TwoFactorRepository
+	roleRepo      models.RoleRepository
+	userRepo      models.UserRepository
+	issuer        string
+}
+
+// NewTwoFactorService creates a new two-factor service. The issuer names the service in
+// authenticator apps.
+func NewTwoFactorService(twoFactorRepo models.TwoFactorRepository, roleRepo models.RoleRepository, userRepo models.UserRepository, issuer string) models.TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		roleRepo:      roleRepo,
+		userRepo:      userRepo,
+		issuer:        issuer,
+	}
+}
+
+// BeginLogin starts the second step of a login that passed its first step with the given
+// authentication methods. It returns nil if the user can be issued tokens straight away:
+// the first step already used more than one factor, or the user has no authenticator and
+// none of their roles requires one.
+func (s *TwoFactorService) BeginLogin(user *models.User, amr []string) (*models.TwoFactorLogin, error) {
+	if models.HasAuthMethod(amr, models.AuthMethodMFA) {
+		return nil, nil
+	}
+
+	twoFactor, err := s.twoFactorRepo.GetTwoFactor(user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	enabled := twoFactor != nil && twoFactor.IsEnabled()
+
+	if !enabled {
+		required, err := s.roleRepo.IsTwoFactorRequired(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	// Expired challenges cannot be completed; clear them out as new ones are created
+	if err := s.twoFactorRepo.DeleteExpiredChallenges(time.Now()); err != nil {
+		return nil, err
+	}
+
+	token, err := generateRandomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.TwoFactorChallenge{
+		TokenHash: hashHex(token),
+		UserID:    user.ID,
+		AMR:       amr,
+		ExpiresAt: time.Now().Add(twoFactorChallengeExpiration),
+	}
+	if err := s.twoFactorRepo.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorLogin{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         challenge.ExpiresAt,
+		EnrolmentRequired: !enabled,
+	}, nil
+}
+
+// GetChallengeUser retrieves the user signing in with a login challenge that can still
+// be completed
+func (s *TwoFactorService) GetChallengeUser(challengeToken string) (*models.User, error) {
+	challenge, err := s.getChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// StartLoginEnrolment generates an authenticator secret for a user who must enrol before
+// completing their login
+func (s *TwoFactorService) StartLoginEnrolment(challengeToken string) (*models.TwoFactorEnrolment, error) {
+	challenge, err := s.getChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.StartEnrolment(challenge.UserID)
+}
+
+// CompleteLogin checks the code of a login challenge. For a user enrolling during login,
+// the code confirms the new authenticator and the result carries their recovery codes.
+func (s *TwoFactorService) CompleteLogin(challengeToken, code string) (*models.TwoFactorLoginResult, error) {
+	challenge, err := s.getChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := s.twoFactorRepo.GetTwoFactor(challenge.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, auth.ErrTwoFactorNotEnrolled
+	}
+
+	result := &models.TwoFactorLoginResult{}
+	var step int64
+	if twoFactor.IsEnabled() {
+		valid, err := s.verifyCode(twoFactor, code)
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			return nil, s.failAttempt(challenge)
+		}
+	} else {
+		var valid bool
+		step, valid = auth.ValidateTOTP(twoFactor.Secret, normalizeTOTPCode(code), time.Now())
+		if !valid {
+			return nil, s.failAttempt(challenge)
+		}
+	}
+
+	// Consume the challenge first so that it completes at most once
+	consumed, err := s.twoFactorRepo.ConsumeChallenge(challenge.TokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, auth.ErrInvalidTwoFactorChallenge
+	}
+
+	if !twoFactor.IsEnabled() {
+		codes, hashes, err := generateRecoveryCodes()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.twoFactorRepo.Enable(challenge.UserID, step, hashes); err != nil {
+			return nil, err
+		}
+		result.RecoveryCodes = codes
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+	if !user.IsActive {
+		return nil, auth.ErrUserNotActive
+	}
+
+	result.User = user
+	result.AMR = append(append([]string{}, challenge.AMR...), models.AuthMethodOTP, models.AuthMethodMFA)
+	return result, nil
+}
+
+// GetStatus retrieves a user's two-factor settings
+func (s *TwoFactorService) GetStatus(userID int) (*models.TwoFactorStatus, error) {
+	twoFactor, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	required, err := s.roleRepo.IsTwoFactorRequired(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Required: required}
+	if twoFactor != nil && twoFactor.IsEnabled() {
+		count, err := s.twoFactorRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+		status.Enabled = true
+		status.EnabledAt = twoFactor.EnabledAt
+		status.RecoveryCodesLeft = count
+	}
+
+	return status, nil
+}
+
+// StartEnrolment generates a new authenticator secret for a user, replacing any earlier
+// enrolment that was not confirmed
+func (s *TwoFactorService) StartEnrolment(userID int) (*models.TwoFactorEnrolment, error) {
+	twoFactor, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.IsEnabled() {
+		return nil, auth.ErrTwoFactorAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrUserNotFound
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.SaveSecret(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrolment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, s.issuer, user.Username),
+	}, nil
+}
+
+// ConfirmEnrolment enables a user's new authenticator once they enter a code from it,
+// returning their recovery codes. The codes are only shown this once.
+func (s *TwoFactorService) ConfirmEnrolment(userID int, code string) ([]string, error) {
+	twoFactor, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, auth.ErrTwoFactorNotEnrolled
+	}
+	if twoFactor.IsEnabled() {
+		return nil, auth.ErrTwoFactorAlreadyEnabled
+	}
+
+	step, valid := auth.ValidateTOTP(twoFactor.Secret, normalizeTOTPCode(code), time.Now())
+	if !valid {
+		return nil, auth.ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking a current code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	twoFactor, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := s.verifyCode(twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, auth.ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication after checking a current code. Users whose
+// role requires it cannot turn it off.
+func (s *TwoFactorService) Disable(userID int, code string) error {
+	twoFactor, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+
+	required, err := s.roleRepo.IsTwoFactorRequired(userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return auth.ErrTwoFactorMandatory
+	}
+
+	valid, err := s.verifyCode(twoFactor, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return auth.ErrInvalidTwoFactorCode
+	}
+
+	return s.twoFactorRepo.Delete(userID)
+}
+
+// Reset removes a user's authenticator and recovery codes, for a user who lost both.
+// If their role requires two-factor authentication they enrol again at their next login.
+func (s *TwoFactorService) Reset(userID int) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if user == nil {
+		return auth.ErrUserNotFound
+	}
+
+	return s.twoFactorRepo.Delete(userID)
+}
+
+// getChallenge retrieves a login challenge that can still be completed
+func (s *TwoFactorService) getChallenge(challengeToken string) (*models.TwoFactorChallenge, error) {
+	challenge, err := s.twoFactorRepo.GetChallenge(hashHex(challengeToken))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if challenge == nil || challenge.IsExpired() || challenge.Attempts >= twoFactorMaxAttempts {
+		return nil, auth.ErrInvalidTwoFactorChallenge
+	}
+	return challenge, nil
+}
+
+// failAttempt counts a wrong code against a login challenge
+func (s *TwoFactorService) failAttempt(challenge *models.TwoFactorChallenge) error {
+	if err := s.twoFactorRepo.RecordFailedAttempt(challenge.TokenHash); err != nil {
+		return err
+	}
+	return auth.ErrInvalidTwoFactorCode
+}
+
+// enabledTwoFactor retrieves a user's authenticator if two-factor authentication is enabled
+func (s *TwoFactorService) enabledTwoFactor(userID int) (*models.TwoFactor, error) {
+	twoFactor, err := s.twoFactorRepo.GetTwoFactor(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if twoFactor == nil || !twoFactor.IsEnabled() {
+		return nil, auth.ErrTwoFactorNotEnabled
+	}
+	return twoFactor, nil
+}
+
+// verifyCode checks a TOTP code or, failing that format, a recovery code. Either can only
+// be used once: a TOTP code's time step is recorded and a recovery code is marked used.
+func (s *TwoFactorService) verifyCode(twoFactor *models.TwoFactor, code string) (bool, error) {
+	totpCode := normalizeTOTPCode(code)
+	if isDigits(totpCode) {
+		step, valid := auth.ValidateTOTP(twoFactor.Secret, totpCode, time.Now())
+		if !valid {
+			return false, nil
+		}
+		return s.twoFactorRepo.UseStep(twoFactor.UserID, step)
+	}
+
+	recoveryCode := normalizeRecoveryCode(code)
+	if recoveryCode == "" {
+		return false, nil
+	}
+	return s.twoFactorRepo.UseRecoveryCode(twoFactor.UserID, hashHex(recoveryCode))
+}
+
+// generateRecoveryCodes generates a set of recovery codes, formatted for display as
+// xxxxx-xxxxx, and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 6)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		code := recoveryCodeEncoding.EncodeToString(bytes)[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashHex(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeTOTPCode removes the spaces users type into or copy with TOTP codes
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// normalizeRecoveryCode lowercases a recovery code and removes its dash and any spaces
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// isDigits checks if a non-empty string only contains the digits 0-9
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"gocbt/internal/auth"
+	"gocbt/internal/database"
+	"gocbt/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// twoFactorTestEnv is a two-factor service with a teacher account, backed by a fresh
+// SQLite database
+type twoFactorTestEnv struct {
+	roleRepo models.RoleRepository
+	service  models.TwoFactorService
+	tokens   models.TokenService
+	teacher  *models.User
+}
+
+func newTwoFactorTestEnv(t *testing.T) *twoFactorTestEnv {
+	t.Helper()
+
+	db := newTestDB(t)
+
+	userRepo := database.NewUserRepository(db)
+	roleRepo := database.NewRoleRepository(db)
+
+	teacher := &models.User{
+		Username:  "teacher",
+		Email:     "teacher@school.example",
+		FirstName: "Grace",
+		LastName:  "Hopper",
+		Role:      models.RoleTeacher,
+		IsActive:  true,
+	}
+	if err := userRepo.Create(teacher); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	return &twoFactorTestEnv{
+		roleRepo: roleRepo,
+		service:  NewTwoFactorService(database.NewTwoFactorRepository(db), roleRepo, userRepo, "GoCBT"),
+		tokens:   NewTokenService(database.NewTokenRepository(db), userRepo, roleRepo, newTestJWT(t), time.Hour),
+		teacher:  teacher,
+	}
+}
+
+// enable enrols the teacher with a code of the current time step, returning the secret
+// and recovery codes
+func (e *twoFactorTestEnv) enable(t *testing.T) (string, []string) {
+	t.Helper()
+
+	enrolment, err := e.service.StartEnrolment(e.teacher.ID)
+	if err != nil {
+		t.Fatalf("StartEnrolment() error = %v", err)
+	}
+
+	codes, err := e.service.ConfirmEnrolment(e.teacher.ID, totpCode(t, enrolment.Secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmEnrolment() error = %v", err)
+	}
+	return enrolment.Secret, codes
+}
+
+// requireForTeachers makes two-factor authentication mandatory for the teacher role
+func (e *twoFactorTestEnv) requireForTeachers(t *testing.T) {
+	t.Helper()
+
+	role, err := e.roleRepo.GetByName(string(models.RoleTeacher))
+	if err != nil || role == nil {
+		t.Fatalf("GetByName() = %v, %v", role, err)
+	}
+	if err := e.roleRepo.SetTwoFactorRequired(role.ID, true); err != nil {
+		t.Fatalf("SetTwoFactorRequired() error = %v", err)
+	}
+}
+
+// totpCode returns the code of a secret for the time step at the given offset from now
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	return code
+}
+
+func TestTwoFactorLoginWithTOTP(t *testing.T) {
+	env := newTwoFactorTestEnv(t)
+	password := []string{models.AuthMethodPassword}
+
+	if challenge, err := env.service.BeginLogin(env.teacher, password); err != nil || challenge != nil {
+		t.Fatalf("BeginLogin() without 2FA = %+v, %v; expected no challenge", challenge, err)
+	}
+
+	secret, _ := env.enable(t)
+
+	challenge, err := env.service.BeginLogin(env.teacher, password)
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	if challenge == nil || challenge.EnrolmentRequired {
+		t.Fatalf("BeginLogin() = %+v, expected a challenge for an enrolled user", challenge)
+	}
+
+	if _, err := env.service.CompleteLogin(challenge.ChallengeToken, totpCode(t, secret, 5)); err != auth.ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() with a future code error = %v, expected %v", err, auth.ErrInvalidTwoFactorCode)
+	}
+
+	// The code that confirmed enrolment was used; the next step's code is accepted once
+	code := totpCode(t, secret, 1)
+	result, err := env.service.CompleteLogin(challenge.ChallengeToken, code)
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if result.User.ID != env.teacher.ID || len(result.RecoveryCodes) != 0 {
+		t.Errorf("CompleteLogin() = user %d with %d recovery codes", result.User.ID, len(result.RecoveryCodes))
+	}
+	if expected := []string{"pwd", "otp", "mfa"}; !reflect.DeepEqual(result.AMR, expected) {
+		t.Errorf("CompleteLogin() amr = %v, expected %v", result.AMR, expected)
+	}
+
+	if _, err := env.service.CompleteLogin(challenge.ChallengeToken, code); err != auth.ErrInvalidTwoFactorChallenge {
+		t.Errorf("CompleteLogin() with a used challenge error = %v, expected %v", err, auth.ErrInvalidTwoFactorChallenge)
+	}
+
+	// A code cannot be replayed in another login
+	again, err := env.service.BeginLogin(env.teacher, password)
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	if _, err := env.service.CompleteLogin(again.ChallengeToken, code); err != auth.ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() with a replayed code error = %v, expected %v", err, auth.ErrInvalidTwoFactorCode)
+	}
+}
+
+func TestTwoFactorRecoveryCodesWorkOnce(t *testing.T) {
+	env := newTwoFactorTestEnv(t)
+	_, codes := env.enable(t)
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("ConfirmEnrolment() returned %d recovery codes, expected %d", len(codes), recoveryCodeCount)
+	}
+
+	challenge, err := env.service.BeginLogin(env.teacher, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	// Recovery codes are accepted without their dash
+	if _, err := env.service.CompleteLogin(challenge.ChallengeToken, " "+codes[0][:5]+codes[0][6:]+" "); err != nil {
+		t.Fatalf("CompleteLogin() with a recovery code error = %v", err)
+	}
+
+	challenge, err = env.service.BeginLogin(env.teacher, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	if _, err := env.service.CompleteLogin(challenge.ChallengeToken, codes[0]); err != auth.ErrInvalidTwoFactorCode {
+		t.Errorf("CompleteLogin() with a used recovery code error = %v, expected %v", err, auth.ErrInvalidTwoFactorCode)
+	}
+
+	status, err := env.service.GetStatus(env.teacher.ID)
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Errorf("GetStatus() = %+v, expected enabled with %d recovery codes left", status, recoveryCodeCount-1)
+	}
+}
+
+func TestTwoFactorChallengeAttemptLimit(t *testing.T) {
+	env := newTwoFactorTestEnv(t)
+	secret, _ := env.enable(t)
+
+	challenge, err := env.service.BeginLogin(env.teacher, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+
+	// The login handler throttles wrong codes by the challenge's user
+	user, err := env.service.GetChallengeUser(challenge.ChallengeToken)
+	if err != nil || user.ID != env.teacher.ID {
+		t.Fatalf("GetChallengeUser() = %v, %v, expected the teacher", user, err)
+	}
+
+	for i := 0; i < twoFactorMaxAttempts; i++ {
+		if _, err := env.service.CompleteLogin(challenge.ChallengeToken, "wrong-code"); err != auth.ErrInvalidTwoFactorCode {
+			t.Fatalf("CompleteLogin() attempt %d error = %v, expected %v", i+1, err, auth.ErrInvalidTwoFactorCode)
+		}
+	}
+
+	if _, err := env.service.CompleteLogin(challenge.ChallengeToken, totpCode(t, secret, 1)); err != auth.ErrInvalidTwoFactorChallenge {
+		t.Errorf("CompleteLogin() after too many attempts error = %v, expected %v", err, auth.ErrInvalidTwoFactorChallenge)
+	}
+	if _, err := env.service.GetChallengeUser(challenge.ChallengeToken); err != auth.ErrInvalidTwoFactorChallenge {
+		t.Errorf("GetChallengeUser() after too many attempts error = %v, expected %v", err, auth.ErrInvalidTwoFactorChallenge)
+	}
+}
+
+func TestTwoFactorRequiredForRole(t *testing.T) {
+	env := newTwoFactorTestEnv(t)
+
+	// A login from before the requirement keeps working until it is refreshed
+	tokens, err := env.tokens.IssueTokens(env.teacher, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("IssueTokens() error = %v", err)
+	}
+
+	env.requireForTeachers(t)
+
+	if _, err := env.tokens.Refresh(tokens.RefreshToken); err != auth.ErrTwoFactorRequired {
+		t.Errorf("Refresh() without a second factor error = %v, expected %v", err, auth.ErrTwoFactorRequired)
+	}
+
+	challenge, err := env.service.BeginLogin(env.teacher, []string{models.AuthMethodPassword})
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	if challenge == nil || !challenge.EnrolmentRequired {
+		t.Fatalf("BeginLogin() = %+v, expected a challenge requiring enrolment", challenge)
+	}
+
+	if _, err := env.service.CompleteLogin(challenge.ChallengeToken, "123456"); err != auth.ErrTwoFactorNotEnrolled {
+		t.Errorf("CompleteLogin() before enrolling error = %v, expected %v", err, auth.ErrTwoFactorNotEnrolled)
+	}
+
+	enrolment, err := env.service.StartLoginEnrolment(challenge.ChallengeToken)
+	if err != nil {
+		t.Fatalf("StartLoginEnrolment() error = %v", err)
+	}
+
+	result, err := env.service.CompleteLogin(challenge.ChallengeToken, totpCode(t, enrolment.Secret, 0))
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if len(result.RecoveryCodes) != recoveryCodeCount {
+		t.Errorf("CompleteLogin() returned %d recovery codes, expected %d", len(result.RecoveryCodes), recoveryCodeCount)
+	}
+
+	// Tokens of a two-factor login can be refreshed and keep their amr
+	tokens, err = env.tokens.IssueTokens(result.User, result.AMR)
+	if err != nil {
+		t.Fatalf("IssueTokens() error = %v", err)
+	}
+	if _, err := env.tokens.Refresh(tokens.RefreshToken); err != nil {
+		t.Errorf("Refresh() after a two-factor login error = %v", err)
+	}
+
+	if err := env.service.Disable(env.teacher.ID, totpCode(t, enrolment.Secret, 1)); err != auth.ErrTwoFactorMandatory {
+		t.Errorf("Disable() error = %v, expected %v", err, auth.ErrTwoFactorMandatory)
+	}
+}
+
+func TestTwoFactorDisable(t *testing.T) {
+	env := newTwoFactorTestEnv(t)
+	secret, _ := env.enable(t)
+
+	if _, err := env.service.StartEnrolment(env.teacher.ID); err != auth.ErrTwoFactorAlreadyEnabled {
+		t.Errorf("StartEnrolment() when enabled error = %v, expected %v", err, auth.ErrTwoFactorAlreadyEnabled)
+	}
+
+	if err := env.service.Disable(env.teacher.ID, totpCode(t, secret, 1)); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+
+	if challenge, err := env.service.BeginLogin(env.teacher, []string{models.AuthMethodPassword}); err != nil || challenge != nil {
+		t.Errorf("BeginLogin() after disabling = %+v, %v; expected no challenge", challenge, err)
+	}
+}
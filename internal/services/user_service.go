@@ -2,6 +2,7 @@ package services
 
 import (
 	"database/sql"
+	"errors"
 	"gocbt/internal/auth"
 	"gocbt/internal/models"
 	"strings"
@@ -11,13 +12,19 @@ import (
 type UserService struct {
 	userRepo        models.UserRepository
 	passwordManager *auth.PasswordManager
+	authenticators  []models.Authenticator
 }
 
-// NewUserService creates a new user service
-func NewUserService(userRepo models.UserRepository, passwordManager *auth.PasswordManager) models.UserService {
+// NewUserService creates a new user service. Logins are checked by each authenticator in
+// turn; without any, local passwords are checked.
+func NewUserService(userRepo models.UserRepository, passwordManager *auth.PasswordManager, authenticators ...models.Authenticator) models.UserService {
+	if len(authenticators) == 0 {
+		authenticators = []models.Authenticator{NewLocalAuthenticator(userRepo, passwordManager, nil)}
+	}
 	return &UserService{
 		userRepo:        userRepo,
 		passwordManager: passwordManager,
+		authenticators:  authenticators,
 	}
 }
 
@@ -87,32 +94,30 @@ func (s *UserService) Register(username, email, password, firstName, lastName st
 	return user, nil
 }
 
-// Login authenticates a user and returns user data
+// Login authenticates a user and returns user data. Authenticators are tried in order
+// until one accepts the credentials; an unreachable directory is skipped so that local
+// accounts can still sign in, and reported only if no authenticator accepts them.
 func (s *UserService) Login(username, password string) (*models.User, error) {
-	// Get user by username
-	user, err := s.userRepo.GetByUsername(username)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, auth.ErrInvalidCredentials
+	unavailable := false
+
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(username, password)
+		switch {
+		case err == nil:
+			return user, nil
+		case err == auth.ErrInvalidCredentials:
+			continue
+		case errors.Is(err, auth.ErrDirectoryUnavailable):
+			unavailable = true
+		default:
+			return nil, err
 		}
-		return nil, err
-	}
-
-	if user == nil {
-		return nil, auth.ErrInvalidCredentials
 	}
 
-	// Check if user is active
-	if !user.IsActive {
-		return nil, auth.ErrUserNotActive
+	if unavailable {
+		return nil, auth.ErrDirectoryUnavailable
 	}
-
-	// Verify password
-	if !s.passwordManager.VerifyPassword(password, user.PasswordHash) {
-		return nil, auth.ErrInvalidCredentials
-	}
-
-	return user, nil
+	return nil, auth.ErrInvalidCredentials
 }
 
 // GetProfile retrieves user profile by ID
@@ -162,6 +167,11 @@ func (s *UserService) UpdateProfile(userID int, firstName, lastName, email strin
 		}
 	}
 
+	// A new address has to be verified again
+	if !strings.EqualFold(user.Email, strings.TrimSpace(email)) {
+		user.EmailVerified = false
+	}
+
 	// Update user fields
 	user.FirstName = strings.TrimSpace(firstName)
 	user.LastName = strings.TrimSpace(lastName)
@@ -199,8 +209,7 @@ func (s *UserService) ChangePassword(userID int, oldPassword, newPassword string
 	}
 
 	// Update password
-	user.PasswordHash = hashedPassword
-	return s.userRepo.Update(user)
+	return s.userRepo.UpdatePassword(user.ID, hashedPassword)
 }
 
 // ListUsers retrieves a list of users by role
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PDFFont identifies one of the standard PDF fonts, which every PDF reader provides
+// without embedding
+type PDFFont int
+
+const (
+	FontRegular PDFFont = iota
+	FontBold
+)
+
+// Page sizes in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// PDFDocument builds a simple PDF of text, lines and rectangles
+type PDFDocument struct {
+	title string
+	pages []*PDFPage
+}
+
+// PDFPage represents one page of a PDF document. Coordinates are in points from the
+// bottom-left corner of the page.
+type PDFPage struct {
+	Width   float64
+	Height  float64
+	content bytes.Buffer
+}
+
+// NewPDFDocument creates an empty PDF document
+func NewPDFDocument(title string) *PDFDocument {
+	return &PDFDocument{title: title}
+}
+
+// AddPage adds a page of the given size
+func (d *PDFDocument) AddPage(width, height float64) *PDFPage {
+	page := &PDFPage{Width: width, Height: height}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text draws text with its baseline starting at x, y
+func (p *PDFPage) Text(x, y float64, font PDFFont, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font+1, formatPDFNumber(size), formatPDFNumber(x), formatPDFNumber(y), escapePDFText(text))
+}
+
+// TextCentered draws text centered horizontally on the page
+func (p *PDFPage) TextCentered(y float64, font PDFFont, size float64, text string) {
+	p.Text((p.Width-TextWidth(font, size, text))/2, y, font, size, text)
+}
+
+// TextRight draws text ending at x
+func (p *PDFPage) TextRight(x, y float64, font PDFFont, size float64, text string) {
+	p.Text(x-TextWidth(font, size, text), y, font, size, text)
+}
+
+// Line draws a straight line
+func (p *PDFPage) Line(x1, y1, x2, y2, lineWidth float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", formatPDFNumber(lineWidth),
+		formatPDFNumber(x1), formatPDFNumber(y1), formatPDFNumber(x2), formatPDFNumber(y2))
+}
+
+// Rect draws the outline of a rectangle
+func (p *PDFPage) Rect(x, y, width, height, lineWidth float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n", formatPDFNumber(lineWidth),
+		formatPDFNumber(x), formatPDFNumber(y), formatPDFNumber(width), formatPDFNumber(height))
+}
+
+// WriteTo writes the document to w
+func (d *PDFDocument) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-5 are fixed; each page then takes a page object and a content stream
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (GoCBT) >>", escapePDFText(d.title)))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			formatPDFNumber(page.Width), formatPDFNumber(page.Height), 7+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// Bytes returns the encoded document
+func (d *PDFDocument) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// TextWidth returns the width of text in points
+func TextWidth(font PDFFont, size float64, text string) float64 {
+	widths := helveticaWidths
+	if font == FontBold {
+		widths = helveticaBoldWidths
+	}
+
+	var units int
+	for _, b := range encodeWinAnsi(text) {
+		if b >= 32 && b <= 126 {
+			units += widths[b-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// WrapText splits text into lines no wider than maxWidth, breaking between words
+func WrapText(font PDFFont, size float64, text string, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && TextWidth(font, size, candidate) > maxWidth {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// encodeWinAnsi converts text to the single-byte encoding of the standard fonts.
+// Characters outside Latin-1 are replaced with a question mark.
+func encodeWinAnsi(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			encoded = append(encoded, ' ')
+		case r >= 32 && r <= 126, r >= 160 && r <= 255:
+			encoded = append(encoded, byte(r))
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// escapePDFText encodes text as the contents of a PDF string literal
+func escapePDFText(text string) string {
+	var b strings.Builder
+	for _, c := range encodeWinAnsi(text) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c > 126 {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatPDFNumber formats a coordinate or size with at most two decimals
+func formatPDFNumber(value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', 2, 64)
+	return strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+}
+
+// Glyph widths of the printable ASCII characters (32-126) in thousandths of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the client IP address of a request without the port.
+// Proxy headers are only honoured when the server is configured to trust them,
+// in which case they have already been applied to RemoteAddr.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableWriter writes rows of cells to a spreadsheet file. Cells may be strings,
+// integers, floats, booleans, times, pointers to those, or nil for an empty cell.
+type TableWriter interface {
+	WriteRow(cells ...interface{}) error
+	Close() error
+}
+
+// CSVWriter writes rows as comma-separated values
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter creates a table writer that writes CSV to w
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{writer: csv.NewWriter(w)}
+}
+
+// WriteRow writes one row
+func (c *CSVWriter) WriteRow(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		value, isText := formatCell(cell)
+		if isText {
+			value = escapeFormula(value)
+		}
+		record[i] = value
+	}
+	return c.writer.Write(record)
+}
+
+// Close flushes any buffered rows
+func (c *CSVWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// XLSXWriter writes rows to a single-sheet Office Open XML workbook. Rows are streamed
+// into the archive as they are written, so large tables are never held in memory.
+type XLSXWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	rows    int
+}
+
+// NewXLSXWriter creates a table writer that writes an XLSX workbook with one sheet to w
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	archive := zip.NewWriter(w)
+
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetTitle(sheetName)))
+
+	parts := []struct{ path, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", strings.Replace(xlsxWorkbook, "{{sheet}}", name.String(), 1)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(file)
+	if _, err := sheet.WriteString(xlsxSheetHeader); err != nil {
+		return nil, err
+	}
+
+	return &XLSXWriter{archive: archive, sheet: sheet}, nil
+}
+
+// WriteRow writes one row
+func (x *XLSXWriter) WriteRow(cells ...interface{}) error {
+	x.rows++
+	row := strconv.Itoa(x.rows)
+
+	x.sheet.WriteString(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		if cell == nil {
+			continue
+		}
+		ref := columnName(i) + row
+		value, isText := formatCell(cell)
+		if value == "" {
+			continue
+		}
+
+		switch {
+		case isText:
+			x.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(x.sheet, []byte(value))
+			x.sheet.WriteString(`</t></is></c>`)
+		case value == "true" || value == "false":
+			flag := "0"
+			if value == "true" {
+				flag = "1"
+			}
+			x.sheet.WriteString(`<c r="` + ref + `" t="b"><v>` + flag + `</v></c>`)
+		default:
+			x.sheet.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close finishes the sheet and the archive
+func (x *XLSXWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetFooter); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
+
+// formatCell converts a cell value to its text and reports whether it is text rather
+// than a number or boolean
+func formatCell(cell interface{}) (string, bool) {
+	switch value := cell.(type) {
+	case nil:
+		return "", false
+	case string:
+		return value, true
+	case *string:
+		if value == nil {
+			return "", false
+		}
+		return *value, true
+	case int:
+		return strconv.Itoa(value), false
+	case *int:
+		if value == nil {
+			return "", false
+		}
+		return strconv.Itoa(*value), false
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), false
+	case *float64:
+		if value == nil {
+			return "", false
+		}
+		return strconv.FormatFloat(*value, 'f', -1, 64), false
+	case bool:
+		return strconv.FormatBool(value), false
+	case *bool:
+		if value == nil {
+			return "", false
+		}
+		return strconv.FormatBool(*value), false
+	case time.Time:
+		return value.UTC().Format(time.RFC3339), true
+	case *time.Time:
+		if value == nil {
+			return "", false
+		}
+		return value.UTC().Format(time.RFC3339), true
+	default:
+		return "", false
+	}
+}
+
+// escapeFormula stops spreadsheet applications from evaluating text that starts like a
+// formula, such as a candidate's answer of "=HYPERLINK(...)"
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// columnName returns the spreadsheet column letters of a zero-based column index
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// sheetTitle shortens a sheet name to the 31 characters allowed and removes the
+// characters that sheet names cannot contain
+func sheetTitle(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "Sheet1"
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="{{sheet}}" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+const xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetFooter = `</sheetData></worksheet>`
@@ -0,0 +1,26 @@
+-- Create test_access_policies table for optional per-test session gates
+CREATE TABLE IF NOT EXISTS test_access_policies (
+    test_id INTEGER PRIMARY KEY,
+    access_code VARCHAR(100), -- static code, or seed for rotating codes
+    code_rotation_minutes INTEGER NOT NULL DEFAULT 0, -- 0 means the code never rotates
+    allowed_cidrs TEXT, -- comma-separated CIDR blocks
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE
+);
+
+-- Create access_gate_failures table for auditing rejected session starts
+CREATE TABLE IF NOT EXISTS access_gate_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    test_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    reason VARCHAR(30) NOT NULL, -- missing_access_code, invalid_access_code, ip_not_allowed
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_access_gate_failures_test_id ON access_gate_failures(test_id);
+CREATE INDEX IF NOT EXISTS idx_access_gate_failures_user_id ON access_gate_failures(user_id);
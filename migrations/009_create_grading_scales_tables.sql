@@ -0,0 +1,32 @@
+-- Create grading_scales table
+CREATE TABLE IF NOT EXISTS grading_scales (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    test_id INTEGER, -- NULL for global scales
+    created_by INTEGER NOT NULL,
+    is_default BOOLEAN DEFAULT FALSE, -- global scale used when a test has none assigned
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create grading_bands table
+CREATE TABLE IF NOT EXISTS grading_bands (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scale_id INTEGER NOT NULL,
+    label VARCHAR(20) NOT NULL,
+    min_percentage DECIMAL(5,2) NOT NULL,
+    gpa_points DECIMAL(4,2),
+    FOREIGN KEY (scale_id) REFERENCES grading_scales(id) ON DELETE CASCADE,
+    UNIQUE(scale_id, min_percentage)
+);
+
+-- Link tests to their grading scale and store GPA points on results
+ALTER TABLE tests ADD COLUMN grading_scale_id INTEGER REFERENCES grading_scales(id) ON DELETE SET NULL;
+ALTER TABLE test_results ADD COLUMN grade_points DECIMAL(4,2);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_grading_scales_test_id ON grading_scales(test_id);
+CREATE INDEX IF NOT EXISTS idx_grading_bands_scale_id ON grading_bands(scale_id);
@@ -0,0 +1,11 @@
+-- Create test_release_settings table for per-test result release policies
+CREATE TABLE IF NOT EXISTS test_release_settings (
+    test_id INTEGER PRIMARY KEY,
+    score_release VARCHAR(20) NOT NULL DEFAULT 'immediate', -- immediate, after_window, manual
+    released_at DATETIME, -- set by a manual release
+    show_correct_answers BOOLEAN DEFAULT FALSE,
+    show_explanations BOOLEAN DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- Add explanation and feedback columns to questions and question options
+ALTER TABLE questions ADD COLUMN explanation TEXT;
+ALTER TABLE questions ADD COLUMN correct_feedback TEXT; -- shown when the answer is correct
+ALTER TABLE questions ADD COLUMN incorrect_feedback TEXT; -- shown when the answer is wrong or missing
+ALTER TABLE question_options ADD COLUMN feedback TEXT; -- shown when the option is selected
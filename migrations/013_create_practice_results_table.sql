@@ -0,0 +1,27 @@
+-- Add test mode and create practice_results table for practice quiz attempts
+ALTER TABLE tests ADD COLUMN mode VARCHAR(20) NOT NULL DEFAULT 'graded'; -- graded, practice
+
+-- Practice attempts are kept apart from graded test_results. The session of an
+-- attempt is replaced by the next attempt, so session_id is not a foreign key.
+CREATE TABLE IF NOT EXISTS practice_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL,
+    test_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    attempt_number INTEGER NOT NULL,
+    total_questions INTEGER NOT NULL,
+    answered_questions INTEGER NOT NULL DEFAULT 0,
+    correct_answers INTEGER NOT NULL DEFAULT 0,
+    total_marks INTEGER NOT NULL,
+    marks_obtained INTEGER NOT NULL DEFAULT 0,
+    percentage DECIMAL(5,2) NOT NULL DEFAULT 0.00,
+    time_taken INTEGER, -- in seconds
+    completed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(test_id, user_id, attempt_number)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_practice_results_test_id ON practice_results(test_id);
+CREATE INDEX IF NOT EXISTS idx_practice_results_user_id ON practice_results(user_id);
@@ -0,0 +1,8 @@
+-- Add item response theory parameters to questions and ability estimates to test results
+ALTER TABLE questions ADD COLUMN irt_model VARCHAR(10); -- rasch or 2pl, NULL until calibrated
+ALTER TABLE questions ADD COLUMN irt_difficulty REAL;
+ALTER TABLE questions ADD COLUMN irt_discrimination REAL;
+ALTER TABLE questions ADD COLUMN irt_responses INTEGER; -- responses used for the calibration
+ALTER TABLE questions ADD COLUMN irt_calibrated_at DATETIME;
+ALTER TABLE test_results ADD COLUMN theta REAL;
+ALTER TABLE test_results ADD COLUMN theta_se REAL;
@@ -0,0 +1,27 @@
+-- Create tables for adaptive test stopping rules and the questions administered per session
+CREATE TABLE IF NOT EXISTS test_adaptive_settings (
+    test_id INTEGER PRIMARY KEY,
+    min_questions INTEGER NOT NULL DEFAULT 5,
+    max_questions INTEGER NOT NULL DEFAULT 20,
+    target_se REAL NOT NULL DEFAULT 0.3, -- stop once the ability standard error is at most this
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS session_adaptive_steps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL,
+    step_number INTEGER NOT NULL,
+    question_id INTEGER NOT NULL,
+    is_correct BOOLEAN, -- NULL until answered
+    theta REAL, -- ability estimate after the answer
+    theta_se REAL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    answered_at DATETIME,
+    FOREIGN KEY (session_id) REFERENCES test_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE,
+    UNIQUE(session_id, step_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_adaptive_steps_session_id ON session_adaptive_steps(session_id);
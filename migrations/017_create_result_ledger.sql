@@ -0,0 +1,21 @@
+-- Create the hash-chained ledger of result changes and add result signatures
+CREATE TABLE IF NOT EXISTS result_ledger (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sequence INTEGER UNIQUE NOT NULL,
+    result_id INTEGER NOT NULL, -- no foreign keys: entries outlive deleted results
+    test_id INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL, -- create, update or delete
+    reason VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL, -- canonical JSON of the result after the change
+    payload_hash VARCHAR(64) NOT NULL,
+    prev_hash VARCHAR(64) UNIQUE NOT NULL, -- entry hash of the previous entry
+    entry_hash VARCHAR(64) UNIQUE NOT NULL,
+    signature TEXT NOT NULL, -- Ed25519 signature of the entry hash
+    key_id VARCHAR(16) NOT NULL,
+    recorded_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_result_ledger_result_id ON result_ledger(result_id);
+CREATE INDEX IF NOT EXISTS idx_result_ledger_test_id ON result_ledger(test_id);
+
+ALTER TABLE test_results ADD COLUMN signature TEXT; -- Ed25519 signature of the canonical result
@@ -0,0 +1,12 @@
+-- Create test_co_owners table for teachers who share ownership of a test with its creator
+CREATE TABLE IF NOT EXISTS test_co_owners (
+    test_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (test_id, user_id),
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_test_co_owners_user_id ON test_co_owners(user_id);
@@ -0,0 +1,54 @@
+-- Create roles built from permissions, and the roles assigned to users
+CREATE TABLE IF NOT EXISTS roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    is_system BOOLEAN DEFAULT FALSE, -- built-in role matching users.role
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role_id, permission),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INTEGER NOT NULL,
+    role_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+
+-- Built-in roles keep the permissions of the student, teacher and admin accounts
+INSERT INTO roles (name, description, is_system) VALUES
+    ('student', 'Takes the tests assigned to their classes', TRUE),
+    ('teacher', 'Writes and grades their own tests', TRUE),
+    ('admin', 'Manages the whole institution', TRUE),
+    ('teaching_assistant', 'Helps grade and proctor the tests they are a co-owner of', FALSE),
+    ('proctor', 'Monitors candidates while they take a test', FALSE),
+    ('grader', 'Views and grades the results of every test', FALSE),
+    ('department_head', 'Oversees every test, class and course', FALSE),
+    ('auditor', 'Reviews results and verifies the result ledger', FALSE);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'tests.create' FROM roles WHERE name IN ('teacher', 'admin')
+UNION ALL SELECT id, 'tests.manage_all' FROM roles WHERE name IN ('admin', 'department_head')
+UNION ALL SELECT id, 'results.view' FROM roles WHERE name IN ('teacher', 'admin', 'teaching_assistant')
+UNION ALL SELECT id, 'results.view_all' FROM roles WHERE name IN ('admin', 'grader', 'department_head', 'auditor')
+UNION ALL SELECT id, 'results.grade' FROM roles WHERE name IN ('teacher', 'admin', 'teaching_assistant')
+UNION ALL SELECT id, 'results.grade_all' FROM roles WHERE name IN ('admin', 'grader')
+UNION ALL SELECT id, 'sessions.monitor' FROM roles WHERE name IN ('admin', 'teaching_assistant', 'proctor')
+UNION ALL SELECT id, 'groups.manage' FROM roles WHERE name IN ('teacher', 'admin')
+UNION ALL SELECT id, 'groups.manage_all' FROM roles WHERE name IN ('admin', 'department_head')
+UNION ALL SELECT id, 'courses.manage' FROM roles WHERE name IN ('teacher', 'admin', 'department_head')
+UNION ALL SELECT id, 'grading_scales.manage' FROM roles WHERE name IN ('admin')
+UNION ALL SELECT id, 'integrity.audit' FROM roles WHERE name IN ('admin', 'auditor')
+UNION ALL SELECT id, 'roles.manage' FROM roles WHERE name IN ('admin');
@@ -0,0 +1,43 @@
+-- Create user_two_factor table for TOTP authenticators. The secret is stored when
+-- enrolment starts and enabled once a code from it is confirmed; codes of a time step up
+-- to last_used_step are rejected so that each code works once.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id INTEGER PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at DATETIME, -- NULL while enrolment is pending
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create two_factor_recovery_codes table for single-use recovery codes, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create two_factor_challenges table for logins waiting for their second step. The
+-- challenge token is stored as a SHA-256 hash with the methods of the first step.
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    amr VARCHAR(100) NOT NULL DEFAULT '', -- comma-separated authentication methods
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Roles can make two-factor authentication mandatory for their users
+ALTER TABLE roles ADD COLUMN require_two_factor BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Refresh tokens keep the authentication methods of their login for the tokens they renew
+ALTER TABLE refresh_tokens ADD COLUMN amr VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_user_id ON two_factor_recovery_codes(user_id);
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_expires_at ON two_factor_challenges(expires_at);
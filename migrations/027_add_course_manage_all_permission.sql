@@ -0,0 +1,6 @@
+-- Courses are managed by the teacher who created them; courses.manage_all covers every
+-- course, as groups.manage_all does for classes
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'courses.manage_all' FROM roles WHERE name IN ('admin', 'department_head');
+
+CREATE INDEX IF NOT EXISTS idx_courses_created_by ON courses(created_by);
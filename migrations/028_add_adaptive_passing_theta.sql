@@ -0,0 +1,3 @@
+-- Adaptive results pass on the final ability estimate rather than the marks of the
+-- questions each candidate happened to be asked
+ALTER TABLE test_adaptive_settings ADD COLUMN passing_theta REAL NOT NULL DEFAULT 0; -- lowest passing theta, on the logit scale
@@ -0,0 +1,11 @@
+-- Create test_seb_settings table for Safe Exam Browser requirements (PostgreSQL version)
+CREATE TABLE IF NOT EXISTS test_seb_settings (
+    test_id INTEGER PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    config_keys TEXT, -- comma-separated SEB Config Keys (hex)
+    browser_exam_keys TEXT, -- comma-separated Browser Exam Keys (hex)
+    quit_password_hash VARCHAR(64), -- SHA-256 hex, as expected by SEB
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE
+);
@@ -0,0 +1,32 @@
+-- Create score_overrides table for manual mark changes by teachers (PostgreSQL version)
+CREATE TABLE IF NOT EXISTS score_overrides (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL, -- answer, result, question
+    test_id INTEGER NOT NULL,
+    question_id INTEGER, -- set for answer and question overrides
+    answer_id INTEGER, -- set for answer overrides
+    result_id INTEGER, -- set for result overrides
+    session_id INTEGER, -- set for answer and result overrides
+    original_value INTEGER, -- marks before the override, NULL for voided questions
+    new_value INTEGER NOT NULL,
+    reason_code VARCHAR(30) NOT NULL,
+    reason TEXT NOT NULL,
+    created_by INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_by INTEGER,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE,
+    FOREIGN KEY (answer_id) REFERENCES user_answers(id) ON DELETE CASCADE,
+    FOREIGN KEY (result_id) REFERENCES test_results(id) ON DELETE CASCADE,
+    FOREIGN KEY (session_id) REFERENCES test_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (revoked_by) REFERENCES users(id)
+);
+
+-- Flag results whose score includes a manual override
+ALTER TABLE test_results ADD COLUMN IF NOT EXISTS has_override BOOLEAN DEFAULT FALSE;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_score_overrides_test_id ON score_overrides(test_id);
+CREATE INDEX IF NOT EXISTS idx_score_overrides_session_id ON score_overrides(session_id);
@@ -0,0 +1,5 @@
+-- Add explanation and feedback columns to questions and question options (PostgreSQL version)
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS explanation TEXT;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS correct_feedback TEXT; -- shown when the answer is correct
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS incorrect_feedback TEXT; -- shown when the answer is wrong or missing
+ALTER TABLE question_options ADD COLUMN IF NOT EXISTS feedback TEXT; -- shown when the option is selected
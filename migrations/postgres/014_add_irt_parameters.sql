@@ -0,0 +1,8 @@
+-- Add item response theory parameters to questions and ability estimates to test results (PostgreSQL version)
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS irt_model VARCHAR(10); -- rasch or 2pl, NULL until calibrated
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS irt_difficulty DOUBLE PRECISION;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS irt_discrimination DOUBLE PRECISION;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS irt_responses INTEGER; -- responses used for the calibration
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS irt_calibrated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE test_results ADD COLUMN IF NOT EXISTS theta DOUBLE PRECISION;
+ALTER TABLE test_results ADD COLUMN IF NOT EXISTS theta_se DOUBLE PRECISION;
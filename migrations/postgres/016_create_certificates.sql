@@ -0,0 +1,30 @@
+-- Create tables for certificate templates and the certificates issued for passed results (PostgreSQL version)
+CREATE TABLE IF NOT EXISTS certificate_templates (
+    test_id INTEGER PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL, -- may use {name}, {test}, {score}, {grade}, {date} and {id}
+    signatory_name VARCHAR(200) NOT NULL DEFAULT '',
+    signatory_title VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS certificates (
+    id SERIAL PRIMARY KEY,
+    verification_id VARCHAR(32) UNIQUE NOT NULL,
+    result_id INTEGER UNIQUE NOT NULL,
+    test_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    candidate_name VARCHAR(200) NOT NULL, -- as printed on the certificate
+    test_title VARCHAR(200) NOT NULL,
+    percentage DOUBLE PRECISION NOT NULL,
+    grade VARCHAR(10),
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (result_id) REFERENCES test_results(id) ON DELETE CASCADE,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_certificates_user_id ON certificates(user_id);
@@ -0,0 +1,38 @@
+-- Create courses, their weighted grade categories and the tests counted in each category (PostgreSQL version)
+CREATE TABLE IF NOT EXISTS courses (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_by INTEGER NOT NULL,
+    grading_scale_id INTEGER, -- global scale for the course grade, NULL for the default scale
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (grading_scale_id) REFERENCES grading_scales(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS course_categories (
+    id SERIAL PRIMARY KEY,
+    course_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    weight DECIMAL(5,2) NOT NULL, -- percent of the course grade; a course's weights total 100
+    drop_lowest INTEGER NOT NULL DEFAULT 0, -- lowest test scores left out of the category
+    position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (course_id) REFERENCES courses(id) ON DELETE CASCADE,
+    UNIQUE(course_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS course_tests (
+    course_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    test_id INTEGER NOT NULL,
+    PRIMARY KEY (course_id, test_id),
+    FOREIGN KEY (course_id) REFERENCES courses(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES course_categories(id) ON DELETE CASCADE,
+    FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_course_categories_course_id ON course_categories(course_id);
+CREATE INDEX IF NOT EXISTS idx_course_tests_category_id ON course_tests(category_id);
+CREATE INDEX IF NOT EXISTS idx_course_tests_test_id ON course_tests(test_id);
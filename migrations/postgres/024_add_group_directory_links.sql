@@ -0,0 +1,4 @@
+-- Link classes to directory groups so that LDAP sign-in keeps class membership in sync (PostgreSQL version)
+ALTER TABLE class_groups ADD COLUMN IF NOT EXISTS directory_group VARCHAR(255); -- lowercase group common name, NULL when not linked
+
+CREATE INDEX IF NOT EXISTS idx_class_groups_directory_group ON class_groups(directory_group);
@@ -0,0 +1,30 @@
+-- Create account_tokens table for password reset and email verification links. The
+-- links carry a signed token; its record makes the token single-use and ties it to the
+-- address it was sent to (PostgreSQL version).
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id VARCHAR(64) PRIMARY KEY, -- token ID (jti)
+    user_id INTEGER NOT NULL,
+    purpose VARCHAR(30) NOT NULL, -- password_reset or email_verification
+    email VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create failed_logins table for throttling password guessing by username and by IP address
+CREATE TABLE IF NOT EXISTS failed_logins (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL, -- lowercased, whether or not the account exists
+    ip_address VARCHAR(45) NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Users confirm their email address through a verification link
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_expires_at ON account_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_failed_logins_username ON failed_logins(username, attempted_at);
+CREATE INDEX IF NOT EXISTS idx_failed_logins_ip_address ON failed_logins(ip_address, attempted_at);
@@ -0,0 +1,3 @@
+-- Adaptive results pass on the final ability estimate rather than the marks of the
+-- questions each candidate happened to be asked (PostgreSQL version)
+ALTER TABLE test_adaptive_settings ADD COLUMN IF NOT EXISTS passing_theta DOUBLE PRECISION NOT NULL DEFAULT 0; -- lowest passing theta, on the logit scale